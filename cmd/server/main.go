@@ -1,22 +1,29 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"fmt"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
-	"strings"
+	"time"
 
 	"github.com/biqiangwu/flowerSalesSystem/internal/address"
 	"github.com/biqiangwu/flowerSalesSystem/internal/auth"
+	"github.com/biqiangwu/flowerSalesSystem/internal/cart"
 	"github.com/biqiangwu/flowerSalesSystem/internal/config"
 	"github.com/biqiangwu/flowerSalesSystem/internal/database"
+	"github.com/biqiangwu/flowerSalesSystem/internal/discount"
 	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
 	"github.com/biqiangwu/flowerSalesSystem/internal/handler"
+	"github.com/biqiangwu/flowerSalesSystem/internal/maintenance"
 	"github.com/biqiangwu/flowerSalesSystem/internal/order"
+	"github.com/biqiangwu/flowerSalesSystem/internal/report"
 	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+	"github.com/biqiangwu/flowerSalesSystem/pkg/logger"
 	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
 )
 
@@ -26,10 +33,13 @@ var staticFiles embed.FS
 func main() {
 	// 1. 加载配置
 	cfg := config.Load()
-	log.Printf("加载配置: DBHost=%s, DBName=%s", cfg.DBHost, cfg.DBName)
+	if err := logger.Setup(os.Stdout, cfg.LogLevel); err != nil {
+		log.Fatalf("配置错误: %v", err)
+	}
+	log.Printf("加载配置: DBDriver=%s, DBHost=%s, DBName=%s", cfg.DBDriver, cfg.DBHost, cfg.DBName)
 
 	// 2. 建立数据库连接
-	db, err := database.Open(cfg)
+	db, err := database.OpenWithRetry(cfg, cfg.DBConnectAttempts, time.Duration(cfg.DBConnectBackoff)*time.Second)
 	if err != nil {
 		log.Fatalf("数据库连接失败: %v", err)
 	}
@@ -40,36 +50,123 @@ func main() {
 	if err := database.Migrate(db); err != nil {
 		log.Fatalf("数据库迁移失败: %v", err)
 	}
-	log.Println("数据库迁移完成")
+	version, err := database.CurrentVersion(db)
+	if err != nil {
+		log.Fatalf("查询数据库版本失败: %v", err)
+	}
+	log.Printf("数据库迁移完成，当前版本: %d", version)
 
 	// 4. 初始化 Repository 层
 	userRepo := user.NewMySQLUserRepository(db)
 	addressRepo := address.NewAddressRepository(db)
 	flowerRepo := flower.NewFlowerRepository(db)
+	flowerLogRepo := flower.NewFlowerLogRepository(db)
 	orderRepo := order.NewOrderRepository(db)
 	orderLogRepo := order.NewOrderLogRepository(db)
+	reportRepo := report.NewReportRepository(db)
+	impersonationLogRepo := auth.NewImpersonationLogRepository(db)
+	passwordResetRepo := auth.NewPasswordResetRepository(db)
+	maintenanceRepo := maintenance.NewMaintenanceRepository(db)
+	discountRepo := discount.NewDiscountRepository(db)
+	cartRepo := cart.NewCartRepository(db)
 
 	// 5. 初始化 Session 管理
-	sessionMgr := auth.NewMemorySessionManager()
+	sessionExpiry := time.Duration(cfg.SessionExpiry) * time.Hour
+	sessionMgr, err := auth.NewSessionManager(cfg.SessionStore, db, sessionExpiry)
+	if err != nil {
+		log.Fatalf("配置错误: %v", err)
+	}
+	log.Printf("Session 存储: %s, 有效期: %s", cfg.SessionStore, sessionExpiry)
+	sameSite, err := handler.ParseSameSite(cfg.SessionCookieSameSite)
+	if err != nil {
+		log.Fatalf("配置错误: %v", err)
+	}
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("配置错误: %v", err)
+	}
 
 	// 6. 初始化服务层
-	authSvc := auth.NewAuthService(userRepo, sessionMgr)
-	flowerSvc := flower.NewFlowerService(flowerRepo)
-	addressSvc := address.NewAddressService(addressRepo)
-	orderSvc := order.NewOrderService(orderRepo, flowerRepo, orderLogRepo)
+	passwordPolicy := user.PasswordPolicy{
+		MinLength:     cfg.PasswordMinLen,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireLetter: cfg.PasswordRequireLetter,
+	}
+	authSvc := auth.NewAuthServiceWithPolicy(userRepo, sessionMgr, cfg.PasswordPepper, passwordPolicy)
+	flowerSvc := flower.NewFlowerServiceWithThreshold(flowerRepo, flowerLogRepo, cfg.StockWarningThreshold)
+	addressSvc := address.NewAddressServiceWithStrictPhone(addressRepo, cfg.AddressStrictPhone)
+	stockCheck, err := order.ParseStockCheckMode(cfg.OrderStockCheck)
+	if err != nil {
+		log.Fatalf("配置错误: %v", err)
+	}
+	catalogCheck, err := order.ParseStockCheckMode(cfg.OrderCatalogCheck)
+	if err != nil {
+		log.Fatalf("配置错误: %v", err)
+	}
+	notifier, err := order.NewNotifier(cfg.OrderNotifier)
+	if err != nil {
+		log.Fatalf("配置错误: %v", err)
+	}
+	orderSvc := order.NewOrderServiceWithOptions(orderRepo, flowerRepo, orderLogRepo, order.OrderServiceOptions{
+		AddressRepo:  addressRepo,
+		StockCheck:   stockCheck,
+		CatalogCheck: catalogCheck,
+		Notifier:     notifier,
+		DiscountRepo: discountRepo,
+		Limits:       order.OrderLimits{MaxItemQuantity: cfg.MaxItemQuantity, MaxOrderItems: cfg.MaxOrderItems},
+	})
 	orderLogSvc := order.NewOrderLogService(orderLogRepo)
-	userSvc := user.NewUserService(userRepo)
+	userSvc := user.NewUserServiceWithPolicy(userRepo, cfg.PasswordPepper, passwordPolicy)
+	reportSvc := report.NewReportService(reportRepo)
+	impersonationSvc := auth.NewImpersonationService(userRepo, sessionMgr, impersonationLogRepo, auth.DefaultImpersonationTTL)
+	maintenanceSvc := maintenance.NewMaintenanceService(maintenanceRepo)
+	discountSvc := discount.NewDiscountService(discountRepo)
+	cartSvc := cart.NewCartService(cartRepo, flowerRepo, orderSvc)
+	passwordResetSvc := auth.NewPasswordResetService(userRepo, authSvc, passwordResetRepo, time.Duration(cfg.PasswordResetTTL)*time.Second)
+	if cfg.PasswordResetExposeToken {
+		log.Printf("警告: PASSWORD_RESET_EXPOSE_TOKEN 已启用，重置令牌将直接返回给客户端，仅限开发环境使用")
+	}
+
+	// 启动过期会话清理任务，数据库存储的会话不会自行删除
+	go auth.RunSessionCleanup(context.Background(), sessionMgr, min(sessionExpiry, auth.DefaultSessionCleanupInterval))
+
+	// 启动库存预留超时释放任务
+	if cfg.OrderReservationTTL > 0 {
+		reservationTTL := time.Duration(cfg.OrderReservationTTL) * time.Second
+		reapInterval := min(reservationTTL, time.Minute)
+		go order.RunReservationReaper(context.Background(), orderSvc, reservationTTL, reapInterval)
+		log.Printf("库存预留时长: %s", reservationTTL)
+	}
 
 	// 7. 创建 Handler 并注入所有服务
 	h := handler.NewHandler(authSvc, orderSvc)
 	h.SetServices(authSvc, orderSvc, orderLogSvc, userSvc, flowerSvc, addressSvc, userRepo)
+	h.SetReportService(reportSvc)
+	h.SetImpersonationService(impersonationSvc)
+	h.SetPasswordResetService(passwordResetSvc, cfg.PasswordResetExposeToken)
+	h.SetMaintenanceService(maintenanceSvc)
+	h.SetDiscountService(discountSvc)
+	h.SetCartService(cartSvc)
+	h.SetAPIPrefix(cfg.APIPrefix)
+	h.SetConfig(cfg)
+	h.SetSessionCookieOptions(handler.SessionCookieOptions{
+		Path:     cfg.SessionCookiePath,
+		Domain:   cfg.SessionCookieDomain,
+		SameSite: sameSite,
+		Secure:   cfg.TLSEnabled,
+	})
+	h.SetDB(db)
+	h.SetReportConcurrency(cfg.ReportMaxConcurrency)
+	h.SetLoginRateLimit(cfg.LoginRateLimit, time.Duration(cfg.LoginRateWindow)*time.Second)
+	h.SetTrustedProxies(trustedProxies)
+	h.SetIdempotencyWindow(time.Duration(cfg.IdempotencyWindow) * time.Second)
 
 	// 8. 创建 HTTP ServeMux
 	mux := http.NewServeMux()
 
 	// 9. 注册所有 API 路由
 	h.RegisterRoutes(mux)
-	log.Println("API 路由注册完成")
+	log.Printf("API 路由注册完成，前缀: %v", h.APIPrefixes())
 
 	// 10. 注册静态文件服务（SPA 模式）
 	staticFS, err := fs.Sub(staticFiles, "static")
@@ -77,37 +174,40 @@ func main() {
 		log.Fatalf("静态文件系统配置失败: %v", err)
 	}
 
-	// 创建 SPA 处理器：未匹配的路由返回 index.html
-	spaHandler := &spaHandler{http.FileServer(http.FS(staticFS))}
-	mux.Handle("/", spaHandler)
+	// 创建 SPA 处理器：未匹配的路由返回 index.html，带内容哈希的静态资源按配置长期缓存
+	mux.Handle("/", newSPAHandler(staticFS, time.Duration(cfg.StaticMaxAge)*time.Second))
 
 	// 11. 应用中间件
-	// 包装日志中间件和恢复中间件
-	finalHandler := middleware.LoggingMiddleware(middleware.RecoveryMiddleware(mux))
-
-	// 12. 启动 HTTP 服务器
+	// 包装请求 ID 中间件、日志中间件、恢复中间件、跨域中间件、压缩中间件、路径规范化中间件、请求体大小限制中间件、CSRF 中间件和请求超时中间件
+	// 跨域中间件位于路由之前，预检请求不会进入 mux
+	// 压缩中间件位于恢复中间件之内，处理器 panic 时尚未写出的缓冲内容被丢弃，由恢复中间件返回 500
+	// 请求体大小限制与 CSRF 中间件位于路径规范化之后，按规范化后的路径匹配；登录、注册与找回密码时尚无令牌，不校验 CSRF
+	// 请求超时中间件为请求上下文设置截止时间，处理器将其传给服务层与数据库查询
+	// 请求 ID 中间件位于最外层，日志与错误响应均可获取请求 ID
+	cors := middleware.CORSMiddleware(cfg.CORSAllowedOrigins, handler.TotalCountHeader, handler.HasMoreHeader, middleware.RequestIDHeader)
+	pathLimits := make(map[string]int64)
+	for _, path := range h.APIPaths("/flowers/import") {
+		pathLimits[path] = max(int64(cfg.MaxBodyBytes), handler.MaxFlowerImportSize)
+	}
+	bodyLimit := middleware.MaxBodyBytesMiddleware(int64(cfg.MaxBodyBytes), pathLimits)
+	timeout := middleware.RequestTimeoutMiddleware(time.Duration(cfg.RequestTimeout) * time.Second)
+	csrf := middleware.CSRFMiddleware(h.APIPaths("/login", "/register", "/password-reset/request", "/password-reset/confirm")...)
+	normalizePath := middleware.NormalizePathPrefixesMiddleware(h.APIPrefixes()...)
+	gzip := middleware.GzipMiddleware(cfg.GzipMinSize)
+	finalHandler := middleware.RequestIDMiddleware(middleware.LoggingMiddleware(middleware.RecoveryMiddleware(cors(gzip(normalizePath(bodyLimit(csrf(timeout(mux)))))))))
+
+	// 12. 启动 HTTP 服务器，启用 TLS 时提供 HTTPS
 	addr := fmt.Sprintf(":%d", cfg.ServerPort)
-	log.Printf("HTTP 服务器启动在 http://0.0.0.0%s", addr)
-
-	if err := http.ListenAndServe(addr, finalHandler); err != nil {
-		log.Fatalf("HTTP 服务器错误: %v", err)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("监听 %s 失败: %v", addr, err)
 	}
-}
+	log.Printf("HTTP 服务器启动在 %s://0.0.0.0%s", serverScheme(cfg), addr)
 
-// spaHandler 处理 SPA 路由，未匹配的路由返回 index.html
-type spaHandler struct {
-	handler http.Handler
-}
-
-func (h *spaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// 如果是 API 请求，不处理
-	if strings.HasPrefix(r.URL.Path, "/api/") {
-		h.handler.ServeHTTP(w, r)
-		return
+	srv := &http.Server{Addr: addr, Handler: finalHandler}
+	if err := serve(srv, ln, cfg); err != nil {
+		log.Fatalf("HTTP 服务器错误: %v", err)
 	}
-
-	// 尝试提供静态文件
-	h.handler.ServeHTTP(w, r)
 }
 
 // init 用于日志初始化
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/config"
+)
+
+// errTLSFilesMissing 启用 TLS 但未配置证书或私钥文件
+var errTLSFilesMissing = errors.New("TLS_ENABLED 已启用，但未配置 TLS_CERT_FILE 或 TLS_KEY_FILE")
+
+// serve 按配置在 ln 上提供服务：启用 TLS 时使用证书与私钥文件提供 HTTPS，否则提供 HTTP
+// 与 http.Server.Serve 一样阻塞直到服务器关闭
+func serve(srv *http.Server, ln net.Listener, cfg *config.Config) error {
+	if !cfg.TLSEnabled {
+		return srv.Serve(ln)
+	}
+	if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+		return errTLSFilesMissing
+	}
+	return srv.ServeTLS(ln, cfg.TLSCertFile, cfg.TLSKeyFile)
+}
+
+// serverScheme 返回服务器对外的协议，用于启动日志
+func serverScheme(cfg *config.Config) string {
+	if cfg.TLSEnabled {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/config"
+)
+
+// writeTestCert 在临时目录生成自签名证书与私钥文件
+func writeTestCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+// TestServe 测试启用 TLS 时提供 HTTPS，未启用时提供 HTTP
+func TestServe(t *testing.T) {
+	certFile, keyFile := writeTestCert(t)
+
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		wantTLS bool
+	}{
+		{name: "未启用 TLS", cfg: &config.Config{}, wantTLS: false},
+		{name: "启用 TLS", cfg: &config.Config{TLSEnabled: true, TLSCertFile: certFile, TLSKeyFile: keyFile}, wantTLS: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("failed to listen: %v", err)
+			}
+			srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})}
+			done := make(chan error, 1)
+			go func() { done <- serve(srv, ln, tt.cfg) }()
+			t.Cleanup(func() {
+				srv.Close()
+				if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+					t.Errorf("serve() error = %v, want ErrServerClosed", err)
+				}
+			})
+
+			client := &http.Client{Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			}}
+			url := serverScheme(tt.cfg) + "://" + ln.Addr().String()
+			resp, err := client.Get(url)
+			if err != nil {
+				t.Fatalf("GET %s error = %v", url, err)
+			}
+			resp.Body.Close()
+
+			if (resp.TLS != nil) != tt.wantTLS {
+				t.Errorf("response over TLS = %v, want %v", resp.TLS != nil, tt.wantTLS)
+			}
+		})
+	}
+}
+
+// TestServe_TLSFilesMissing 测试启用 TLS 但未配置证书或私钥时拒绝启动
+func TestServe_TLSFilesMissing(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	err = serve(&http.Server{}, ln, &config.Config{TLSEnabled: true, TLSCertFile: "cert.pem"})
+	if !errors.Is(err, errTLSFilesMissing) {
+		t.Errorf("serve() error = %v, want errTLSFilesMissing", err)
+	}
+}
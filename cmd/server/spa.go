@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// hashedAssetPattern 文件名中带内容哈希的静态资源，如 app.3f9a1c2e.js，内容变化时文件名随之变化，可长期缓存
+var hashedAssetPattern = regexp.MustCompile(`\.[0-9a-f]{8,}\.[a-z0-9]+$`)
+
+// spaHandler 处理 SPA 路由：存在的静态文件直接返回，不存在的静态资源返回 404，其余路径返回 index.html
+// API 前缀（版本前缀与旧版 /api）下的路径由 handler.RegisterRoutes 注册的兜底路由返回 JSON 404，不会进入这里
+type spaHandler struct {
+	fsys       fs.FS
+	fileServer http.Handler
+	maxAge     time.Duration     // 带内容哈希的静态资源缓存时长，<= 0 表示不缓存
+	etags      map[string]string // 文件路径 -> 按内容计算的 ETag
+}
+
+// newSPAHandler 创建 SPA 处理器，启动时按文件内容计算各静态文件的 ETag
+func newSPAHandler(fsys fs.FS, maxAge time.Duration) http.Handler {
+	return &spaHandler{
+		fsys:       fsys,
+		fileServer: http.FileServer(http.FS(fsys)),
+		maxAge:     maxAge,
+		etags:      contentETags(fsys),
+	}
+}
+
+func (h *spaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+	if name == "" {
+		h.setCacheHeaders(w, "index.html")
+		h.fileServer.ServeHTTP(w, r)
+		return
+	}
+
+	// 静态文件存在时直接提供
+	if info, err := fs.Stat(h.fsys, name); err == nil && !info.IsDir() {
+		h.setCacheHeaders(w, name)
+		h.fileServer.ServeHTTP(w, r)
+		return
+	}
+
+	// 带扩展名的路径视为静态资源请求，文件不存在时返回 404，避免脚本或样式请求拿到 index.html
+	if path.Ext(name) != "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// 前端路由（如 /orders/123）回退到 index.html，刷新页面时由前端路由渲染
+	h.setCacheHeaders(w, "index.html")
+	http.ServeFileFS(w, r, h.fsys, "index.html")
+}
+
+// setCacheHeaders 设置缓存响应头：带内容哈希的资源长期缓存，index.html 与其他文件每次按 ETag 协商
+// 设置 ETag 后由 http.ServeContent 处理 If-None-Match 并返回 304
+func (h *spaHandler) setCacheHeaders(w http.ResponseWriter, name string) {
+	if etag, ok := h.etags[name]; ok {
+		w.Header().Set("ETag", etag)
+	}
+	if h.maxAge > 0 && hashedAssetPattern.MatchString(path.Base(name)) {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int(h.maxAge.Seconds())))
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+}
+
+// contentETags 遍历静态文件，按内容的 SHA-256 计算强 ETag；读取失败的文件不设置 ETag
+func contentETags(fsys fs.FS) map[string]string {
+	etags := make(map[string]string)
+	fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil
+		}
+		sum := sha256.Sum256(data)
+		etags[name] = `"` + hex.EncodeToString(sum[:16]) + `"`
+		return nil
+	})
+	return etags
+}
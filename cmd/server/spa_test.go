@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/handler"
+	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
+)
+
+// setupTestRouter 创建与 main 一致的路由：API 路由 + SPA 回退
+func setupTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+
+	staticFS := fstest.MapFS{
+		"index.html":         {Data: []byte("<html>index</html>")},
+		"js/app.js":          {Data: []byte("console.log('app')")},
+		"js/app.3f9a1c2e.js": {Data: []byte("console.log('hashed')")},
+		"css/app.css":        {Data: []byte("body{}")},
+	}
+
+	mux := http.NewServeMux()
+	handler.NewHandler(nil, nil).RegisterRoutes(mux)
+	mux.Handle("/", newSPAHandler(staticFS, time.Hour))
+
+	return middleware.NormalizePathMiddleware(mux)
+}
+
+// TestSPAHandler_APINotFound 测试未匹配的 API 路径返回 JSON 404
+func TestSPAHandler_APINotFound(t *testing.T) {
+	router := setupTestRouter(t)
+
+	paths := []string{"/api/does-not-exist", "/api/does-not-exist/", "/api", "/api/", "/api/v1/does-not-exist", "/api/v1"}
+	for _, p := range paths {
+		t.Run(p, func(t *testing.T) {
+			req := httptest.NewRequest("GET", p, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("GET %s status = %d, want %d", p, w.Code, http.StatusNotFound)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+
+			var resp map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp["error"] != "not found" {
+				t.Errorf("error = %q, want %q", resp["error"], "not found")
+			}
+		})
+	}
+}
+
+// TestSPAHandler_Fallback 测试非 API 路径的静态文件、不存在静态资源的 404 与前端路由的 index.html 回退
+func TestSPAHandler_Fallback(t *testing.T) {
+	router := setupTestRouter(t)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"根路径", "/", http.StatusOK, "<html>index</html>"},
+		{"前端路由", "/some/spa/route", http.StatusOK, "<html>index</html>"},
+		{"带 ID 的前端路由", "/orders/123", http.StatusOK, "<html>index</html>"},
+		{"静态脚本", "/js/app.js", http.StatusOK, "console.log('app')"},
+		{"不存在的静态文件", "/js/missing.js", http.StatusNotFound, "404 page not found"},
+		{"根目录下不存在的脚本", "/missing.js", http.StatusNotFound, "404 page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("GET %s status = %d, want %d", tt.path, w.Code, tt.wantStatus)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("GET %s body = %q, want %q", tt.path, w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+// TestSPAHandler_CacheHeaders 测试带内容哈希的资源长期缓存，index.html 与未带哈希的文件每次协商缓存
+func TestSPAHandler_CacheHeaders(t *testing.T) {
+	router := setupTestRouter(t)
+
+	tests := []struct {
+		name             string
+		path             string
+		wantCacheControl string
+	}{
+		{"带哈希的静态资源", "/js/app.3f9a1c2e.js", "public, max-age=3600, immutable"},
+		{"未带哈希的静态资源", "/js/app.js", "no-cache"},
+		{"根路径", "/", "no-cache"},
+		{"前端路由", "/some/spa/route", "no-cache"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %s status = %d, want %d", tt.path, w.Code, http.StatusOK)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.wantCacheControl {
+				t.Errorf("GET %s Cache-Control = %q, want %q", tt.path, got, tt.wantCacheControl)
+			}
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				t.Fatalf("GET %s ETag is empty", tt.path)
+			}
+
+			// 携带 ETag 再次请求时返回 304
+			req = httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("If-None-Match", etag)
+			w = httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusNotModified {
+				t.Errorf("GET %s with If-None-Match status = %d, want %d", tt.path, w.Code, http.StatusNotModified)
+			}
+		})
+	}
+}
+
+// TestSPAHandler_CacheDisabled 测试缓存时长 <= 0 时带哈希的资源也不长期缓存
+func TestSPAHandler_CacheDisabled(t *testing.T) {
+	staticFS := fstest.MapFS{
+		"index.html":         {Data: []byte("<html>index</html>")},
+		"js/app.3f9a1c2e.js": {Data: []byte("console.log('hashed')")},
+	}
+	req := httptest.NewRequest("GET", "/js/app.3f9a1c2e.js", nil)
+	w := httptest.NewRecorder()
+
+	newSPAHandler(staticFS, 0).ServeHTTP(w, req)
+
+	if got := w.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Cache-Control = %q, want %q", got, "no-cache")
+	}
+}
@@ -1,7 +1,8 @@
 // ==================== API 客户端 ====================
 class ApiClient {
     constructor() {
-        this.baseURL = '';
+        // API 版本前缀，与服务端 API_PREFIX 的默认值一致
+        this.baseURL = '/api/v1';
         this.sessionToken = this.getCookie('session_token') || null;
     }
 
@@ -28,6 +29,13 @@ class ApiClient {
             ...options.headers
         };
 
+        // 非 GET 请求回传登录时下发的 CSRF 令牌
+        const method = (options.method || 'GET').toUpperCase();
+        const csrfToken = this.getCookie('csrf_token');
+        if (method !== 'GET' && method !== 'HEAD' && csrfToken) {
+            headers['X-CSRF-Token'] = csrfToken;
+        }
+
         const config = {
             ...options,
             headers
@@ -50,7 +58,7 @@ class ApiClient {
 
     // 认证相关
     async register(username, password) {
-        const result = await this.request('/api/register', {
+        const result = await this.request('/register', {
             method: 'POST',
             body: JSON.stringify({ username, password })
         });
@@ -58,7 +66,7 @@ class ApiClient {
     }
 
     async login(username, password) {
-        const result = await this.request('/api/login', {
+        const result = await this.request('/login', {
             method: 'POST',
             body: JSON.stringify({ username, password })
         });
@@ -69,7 +77,7 @@ class ApiClient {
 
     async logout() {
         try {
-            await this.request('/api/logout', { method: 'POST' });
+            await this.request('/logout', { method: 'POST' });
         } finally {
             this.sessionToken = null;
             this.deleteCookie('session_token');
@@ -79,33 +87,33 @@ class ApiClient {
     // 鲜花相关
     async getFlowers(params = {}) {
         const queryString = new URLSearchParams(params).toString();
-        return this.request(`/api/flowers?${queryString}`);
+        return this.request(`/flowers?${queryString}`);
     }
 
     async getFlower(sku) {
-        return this.request(`/api/flowers/${sku}`);
+        return this.request(`/flowers/${sku}`);
     }
 
     async createFlower(data) {
-        return this.request('/api/flowers', {
+        return this.request('/flowers', {
             method: 'POST',
             body: JSON.stringify(data)
         });
     }
 
     async updateFlower(sku, data) {
-        return this.request(`/api/flowers/${sku}`, {
+        return this.request(`/flowers/${sku}`, {
             method: 'PUT',
             body: JSON.stringify(data)
         });
     }
 
     async deleteFlower(sku) {
-        return this.request(`/api/flowers/${sku}`, { method: 'DELETE' });
+        return this.request(`/flowers/${sku}`, { method: 'DELETE' });
     }
 
     async addStock(sku, quantity) {
-        return this.request(`/api/flowers/${sku}/stock`, {
+        return this.request(`/flowers/${sku}/stock`, {
             method: 'POST',
             body: JSON.stringify({ quantity })
         });
@@ -113,68 +121,68 @@ class ApiClient {
 
     // 地址相关
     async getAddresses() {
-        return this.request('/api/addresses');
+        return this.request('/addresses');
     }
 
     async createAddress(data) {
-        return this.request('/api/addresses', {
+        return this.request('/addresses', {
             method: 'POST',
             body: JSON.stringify(data)
         });
     }
 
     async updateAddress(id, data) {
-        return this.request(`/api/addresses/${id}`, {
+        return this.request(`/addresses/${id}`, {
             method: 'PUT',
             body: JSON.stringify(data)
         });
     }
 
     async deleteAddress(id) {
-        return this.request(`/api/addresses/${id}`, { method: 'DELETE' });
+        return this.request(`/addresses/${id}`, { method: 'DELETE' });
     }
 
     // 订单相关
     async getOrders(params = {}) {
         const queryString = new URLSearchParams(params).toString();
-        return this.request(`/api/orders?${queryString}`);
+        return this.request(`/orders?${queryString}`);
     }
 
     async getOrder(id) {
-        return this.request(`/api/orders/${id}`);
+        return this.request(`/orders/${id}`);
     }
 
     async createOrder(data) {
-        return this.request('/api/orders', {
+        return this.request('/orders', {
             method: 'POST',
             body: JSON.stringify(data)
         });
     }
 
     async completeOrder(id) {
-        return this.request(`/api/orders/${id}/complete`, { method: 'PUT' });
+        return this.request(`/orders/${id}/complete`, { method: 'PUT' });
     }
 
     async cancelOrder(id) {
-        return this.request(`/api/orders/${id}/cancel`, { method: 'PUT' });
+        return this.request(`/orders/${id}/cancel`, { method: 'PUT' });
     }
 
     async getOrderLogs(id) {
-        return this.request(`/api/orders/${id}/logs`);
+        return this.request(`/orders/${id}/logs`);
     }
 
     // 用户管理（管理员）
     async getUsers(params = {}) {
         const queryString = new URLSearchParams(params).toString();
-        return this.request(`/api/users?${queryString}`);
+        return this.request(`/users?${queryString}`);
     }
 
     async deleteUser(id) {
-        return this.request(`/api/users/${id}`, { method: 'DELETE' });
+        return this.request(`/users/${id}`, { method: 'DELETE' });
     }
 
     async resetPassword(id, newPassword) {
-        return this.request(`/api/users/${id}/password`, {
+        return this.request(`/users/${id}/password`, {
             method: 'PUT',
             body: JSON.stringify({ new_password: newPassword })
         });
@@ -183,7 +191,7 @@ class ApiClient {
     // 获取当前用户信息
     async getCurrentUser() {
         try {
-            return await this.request('/api/me');
+            return await this.request('/me');
         } catch (error) {
             return null;
         }
@@ -504,7 +512,7 @@ class Pages {
     static async flowers() {
         try {
             UI.showLoading();
-            const flowers = await api.getFlowers();
+            const { data: flowers } = await api.getFlowers();
 
             const flowersHtml = flowers.map(flower => `
                 <div class="product-card">
@@ -584,7 +592,7 @@ class Pages {
     static async orders(all = false) {
         try {
             UI.showLoading();
-            const orders = await api.getOrders(all ? { all: 'true' } : {});
+            const { data: orders } = await api.getOrders(all ? { all: 'true' } : {});
 
             const ordersHtml = orders.length === 0 ? `
                 <p class="text-muted text-center">暂无订单</p>
@@ -644,7 +652,7 @@ class Pages {
 
         try {
             UI.showLoading();
-            const users = await api.getUsers();
+            const { data: users } = await api.getUsers();
 
             const usersHtml = users.map(user => `
                 <tr>
@@ -694,6 +702,7 @@ class Pages {
     static getStatusName(status) {
         const names = {
             'pending': '待处理',
+            'shipped': '已发货',
             'completed': '已完成',
             'cancelled': '已取消'
         };
@@ -4,10 +4,8 @@ go 1.25.4
 
 require (
 	github.com/go-sql-driver/mysql v1.8.1
+	github.com/mattn/go-sqlite3 v1.14.33
 	golang.org/x/crypto v0.47.0
 )
 
-require (
-	filippo.io/edwards25519 v1.1.0 // indirect
-	github.com/mattn/go-sqlite3 v1.14.33 // indirect
-)
+require filippo.io/edwards25519 v1.1.0 // indirect
@@ -1,21 +1,48 @@
 package address
 
 import (
-	"fmt"
+	"regexp"
+	"strings"
 	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 )
 
 // Address 表示收货地址实体
 type Address struct {
 	ID        int       `json:"id"`
 	UserID    int       `json:"user_id"`
-	Label     string    `json:"label"`      // 可选，如：家、公司
-	Address   string    `json:"address"`    // 详细地址
-	Contact   string    `json:"contact"`    // 联系方式（电话/微信）
+	Label     string    `json:"label"`   // 可选，如：家、公司
+	Address   string    `json:"address"` // 详细地址
+	Contact   string    `json:"contact"` // 联系方式（电话/微信）
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// MaxPageSize 地址列表分页时每页允许的最大数量
+var MaxPageSize = 100
+
+// AddressFilter 地址列表分页条件
+// Page 和 PageSize 均为 0 时不分页，返回用户全部地址
+type AddressFilter struct {
+	Page     int
+	PageSize int
+}
+
+// Validate 验证分页参数
+func (f *AddressFilter) Validate() error {
+	if f.Page < 0 || f.PageSize < 0 {
+		return apperror.Validation("页码和每页数量不能为负数")
+	}
+	if (f.Page > 0 && f.PageSize == 0) || (f.Page == 0 && f.PageSize > 0) {
+		return apperror.Validation("页码和每页数量必须同时设置")
+	}
+	if f.PageSize > MaxPageSize {
+		return apperror.Validation("每页数量不能超过%d", MaxPageSize)
+	}
+	return nil
+}
+
 // NewAddress 创建一个新的地址实体
 func NewAddress(userID int, label, address, contact string) *Address {
 	now := time.Now()
@@ -32,25 +59,67 @@ func NewAddress(userID int, label, address, contact string) *Address {
 // Validate 验证地址数据是否有效
 func (a *Address) Validate() error {
 	if a.UserID <= 0 {
-		return fmt.Errorf("用户ID不能为空")
+		return apperror.Validation("用户ID不能为空")
 	}
 	if a.Address == "" {
-		return fmt.Errorf("地址不能为空")
+		return apperror.Validation("地址不能为空")
 	}
 	if a.Contact == "" {
-		return fmt.Errorf("联系方式不能为空")
+		return apperror.Validation("联系方式不能为空")
 	}
 	if len(a.Address) < 5 {
-		return fmt.Errorf("地址长度不能少于5个字符")
+		return apperror.Validation("地址长度不能少于5个字符")
 	}
 	if len(a.Contact) < 5 {
-		return fmt.Errorf("联系方式长度不能少于5个字符")
+		return apperror.Validation("联系方式长度不能少于5个字符")
 	}
 	if len(a.Address) > 255 {
-		return fmt.Errorf("地址长度不能超过255个字符")
+		return apperror.Validation("地址长度不能超过255个字符")
 	}
 	if len(a.Contact) > 50 {
-		return fmt.Errorf("联系方式长度不能超过50个字符")
+		return apperror.Validation("联系方式长度不能超过50个字符")
 	}
 	return nil
 }
+
+// 联系方式中电话号码的校验规则
+var (
+	// mainlandMobilePattern 中国大陆手机号：11 位、以 1 开头，可带 +86/86 前缀
+	mainlandMobilePattern = regexp.MustCompile(`^(\+?86)?1\d{10}$`)
+	// phonePattern 宽松规则：5-15 位数字，可带 + 前缀，兼容国际号码与座机
+	phonePattern = regexp.MustCompile(`^\+?\d{5,15}$`)
+)
+
+// normalizePhone 去除电话号码中的空格与连字符
+func normalizePhone(phone string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(phone)
+}
+
+// normalizeContact 校验并规范化联系方式
+// 联系方式为电话号码，或姓名加电话号码（如 "张三，138 0013 8000"），号码位于末尾；
+// 号码中的空格与连字符被去除，姓名部分保持不变
+// strict 为 true 时号码必须是中国大陆手机号，否则接受 5-15 位号码
+func normalizeContact(contact string, strict bool) (string, error) {
+	contact = strings.TrimSpace(contact)
+
+	// 从末尾向前截取由数字、+、空格、连字符组成的号码部分
+	i := len(contact)
+	for i > 0 && strings.IndexByte("0123456789+- ", contact[i-1]) >= 0 {
+		i--
+	}
+	// 号码前的空格与连字符视为姓名与号码之间的分隔符
+	for i < len(contact) && (contact[i] == ' ' || contact[i] == '-') {
+		i++
+	}
+	name, phone := contact[:i], normalizePhone(contact[i:])
+
+	if strict {
+		if !mainlandMobilePattern.MatchString(phone) {
+			return "", apperror.Validation("联系方式需包含有效的手机号码，如：张三，13800138000")
+		}
+	} else if !phonePattern.MatchString(phone) {
+		return "", apperror.Validation("联系方式需包含有效的电话号码，如：张三，13800138000")
+	}
+
+	return name + phone, nil
+}
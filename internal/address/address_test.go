@@ -138,3 +138,41 @@ func TestNewAddress(t *testing.T) {
 		t.Errorf("NewAddress() UpdatedAt should be set")
 	}
 }
+
+// TestNormalizeContact 测试联系方式中电话号码的校验与规范化
+func TestNormalizeContact(t *testing.T) {
+	tests := []struct {
+		name    string
+		contact string
+		strict  bool
+		want    string
+		wantErr bool
+	}{
+		{name: "纯手机号", contact: "13800138000", strict: true, want: "13800138000"},
+		{name: "姓名加手机号", contact: "张三，13800138000", strict: true, want: "张三，13800138000"},
+		{name: "去除空格与连字符", contact: "张三 138-0013 8000", strict: true, want: "张三 13800138000"},
+		{name: "带 +86 前缀", contact: "+86 138 0013 8000", strict: true, want: "+8613800138000"},
+		{name: "首尾空白", contact: "  李四：13900139000  ", strict: true, want: "李四：13900139000"},
+		{name: "非法字符", contact: "abc", strict: true, wantErr: true},
+		{name: "只有姓名", contact: "张三", strict: false, wantErr: true},
+		{name: "位数不足", contact: "张三，1380013800", strict: true, wantErr: true},
+		{name: "非 1 开头", contact: "张三，23800138000", strict: true, wantErr: true},
+		{name: "严格模式拒绝国际号码", contact: "John +1 415-555-0100", strict: true, wantErr: true},
+		{name: "宽松模式接受国际号码", contact: "John +1 415-555-0100", strict: false, want: "John +14155550100"},
+		{name: "宽松模式接受座机", contact: "王五，010-12345678", strict: false, want: "王五，01012345678"},
+		{name: "宽松模式号码过长", contact: "1234567890123456", strict: false, wantErr: true},
+		{name: "号码中间夹杂加号", contact: "张三，138+00138000", strict: false, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeContact(tt.contact, tt.strict)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeContact(%q) error = %v, wantErr %v", tt.contact, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeContact(%q) = %q, want %q", tt.contact, got, tt.want)
+			}
+		})
+	}
+}
@@ -5,13 +5,18 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 )
 
+// ErrAddressNotFound 地址不存在
+var ErrAddressNotFound = apperror.New(apperror.KindNotFound, "address not found")
+
 // AddressRepository 定义地址数据访问接口
 type AddressRepository interface {
 	Create(ctx context.Context, a *Address) error
 	GetByID(ctx context.Context, id int) (*Address, error)
-	ListByUserID(ctx context.Context, userID int) ([]*Address, error)
+	ListByUserID(ctx context.Context, userID int, filter AddressFilter) ([]*Address, error)
 	Update(ctx context.Context, a *Address) error
 	Delete(ctx context.Context, id int) error
 }
@@ -67,7 +72,7 @@ func (r *addressRepository) GetByID(ctx context.Context, id int) (*Address, erro
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("address not found: %d", id)
+		return nil, fmt.Errorf("%w: %d", ErrAddressNotFound, id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get address by id: %w", err)
@@ -76,15 +81,22 @@ func (r *addressRepository) GetByID(ctx context.Context, id int) (*Address, erro
 	return &a, nil
 }
 
-// ListByUserID 根据用户 ID 获取地址列表
-func (r *addressRepository) ListByUserID(ctx context.Context, userID int) ([]*Address, error) {
+// ListByUserID 根据用户 ID 获取地址列表，filter 未设置分页时返回全部
+func (r *addressRepository) ListByUserID(ctx context.Context, userID int, filter AddressFilter) ([]*Address, error) {
 	query := `
 		SELECT id, user_id, label, address, contact, created_at, updated_at
 		FROM addresses WHERE user_id = ?
-		ORDER BY created_at DESC
+		ORDER BY created_at DESC, id DESC
 	`
+	args := []interface{}{userID}
+
+	if filter.Page > 0 && filter.PageSize > 0 {
+		offset := (filter.Page - 1) * filter.PageSize
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.PageSize, offset)
+	}
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("list addresses: %w", err)
 	}
@@ -132,7 +144,7 @@ func (r *addressRepository) Update(ctx context.Context, a *Address) error {
 		return fmt.Errorf("check rows affected: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("address not found: %d", a.ID)
+		return fmt.Errorf("%w: %d", ErrAddressNotFound, a.ID)
 	}
 
 	return nil
@@ -152,7 +164,7 @@ func (r *addressRepository) Delete(ctx context.Context, id int) error {
 		return fmt.Errorf("check rows affected: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("address not found: %d", id)
+		return fmt.Errorf("%w: %d", ErrAddressNotFound, id)
 	}
 
 	return nil
@@ -233,7 +233,7 @@ func TestAddressRepository_ListByUserID(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := repo.ListByUserID(ctx, tt.userID)
+			result, err := repo.ListByUserID(ctx, tt.userID, AddressFilter{})
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ListByUserID() error = %v, wantErr %v", err, tt.wantErr)
@@ -2,14 +2,15 @@ package address
 
 import (
 	"context"
-	"fmt"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 )
 
 // AddressService 定义地址业务逻辑接口
 type AddressService interface {
 	CreateAddress(ctx context.Context, userID int, req *CreateAddressRequest) error
 	GetAddress(ctx context.Context, userID, id int) (*AddressResponse, error)
-	ListAddresses(ctx context.Context, userID int) ([]*AddressResponse, error)
+	ListAddresses(ctx context.Context, userID int, filter AddressFilter) ([]*AddressResponse, error)
 	UpdateAddress(ctx context.Context, userID, id int, req *UpdateAddressRequest) error
 	DeleteAddress(ctx context.Context, userID, id int) error
 }
@@ -41,12 +42,18 @@ type AddressResponse struct {
 
 // addressService 实现 AddressService 接口
 type addressService struct {
-	repo AddressRepository
+	repo        AddressRepository
+	strictPhone bool // 联系方式是否只接受中国大陆手机号
 }
 
-// NewAddressService 创建 AddressService 实例
+// NewAddressService 创建 AddressService 实例，联系方式接受国际号码
 func NewAddressService(repo AddressRepository) AddressService {
-	return &addressService{repo: repo}
+	return NewAddressServiceWithStrictPhone(repo, false)
+}
+
+// NewAddressServiceWithStrictPhone 创建 AddressService 实例，strictPhone 为 true 时联系方式只接受中国大陆手机号
+func NewAddressServiceWithStrictPhone(repo AddressRepository, strictPhone bool) AddressService {
+	return &addressService{repo: repo, strictPhone: strictPhone}
 }
 
 // CreateAddress 创建地址
@@ -65,6 +72,11 @@ func (s *addressService) CreateAddress(ctx context.Context, userID int, req *Cre
 	if err := address.Validate(); err != nil {
 		return err
 	}
+	contact, err := normalizeContact(address.Contact, s.strictPhone)
+	if err != nil {
+		return err
+	}
+	address.Contact = contact
 
 	// 保存到数据库
 	return s.repo.Create(ctx, address)
@@ -79,15 +91,19 @@ func (s *addressService) GetAddress(ctx context.Context, userID, id int) (*Addre
 
 	// 验证用户只能访问自己的地址
 	if address.UserID != userID {
-		return nil, fmt.Errorf("无权访问该地址")
+		return nil, apperror.Forbidden("无权访问该地址")
 	}
 
 	return s.toResponse(address), nil
 }
 
-// ListAddresses 获取用户地址列表
-func (s *addressService) ListAddresses(ctx context.Context, userID int) ([]*AddressResponse, error) {
-	addresses, err := s.repo.ListByUserID(ctx, userID)
+// ListAddresses 获取用户地址列表，默认返回全部，设置 filter 时分页
+func (s *addressService) ListAddresses(ctx context.Context, userID int, filter AddressFilter) ([]*AddressResponse, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	addresses, err := s.repo.ListByUserID(ctx, userID, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +126,7 @@ func (s *addressService) UpdateAddress(ctx context.Context, userID, id int, req
 
 	// 验证用户只能操作自己的地址
 	if address.UserID != userID {
-		return fmt.Errorf("无权操作该地址")
+		return apperror.Forbidden("无权操作该地址")
 	}
 
 	// 更新字段
@@ -124,10 +140,17 @@ func (s *addressService) UpdateAddress(ctx context.Context, userID, id int, req
 		address.Contact = *req.Contact
 	}
 
-	// 验证更新后的数据
+	// 验证更新后的数据；联系方式只在本次修改时校验号码，历史数据不受影响
 	if err := address.Validate(); err != nil {
 		return err
 	}
+	if req.Contact != nil {
+		contact, err := normalizeContact(address.Contact, s.strictPhone)
+		if err != nil {
+			return err
+		}
+		address.Contact = contact
+	}
 
 	// 保存到数据库
 	return s.repo.Update(ctx, address)
@@ -143,7 +166,7 @@ func (s *addressService) DeleteAddress(ctx context.Context, userID, id int) erro
 
 	// 验证用户只能操作自己的地址
 	if address.UserID != userID {
-		return fmt.Errorf("无权操作该地址")
+		return apperror.Forbidden("无权操作该地址")
 	}
 
 	// 删除地址
@@ -3,6 +3,7 @@ package address
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3" // SQLite 驱动
@@ -106,6 +107,50 @@ func TestAddressService_CreateAddress(t *testing.T) {
 	}
 }
 
+// TestAddressService_ContactPhone 测试创建与更新地址时联系方式的号码校验与规范化
+func TestAddressService_ContactPhone(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	lenient := NewAddressService(NewAddressRepository(db))
+	strict := NewAddressServiceWithStrictPhone(NewAddressRepository(db), true)
+
+	if err := strict.CreateAddress(ctx, 1, &CreateAddressRequest{Address: "北京市朝阳区xxx街道xxx号", Contact: "abc12"}); err == nil {
+		t.Error("CreateAddress() should reject contact without a phone number")
+	}
+	if err := strict.CreateAddress(ctx, 1, &CreateAddressRequest{Address: "北京市朝阳区xxx街道xxx号", Contact: "John +1 415-555-0100"}); err == nil {
+		t.Error("strict CreateAddress() should reject international number")
+	}
+	if err := lenient.CreateAddress(ctx, 2, &CreateAddressRequest{Address: "1 Market St, San Francisco", Contact: "John +1 415-555-0100"}); err != nil {
+		t.Errorf("lenient CreateAddress() error = %v", err)
+	}
+
+	if err := strict.CreateAddress(ctx, 1, &CreateAddressRequest{Address: "北京市朝阳区xxx街道xxx号", Contact: "张三，138-0013-8000"}); err != nil {
+		t.Fatalf("CreateAddress() error = %v", err)
+	}
+	addresses, err := strict.ListAddresses(ctx, 1, AddressFilter{})
+	if err != nil || len(addresses) != 1 {
+		t.Fatalf("ListAddresses() = %v, %v, want 1 address", addresses, err)
+	}
+	if got := addresses[0].Contact; got != "张三，13800138000" {
+		t.Errorf("stored contact = %q, want %q", got, "张三，13800138000")
+	}
+
+	id := addresses[0].ID
+	if err := strict.UpdateAddress(ctx, 1, id, &UpdateAddressRequest{Contact: stringPtr("张三，12345")}); err == nil {
+		t.Error("UpdateAddress() should reject invalid phone number")
+	}
+	if err := strict.UpdateAddress(ctx, 1, id, &UpdateAddressRequest{Contact: stringPtr("李四 139 0013 9000")}); err != nil {
+		t.Fatalf("UpdateAddress() error = %v", err)
+	}
+	updated, err := strict.GetAddress(ctx, 1, id)
+	if err != nil {
+		t.Fatalf("GetAddress() error = %v", err)
+	}
+	if updated.Contact != "李四 13900139000" {
+		t.Errorf("updated contact = %q, want %q", updated.Contact, "李四 13900139000")
+	}
+}
+
 // TestAddressService_GetAddress 测试获取地址
 func TestAddressService_GetAddress(t *testing.T) {
 	if testing.Short() {
@@ -126,7 +171,7 @@ func TestAddressService_GetAddress(t *testing.T) {
 	}
 
 	// 获取所有地址列表来找到创建的地址 ID
-	addresses, err := service.ListAddresses(ctx, 1)
+	addresses, err := service.ListAddresses(ctx, 1, AddressFilter{})
 	if err != nil {
 		t.Fatalf("failed to list addresses: %v", err)
 	}
@@ -248,7 +293,7 @@ func TestAddressService_ListAddresses(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := service.ListAddresses(ctx, tt.userID)
+			result, err := service.ListAddresses(ctx, tt.userID, AddressFilter{})
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ListAddresses() error = %v, wantErr %v", err, tt.wantErr)
@@ -264,6 +309,63 @@ func TestAddressService_ListAddresses(t *testing.T) {
 	}
 }
 
+// TestAddressService_ListAddresses_Paging 测试地址列表分页
+func TestAddressService_ListAddresses_Paging(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	service, _ := setupTestService(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 5; i++ {
+		req := &CreateAddressRequest{
+			Address: fmt.Sprintf("北京市朝阳区测试路%d号", i),
+			Contact: "张三，13800138000",
+		}
+		if err := service.CreateAddress(ctx, 1, req); err != nil {
+			t.Fatalf("failed to create address: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		filter    AddressFilter
+		wantCount int
+		wantErr   bool
+	}{
+		{name: "默认返回全部", filter: AddressFilter{}, wantCount: 5},
+		{name: "第一页", filter: AddressFilter{Page: 1, PageSize: 2}, wantCount: 2},
+		{name: "最后一页", filter: AddressFilter{Page: 3, PageSize: 2}, wantCount: 1},
+		{name: "超出范围", filter: AddressFilter{Page: 4, PageSize: 2}, wantCount: 0},
+		{name: "只设置页码", filter: AddressFilter{Page: 1}, wantErr: true},
+		{name: "超过最大每页数量", filter: AddressFilter{Page: 1, PageSize: MaxPageSize + 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.ListAddresses(ctx, 1, tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ListAddresses() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(result) != tt.wantCount {
+				t.Errorf("ListAddresses() count = %d, want %d", len(result), tt.wantCount)
+			}
+		})
+	}
+
+	// 分页结果不重叠
+	page1, _ := service.ListAddresses(ctx, 1, AddressFilter{Page: 1, PageSize: 2})
+	page2, _ := service.ListAddresses(ctx, 1, AddressFilter{Page: 2, PageSize: 2})
+	seen := make(map[int]bool)
+	for _, a := range append(page1, page2...) {
+		if seen[a.ID] {
+			t.Errorf("address %d returned on more than one page", a.ID)
+		}
+		seen[a.ID] = true
+	}
+}
+
 // TestAddressService_UpdateAddress 测试更新地址
 func TestAddressService_UpdateAddress(t *testing.T) {
 	if testing.Short() {
@@ -284,7 +386,7 @@ func TestAddressService_UpdateAddress(t *testing.T) {
 	}
 
 	// 获取创建的地址 ID
-	addresses, err := service.ListAddresses(ctx, 1)
+	addresses, err := service.ListAddresses(ctx, 1, AddressFilter{})
 	if err != nil {
 		t.Fatalf("failed to list addresses: %v", err)
 	}
@@ -363,7 +465,7 @@ func TestAddressService_DeleteAddress(t *testing.T) {
 	}
 
 	// 获取创建的地址 ID
-	addresses, err := service.ListAddresses(ctx, 1)
+	addresses, err := service.ListAddresses(ctx, 1, AddressFilter{})
 	if err != nil {
 		t.Fatalf("failed to list addresses: %v", err)
 	}
@@ -432,7 +534,7 @@ func TestAddressService_UserCanOnlyAccessOwnAddresses(t *testing.T) {
 	}
 
 	// 获取用户1的地址 ID
-	addresses1, err := service.ListAddresses(ctx, 1)
+	addresses1, err := service.ListAddresses(ctx, 1, AddressFilter{})
 	if err != nil {
 		t.Fatalf("failed to list addresses: %v", err)
 	}
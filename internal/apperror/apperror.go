@@ -0,0 +1,114 @@
+// Package apperror 定义带分类的业务错误，并统一映射为 HTTP 状态码
+// 服务层返回分类错误，处理器通过 HTTPStatus 决定响应状态，无需匹配错误文本
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kind 错误分类
+type Kind int
+
+const (
+	// KindInternal 未分类错误（如数据库故障），映射为 500
+	KindInternal Kind = iota
+	// KindValidation 请求参数或业务规则校验失败，映射为 400
+	KindValidation
+	// KindUnauthorized 身份认证失败，映射为 401
+	KindUnauthorized
+	// KindForbidden 无权操作，映射为 403
+	KindForbidden
+	// KindNotFound 资源不存在，映射为 404
+	KindNotFound
+	// KindConflict 与当前状态冲突（如重复、并发任务），映射为 409
+	KindConflict
+	// KindUnavailable 依赖暂不可用，映射为 503
+	KindUnavailable
+)
+
+// Error 带分类的错误，可包装底层错误
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+// Error 返回错误信息
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 返回被包装的底层错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New 创建指定分类的错误，常用于定义哨兵错误
+func New(kind Kind, msg string) *Error {
+	return &Error{Kind: kind, Err: errors.New(msg)}
+}
+
+// newf 按格式创建指定分类的错误，支持 %w 包装
+func newf(kind Kind, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// Validation 创建校验失败错误
+func Validation(format string, args ...interface{}) error {
+	return newf(KindValidation, format, args...)
+}
+
+// Unauthorized 创建认证失败错误
+func Unauthorized(format string, args ...interface{}) error {
+	return newf(KindUnauthorized, format, args...)
+}
+
+// Forbidden 创建无权操作错误
+func Forbidden(format string, args ...interface{}) error {
+	return newf(KindForbidden, format, args...)
+}
+
+// NotFound 创建资源不存在错误
+func NotFound(format string, args ...interface{}) error {
+	return newf(KindNotFound, format, args...)
+}
+
+// Conflict 创建状态冲突错误
+func Conflict(format string, args ...interface{}) error {
+	return newf(KindConflict, format, args...)
+}
+
+// Unavailable 创建依赖不可用错误
+func Unavailable(format string, args ...interface{}) error {
+	return newf(KindUnavailable, format, args...)
+}
+
+// KindOf 返回错误链中最外层分类错误的分类，未分类时返回 KindInternal
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// HTTPStatus 将错误映射为 HTTP 状态码，未分类错误返回 500
+func HTTPStatus(err error) int {
+	switch KindOf(err) {
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
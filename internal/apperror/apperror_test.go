@@ -0,0 +1,52 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// TestHTTPStatus 测试错误分类到 HTTP 状态码的映射
+func TestHTTPStatus(t *testing.T) {
+	sentinel := New(KindNotFound, "flower not found")
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "校验失败", err: Validation("数量必须大于0"), want: http.StatusBadRequest},
+		{name: "认证失败", err: Unauthorized("invalid username or password"), want: http.StatusUnauthorized},
+		{name: "无权操作", err: Forbidden("无权访问该订单"), want: http.StatusForbidden},
+		{name: "资源不存在", err: NotFound("order not found: %d", 1), want: http.StatusNotFound},
+		{name: "状态冲突", err: Conflict("鲜花 SKU 已存在"), want: http.StatusConflict},
+		{name: "依赖不可用", err: Unavailable("config not available"), want: http.StatusServiceUnavailable},
+		{name: "包装后的哨兵错误", err: fmt.Errorf("获取鲜花失败: %w", fmt.Errorf("%w: ROS001", sentinel)), want: http.StatusNotFound},
+		{name: "未分类错误", err: errors.New("connection refused"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTTPStatus(tt.err); got != tt.want {
+				t.Errorf("HTTPStatus(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestError_Wrap 测试分类错误保留底层错误链与原始信息
+func TestError_Wrap(t *testing.T) {
+	cause := errors.New("boom")
+	err := Conflict("更新失败: %w", cause)
+
+	if !errors.Is(err, cause) {
+		t.Error("errors.Is should find the wrapped cause")
+	}
+	if err.Error() != "更新失败: boom" {
+		t.Errorf("Error() = %q, want %q", err.Error(), "更新失败: boom")
+	}
+	if KindOf(err) != KindConflict {
+		t.Errorf("KindOf() = %v, want %v", KindOf(err), KindConflict)
+	}
+}
@@ -2,58 +2,105 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 	"github.com/biqiangwu/flowerSalesSystem/internal/user"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// 错误定义
+var (
+	// ErrUsernameExists 用户名已存在，与 user.ErrUsernameTaken 为同一个错误
+	ErrUsernameExists     = user.ErrUsernameTaken
+	ErrInvalidCredentials = apperror.New(apperror.KindUnauthorized, "invalid username or password")
+)
+
 // AuthService 认证服务接口
 type AuthService interface {
 	Register(ctx context.Context, username, password string) (*user.User, error)
+	RegisterWithEmail(ctx context.Context, username, password, email string) (*user.User, error)
 	Login(ctx context.Context, username, password string) (*Session, error)
 	Logout(ctx context.Context, sessionToken string) error
 	ValidateSession(ctx context.Context, sessionToken string) (*user.User, error)
+	ResolveSession(ctx context.Context, sessionToken string) (*user.User, int, error)
 	HashPassword(password string) (string, error)
 	VerifyPassword(password, hash string) bool
 }
 
 // authService 认证服务实现
 type authService struct {
-	userRepo    user.UserRepository
-	sessionMgr  SessionManager
-	minPwdLen   int
+	userRepo   user.UserRepository
+	sessionMgr SessionManager
+	policy     user.PasswordPolicy
+	pepper     string
 }
 
 // NewAuthService 创建认证服务
 func NewAuthService(userRepo user.UserRepository, sessionMgr SessionManager) AuthService {
+	return NewAuthServiceWithPepper(userRepo, sessionMgr, "")
+}
+
+// NewAuthServiceWithPepper 创建带密码 pepper 的认证服务
+// 哈希与校验使用同一个 pepper，详见 user.PepperPassword
+func NewAuthServiceWithPepper(userRepo user.UserRepository, sessionMgr SessionManager, pepper string) AuthService {
+	return NewAuthServiceWithPolicy(userRepo, sessionMgr, pepper, user.DefaultPasswordPolicy)
+}
+
+// NewAuthServiceWithPolicy 创建带密码 pepper 与密码强度策略的认证服务
+// 策略应与用户服务一致，注册、修改密码与重置密码使用相同规则
+func NewAuthServiceWithPolicy(userRepo user.UserRepository, sessionMgr SessionManager, pepper string, policy user.PasswordPolicy) AuthService {
 	return &authService{
 		userRepo:   userRepo,
 		sessionMgr: sessionMgr,
-		minPwdLen:  6, // 最小密码长度 6 位
+		policy:     policy,
+		pepper:     pepper,
 	}
 }
 
 // Register 用户注册
 func (s *authService) Register(ctx context.Context, username, password string) (*user.User, error) {
+	return s.RegisterWithEmail(ctx, username, password, "")
+}
+
+// RegisterWithEmail 用户注册，可同时设置邮箱；email 为空表示不设置
+// 用户名按 user.NormalizeUsername 规范化后存储，"Alice" 与 " alice" 视为同一用户名
+// 邮箱按 user.NormalizeEmail 规范化后校验格式与唯一性
+func (s *authService) RegisterWithEmail(ctx context.Context, username, password, email string) (*user.User, error) {
 	// 验证用户名
+	username = user.NormalizeUsername(username)
 	if username == "" {
-		return nil, fmt.Errorf("username cannot be empty")
+		return nil, apperror.Validation("username cannot be empty")
 	}
 
-	// 验证密码
-	if password == "" {
-		return nil, fmt.Errorf("password cannot be empty")
+	// 验证密码强度
+	if err := user.ValidatePassword(password, s.policy); err != nil {
+		return nil, err
 	}
 
-	if len(password) < s.minPwdLen {
-		return nil, fmt.Errorf("password must be at least %d characters", s.minPwdLen)
+	// 验证邮箱格式与唯一性；并发注册时的竞争由 Create 的唯一约束兜底
+	email = user.NormalizeEmail(email)
+	if email != "" {
+		if err := user.ValidateEmail(email); err != nil {
+			return nil, err
+		}
+		_, err := s.userRepo.GetByEmail(ctx, email)
+		if err == nil {
+			return nil, user.ErrEmailTaken
+		}
+		if apperror.KindOf(err) != apperror.KindNotFound {
+			return nil, fmt.Errorf("failed to check email: %w", err)
+		}
 	}
 
-	// 检查用户名是否已存在
+	// 检查用户名是否已存在；并发注册时的竞争由 Create 的唯一约束兜底
 	_, err := s.userRepo.GetByUsername(ctx, username)
 	if err == nil {
-		return nil, fmt.Errorf("username already exists")
+		return nil, user.ErrUsernameTaken
+	}
+	if apperror.KindOf(err) != apperror.KindNotFound {
+		return nil, fmt.Errorf("failed to check username: %w", err)
 	}
 
 	// 哈希密码
@@ -65,11 +112,18 @@ func (s *authService) Register(ctx context.Context, username, password string) (
 	// 创建用户
 	u := &user.User{
 		Username:     username,
+		Email:        email,
 		PasswordHash: hash,
 		Role:         user.RoleCustomer, // 默认角色为 customer
 	}
 
 	err = s.userRepo.Create(ctx, u)
+	if errors.Is(err, user.ErrUsernameTaken) {
+		return nil, user.ErrUsernameTaken
+	}
+	if errors.Is(err, user.ErrEmailTaken) {
+		return nil, user.ErrEmailTaken
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
@@ -77,26 +131,27 @@ func (s *authService) Register(ctx context.Context, username, password string) (
 	return u, nil
 }
 
-// Login 用户登录
+// Login 用户登录，用户名不区分大小写并忽略首尾空白
 func (s *authService) Login(ctx context.Context, username, password string) (*Session, error) {
 	// 验证输入
+	username = user.NormalizeUsername(username)
 	if username == "" {
-		return nil, fmt.Errorf("username cannot be empty")
+		return nil, apperror.Validation("username cannot be empty")
 	}
 
 	if password == "" {
-		return nil, fmt.Errorf("password cannot be empty")
+		return nil, apperror.Validation("password cannot be empty")
 	}
 
 	// 获取用户
 	u, err := s.userRepo.GetByUsername(ctx, username)
 	if err != nil {
-		return nil, fmt.Errorf("invalid username or password")
+		return nil, ErrInvalidCredentials
 	}
 
 	// 验证密码
 	if !s.VerifyPassword(password, u.PasswordHash) {
-		return nil, fmt.Errorf("invalid username or password")
+		return nil, ErrInvalidCredentials
 	}
 
 	// 创建 Session
@@ -111,7 +166,7 @@ func (s *authService) Login(ctx context.Context, username, password string) (*Se
 // Logout 用户登出
 func (s *authService) Logout(ctx context.Context, sessionToken string) error {
 	if sessionToken == "" {
-		return fmt.Errorf("session token cannot be empty")
+		return apperror.Validation("session token cannot be empty")
 	}
 
 	err := s.sessionMgr.DeleteSession(ctx, sessionToken)
@@ -124,35 +179,42 @@ func (s *authService) Logout(ctx context.Context, sessionToken string) error {
 
 // ValidateSession 验证 Session 并返回用户信息
 func (s *authService) ValidateSession(ctx context.Context, sessionToken string) (*user.User, error) {
+	u, _, err := s.ResolveSession(ctx, sessionToken)
+	return u, err
+}
+
+// ResolveSession 验证 Session，返回用户信息与发起代客登录的管理员 ID（普通会话为 0）
+func (s *authService) ResolveSession(ctx context.Context, sessionToken string) (*user.User, int, error) {
 	if sessionToken == "" {
-		return nil, fmt.Errorf("session token cannot be empty")
+		return nil, 0, apperror.Validation("session token cannot be empty")
 	}
 
 	session, err := s.sessionMgr.ValidateSession(ctx, sessionToken)
 	if err != nil {
-		return nil, fmt.Errorf("invalid session: %w", err)
+		return nil, 0, apperror.Unauthorized("invalid session: %w", err)
 	}
 
 	// 从 Session 中获取用户信息（为了获取最新信息，可以从数据库重新查询）
 	u, err := s.userRepo.GetByID(ctx, session.UserID)
 	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
+		return nil, 0, fmt.Errorf("user not found: %w", err)
 	}
 
-	return u, nil
+	// 代客登录会话只能以顾客身份操作，不具备任何管理权限
+	if session.IsImpersonation() {
+		u.Role = user.RoleCustomer
+	}
+
+	return u, session.ImpersonatorID, nil
 }
 
-// HashPassword 哈希密码
+// HashPassword 按密码强度策略校验后哈希密码
 func (s *authService) HashPassword(password string) (string, error) {
-	if password == "" {
-		return "", fmt.Errorf("password cannot be empty")
-	}
-
-	if len(password) < s.minPwdLen {
-		return "", fmt.Errorf("password must be at least %d characters", s.minPwdLen)
+	if err := user.ValidatePassword(password, s.policy); err != nil {
+		return "", err
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := bcrypt.GenerateFromPassword(user.PepperPassword(password, s.pepper), bcrypt.DefaultCost)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate hash: %w", err)
 	}
@@ -166,6 +228,6 @@ func (s *authService) VerifyPassword(password, hash string) bool {
 		return false
 	}
 
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	err := bcrypt.CompareHashAndPassword([]byte(hash), user.PepperPassword(password, s.pepper))
 	return err == nil
 }
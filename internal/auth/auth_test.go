@@ -3,9 +3,11 @@ package auth
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 	"github.com/biqiangwu/flowerSalesSystem/internal/user"
 	_ "github.com/mattn/go-sqlite3" // SQLite 驱动用于测试
 )
@@ -23,6 +25,7 @@ func setupTestDB(t *testing.T) *sql.DB {
 	CREATE TABLE IF NOT EXISTS users (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		username TEXT UNIQUE NOT NULL,
+		email TEXT UNIQUE,
 		password_hash TEXT NOT NULL,
 		role TEXT NOT NULL DEFAULT 'customer',
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -147,6 +150,82 @@ func TestVerifyPassword(t *testing.T) {
 	}
 }
 
+// TestVerifyPassword_Pepper 测试配置 pepper 后的哈希与校验
+func TestVerifyPassword_Pepper(t *testing.T) {
+	password := "testPassword123"
+	peppered := NewAuthServiceWithPepper(nil, nil, "pepper-a")
+
+	hash, err := peppered.HashPassword(password)
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		svc      AuthService
+		password string
+		want     bool
+	}{
+		{
+			name:     "same pepper (e.g. after restart)",
+			svc:      NewAuthServiceWithPepper(nil, nil, "pepper-a"),
+			password: password,
+			want:     true,
+		},
+		{
+			name:     "same pepper wrong password",
+			svc:      NewAuthServiceWithPepper(nil, nil, "pepper-a"),
+			password: "wrongPassword",
+			want:     false,
+		},
+		{
+			name:     "wrong pepper",
+			svc:      NewAuthServiceWithPepper(nil, nil, "pepper-b"),
+			password: password,
+			want:     false,
+		},
+		{
+			name:     "no pepper",
+			svc:      NewAuthService(nil, nil),
+			password: password,
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.svc.VerifyPassword(tt.password, hash); got != tt.want {
+				t.Errorf("VerifyPassword() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLogin_PepperedResetPassword 测试重置密码与登录使用同一 pepper
+func TestLogin_PepperedResetPassword(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := user.NewMySQLUserRepository(db)
+	authSvc := NewAuthServiceWithPepper(userRepo, NewMemorySessionManager(), "pepper-a")
+	userSvc := user.NewUserServiceWithPepper(userRepo, "pepper-a")
+	ctx := context.Background()
+
+	u, err := authSvc.Register(ctx, "pepperuser", "password123")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if err := userSvc.ResetPassword(ctx, u.ID, "newpassword456", 0, user.RoleAdmin); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+
+	if _, err := authSvc.Login(ctx, "pepperuser", "newpassword456"); err != nil {
+		t.Errorf("Login() with reset password error = %v", err)
+	}
+	if _, err := authSvc.Login(ctx, "pepperuser", "password123"); err == nil {
+		t.Error("Login() with old password should fail")
+	}
+}
+
 // TestRegister 测试用户注册
 func TestRegister(t *testing.T) {
 	db := setupTestDB(t)
@@ -238,8 +317,153 @@ func TestRegister_DuplicateUsername(t *testing.T) {
 
 	// 尝试注册相同用户名
 	_, err = authSvc.Register(ctx, "duplicate", "differentpassword")
-	if err == nil {
-		t.Error("Register() expected error for duplicate username, got nil")
+	if !errors.Is(err, user.ErrUsernameTaken) {
+		t.Errorf("Register() error = %v, want ErrUsernameTaken", err)
+	}
+}
+
+// TestRegister_NormalizedUsername 测试用户名不区分大小写并忽略首尾空白："Alice" 与 " alice " 是同一账号
+func TestRegister_NormalizedUsername(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := user.NewMySQLUserRepository(db)
+	authSvc := NewAuthService(userRepo, NewMemorySessionManager())
+	ctx := context.Background()
+
+	u, err := authSvc.Register(ctx, "  Alice ", "password123")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if u.Username != "alice" {
+		t.Errorf("Register() username = %q, want %q", u.Username, "alice")
+	}
+
+	// 大小写或空白不同的用户名视为重复
+	for _, username := range []string{"alice", "ALICE", " Alice"} {
+		if _, err := authSvc.Register(ctx, username, "password123"); !errors.Is(err, user.ErrUsernameTaken) {
+			t.Errorf("Register(%q) error = %v, want ErrUsernameTaken", username, err)
+		}
+	}
+
+	// 仅包含空白的用户名按空用户名处理
+	if _, err := authSvc.Register(ctx, "   ", "password123"); apperror.KindOf(err) != apperror.KindValidation {
+		t.Errorf("Register(blank) error = %v, want validation error", err)
+	}
+
+	for _, username := range []string{"Alice", "alice ", " ALICE "} {
+		session, err := authSvc.Login(ctx, username, "password123")
+		if err != nil {
+			t.Errorf("Login(%q) error = %v", username, err)
+			continue
+		}
+		if session.UserID != u.ID || session.Username != "alice" {
+			t.Errorf("Login(%q) session = (%d, %q), want (%d, %q)", username, session.UserID, session.Username, u.ID, "alice")
+		}
+	}
+
+	got, err := userRepo.GetByUsername(ctx, " ALICE")
+	if err != nil || got.ID != u.ID {
+		t.Errorf("GetByUsername() = %v, %v, want user %d", got, err, u.ID)
+	}
+}
+
+// TestRegister_PasswordPolicy 测试注册与哈希密码按配置的密码强度策略校验
+func TestRegister_PasswordPolicy(t *testing.T) {
+	db := setupTestDB(t)
+	policy := user.PasswordPolicy{MinLength: 8, RequireDigit: true, RequireLetter: true}
+	authSvc := NewAuthServiceWithPolicy(user.NewMySQLUserRepository(db), NewMemorySessionManager(), "", policy)
+	ctx := context.Background()
+
+	for _, password := range []string{"short1", "onlyletters", "1234567890"} {
+		if _, err := authSvc.Register(ctx, "weak_"+password, password); !errors.Is(err, user.ErrInvalidPassword) {
+			t.Errorf("Register(%q) error = %v, want ErrInvalidPassword", password, err)
+		}
+		if _, err := authSvc.HashPassword(password); apperror.HTTPStatus(err) != 400 {
+			t.Errorf("HashPassword(%q) status = %d, want 400", password, apperror.HTTPStatus(err))
+		}
+	}
+
+	if _, err := authSvc.Register(ctx, "strong", "flower2024"); err != nil {
+		t.Errorf("Register() with strong password error = %v", err)
+	}
+}
+
+// TestRegisterWithEmail 测试注册时设置邮箱：规范化存储、重复邮箱与格式无效被拒绝
+func TestRegisterWithEmail(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := user.NewMySQLUserRepository(db)
+	authSvc := NewAuthService(userRepo, NewMemorySessionManager())
+	ctx := context.Background()
+
+	u, err := authSvc.RegisterWithEmail(ctx, "alice", "password123", "  Alice@Example.com ")
+	if err != nil {
+		t.Fatalf("RegisterWithEmail() error = %v", err)
+	}
+	if u.Email != "alice@example.com" {
+		t.Errorf("Email = %q, want alice@example.com", u.Email)
+	}
+	stored, err := userRepo.GetByEmail(ctx, "alice@example.com")
+	if err != nil || stored.ID != u.ID {
+		t.Fatalf("GetByEmail() = %v, %v, want user %d", stored, err, u.ID)
+	}
+
+	// 未设置邮箱的用户可以有多个
+	for _, name := range []string{"bob", "carol"} {
+		if _, err := authSvc.Register(ctx, name, "password123"); err != nil {
+			t.Fatalf("Register(%s) error = %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		email    string
+		wantErr  error
+	}{
+		{name: "重复邮箱（大小写不同）", username: "dave", email: "ALICE@example.com", wantErr: user.ErrEmailTaken},
+		{name: "缺少 @", username: "erin", email: "erin.example.com", wantErr: user.ErrInvalidEmail},
+		{name: "域名无点号", username: "frank", email: "frank@localhost", wantErr: user.ErrInvalidEmail},
+		{name: "带显示名", username: "grace", email: "Grace <grace@example.com>", wantErr: user.ErrInvalidEmail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := authSvc.RegisterWithEmail(ctx, tt.username, "password123", tt.email)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RegisterWithEmail() error = %v, want %v", err, tt.wantErr)
+			}
+			if _, err := userRepo.GetByUsername(ctx, tt.username); err == nil {
+				t.Errorf("user %s should not be created", tt.username)
+			}
+		})
+	}
+}
+
+// racingUserRepository 模拟并发注册：用户名预检查总是返回不存在，由唯一约束拦截重复插入
+type racingUserRepository struct {
+	user.UserRepository
+}
+
+func (r *racingUserRepository) GetByUsername(ctx context.Context, username string) (*user.User, error) {
+	return nil, apperror.NotFound("user not found: username=%s", username)
+}
+
+// TestRegister_ConcurrentDuplicateUsername 测试预检查通过后插入时撞上唯一约束，同样返回 ErrUsernameTaken
+func TestRegister_ConcurrentDuplicateUsername(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := &racingUserRepository{UserRepository: user.NewMySQLUserRepository(db)}
+	authSvc := NewAuthService(userRepo, NewMemorySessionManager())
+	ctx := context.Background()
+
+	if _, err := authSvc.Register(ctx, "racer", "password123"); err != nil {
+		t.Fatalf("failed to register first user: %v", err)
+	}
+
+	_, err := authSvc.Register(ctx, "racer", "password456")
+	if !errors.Is(err, user.ErrUsernameTaken) {
+		t.Fatalf("Register() error = %v, want ErrUsernameTaken", err)
+	}
+	if got := apperror.HTTPStatus(err); got != 409 {
+		t.Errorf("HTTPStatus() = %d, want 409", got)
 	}
 }
 
@@ -350,9 +574,9 @@ func TestValidateSession(t *testing.T) {
 	}
 
 	tests := []struct {
-		name     string
-		token    string
-		wantErr  bool
+		name      string
+		token     string
+		wantErr   bool
 		checkUser func(*testing.T, *user.User)
 	}{
 		{
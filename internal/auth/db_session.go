@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+)
+
+// DBSessionManager 数据库 Session 管理实现，会话保存在 sessions 表中，服务重启后仍然有效
+// token 列只保存令牌的 SHA-256 哈希，数据库泄露时无法直接冒用会话；过期时间统一以 UTC 存储
+type DBSessionManager struct {
+	db     *sql.DB
+	expiry time.Duration
+}
+
+// NewDBSessionManager 创建数据库 Session 管理器，会话有效期为 DefaultSessionExpiry
+func NewDBSessionManager(db *sql.DB) *DBSessionManager {
+	return NewDBSessionManagerWithExpiry(db, DefaultSessionExpiry)
+}
+
+// NewDBSessionManagerWithExpiry 创建指定会话有效期的数据库 Session 管理器
+// expiry <= 0 时使用 DefaultSessionExpiry
+func NewDBSessionManagerWithExpiry(db *sql.DB, expiry time.Duration) *DBSessionManager {
+	return &DBSessionManager{db: db, expiry: sessionExpiry(expiry)}
+}
+
+// CreateSession 创建新 Session
+func (m *DBSessionManager) CreateSession(ctx context.Context, userID int, username string, role user.Role) (*Session, error) {
+	token, err := generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	session := &Session{
+		Token:     token,
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		ExpiresAt: time.Now().UTC().Add(m.expiry),
+	}
+	if err := m.insert(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// CreateImpersonationSession 创建代客登录 Session
+// 会话角色固定为 customer，有效期为 ttl
+func (m *DBSessionManager) CreateImpersonationSession(ctx context.Context, impersonatorID, userID int, username string, ttl time.Duration) (*Session, error) {
+	if impersonatorID == 0 {
+		return nil, fmt.Errorf("impersonator id cannot be empty")
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	session := &Session{
+		Token:          token,
+		UserID:         userID,
+		Username:       username,
+		Role:           user.RoleCustomer,
+		ExpiresAt:      time.Now().UTC().Add(ttl),
+		ImpersonatorID: impersonatorID,
+	}
+	if err := m.insert(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ValidateSession 验证 Session，过期的会话视为无效
+func (m *DBSessionManager) ValidateSession(ctx context.Context, token string) (*Session, error) {
+	if token == "" {
+		return nil, fmt.Errorf("empty token")
+	}
+
+	query := `
+		SELECT token, user_id, username, role, impersonator_id, expires_at
+		FROM sessions
+		WHERE token = ?
+	`
+	session := &Session{}
+	var tokenHash string
+	err := m.db.QueryRowContext(ctx, query, hashToken(token)).Scan(
+		&tokenHash,
+		&session.UserID,
+		&session.Username,
+		&session.Role,
+		&session.ImpersonatorID,
+		&session.ExpiresAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("session not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		return nil, fmt.Errorf("session expired")
+	}
+
+	session.Token = token
+	return session, nil
+}
+
+// DeleteSession 删除 Session
+func (m *DBSessionManager) DeleteSession(ctx context.Context, token string) error {
+	if token == "" {
+		return fmt.Errorf("empty token")
+	}
+
+	if _, err := m.db.ExecContext(ctx, `DELETE FROM sessions WHERE token = ?`, hashToken(token)); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// CleanupExpiredSessions 清理过期的 Session
+func (m *DBSessionManager) CleanupExpiredSessions(ctx context.Context) error {
+	if _, err := m.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at < ?`, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to cleanup expired sessions: %w", err)
+	}
+	return nil
+}
+
+// insert 写入会话记录
+func (m *DBSessionManager) insert(ctx context.Context, session *Session) error {
+	query := `
+		INSERT INTO sessions (token, user_id, username, role, impersonator_id, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	_, err := m.db.ExecContext(ctx, query,
+		hashToken(session.Token),
+		session.UserID,
+		session.Username,
+		session.Role,
+		session.ImpersonatorID,
+		session.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
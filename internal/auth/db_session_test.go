@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+)
+
+// setupSessionTestDB 创建包含 sessions 表的测试数据库
+func setupSessionTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db := setupTestDB(t)
+	// :memory: 数据库按连接隔离，固定为单连接保证各管理器看到同一份数据
+	db.SetMaxOpenConns(1)
+
+	createTableSQL := `
+	CREATE TABLE IF NOT EXISTS sessions (
+		token TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		username TEXT NOT NULL,
+		role TEXT NOT NULL,
+		impersonator_id INTEGER NOT NULL DEFAULT 0,
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	if _, err := db.Exec(createTableSQL); err != nil {
+		t.Fatalf("failed to create sessions table: %v", err)
+	}
+
+	return db
+}
+
+// countSessions 统计 sessions 表中的记录数
+func countSessions(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sessions`).Scan(&count); err != nil {
+		t.Fatalf("failed to count sessions: %v", err)
+	}
+	return count
+}
+
+// TestDBSessionManager_SurvivesRestart 测试新建的管理器可以验证之前创建的会话
+func TestDBSessionManager_SurvivesRestart(t *testing.T) {
+	db := setupSessionTestDB(t)
+	ctx := context.Background()
+
+	session, err := NewDBSessionManager(db).CreateSession(ctx, 1, "testuser", user.RoleClerk)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	// 模拟服务重启：使用新的管理器实例
+	restarted := NewDBSessionManager(db)
+	got, err := restarted.ValidateSession(ctx, session.Token)
+	if err != nil {
+		t.Fatalf("ValidateSession() error = %v", err)
+	}
+	if got.UserID != 1 || got.Username != "testuser" || got.Role != user.RoleClerk {
+		t.Errorf("ValidateSession() = %+v, want user 1 testuser clerk", got)
+	}
+	if got.ExpiresAt.Sub(session.ExpiresAt).Abs() > time.Second {
+		t.Errorf("ExpiresAt = %v, want %v", got.ExpiresAt, session.ExpiresAt)
+	}
+
+	// 数据库中只保存令牌哈希
+	var stored string
+	if err := db.QueryRow(`SELECT token FROM sessions`).Scan(&stored); err != nil {
+		t.Fatalf("failed to query session token: %v", err)
+	}
+	if stored != hashToken(session.Token) {
+		t.Errorf("stored token = %q, want SHA-256 hash of session token", stored)
+	}
+	if _, err := restarted.ValidateSession(ctx, stored); err == nil {
+		t.Error("ValidateSession() should reject the stored hash used as a token")
+	}
+
+	if err := restarted.DeleteSession(ctx, session.Token); err != nil {
+		t.Fatalf("DeleteSession() error = %v", err)
+	}
+	if _, err := NewDBSessionManager(db).ValidateSession(ctx, session.Token); err == nil {
+		t.Error("ValidateSession() should fail after DeleteSession")
+	}
+}
+
+// TestDBSessionManager_Impersonation 测试代客登录会话保留模拟者信息
+func TestDBSessionManager_Impersonation(t *testing.T) {
+	db := setupSessionTestDB(t)
+	ctx := context.Background()
+	mgr := NewDBSessionManager(db)
+
+	if _, err := mgr.CreateImpersonationSession(ctx, 0, 2, "customer", time.Minute); err == nil {
+		t.Error("CreateImpersonationSession() should fail without impersonator")
+	}
+
+	session, err := mgr.CreateImpersonationSession(ctx, 9, 2, "customer", time.Minute)
+	if err != nil {
+		t.Fatalf("CreateImpersonationSession() error = %v", err)
+	}
+
+	got, err := NewDBSessionManager(db).ValidateSession(ctx, session.Token)
+	if err != nil {
+		t.Fatalf("ValidateSession() error = %v", err)
+	}
+	if !got.IsImpersonation() || got.ImpersonatorID != 9 || got.Role != user.RoleCustomer {
+		t.Errorf("ValidateSession() = %+v, want impersonation by 9 as customer", got)
+	}
+}
+
+// TestDBSessionManager_Expired 测试过期会话被拒绝并由清理一次性删除
+func TestDBSessionManager_Expired(t *testing.T) {
+	db := setupSessionTestDB(t)
+	ctx := context.Background()
+	mgr := NewDBSessionManager(db)
+
+	active, err := mgr.CreateSession(ctx, 1, "active", user.RoleCustomer)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	var expired []string
+	for i := 0; i < 3; i++ {
+		s, err := mgr.CreateImpersonationSession(ctx, 9, 2, "customer", -time.Minute)
+		if err != nil {
+			t.Fatalf("CreateImpersonationSession() error = %v", err)
+		}
+		expired = append(expired, s.Token)
+	}
+
+	for _, token := range expired {
+		if _, err := mgr.ValidateSession(ctx, token); err == nil {
+			t.Errorf("ValidateSession(%s) should reject expired session", token)
+		}
+	}
+
+	if err := mgr.CleanupExpiredSessions(ctx); err != nil {
+		t.Fatalf("CleanupExpiredSessions() error = %v", err)
+	}
+	if got := countSessions(t, db); got != 1 {
+		t.Errorf("sessions after cleanup = %d, want 1", got)
+	}
+	if _, err := mgr.ValidateSession(ctx, active.Token); err != nil {
+		t.Errorf("ValidateSession() active session error = %v", err)
+	}
+}
+
+// TestNewSessionManager 测试按配置选择 Session 存储
+func TestNewSessionManager(t *testing.T) {
+	db := setupSessionTestDB(t)
+
+	tests := []struct {
+		name    string
+		store   string
+		wantDB  bool
+		wantErr bool
+	}{
+		{name: "默认内存", store: "", wantDB: false},
+		{name: "内存", store: SessionStoreMemory, wantDB: false},
+		{name: "数据库", store: SessionStoreDB, wantDB: true},
+		{name: "非法配置", store: "redis", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr, err := NewSessionManager(tt.store, db, time.Hour)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSessionManager() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if _, isDB := mgr.(*DBSessionManager); isDB != tt.wantDB {
+				t.Errorf("NewSessionManager(%q) = %T, want DB = %v", tt.store, mgr, tt.wantDB)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+)
+
+// DefaultImpersonationTTL 代客登录会话的默认有效期
+const DefaultImpersonationTTL = 15 * time.Minute
+
+// 错误定义
+var (
+	ErrImpersonationForbidden = apperror.New(apperror.KindForbidden, "仅管理员可发起代客登录")
+	ErrImpersonationTarget    = apperror.New(apperror.KindValidation, "只能模拟顾客账号")
+)
+
+// ImpersonationLog 代客登录审计记录
+type ImpersonationLog struct {
+	ID           int       `json:"id"`
+	AdminID      int       `json:"admin_id"`
+	TargetUserID int       `json:"target_user_id"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ImpersonationLogRepository 代客登录审计数据访问接口
+type ImpersonationLogRepository interface {
+	Create(ctx context.Context, log *ImpersonationLog) error
+}
+
+// impersonationLogRepository 实现 ImpersonationLogRepository 接口
+type impersonationLogRepository struct {
+	db *sql.DB
+}
+
+// NewImpersonationLogRepository 创建 ImpersonationLogRepository 实例
+func NewImpersonationLogRepository(db *sql.DB) ImpersonationLogRepository {
+	return &impersonationLogRepository{db: db}
+}
+
+// Create 写入审计记录
+func (r *impersonationLogRepository) Create(ctx context.Context, log *ImpersonationLog) error {
+	log.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO impersonation_logs (admin_id, target_user_id, expires_at, created_at)
+		VALUES (?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, log.AdminID, log.TargetUserID, log.ExpiresAt, log.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create impersonation log: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("get last insert id: %w", err)
+	}
+
+	log.ID = int(id)
+	return nil
+}
+
+// ImpersonationService 代客登录服务接口
+type ImpersonationService interface {
+	Impersonate(ctx context.Context, admin *user.User, targetUserID int) (*Session, error)
+}
+
+// impersonationService 实现 ImpersonationService 接口
+type impersonationService struct {
+	userRepo   user.UserRepository
+	sessionMgr SessionManager
+	logRepo    ImpersonationLogRepository
+	ttl        time.Duration
+}
+
+// NewImpersonationService 创建 ImpersonationService 实例
+// ttl <= 0 时使用 DefaultImpersonationTTL
+func NewImpersonationService(userRepo user.UserRepository, sessionMgr SessionManager, logRepo ImpersonationLogRepository, ttl time.Duration) ImpersonationService {
+	if ttl <= 0 {
+		ttl = DefaultImpersonationTTL
+	}
+	return &impersonationService{
+		userRepo:   userRepo,
+		sessionMgr: sessionMgr,
+		logRepo:    logRepo,
+		ttl:        ttl,
+	}
+}
+
+// Impersonate 以目标顾客身份创建短期会话
+// 审计记录写入失败时撤销会话，保证每个代客会话都有审计
+func (s *impersonationService) Impersonate(ctx context.Context, admin *user.User, targetUserID int) (*Session, error) {
+	if admin == nil || admin.Role != user.RoleAdmin {
+		return nil, ErrImpersonationForbidden
+	}
+
+	target, err := s.userRepo.GetByID(ctx, targetUserID)
+	if err != nil {
+		return nil, fmt.Errorf("目标用户不存在: %w", err)
+	}
+
+	if target.Role != user.RoleCustomer {
+		return nil, ErrImpersonationTarget
+	}
+
+	session, err := s.sessionMgr.CreateImpersonationSession(ctx, admin.ID, target.ID, target.Username, s.ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	log := &ImpersonationLog{
+		AdminID:      admin.ID,
+		TargetUserID: target.ID,
+		ExpiresAt:    session.ExpiresAt,
+	}
+	if err := s.logRepo.Create(ctx, log); err != nil {
+		_ = s.sessionMgr.DeleteSession(ctx, session.Token)
+		return nil, fmt.Errorf("写入审计记录失败: %w", err)
+	}
+
+	return session, nil
+}
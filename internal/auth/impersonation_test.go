@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+)
+
+// setupImpersonationTestDB 创建包含审计表的测试数据库
+func setupImpersonationTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db := setupTestDB(t)
+
+	createTableSQL := `
+	CREATE TABLE IF NOT EXISTS impersonation_logs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		admin_id INTEGER NOT NULL,
+		target_user_id INTEGER NOT NULL,
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	if _, err := db.Exec(createTableSQL); err != nil {
+		t.Fatalf("failed to create impersonation_logs table: %v", err)
+	}
+
+	return db
+}
+
+// createImpersonationTestUser 创建指定角色的测试用户
+func createImpersonationTestUser(t *testing.T, repo user.UserRepository, username string, role user.Role) *user.User {
+	t.Helper()
+
+	u := &user.User{Username: username, PasswordHash: "hash", Role: role}
+	if err := repo.Create(context.Background(), u); err != nil {
+		t.Fatalf("failed to create user %s: %v", username, err)
+	}
+	return u
+}
+
+// TestImpersonationService_Impersonate 测试代客登录
+func TestImpersonationService_Impersonate(t *testing.T) {
+	db := setupImpersonationTestDB(t)
+	userRepo := user.NewMySQLUserRepository(db)
+	sessionMgr := NewMemorySessionManager()
+	authSvc := NewAuthService(userRepo, sessionMgr)
+	svc := NewImpersonationService(userRepo, sessionMgr, NewImpersonationLogRepository(db), time.Minute)
+	ctx := context.Background()
+
+	admin := createImpersonationTestUser(t, userRepo, "admin", user.RoleAdmin)
+	clerk := createImpersonationTestUser(t, userRepo, "clerk", user.RoleClerk)
+	customer := createImpersonationTestUser(t, userRepo, "customer", user.RoleCustomer)
+
+	tests := []struct {
+		name     string
+		operator *user.User
+		targetID int
+		wantErr  error
+	}{
+		{name: "管理员模拟顾客", operator: admin, targetID: customer.ID},
+		{name: "店员无权模拟", operator: clerk, targetID: customer.ID, wantErr: ErrImpersonationForbidden},
+		{name: "顾客无权模拟", operator: customer, targetID: customer.ID, wantErr: ErrImpersonationForbidden},
+		{name: "不能模拟店员", operator: admin, targetID: clerk.ID, wantErr: ErrImpersonationTarget},
+		{name: "不能模拟管理员", operator: admin, targetID: admin.ID, wantErr: ErrImpersonationTarget},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := svc.Impersonate(ctx, tt.operator, tt.targetID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Impersonate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Impersonate() error = %v", err)
+			}
+
+			if !session.IsImpersonation() || session.ImpersonatorID != tt.operator.ID {
+				t.Errorf("Impersonate() ImpersonatorID = %d, want %d", session.ImpersonatorID, tt.operator.ID)
+			}
+			if session.UserID != tt.targetID {
+				t.Errorf("Impersonate() UserID = %d, want %d", session.UserID, tt.targetID)
+			}
+			if session.ExpiresAt.After(time.Now().Add(time.Minute)) {
+				t.Errorf("Impersonate() ExpiresAt = %v, exceeds ttl", session.ExpiresAt)
+			}
+
+			u, err := authSvc.ValidateSession(ctx, session.Token)
+			if err != nil {
+				t.Fatalf("ValidateSession() error = %v", err)
+			}
+			if u.ID != tt.targetID || u.Role != user.RoleCustomer {
+				t.Errorf("ValidateSession() = (id %d, role %s), want (id %d, role customer)", u.ID, u.Role, tt.targetID)
+			}
+		})
+	}
+
+	// 只有成功的模拟会写入审计记录
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM impersonation_logs WHERE admin_id = ? AND target_user_id = ?", admin.ID, customer.ID).Scan(&count); err != nil {
+		t.Fatalf("failed to query impersonation_logs: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("impersonation_logs count = %d, want 1", count)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM impersonation_logs").Scan(&count); err != nil {
+		t.Fatalf("failed to query impersonation_logs: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("total impersonation_logs count = %d, want 1", count)
+	}
+}
+
+// TestImpersonationService_Expiry 测试代客会话到期失效
+func TestImpersonationService_Expiry(t *testing.T) {
+	db := setupImpersonationTestDB(t)
+	userRepo := user.NewMySQLUserRepository(db)
+	sessionMgr := NewMemorySessionManager()
+	authSvc := NewAuthService(userRepo, sessionMgr)
+	svc := NewImpersonationService(userRepo, sessionMgr, NewImpersonationLogRepository(db), 50*time.Millisecond)
+	ctx := context.Background()
+
+	admin := createImpersonationTestUser(t, userRepo, "admin", user.RoleAdmin)
+	customer := createImpersonationTestUser(t, userRepo, "customer", user.RoleCustomer)
+
+	session, err := svc.Impersonate(ctx, admin, customer.ID)
+	if err != nil {
+		t.Fatalf("Impersonate() error = %v", err)
+	}
+
+	if _, err := authSvc.ValidateSession(ctx, session.Token); err != nil {
+		t.Fatalf("ValidateSession() before expiry error = %v", err)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+
+	if _, err := authSvc.ValidateSession(ctx, session.Token); err == nil {
+		t.Error("ValidateSession() after expiry should fail")
+	}
+}
+
+// TestAuthService_ValidateSession_ImpersonationRole 测试代客会话始终以顾客身份生效
+func TestAuthService_ValidateSession_ImpersonationRole(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := user.NewMySQLUserRepository(db)
+	sessionMgr := NewMemorySessionManager()
+	authSvc := NewAuthService(userRepo, sessionMgr)
+	ctx := context.Background()
+
+	// 目标账号在会话期间被提升为管理员，代客会话仍不应获得管理权限
+	target := createImpersonationTestUser(t, userRepo, "promoted", user.RoleAdmin)
+	session, err := sessionMgr.CreateImpersonationSession(ctx, 99, target.ID, target.Username, time.Minute)
+	if err != nil {
+		t.Fatalf("CreateImpersonationSession() error = %v", err)
+	}
+
+	u, err := authSvc.ValidateSession(ctx, session.Token)
+	if err != nil {
+		t.Fatalf("ValidateSession() error = %v", err)
+	}
+	if u.Role != user.RoleCustomer {
+		t.Errorf("ValidateSession() role = %s, want customer", u.Role)
+	}
+
+	// ResolveSession 同时返回发起代客登录的管理员
+	if _, impersonatorID, err := authSvc.ResolveSession(ctx, session.Token); err != nil || impersonatorID != 99 {
+		t.Errorf("ResolveSession() impersonator = %d, error = %v, want 99", impersonatorID, err)
+	}
+}
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+)
+
+// DefaultPasswordResetTTL 密码重置令牌的默认有效期
+const DefaultPasswordResetTTL = 30 * time.Minute
+
+// ErrInvalidResetToken 重置令牌不存在、已使用或已过期，三种情况返回相同错误
+var ErrInvalidResetToken = apperror.New(apperror.KindValidation, "重置令牌无效或已过期")
+
+// PasswordReset 密码重置令牌记录，只保存令牌的 SHA-256 哈希
+// 过期与使用时间统一以 UTC 存储
+type PasswordReset struct {
+	ID        int
+	UserID    int
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// PasswordResetRepository 密码重置令牌数据访问接口
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset *PasswordReset) error
+	Consume(ctx context.Context, tokenHash string, now time.Time) (int, error)
+}
+
+// passwordResetRepository 实现 PasswordResetRepository 接口
+type passwordResetRepository struct {
+	db *sql.DB
+}
+
+// NewPasswordResetRepository 创建 PasswordResetRepository 实例
+func NewPasswordResetRepository(db *sql.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+// Create 写入重置令牌记录
+func (r *passwordResetRepository) Create(ctx context.Context, reset *PasswordReset) error {
+	reset.CreatedAt = time.Now().UTC()
+
+	query := `
+		INSERT INTO password_resets (user_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, reset.UserID, reset.TokenHash, reset.ExpiresAt.UTC(), reset.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create password reset: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("get last insert id: %w", err)
+	}
+
+	reset.ID = int(id)
+	return nil
+}
+
+// Consume 在事务中核销令牌并返回对应的用户 ID
+// 令牌不存在、已使用或已过期时返回 ErrInvalidResetToken；核销成功后该用户其余未使用的令牌一并作废
+func (r *passwordResetRepository) Consume(ctx context.Context, tokenHash string, now time.Time) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id, userID int
+	var expiresAt time.Time
+	var usedAt sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		"SELECT id, user_id, expires_at, used_at FROM password_resets WHERE token_hash = ?", tokenHash,
+	).Scan(&id, &userID, &expiresAt, &usedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrInvalidResetToken
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get password reset: %w", err)
+	}
+	if usedAt.Valid || !now.Before(expiresAt) {
+		return 0, ErrInvalidResetToken
+	}
+
+	// 条件更新，并发核销同一令牌时只有一个请求成功
+	result, err := tx.ExecContext(ctx,
+		"UPDATE password_resets SET used_at = ? WHERE id = ? AND used_at IS NULL", now.UTC(), id)
+	if err != nil {
+		return 0, fmt.Errorf("consume password reset: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return 0, ErrInvalidResetToken
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE password_resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL", now.UTC(), userID,
+	); err != nil {
+		return 0, fmt.Errorf("invalidate password resets: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return userID, nil
+}
+
+// PasswordResetService 忘记密码服务接口
+type PasswordResetService interface {
+	RequestReset(ctx context.Context, username, email string) (string, error)
+	ConfirmReset(ctx context.Context, token, newPassword string) error
+}
+
+// passwordResetService 实现 PasswordResetService 接口
+type passwordResetService struct {
+	userRepo  user.UserRepository
+	authSvc   AuthService
+	resetRepo PasswordResetRepository
+	ttl       time.Duration
+}
+
+// NewPasswordResetService 创建 PasswordResetService 实例
+// 新密码通过 authSvc 哈希，与登录使用相同的 pepper 与密码强度策略；ttl <= 0 时使用 DefaultPasswordResetTTL
+func NewPasswordResetService(userRepo user.UserRepository, authSvc AuthService, resetRepo PasswordResetRepository, ttl time.Duration) PasswordResetService {
+	if ttl <= 0 {
+		ttl = DefaultPasswordResetTTL
+	}
+	return &passwordResetService{
+		userRepo:  userRepo,
+		authSvc:   authSvc,
+		resetRepo: resetRepo,
+		ttl:       ttl,
+	}
+}
+
+// RequestReset 为用户名或邮箱对应的用户生成一次性重置令牌，两者都提供时按邮箱查找
+// 用户不存在时返回空令牌且不报错，避免通过该接口探测账号是否存在
+func (s *passwordResetService) RequestReset(ctx context.Context, username, email string) (string, error) {
+	username = user.NormalizeUsername(username)
+	email = user.NormalizeEmail(email)
+	if username == "" && email == "" {
+		return "", apperror.Validation("用户名或邮箱不能为空")
+	}
+
+	var u *user.User
+	var err error
+	if email != "" {
+		u, err = s.userRepo.GetByEmail(ctx, email)
+	} else {
+		u, err = s.userRepo.GetByUsername(ctx, username)
+	}
+	if apperror.KindOf(err) == apperror.KindNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("查询用户失败: %w", err)
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	reset := &PasswordReset{
+		UserID:    u.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.ttl).UTC(),
+	}
+	if err := s.resetRepo.Create(ctx, reset); err != nil {
+		return "", fmt.Errorf("保存重置令牌失败: %w", err)
+	}
+
+	return token, nil
+}
+
+// ConfirmReset 核销重置令牌并设置新密码
+// 新密码先按策略校验，不满足时令牌保持可用；令牌核销后不能再次使用
+func (s *passwordResetService) ConfirmReset(ctx context.Context, token, newPassword string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+
+	passwordHash, err := s.authSvc.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	userID, err := s.resetRepo.Consume(ctx, hashToken(token), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, userID, passwordHash); err != nil {
+		return fmt.Errorf("更新密码失败: %w", err)
+	}
+	return nil
+}
+
+// hashToken 计算令牌的 SHA-256 哈希，重置令牌与会话令牌在数据库中只保存哈希
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
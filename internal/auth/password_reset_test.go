@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+)
+
+// setupPasswordResetTest 创建忘记密码测试所需的数据库、服务与一个已注册的用户
+func setupPasswordResetTest(t *testing.T) (*sql.DB, PasswordResetService, AuthService, PasswordResetRepository) {
+	t.Helper()
+
+	db := setupTestDB(t)
+	// 内存数据库每个连接独立，限制为单连接
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS password_resets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			expires_at DATETIME NOT NULL,
+			used_at DATETIME,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+		t.Fatalf("failed to create password_resets table: %v", err)
+	}
+
+	userRepo := user.NewMySQLUserRepository(db)
+	authSvc := NewAuthService(userRepo, NewMemorySessionManager())
+	resetRepo := NewPasswordResetRepository(db)
+	resetSvc := NewPasswordResetService(userRepo, authSvc, resetRepo, time.Hour)
+
+	if _, err := authSvc.RegisterWithEmail(context.Background(), "forgetful", "password123", "forgetful@example.com"); err != nil {
+		t.Fatalf("RegisterWithEmail() error = %v", err)
+	}
+	return db, resetSvc, authSvc, resetRepo
+}
+
+// TestPasswordReset_Valid 测试通过用户名或邮箱申请令牌并重置密码
+func TestPasswordReset_Valid(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		email    string
+	}{
+		{name: "按用户名申请", username: "forgetful"},
+		{name: "按邮箱申请（大小写不敏感）", email: "Forgetful@Example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resetSvc, authSvc, _ := setupPasswordResetTest(t)
+			ctx := context.Background()
+
+			token, err := resetSvc.RequestReset(ctx, tt.username, tt.email)
+			if err != nil || token == "" {
+				t.Fatalf("RequestReset() = %q, %v, want token", token, err)
+			}
+
+			// 新密码不满足策略时令牌保持可用
+			if err := resetSvc.ConfirmReset(ctx, token, "123"); !errors.Is(err, user.ErrInvalidPassword) {
+				t.Fatalf("ConfirmReset() with weak password error = %v, want ErrInvalidPassword", err)
+			}
+			if err := resetSvc.ConfirmReset(ctx, token, "newpassword456"); err != nil {
+				t.Fatalf("ConfirmReset() error = %v", err)
+			}
+
+			if _, err := authSvc.Login(ctx, "forgetful", "newpassword456"); err != nil {
+				t.Errorf("Login() with new password error = %v", err)
+			}
+			if _, err := authSvc.Login(ctx, "forgetful", "password123"); err == nil {
+				t.Error("Login() with old password should fail after reset")
+			}
+		})
+	}
+}
+
+// TestPasswordReset_InvalidTokens 测试过期、重复使用、被作废与伪造的令牌均被拒绝
+func TestPasswordReset_InvalidTokens(t *testing.T) {
+	_, resetSvc, authSvc, resetRepo := setupPasswordResetTest(t)
+	ctx := context.Background()
+
+	expiredToken := "expired-token"
+	if err := resetRepo.Create(ctx, &PasswordReset{
+		UserID:    1,
+		TokenHash: hashToken(expiredToken),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	first, err := resetSvc.RequestReset(ctx, "forgetful", "")
+	if err != nil {
+		t.Fatalf("RequestReset() error = %v", err)
+	}
+	second, err := resetSvc.RequestReset(ctx, "forgetful", "")
+	if err != nil {
+		t.Fatalf("RequestReset() error = %v", err)
+	}
+	if err := resetSvc.ConfirmReset(ctx, first, "newpassword456"); err != nil {
+		t.Fatalf("ConfirmReset() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "已过期", token: expiredToken},
+		{name: "重复使用", token: first},
+		{name: "同一用户的其他令牌已作废", token: second},
+		{name: "伪造令牌", token: "not-a-real-token"},
+		{name: "空令牌", token: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := resetSvc.ConfirmReset(ctx, tt.token, "anotherpass789"); !errors.Is(err, ErrInvalidResetToken) {
+				t.Errorf("ConfirmReset() error = %v, want ErrInvalidResetToken", err)
+			}
+		})
+	}
+
+	if _, err := authSvc.Login(ctx, "forgetful", "newpassword456"); err != nil {
+		t.Errorf("password should stay as first reset: %v", err)
+	}
+}
+
+// TestPasswordReset_UnknownAccount 测试不存在的账号不生成令牌也不报错，缺少参数返回校验错误
+func TestPasswordReset_UnknownAccount(t *testing.T) {
+	db, resetSvc, _, _ := setupPasswordResetTest(t)
+	ctx := context.Background()
+
+	token, err := resetSvc.RequestReset(ctx, "nobody", "")
+	if err != nil || token != "" {
+		t.Errorf("RequestReset(unknown) = %q, %v, want empty token and nil error", token, err)
+	}
+	if _, err := resetSvc.RequestReset(ctx, " ", ""); err == nil {
+		t.Error("RequestReset() without username or email should fail")
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM password_resets").Scan(&count); err != nil {
+		t.Fatalf("count password_resets error = %v", err)
+	}
+	if count != 0 {
+		t.Errorf("password_resets count = %d, want 0", count)
+	}
+}
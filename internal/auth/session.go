@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"crypto/rand"
+	"database/sql"
 	"encoding/hex"
 	"fmt"
 	"sync"
@@ -11,6 +12,15 @@ import (
 	"github.com/biqiangwu/flowerSalesSystem/internal/user"
 )
 
+// DefaultSessionExpiry 普通登录会话的默认有效期
+const DefaultSessionExpiry = 24 * time.Hour
+
+// Session 存储方式，对应配置 SESSION_STORE
+const (
+	SessionStoreMemory = "memory" // 进程内存，重启后所有会话失效
+	SessionStoreDB     = "db"     // 数据库 sessions 表，重启后会话保持
+)
+
 // Session 用户会话
 type Session struct {
 	Token     string
@@ -18,27 +28,81 @@ type Session struct {
 	Username  string
 	Role      user.Role
 	ExpiresAt time.Time
+	// ImpersonatorID 非 0 表示这是管理员代客登录的会话，值为发起模拟的管理员 ID
+	ImpersonatorID int
+}
+
+// IsImpersonation 是否为代客登录会话
+func (s *Session) IsImpersonation() bool {
+	return s.ImpersonatorID != 0
+}
+
+// impersonatorKey 请求上下文中代客登录管理员 ID 的键
+type impersonatorKey struct{}
+
+// WithImpersonator 在上下文中记录发起代客登录的管理员 ID，adminID 为 0 时原样返回
+func WithImpersonator(ctx context.Context, adminID int) context.Context {
+	if adminID == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, impersonatorKey{}, adminID)
+}
+
+// ImpersonatorFromContext 返回当前请求代客登录的管理员 ID，非代客登录会话返回 false
+func ImpersonatorFromContext(ctx context.Context) (int, bool) {
+	adminID, ok := ctx.Value(impersonatorKey{}).(int)
+	return adminID, ok && adminID != 0
 }
 
 // SessionManager Session 管理接口
 type SessionManager interface {
 	CreateSession(ctx context.Context, userID int, username string, role user.Role) (*Session, error)
+	CreateImpersonationSession(ctx context.Context, impersonatorID, userID int, username string, ttl time.Duration) (*Session, error)
 	ValidateSession(ctx context.Context, token string) (*Session, error)
 	DeleteSession(ctx context.Context, token string) error
 	CleanupExpiredSessions(ctx context.Context) error
 }
 
+// NewSessionManager 按存储方式创建 Session 管理器，store 为空时使用内存存储
+// expiry 为普通登录会话的有效期，<= 0 时使用 DefaultSessionExpiry
+func NewSessionManager(store string, db *sql.DB, expiry time.Duration) (SessionManager, error) {
+	switch store {
+	case "", SessionStoreMemory:
+		return NewMemorySessionManagerWithExpiry(expiry), nil
+	case SessionStoreDB:
+		return NewDBSessionManagerWithExpiry(db, expiry), nil
+	default:
+		return nil, fmt.Errorf("invalid session store %q: must be memory or db", store)
+	}
+}
+
 // MemorySessionManager 内存 Session 管理实现
 type MemorySessionManager struct {
 	mu       sync.RWMutex
 	sessions map[string]*Session
+	expiry   time.Duration
 }
 
-// NewMemorySessionManager 创建内存 Session 管理器
+// NewMemorySessionManager 创建内存 Session 管理器，会话有效期为 DefaultSessionExpiry
 func NewMemorySessionManager() *MemorySessionManager {
+	return NewMemorySessionManagerWithExpiry(DefaultSessionExpiry)
+}
+
+// NewMemorySessionManagerWithExpiry 创建指定会话有效期的内存 Session 管理器
+// expiry <= 0 时使用 DefaultSessionExpiry
+func NewMemorySessionManagerWithExpiry(expiry time.Duration) *MemorySessionManager {
 	return &MemorySessionManager{
 		sessions: make(map[string]*Session),
+		expiry:   sessionExpiry(expiry),
+	}
+}
+
+// sessionExpiry 规范化会话有效期，<= 0 时使用默认值
+func sessionExpiry(expiry time.Duration) time.Duration {
+	if expiry <= 0 {
+		return DefaultSessionExpiry
 	}
+	return expiry
 }
 
 // CreateSession 创建新 Session
@@ -53,7 +117,35 @@ func (m *MemorySessionManager) CreateSession(ctx context.Context, userID int, us
 		UserID:    userID,
 		Username:  username,
 		Role:      role,
-		ExpiresAt: time.Now().Add(24 * time.Hour), // 默认 24 小时过期
+		ExpiresAt: time.Now().Add(m.expiry),
+	}
+
+	m.mu.Lock()
+	m.sessions[token] = session
+	m.mu.Unlock()
+
+	return session, nil
+}
+
+// CreateImpersonationSession 创建代客登录 Session
+// 会话角色固定为 customer，有效期为 ttl
+func (m *MemorySessionManager) CreateImpersonationSession(ctx context.Context, impersonatorID, userID int, username string, ttl time.Duration) (*Session, error) {
+	if impersonatorID == 0 {
+		return nil, fmt.Errorf("impersonator id cannot be empty")
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	session := &Session{
+		Token:          token,
+		UserID:         userID,
+		Username:       username,
+		Role:           user.RoleCustomer,
+		ExpiresAt:      time.Now().Add(ttl),
+		ImpersonatorID: impersonatorID,
 	}
 
 	m.mu.Lock()
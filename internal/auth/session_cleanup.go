@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// DefaultSessionCleanupInterval 过期会话的默认清理间隔
+const DefaultSessionCleanupInterval = time.Hour
+
+// RunSessionCleanup 每隔 interval 清理一次过期会话，直到 ctx 取消
+// 数据库存储的会话只在验证时判断过期，不定期清理时 sessions 表会无限增长
+// interval <= 0 时使用 DefaultSessionCleanupInterval
+func RunSessionCleanup(ctx context.Context, mgr SessionManager, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSessionCleanupInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := mgr.CleanupExpiredSessions(ctx); err != nil {
+				slog.Warn("failed to cleanup expired sessions", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestRunSessionCleanup_NonPositiveInterval 测试清理间隔非正数时使用默认间隔而不是 panic
+func TestRunSessionCleanup_NonPositiveInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Hour} {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			RunSessionCleanup(ctx, NewMemorySessionManager(), interval)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("RunSessionCleanup(interval=%v) did not return after ctx cancelled", interval)
+		}
+	}
+}
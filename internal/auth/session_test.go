@@ -234,3 +234,54 @@ func TestMemorySessionManager_ConcurrentAccess(t *testing.T) {
 		sessions[session.Token] = true
 	}
 }
+
+// TestSessionManager_ConfiguredExpiry 测试会话按配置的有效期过期
+func TestSessionManager_ConfiguredExpiry(t *testing.T) {
+	tests := []struct {
+		name string
+		mgr  func(t *testing.T) SessionManager
+	}{
+		{name: "内存", mgr: func(t *testing.T) SessionManager { return NewMemorySessionManagerWithExpiry(time.Second) }},
+		{name: "数据库", mgr: func(t *testing.T) SessionManager {
+			return NewDBSessionManagerWithExpiry(setupSessionTestDB(t), time.Second)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mgr := tt.mgr(t)
+			ctx := context.Background()
+
+			session, err := mgr.CreateSession(ctx, 1, "testuser", user.RoleCustomer)
+			if err != nil {
+				t.Fatalf("CreateSession() error = %v", err)
+			}
+			if remaining := time.Until(session.ExpiresAt); remaining > time.Second {
+				t.Errorf("ExpiresAt in %v, want <= 1s", remaining)
+			}
+			if _, err := mgr.ValidateSession(ctx, session.Token); err != nil {
+				t.Fatalf("ValidateSession() before expiry error = %v", err)
+			}
+
+			time.Sleep(1100 * time.Millisecond)
+
+			if _, err := mgr.ValidateSession(ctx, session.Token); err == nil {
+				t.Error("ValidateSession() should fail after configured expiry")
+			}
+		})
+	}
+}
+
+// TestNewMemorySessionManagerWithExpiry_Default 测试未配置有效期时使用默认值
+func TestNewMemorySessionManagerWithExpiry_Default(t *testing.T) {
+	for _, expiry := range []time.Duration{0, -time.Hour} {
+		session, err := NewMemorySessionManagerWithExpiry(expiry).CreateSession(context.Background(), 1, "testuser", user.RoleCustomer)
+		if err != nil {
+			t.Fatalf("CreateSession() error = %v", err)
+		}
+		if remaining := time.Until(session.ExpiresAt); remaining < DefaultSessionExpiry-time.Minute {
+			t.Errorf("expiry %v: ExpiresAt in %v, want about %v", expiry, remaining, DefaultSessionExpiry)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package cart
+
+import (
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+)
+
+// 购物车错误
+var (
+	ErrItemNotFound = apperror.New(apperror.KindNotFound, "购物车中没有该鲜花")
+	ErrCartEmpty    = apperror.New(apperror.KindValidation, "购物车为空")
+)
+
+// CartItem 购物车项，每个用户每种鲜花一条记录
+// 购物车只记录数量，价格与库存在查看和结算时按鲜花当前信息计算
+type CartItem struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	FlowerSKU string    `json:"flower_sku"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CartResponse 购物车响应
+type CartResponse struct {
+	Items            []*CartItemResponse `json:"items"`
+	TotalAmount      flower.Decimal      `json:"total_amount"`       // 可下单项按当前实际售价合计
+	TotalAmountCents int64               `json:"total_amount_cents"` // 以分为单位
+}
+
+// CartItemResponse 购物车项响应
+type CartItemResponse struct {
+	FlowerSKU     string         `json:"flower_sku"`
+	FlowerName    string         `json:"flower_name"`
+	Quantity      int            `json:"quantity"`
+	UnitPrice     flower.Decimal `json:"unit_price"` // 当前实际售价
+	Subtotal      flower.Decimal `json:"subtotal"`
+	SubtotalCents int64          `json:"subtotal_cents"`
+	Available     bool           `json:"available"` // 鲜花已下架或删除时为 false，不计入合计
+}
@@ -0,0 +1,128 @@
+package cart
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CartRepository 定义购物车数据访问接口
+type CartRepository interface {
+	// AddQuantity 增加鲜花数量，购物车中没有该鲜花时新建
+	AddQuantity(ctx context.Context, userID int, sku string, quantity int) error
+	// SetQuantity 设置购物车中已有鲜花的数量，不存在时返回 ErrItemNotFound
+	SetQuantity(ctx context.Context, userID int, sku string, quantity int) error
+	Delete(ctx context.Context, userID int, sku string) error
+	ListByUserID(ctx context.Context, userID int) ([]*CartItem, error)
+	Clear(ctx context.Context, userID int) error
+}
+
+// cartRepository 实现 CartRepository 接口
+type cartRepository struct {
+	db *sql.DB
+}
+
+// NewCartRepository 创建 CartRepository 实例
+func NewCartRepository(db *sql.DB) CartRepository {
+	return &cartRepository{db: db}
+}
+
+// AddQuantity 增加鲜花数量
+// 先尝试累加已有记录，没有记录时插入；(user_id, flower_sku) 唯一约束保证每种鲜花只有一条
+func (r *cartRepository) AddQuantity(ctx context.Context, userID int, sku string, quantity int) error {
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE cart_items SET quantity = quantity + ?, updated_at = ? WHERE user_id = ? AND flower_sku = ?",
+		quantity, now, userID, sku)
+	if err != nil {
+		return fmt.Errorf("update cart item: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows > 0 {
+		return nil
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		"INSERT INTO cart_items (user_id, flower_sku, quantity, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		userID, sku, quantity, now, now)
+	if err != nil {
+		return fmt.Errorf("insert cart item: %w", err)
+	}
+	return nil
+}
+
+// SetQuantity 设置鲜花数量
+func (r *cartRepository) SetQuantity(ctx context.Context, userID int, sku string, quantity int) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE cart_items SET quantity = ?, updated_at = ? WHERE user_id = ? AND flower_sku = ?",
+		quantity, time.Now(), userID, sku)
+	if err != nil {
+		return fmt.Errorf("update cart item: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("%w: %s", ErrItemNotFound, sku)
+	}
+	return nil
+}
+
+// Delete 从购物车移除鲜花
+func (r *cartRepository) Delete(ctx context.Context, userID int, sku string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM cart_items WHERE user_id = ? AND flower_sku = ?", userID, sku)
+	if err != nil {
+		return fmt.Errorf("delete cart item: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("%w: %s", ErrItemNotFound, sku)
+	}
+	return nil
+}
+
+// ListByUserID 获取用户的购物车项，按加入顺序排列
+func (r *cartRepository) ListByUserID(ctx context.Context, userID int) ([]*CartItem, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, flower_sku, quantity, created_at, updated_at
+		FROM cart_items WHERE user_id = ? ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list cart items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*CartItem{}
+	for rows.Next() {
+		var item CartItem
+		if err := rows.Scan(&item.ID, &item.UserID, &item.FlowerSKU, &item.Quantity, &item.CreatedAt, &item.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan cart item: %w", err)
+		}
+		items = append(items, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate cart items: %w", err)
+	}
+
+	return items, nil
+}
+
+// Clear 清空用户的购物车
+func (r *cartRepository) Clear(ctx context.Context, userID int) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM cart_items WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("clear cart: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package cart
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite 驱动
+)
+
+// setupCartTestDB 创建测试数据库，包含鲜花表与购物车表
+func setupCartTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	// 内存数据库每个连接独立，限制为单连接
+	db.SetMaxOpenConns(1)
+
+	createTablesSQL := `
+	CREATE TABLE IF NOT EXISTS flowers (
+		sku TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		origin TEXT NOT NULL,
+		shelf_life TEXT NOT NULL,
+		preservation TEXT NOT NULL,
+		purchase_price INTEGER NOT NULL,
+		sale_price INTEGER NOT NULL,
+		stock INTEGER NOT NULL DEFAULT 0,
+		max_order_qty INTEGER,
+		discount_price INTEGER,
+		discount_until DATETIME,
+		is_active INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS cart_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		flower_sku TEXT NOT NULL,
+		quantity INTEGER NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, flower_sku)
+	);
+	`
+
+	if _, err := db.Exec(createTablesSQL); err != nil {
+		db.Close()
+		t.Fatalf("failed to create tables: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	return db
+}
+
+// TestCartRepository 测试购物车项的累加、设置数量、移除与清空
+func TestCartRepository(t *testing.T) {
+	db := setupCartTestDB(t)
+	repo := NewCartRepository(db)
+	ctx := context.Background()
+
+	for _, add := range []struct {
+		userID   int
+		sku      string
+		quantity int
+	}{
+		{1, "FLW001", 2},
+		{1, "FLW002", 1},
+		{1, "FLW001", 3},
+		{2, "FLW001", 5},
+	} {
+		if err := repo.AddQuantity(ctx, add.userID, add.sku, add.quantity); err != nil {
+			t.Fatalf("AddQuantity(%d, %s) error = %v", add.userID, add.sku, err)
+		}
+	}
+
+	items, err := repo.ListByUserID(ctx, 1)
+	if err != nil {
+		t.Fatalf("ListByUserID() error = %v", err)
+	}
+	if len(items) != 2 || items[0].FlowerSKU != "FLW001" || items[0].Quantity != 5 || items[1].Quantity != 1 {
+		t.Fatalf("items = %+v, want FLW001 x5 and FLW002 x1", items)
+	}
+
+	if err := repo.SetQuantity(ctx, 1, "FLW002", 4); err != nil {
+		t.Errorf("SetQuantity() error = %v", err)
+	}
+	if err := repo.SetQuantity(ctx, 1, "FLW003", 1); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("SetQuantity() missing item error = %v, want ErrItemNotFound", err)
+	}
+	if err := repo.Delete(ctx, 1, "FLW001"); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if err := repo.Delete(ctx, 1, "FLW001"); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("Delete() twice error = %v, want ErrItemNotFound", err)
+	}
+
+	items, _ = repo.ListByUserID(ctx, 1)
+	if len(items) != 1 || items[0].FlowerSKU != "FLW002" || items[0].Quantity != 4 {
+		t.Errorf("items after edits = %+v, want FLW002 x4", items)
+	}
+
+	if err := repo.Clear(ctx, 1); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if items, _ := repo.ListByUserID(ctx, 1); len(items) != 0 {
+		t.Errorf("items after Clear() = %+v, want empty", items)
+	}
+	if items, _ := repo.ListByUserID(ctx, 2); len(items) != 1 {
+		t.Errorf("other user's items = %+v, want untouched", items)
+	}
+}
@@ -0,0 +1,173 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+	"github.com/biqiangwu/flowerSalesSystem/internal/order"
+)
+
+// CartService 定义购物车业务逻辑接口
+// 加入购物车时只校验鲜花存在且在售，不校验也不占用库存；库存、限购与价格在结算下单时校验
+type CartService interface {
+	AddItem(ctx context.Context, userID int, sku string, quantity int) (*CartResponse, error)
+	UpdateQuantity(ctx context.Context, userID int, sku string, quantity int) (*CartResponse, error)
+	RemoveItem(ctx context.Context, userID int, sku string) (*CartResponse, error)
+	GetCart(ctx context.Context, userID int) (*CartResponse, error)
+	Checkout(ctx context.Context, userID int, req *CheckoutRequest) (string, error)
+}
+
+// CheckoutRequest 结算请求
+type CheckoutRequest struct {
+	AddressID    int
+	DiscountCode string // 可选
+}
+
+// cartService 实现 CartService 接口
+type cartService struct {
+	repo         CartRepository
+	flowerRepo   flower.FlowerRepository
+	orderService order.OrderService
+}
+
+// NewCartService 创建 CartService 实例，结算时通过 orderService 下单
+func NewCartService(repo CartRepository, flowerRepo flower.FlowerRepository, orderService order.OrderService) CartService {
+	return &cartService{
+		repo:         repo,
+		flowerRepo:   flowerRepo,
+		orderService: orderService,
+	}
+}
+
+// AddItem 将鲜花加入购物车，已在购物车中时累加数量
+func (s *cartService) AddItem(ctx context.Context, userID int, sku string, quantity int) (*CartResponse, error) {
+	if sku == "" {
+		return nil, apperror.Validation("鲜花SKU不能为空")
+	}
+	if quantity <= 0 {
+		return nil, apperror.Validation("数量必须大于0")
+	}
+
+	flw, err := s.flowerRepo.GetBySKU(ctx, sku)
+	if errors.Is(err, flower.ErrFlowerNotFound) {
+		return nil, apperror.Validation("鲜花不存在: %s", sku)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取鲜花信息失败: %w", err)
+	}
+	if !flw.IsActive {
+		return nil, apperror.Validation("鲜花 %s 已下架", flw.Name)
+	}
+
+	if err := s.repo.AddQuantity(ctx, userID, sku, quantity); err != nil {
+		return nil, err
+	}
+	return s.GetCart(ctx, userID)
+}
+
+// UpdateQuantity 设置购物车中鲜花的数量，数量为 0 时移除
+func (s *cartService) UpdateQuantity(ctx context.Context, userID int, sku string, quantity int) (*CartResponse, error) {
+	if quantity < 0 {
+		return nil, apperror.Validation("数量不能为负数")
+	}
+	if quantity == 0 {
+		return s.RemoveItem(ctx, userID, sku)
+	}
+
+	if err := s.repo.SetQuantity(ctx, userID, sku, quantity); err != nil {
+		return nil, err
+	}
+	return s.GetCart(ctx, userID)
+}
+
+// RemoveItem 从购物车移除鲜花
+func (s *cartService) RemoveItem(ctx context.Context, userID int, sku string) (*CartResponse, error) {
+	if err := s.repo.Delete(ctx, userID, sku); err != nil {
+		return nil, err
+	}
+	return s.GetCart(ctx, userID)
+}
+
+// GetCart 获取购物车，按鲜花当前实际售价计算小计与合计
+func (s *cartService) GetCart(ctx context.Context, userID int) (*CartResponse, error) {
+	items, err := s.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 一次查询获取所有鲜花，已删除的鲜花不在结果中，标记为不可购买
+	skus := make([]string, len(items))
+	for i, item := range items {
+		skus[i] = item.FlowerSKU
+	}
+	flowers, err := s.flowerRepo.GetBySKUs(ctx, skus)
+	if err != nil {
+		return nil, fmt.Errorf("获取鲜花信息失败: %w", err)
+	}
+
+	resp := &CartResponse{Items: make([]*CartItemResponse, 0, len(items))}
+	var total flower.Decimal
+	for _, item := range items {
+		itemResp := &CartItemResponse{FlowerSKU: item.FlowerSKU, Quantity: item.Quantity}
+
+		flw, ok := flowers[item.FlowerSKU]
+		if ok {
+			itemResp.FlowerName = flw.Name
+			itemResp.UnitPrice = flw.EffectivePrice()
+			itemResp.Available = flw.IsActive
+		}
+
+		if itemResp.Available {
+			subtotal, err := itemResp.UnitPrice.CheckedMul(int64(item.Quantity))
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s 小计超出范围", order.ErrAmountOverflow, flw.Name)
+			}
+			if total, err = total.CheckedAdd(subtotal); err != nil {
+				return nil, fmt.Errorf("%w: 购物车总额超出范围", order.ErrAmountOverflow)
+			}
+			itemResp.Subtotal = subtotal
+			itemResp.SubtotalCents = subtotal.Cents()
+		}
+		resp.Items = append(resp.Items, itemResp)
+	}
+
+	resp.TotalAmount = total
+	resp.TotalAmountCents = total.Cents()
+	return resp, nil
+}
+
+// Checkout 结算购物车：通过 OrderService.CreateOrder 下单（校验库存、限购并扣减库存），成功后清空购物车
+// 下单失败时购物车保持不变，返回订单号
+func (s *cartService) Checkout(ctx context.Context, userID int, req *CheckoutRequest) (string, error) {
+	items, err := s.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "", ErrCartEmpty
+	}
+
+	orderItems := make([]*order.CreateOrderItemRequest, len(items))
+	for i, item := range items {
+		orderItems[i] = &order.CreateOrderItemRequest{FlowerSKU: item.FlowerSKU, Quantity: item.Quantity}
+	}
+
+	orderNo, err := s.orderService.CreateOrder(ctx, userID, &order.CreateOrderRequest{
+		AddressID:    req.AddressID,
+		Items:        orderItems,
+		DiscountCode: req.DiscountCode,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// 订单已创建，清空失败不影响下单结果
+	if err := s.repo.Clear(ctx, userID); err != nil {
+		slog.Warn("failed to clear cart after checkout", "user_id", userID, "order_no", orderNo, "error", err)
+	}
+	return orderNo, nil
+}
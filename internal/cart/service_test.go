@@ -0,0 +1,163 @@
+package cart
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+	"github.com/biqiangwu/flowerSalesSystem/internal/order"
+)
+
+// fakeOrderService 记录结算时的下单请求，可配置返回错误
+type fakeOrderService struct {
+	order.OrderService
+	requests []*order.CreateOrderRequest
+	err      error
+}
+
+func (s *fakeOrderService) CreateOrder(ctx context.Context, userID int, req *order.CreateOrderRequest) (string, error) {
+	s.requests = append(s.requests, req)
+	if s.err != nil {
+		return "", s.err
+	}
+	return "ORD001", nil
+}
+
+// insertCartTestFlower 插入测试鲜花
+func insertCartTestFlower(t *testing.T, db *sql.DB, sku string, salePrice int64, isActive bool) {
+	t.Helper()
+	_, err := db.Exec(`INSERT INTO flowers (sku, name, origin, shelf_life, preservation, purchase_price, sale_price, stock, is_active)
+		VALUES (?, ?, '云南', '7天', '冷藏', 1000, ?, 1, ?)`, sku, "鲜花"+sku, salePrice, isActive)
+	if err != nil {
+		t.Fatalf("failed to insert test flower: %v", err)
+	}
+}
+
+// TestCartService_AddItemAndGetCart 测试加入购物车只校验鲜花在售，合计按实际售价计算且跳过已下架鲜花
+func TestCartService_AddItemAndGetCart(t *testing.T) {
+	db := setupCartTestDB(t)
+	ctx := context.Background()
+	insertCartTestFlower(t, db, "FLW001", 1000, true)
+	insertCartTestFlower(t, db, "FLW002", 2000, true)
+	insertCartTestFlower(t, db, "FLW003", 3000, false)
+	if _, err := db.Exec("UPDATE flowers SET discount_price = 1500, discount_until = ? WHERE sku = 'FLW002'",
+		time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to set discount: %v", err)
+	}
+
+	service := NewCartService(NewCartRepository(db), flower.NewFlowerRepository(db), &fakeOrderService{})
+
+	tests := []struct {
+		name     string
+		sku      string
+		quantity int
+		wantErr  bool // 期望校验错误
+	}{
+		{name: "库存不足也可加入", sku: "FLW001", quantity: 5},
+		{name: "促销鲜花", sku: "FLW002", quantity: 2},
+		{name: "鲜花不存在", sku: "NOPE", quantity: 1, wantErr: true},
+		{name: "鲜花已下架", sku: "FLW003", quantity: 1, wantErr: true},
+		{name: "数量无效", sku: "FLW001", quantity: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.AddItem(ctx, 1, tt.sku, tt.quantity)
+			if tt.wantErr && apperror.KindOf(err) != apperror.KindValidation {
+				t.Fatalf("AddItem() error = %v, want validation error", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("AddItem() error = %v", err)
+			}
+		})
+	}
+
+	// 加入后下架的鲜花保留在购物车中但不计入合计
+	if _, err := service.AddItem(ctx, 1, "FLW001", 1); err != nil {
+		t.Fatalf("AddItem() error = %v", err)
+	}
+	if _, err := db.Exec("UPDATE flowers SET is_active = 0 WHERE sku = 'FLW001'"); err != nil {
+		t.Fatalf("failed to deactivate flower: %v", err)
+	}
+
+	cart, err := service.GetCart(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetCart() error = %v", err)
+	}
+	if len(cart.Items) != 2 || cart.Items[0].Quantity != 6 || cart.Items[0].Available {
+		t.Fatalf("cart items = %+v, want unavailable FLW001 x6 first", cart.Items)
+	}
+	if cart.Items[1].UnitPrice.Value != 1500 || cart.TotalAmountCents != 3000 {
+		t.Errorf("FLW002 unit price = %d, total = %d, want 1500 and 3000", cart.Items[1].UnitPrice.Value, cart.TotalAmountCents)
+	}
+}
+
+// TestCartService_UpdateAndRemove 测试修改数量（0 表示移除）与移除不存在的鲜花
+func TestCartService_UpdateAndRemove(t *testing.T) {
+	db := setupCartTestDB(t)
+	ctx := context.Background()
+	insertCartTestFlower(t, db, "FLW001", 1000, true)
+	insertCartTestFlower(t, db, "FLW002", 2000, true)
+
+	service := NewCartService(NewCartRepository(db), flower.NewFlowerRepository(db), &fakeOrderService{})
+	service.AddItem(ctx, 1, "FLW001", 1)
+	service.AddItem(ctx, 1, "FLW002", 1)
+
+	cart, err := service.UpdateQuantity(ctx, 1, "FLW001", 3)
+	if err != nil || cart.TotalAmountCents != 5000 {
+		t.Fatalf("UpdateQuantity() = (%+v, %v), want total 5000", cart, err)
+	}
+	if _, err := service.UpdateQuantity(ctx, 1, "FLW001", -1); apperror.KindOf(err) != apperror.KindValidation {
+		t.Errorf("UpdateQuantity(-1) error = %v, want validation error", err)
+	}
+	if cart, err = service.UpdateQuantity(ctx, 1, "FLW002", 0); err != nil || len(cart.Items) != 1 {
+		t.Errorf("UpdateQuantity(0) = (%+v, %v), want FLW002 removed", cart, err)
+	}
+	if _, err := service.RemoveItem(ctx, 1, "FLW002"); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("RemoveItem() missing item error = %v, want ErrItemNotFound", err)
+	}
+}
+
+// TestCartService_Checkout 测试结算将购物车转为下单请求，成功后清空，失败时保留购物车
+func TestCartService_Checkout(t *testing.T) {
+	db := setupCartTestDB(t)
+	ctx := context.Background()
+	insertCartTestFlower(t, db, "FLW001", 1000, true)
+	insertCartTestFlower(t, db, "FLW002", 2000, true)
+
+	orders := &fakeOrderService{}
+	service := NewCartService(NewCartRepository(db), flower.NewFlowerRepository(db), orders)
+
+	if _, err := service.Checkout(ctx, 1, &CheckoutRequest{AddressID: 1}); !errors.Is(err, ErrCartEmpty) {
+		t.Fatalf("Checkout() empty cart error = %v, want ErrCartEmpty", err)
+	}
+
+	service.AddItem(ctx, 1, "FLW001", 2)
+	service.AddItem(ctx, 1, "FLW002", 1)
+
+	orders.err = apperror.Validation("库存不足")
+	if _, err := service.Checkout(ctx, 1, &CheckoutRequest{AddressID: 1}); apperror.KindOf(err) != apperror.KindValidation {
+		t.Fatalf("Checkout() error = %v, want validation error", err)
+	}
+	if cart, _ := service.GetCart(ctx, 1); len(cart.Items) != 2 {
+		t.Fatalf("cart after failed checkout = %+v, want kept", cart.Items)
+	}
+
+	orders.err = nil
+	orderNo, err := service.Checkout(ctx, 1, &CheckoutRequest{AddressID: 7, DiscountCode: "SPRING"})
+	if err != nil || orderNo != "ORD001" {
+		t.Fatalf("Checkout() = (%q, %v), want ORD001", orderNo, err)
+	}
+
+	req := orders.requests[len(orders.requests)-1]
+	if req.AddressID != 7 || req.DiscountCode != "SPRING" || len(req.Items) != 2 ||
+		req.Items[0].FlowerSKU != "FLW001" || req.Items[0].Quantity != 2 || req.Items[1].FlowerSKU != "FLW002" {
+		t.Errorf("CreateOrder request = %+v, want address 7, SPRING, FLW001 x2 and FLW002 x1", req)
+	}
+	if cart, _ := service.GetCart(ctx, 1); len(cart.Items) != 0 {
+		t.Errorf("cart after checkout = %+v, want empty", cart.Items)
+	}
+}
@@ -3,44 +3,173 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config 应用程序配置
 // 所有配置项通过环境变量注入，具有合理的默认值
 type Config struct {
 	// 数据库配置
-	DBHost     string
-	DBPort     int
-	DBName     string
-	DBUser     string
-	DBPassword string
+	// 数据库驱动：mysql（默认）或 sqlite3；sqlite3 使用 DBPath 指定的单个文件，忽略主机、端口与账号
+	DBDriver   string `json:"db_driver"`
+	DBPath     string `json:"db_path"`
+	DBHost     string `json:"db_host"`
+	DBPort     int    `json:"db_port"`
+	DBName     string `json:"db_name"`
+	DBUser     string `json:"db_user"`
+	DBPassword string `json:"db_password"`
+	// 连接池：最大打开连接数、最大空闲连接数、连接最大生命周期（秒），<= 0 时使用 database 包的默认值
+	DBMaxOpenConns    int `json:"db_max_open_conns"`
+	DBMaxIdleConns    int `json:"db_max_idle_conns"`
+	DBConnMaxLifetime int `json:"db_conn_max_lifetime"`
+	// 启动时连接数据库的最大尝试次数与首次重试间隔（秒），间隔每次加倍
+	DBConnectAttempts int `json:"db_connect_attempts"`
+	DBConnectBackoff  int `json:"db_connect_backoff"`
 
 	// Session 配置
-	SessionSecret string
-	SessionExpiry int // hours
+	SessionSecret string `json:"session_secret"`
+	SessionExpiry int    `json:"session_expiry"` // hours
+	// Session 存储方式：memory（默认，重启后失效）或 db（持久化到 sessions 表）
+	SessionStore string `json:"session_store"`
+	// Session Cookie 属性：SameSite 可选 lax（默认）或 strict；Path 默认 "/"；Domain 为空时仅对当前主机有效
+	SessionCookieSameSite string `json:"session_cookie_samesite"`
+	SessionCookiePath     string `json:"session_cookie_path"`
+	SessionCookieDomain   string `json:"session_cookie_domain"`
+
+	// 密码 pepper（可选），与密码一起参与 bcrypt 哈希
+	// 一旦启用不可随意更换：更换后所有已有密码都无法验证
+	PasswordPepper string `json:"password_pepper"`
 
 	// 服务器配置
-	ServerPort int
-	LogLevel   string
+	ServerPort int    `json:"server_port"`
+	LogLevel   string `json:"log_level"`
+	// 是否以 HTTPS 提供服务，启用时必须同时配置证书与私钥文件，Session Cookie 带 Secure 标记
+	TLSEnabled  bool   `json:"tls_enabled"`
+	TLSCertFile string `json:"tls_cert_file"`
+	TLSKeyFile  string `json:"tls_key_file"`
+	// 请求体大小上限（字节），超出返回 413，<= 0 表示不限制；批量导入接口使用更高的固定上限
+	MaxBodyBytes int `json:"max_body_bytes"`
+	// 单个请求的处理超时（秒），超时后数据库查询被取消并返回 504，<= 0 表示不限制
+	RequestTimeout int `json:"request_timeout"`
+	// 带内容哈希的静态资源（如 app.3f9a1c2e.js）的缓存时长（秒），<= 0 表示不缓存；index.html 与其他静态文件每次协商缓存
+	StaticMaxAge int `json:"static_max_age"`
+	// 响应压缩的最小长度（字节），客户端支持 gzip 且响应体不小于该长度时压缩，<= 0 表示压缩所有非空响应
+	GzipMinSize int `json:"gzip_min_size"`
+	// API 版本前缀，路由注册在该前缀下，旧版 /api 前缀作为别名保留一个版本
+	APIPrefix string `json:"api_prefix"`
 
 	// 业务配置
-	StockWarningThreshold int
+	StockWarningThreshold int `json:"stock_warning_threshold"`
+	// 报表接口最大并发数，超出返回 429，<= 0 表示不限制
+	ReportMaxConcurrency int `json:"report_max_concurrency"`
+	// 完成订单时的库存核对策略：off（默认）、warn（仅告警）、strict（拒绝完成）
+	OrderStockCheck string `json:"order_stock_check"`
+	// 完成订单时核对订单项鲜花是否仍存在且在售：off（默认）、warn、strict
+	OrderCatalogCheck string `json:"order_catalog_check"`
+	// 订单发货、完成与取消时的通知方式：none（默认）或 log
+	OrderNotifier string `json:"order_notifier"`
+	// 单个订单项的最大购买数量，<= 0 表示不限制
+	MaxItemQuantity int `json:"max_item_quantity"`
+	// 单个订单的最大订单项数，<= 0 表示不限制
+	MaxOrderItems int `json:"max_order_items"`
+	// 登录与注册接口每个客户端 IP 在窗口内允许的请求数，<= 0 表示不限制
+	LoginRateLimit int `json:"login_rate_limit"`
+	// 登录限流窗口（秒）
+	LoginRateWindow int `json:"login_rate_window"`
+	// 受信任的反向代理 IP 或 CIDR，逗号分隔；仅来自这些地址的请求才读取 X-Forwarded-For / X-Real-IP
+	TrustedProxies []string `json:"trusted_proxies"`
+	// 下单 Idempotency-Key 的有效期（秒），窗口内重复提交返回首次创建的订单，<= 0 表示不启用
+	IdempotencyWindow int `json:"idempotency_window"`
+	// 待处理订单的库存预留时长（秒），超时未处理的订单自动取消并回退库存，<= 0 表示不启用
+	OrderReservationTTL int `json:"order_reservation_ttl"`
+	// 密码强度策略：最小字符数、是否必须包含数字与字母
+	PasswordMinLen        int  `json:"password_min_len"`
+	PasswordRequireDigit  bool `json:"password_require_digit"`
+	PasswordRequireLetter bool `json:"password_require_letter"`
+	// 密码重置令牌有效期（秒），<= 0 时使用默认值
+	PasswordResetTTL int `json:"password_reset_ttl"`
+	// 是否在申请重置密码的响应中直接返回令牌，仅用于尚未接入邮件发送的开发环境
+	PasswordResetExposeToken bool `json:"password_reset_expose_token"`
+	// 地址联系方式是否只接受中国大陆手机号，关闭时接受 5-15 位的国际号码
+	AddressStrictPhone bool `json:"address_strict_phone"`
+
+	// 允许跨域携带 Cookie 调用 API 的前端来源，逗号分隔；为空表示不启用 CORS，"*" 表示允许任意来源匿名调用（不携带 Cookie）
+	CORSAllowedOrigins []string `json:"cors_allowed_origins"`
+}
+
+// RedactedValue 脱敏后敏感配置项的占位值
+const RedactedValue = "[REDACTED]"
+
+// Redacted 返回敏感字段已脱敏的配置副本，用于诊断输出
+// 已设置的密钥替换为 RedactedValue，未设置的保持为空，便于确认是否配置
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.DBPassword = redact(c.DBPassword)
+	redacted.SessionSecret = redact(c.SessionSecret)
+	redacted.PasswordPepper = redact(c.PasswordPepper)
+	return &redacted
+}
+
+// redact 对非空的敏感值进行脱敏
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return RedactedValue
 }
 
 // Load 从环境变量加载配置
 // 如果环境变量未设置，使用默认值
 func Load() *Config {
 	return &Config{
-		DBHost:               getEnv("DB_HOST", "mysql-service"),
-		DBPort:               getEnvInt("DB_PORT", 3306),
-		DBName:               getEnv("DB_NAME", "flower_sales"),
-		DBUser:               getEnv("DB_USER", "flower_user"),
-		DBPassword:           getEnv("DB_PASSWORD", ""),
-		SessionSecret:        getEnv("SESSION_SECRET", ""),
-		SessionExpiry:        getEnvInt("SESSION_EXPIRY", 24),
-		ServerPort:           getEnvInt("SERVER_PORT", 8080),
-		LogLevel:             getEnv("LOG_LEVEL", "info"),
-		StockWarningThreshold: getEnvInt("STOCK_WARNING_THRESHOLD", 10),
+		DBDriver:                 getEnv("DB_DRIVER", "mysql"),
+		DBPath:                   getEnv("DB_PATH", "flower_sales.db"),
+		DBHost:                   getEnv("DB_HOST", "mysql-service"),
+		DBPort:                   getEnvInt("DB_PORT", 3306),
+		DBName:                   getEnv("DB_NAME", "flower_sales"),
+		DBUser:                   getEnv("DB_USER", "flower_user"),
+		DBPassword:               getEnv("DB_PASSWORD", ""),
+		DBMaxOpenConns:           getEnvInt("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:           getEnvInt("DB_MAX_IDLE_CONNS", 10),
+		DBConnMaxLifetime:        getEnvInt("DB_CONN_MAX_LIFETIME", 300),
+		DBConnectAttempts:        getEnvInt("DB_CONNECT_ATTEMPTS", 5),
+		DBConnectBackoff:         getEnvInt("DB_CONNECT_BACKOFF", 1),
+		SessionSecret:            getEnv("SESSION_SECRET", ""),
+		SessionExpiry:            getEnvInt("SESSION_EXPIRY", 24),
+		SessionStore:             getEnv("SESSION_STORE", "memory"),
+		SessionCookieSameSite:    getEnv("SESSION_COOKIE_SAMESITE", "lax"),
+		SessionCookiePath:        getEnv("SESSION_COOKIE_PATH", "/"),
+		SessionCookieDomain:      getEnv("SESSION_COOKIE_DOMAIN", ""),
+		PasswordPepper:           getEnv("PASSWORD_PEPPER", ""),
+		ServerPort:               getEnvInt("SERVER_PORT", 8080),
+		LogLevel:                 getEnv("LOG_LEVEL", "info"),
+		TLSEnabled:               getEnvBool("TLS_ENABLED", false),
+		TLSCertFile:              getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:               getEnv("TLS_KEY_FILE", ""),
+		MaxBodyBytes:             getEnvInt("MAX_BODY_BYTES", 1<<20),
+		RequestTimeout:           getEnvInt("REQUEST_TIMEOUT", 30),
+		StaticMaxAge:             getEnvInt("STATIC_MAX_AGE", 31536000),
+		GzipMinSize:              getEnvInt("GZIP_MIN_SIZE", 1024),
+		APIPrefix:                getEnv("API_PREFIX", "/api/v1"),
+		StockWarningThreshold:    getEnvInt("STOCK_WARNING_THRESHOLD", 10),
+		ReportMaxConcurrency:     getEnvInt("REPORT_MAX_CONCURRENCY", 2),
+		OrderStockCheck:          getEnv("ORDER_STOCK_CHECK", "off"),
+		OrderCatalogCheck:        getEnv("ORDER_CATALOG_CHECK", "off"),
+		OrderNotifier:            getEnv("ORDER_NOTIFIER", "none"),
+		MaxItemQuantity:          getEnvInt("MAX_ITEM_QUANTITY", 999),
+		MaxOrderItems:            getEnvInt("MAX_ORDER_ITEMS", 50),
+		LoginRateLimit:           getEnvInt("LOGIN_RATE_LIMIT", 10),
+		LoginRateWindow:          getEnvInt("LOGIN_RATE_WINDOW", 60),
+		TrustedProxies:           getEnvList("TRUSTED_PROXIES"),
+		IdempotencyWindow:        getEnvInt("IDEMPOTENCY_WINDOW", 3600),
+		OrderReservationTTL:      getEnvInt("ORDER_RESERVATION_TTL", 0),
+		AddressStrictPhone:       getEnvBool("ADDRESS_STRICT_PHONE", false),
+		PasswordMinLen:           getEnvInt("PASSWORD_MIN_LEN", 6),
+		PasswordRequireDigit:     getEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireLetter:    getEnvBool("PASSWORD_REQUIRE_LETTER", false),
+		PasswordResetTTL:         getEnvInt("PASSWORD_RESET_TTL", 1800),
+		PasswordResetExposeToken: getEnvBool("PASSWORD_RESET_EXPOSE_TOKEN", false),
+		CORSAllowedOrigins:       getEnvList("CORS_ALLOWED_ORIGINS"),
 	}
 }
 
@@ -64,3 +193,23 @@ func getEnvInt(key string, defaultVal int) int {
 	}
 	return value
 }
+
+// getEnvBool 从环境变量获取布尔值，如果未设置或转换失败则返回默认值
+func getEnvBool(key string, defaultVal bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultVal
+	}
+	return value
+}
+
+// getEnvList 从环境变量获取逗号分隔的列表，忽略空白项，未设置时返回 nil
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
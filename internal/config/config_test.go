@@ -9,8 +9,17 @@ import (
 func TestConfigLoad_Defaults(t *testing.T) {
 	// 清除所有相关环境变量
 	envVars := []string{
-		"DB_HOST", "DB_PORT", "DB_NAME", "DB_USER", "DB_PASSWORD",
+		"DB_DRIVER", "DB_PATH", "DB_HOST", "DB_PORT", "DB_NAME", "DB_USER", "DB_PASSWORD",
 		"SESSION_SECRET", "SESSION_EXPIRY", "SERVER_PORT", "LOG_LEVEL", "STOCK_WARNING_THRESHOLD",
+		"PASSWORD_PEPPER", "REPORT_MAX_CONCURRENCY", "SESSION_STORE", "LOGIN_RATE_LIMIT", "LOGIN_RATE_WINDOW",
+		"TRUSTED_PROXIES",
+		"CORS_ALLOWED_ORIGINS", "IDEMPOTENCY_WINDOW", "ORDER_RESERVATION_TTL",
+		"ADDRESS_STRICT_PHONE", "PASSWORD_MIN_LEN", "PASSWORD_REQUIRE_DIGIT", "PASSWORD_REQUIRE_LETTER",
+		"DB_MAX_OPEN_CONNS", "DB_MAX_IDLE_CONNS", "DB_CONN_MAX_LIFETIME", "DB_CONNECT_ATTEMPTS", "DB_CONNECT_BACKOFF",
+		"PASSWORD_RESET_TTL", "PASSWORD_RESET_EXPOSE_TOKEN", "ORDER_NOTIFIER",
+		"TLS_ENABLED", "TLS_CERT_FILE", "TLS_KEY_FILE",
+		"SESSION_COOKIE_SAMESITE", "SESSION_COOKIE_PATH", "SESSION_COOKIE_DOMAIN", "MAX_BODY_BYTES",
+		"REQUEST_TIMEOUT", "STATIC_MAX_AGE", "GZIP_MIN_SIZE", "API_PREFIX",
 	}
 	for _, env := range envVars {
 		os.Unsetenv(env)
@@ -18,6 +27,9 @@ func TestConfigLoad_Defaults(t *testing.T) {
 
 	cfg := Load()
 
+	if cfg.DBDriver != "mysql" || cfg.DBPath != "flower_sales.db" {
+		t.Errorf("DB driver = (%s, %s), want (mysql, flower_sales.db)", cfg.DBDriver, cfg.DBPath)
+	}
 	if cfg.DBHost != "mysql-service" {
 		t.Errorf("DBHost = %s, want %s", cfg.DBHost, "mysql-service")
 	}
@@ -30,6 +42,12 @@ func TestConfigLoad_Defaults(t *testing.T) {
 	if cfg.DBUser != "flower_user" {
 		t.Errorf("DBUser = %s, want %s", cfg.DBUser, "flower_user")
 	}
+	if cfg.DBMaxOpenConns != 25 || cfg.DBMaxIdleConns != 10 || cfg.DBConnMaxLifetime != 300 {
+		t.Errorf("DB pool = (%d, %d, %d), want (25, 10, 300)", cfg.DBMaxOpenConns, cfg.DBMaxIdleConns, cfg.DBConnMaxLifetime)
+	}
+	if cfg.DBConnectAttempts != 5 || cfg.DBConnectBackoff != 1 {
+		t.Errorf("DB connect retry = (%d, %d), want (5, 1)", cfg.DBConnectAttempts, cfg.DBConnectBackoff)
+	}
 	if cfg.SessionExpiry != 24 {
 		t.Errorf("SessionExpiry = %d, want %d", cfg.SessionExpiry, 24)
 	}
@@ -42,6 +60,79 @@ func TestConfigLoad_Defaults(t *testing.T) {
 	if cfg.StockWarningThreshold != 10 {
 		t.Errorf("StockWarningThreshold = %d, want %d", cfg.StockWarningThreshold, 10)
 	}
+	if cfg.ReportMaxConcurrency != 2 {
+		t.Errorf("ReportMaxConcurrency = %d, want %d", cfg.ReportMaxConcurrency, 2)
+	}
+	if cfg.OrderCatalogCheck != "off" {
+		t.Errorf("OrderCatalogCheck = %s, want %s", cfg.OrderCatalogCheck, "off")
+	}
+	if cfg.OrderNotifier != "none" {
+		t.Errorf("OrderNotifier = %s, want %s", cfg.OrderNotifier, "none")
+	}
+	if cfg.SessionStore != "memory" {
+		t.Errorf("SessionStore = %s, want %s", cfg.SessionStore, "memory")
+	}
+	if cfg.LoginRateLimit != 10 {
+		t.Errorf("LoginRateLimit = %d, want %d", cfg.LoginRateLimit, 10)
+	}
+	if cfg.LoginRateWindow != 60 {
+		t.Errorf("LoginRateWindow = %d, want %d", cfg.LoginRateWindow, 60)
+	}
+	if cfg.IdempotencyWindow != 3600 {
+		t.Errorf("IdempotencyWindow = %d, want %d", cfg.IdempotencyWindow, 3600)
+	}
+	if cfg.OrderReservationTTL != 0 {
+		t.Errorf("OrderReservationTTL = %d, want %d", cfg.OrderReservationTTL, 0)
+	}
+	if cfg.AddressStrictPhone {
+		t.Error("AddressStrictPhone = true, want false")
+	}
+	if cfg.PasswordMinLen != 6 || cfg.PasswordRequireDigit || cfg.PasswordRequireLetter {
+		t.Errorf("password policy = (%d, %v, %v), want (6, false, false)", cfg.PasswordMinLen, cfg.PasswordRequireDigit, cfg.PasswordRequireLetter)
+	}
+	if cfg.PasswordPepper != "" {
+		t.Errorf("PasswordPepper = %q, want empty", cfg.PasswordPepper)
+	}
+	if cfg.PasswordResetTTL != 1800 || cfg.PasswordResetExposeToken {
+		t.Errorf("password reset = (%d, %v), want (1800, false)", cfg.PasswordResetTTL, cfg.PasswordResetExposeToken)
+	}
+	if cfg.CORSAllowedOrigins != nil {
+		t.Errorf("CORSAllowedOrigins = %v, want nil", cfg.CORSAllowedOrigins)
+	}
+	if cfg.TLSEnabled || cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		t.Errorf("TLS = (%v, %q, %q), want disabled", cfg.TLSEnabled, cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	if cfg.SessionCookieSameSite != "lax" || cfg.SessionCookiePath != "/" || cfg.SessionCookieDomain != "" {
+		t.Errorf("session cookie = (%q, %q, %q), want (lax, /, empty)", cfg.SessionCookieSameSite, cfg.SessionCookiePath, cfg.SessionCookieDomain)
+	}
+	if cfg.MaxBodyBytes != 1<<20 {
+		t.Errorf("MaxBodyBytes = %d, want %d", cfg.MaxBodyBytes, 1<<20)
+	}
+	if cfg.RequestTimeout != 30 {
+		t.Errorf("RequestTimeout = %d, want %d", cfg.RequestTimeout, 30)
+	}
+	if cfg.StaticMaxAge != 31536000 {
+		t.Errorf("StaticMaxAge = %d, want %d", cfg.StaticMaxAge, 31536000)
+	}
+	if cfg.GzipMinSize != 1024 {
+		t.Errorf("GzipMinSize = %d, want %d", cfg.GzipMinSize, 1024)
+	}
+	if cfg.APIPrefix != "/api/v1" {
+		t.Errorf("APIPrefix = %q, want %q", cfg.APIPrefix, "/api/v1")
+	}
+	if cfg.MaxItemQuantity != 999 || cfg.MaxOrderItems != 50 {
+		t.Errorf("order limits = (%d, %d), want (999, 50)", cfg.MaxItemQuantity, cfg.MaxOrderItems)
+	}
+}
+
+// TestConfigLoad_WithPasswordPepper 测试设置 PASSWORD_PEPPER 环境变量
+func TestConfigLoad_WithPasswordPepper(t *testing.T) {
+	t.Setenv("PASSWORD_PEPPER", "server-side-pepper")
+
+	cfg := Load()
+	if cfg.PasswordPepper != "server-side-pepper" {
+		t.Errorf("PasswordPepper = %q, want %q", cfg.PasswordPepper, "server-side-pepper")
+	}
 }
 
 // TestConfigLoad_WithDBHost 测试设置 DB_HOST 环境变量
@@ -78,13 +169,16 @@ func TestConfigLoad_AllEnvVars(t *testing.T) {
 	t.Setenv("SERVER_PORT", "9000")
 	t.Setenv("LOG_LEVEL", "debug")
 	t.Setenv("STOCK_WARNING_THRESHOLD", "20")
+	t.Setenv("DB_MAX_OPEN_CONNS", "50")
+	t.Setenv("DB_MAX_IDLE_CONNS", "5")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "600")
 
 	cfg := Load()
 
 	tests := []struct {
-		name  string
-		got   any
-		want  any
+		name string
+		got  any
+		want any
 	}{
 		{"DBHost", cfg.DBHost, "prod-db.example.com"},
 		{"DBPort", cfg.DBPort, 5432},
@@ -96,6 +190,9 @@ func TestConfigLoad_AllEnvVars(t *testing.T) {
 		{"ServerPort", cfg.ServerPort, 9000},
 		{"LogLevel", cfg.LogLevel, "debug"},
 		{"StockWarningThreshold", cfg.StockWarningThreshold, 20},
+		{"DBMaxOpenConns", cfg.DBMaxOpenConns, 50},
+		{"DBMaxIdleConns", cfg.DBMaxIdleConns, 5},
+		{"DBConnMaxLifetime", cfg.DBConnMaxLifetime, 600},
 	}
 
 	for _, tt := range tests {
@@ -130,6 +227,37 @@ func TestGetEnv_WithEnvVar(t *testing.T) {
 	}
 }
 
+// TestGetEnvList 测试逗号分隔列表的解析
+func TestGetEnvList(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{"未设置", "", nil},
+		{"单个值", "https://shop.example.com", []string{"https://shop.example.com"}},
+		{"多个值去除空白", " https://a.example.com , https://b.example.com ", []string{"https://a.example.com", "https://b.example.com"}},
+		{"忽略空项", "https://a.example.com,,", []string{"https://a.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_GETENV_LIST", tt.value)
+
+			got := getEnvList("TEST_GETENV_LIST")
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("getEnvList() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("getEnvList()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
 // TestGetEnvInt_ValidInt 测试环境变量为有效整数
 func TestGetEnvInt_ValidInt(t *testing.T) {
 	tests := []struct {
@@ -235,3 +363,32 @@ func TestConfigLoad_InvalidStockWarningThreshold(t *testing.T) {
 		t.Errorf("StockWarningThreshold = %d, want %d (default)", cfg.StockWarningThreshold, 10)
 	}
 }
+
+// TestConfigRedacted 测试敏感字段脱敏且不修改原配置
+func TestConfigRedacted(t *testing.T) {
+	cfg := &Config{
+		DBHost:         "mysql-service",
+		DBPassword:     "db-secret",
+		SessionSecret:  "session-secret",
+		PasswordPepper: "",
+		ServerPort:     8080,
+	}
+
+	redacted := cfg.Redacted()
+
+	if redacted.DBPassword != RedactedValue {
+		t.Errorf("DBPassword = %s, want %s", redacted.DBPassword, RedactedValue)
+	}
+	if redacted.SessionSecret != RedactedValue {
+		t.Errorf("SessionSecret = %s, want %s", redacted.SessionSecret, RedactedValue)
+	}
+	if redacted.PasswordPepper != "" {
+		t.Errorf("PasswordPepper = %s, want empty", redacted.PasswordPepper)
+	}
+	if redacted.DBHost != "mysql-service" || redacted.ServerPort != 8080 {
+		t.Errorf("non-secret fields changed: %+v", redacted)
+	}
+	if cfg.DBPassword != "db-secret" || cfg.SessionSecret != "session-secret" {
+		t.Errorf("original config was modified: %+v", cfg)
+	}
+}
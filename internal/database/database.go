@@ -3,10 +3,18 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/biqiangwu/flowerSalesSystem/internal/config"
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// 支持的数据库驱动（DB_DRIVER）
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite3"
 )
 
 const (
@@ -18,20 +26,30 @@ const (
 	DefaultConnMaxLifetime = 5 * time.Minute
 )
 
-// Open 打开数据库连接并配置连接池
-// 返回配置好的 *sql.DB 实例
+// Open 按 cfg.DBDriver 打开数据库连接并配置连接池
+// 返回配置好的 *sql.DB 实例；驱动为空时使用 MySQL
 func Open(cfg *config.Config) (*sql.DB, error) {
-	dsn := buildDSN(cfg)
+	driver := cfg.DBDriver
+	if driver == "" {
+		driver = DriverMySQL
+	}
+
+	var dsn string
+	switch driver {
+	case DriverMySQL:
+		dsn = buildDSN(cfg)
+	case DriverSQLite:
+		dsn = buildSQLiteDSN(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", cfg.DBDriver)
+	}
 
-	db, err := sql.Open("mysql", dsn)
+	db, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
 
-	// 配置连接池
-	db.SetMaxOpenConns(DefaultMaxOpenConns)
-	db.SetMaxIdleConns(DefaultMaxIdleConns)
-	db.SetConnMaxLifetime(DefaultConnMaxLifetime)
+	configurePool(db, cfg)
 
 	// 验证连接是否可用
 	if err := db.Ping(); err != nil {
@@ -42,6 +60,57 @@ func Open(cfg *config.Config) (*sql.DB, error) {
 	return db, nil
 }
 
+// OpenWithRetry 打开数据库连接，失败时按指数退避重试，最多尝试 attempts 次
+// 首次重试前等待 backoff，之后每次加倍；用于应用与 MySQL 同时启动、数据库尚未就绪的场景
+func OpenWithRetry(cfg *config.Config, attempts int, backoff time.Duration) (*sql.DB, error) {
+	return openWithRetry(cfg, attempts, backoff, Open)
+}
+
+// openWithRetry 是 OpenWithRetry 的实现，open 可在测试中替换
+func openWithRetry(cfg *config.Config, attempts int, backoff time.Duration, open func(*config.Config) (*sql.DB, error)) (*sql.DB, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var db *sql.DB
+		if db, err = open(cfg); err == nil {
+			return db, nil
+		}
+		if attempt == attempts {
+			break
+		}
+
+		slog.Warn("database connection failed, retrying", "attempt", attempt, "max_attempts", attempts,
+			"backoff", backoff.String(), "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	return nil, fmt.Errorf("connect database after %d attempts: %w", attempts, err)
+}
+
+// configurePool 按配置设置连接池参数，未设置（<= 0）的项使用默认值
+func configurePool(db *sql.DB, cfg *config.Config) {
+	maxOpen := DefaultMaxOpenConns
+	if cfg.DBMaxOpenConns > 0 {
+		maxOpen = cfg.DBMaxOpenConns
+	}
+	maxIdle := DefaultMaxIdleConns
+	if cfg.DBMaxIdleConns > 0 {
+		maxIdle = cfg.DBMaxIdleConns
+	}
+	lifetime := DefaultConnMaxLifetime
+	if cfg.DBConnMaxLifetime > 0 {
+		lifetime = time.Duration(cfg.DBConnMaxLifetime) * time.Second
+	}
+
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(lifetime)
+}
+
 // buildDSN 构建 MySQL Data Source Name (DSN)
 // 格式: user:password@tcp(host:port)/dbname?parseTime=true
 func buildDSN(cfg *config.Config) string {
@@ -53,3 +122,9 @@ func buildDSN(cfg *config.Config) string {
 		cfg.DBName,
 	)
 }
+
+// buildSQLiteDSN 构建 SQLite DSN，数据库文件路径为 cfg.DBPath
+// 启用外键约束与 WAL 日志，并设置忙等待超时以支持并发读写
+func buildSQLiteDSN(cfg *config.Config) string {
+	return fmt.Sprintf("file:%s?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000", cfg.DBPath)
+}
@@ -3,10 +3,14 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/biqiangwu/flowerSalesSystem/internal/config"
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
 )
 
 // MockConfig 用于测试的配置辅助函数
@@ -306,10 +310,154 @@ func TestClose_Connection(t *testing.T) {
 	}
 }
 
+// TestConfigurePool 测试连接池参数按配置设置，未设置时使用默认值
+func TestConfigurePool(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxOpen     int
+		wantMaxOpen int
+	}{
+		{"未设置使用默认值", 0, DefaultMaxOpenConns},
+		{"负数使用默认值", -1, DefaultMaxOpenConns},
+		{"使用配置值", 50, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := mockConfig("localhost", 3306, "user", "pass", "db")
+			cfg.DBMaxOpenConns = tt.maxOpen
+			cfg.DBMaxIdleConns = 5
+			cfg.DBConnMaxLifetime = 60
+
+			// sql.Open 不会建立连接，无需真实数据库
+			db, err := sql.Open("mysql", buildDSN(cfg))
+			if err != nil {
+				t.Fatalf("sql.Open() error = %v", err)
+			}
+			defer db.Close()
+
+			configurePool(db, cfg)
+
+			if got := db.Stats().MaxOpenConnections; got != tt.wantMaxOpen {
+				t.Errorf("MaxOpenConnections = %d, want %d", got, tt.wantMaxOpen)
+			}
+		})
+	}
+}
+
+// TestOpenWithRetry 测试连接失败时按配置的次数重试后返回错误
+func TestOpenWithRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		attempts  int
+		failTimes int
+		wantCalls int
+		wantErr   bool
+	}{
+		{"始终失败重试到上限", 3, 3, 3, true},
+		{"重试后成功", 3, 2, 3, false},
+		{"首次成功不重试", 3, 0, 1, false},
+		{"次数小于 1 时只尝试一次", 0, 1, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			open := func(*config.Config) (*sql.DB, error) {
+				calls++
+				if calls <= tt.failTimes {
+					return nil, errors.New("connection refused")
+				}
+				return &sql.DB{}, nil
+			}
+
+			_, err := openWithRetry(mockConfig("localhost", 3306, "user", "pass", "db"), tt.attempts, time.Millisecond, open)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("openWithRetry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("open called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// TestOpenWithRetry_InvalidConfig 测试无法连接的配置在重试后返回错误
+func TestOpenWithRetry_InvalidConfig(t *testing.T) {
+	cfg := mockConfig("localhost", 0, "user", "pass", "db")
+
+	db, err := OpenWithRetry(cfg, 2, time.Millisecond)
+	if err == nil {
+		db.Close()
+		t.Fatal("OpenWithRetry() expected error, got nil")
+	}
+}
+
+// TestOpen_SQLite 测试 sqlite3 驱动打开文件数据库、执行迁移并通过仓储读写
+func TestOpen_SQLite(t *testing.T) {
+	cfg := &config.Config{DBDriver: DriverSQLite, DBPath: filepath.Join(t.TempDir(), "flower_sales.db")}
+	ctx := context.Background()
+
+	db, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	if err := user.NewMySQLUserRepository(db).Create(ctx, &user.User{
+		Username: "alice", PasswordHash: "hash", Role: user.RoleCustomer,
+	}); err != nil {
+		t.Fatalf("create user error = %v", err)
+	}
+	if err := flower.NewFlowerRepository(db).Create(ctx, &flower.Flower{
+		SKU: "ROSE-001", Name: "红玫瑰", Origin: "云南", ShelfLife: "7天", Preservation: "冷藏",
+		PurchasePrice: flower.Decimal{Value: 500}, SalePrice: flower.Decimal{Value: 1500}, Stock: 10, IsActive: true,
+	}); err != nil {
+		t.Fatalf("create flower error = %v", err)
+	}
+	db.Close()
+
+	// 重新打开后数据仍在，再次迁移不会重复执行
+	db, err = Open(cfg)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer db.Close()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("second Migrate() error = %v", err)
+	}
+	if version, err := CurrentVersion(db); err != nil || version != len(migrations) {
+		t.Errorf("CurrentVersion() = (%d, %v), want (%d, nil)", version, err, len(migrations))
+	}
+
+	u, err := user.NewMySQLUserRepository(db).GetByUsername(ctx, "alice")
+	if err != nil || u.Role != user.RoleCustomer {
+		t.Errorf("GetByUsername() = (%+v, %v), want customer alice", u, err)
+	}
+	f, err := flower.NewFlowerRepository(db).GetBySKU(ctx, "ROSE-001")
+	if err != nil {
+		t.Fatalf("GetBySKU() error = %v", err)
+	}
+	if f.SalePrice.String() != "15.00" || !f.IsActive || f.Stock != 10 {
+		t.Errorf("GetBySKU() = %+v, want sale price 15.00, active, stock 10", f)
+	}
+}
+
+// TestOpen_UnsupportedDriver 测试不支持的驱动返回错误
+func TestOpen_UnsupportedDriver(t *testing.T) {
+	db, err := Open(&config.Config{DBDriver: "postgres"})
+	if err == nil {
+		db.Close()
+		t.Fatal("Open() expected error, got nil")
+	}
+}
+
 // MockDB 模拟数据库接口（用于单元测试）
 type MockDB struct {
 	*sql.DB
-	PingFunc func(context.Context) error
+	PingFunc  func(context.Context) error
 	CloseFunc func() error
 }
 
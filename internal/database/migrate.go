@@ -4,25 +4,444 @@ import (
 	"database/sql"
 	"embed"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 )
 
-//go:embed schema.sql
+//go:embed schema.sql schema_sqlite.sql
 var schemaFS embed.FS
 
-// Migrate 读取并执行数据库迁移脚本
-// 创建所有必需的数据库表
+// migration 一个迁移步骤，version 必须递增，已发布的步骤不可修改，只能追加新步骤
+// 每个步骤分别提供 MySQL 与 SQLite 的 SQL，按连接的驱动选择
+type migration struct {
+	version int
+	name    string
+	mysql   string
+	sqlite  string
+	// precheck 可选，在执行 SQL 前于同一事务中检查已有数据，返回错误时中止迁移
+	precheck func(tx *sql.Tx) error
+}
+
+// migrations 按顺序排列的迁移步骤
+// 版本 1 是引入版本化迁移前的 schema.sql，已有数据库上相当于空操作，之后的表结构变更都必须作为新步骤追加
+var migrations = []migration{
+	{version: 1, name: "initial schema", mysql: mustReadSchema("schema.sql"), sqlite: mustReadSchema("schema_sqlite.sql")},
+	{version: 2, name: "order log reason",
+		mysql:  "ALTER TABLE order_logs ADD COLUMN reason VARCHAR(255) NULL AFTER new_status",
+		sqlite: "ALTER TABLE order_logs ADD COLUMN reason TEXT"},
+	{version: 3, name: "user email",
+		mysql: `ALTER TABLE users ADD COLUMN email VARCHAR(254) NULL AFTER username;
+			CREATE UNIQUE INDEX idx_users_email ON users (email)`,
+		sqlite: `ALTER TABLE users ADD COLUMN email TEXT;
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email)`},
+	{version: 4, name: "password resets",
+		mysql: `CREATE TABLE IF NOT EXISTS password_resets (
+			id INT PRIMARY KEY AUTO_INCREMENT,
+			user_id INT NOT NULL,
+			token_hash CHAR(64) NOT NULL UNIQUE,
+			expires_at DATETIME NOT NULL,
+			used_at DATETIME NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			INDEX idx_password_resets_user_id (user_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+		sqlite: `CREATE TABLE IF NOT EXISTS password_resets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			expires_at DATETIME NOT NULL,
+			used_at DATETIME,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets (user_id)`},
+	{version: 5, name: "discount codes",
+		mysql: `CREATE TABLE IF NOT EXISTS discounts (
+			id INT PRIMARY KEY AUTO_INCREMENT,
+			code VARCHAR(32) NOT NULL UNIQUE,
+			type ENUM('percent', 'fixed') NOT NULL,
+			value BIGINT NOT NULL,
+			valid_from DATETIME NULL,
+			valid_to DATETIME NULL,
+			usage_limit INT NOT NULL DEFAULT 0,
+			used_count INT NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+		ALTER TABLE orders ADD COLUMN discount_id INT NULL AFTER total_amount;
+		ALTER TABLE orders ADD COLUMN discount_amount DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER discount_id`,
+		sqlite: `CREATE TABLE IF NOT EXISTS discounts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			code TEXT NOT NULL UNIQUE,
+			type TEXT NOT NULL CHECK (type IN ('percent', 'fixed')),
+			value INTEGER NOT NULL,
+			valid_from DATETIME,
+			valid_to DATETIME,
+			usage_limit INTEGER NOT NULL DEFAULT 0,
+			used_count INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		ALTER TABLE orders ADD COLUMN discount_id INTEGER;
+		ALTER TABLE orders ADD COLUMN discount_amount INTEGER NOT NULL DEFAULT 0`},
+	{version: 6, name: "flower discount price",
+		mysql: `ALTER TABLE flowers ADD COLUMN discount_price DECIMAL(10, 2) NULL AFTER sale_price;
+			ALTER TABLE flowers ADD COLUMN discount_until DATETIME NULL AFTER discount_price`,
+		sqlite: `ALTER TABLE flowers ADD COLUMN discount_price INTEGER;
+			ALTER TABLE flowers ADD COLUMN discount_until DATETIME`},
+	{version: 7, name: "cart items",
+		mysql: `CREATE TABLE IF NOT EXISTS cart_items (
+			id INT PRIMARY KEY AUTO_INCREMENT,
+			user_id INT NOT NULL,
+			flower_sku VARCHAR(50) NOT NULL,
+			quantity INT NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			UNIQUE KEY uk_cart_user_flower (user_id, flower_sku),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+		sqlite: `CREATE TABLE IF NOT EXISTS cart_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			flower_sku TEXT NOT NULL,
+			quantity INTEGER NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, flower_sku),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`},
+	{version: 8, name: "order version",
+		mysql:  "ALTER TABLE orders ADD COLUMN version INT NOT NULL DEFAULT 0 AFTER status",
+		sqlite: "ALTER TABLE orders ADD COLUMN version INTEGER NOT NULL DEFAULT 0"},
+	// 已有用户名改为规范形式（去除首尾空白、小写），与 user.NormalizeUsername 一致；
+	// 规范化后重名的用户无法自动处理，迁移失败并列出这些用户名，需人工改名后重新启动
+	{version: 9, name: "normalize usernames",
+		mysql:    "UPDATE users SET username = LOWER(TRIM(username)) WHERE BINARY username <> LOWER(TRIM(username))",
+		sqlite:   "UPDATE users SET username = LOWER(TRIM(username)) WHERE username <> LOWER(TRIM(username))",
+		precheck: checkUsernameCollisions},
+	// 版本 1 之前直接写在建表语句中的变更，已有数据库执行版本 1 时不会生效，需单独追加
+	{version: 10, name: "flower max order qty",
+		mysql:  "ALTER TABLE flowers ADD COLUMN max_order_qty INT NULL AFTER stock",
+		sqlite: "ALTER TABLE flowers ADD COLUMN max_order_qty INTEGER"},
+	// SQLite 的 CHECK 约束无法修改，SQLite 版本 1 的 status 约束已包含 draft 与 shipped
+	{version: 11, name: "order status draft and shipped",
+		mysql:  "ALTER TABLE orders MODIFY COLUMN status ENUM('draft', 'pending', 'shipped', 'completed', 'cancelled') NOT NULL DEFAULT 'pending'",
+		sqlite: "-- SQLite 版本 1 已包含全部订单状态"},
+	{version: 12, name: "order reservation",
+		mysql: `ALTER TABLE orders ADD COLUMN reserved_at DATETIME NULL AFTER status;
+			CREATE INDEX idx_status_reserved_at ON orders (status, reserved_at)`,
+		sqlite: `ALTER TABLE orders ADD COLUMN reserved_at DATETIME;
+			CREATE INDEX IF NOT EXISTS idx_orders_status_reserved_at ON orders (status, reserved_at)`},
+	{version: 13, name: "flower logs",
+		mysql: `CREATE TABLE IF NOT EXISTS flower_logs (
+			id INT PRIMARY KEY AUTO_INCREMENT,
+			sku VARCHAR(50) NOT NULL,
+			operator_id INT NOT NULL,
+			action VARCHAR(50) NOT NULL,
+			old_value TEXT NOT NULL,
+			new_value TEXT NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (operator_id) REFERENCES users(id),
+			INDEX idx_sku (sku),
+			INDEX idx_created_at (created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+		sqlite: `CREATE TABLE IF NOT EXISTS flower_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			sku TEXT NOT NULL,
+			operator_id INTEGER NOT NULL,
+			action TEXT NOT NULL,
+			old_value TEXT NOT NULL,
+			new_value TEXT NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (operator_id) REFERENCES users(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_flower_logs_sku ON flower_logs (sku);
+		CREATE INDEX IF NOT EXISTS idx_flower_logs_created_at ON flower_logs (created_at)`},
+	{version: 14, name: "impersonation logs",
+		mysql: `CREATE TABLE IF NOT EXISTS impersonation_logs (
+			id INT PRIMARY KEY AUTO_INCREMENT,
+			admin_id INT NOT NULL,
+			target_user_id INT NOT NULL,
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (admin_id) REFERENCES users(id),
+			FOREIGN KEY (target_user_id) REFERENCES users(id),
+			INDEX idx_admin_id (admin_id),
+			INDEX idx_target_user_id (target_user_id),
+			INDEX idx_created_at (created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+		sqlite: `CREATE TABLE IF NOT EXISTS impersonation_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			admin_id INTEGER NOT NULL,
+			target_user_id INTEGER NOT NULL,
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (admin_id) REFERENCES users(id),
+			FOREIGN KEY (target_user_id) REFERENCES users(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_impersonation_logs_admin_id ON impersonation_logs (admin_id);
+		CREATE INDEX IF NOT EXISTS idx_impersonation_logs_target_user_id ON impersonation_logs (target_user_id);
+		CREATE INDEX IF NOT EXISTS idx_impersonation_logs_created_at ON impersonation_logs (created_at)`},
+	{version: 15, name: "sessions",
+		mysql: `CREATE TABLE IF NOT EXISTS sessions (
+			token CHAR(64) PRIMARY KEY,
+			user_id INT NOT NULL,
+			username VARCHAR(50) NOT NULL,
+			role ENUM('customer', 'clerk', 'admin') NOT NULL,
+			impersonator_id INT NOT NULL DEFAULT 0,
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			INDEX idx_user_id (user_id),
+			INDEX idx_expires_at (expires_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+		sqlite: `CREATE TABLE IF NOT EXISTS sessions (
+			token TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			username TEXT NOT NULL,
+			role TEXT NOT NULL CHECK (role IN ('customer', 'clerk', 'admin')),
+			impersonator_id INTEGER NOT NULL DEFAULT 0,
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+		CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions (expires_at)`},
+	{version: 16, name: "order log impersonator",
+		mysql:  "ALTER TABLE order_logs ADD COLUMN impersonator_id INT NULL AFTER operator_id",
+		sqlite: "ALTER TABLE order_logs ADD COLUMN impersonator_id INTEGER"},
+	// 会话令牌改为只保存 SHA-256 哈希，此前以明文保存的会话无法再匹配，直接清除（用户需重新登录）
+	{version: 17, name: "hash session tokens",
+		mysql:  "DELETE FROM sessions",
+		sqlite: "DELETE FROM sessions"},
+	// 早期以草稿订单保存的购物车迁入 cart_items（与已有购物车项合并数量），随后删除草稿订单
+	{version: 18, name: "move draft orders to cart",
+		mysql: `INSERT INTO cart_items (user_id, flower_sku, quantity)
+			SELECT * FROM (
+				SELECT o.user_id, i.flower_sku, SUM(i.quantity) AS quantity
+				FROM orders o JOIN order_items i ON i.order_id = o.id
+				WHERE o.status = 'draft'
+				GROUP BY o.user_id, i.flower_sku
+			) AS draft
+			ON DUPLICATE KEY UPDATE quantity = cart_items.quantity + draft.quantity;
+			DELETE FROM order_logs WHERE order_id IN (SELECT id FROM orders WHERE status = 'draft');
+			DELETE FROM order_items WHERE order_id IN (SELECT id FROM orders WHERE status = 'draft');
+			DELETE FROM orders WHERE status = 'draft'`,
+		sqlite: `INSERT INTO cart_items (user_id, flower_sku, quantity)
+			SELECT o.user_id, i.flower_sku, SUM(i.quantity)
+			FROM orders o JOIN order_items i ON i.order_id = o.id
+			WHERE o.status = 'draft'
+			GROUP BY o.user_id, i.flower_sku
+			ON CONFLICT (user_id, flower_sku) DO UPDATE SET quantity = cart_items.quantity + excluded.quantity;
+			DELETE FROM order_logs WHERE order_id IN (SELECT id FROM orders WHERE status = 'draft');
+			DELETE FROM order_items WHERE order_id IN (SELECT id FROM orders WHERE status = 'draft');
+			DELETE FROM orders WHERE status = 'draft'`},
+	// 草稿订单已全部迁入购物车，不再产生 draft 状态；SQLite 的 CHECK 约束无法修改，保留 draft 但不再写入
+	{version: 19, name: "drop order status draft",
+		mysql:  "ALTER TABLE orders MODIFY COLUMN status ENUM('pending', 'shipped', 'completed', 'cancelled') NOT NULL DEFAULT 'pending'",
+		sqlite: "-- SQLite 的 CHECK 约束无法修改，draft 状态不再写入"},
+}
+
+// statements 返回步骤在指定驱动下的 SQL
+func (m migration) statements(driver string) string {
+	if driver == DriverSQLite {
+		return m.sqlite
+	}
+	return m.mysql
+}
+
+// createMigrationsTableSQL 记录已执行迁移的版本表
+const createMigrationsTableSQL = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at DATETIME NOT NULL
+	)
+`
+
+// mustReadSchema 读取嵌入的 SQL 文件，文件缺失属于编译期错误
+func mustReadSchema(name string) string {
+	content, err := schemaFS.ReadFile(name)
+	if err != nil {
+		panic(fmt.Sprintf("read %s: %v", name, err))
+	}
+	return string(content)
+}
+
+// Migrate 执行尚未执行的迁移步骤
+// 每个步骤在独立事务中执行并记录到 schema_migrations，重复调用不会重复执行
 func Migrate(db *sql.DB) error {
-	// 从嵌入的文件系统中读取 schema.sql
-	schemaContent, err := schemaFS.ReadFile("schema.sql")
+	return migrate(db, migrations)
+}
+
+// CurrentVersion 返回已执行的最新迁移版本，未执行过任何迁移时返回 0
+func CurrentVersion(db *sql.DB) (int, error) {
+	if _, err := db.Exec(createMigrationsTableSQL); err != nil {
+		return 0, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	var version int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("query schema version: %w", err)
+	}
+	return version, nil
+}
+
+// migrate 按顺序执行 steps 中尚未执行的步骤
+func migrate(db *sql.DB, steps []migration) error {
+	driver := detectDriver(db)
+
+	if _, err := db.Exec(createMigrationsTableSQL); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return err
+	}
+
+	for _, step := range steps {
+		if applied[step.version] {
+			continue
+		}
+		if err := applyMigration(db, step, driver); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appliedVersions 查询已执行的迁移版本
+func appliedVersions(db *sql.DB) (map[int]bool, error) {
+	rows, err := db.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("scan schema_migrations: %w", err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate schema_migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// applyMigration 在事务中执行一个迁移步骤并记录版本
+// 注意 MySQL 的 DDL 会隐式提交事务，步骤中的 DDL 应保持可重复执行（如 IF NOT EXISTS）
+func applyMigration(db *sql.DB, step migration, driver string) error {
+	script := step.statements(driver)
+	if strings.TrimSpace(script) == "" {
+		return fmt.Errorf("migration %d (%s) has no SQL for %s", step.version, step.name, driver)
+	}
+
+	tx, err := db.Begin()
 	if err != nil {
-		return fmt.Errorf("read schema.sql: %w", err)
+		return fmt.Errorf("begin migration %d: %w", step.version, err)
 	}
+	defer tx.Rollback()
 
-	// 执行 SQL 脚本
-	_, err = db.Exec(string(schemaContent))
+	if step.precheck != nil {
+		if err := step.precheck(tx); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", step.version, step.name, err)
+		}
+	}
+
+	for _, stmt := range splitStatements(script) {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("execute migration %d (%s): %w", step.version, step.name, err)
+		}
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+		step.version, step.name, time.Now(),
+	); err != nil {
+		return fmt.Errorf("record migration %d: %w", step.version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit migration %d: %w", step.version, err)
+	}
+	return nil
+}
+
+// detectDriver 根据驱动类型判断数据库类型，无法识别时按 MySQL 处理
+func detectDriver(db *sql.DB) string {
+	if strings.Contains(strings.ToLower(fmt.Sprintf("%T", db.Driver())), "sqlite") {
+		return DriverSQLite
+	}
+	return DriverMySQL
+}
+
+// splitStatements 按分号拆分 SQL 脚本并去掉 -- 注释行，MySQL 驱动默认不支持一次执行多条语句
+func splitStatements(script string) []string {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	var stmts []string
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
+
+// checkUsernameCollisions 检查规范化后重名的用户名，存在时返回列出全部冲突用户名的错误
+func checkUsernameCollisions(tx *sql.Tx) error {
+	rows, err := tx.Query(`
+		SELECT LOWER(TRIM(username)), username FROM users
+		WHERE LOWER(TRIM(username)) IN (
+			SELECT LOWER(TRIM(username)) FROM users
+			GROUP BY LOWER(TRIM(username)) HAVING COUNT(*) > 1
+		)
+		ORDER BY LOWER(TRIM(username)), id
+	`)
 	if err != nil {
-		return fmt.Errorf("execute migration: %w", err)
+		return fmt.Errorf("check username collisions: %w", err)
 	}
+	defer rows.Close()
 
+	var groups []string
+	var current string
+	var names []string
+	flush := func() {
+		if len(names) > 0 {
+			groups = append(groups, strings.Join(names, ", "))
+		}
+	}
+	for rows.Next() {
+		var normalized, username string
+		if err := rows.Scan(&normalized, &username); err != nil {
+			return fmt.Errorf("scan username collision: %w", err)
+		}
+		if normalized != current {
+			flush()
+			current, names = normalized, nil
+		}
+		names = append(names, strconv.Quote(username))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate username collisions: %w", err)
+	}
+	flush()
+
+	if len(groups) > 0 {
+		return fmt.Errorf("usernames collide after normalization, rename them and restart: %s", strings.Join(groups, "; "))
+	}
 	return nil
 }
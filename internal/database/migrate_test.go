@@ -0,0 +1,304 @@
+package database
+
+import (
+	"database/sql"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupMigrateTestDB 创建用于迁移测试的内存 SQLite 数据库
+func setupMigrateTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	// 内存数据库每个连接独立，限制为单连接
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestMigrate_AppliesEachStepOnce 测试重复执行迁移时每个步骤只执行一次，新增步骤在下次执行
+func TestMigrate_AppliesEachStepOnce(t *testing.T) {
+	db := setupMigrateTestDB(t)
+
+	steps := []migration{
+		{version: 1, name: "create widgets", sqlite: `
+			-- 第一个步骤
+			CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+			INSERT INTO widgets (name) VALUES ('a');
+		`},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := migrate(db, steps); err != nil {
+			t.Fatalf("migrate() run %d error = %v", i+1, err)
+		}
+	}
+	assertCount(t, db, "SELECT COUNT(*) FROM widgets", 1)
+	assertVersion(t, db, 1)
+
+	// 后续追加的步骤在下次迁移时执行
+	steps = append(steps, migration{version: 2, name: "add color", sqlite: `
+		ALTER TABLE widgets ADD COLUMN color TEXT;
+		INSERT INTO widgets (name, color) VALUES ('b', 'red');
+	`})
+	for i := 0; i < 2; i++ {
+		if err := migrate(db, steps); err != nil {
+			t.Fatalf("migrate() with new step run %d error = %v", i+1, err)
+		}
+	}
+	assertCount(t, db, "SELECT COUNT(*) FROM widgets", 2)
+	assertCount(t, db, "SELECT COUNT(*) FROM schema_migrations", 2)
+	assertVersion(t, db, 2)
+}
+
+// TestMigrate_FailedStepNotRecorded 测试执行失败的步骤回滚且不记录版本
+func TestMigrate_FailedStepNotRecorded(t *testing.T) {
+	db := setupMigrateTestDB(t)
+
+	steps := []migration{
+		{version: 1, name: "create widgets", sqlite: "CREATE TABLE widgets (id INTEGER PRIMARY KEY)"},
+		{version: 2, name: "broken", sqlite: "INSERT INTO widgets (id) VALUES (1); INSERT INTO missing_table VALUES (1)"},
+	}
+
+	if err := migrate(db, steps); err == nil {
+		t.Fatal("migrate() expected error, got nil")
+	}
+	assertVersion(t, db, 1)
+	assertCount(t, db, "SELECT COUNT(*) FROM widgets", 0)
+}
+
+// TestMigrate_OrderIndexes 测试完整迁移后订单查询依赖的索引存在
+func TestMigrate_OrderIndexes(t *testing.T) {
+	db := setupMigrateTestDB(t)
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	tests := []struct {
+		table string
+		index string
+	}{
+		{table: "orders", index: "idx_orders_user_id"},
+		{table: "orders", index: "idx_orders_status"},
+		{table: "order_items", index: "idx_order_items_order_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.index, func(t *testing.T) {
+			assertCount(t, db, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = '"+tt.table+"' AND name = '"+tt.index+"'", 1)
+		})
+	}
+}
+
+// TestMigrate_FromBaselineSchema 测试引入版本化迁移前建好的数据库（只执行过版本 1 的建表语句、没有 schema_migrations）
+// 能升级到最新版本，之后各版本新增的列与表都存在
+func TestMigrate_FromBaselineSchema(t *testing.T) {
+	db := setupMigrateTestDB(t)
+	for _, stmt := range splitStatements(migrations[0].sqlite) {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("baseline schema error = %v", err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO users (username, password_hash) VALUES ('alice', 'hash');
+		INSERT INTO addresses (user_id, address, contact) VALUES (1, '北京', '13800000000');
+		INSERT INTO orders (order_no, user_id, address_id, total_amount) VALUES ('ORD001', 1, 1, 1000)`); err != nil {
+		t.Fatalf("insert baseline data error = %v", err)
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	assertVersion(t, db, migrations[len(migrations)-1].version)
+	assertCount(t, db, "SELECT COUNT(*) FROM orders WHERE status = 'pending' AND version = 0 AND reserved_at IS NULL", 1)
+
+	queries := []string{
+		"SELECT email FROM users",
+		"SELECT max_order_qty, discount_price, discount_until FROM flowers",
+		"SELECT reserved_at, version, discount_id, discount_amount FROM orders",
+		"SELECT reason FROM order_logs",
+		"SELECT id FROM flower_logs",
+		"SELECT id FROM impersonation_logs",
+		"SELECT token FROM sessions",
+		"SELECT id FROM password_resets",
+		"SELECT id FROM discounts",
+		"SELECT id FROM cart_items",
+	}
+	for _, query := range queries {
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Errorf("%s error = %v", query, err)
+			continue
+		}
+		rows.Close()
+	}
+
+	// 新增的订单状态可以写入
+	if _, err := db.Exec("UPDATE orders SET status = 'shipped' WHERE order_no = 'ORD001'"); err != nil {
+		t.Errorf("update status to shipped error = %v", err)
+	}
+}
+
+// TestMigrations_MySQLAfterColumnsExist 测试 MySQL 步骤中 ADD COLUMN ... AFTER 引用的列在之前的步骤中已创建
+// 测试环境没有 MySQL，只能静态检查，避免升级时因列顺序引用不存在的列而失败
+func TestMigrations_MySQLAfterColumnsExist(t *testing.T) {
+	afterPattern := regexp.MustCompile(`(?i)\bAFTER\s+(\w+)`)
+	var earlier strings.Builder
+	for _, step := range migrations {
+		for _, stmt := range splitStatements(step.mysql) {
+			for _, m := range afterPattern.FindAllStringSubmatch(stmt, -1) {
+				column := m[1]
+				if !regexp.MustCompile(`(?m)^\s*` + column + `\s|ADD COLUMN ` + column + `\s`).MatchString(earlier.String()) {
+					t.Errorf("migration %d (%s): AFTER %s references a column not created by an earlier step", step.version, step.name, column)
+				}
+			}
+			earlier.WriteString(stmt)
+			earlier.WriteString("\n")
+		}
+	}
+}
+
+// queryUsernames 按 ID 顺序返回全部用户名
+func queryUsernames(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	rows, err := db.Query("SELECT username FROM users ORDER BY id")
+	if err != nil {
+		t.Fatalf("query users error = %v", err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			t.Fatalf("scan username error = %v", err)
+		}
+		got = append(got, username)
+	}
+	return got
+}
+
+// TestMigrate_NormalizeUsernames 测试已有用户名改为规范形式
+func TestMigrate_NormalizeUsernames(t *testing.T) {
+	db := setupMigrateTestDB(t)
+	if err := migrate(db, migrations[:8]); err != nil {
+		t.Fatalf("migrate() to version 8 error = %v", err)
+	}
+
+	for _, username := range []string{"Alice", " bob ", "carol"} {
+		if _, err := db.Exec("INSERT INTO users (username, password_hash) VALUES (?, 'hash')", username); err != nil {
+			t.Fatalf("insert user %q error = %v", username, err)
+		}
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	want := []string{"alice", "bob", "carol"}
+	if got := queryUsernames(t, db); !reflect.DeepEqual(got, want) {
+		t.Errorf("usernames = %q, want %q", got, want)
+	}
+}
+
+// TestMigrate_NormalizeUsernames_Collision 测试规范化后重名时迁移失败并列出冲突用户名，数据保持不变
+func TestMigrate_NormalizeUsernames_Collision(t *testing.T) {
+	db := setupMigrateTestDB(t)
+	if err := migrate(db, migrations[:8]); err != nil {
+		t.Fatalf("migrate() to version 8 error = %v", err)
+	}
+
+	usernames := []string{"Alice", "Dave", "carol", " dave", "CAROL "}
+	for _, username := range usernames {
+		if _, err := db.Exec("INSERT INTO users (username, password_hash) VALUES (?, 'hash')", username); err != nil {
+			t.Fatalf("insert user %q error = %v", username, err)
+		}
+	}
+
+	err := Migrate(db)
+	if err == nil {
+		t.Fatal("Migrate() error = nil, want username collision error")
+	}
+	for _, want := range []string{`"carol", "CAROL "`, `"Dave", " dave"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Migrate() error = %v, want it to list %s", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "Alice") {
+		t.Errorf("Migrate() error = %v, should not list non-colliding usernames", err)
+	}
+
+	assertVersion(t, db, 8)
+	if got := queryUsernames(t, db); !reflect.DeepEqual(got, usernames) {
+		t.Errorf("usernames = %q, want unchanged %q", got, usernames)
+	}
+}
+
+// TestMigrate_MoveDraftOrdersToCart 测试草稿订单迁入购物车并与已有购物车项合并，其它订单不受影响
+func TestMigrate_MoveDraftOrdersToCart(t *testing.T) {
+	db := setupMigrateTestDB(t)
+	if err := migrate(db, migrations[:17]); err != nil {
+		t.Fatalf("migrate() to version 17 error = %v", err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO users (username, password_hash) VALUES ('alice', 'hash');
+		INSERT INTO addresses (user_id, address, contact) VALUES (1, '北京', '13800000000');
+		INSERT INTO orders (id, order_no, user_id, address_id, total_amount, status) VALUES
+			(1, 'ORD001', 1, 1, 5000, 'draft'),
+			(2, 'ORD002', 1, 1, 1000, 'pending');
+		INSERT INTO order_items (order_id, flower_sku, flower_name, quantity, unit_price, subtotal) VALUES
+			(1, 'FLW001', '红玫瑰', 3, 1000, 3000),
+			(1, 'FLW002', '百合', 1, 2000, 2000),
+			(2, 'FLW001', '红玫瑰', 1, 1000, 1000);
+		INSERT INTO order_logs (order_id, operator_id, action, new_status) VALUES (1, 1, 'save_cart', 'draft');
+		INSERT INTO cart_items (user_id, flower_sku, quantity) VALUES (1, 'FLW002', 2)`); err != nil {
+		t.Fatalf("insert draft data error = %v", err)
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	assertCount(t, db, "SELECT COUNT(*) FROM cart_items WHERE user_id = 1 AND flower_sku = 'FLW001' AND quantity = 3", 1)
+	assertCount(t, db, "SELECT COUNT(*) FROM cart_items WHERE user_id = 1 AND flower_sku = 'FLW002' AND quantity = 3", 1)
+	assertCount(t, db, "SELECT COUNT(*) FROM orders WHERE status = 'draft'", 0)
+	assertCount(t, db, "SELECT COUNT(*) FROM order_items WHERE order_id = 1", 0)
+	assertCount(t, db, "SELECT COUNT(*) FROM order_logs WHERE order_id = 1", 0)
+	assertCount(t, db, "SELECT COUNT(*) FROM order_items WHERE order_id = 2", 1)
+}
+
+// TestCurrentVersion_Empty 测试未执行迁移时版本为 0
+func TestCurrentVersion_Empty(t *testing.T) {
+	assertVersion(t, setupMigrateTestDB(t), 0)
+}
+
+// assertVersion 检查当前迁移版本
+func assertVersion(t *testing.T, db *sql.DB, want int) {
+	t.Helper()
+
+	got, err := CurrentVersion(db)
+	if err != nil {
+		t.Fatalf("CurrentVersion() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("CurrentVersion() = %d, want %d", got, want)
+	}
+}
+
+// assertCount 检查计数查询的结果
+func assertCount(t *testing.T, db *sql.DB, query string, want int) {
+	t.Helper()
+
+	var got int
+	if err := db.QueryRow(query).Scan(&got); err != nil {
+		t.Fatalf("%s error = %v", query, err)
+	}
+	if got != want {
+		t.Errorf("%s = %d, want %d", query, got, want)
+	}
+}
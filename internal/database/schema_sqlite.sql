@@ -0,0 +1,92 @@
+-- 鲜花销售系统数据库迁移脚本（SQLite）
+-- 与 schema.sql 的表结构一致，作为 SQLite 的迁移版本 1 执行，不再修改，表结构变更通过追加迁移步骤完成
+-- 金额以分为单位存为 INTEGER，布尔值存为 0/1，枚举使用 CHECK 约束
+
+-- 用户表 (users)
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT UNIQUE NOT NULL,
+    password_hash TEXT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'customer' CHECK (role IN ('customer', 'clerk', 'admin')),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_users_role ON users (role);
+
+-- 地址表 (addresses)
+CREATE TABLE IF NOT EXISTS addresses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    label TEXT,
+    address TEXT NOT NULL,
+    contact TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_addresses_user_id ON addresses (user_id);
+
+-- 鲜花表 (flowers)
+CREATE TABLE IF NOT EXISTS flowers (
+    sku TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    origin TEXT NOT NULL,
+    shelf_life TEXT NOT NULL,
+    preservation TEXT NOT NULL,
+    purchase_price INTEGER NOT NULL,
+    sale_price INTEGER NOT NULL,
+    stock INTEGER NOT NULL DEFAULT 0,
+    is_active INTEGER NOT NULL DEFAULT 1,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_flowers_name ON flowers (name);
+CREATE INDEX IF NOT EXISTS idx_flowers_origin ON flowers (origin);
+CREATE INDEX IF NOT EXISTS idx_flowers_is_active ON flowers (is_active);
+CREATE INDEX IF NOT EXISTS idx_flowers_stock ON flowers (stock);
+
+-- 订单表 (orders)，SQLite 支持引入时已有 draft 与 shipped 状态，CHECK 约束直接包含全部状态
+CREATE TABLE IF NOT EXISTS orders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_no TEXT UNIQUE NOT NULL,
+    user_id INTEGER NOT NULL,
+    address_id INTEGER NOT NULL,
+    total_amount INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('draft', 'pending', 'shipped', 'completed', 'cancelled')),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (address_id) REFERENCES addresses(id)
+);
+CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders (user_id);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders (status);
+CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders (created_at);
+
+-- 订单项表 (order_items)
+CREATE TABLE IF NOT EXISTS order_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id INTEGER NOT NULL,
+    flower_sku TEXT NOT NULL,
+    flower_name TEXT NOT NULL,
+    quantity INTEGER NOT NULL,
+    unit_price INTEGER NOT NULL,
+    subtotal INTEGER NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items (order_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_flower_sku ON order_items (flower_sku);
+
+-- 订单操作日志表 (order_logs)
+CREATE TABLE IF NOT EXISTS order_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id INTEGER NOT NULL,
+    operator_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    old_status TEXT,
+    new_status TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    FOREIGN KEY (operator_id) REFERENCES users(id)
+);
+CREATE INDEX IF NOT EXISTS idx_order_logs_order_id ON order_logs (order_id);
+CREATE INDEX IF NOT EXISTS idx_order_logs_created_at ON order_logs (created_at);
@@ -0,0 +1,104 @@
+package discount
+
+import (
+	"strings"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+)
+
+// Type 优惠类型
+type Type string
+
+// 优惠类型常量
+const (
+	TypePercent Type = "percent" // 按百分比减免，Value 为减免的百分比（1-100）
+	TypeFixed   Type = "fixed"   // 固定金额减免，Value 为减免金额（分）
+)
+
+// MaxCodeLength 优惠码最大长度
+const MaxCodeLength = 32
+
+// 优惠码错误
+var (
+	ErrDiscountNotFound  = apperror.New(apperror.KindNotFound, "优惠码不存在")
+	ErrCodeTaken         = apperror.New(apperror.KindConflict, "优惠码已存在")
+	ErrInvalidCode       = apperror.New(apperror.KindValidation, "优惠码无效")
+	ErrDiscountExpired   = apperror.New(apperror.KindValidation, "优惠码不在有效期内")
+	ErrDiscountExhausted = apperror.New(apperror.KindValidation, "优惠码已达到使用次数上限")
+)
+
+// Discount 优惠码实体
+type Discount struct {
+	ID         int        `json:"id"`
+	Code       string     `json:"code"` // 统一存储为大写
+	Type       Type       `json:"type"`
+	Value      int64      `json:"value"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty"` // 为 nil 时不限开始时间
+	ValidTo    *time.Time `json:"valid_to,omitempty"`   // 为 nil 时不限结束时间
+	UsageLimit int        `json:"usage_limit"`          // 0 表示不限次数
+	UsedCount  int        `json:"used_count"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// NormalizeCode 去除首尾空格并转为大写，优惠码不区分大小写
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate 验证优惠码数据是否有效
+func (d *Discount) Validate() error {
+	if d.Code == "" {
+		return apperror.Validation("优惠码不能为空")
+	}
+	if len(d.Code) > MaxCodeLength {
+		return apperror.Validation("优惠码长度不能超过%d个字符", MaxCodeLength)
+	}
+	switch d.Type {
+	case TypePercent:
+		if d.Value < 1 || d.Value > 100 {
+			return apperror.Validation("百分比优惠的数值必须在1到100之间")
+		}
+	case TypeFixed:
+		if d.Value <= 0 {
+			return apperror.Validation("固定金额优惠的数值必须大于0")
+		}
+	default:
+		return apperror.Validation("无效的优惠类型: %s", d.Type)
+	}
+	if d.ValidFrom != nil && d.ValidTo != nil && !d.ValidFrom.Before(*d.ValidTo) {
+		return apperror.Validation("有效期开始时间必须早于结束时间")
+	}
+	if d.UsageLimit < 0 {
+		return apperror.Validation("使用次数上限不能为负数")
+	}
+	return nil
+}
+
+// CheckUsable 检查优惠码在 now 时刻是否可用
+func (d *Discount) CheckUsable(now time.Time) error {
+	if (d.ValidFrom != nil && now.Before(*d.ValidFrom)) || (d.ValidTo != nil && !now.Before(*d.ValidTo)) {
+		return ErrDiscountExpired
+	}
+	if d.UsageLimit > 0 && d.UsedCount >= d.UsageLimit {
+		return ErrDiscountExhausted
+	}
+	return nil
+}
+
+// Amount 计算订单金额 total 可减免的金额，减免后金额不低于 0
+func (d *Discount) Amount(total flower.Decimal) flower.Decimal {
+	var amount flower.Decimal
+	switch d.Type {
+	case TypePercent:
+		amount = total.Percent(int(d.Value))
+	case TypeFixed:
+		amount = flower.Decimal{Value: d.Value}
+	}
+	if amount.GreaterThan(total) {
+		return total
+	}
+	return amount
+}
@@ -0,0 +1,98 @@
+package discount
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+)
+
+// TestDiscount_Validate 测试优惠码数据校验
+func TestDiscount_Validate(t *testing.T) {
+	from := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(14 * 24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		discount Discount
+		wantErr  bool
+	}{
+		{name: "百分比优惠", discount: Discount{Code: "SPRING15", Type: TypePercent, Value: 15}},
+		{name: "固定金额优惠带有效期", discount: Discount{Code: "VDAY", Type: TypeFixed, Value: 500, ValidFrom: &from, ValidTo: &to, UsageLimit: 100}},
+		{name: "优惠码为空", discount: Discount{Type: TypePercent, Value: 15}, wantErr: true},
+		{name: "优惠码过长", discount: Discount{Code: "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456", Type: TypePercent, Value: 15}, wantErr: true},
+		{name: "百分比为0", discount: Discount{Code: "ZERO", Type: TypePercent, Value: 0}, wantErr: true},
+		{name: "百分比超过100", discount: Discount{Code: "MORE", Type: TypePercent, Value: 101}, wantErr: true},
+		{name: "固定金额为负数", discount: Discount{Code: "NEG", Type: TypeFixed, Value: -1}, wantErr: true},
+		{name: "无效类型", discount: Discount{Code: "BOGO", Type: "bogo", Value: 1}, wantErr: true},
+		{name: "有效期颠倒", discount: Discount{Code: "BACK", Type: TypeFixed, Value: 100, ValidFrom: &to, ValidTo: &from}, wantErr: true},
+		{name: "使用次数上限为负数", discount: Discount{Code: "LIMIT", Type: TypeFixed, Value: 100, UsageLimit: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.discount.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && apperror.KindOf(err) != apperror.KindValidation {
+				t.Errorf("Validate() error kind = %v, want validation", apperror.KindOf(err))
+			}
+		})
+	}
+}
+
+// TestDiscount_CheckUsable 测试有效期与使用次数检查
+func TestDiscount_CheckUsable(t *testing.T) {
+	now := time.Date(2026, 2, 14, 12, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		discount Discount
+		wantErr  error
+	}{
+		{name: "不限有效期与次数", discount: Discount{}},
+		{name: "在有效期内", discount: Discount{ValidFrom: &before, ValidTo: &after, UsageLimit: 2, UsedCount: 1}},
+		{name: "尚未开始", discount: Discount{ValidFrom: &after}, wantErr: ErrDiscountExpired},
+		{name: "已过期", discount: Discount{ValidTo: &before}, wantErr: ErrDiscountExpired},
+		{name: "结束时间不包含在内", discount: Discount{ValidTo: &now}, wantErr: ErrDiscountExpired},
+		{name: "已用尽", discount: Discount{UsageLimit: 2, UsedCount: 2}, wantErr: ErrDiscountExhausted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.discount.CheckUsable(now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckUsable() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestDiscount_Amount 测试减免金额计算
+func TestDiscount_Amount(t *testing.T) {
+	tests := []struct {
+		name     string
+		discount Discount
+		total    int64
+		want     int64
+	}{
+		{name: "百分比", discount: Discount{Type: TypePercent, Value: 15}, total: 3000, want: 450},
+		{name: "百分比四舍五入", discount: Discount{Type: TypePercent, Value: 15}, total: 999, want: 150},
+		{name: "百分之百", discount: Discount{Type: TypePercent, Value: 100}, total: 3000, want: 3000},
+		{name: "固定金额", discount: Discount{Type: TypeFixed, Value: 500}, total: 3000, want: 500},
+		{name: "固定金额超过订单金额", discount: Discount{Type: TypeFixed, Value: 5000}, total: 3000, want: 3000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.discount.Amount(flower.Decimal{Value: tt.total})
+			if got.Value != tt.want {
+				t.Errorf("Amount(%d) = %d, want %d", tt.total, got.Value, tt.want)
+			}
+		})
+	}
+}
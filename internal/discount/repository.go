@@ -0,0 +1,234 @@
+package discount
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// DiscountRepository 定义优惠码数据访问接口
+type DiscountRepository interface {
+	Create(ctx context.Context, d *Discount) error
+	GetByID(ctx context.Context, id int) (*Discount, error)
+	GetByCode(ctx context.Context, code string) (*Discount, error)
+	List(ctx context.Context) ([]*Discount, error)
+	Update(ctx context.Context, d *Discount) error
+	Delete(ctx context.Context, id int) error
+	// RedeemTx 在调用方事务中使用一次优惠码，已达使用次数上限时返回 ErrDiscountExhausted
+	RedeemTx(ctx context.Context, tx *sql.Tx, id int) error
+	// ReleaseTx 在调用方事务中归还一次使用次数，用于取消使用了优惠码的订单
+	ReleaseTx(ctx context.Context, tx *sql.Tx, id int) error
+}
+
+// discountRepository 实现 DiscountRepository 接口
+type discountRepository struct {
+	db *sql.DB
+}
+
+// NewDiscountRepository 创建 DiscountRepository 实例
+func NewDiscountRepository(db *sql.DB) DiscountRepository {
+	return &discountRepository{db: db}
+}
+
+// discountColumns 查询优惠码时的列，顺序与 scanDiscount 一致
+const discountColumns = `id, code, type, value, valid_from, valid_to, usage_limit, used_count, created_at, updated_at`
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanDiscount 扫描一行优惠码数据
+func scanDiscount(row rowScanner) (*Discount, error) {
+	var d Discount
+	var discountType string
+	var validFrom, validTo sql.NullTime
+	if err := row.Scan(&d.ID, &d.Code, &discountType, &d.Value, &validFrom, &validTo,
+		&d.UsageLimit, &d.UsedCount, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	d.Type = Type(discountType)
+	if validFrom.Valid {
+		d.ValidFrom = &validFrom.Time
+	}
+	if validTo.Valid {
+		d.ValidTo = &validTo.Time
+	}
+	return &d, nil
+}
+
+// Create 创建优惠码
+func (r *discountRepository) Create(ctx context.Context, d *Discount) error {
+	now := time.Now()
+	d.CreatedAt = now
+	d.UpdatedAt = now
+
+	query := `
+		INSERT INTO discounts (code, type, value, valid_from, valid_to, usage_limit, used_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		d.Code, string(d.Type), d.Value, d.ValidFrom, d.ValidTo, d.UsageLimit, d.UsedCount, d.CreatedAt, d.UpdatedAt,
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return fmt.Errorf("%w: %s", ErrCodeTaken, d.Code)
+		}
+		return fmt.Errorf("create discount: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("get last insert id: %w", err)
+	}
+
+	d.ID = int(id)
+	return nil
+}
+
+// GetByID 根据 ID 获取优惠码
+func (r *discountRepository) GetByID(ctx context.Context, id int) (*Discount, error) {
+	d, err := scanDiscount(r.db.QueryRowContext(ctx, "SELECT "+discountColumns+" FROM discounts WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", ErrDiscountNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get discount by id: %w", err)
+	}
+	return d, nil
+}
+
+// GetByCode 根据优惠码获取，code 需已通过 NormalizeCode 规范化
+func (r *discountRepository) GetByCode(ctx context.Context, code string) (*Discount, error) {
+	d, err := scanDiscount(r.db.QueryRowContext(ctx, "SELECT "+discountColumns+" FROM discounts WHERE code = ?", code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrDiscountNotFound, code)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get discount by code: %w", err)
+	}
+	return d, nil
+}
+
+// List 获取全部优惠码，按创建时间倒序
+func (r *discountRepository) List(ctx context.Context) ([]*Discount, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+discountColumns+" FROM discounts ORDER BY created_at DESC, id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("list discounts: %w", err)
+	}
+	defer rows.Close()
+
+	discounts := []*Discount{}
+	for rows.Next() {
+		d, err := scanDiscount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan discount: %w", err)
+		}
+		discounts = append(discounts, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate discounts: %w", err)
+	}
+
+	return discounts, nil
+}
+
+// Update 更新优惠码信息，不修改已使用次数
+func (r *discountRepository) Update(ctx context.Context, d *Discount) error {
+	d.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE discounts SET
+			code = ?, type = ?, value = ?, valid_from = ?, valid_to = ?, usage_limit = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		d.Code, string(d.Type), d.Value, d.ValidFrom, d.ValidTo, d.UsageLimit, d.UpdatedAt, d.ID,
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return fmt.Errorf("%w: %s", ErrCodeTaken, d.Code)
+		}
+		return fmt.Errorf("update discount: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("%w: %d", ErrDiscountNotFound, d.ID)
+	}
+
+	return nil
+}
+
+// Delete 删除优惠码，已使用该优惠码的订单保留减免金额
+func (r *discountRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM discounts WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("delete discount: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("%w: %d", ErrDiscountNotFound, id)
+	}
+
+	return nil
+}
+
+// RedeemTx 在调用方事务中将使用次数加一
+// 条件更新保证并发下单时不会超过使用次数上限
+func (r *discountRepository) RedeemTx(ctx context.Context, tx *sql.Tx, id int) error {
+	result, err := tx.ExecContext(ctx, `
+		UPDATE discounts SET used_count = used_count + 1, updated_at = ?
+		WHERE id = ? AND (usage_limit = 0 OR used_count < usage_limit)
+	`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("redeem discount: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrDiscountExhausted
+	}
+	return nil
+}
+
+// ReleaseTx 在调用方事务中将使用次数减一
+// 优惠码已被删除或使用次数已为 0 时不做处理
+func (r *discountRepository) ReleaseTx(ctx context.Context, tx *sql.Tx, id int) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE discounts SET used_count = used_count - 1, updated_at = ?
+		WHERE id = ? AND used_count > 0
+	`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("release discount: %w", err)
+	}
+	return nil
+}
+
+// isDuplicateKey 判断错误是否为违反唯一约束
+// MySQL 返回 1062 (ER_DUP_ENTRY)；SQLite（测试环境）没有在此引入驱动，按错误信息识别
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1062
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
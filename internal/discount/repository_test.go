@@ -0,0 +1,140 @@
+package discount
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite 驱动
+)
+
+// setupDiscountTestDB 创建优惠码测试使用的内存 SQLite 数据库
+func setupDiscountTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	// 内存数据库每个连接独立，限制为单连接
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS discounts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			code TEXT NOT NULL UNIQUE,
+			type TEXT NOT NULL,
+			value INTEGER NOT NULL,
+			valid_from DATETIME,
+			valid_to DATETIME,
+			usage_limit INTEGER NOT NULL DEFAULT 0,
+			used_count INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+		t.Fatalf("failed to create discounts table: %v", err)
+	}
+	return db
+}
+
+// TestDiscountRepository_RedeemTx 测试使用次数在达到上限后不再增加
+func TestDiscountRepository_RedeemTx(t *testing.T) {
+	db := setupDiscountTestDB(t)
+	repo := NewDiscountRepository(db)
+	ctx := context.Background()
+
+	limited := &Discount{Code: "TWICE", Type: TypeFixed, Value: 100, UsageLimit: 2}
+	unlimited := &Discount{Code: "ALWAYS", Type: TypeFixed, Value: 100}
+	for _, d := range []*Discount{limited, unlimited} {
+		if err := repo.Create(ctx, d); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	redeem := func(id int) error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("BeginTx() error = %v", err)
+		}
+		defer tx.Rollback()
+		if err := repo.RedeemTx(ctx, tx, id); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	for i := 0; i < 3; i++ {
+		err := redeem(limited.ID)
+		if i < 2 && err != nil {
+			t.Fatalf("RedeemTx() #%d error = %v", i+1, err)
+		}
+		if i == 2 && !errors.Is(err, ErrDiscountExhausted) {
+			t.Errorf("RedeemTx() over limit error = %v, want ErrDiscountExhausted", err)
+		}
+		if err := redeem(unlimited.ID); err != nil {
+			t.Fatalf("RedeemTx() unlimited error = %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		id   int
+		want int
+	}{{limited.ID, 2}, {unlimited.ID, 3}} {
+		d, err := repo.GetByID(ctx, tc.id)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if d.UsedCount != tc.want {
+			t.Errorf("%s UsedCount = %d, want %d", d.Code, d.UsedCount, tc.want)
+		}
+	}
+}
+
+// TestDiscountRepository_ReleaseTx 测试归还使用次数，次数为 0 时不再减少
+func TestDiscountRepository_ReleaseTx(t *testing.T) {
+	db := setupDiscountTestDB(t)
+	repo := NewDiscountRepository(db)
+	ctx := context.Background()
+
+	d := &Discount{Code: "ONCE", Type: TypeFixed, Value: 100, UsageLimit: 1}
+	if err := repo.Create(ctx, d); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	inTx := func(fn func(tx *sql.Tx) error) error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("BeginTx() error = %v", err)
+		}
+		defer tx.Rollback()
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	steps := []struct {
+		name string
+		fn   func(tx *sql.Tx) error
+	}{
+		{"redeem", func(tx *sql.Tx) error { return repo.RedeemTx(ctx, tx, d.ID) }},
+		{"release", func(tx *sql.Tx) error { return repo.ReleaseTx(ctx, tx, d.ID) }},
+		{"release at zero", func(tx *sql.Tx) error { return repo.ReleaseTx(ctx, tx, d.ID) }},
+		{"redeem after release", func(tx *sql.Tx) error { return repo.RedeemTx(ctx, tx, d.ID) }},
+	}
+	for _, step := range steps {
+		if err := inTx(step.fn); err != nil {
+			t.Fatalf("%s error = %v", step.name, err)
+		}
+	}
+
+	got, err := repo.GetByID(ctx, d.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.UsedCount != 1 {
+		t.Errorf("UsedCount = %d, want 1", got.UsedCount)
+	}
+}
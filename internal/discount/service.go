@@ -0,0 +1,88 @@
+package discount
+
+import (
+	"context"
+	"time"
+)
+
+// DiscountService 定义优惠码管理业务逻辑接口
+type DiscountService interface {
+	CreateDiscount(ctx context.Context, req *DiscountRequest) (*Discount, error)
+	GetDiscount(ctx context.Context, id int) (*Discount, error)
+	ListDiscounts(ctx context.Context) ([]*Discount, error)
+	UpdateDiscount(ctx context.Context, id int, req *DiscountRequest) (*Discount, error)
+	DeleteDiscount(ctx context.Context, id int) error
+}
+
+// DiscountRequest 创建或更新优惠码请求，更新时整体替换，不影响已使用次数
+type DiscountRequest struct {
+	Code       string
+	Type       Type
+	Value      int64
+	ValidFrom  *time.Time
+	ValidTo    *time.Time
+	UsageLimit int
+}
+
+// discountService 实现 DiscountService 接口
+type discountService struct {
+	repo DiscountRepository
+}
+
+// NewDiscountService 创建 DiscountService 实例
+func NewDiscountService(repo DiscountRepository) DiscountService {
+	return &discountService{repo: repo}
+}
+
+// apply 将请求内容写入优惠码实体并校验
+func (req *DiscountRequest) apply(d *Discount) error {
+	d.Code = NormalizeCode(req.Code)
+	d.Type = req.Type
+	d.Value = req.Value
+	d.ValidFrom = req.ValidFrom
+	d.ValidTo = req.ValidTo
+	d.UsageLimit = req.UsageLimit
+	return d.Validate()
+}
+
+// CreateDiscount 创建优惠码
+func (s *discountService) CreateDiscount(ctx context.Context, req *DiscountRequest) (*Discount, error) {
+	d := &Discount{}
+	if err := req.apply(d); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// GetDiscount 获取优惠码
+func (s *discountService) GetDiscount(ctx context.Context, id int) (*Discount, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// ListDiscounts 获取全部优惠码
+func (s *discountService) ListDiscounts(ctx context.Context) ([]*Discount, error) {
+	return s.repo.List(ctx)
+}
+
+// UpdateDiscount 更新优惠码
+func (s *discountService) UpdateDiscount(ctx context.Context, id int, req *DiscountRequest) (*Discount, error) {
+	d, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.apply(d); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DeleteDiscount 删除优惠码
+func (s *discountService) DeleteDiscount(ctx context.Context, id int) error {
+	return s.repo.Delete(ctx, id)
+}
@@ -0,0 +1,57 @@
+package discount
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestDiscountService_CRUD 测试优惠码的创建、查询、更新与删除
+func TestDiscountService_CRUD(t *testing.T) {
+	db := setupDiscountTestDB(t)
+	repo := NewDiscountRepository(db)
+	svc := NewDiscountService(repo)
+	ctx := context.Background()
+
+	created, err := svc.CreateDiscount(ctx, &DiscountRequest{Code: " spring15 ", Type: TypePercent, Value: 15, UsageLimit: 10})
+	if err != nil {
+		t.Fatalf("CreateDiscount() error = %v", err)
+	}
+	if created.Code != "SPRING15" {
+		t.Errorf("Code = %q, want SPRING15", created.Code)
+	}
+
+	if _, err := svc.CreateDiscount(ctx, &DiscountRequest{Code: "Spring15", Type: TypeFixed, Value: 100}); !errors.Is(err, ErrCodeTaken) {
+		t.Errorf("CreateDiscount() duplicate error = %v, want ErrCodeTaken", err)
+	}
+	if _, err := svc.CreateDiscount(ctx, &DiscountRequest{Code: "BAD", Type: TypePercent, Value: 200}); err == nil {
+		t.Error("CreateDiscount() with invalid value should fail")
+	}
+
+	updated, err := svc.UpdateDiscount(ctx, created.ID, &DiscountRequest{Code: "SPRING20", Type: TypePercent, Value: 20, UsageLimit: 5})
+	if err != nil {
+		t.Fatalf("UpdateDiscount() error = %v", err)
+	}
+	got, err := svc.GetDiscount(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetDiscount() error = %v", err)
+	}
+	if got.Code != "SPRING20" || got.Value != 20 || got.UsageLimit != 5 || got.Code != updated.Code {
+		t.Errorf("GetDiscount() = %+v, want updated values", got)
+	}
+
+	list, err := svc.ListDiscounts(ctx)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListDiscounts() = %d items, %v, want 1", len(list), err)
+	}
+
+	if err := svc.DeleteDiscount(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteDiscount() error = %v", err)
+	}
+	if _, err := svc.GetDiscount(ctx, created.ID); !errors.Is(err, ErrDiscountNotFound) {
+		t.Errorf("GetDiscount() after delete error = %v, want ErrDiscountNotFound", err)
+	}
+	if err := svc.DeleteDiscount(ctx, created.ID); !errors.Is(err, ErrDiscountNotFound) {
+		t.Errorf("DeleteDiscount() twice error = %v, want ErrDiscountNotFound", err)
+	}
+}
@@ -1,10 +1,23 @@
 package flower
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"strings"
 )
 
+// ErrDecimalOverflow 金额计算超出 int64 范围
+var ErrDecimalOverflow = errors.New("金额计算溢出")
+
+// ErrDivisionByZero 除数为零
+var ErrDivisionByZero = errors.New("除数不能为零")
+
+// ErrInvalidDecimal 金额格式不正确
+var ErrInvalidDecimal = errors.New("金额格式不正确")
+
 // Decimal 表示一个精确的十进制数，内部存储为"分"（整数）
 // 用于处理金额等需要精确计算的场景
 type Decimal struct {
@@ -21,6 +34,52 @@ func roundToCent(f float64) int64 {
 	return int64(math.Round(f*100) / 100 * 100)
 }
 
+// ParseDecimal 解析十进制金额字符串（如 "100.55"、"-3"、"0.5"）
+// 最多两位小数，不支持科学计数法；超出 int64 范围时返回 ErrDecimalOverflow
+func ParseDecimal(s string) (Decimal, error) {
+	str := strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(str, "-") || strings.HasPrefix(str, "+") {
+		negative = str[0] == '-'
+		str = str[1:]
+	}
+
+	intPart, fracPart, hasDot := strings.Cut(str, ".")
+	if intPart == "" || len(fracPart) > 2 || (hasDot && fracPart == "") ||
+		!isDigits(intPart) || !isDigits(fracPart) {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+	fracPart += strings.Repeat("0", 2-len(fracPart))
+
+	var cents int64
+	for _, c := range intPart + fracPart {
+		digit := int64(c - '0')
+		if cents > (math.MaxInt64-digit)/10 {
+			return Decimal{}, ErrDecimalOverflow
+		}
+		cents = cents*10 + digit
+	}
+	if negative {
+		cents = -cents
+	}
+	return Decimal{Value: cents}, nil
+}
+
+// isDigits 判断字符串是否只包含 ASCII 数字
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Cents 返回以分为单位的整数金额
+func (d Decimal) Cents() int64 {
+	return d.Value
+}
+
 // ToFloat64 将 Decimal 转换为 float64
 func (d Decimal) ToFloat64() float64 {
 	return float64(d.Value) / 100
@@ -41,6 +100,68 @@ func (d Decimal) Mul(factor int64) Decimal {
 	return Decimal{Value: d.Value * factor}
 }
 
+// Div 返回 d / divisor，结果按分四舍五入（.5 远离零进位）
+// divisor 为零时返回 ErrDivisionByZero，结果溢出时返回 ErrDecimalOverflow
+func (d Decimal) Div(divisor int64) (Decimal, error) {
+	if divisor == 0 {
+		return Decimal{}, ErrDivisionByZero
+	}
+	if d.Value == math.MinInt64 && divisor == -1 {
+		return Decimal{}, ErrDecimalOverflow
+	}
+
+	quotient := d.Value / divisor
+	remainder := d.Value % divisor
+	// 用无符号绝对值比较 2*|r| >= |divisor|，避免乘 2 溢出
+	if r, div := absUint64(remainder), absUint64(divisor); r != 0 && r >= div-r {
+		if (d.Value < 0) != (divisor < 0) {
+			quotient--
+		} else {
+			quotient++
+		}
+	}
+	return Decimal{Value: quotient}, nil
+}
+
+// Percent 返回 d 的 p%，结果按分四舍五入，例如折扣 Percent(85) 表示八五折
+func (d Decimal) Percent(p int) Decimal {
+	// 先拆出整百部分，减小中间结果溢出的可能
+	whole := d.Value / 100 * int64(p)
+	rest, _ := Decimal{Value: d.Value % 100 * int64(p)}.Div(100)
+	return Decimal{Value: whole + rest.Value}
+}
+
+// absUint64 返回 v 的绝对值，math.MinInt64 也能正确表示
+func absUint64(v int64) uint64 {
+	if v < 0 {
+		return uint64(-(v + 1)) + 1
+	}
+	return uint64(v)
+}
+
+// CheckedAdd 返回 d + other，结果溢出时返回 ErrDecimalOverflow
+func (d Decimal) CheckedAdd(other Decimal) (Decimal, error) {
+	if (other.Value > 0 && d.Value > math.MaxInt64-other.Value) ||
+		(other.Value < 0 && d.Value < math.MinInt64-other.Value) {
+		return Decimal{}, ErrDecimalOverflow
+	}
+	return Decimal{Value: d.Value + other.Value}, nil
+}
+
+// CheckedMul 返回 d * factor，结果溢出时返回 ErrDecimalOverflow
+func (d Decimal) CheckedMul(factor int64) (Decimal, error) {
+	if d.Value == 0 || factor == 0 {
+		return Decimal{}, nil
+	}
+	result := d.Value * factor
+	if result/factor != d.Value ||
+		(d.Value == -1 && factor == math.MinInt64) ||
+		(factor == -1 && d.Value == math.MinInt64) {
+		return Decimal{}, ErrDecimalOverflow
+	}
+	return Decimal{Value: result}, nil
+}
+
 // Cmp 比较两个 Decimal
 // 返回值：-1 表示 d < other, 0 表示 d == other, 1 表示 d > other
 func (d Decimal) Cmp(other Decimal) int {
@@ -79,3 +200,30 @@ func (d Decimal) String() string {
 	cents := d.Value % 100
 	return fmt.Sprintf("%s%d.%02d", sign, yuan, cents)
 }
+
+// MarshalJSON 按两位小数输出字符串形式的金额（如 "175.00"），避免 JS 客户端处理大数值时丢失精度
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// UnmarshalJSON 解析字符串（"100.55"）或数字（100.55）形式的金额，null 保持原值
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	str := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &str); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidDecimal, data)
+		}
+	}
+
+	parsed, err := ParseDecimal(str)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
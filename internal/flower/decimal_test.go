@@ -1,6 +1,9 @@
 package flower
 
 import (
+	"encoding/json"
+	"errors"
+	"math"
 	"testing"
 )
 
@@ -58,10 +61,10 @@ func TestDecimalToFloat64(t *testing.T) {
 
 func TestDecimalAdd(t *testing.T) {
 	tests := []struct {
-		name  string
-		a     int64
-		b     int64
-		want  int64
+		name string
+		a    int64
+		b    int64
+		want int64
 	}{
 		{"两个正数", 10000, 5000, 15000},
 		{"正数加零", 10000, 0, 10000},
@@ -84,10 +87,10 @@ func TestDecimalAdd(t *testing.T) {
 
 func TestDecimalSub(t *testing.T) {
 	tests := []struct {
-		name  string
-		a     int64
-		b     int64
-		want  int64
+		name string
+		a    int64
+		b    int64
+		want int64
 	}{
 		{"正数减正数", 10000, 3000, 7000},
 		{"正数减零", 10000, 0, 10000},
@@ -110,10 +113,10 @@ func TestDecimalSub(t *testing.T) {
 
 func TestDecimalMul(t *testing.T) {
 	tests := []struct {
-		name  string
-		a     int64
-		b     int64
-		want  int64
+		name string
+		a    int64
+		b    int64
+		want int64
 	}{
 		{"正数乘正数", 10000, 2, 20000},
 		{"乘零", 10000, 0, 0},
@@ -134,6 +137,68 @@ func TestDecimalMul(t *testing.T) {
 	}
 }
 
+func TestDecimalDiv(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   int64
+		divisor int64
+		want    int64
+		wantErr error
+	}{
+		{"整除", 9000, 3, 3000, nil},
+		{"舍去", 10001, 3, 3334, nil},
+		{"向上进位", 10002, 3, 3334, nil},
+		{"恰好一半进位", 5, 2, 3, nil},
+		{"负数恰好一半远离零", -5, 2, -3, nil},
+		{"负除数", 10001, -3, -3334, nil},
+		{"负数舍去", -10001, 3, -3334, nil},
+		{"不足一分进位", 1, 2, 1, nil},
+		{"不足一分舍去", 1, 3, 0, nil},
+		{"大除数", math.MaxInt64, math.MinInt64, -1, nil},
+		{"除数为零", 100, 0, 0, ErrDivisionByZero},
+		{"溢出", math.MinInt64, -1, 0, ErrDecimalOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decimal{Value: tt.value}.Div(tt.divisor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Div() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got.Value != tt.want {
+				t.Errorf("Decimal{%d}.Div(%d) = %d, want %d", tt.value, tt.divisor, got.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimalPercent(t *testing.T) {
+	tests := []struct {
+		name  string
+		value int64
+		p     int
+		want  int64
+	}{
+		{"八五折", 10000, 85, 8500},
+		{"百分之零", 10000, 0, 0},
+		{"百分之百", 12345, 100, 12345},
+		{"四舍五入进位", 999, 15, 150},
+		{"四舍五入舍去", 1001, 33, 330},
+		{"负数", -999, 15, -150},
+		{"超过百分之百", 2000, 150, 3000},
+		{"大金额不溢出", math.MaxInt64 / 2, 100, math.MaxInt64 / 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Decimal{Value: tt.value}.Percent(tt.p)
+			if got.Value != tt.want {
+				t.Errorf("Decimal{%d}.Percent(%d) = %d, want %d", tt.value, tt.p, got.Value, tt.want)
+			}
+		})
+	}
+}
+
 func TestDecimalCmp(t *testing.T) {
 	tests := []struct {
 		name string
@@ -163,10 +228,10 @@ func TestDecimalCmp(t *testing.T) {
 
 func TestDecimalEqual(t *testing.T) {
 	tests := []struct {
-		name  string
-		a     int64
-		b     int64
-		want  bool
+		name string
+		a    int64
+		b    int64
+		want bool
 	}{
 		{"相等", 10000, 10000, true},
 		{"不相等", 10000, 5000, false},
@@ -189,10 +254,10 @@ func TestDecimalEqual(t *testing.T) {
 
 func TestDecimalGreaterThan(t *testing.T) {
 	tests := []struct {
-		name  string
-		a     int64
-		b     int64
-		want  bool
+		name string
+		a    int64
+		b    int64
+		want bool
 	}{
 		{"a大于b", 10000, 5000, true},
 		{"a等于b", 10000, 10000, false},
@@ -214,10 +279,10 @@ func TestDecimalGreaterThan(t *testing.T) {
 
 func TestDecimalLessThan(t *testing.T) {
 	tests := []struct {
-		name  string
-		a     int64
-		b     int64
-		want  bool
+		name string
+		a    int64
+		b    int64
+		want bool
 	}{
 		{"a小于b", 5000, 10000, true},
 		{"a等于b", 10000, 10000, false},
@@ -263,3 +328,170 @@ func TestDecimalString(t *testing.T) {
 	}
 }
 
+func TestDecimalCheckedAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    int64
+		want    int64
+		wantErr bool
+	}{
+		{"正常相加", 10000, 5050, 15050, false},
+		{"负数相加", -100, -200, -300, false},
+		{"正向溢出", math.MaxInt64, 1, 0, true},
+		{"负向溢出", math.MinInt64, -1, 0, true},
+		{"边界不溢出", math.MaxInt64 - 1, 1, math.MaxInt64, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decimal{Value: tt.a}.CheckedAdd(Decimal{Value: tt.b})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decimal.CheckedAdd() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrDecimalOverflow) {
+				t.Errorf("Decimal.CheckedAdd() error = %v, want ErrDecimalOverflow", err)
+			}
+			if !tt.wantErr && got.Value != tt.want {
+				t.Errorf("Decimal.CheckedAdd() = %v, want %v", got.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimalCheckedMul(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   int64
+		factor  int64
+		want    int64
+		wantErr bool
+	}{
+		{"正常相乘", 1000, 10, 10000, false},
+		{"乘以零", math.MaxInt64, 0, 0, false},
+		{"正向溢出", math.MaxInt64/2 + 1, 2, 0, true},
+		{"负向溢出", math.MinInt64, -1, 0, true},
+		{"边界不溢出", math.MaxInt64 / 2, 2, math.MaxInt64 - 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decimal{Value: tt.value}.CheckedMul(tt.factor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decimal.CheckedMul() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Value != tt.want {
+				t.Errorf("Decimal.CheckedMul() = %v, want %v", got.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimalMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input int64
+		want  string
+	}{
+		{"整数金额", 17500, `"175.00"`},
+		{"负数", -5025, `"-50.25"`},
+		{"补零", 5, `"0.05"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(Decimal{Value: tt.input})
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("json.Marshal(Decimal) = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int64
+		wantErr error
+	}{
+		{"两位小数", "100.55", 10055, nil},
+		{"一位小数", "0.5", 50, nil},
+		{"整数", "175", 17500, nil},
+		{"负数", "-50.25", -5025, nil},
+		{"正号", "+1.01", 101, nil},
+		{"首尾空格", " 3.00 ", 300, nil},
+		{"空字符串", "", 0, ErrInvalidDecimal},
+		{"三位小数", "1.005", 0, ErrInvalidDecimal},
+		{"缺少小数部分", "1.", 0, ErrInvalidDecimal},
+		{"缺少整数部分", ".5", 0, ErrInvalidDecimal},
+		{"非数字", "abc", 0, ErrInvalidDecimal},
+		{"多个小数点", "1.2.3", 0, ErrInvalidDecimal},
+		{"科学计数法", "1e2", 0, ErrInvalidDecimal},
+		{"溢出", "99999999999999999999", 0, ErrDecimalOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDecimal(tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseDecimal(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && got.Value != tt.want {
+				t.Errorf("ParseDecimal(%q) = %d, want %d", tt.input, got.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimalUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"字符串形式", `"100.55"`, 10055, false},
+		{"数字形式", `100.55`, 10055, false},
+		{"整数", `12`, 1200, false},
+		{"负数字符串", `"-0.05"`, -5, false},
+		{"格式错误", `"12.345"`, 0, true},
+		{"非数字字符串", `"abc"`, 0, true},
+		{"布尔值", `true`, 0, true},
+		{"空字符串", `""`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Decimal
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("json.Unmarshal(%s) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Value != tt.want {
+				t.Errorf("json.Unmarshal(%s) = %d, want %d", tt.input, got.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimalJSONRoundTrip(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Price Decimal `json:"price"`
+	}{Decimal{Value: 10055}})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	var got struct {
+		Price Decimal `json:"price"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v", data, err)
+	}
+	if got.Price.Value != 10055 {
+		t.Errorf("round trip %s = %d, want 10055", data, got.Price.Value)
+	}
+}
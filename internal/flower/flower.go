@@ -1,34 +1,67 @@
 package flower
 
 import (
-	"fmt"
+	"encoding/json"
 	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 )
 
 // Flower 表示鲜花实体
 type Flower struct {
-	SKU           string    `json:"sku"`
-	Name          string    `json:"name"`
-	Origin        string    `json:"origin"`
-	ShelfLife     string    `json:"shelf_life"`
-	Preservation  string    `json:"preservation"`
-	PurchasePrice Decimal   `json:"purchase_price"`
-	SalePrice     Decimal   `json:"sale_price"`
-	Stock         int       `json:"stock"`
-	IsActive      bool      `json:"is_active"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	SKU           string  `json:"sku"`
+	Name          string  `json:"name"`
+	Origin        string  `json:"origin"`
+	ShelfLife     string  `json:"shelf_life"`
+	Preservation  string  `json:"preservation"`
+	PurchasePrice Decimal `json:"purchase_price"`
+	SalePrice     Decimal `json:"sale_price"`
+	// DiscountPrice 促销价，DiscountUntil 之前生效（为 nil 时长期有效）；为 nil 表示无促销
+	DiscountPrice *Decimal   `json:"discount_price,omitempty"`
+	DiscountUntil *time.Time `json:"discount_until,omitempty"`
+	Stock         int        `json:"stock"`
+	MaxOrderQty   *int       `json:"max_order_qty"` // 单笔订单限购数量，nil 表示不限购
+	IsActive      bool       `json:"is_active"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// MarshalJSON 在格式化金额（purchase_price/sale_price）之外，同时输出以分为单位的整数金额
+func (f Flower) MarshalJSON() ([]byte, error) {
+	type flowerJSON Flower
+	return json.Marshal(struct {
+		flowerJSON
+		PurchasePriceCents int64 `json:"purchase_price_cents"`
+		SalePriceCents     int64 `json:"sale_price_cents"`
+	}{
+		flowerJSON:         flowerJSON(f),
+		PurchasePriceCents: f.PurchasePrice.Cents(),
+		SalePriceCents:     f.SalePrice.Cents(),
+	})
 }
 
+// 按毛利排序，仅管理视图可用，避免公开列表泄露成本信息
+const (
+	SortMarginAsc  = "margin_asc"
+	SortMarginDesc = "margin_desc"
+)
+
 // FlowerFilter 表示鲜花列表查询的筛选条件
 type FlowerFilter struct {
-	Search   string  // 按 sku/name 搜索
+	Search   string  // 关键词搜索 sku/name/产地/保存方式，空格分隔的多个关键词须同时匹配
 	Origin   string  // 按产地筛选
 	MinPrice float64 // 最低价
 	MaxPrice float64 // 最高价
-	SortBy   string  // price_asc, price_desc, stock
-	Page     int
-	PageSize int
+	SortBy   string  // price_asc, price_desc, stock；管理视图另支持 margin_asc, margin_desc
+	InStock  bool    // 仅显示有库存（stock > 0）
+	// IncludeInactive 同时返回已下架鲜花，默认只返回上架鲜花
+	IncludeInactive bool
+	// LowStockThreshold 覆盖服务的库存预警阈值，0 表示使用服务配置
+	LowStockThreshold int
+	Page              int
+	PageSize          int
+	// LookAhead 分页时多取一行，用于判断是否还有下一页
+	LookAhead bool
 }
 
 // NewFlower 创建一个新的鲜花实体
@@ -52,23 +85,45 @@ func NewFlower(sku, name, origin, shelfLife, preservation string, purchasePrice,
 // Validate 验证鲜花数据是否有效
 func (f *Flower) Validate() error {
 	if f.SKU == "" {
-		return fmt.Errorf("SKU不能为空")
+		return apperror.Validation("SKU不能为空")
 	}
 	if f.Name == "" {
-		return fmt.Errorf("名称不能为空")
+		return apperror.Validation("名称不能为空")
 	}
 	if f.Origin == "" {
-		return fmt.Errorf("产地不能为空")
+		return apperror.Validation("产地不能为空")
 	}
 	if f.SalePrice.LessThan(f.PurchasePrice) {
-		return fmt.Errorf("销售价格不能低于进货价格")
+		return apperror.Validation("销售价格不能低于进货价格")
 	}
 	if f.Stock < 0 {
-		return fmt.Errorf("库存不能为负数")
+		return apperror.Validation("库存不能为负数")
+	}
+	if f.MaxOrderQty != nil && *f.MaxOrderQty <= 0 {
+		return apperror.Validation("限购数量必须大于0")
+	}
+	if f.DiscountPrice != nil && (f.DiscountPrice.Value <= 0 || !f.DiscountPrice.LessThan(f.SalePrice)) {
+		return apperror.Validation("促销价必须大于0且低于销售价格")
+	}
+	if f.DiscountPrice == nil && f.DiscountUntil != nil {
+		return apperror.Validation("设置促销截止时间时必须设置促销价")
 	}
 	return nil
 }
 
+// EffectivePrice 返回当前实际售价：促销有效时为促销价，否则为销售价格
+func (f *Flower) EffectivePrice() Decimal {
+	return f.EffectivePriceAt(time.Now())
+}
+
+// EffectivePriceAt 返回 now 时刻的实际售价，促销在 DiscountUntil 时刻结束
+func (f *Flower) EffectivePriceAt(now time.Time) Decimal {
+	if f.DiscountPrice != nil && (f.DiscountUntil == nil || now.Before(*f.DiscountUntil)) {
+		return *f.DiscountPrice
+	}
+	return f.SalePrice
+}
+
 // IsLowStock 判断库存是否低于或等于阈值
 func (f *Flower) IsLowStock(threshold int) bool {
 	return f.Stock <= threshold
@@ -77,16 +132,19 @@ func (f *Flower) IsLowStock(threshold int) bool {
 // Validate 验证筛选条件是否有效
 func (f *FlowerFilter) Validate() error {
 	if f.MinPrice > f.MaxPrice && f.MaxPrice > 0 {
-		return fmt.Errorf("最低价格不能高于最高价格")
+		return apperror.Validation("最低价格不能高于最高价格")
 	}
 	// Page 和 PageSize 为 0 时表示不分页（返回所有结果）
 	// 如果设置了分页，则必须大于 0
 	if f.Page < 0 || f.PageSize < 0 {
-		return fmt.Errorf("页码和每页数量不能为负数")
+		return apperror.Validation("页码和每页数量不能为负数")
 	}
 	// 如果其中一个设置了，另一个也必须设置
 	if (f.Page > 0 && f.PageSize == 0) || (f.Page == 0 && f.PageSize > 0) {
-		return fmt.Errorf("页码和每页数量必须同时设置")
+		return apperror.Validation("页码和每页数量必须同时设置")
+	}
+	if f.LowStockThreshold < 0 {
+		return apperror.Validation("库存预警阈值不能为负数")
 	}
 	return nil
 }
@@ -1,7 +1,10 @@
 package flower
 
 import (
+	"encoding/json"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestFlowerValidation(t *testing.T) {
@@ -14,13 +17,13 @@ func TestFlowerValidation(t *testing.T) {
 		{
 			name: "有效的鲜花",
 			flower: Flower{
-				SKU:          "FLW001",
-				Name:         "红玫瑰",
-				Origin:       "云南",
-				ShelfLife:    "7天",
-				Preservation: "常温",
+				SKU:           "FLW001",
+				Name:          "红玫瑰",
+				Origin:        "云南",
+				ShelfLife:     "7天",
+				Preservation:  "常温",
 				PurchasePrice: Decimal{Value: 5000},  // 50.00
-				SalePrice:     Decimal{Value: 10000},  // 100.00
+				SalePrice:     Decimal{Value: 10000}, // 100.00
 				Stock:         100,
 				IsActive:      true,
 			},
@@ -29,11 +32,11 @@ func TestFlowerValidation(t *testing.T) {
 		{
 			name: "SKU为空",
 			flower: Flower{
-				SKU:          "",
-				Name:         "红玫瑰",
-				Origin:       "云南",
-				ShelfLife:    "7天",
-				Preservation: "常温",
+				SKU:           "",
+				Name:          "红玫瑰",
+				Origin:        "云南",
+				ShelfLife:     "7天",
+				Preservation:  "常温",
 				PurchasePrice: Decimal{Value: 5000},
 				SalePrice:     Decimal{Value: 10000},
 				Stock:         100,
@@ -45,11 +48,11 @@ func TestFlowerValidation(t *testing.T) {
 		{
 			name: "名称为空",
 			flower: Flower{
-				SKU:          "FLW001",
-				Name:         "",
-				Origin:       "云南",
-				ShelfLife:    "7天",
-				Preservation: "常温",
+				SKU:           "FLW001",
+				Name:          "",
+				Origin:        "云南",
+				ShelfLife:     "7天",
+				Preservation:  "常温",
 				PurchasePrice: Decimal{Value: 5000},
 				SalePrice:     Decimal{Value: 10000},
 				Stock:         100,
@@ -61,11 +64,11 @@ func TestFlowerValidation(t *testing.T) {
 		{
 			name: "产地为空",
 			flower: Flower{
-				SKU:          "FLW001",
-				Name:         "红玫瑰",
-				Origin:       "",
-				ShelfLife:    "7天",
-				Preservation: "常温",
+				SKU:           "FLW001",
+				Name:          "红玫瑰",
+				Origin:        "",
+				ShelfLife:     "7天",
+				Preservation:  "常温",
 				PurchasePrice: Decimal{Value: 5000},
 				SalePrice:     Decimal{Value: 10000},
 				Stock:         100,
@@ -77,13 +80,13 @@ func TestFlowerValidation(t *testing.T) {
 		{
 			name: "销售价格低于进货价格",
 			flower: Flower{
-				SKU:          "FLW001",
-				Name:         "红玫瑰",
-				Origin:       "云南",
-				ShelfLife:    "7天",
-				Preservation: "常温",
-				PurchasePrice: Decimal{Value: 10000},  // 100.00
-				SalePrice:     Decimal{Value: 5000},   // 50.00
+				SKU:           "FLW001",
+				Name:          "红玫瑰",
+				Origin:        "云南",
+				ShelfLife:     "7天",
+				Preservation:  "常温",
+				PurchasePrice: Decimal{Value: 10000}, // 100.00
+				SalePrice:     Decimal{Value: 5000},  // 50.00
 				Stock:         100,
 				IsActive:      true,
 			},
@@ -93,11 +96,11 @@ func TestFlowerValidation(t *testing.T) {
 		{
 			name: "库存为负数",
 			flower: Flower{
-				SKU:          "FLW001",
-				Name:         "红玫瑰",
-				Origin:       "云南",
-				ShelfLife:    "7天",
-				Preservation: "常温",
+				SKU:           "FLW001",
+				Name:          "红玫瑰",
+				Origin:        "云南",
+				ShelfLife:     "7天",
+				Preservation:  "常温",
 				PurchasePrice: Decimal{Value: 5000},
 				SalePrice:     Decimal{Value: 10000},
 				Stock:         -10,
@@ -124,10 +127,10 @@ func TestFlowerValidation(t *testing.T) {
 
 func TestFlowerIsLowStock(t *testing.T) {
 	tests := []struct {
-		name           string
-		flower         Flower
-		threshold      int
-		wantLowStock   bool
+		name         string
+		flower       Flower
+		threshold    int
+		wantLowStock bool
 	}{
 		{
 			name: "库存充足",
@@ -177,6 +180,63 @@ func TestFlowerIsLowStock(t *testing.T) {
 	}
 }
 
+// TestFlowerEffectivePrice 测试促销有效、已过期与无促销时的实际售价
+func TestFlowerEffectivePrice(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	discount := Decimal{Value: 8000}
+	future := now.Add(24 * time.Hour)
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name   string
+		flower Flower
+		want   int64
+	}{
+		{name: "无促销", flower: Flower{SalePrice: Decimal{Value: 10000}}, want: 10000},
+		{name: "促销有效", flower: Flower{SalePrice: Decimal{Value: 10000}, DiscountPrice: &discount, DiscountUntil: &future}, want: 8000},
+		{name: "促销长期有效", flower: Flower{SalePrice: Decimal{Value: 10000}, DiscountPrice: &discount}, want: 8000},
+		{name: "促销已过期", flower: Flower{SalePrice: Decimal{Value: 10000}, DiscountPrice: &discount, DiscountUntil: &past}, want: 10000},
+		{name: "截止时刻促销结束", flower: Flower{SalePrice: Decimal{Value: 10000}, DiscountPrice: &discount, DiscountUntil: &now}, want: 10000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.flower.EffectivePriceAt(now); got.Value != tt.want {
+				t.Errorf("EffectivePriceAt() = %d, want %d", got.Value, tt.want)
+			}
+		})
+	}
+}
+
+// TestFlowerValidation_Discount 测试促销价必须大于 0 且低于销售价格
+func TestFlowerValidation_Discount(t *testing.T) {
+	until := time.Now().Add(time.Hour)
+	tests := []struct {
+		name     string
+		discount *Decimal
+		until    *time.Time
+		wantErr  bool
+	}{
+		{name: "无促销", wantErr: false},
+		{name: "促销价低于售价", discount: &Decimal{Value: 8000}, until: &until, wantErr: false},
+		{name: "促销价等于售价", discount: &Decimal{Value: 10000}, wantErr: true},
+		{name: "促销价高于售价", discount: &Decimal{Value: 12000}, wantErr: true},
+		{name: "促销价为零", discount: &Decimal{Value: 0}, wantErr: true},
+		{name: "只有截止时间", until: &until, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFlower("FLW001", "红玫瑰", "云南", "7天", "常温", 50.00, 100.00, 10)
+			f.DiscountPrice = tt.discount
+			f.DiscountUntil = tt.until
+			if err := f.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestFlowerFilter(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -184,9 +244,9 @@ func TestFlowerFilter(t *testing.T) {
 		valid  bool
 	}{
 		{
-			name: "有效的空筛选条件",
+			name:   "有效的空筛选条件",
 			filter: FlowerFilter{},
-			valid: true,
+			valid:  true,
 		},
 		{
 			name: "有效的搜索条件",
@@ -310,3 +370,51 @@ func TestNewFlower(t *testing.T) {
 		t.Errorf("NewFlower() UpdatedAt should be set")
 	}
 }
+
+// TestFlowerMarshalJSON 测试鲜花 JSON 同时包含格式化金额与分
+func TestFlowerMarshalJSON(t *testing.T) {
+	f := NewFlower("FLW001", "红玫瑰", "云南", "7天", "冷藏", 5.5, 12.8, 100)
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	tests := []struct {
+		formattedKey string
+		centsKey     string
+		wantFormat   string
+		wantCents    float64
+	}{
+		{"purchase_price", "purchase_price_cents", "5.50", 550},
+		{"sale_price", "sale_price_cents", "12.80", 1280},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.formattedKey, func(t *testing.T) {
+			formatted, ok := got[tt.formattedKey].(string)
+			if !ok || formatted != tt.wantFormat {
+				t.Errorf("%s = %v, want %q", tt.formattedKey, got[tt.formattedKey], tt.wantFormat)
+			}
+			cents, ok := got[tt.centsKey].(float64)
+			if !ok || cents != tt.wantCents {
+				t.Errorf("%s = %v, want %v", tt.centsKey, got[tt.centsKey], tt.wantCents)
+			}
+
+			// 两种表示必须一致
+			yuan, err := strconv.ParseFloat(formatted, 64)
+			if err != nil || int64(yuan*100+0.5) != int64(cents) {
+				t.Errorf("%s (%s) inconsistent with %s (%v)", tt.formattedKey, formatted, tt.centsKey, cents)
+			}
+		})
+	}
+
+	if got["sku"] != "FLW001" || got["stock"] != float64(100) {
+		t.Errorf("other fields lost in JSON: %s", data)
+	}
+}
@@ -0,0 +1,45 @@
+package flower
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 鲜花审计日志的操作类型
+const (
+	LogActionUpdate     = "update"     // 修改鲜花信息或价格
+	LogActionAddStock   = "add_stock"  // 进货入库
+	LogActionDeactivate = "deactivate" // 下架
+	LogActionActivate   = "activate"   // 重新上架
+)
+
+// FlowerLog 鲜花变更审计日志，记录操作人及变更前后的字段值
+type FlowerLog struct {
+	ID         int             `json:"id"`
+	SKU        string          `json:"sku"`
+	OperatorID int             `json:"operator_id"`
+	Action     string          `json:"action"`
+	OldValue   json.RawMessage `json:"old_value"` // 变更前的字段值，如 {"stock":100}
+	NewValue   json.RawMessage `json:"new_value"` // 变更后的字段值，如 {"stock":120}
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// NewFlowerLog 创建鲜花审计日志，oldValue/newValue 只包含发生变化的字段
+func NewFlowerLog(sku string, operatorID int, action string, oldValue, newValue map[string]interface{}) (*FlowerLog, error) {
+	oldJSON, err := json.Marshal(oldValue)
+	if err != nil {
+		return nil, err
+	}
+	newJSON, err := json.Marshal(newValue)
+	if err != nil {
+		return nil, err
+	}
+	return &FlowerLog{
+		SKU:        sku,
+		OperatorID: operatorID,
+		Action:     action,
+		OldValue:   oldJSON,
+		NewValue:   newJSON,
+		CreatedAt:  time.Now(),
+	}, nil
+}
@@ -0,0 +1,82 @@
+package flower
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// FlowerLogRepository 定义鲜花审计日志数据访问接口
+type FlowerLogRepository interface {
+	CreateLog(ctx context.Context, log *FlowerLog) error
+	GetLogs(ctx context.Context, sku string) ([]*FlowerLog, error)
+}
+
+// flowerLogRepository 实现 FlowerLogRepository 接口
+type flowerLogRepository struct {
+	db *sql.DB
+}
+
+// NewFlowerLogRepository 创建 FlowerLogRepository 实例
+func NewFlowerLogRepository(db *sql.DB) FlowerLogRepository {
+	return &flowerLogRepository{db: db}
+}
+
+// CreateLog 创建鲜花审计日志
+func (r *flowerLogRepository) CreateLog(ctx context.Context, log *FlowerLog) error {
+	query := `
+		INSERT INTO flower_logs (sku, operator_id, action, old_value, new_value, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		log.SKU, log.OperatorID, log.Action, string(log.OldValue), string(log.NewValue), log.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("create flower log: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("get last insert id: %w", err)
+	}
+
+	log.ID = int(id)
+	return nil
+}
+
+// GetLogs 获取鲜花的审计日志，按记录时间倒序（最新在前）
+func (r *flowerLogRepository) GetLogs(ctx context.Context, sku string) ([]*FlowerLog, error) {
+	query := `
+		SELECT id, sku, operator_id, action, old_value, new_value, created_at
+		FROM flower_logs WHERE sku = ?
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, sku)
+	if err != nil {
+		return nil, fmt.Errorf("get flower logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []*FlowerLog{}
+	for rows.Next() {
+		var log FlowerLog
+		var oldValue, newValue string
+
+		if err := rows.Scan(&log.ID, &log.SKU, &log.OperatorID, &log.Action, &oldValue, &newValue, &log.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan flower log: %w", err)
+		}
+
+		log.OldValue = []byte(oldValue)
+		log.NewValue = []byte(newValue)
+
+		logs = append(logs, &log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate flower logs: %w", err)
+	}
+
+	return logs, nil
+}
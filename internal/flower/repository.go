@@ -4,17 +4,41 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 )
 
+// ErrFlowerNotFound 鲜花不存在
+var ErrFlowerNotFound = apperror.New(apperror.KindNotFound, "flower not found")
+
+// ErrInsufficientStock 库存不足，扣减被拒绝
+var ErrInsufficientStock = apperror.New(apperror.KindValidation, "库存不足")
+
 // FlowerRepository 定义鲜花数据访问接口
 type FlowerRepository interface {
 	Create(ctx context.Context, f *Flower) error
 	GetBySKU(ctx context.Context, sku string) (*Flower, error)
+	GetBySKUs(ctx context.Context, skus []string) (map[string]*Flower, error)
+	ListActiveSKUsByName(ctx context.Context, name string) ([]string, error)
 	List(ctx context.Context, filter FlowerFilter) ([]*Flower, error)
+	Count(ctx context.Context, filter FlowerFilter) (int, error)
+	ListLowStock(ctx context.Context, threshold int) ([]*Flower, error)
 	Update(ctx context.Context, f *Flower) error
 	Delete(ctx context.Context, sku string) error
+	Deactivate(ctx context.Context, sku string) error
+	Activate(ctx context.Context, sku string) error
 	UpdateStock(ctx context.Context, sku string, delta int) error
+	UpdateStockTx(ctx context.Context, tx *sql.Tx, sku string, delta int) error
+	GetStockTx(ctx context.Context, tx *sql.Tx, sku string) (int, error)
+	BeginTx(ctx context.Context) (*sql.Tx, error)
+	DeductStockTx(ctx context.Context, tx *sql.Tx, sku string, quantity int) error
+}
+
+// execer 可执行写操作的对象（*sql.DB 或 *sql.Tx）
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
 // flowerRepository 实现 FlowerRepository 接口
@@ -35,8 +59,8 @@ func (r *flowerRepository) Create(ctx context.Context, f *Flower) error {
 
 	query := `
 		INSERT INTO flowers (sku, name, origin, shelf_life, preservation,
-			purchase_price, sale_price, stock, is_active, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			purchase_price, sale_price, discount_price, discount_until, stock, max_order_qty, is_active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	// SQLite 使用 1/0 表示布尔值，MySQL 使用 TRUE/FALSE
@@ -47,8 +71,8 @@ func (r *flowerRepository) Create(ctx context.Context, f *Flower) error {
 
 	result, err := r.db.ExecContext(ctx, query,
 		f.SKU, f.Name, f.Origin, f.ShelfLife, f.Preservation,
-		f.PurchasePrice.Value, f.SalePrice.Value, f.Stock, isActive,
-		f.CreatedAt, f.UpdatedAt,
+		f.PurchasePrice.Value, f.SalePrice.Value, decimalValue(f.DiscountPrice), f.DiscountUntil,
+		f.Stock, f.MaxOrderQty, isActive, f.CreatedAt, f.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("create flower: %w", err)
@@ -69,22 +93,24 @@ func (r *flowerRepository) Create(ctx context.Context, f *Flower) error {
 func (r *flowerRepository) GetBySKU(ctx context.Context, sku string) (*Flower, error) {
 	query := `
 		SELECT sku, name, origin, shelf_life, preservation,
-			purchase_price, sale_price, stock, is_active, created_at, updated_at
+			purchase_price, sale_price, discount_price, discount_until, stock, max_order_qty, is_active, created_at, updated_at
 		FROM flowers WHERE sku = ?
 	`
 
 	var f Flower
 	var isActive int
 	var purchasePrice, salePrice int64
+	var maxOrderQty, discountPrice sql.NullInt64
+	var discountUntil sql.NullTime
 
 	err := r.db.QueryRowContext(ctx, query, sku).Scan(
 		&f.SKU, &f.Name, &f.Origin, &f.ShelfLife, &f.Preservation,
-		&purchasePrice, &salePrice, &f.Stock, &isActive,
+		&purchasePrice, &salePrice, &discountPrice, &discountUntil, &f.Stock, &maxOrderQty, &isActive,
 		&f.CreatedAt, &f.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("flower not found: %s", sku)
+		return nil, fmt.Errorf("%w: %s", ErrFlowerNotFound, sku)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get flower by sku: %w", err)
@@ -93,43 +119,87 @@ func (r *flowerRepository) GetBySKU(ctx context.Context, sku string) (*Flower, e
 	f.PurchasePrice = Decimal{Value: purchasePrice}
 	f.SalePrice = Decimal{Value: salePrice}
 	f.IsActive = isActive != 0
+	f.MaxOrderQty = nullIntPtr(maxOrderQty)
+	f.DiscountPrice = nullDecimalPtr(discountPrice)
+	f.DiscountUntil = nullTimePtr(discountUntil)
 
 	return &f, nil
 }
 
-// List 根据筛选条件获取鲜花列表
-func (r *flowerRepository) List(ctx context.Context, filter FlowerFilter) ([]*Flower, error) {
+// GetBySKUs 批量获取鲜花，单条 IN 查询，返回以 SKU 为键的映射
+// 不存在的 SKU 不出现在结果中，由调用方判断；skus 为空时不查询
+func (r *flowerRepository) GetBySKUs(ctx context.Context, skus []string) (map[string]*Flower, error) {
+	result := make(map[string]*Flower, len(skus))
+	if len(skus) == 0 {
+		return result, nil
+	}
+
+	placeholders := make([]string, 0, len(skus))
+	args := make([]interface{}, 0, len(skus))
+	seen := make(map[string]bool, len(skus))
+	for _, sku := range skus {
+		if seen[sku] {
+			continue
+		}
+		seen[sku] = true
+		placeholders = append(placeholders, "?")
+		args = append(args, sku)
+	}
+
 	query := `
 		SELECT sku, name, origin, shelf_life, preservation,
-			purchase_price, sale_price, stock, is_active, created_at, updated_at
-		FROM flowers WHERE 1=1
-	`
-	args := []interface{}{}
+			purchase_price, sale_price, discount_price, discount_until, stock, max_order_qty, is_active, created_at, updated_at
+		FROM flowers WHERE sku IN (` + strings.Join(placeholders, ", ") + `)`
 
-	// 搜索条件
-	if filter.Search != "" {
-		query += " AND (sku LIKE ? OR name LIKE ?)"
-		searchPattern := "%" + filter.Search + "%"
-		args = append(args, searchPattern, searchPattern)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get flowers by skus: %w", err)
 	}
+	defer rows.Close()
 
-	// 产地筛选
-	if filter.Origin != "" {
-		query += " AND origin = ?"
-		args = append(args, filter.Origin)
+	flowers, err := scanFlowers(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range flowers {
+		result[f.SKU] = f
+	}
+	return result, nil
+}
+
+// ListActiveSKUsByName 根据名称精确查找已上架鲜花的 SKU
+func (r *flowerRepository) ListActiveSKUsByName(ctx context.Context, name string) ([]string, error) {
+	query := `SELECT sku FROM flowers WHERE name = ? AND is_active = 1 ORDER BY sku`
+
+	rows, err := r.db.QueryContext(ctx, query, name)
+	if err != nil {
+		return nil, fmt.Errorf("list skus by name: %w", err)
 	}
+	defer rows.Close()
 
-	// 价格区间筛选
-	if filter.MinPrice > 0 {
-		// 将元转换为分
-		query += " AND sale_price >= ?"
-		args = append(args, int64(filter.MinPrice*100))
+	var skus []string
+	for rows.Next() {
+		var sku string
+		if err := rows.Scan(&sku); err != nil {
+			return nil, fmt.Errorf("scan sku: %w", err)
+		}
+		skus = append(skus, sku)
 	}
-	if filter.MaxPrice > 0 {
-		query += " AND sale_price <= ?"
-		args = append(args, int64(filter.MaxPrice*100))
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate skus: %w", err)
 	}
 
+	return skus, nil
+}
+
+// List 根据筛选条件获取鲜花列表
+func (r *flowerRepository) List(ctx context.Context, filter FlowerFilter) ([]*Flower, error) {
+	where, args := buildFlowerWhere(filter)
+	query := `
+		SELECT sku, name, origin, shelf_life, preservation,
+			purchase_price, sale_price, discount_price, discount_until, stock, max_order_qty, is_active, created_at, updated_at
+		FROM flowers` + where
+
 	// 排序
 	switch filter.SortBy {
 	case "price_asc":
@@ -138,6 +208,10 @@ func (r *flowerRepository) List(ctx context.Context, filter FlowerFilter) ([]*Fl
 		query += " ORDER BY sale_price DESC"
 	case "stock":
 		query += " ORDER BY stock ASC"
+	case SortMarginAsc:
+		query += " ORDER BY sale_price - purchase_price ASC, sku ASC"
+	case SortMarginDesc:
+		query += " ORDER BY sale_price - purchase_price DESC, sku ASC"
 	default:
 		query += " ORDER BY created_at DESC"
 	}
@@ -145,8 +219,12 @@ func (r *flowerRepository) List(ctx context.Context, filter FlowerFilter) ([]*Fl
 	// 分页
 	if filter.Page > 0 && filter.PageSize > 0 {
 		offset := (filter.Page - 1) * filter.PageSize
+		limit := filter.PageSize
+		if filter.LookAhead {
+			limit++
+		}
 		query += " LIMIT ? OFFSET ?"
-		args = append(args, filter.PageSize, offset)
+		args = append(args, limit, offset)
 	}
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -155,43 +233,108 @@ func (r *flowerRepository) List(ctx context.Context, filter FlowerFilter) ([]*Fl
 	}
 	defer rows.Close()
 
+	return scanFlowers(rows)
+}
+
+// ListLowStock 获取库存低于或等于阈值的上架鲜花，按库存升序排列（库存相同按 SKU）
+// 仅查询预警所需的 SKU、名称与库存
+func (r *flowerRepository) ListLowStock(ctx context.Context, threshold int) ([]*Flower, error) {
+	query := `SELECT sku, name, stock FROM flowers WHERE is_active = 1 AND stock <= ? ORDER BY stock ASC, sku ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, threshold)
+	if err != nil {
+		return nil, fmt.Errorf("list low stock flowers: %w", err)
+	}
+	defer rows.Close()
+
 	var flowers []*Flower
 	for rows.Next() {
 		var f Flower
-		var isActive int
-		var purchasePrice, salePrice int64
+		if err := rows.Scan(&f.SKU, &f.Name, &f.Stock); err != nil {
+			return nil, fmt.Errorf("scan low stock flower: %w", err)
+		}
+		f.IsActive = true
+		flowers = append(flowers, &f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate low stock flowers: %w", err)
+	}
 
-		err := rows.Scan(
-			&f.SKU, &f.Name, &f.Origin, &f.ShelfLife, &f.Preservation,
-			&purchasePrice, &salePrice, &f.Stock, &isActive,
-			&f.CreatedAt, &f.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("scan flower: %w", err)
+	return flowers, nil
+}
+
+// Count 统计符合筛选条件的鲜花数量（忽略分页和排序）
+func (r *flowerRepository) Count(ctx context.Context, filter FlowerFilter) (int, error) {
+	where, args := buildFlowerWhere(filter)
+	query := "SELECT COUNT(*) FROM flowers" + where
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count flowers: %w", err)
+	}
+	return count, nil
+}
+
+// searchFields 关键词搜索匹配的字段
+var searchFields = []string{"sku", "name", "origin", "preservation"}
+
+// searchClause 单个关键词的匹配条件，任一字段包含关键词即匹配
+var searchClause = strings.Join(searchFields, " LIKE ? OR ") + " LIKE ?"
+
+// buildFlowerWhere 根据筛选条件构建 WHERE 子句，List 与 Count 共用
+func buildFlowerWhere(filter FlowerFilter) (string, []interface{}) {
+	query := " WHERE 1=1"
+	args := []interface{}{}
+
+	// 搜索条件：按空白拆分关键词，每个关键词须匹配任一搜索字段
+	for _, term := range strings.Fields(filter.Search) {
+		query += " AND (" + searchClause + ")"
+		searchPattern := "%" + term + "%"
+		for range searchFields {
+			args = append(args, searchPattern)
 		}
+	}
 
-		f.PurchasePrice = Decimal{Value: purchasePrice}
-		f.SalePrice = Decimal{Value: salePrice}
-		f.IsActive = isActive != 0
+	// 产地筛选
+	if filter.Origin != "" {
+		query += " AND origin = ?"
+		args = append(args, filter.Origin)
+	}
 
-		flowers = append(flowers, &f)
+	// 价格区间筛选
+	if filter.MinPrice > 0 {
+		// 将元转换为分
+		query += " AND sale_price >= ?"
+		args = append(args, int64(filter.MinPrice*100))
+	}
+	if filter.MaxPrice > 0 {
+		query += " AND sale_price <= ?"
+		args = append(args, int64(filter.MaxPrice*100))
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate flowers: %w", err)
+	// 库存筛选
+	if filter.InStock {
+		query += " AND stock > 0"
 	}
 
-	return flowers, nil
+	// 默认只返回上架鲜花
+	if !filter.IncludeInactive {
+		query += " AND is_active = 1"
+	}
+
+	return query, args
 }
 
 // Update 更新鲜花信息
+// 不写入 stock：库存只通过 UpdateStock 增量修改，避免用读取时的旧值覆盖并发的库存变动
 func (r *flowerRepository) Update(ctx context.Context, f *Flower) error {
 	f.UpdatedAt = time.Now()
 
 	query := `
 		UPDATE flowers SET
 			name = ?, origin = ?, shelf_life = ?, preservation = ?,
-			purchase_price = ?, sale_price = ?, stock = ?, is_active = ?,
+			purchase_price = ?, sale_price = ?, discount_price = ?, discount_until = ?,
+			max_order_qty = ?, is_active = ?,
 			updated_at = ?
 		WHERE sku = ?
 	`
@@ -203,8 +346,8 @@ func (r *flowerRepository) Update(ctx context.Context, f *Flower) error {
 
 	result, err := r.db.ExecContext(ctx, query,
 		f.Name, f.Origin, f.ShelfLife, f.Preservation,
-		f.PurchasePrice.Value, f.SalePrice.Value, f.Stock, isActive,
-		f.UpdatedAt, f.SKU,
+		f.PurchasePrice.Value, f.SalePrice.Value, decimalValue(f.DiscountPrice), f.DiscountUntil,
+		f.MaxOrderQty, isActive, f.UpdatedAt, f.SKU,
 	)
 	if err != nil {
 		return fmt.Errorf("update flower: %w", err)
@@ -215,13 +358,13 @@ func (r *flowerRepository) Update(ctx context.Context, f *Flower) error {
 		return fmt.Errorf("check rows affected: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("flower not found: %s", f.SKU)
+		return fmt.Errorf("%w: %s", ErrFlowerNotFound, f.SKU)
 	}
 
 	return nil
 }
 
-// Delete 删除鲜花
+// Delete 物理删除鲜花，历史订单引用的 SKU 将无法再解析，一般应使用 Deactivate
 func (r *flowerRepository) Delete(ctx context.Context, sku string) error {
 	query := `DELETE FROM flowers WHERE sku = ?`
 
@@ -235,7 +378,41 @@ func (r *flowerRepository) Delete(ctx context.Context, sku string) error {
 		return fmt.Errorf("check rows affected: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("flower not found: %s", sku)
+		return fmt.Errorf("%w: %s", ErrFlowerNotFound, sku)
+	}
+
+	return nil
+}
+
+// Deactivate 下架鲜花（软删除），数据保留以便历史订单继续解析该 SKU
+func (r *flowerRepository) Deactivate(ctx context.Context, sku string) error {
+	return r.setActive(ctx, sku, false)
+}
+
+// Activate 重新上架鲜花
+func (r *flowerRepository) Activate(ctx context.Context, sku string) error {
+	return r.setActive(ctx, sku, true)
+}
+
+// setActive 更新鲜花的上架状态
+func (r *flowerRepository) setActive(ctx context.Context, sku string, active bool) error {
+	isActive := 0
+	if active {
+		isActive = 1
+	}
+
+	query := `UPDATE flowers SET is_active = ?, updated_at = ? WHERE sku = ?`
+	result, err := r.db.ExecContext(ctx, query, isActive, time.Now(), sku)
+	if err != nil {
+		return fmt.Errorf("set flower active: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("%w: %s", ErrFlowerNotFound, sku)
 	}
 
 	return nil
@@ -243,9 +420,71 @@ func (r *flowerRepository) Delete(ctx context.Context, sku string) error {
 
 // UpdateStock 更新库存（增量更新）
 func (r *flowerRepository) UpdateStock(ctx context.Context, sku string, delta int) error {
+	return updateStock(ctx, r.db, sku, delta)
+}
+
+// UpdateStockTx 在调用方事务中更新库存，提交或回滚由调用方负责
+func (r *flowerRepository) UpdateStockTx(ctx context.Context, tx *sql.Tx, sku string, delta int) error {
+	return updateStock(ctx, tx, sku, delta)
+}
+
+// GetStockTx 在调用方事务中读取库存，鲜花不存在时返回 ErrFlowerNotFound
+func (r *flowerRepository) GetStockTx(ctx context.Context, tx *sql.Tx, sku string) (int, error) {
+	var stock int
+	err := tx.QueryRowContext(ctx, `SELECT stock FROM flowers WHERE sku = ?`, sku).Scan(&stock)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: %s", ErrFlowerNotFound, sku)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get stock: %w", err)
+	}
+	return stock, nil
+}
+
+// BeginTx 开启事务，供需要多个库存变更保持原子性的操作使用
+func (r *flowerRepository) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// DeductStockTx 在调用方事务中扣减库存
+// 扣减与库存校验在同一条 UPDATE 中完成，并发下单同一 SKU 时不会超卖；库存不足返回 ErrInsufficientStock
+func (r *flowerRepository) DeductStockTx(ctx context.Context, tx *sql.Tx, sku string, quantity int) error {
+	query := `UPDATE flowers SET stock = stock - ?, updated_at = ? WHERE sku = ? AND stock >= ?`
+
+	result, err := tx.ExecContext(ctx, query, quantity, time.Now(), sku, quantity)
+	if err != nil {
+		return fmt.Errorf("deduct stock: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows > 0 {
+		return nil
+	}
+
+	// 未更新任何行：区分鲜花不存在与库存不足
+	var stock int
+	err = tx.QueryRowContext(ctx, `SELECT stock FROM flowers WHERE sku = ?`, sku).Scan(&stock)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %s", ErrFlowerNotFound, sku)
+	}
+	if err != nil {
+		return fmt.Errorf("get stock: %w", err)
+	}
+	return fmt.Errorf("%w: %s (库存: %d, 需要: %d)", ErrInsufficientStock, sku, stock, quantity)
+}
+
+// updateStock 执行库存增量更新
+func updateStock(ctx context.Context, exec execer, sku string, delta int) error {
 	query := `UPDATE flowers SET stock = stock + ?, updated_at = ? WHERE sku = ?`
 
-	result, err := r.db.ExecContext(ctx, query, delta, time.Now(), sku)
+	result, err := exec.ExecContext(ctx, query, delta, time.Now(), sku)
 	if err != nil {
 		return fmt.Errorf("update stock: %w", err)
 	}
@@ -255,8 +494,77 @@ func (r *flowerRepository) UpdateStock(ctx context.Context, sku string, delta in
 		return fmt.Errorf("check rows affected: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("flower not found: %s", sku)
+		return fmt.Errorf("%w: %s", ErrFlowerNotFound, sku)
 	}
 
 	return nil
 }
+
+// scanFlowers 读取完整鲜花列的查询结果
+func scanFlowers(rows *sql.Rows) ([]*Flower, error) {
+	var flowers []*Flower
+	for rows.Next() {
+		var f Flower
+		var isActive int
+		var purchasePrice, salePrice int64
+		var maxOrderQty, discountPrice sql.NullInt64
+		var discountUntil sql.NullTime
+
+		err := rows.Scan(
+			&f.SKU, &f.Name, &f.Origin, &f.ShelfLife, &f.Preservation,
+			&purchasePrice, &salePrice, &discountPrice, &discountUntil, &f.Stock, &maxOrderQty, &isActive,
+			&f.CreatedAt, &f.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan flower: %w", err)
+		}
+
+		f.PurchasePrice = Decimal{Value: purchasePrice}
+		f.SalePrice = Decimal{Value: salePrice}
+		f.IsActive = isActive != 0
+		f.MaxOrderQty = nullIntPtr(maxOrderQty)
+		f.DiscountPrice = nullDecimalPtr(discountPrice)
+		f.DiscountUntil = nullTimePtr(discountUntil)
+
+		flowers = append(flowers, &f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate flowers: %w", err)
+	}
+
+	return flowers, nil
+}
+
+// nullIntPtr 将可空整数列转换为 *int，NULL 返回 nil
+func nullIntPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
+// nullDecimalPtr 将可空金额列（分）转换为 *Decimal，NULL 时返回 nil
+func nullDecimalPtr(n sql.NullInt64) *Decimal {
+	if !n.Valid {
+		return nil
+	}
+	return &Decimal{Value: n.Int64}
+}
+
+// nullTimePtr 将可空时间列转换为指针，NULL 时返回 nil
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// decimalValue 返回写入数据库的金额（分），nil 写入 NULL
+func decimalValue(d *Decimal) interface{} {
+	if d == nil {
+		return nil
+	}
+	return d.Value
+}
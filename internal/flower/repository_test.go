@@ -3,6 +3,10 @@ package flower
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3" // SQLite 驱动
@@ -29,10 +33,23 @@ func setupTestDB(t *testing.T) *sql.DB {
 		purchase_price INTEGER NOT NULL,
 		sale_price INTEGER NOT NULL,
 		stock INTEGER NOT NULL DEFAULT 0,
+		max_order_qty INTEGER,
+		discount_price INTEGER,
+		discount_until DATETIME,
 		is_active INTEGER NOT NULL DEFAULT 1,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 	);
+
+	CREATE TABLE IF NOT EXISTS flower_logs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		sku TEXT NOT NULL,
+		operator_id INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		old_value TEXT NOT NULL,
+		new_value TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
 	`
 
 	if _, err := db.Exec(createTableSQL); err != nil {
@@ -224,6 +241,65 @@ func TestFlowerRepository_GetBySKU(t *testing.T) {
 	}
 }
 
+// TestFlowerRepository_GetBySKUs 测试批量查询与逐个查询结果一致，不存在的 SKU 不出现在结果中
+func TestFlowerRepository_GetBySKUs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupTestDB(t)
+	repo := NewFlowerRepository(db)
+	ctx := context.Background()
+
+	maxQty := 5
+	for i, sku := range []string{"BAT001", "BAT002", "BAT003"} {
+		f := &Flower{
+			SKU: sku, Name: "鲜花" + sku, Origin: "云南", ShelfLife: "7天", Preservation: "冷藏",
+			PurchasePrice: Decimal{Value: 1000}, SalePrice: Decimal{Value: int64(2000 + i)},
+			Stock: 10 * i, IsActive: i != 1,
+		}
+		if i == 2 {
+			f.MaxOrderQty = &maxQty
+		}
+		if err := repo.Create(ctx, f); err != nil {
+			t.Fatalf("failed to create flower: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		skus     []string
+		wantSKUs []string
+	}{
+		{name: "全部存在", skus: []string{"BAT001", "BAT002", "BAT003"}, wantSKUs: []string{"BAT001", "BAT002", "BAT003"}},
+		{name: "包含不存在与重复的 SKU", skus: []string{"BAT003", "NONEXIST", "BAT003"}, wantSKUs: []string{"BAT003"}},
+		{name: "空列表", skus: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.GetBySKUs(ctx, tt.skus)
+			if err != nil {
+				t.Fatalf("GetBySKUs() error = %v", err)
+			}
+			if len(got) != len(tt.wantSKUs) {
+				t.Fatalf("GetBySKUs() returned %d flowers, want %v", len(got), tt.wantSKUs)
+			}
+			if _, ok := got["NONEXIST"]; ok {
+				t.Error("GetBySKUs() returned missing SKU")
+			}
+			for _, sku := range tt.wantSKUs {
+				want, err := repo.GetBySKU(ctx, sku)
+				if err != nil {
+					t.Fatalf("GetBySKU(%s) error = %v", sku, err)
+				}
+				if !reflect.DeepEqual(got[sku], want) {
+					t.Errorf("GetBySKUs()[%s] = %+v, want %+v", sku, got[sku], want)
+				}
+			}
+		})
+	}
+}
+
 // TestFlowerRepository_List 测试 List 方法
 func TestFlowerRepository_List(t *testing.T) {
 	if testing.Short() {
@@ -389,8 +465,9 @@ func TestFlowerRepository_Update(t *testing.T) {
 	if updated.SalePrice.Value != 12000 {
 		t.Errorf("Update() SalePrice = %d, want %d", updated.SalePrice.Value, 12000)
 	}
-	if updated.Stock != 150 {
-		t.Errorf("Update() Stock = %d, want %d", updated.Stock, 150)
+	// Update 不修改库存
+	if updated.Stock != 100 {
+		t.Errorf("Update() Stock = %d, want %d", updated.Stock, 100)
 	}
 }
 
@@ -456,6 +533,70 @@ func TestFlowerRepository_Delete(t *testing.T) {
 	}
 }
 
+// TestFlowerRepository_Deactivate 测试下架后鲜花不出现在默认列表，但仍可按 SKU 查询
+func TestFlowerRepository_Deactivate(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewFlowerRepository(db)
+	ctx := context.Background()
+
+	for _, sku := range []string{"ACT001", "OFF001"} {
+		f := NewFlower(sku, "红玫瑰", "云南", "7天", "常温", 50, 100, 10)
+		if err := repo.Create(ctx, f); err != nil {
+			t.Fatalf("failed to create flower: %v", err)
+		}
+	}
+	if err := repo.Deactivate(ctx, "OFF001"); err != nil {
+		t.Fatalf("Deactivate() error = %v", err)
+	}
+	if err := repo.Deactivate(ctx, "NONEXIST"); !errors.Is(err, ErrFlowerNotFound) {
+		t.Errorf("Deactivate() non-existing error = %v, want ErrFlowerNotFound", err)
+	}
+
+	got, err := repo.GetBySKU(ctx, "OFF001")
+	if err != nil {
+		t.Fatalf("GetBySKU() error = %v", err)
+	}
+	if got.IsActive {
+		t.Error("GetBySKU() IsActive = true, want false")
+	}
+
+	tests := []struct {
+		name     string
+		filter   FlowerFilter
+		wantSKUs []string
+	}{
+		{name: "默认仅上架", filter: FlowerFilter{SortBy: "price_asc"}, wantSKUs: []string{"ACT001"}},
+		{name: "包含已下架", filter: FlowerFilter{SortBy: "price_asc", IncludeInactive: true}, wantSKUs: []string{"ACT001", "OFF001"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flowers, err := repo.List(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			count, err := repo.Count(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			if len(flowers) != len(tt.wantSKUs) || count != len(tt.wantSKUs) {
+				t.Fatalf("List() = %d flowers, Count() = %d, want %d", len(flowers), count, len(tt.wantSKUs))
+			}
+			for i, f := range flowers {
+				if f.SKU != tt.wantSKUs[i] {
+					t.Errorf("List()[%d].SKU = %s, want %s", i, f.SKU, tt.wantSKUs[i])
+				}
+			}
+		})
+	}
+
+	if err := repo.Activate(ctx, "OFF001"); err != nil {
+		t.Fatalf("Activate() error = %v", err)
+	}
+	if count, _ := repo.Count(ctx, FlowerFilter{}); count != 2 {
+		t.Errorf("Count() after Activate = %d, want 2", count)
+	}
+}
+
 // TestFlowerRepository_UpdateStock 测试 UpdateStock 方法
 func TestFlowerRepository_UpdateStock(t *testing.T) {
 	if testing.Short() {
@@ -483,32 +624,32 @@ func TestFlowerRepository_UpdateStock(t *testing.T) {
 	}
 
 	tests := []struct {
-		name    string
-		sku     string
-		delta   int
+		name      string
+		sku       string
+		delta     int
 		wantStock int
-		wantErr bool
+		wantErr   bool
 	}{
 		{
-			name:     "increase stock",
-			sku:      "STK001",
-			delta:    50,
+			name:      "increase stock",
+			sku:       "STK001",
+			delta:     50,
 			wantStock: 150,
-			wantErr:  false,
+			wantErr:   false,
 		},
 		{
-			name:     "decrease stock",
-			sku:      "STK001",
-			delta:    -30,
+			name:      "decrease stock",
+			sku:       "STK001",
+			delta:     -30,
 			wantStock: 120,
-			wantErr:  false,
+			wantErr:   false,
 		},
 		{
-			name:     "update non-existing flower",
-			sku:      "NONEXIST",
-			delta:    10,
+			name:      "update non-existing flower",
+			sku:       "NONEXIST",
+			delta:     10,
 			wantStock: 0,
-			wantErr:  true,
+			wantErr:   true,
 		},
 	}
 
@@ -535,3 +676,175 @@ func TestFlowerRepository_UpdateStock(t *testing.T) {
 		})
 	}
 }
+
+// TestFlowerRepository_DeductStockTx 测试事务内带库存条件的扣减
+func TestFlowerRepository_DeductStockTx(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupTestDB(t)
+	repo := NewFlowerRepository(db)
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &Flower{
+		SKU: "STK001", Name: "红玫瑰", Origin: "云南", ShelfLife: "7天", Preservation: "常温",
+		PurchasePrice: Decimal{Value: 5000}, SalePrice: Decimal{Value: 10000}, Stock: 10, IsActive: true,
+	}); err != nil {
+		t.Fatalf("failed to create flower: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		sku       string
+		quantity  int
+		wantErr   error
+		wantStock int
+	}{
+		{name: "扣减成功", sku: "STK001", quantity: 4, wantStock: 6},
+		{name: "恰好扣完", sku: "STK001", quantity: 6, wantStock: 0},
+		{name: "库存不足", sku: "STK001", quantity: 1, wantErr: ErrInsufficientStock, wantStock: 0},
+		{name: "鲜花不存在", sku: "NONEXIST", quantity: 1, wantErr: ErrFlowerNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatalf("BeginTx() error = %v", err)
+			}
+			err = repo.DeductStockTx(ctx, tx, tt.sku, tt.quantity)
+			if tt.wantErr != nil {
+				tx.Rollback()
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("DeductStockTx() error = %v, want %v", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("DeductStockTx() unexpected error = %v", err)
+				}
+				if err := tx.Commit(); err != nil {
+					t.Fatalf("Commit() error = %v", err)
+				}
+			}
+
+			if tt.sku == "NONEXIST" {
+				return
+			}
+			f, err := repo.GetBySKU(ctx, tt.sku)
+			if err != nil {
+				t.Fatalf("GetBySKU() error = %v", err)
+			}
+			if f.Stock != tt.wantStock {
+				t.Errorf("Stock = %d, want %d", f.Stock, tt.wantStock)
+			}
+		})
+	}
+}
+
+// TestFlowerRepository_List_Search 测试多字段多关键词搜索：关键词之间为 AND，字段之间为 OR
+func TestFlowerRepository_List_Search(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewFlowerRepository(db)
+	ctx := context.Background()
+
+	flowers := []*Flower{
+		{SKU: "SRC001", Name: "红玫瑰", Origin: "云南", Preservation: "冷藏", PurchasePrice: Decimal{Value: 5000}, SalePrice: Decimal{Value: 10000}, IsActive: true},
+		{SKU: "SRC002", Name: "白玫瑰", Origin: "山东", Preservation: "常温", PurchasePrice: Decimal{Value: 5000}, SalePrice: Decimal{Value: 10000}, IsActive: true},
+		{SKU: "SRC003", Name: "百合", Origin: "云南", Preservation: "冷藏", PurchasePrice: Decimal{Value: 6000}, SalePrice: Decimal{Value: 12000}, IsActive: true},
+		{SKU: "SRC004", Name: "郁金香", Origin: "荷兰", Preservation: "常温", PurchasePrice: Decimal{Value: 6000}, SalePrice: Decimal{Value: 12000}, IsActive: true},
+	}
+	for _, f := range flowers {
+		if err := repo.Create(ctx, f); err != nil {
+			t.Fatalf("failed to create flower: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		search   string
+		wantSKUs []string
+	}{
+		{name: "单个关键词匹配名称", search: "玫瑰", wantSKUs: []string{"SRC001", "SRC002"}},
+		{name: "单个关键词匹配产地", search: "云南", wantSKUs: []string{"SRC001", "SRC003"}},
+		{name: "单个关键词匹配保存方式", search: "常温", wantSKUs: []string{"SRC002", "SRC004"}},
+		{name: "多个关键词须同时匹配", search: "云南 玫瑰", wantSKUs: []string{"SRC001"}},
+		{name: "多余空白被忽略", search: "  玫瑰　 常温 ", wantSKUs: []string{"SRC002"}},
+		{name: "按 SKU 搜索", search: "src004", wantSKUs: []string{"SRC004"}},
+		{name: "无匹配", search: "云南 郁金香", wantSKUs: []string{}},
+		{name: "引号按普通字符匹配", search: "' OR 1=1 --", wantSKUs: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := repo.List(ctx, FlowerFilter{Search: tt.search})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+
+			got := make([]string, len(result))
+			for i, f := range result {
+				got[i] = f.SKU
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.wantSKUs, ",") {
+				t.Errorf("List(%q) = %v, want %v", tt.search, got, tt.wantSKUs)
+			}
+
+			count, err := repo.Count(ctx, FlowerFilter{Search: tt.search})
+			if err != nil || count != len(tt.wantSKUs) {
+				t.Errorf("Count(%q) = (%d, %v), want %d", tt.search, count, err, len(tt.wantSKUs))
+			}
+		})
+	}
+}
+
+// TestFlowerRepository_List_InStock 测试仅显示有库存的筛选
+func TestFlowerRepository_List_InStock(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupTestDB(t)
+	repo := NewFlowerRepository(db)
+	ctx := context.Background()
+
+	flowers := []*Flower{
+		{SKU: "STK001", Name: "红玫瑰", Origin: "云南", PurchasePrice: Decimal{Value: 5000}, SalePrice: Decimal{Value: 10000}, Stock: 20, IsActive: true},
+		{SKU: "STK002", Name: "白玫瑰", Origin: "云南", PurchasePrice: Decimal{Value: 5000}, SalePrice: Decimal{Value: 10000}, Stock: 0, IsActive: true},
+		{SKU: "STK003", Name: "郁金香", Origin: "荷兰", PurchasePrice: Decimal{Value: 6000}, SalePrice: Decimal{Value: 12000}, Stock: 5, IsActive: true},
+	}
+	for _, f := range flowers {
+		if err := repo.Create(ctx, f); err != nil {
+			t.Fatalf("failed to create flower: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		filter   FlowerFilter
+		wantSKUs []string
+	}{
+		{name: "默认包含无库存", filter: FlowerFilter{SortBy: "stock"}, wantSKUs: []string{"STK002", "STK003", "STK001"}},
+		{name: "仅有库存", filter: FlowerFilter{InStock: true, SortBy: "stock"}, wantSKUs: []string{"STK003", "STK001"}},
+		{name: "与其他筛选组合", filter: FlowerFilter{InStock: true, Search: "玫瑰"}, wantSKUs: []string{"STK001"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := repo.List(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+
+			if len(result) != len(tt.wantSKUs) {
+				t.Fatalf("List() count = %d, want %d", len(result), len(tt.wantSKUs))
+			}
+			for i, f := range result {
+				if f.SKU != tt.wantSKUs[i] {
+					t.Errorf("List()[%d] SKU = %s, want %s", i, f.SKU, tt.wantSKUs[i])
+				}
+			}
+		})
+	}
+}
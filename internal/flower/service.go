@@ -2,17 +2,34 @@ package flower
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 )
 
+// ErrFlowerExists 鲜花 SKU 已存在
+var ErrFlowerExists = apperror.New(apperror.KindConflict, "鲜花 SKU 已存在")
+
 // FlowerService 定义鲜花业务逻辑接口
 type FlowerService interface {
 	CreateFlower(ctx context.Context, req *CreateFlowerRequest) error
+	CloneFlower(ctx context.Context, sourceSKU, newSKU string, overrides *UpdateFlowerRequest) (*FlowerResponse, error)
 	GetFlower(ctx context.Context, sku string) (*FlowerResponse, error)
 	ListFlowers(ctx context.Context, filter FlowerFilter) ([]*FlowerResponse, error)
-	UpdateFlower(ctx context.Context, sku string, req *UpdateFlowerRequest) error
-	DeleteFlower(ctx context.Context, sku string) error
-	AddStock(ctx context.Context, sku string, quantity int) error
+	ListFlowersWithMore(ctx context.Context, filter FlowerFilter) ([]*FlowerResponse, bool, error)
+	ListAdminFlowersWithMore(ctx context.Context, filter FlowerFilter) ([]*AdminFlowerResponse, bool, error)
+	CountFlowers(ctx context.Context, filter FlowerFilter) (int, error)
+	ListLowStock(ctx context.Context, threshold int) ([]*LowStockAlert, error)
+	UpdateFlower(ctx context.Context, sku string, req *UpdateFlowerRequest, operatorID int) error
+	DeleteFlower(ctx context.Context, sku string, operatorID int) error
+	PurgeFlower(ctx context.Context, sku string) error
+	ActivateFlower(ctx context.Context, sku string, operatorID int) error
+	AddStock(ctx context.Context, sku string, quantity int, operatorID int) error
+	BatchAddStock(ctx context.Context, items []*StockAdjustment, operatorID int) ([]*StockAdjustmentResult, error)
+	GetFlowerLogs(ctx context.Context, sku string) ([]*FlowerLog, error)
 }
 
 // CreateFlowerRequest 创建鲜花请求
@@ -24,7 +41,10 @@ type CreateFlowerRequest struct {
 	Preservation  string
 	PurchasePrice float64
 	SalePrice     float64
+	DiscountPrice *float64   // 促销价，nil 表示无促销
+	DiscountUntil *time.Time // 促销截止时间，nil 表示长期有效
 	Stock         int
+	MaxOrderQty   *int // 单笔订单限购数量，nil 表示不限购
 }
 
 // UpdateFlowerRequest 更新鲜花请求
@@ -35,33 +55,130 @@ type UpdateFlowerRequest struct {
 	Preservation  *string
 	PurchasePrice *float64
 	SalePrice     *float64
+	DiscountPrice *float64   // 设置为 0 或负数表示取消促销（同时清除截止时间）
+	DiscountUntil *time.Time // 促销截止时间
+	MaxOrderQty   *int       // 设置为 0 或负数表示取消限购
 }
 
 // FlowerResponse 鲜花响应（带库存预警标识）
 type FlowerResponse struct {
-	SKU           string  `json:"sku"`
-	Name          string  `json:"name"`
-	Origin        string  `json:"origin"`
-	ShelfLife     string  `json:"shelf_life"`
-	Preservation  string  `json:"preservation"`
-	PurchasePrice float64 `json:"purchase_price"`
-	SalePrice     float64 `json:"sale_price"`
-	Stock         int     `json:"stock"`
-	IsActive      bool    `json:"is_active"`
-	LowStock      bool    `json:"low_stock"` // 库存预警标识
+	SKU                 string     `json:"sku"`
+	Name                string     `json:"name"`
+	Origin              string     `json:"origin"`
+	ShelfLife           string     `json:"shelf_life"`
+	Preservation        string     `json:"preservation"`
+	PurchasePrice       Decimal    `json:"purchase_price"`        // 两位小数，如 "50.00"
+	PurchasePriceCents  int64      `json:"purchase_price_cents"`  // 以分为单位
+	SalePrice           Decimal    `json:"sale_price"`            // 两位小数，如 "100.00"
+	SalePriceCents      int64      `json:"sale_price_cents"`      // 以分为单位
+	DiscountPrice       *Decimal   `json:"discount_price"`        // 促销价，null 表示无促销
+	DiscountUntil       *time.Time `json:"discount_until"`        // 促销截止时间，null 表示长期有效
+	EffectivePrice      Decimal    `json:"effective_price"`       // 当前实际售价，促销有效时为促销价
+	EffectivePriceCents int64      `json:"effective_price_cents"` // 以分为单位
+	Stock               int        `json:"stock"`
+	MaxOrderQty         *int       `json:"max_order_qty"` // 单笔订单限购数量，null 表示不限购
+	IsActive            bool       `json:"is_active"`
+	LowStock            bool       `json:"low_stock"` // 库存预警标识
+}
+
+// PublicFlowerResponse 面向顾客的鲜花响应，不包含进价等成本信息
+type PublicFlowerResponse struct {
+	SKU                 string     `json:"sku"`
+	Name                string     `json:"name"`
+	Origin              string     `json:"origin"`
+	ShelfLife           string     `json:"shelf_life"`
+	Preservation        string     `json:"preservation"`
+	SalePrice           Decimal    `json:"sale_price"`            // 两位小数，如 "100.00"
+	SalePriceCents      int64      `json:"sale_price_cents"`      // 以分为单位
+	DiscountPrice       *Decimal   `json:"discount_price"`        // 促销价，null 表示无促销
+	DiscountUntil       *time.Time `json:"discount_until"`        // 促销截止时间，null 表示长期有效
+	EffectivePrice      Decimal    `json:"effective_price"`       // 当前实际售价，促销有效时为促销价
+	EffectivePriceCents int64      `json:"effective_price_cents"` // 以分为单位
+	Stock               int        `json:"stock"`
+	MaxOrderQty         *int       `json:"max_order_qty"` // 单笔订单限购数量，null 表示不限购
+	IsActive            bool       `json:"is_active"`
+	LowStock            bool       `json:"low_stock"` // 库存预警标识
+}
+
+// AdminFlowerResponse 管理视图鲜花响应，在完整信息之外附带当前毛利（售价 - 进价）
+type AdminFlowerResponse struct {
+	*FlowerResponse
+	Margin      Decimal `json:"margin"`       // 两位小数
+	MarginCents int64   `json:"margin_cents"` // 以分为单位
+}
+
+// MaxBatchStockItems 单次批量入库允许的最大 SKU 数
+const MaxBatchStockItems = 100
+
+// StockAdjustment 批量入库中的一项
+type StockAdjustment struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+// StockAdjustmentResult 批量入库中单个 SKU 的结果
+type StockAdjustmentResult struct {
+	SKU           string `json:"sku"`
+	Quantity      int    `json:"quantity"`
+	PreviousStock int    `json:"previous_stock"`
+	Stock         int    `json:"stock"`
+}
+
+// LowStockAlert 库存预警项，Shortfall 为补足到阈值所需的数量
+type LowStockAlert struct {
+	SKU       string `json:"sku"`
+	Name      string `json:"name"`
+	Stock     int    `json:"stock"`
+	Threshold int    `json:"threshold"`
+	Shortfall int    `json:"shortfall"`
+}
+
+// Public 转换为不含成本信息的公开响应
+func (r *FlowerResponse) Public() *PublicFlowerResponse {
+	return &PublicFlowerResponse{
+		SKU:                 r.SKU,
+		Name:                r.Name,
+		Origin:              r.Origin,
+		ShelfLife:           r.ShelfLife,
+		Preservation:        r.Preservation,
+		SalePrice:           r.SalePrice,
+		SalePriceCents:      r.SalePriceCents,
+		DiscountPrice:       r.DiscountPrice,
+		DiscountUntil:       r.DiscountUntil,
+		EffectivePrice:      r.EffectivePrice,
+		EffectivePriceCents: r.EffectivePriceCents,
+		Stock:               r.Stock,
+		MaxOrderQty:         r.MaxOrderQty,
+		IsActive:            r.IsActive,
+		LowStock:            r.LowStock,
+	}
 }
 
 // flowerService 实现 FlowerService 接口
 type flowerService struct {
 	repo      FlowerRepository
-	threshold int // 库存预警阈值
+	logRepo   FlowerLogRepository // 审计日志，为 nil 时不记录
+	threshold int                 // 库存预警阈值
 }
 
-// NewFlowerService 创建 FlowerService 实例
+// DefaultLowStockThreshold 默认库存预警阈值
+const DefaultLowStockThreshold = 10
+
+// NewFlowerService 创建 FlowerService 实例，使用默认库存预警阈值，不记录审计日志
 func NewFlowerService(repo FlowerRepository) FlowerService {
+	return NewFlowerServiceWithThreshold(repo, nil, DefaultLowStockThreshold)
+}
+
+// NewFlowerServiceWithThreshold 创建指定审计日志仓储与库存预警阈值的 FlowerService 实例
+// logRepo 为 nil 时不记录审计日志；threshold <= 0 时使用默认阈值
+func NewFlowerServiceWithThreshold(repo FlowerRepository, logRepo FlowerLogRepository, threshold int) FlowerService {
+	if threshold <= 0 {
+		threshold = DefaultLowStockThreshold
+	}
 	return &flowerService{
 		repo:      repo,
-		threshold: 10, // 默认库存预警阈值为 10
+		logRepo:   logRepo,
+		threshold: threshold,
 	}
 }
 
@@ -76,9 +193,15 @@ func (s *flowerService) CreateFlower(ctx context.Context, req *CreateFlowerReque
 		Preservation:  req.Preservation,
 		PurchasePrice: DecimalFromFloat64(req.PurchasePrice),
 		SalePrice:     DecimalFromFloat64(req.SalePrice),
+		DiscountUntil: req.DiscountUntil,
 		Stock:         req.Stock,
+		MaxOrderQty:   req.MaxOrderQty,
 		IsActive:      true,
 	}
+	if req.DiscountPrice != nil {
+		discount := DecimalFromFloat64(*req.DiscountPrice)
+		flower.DiscountPrice = &discount
+	}
 
 	// 验证数据
 	if err := flower.Validate(); err != nil {
@@ -89,6 +212,43 @@ func (s *flowerService) CreateFlower(ctx context.Context, req *CreateFlowerReque
 	return s.repo.Create(ctx, flower)
 }
 
+// CloneFlower 以已有鲜花为模板创建新 SKU
+// 复制源鲜花的属性并应用 overrides（为 nil 表示不覆盖），新鲜花库存为 0 且处于上架状态
+func (s *flowerService) CloneFlower(ctx context.Context, sourceSKU, newSKU string, overrides *UpdateFlowerRequest) (*FlowerResponse, error) {
+	if newSKU == "" {
+		return nil, apperror.Validation("新 SKU 不能为空")
+	}
+
+	source, err := s.repo.GetBySKU(ctx, sourceSKU)
+	if err != nil {
+		return nil, err
+	}
+
+	// 新 SKU 必须未被占用
+	if _, err := s.repo.GetBySKU(ctx, newSKU); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrFlowerExists, newSKU)
+	} else if !errors.Is(err, ErrFlowerNotFound) {
+		return nil, err
+	}
+
+	clone := *source
+	clone.SKU = newSKU
+	clone.Stock = 0
+	clone.IsActive = true
+	if overrides != nil {
+		applyUpdate(&clone, overrides)
+	}
+
+	if err := clone.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, &clone); err != nil {
+		return nil, err
+	}
+
+	return s.toResponse(&clone), nil
+}
+
 // GetFlower 获取鲜花详情
 func (s *flowerService) GetFlower(ctx context.Context, sku string) (*FlowerResponse, error) {
 	flower, err := s.repo.GetBySKU(ctx, sku)
@@ -111,23 +271,166 @@ func (s *flowerService) ListFlowers(ctx context.Context, filter FlowerFilter) ([
 		return nil, err
 	}
 
+	// 筛选条件可覆盖库存预警阈值
+	threshold := s.threshold
+	if filter.LowStockThreshold > 0 {
+		threshold = filter.LowStockThreshold
+	}
+
 	responses := make([]*FlowerResponse, len(flowers))
 	for i, f := range flowers {
 		responses[i] = s.toResponse(f)
+		responses[i].LowStock = f.IsLowStock(threshold)
 	}
 
 	return responses, nil
 }
 
+// ListFlowersWithMore 获取一页鲜花，并通过多取一行判断是否还有下一页（无需 COUNT）
+// 未分页时返回全部结果，hasMore 恒为 false
+func (s *flowerService) ListFlowersWithMore(ctx context.Context, filter FlowerFilter) ([]*FlowerResponse, bool, error) {
+	filter.LookAhead = true
+	flowers, err := s.ListFlowers(ctx, filter)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if filter.PageSize > 0 && len(flowers) > filter.PageSize {
+		return flowers[:filter.PageSize], true, nil
+	}
+	return flowers, false, nil
+}
+
+// ListAdminFlowersWithMore 获取一页管理视图鲜花（含进价与毛利），筛选、排序与分页同 ListFlowersWithMore
+func (s *flowerService) ListAdminFlowersWithMore(ctx context.Context, filter FlowerFilter) ([]*AdminFlowerResponse, bool, error) {
+	flowers, hasMore, err := s.ListFlowersWithMore(ctx, filter)
+	if err != nil {
+		return nil, false, err
+	}
+
+	responses := make([]*AdminFlowerResponse, len(flowers))
+	for i, f := range flowers {
+		margin := f.SalePrice.Sub(f.PurchasePrice)
+		responses[i] = &AdminFlowerResponse{
+			FlowerResponse: f,
+			Margin:         margin,
+			MarginCents:    margin.Cents(),
+		}
+	}
+	return responses, hasMore, nil
+}
+
+// CountFlowers 统计符合筛选条件的鲜花总数（忽略分页）
+func (s *flowerService) CountFlowers(ctx context.Context, filter FlowerFilter) (int, error) {
+	if err := filter.Validate(); err != nil {
+		return 0, err
+	}
+	return s.repo.Count(ctx, filter)
+}
+
+// ListLowStock 获取库存低于或等于阈值的上架鲜花，按库存升序排列
+// threshold <= 0 时使用服务配置的库存预警阈值
+func (s *flowerService) ListLowStock(ctx context.Context, threshold int) ([]*LowStockAlert, error) {
+	if threshold <= 0 {
+		threshold = s.threshold
+	}
+
+	flowers, err := s.repo.ListLowStock(ctx, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]*LowStockAlert, len(flowers))
+	for i, f := range flowers {
+		alerts[i] = &LowStockAlert{
+			SKU:       f.SKU,
+			Name:      f.Name,
+			Stock:     f.Stock,
+			Threshold: threshold,
+			Shortfall: threshold - f.Stock,
+		}
+	}
+	return alerts, nil
+}
+
 // UpdateFlower 更新鲜花信息
-func (s *flowerService) UpdateFlower(ctx context.Context, sku string, req *UpdateFlowerRequest) error {
+// 在现有数据上只应用请求中非 nil 的字段，再按合并后的值重新校验（如售价不低于进价）
+func (s *flowerService) UpdateFlower(ctx context.Context, sku string, req *UpdateFlowerRequest, operatorID int) error {
 	// 获取现有鲜花
 	flower, err := s.repo.GetBySKU(ctx, sku)
 	if err != nil {
 		return err
 	}
+	before := *flower
 
 	// 更新字段
+	applyUpdate(flower, req)
+
+	// 验证更新后的数据
+	if err := flower.Validate(); err != nil {
+		return err
+	}
+
+	// 保存到数据库
+	if err := s.repo.Update(ctx, flower); err != nil {
+		return err
+	}
+
+	if oldValue, newValue := changedFields(&before, flower); len(newValue) > 0 {
+		s.recordLog(ctx, sku, operatorID, LogActionUpdate, oldValue, newValue)
+	}
+	return nil
+}
+
+// changedFields 对比更新前后的鲜花，返回发生变化的字段在变更前后的值
+func changedFields(before, after *Flower) (map[string]interface{}, map[string]interface{}) {
+	oldValue := map[string]interface{}{}
+	newValue := map[string]interface{}{}
+	diff := func(field string, o, n interface{}) {
+		if o != n {
+			oldValue[field] = o
+			newValue[field] = n
+		}
+	}
+
+	diff("name", before.Name, after.Name)
+	diff("origin", before.Origin, after.Origin)
+	diff("shelf_life", before.ShelfLife, after.ShelfLife)
+	diff("preservation", before.Preservation, after.Preservation)
+	diff("purchase_price", before.PurchasePrice.String(), after.PurchasePrice.String())
+	diff("sale_price", before.SalePrice.String(), after.SalePrice.String())
+	diff("discount_price", discountPriceValue(before.DiscountPrice), discountPriceValue(after.DiscountPrice))
+	diff("discount_until", discountUntilValue(before.DiscountUntil), discountUntilValue(after.DiscountUntil))
+	diff("max_order_qty", maxOrderQtyValue(before.MaxOrderQty), maxOrderQtyValue(after.MaxOrderQty))
+	return oldValue, newValue
+}
+
+// maxOrderQtyValue 返回限购数量用于比较和记录，不限购时为 nil
+func maxOrderQtyValue(qty *int) interface{} {
+	if qty == nil {
+		return nil
+	}
+	return *qty
+}
+
+// discountPriceValue 返回促销价用于比较和记录，无促销时为 nil
+func discountPriceValue(price *Decimal) interface{} {
+	if price == nil {
+		return nil
+	}
+	return price.String()
+}
+
+// discountUntilValue 返回促销截止时间用于比较和记录，未设置时为 nil
+func discountUntilValue(until *time.Time) interface{} {
+	if until == nil {
+		return nil
+	}
+	return until.Format(time.RFC3339)
+}
+
+// applyUpdate 将请求中出现的字段写入鲜花实体
+func applyUpdate(flower *Flower, req *UpdateFlowerRequest) {
 	if req.Name != nil {
 		flower.Name = *req.Name
 	}
@@ -146,44 +449,186 @@ func (s *flowerService) UpdateFlower(ctx context.Context, sku string, req *Updat
 	if req.SalePrice != nil {
 		flower.SalePrice = DecimalFromFloat64(*req.SalePrice)
 	}
+	if req.DiscountUntil != nil {
+		flower.DiscountUntil = req.DiscountUntil
+	}
+	if req.DiscountPrice != nil {
+		if *req.DiscountPrice <= 0 {
+			flower.DiscountPrice = nil
+			flower.DiscountUntil = nil
+		} else {
+			discount := DecimalFromFloat64(*req.DiscountPrice)
+			flower.DiscountPrice = &discount
+		}
+	}
+	if req.MaxOrderQty != nil {
+		if *req.MaxOrderQty <= 0 {
+			flower.MaxOrderQty = nil
+		} else {
+			flower.MaxOrderQty = req.MaxOrderQty
+		}
+	}
+}
 
-	// 验证更新后的数据
-	if err := flower.Validate(); err != nil {
+// DeleteFlower 删除鲜花：默认下架而不物理删除，历史订单仍可通过 SKU 查到该鲜花
+func (s *flowerService) DeleteFlower(ctx context.Context, sku string, operatorID int) error {
+	if err := s.repo.Deactivate(ctx, sku); err != nil {
 		return err
 	}
-
-	// 保存到数据库
-	return s.repo.Update(ctx, flower)
+	s.recordLog(ctx, sku, operatorID, LogActionDeactivate,
+		map[string]interface{}{"is_active": true}, map[string]interface{}{"is_active": false})
+	return nil
 }
 
-// DeleteFlower 删除鲜花
-func (s *flowerService) DeleteFlower(ctx context.Context, sku string) error {
+// PurgeFlower 物理删除鲜花
+func (s *flowerService) PurgeFlower(ctx context.Context, sku string) error {
 	return s.repo.Delete(ctx, sku)
 }
 
+// ActivateFlower 重新上架已下架的鲜花
+func (s *flowerService) ActivateFlower(ctx context.Context, sku string, operatorID int) error {
+	if err := s.repo.Activate(ctx, sku); err != nil {
+		return err
+	}
+	s.recordLog(ctx, sku, operatorID, LogActionActivate,
+		map[string]interface{}{"is_active": false}, map[string]interface{}{"is_active": true})
+	return nil
+}
+
 // AddStock 进货入库
-func (s *flowerService) AddStock(ctx context.Context, sku string, quantity int) error {
+func (s *flowerService) AddStock(ctx context.Context, sku string, quantity int, operatorID int) error {
 	// 验证数量
 	if quantity < 0 {
-		return fmt.Errorf("进货数量不能为负数")
+		return apperror.Validation("进货数量不能为负数")
+	}
+
+	// 读取入库前库存用于审计日志
+	flower, err := s.repo.GetBySKU(ctx, sku)
+	if err != nil {
+		return err
 	}
 
 	// 更新库存
-	return s.repo.UpdateStock(ctx, sku, quantity)
+	if err := s.repo.UpdateStock(ctx, sku, quantity); err != nil {
+		return err
+	}
+
+	s.recordLog(ctx, sku, operatorID, LogActionAddStock,
+		map[string]interface{}{"stock": flower.Stock}, map[string]interface{}{"stock": flower.Stock + quantity})
+	return nil
+}
+
+// BatchAddStock 批量进货入库，所有 SKU 在同一事务中更新，任一项失败时整批回滚
+// 数量为负、SKU 为空或重复时在开启事务前拒绝；SKU 不存在时回滚并返回校验错误
+func (s *flowerService) BatchAddStock(ctx context.Context, items []*StockAdjustment, operatorID int) ([]*StockAdjustmentResult, error) {
+	if len(items) == 0 {
+		return nil, apperror.Validation("入库列表不能为空")
+	}
+	if len(items) > MaxBatchStockItems {
+		return nil, apperror.Validation("单次最多入库%d个SKU", MaxBatchStockItems)
+	}
+	seen := make(map[string]bool, len(items))
+	for i, item := range items {
+		if item == nil || item.SKU == "" {
+			return nil, apperror.Validation("第%d项: 鲜花SKU不能为空", i+1)
+		}
+		if item.Quantity < 0 {
+			return nil, apperror.Validation("第%d项: 进货数量不能为负数", i+1)
+		}
+		if seen[item.SKU] {
+			return nil, apperror.Validation("第%d项: SKU %s 重复", i+1, item.SKU)
+		}
+		seen[item.SKU] = true
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make([]*StockAdjustmentResult, len(items))
+	for i, item := range items {
+		stock, err := s.repo.GetStockTx(ctx, tx, item.SKU)
+		if errors.Is(err, ErrFlowerNotFound) {
+			return nil, apperror.Validation("第%d项: 鲜花不存在: %s", i+1, item.SKU)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := s.repo.UpdateStockTx(ctx, tx, item.SKU, item.Quantity); err != nil {
+			return nil, err
+		}
+		results[i] = &StockAdjustmentResult{
+			SKU:           item.SKU,
+			Quantity:      item.Quantity,
+			PreviousStock: stock,
+			Stock:         stock + item.Quantity,
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit batch stock: %w", err)
+	}
+
+	for _, r := range results {
+		s.recordLog(ctx, r.SKU, operatorID, LogActionAddStock,
+			map[string]interface{}{"stock": r.PreviousStock}, map[string]interface{}{"stock": r.Stock})
+	}
+	return results, nil
+}
+
+// GetFlowerLogs 获取鲜花的审计日志，最新在前
+func (s *flowerService) GetFlowerLogs(ctx context.Context, sku string) ([]*FlowerLog, error) {
+	if sku == "" {
+		return nil, apperror.Validation("鲜花SKU不能为空")
+	}
+	if s.logRepo == nil {
+		return []*FlowerLog{}, nil
+	}
+
+	logs, err := s.logRepo.GetLogs(ctx, sku)
+	if err != nil {
+		return nil, fmt.Errorf("查询鲜花日志: %w", err)
+	}
+	return logs, nil
+}
+
+// recordLog 记录鲜花审计日志，日志记录失败不影响业务操作
+func (s *flowerService) recordLog(ctx context.Context, sku string, operatorID int, action string, oldValue, newValue map[string]interface{}) {
+	if s.logRepo == nil {
+		return
+	}
+
+	log, err := NewFlowerLog(sku, operatorID, action, oldValue, newValue)
+	if err == nil {
+		err = s.logRepo.CreateLog(ctx, log)
+	}
+	if err != nil {
+		slog.Warn("failed to create flower log", "sku", sku, "action", action, "error", err)
+	}
 }
 
 // toResponse 将 Flower 实体转换为响应 DTO
 func (s *flowerService) toResponse(f *Flower) *FlowerResponse {
+	effective := f.EffectivePrice()
 	return &FlowerResponse{
-		SKU:           f.SKU,
-		Name:          f.Name,
-		Origin:        f.Origin,
-		ShelfLife:     f.ShelfLife,
-		Preservation:  f.Preservation,
-		PurchasePrice: f.PurchasePrice.ToFloat64(),
-		SalePrice:     f.SalePrice.ToFloat64(),
-		Stock:         f.Stock,
-		IsActive:      f.IsActive,
-		LowStock:      f.IsLowStock(s.threshold),
+		SKU:                 f.SKU,
+		Name:                f.Name,
+		Origin:              f.Origin,
+		ShelfLife:           f.ShelfLife,
+		Preservation:        f.Preservation,
+		PurchasePrice:       f.PurchasePrice,
+		PurchasePriceCents:  f.PurchasePrice.Cents(),
+		SalePrice:           f.SalePrice,
+		SalePriceCents:      f.SalePrice.Cents(),
+		DiscountPrice:       f.DiscountPrice,
+		DiscountUntil:       f.DiscountUntil,
+		EffectivePrice:      effective,
+		EffectivePriceCents: effective.Cents(),
+		Stock:               f.Stock,
+		MaxOrderQty:         f.MaxOrderQty,
+		IsActive:            f.IsActive,
+		LowStock:            f.IsLowStock(s.threshold),
 	}
 }
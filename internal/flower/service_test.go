@@ -3,8 +3,12 @@ package flower
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 	_ "github.com/mattn/go-sqlite3" // SQLite 驱动
 )
 
@@ -37,11 +41,11 @@ func TestFlowerService_CreateFlower(t *testing.T) {
 		{
 			name: "create valid flower",
 			request: &CreateFlowerRequest{
-				SKU:          "SVC001",
-				Name:         "红玫瑰",
-				Origin:       "云南",
-				ShelfLife:    "7天",
-				Preservation: "常温",
+				SKU:           "SVC001",
+				Name:          "红玫瑰",
+				Origin:        "云南",
+				ShelfLife:     "7天",
+				Preservation:  "常温",
 				PurchasePrice: 50.00,
 				SalePrice:     100.00,
 				Stock:         100,
@@ -51,11 +55,11 @@ func TestFlowerService_CreateFlower(t *testing.T) {
 		{
 			name: "create with empty SKU",
 			request: &CreateFlowerRequest{
-				SKU:          "",
-				Name:         "红玫瑰",
-				Origin:       "云南",
-				ShelfLife:    "7天",
-				Preservation: "常温",
+				SKU:           "",
+				Name:          "红玫瑰",
+				Origin:        "云南",
+				ShelfLife:     "7天",
+				Preservation:  "常温",
 				PurchasePrice: 50.00,
 				SalePrice:     100.00,
 				Stock:         100,
@@ -66,11 +70,11 @@ func TestFlowerService_CreateFlower(t *testing.T) {
 		{
 			name: "create with sale price below purchase price",
 			request: &CreateFlowerRequest{
-				SKU:          "SVC002",
-				Name:         "红玫瑰",
-				Origin:       "云南",
-				ShelfLife:    "7天",
-				Preservation: "常温",
+				SKU:           "SVC002",
+				Name:          "红玫瑰",
+				Origin:        "云南",
+				ShelfLife:     "7天",
+				Preservation:  "常温",
 				PurchasePrice: 100.00,
 				SalePrice:     50.00,
 				Stock:         100,
@@ -81,11 +85,11 @@ func TestFlowerService_CreateFlower(t *testing.T) {
 		{
 			name: "create with negative stock",
 			request: &CreateFlowerRequest{
-				SKU:          "SVC003",
-				Name:         "红玫瑰",
-				Origin:       "云南",
-				ShelfLife:    "7天",
-				Preservation: "常温",
+				SKU:           "SVC003",
+				Name:          "红玫瑰",
+				Origin:        "云南",
+				ShelfLife:     "7天",
+				Preservation:  "常温",
 				PurchasePrice: 50.00,
 				SalePrice:     100.00,
 				Stock:         -10,
@@ -122,11 +126,11 @@ func TestFlowerService_GetFlower(t *testing.T) {
 
 	// 创建测试鲜花
 	createReq := &CreateFlowerRequest{
-		SKU:          "GET001",
-		Name:         "向日葵",
-		Origin:       "美国",
-		ShelfLife:    "10天",
-		Preservation: "常温",
+		SKU:           "GET001",
+		Name:          "向日葵",
+		Origin:        "美国",
+		ShelfLife:     "10天",
+		Preservation:  "常温",
 		PurchasePrice: 30.00,
 		SalePrice:     60.00,
 		Stock:         200,
@@ -186,31 +190,31 @@ func TestFlowerService_ListFlowers(t *testing.T) {
 	// 创建多个测试鲜花
 	flowers := []*CreateFlowerRequest{
 		{
-			SKU:          "LST001",
-			Name:         "红玫瑰",
-			Origin:       "云南",
-			ShelfLife:    "7天",
-			Preservation: "常温",
+			SKU:           "LST001",
+			Name:          "红玫瑰",
+			Origin:        "云南",
+			ShelfLife:     "7天",
+			Preservation:  "常温",
 			PurchasePrice: 50.00,
 			SalePrice:     100.00,
 			Stock:         100,
 		},
 		{
-			SKU:          "LST002",
-			Name:         "白百合",
-			Origin:       "荷兰",
-			ShelfLife:    "14天",
-			Preservation: "低温",
+			SKU:           "LST002",
+			Name:          "白百合",
+			Origin:        "荷兰",
+			ShelfLife:     "14天",
+			Preservation:  "低温",
 			PurchasePrice: 80.00,
 			SalePrice:     150.00,
 			Stock:         50,
 		},
 		{
-			SKU:          "LST003",
-			Name:         "康乃馨",
-			Origin:       "云南",
-			ShelfLife:    "10天",
-			Preservation: "常温",
+			SKU:           "LST003",
+			Name:          "康乃馨",
+			Origin:        "云南",
+			ShelfLife:     "10天",
+			Preservation:  "常温",
 			PurchasePrice: 40.00,
 			SalePrice:     80.00,
 			Stock:         5, // 低库存
@@ -296,11 +300,11 @@ func TestFlowerService_UpdateFlower(t *testing.T) {
 
 	// 创建测试鲜花
 	createReq := &CreateFlowerRequest{
-		SKU:          "UPD001",
-		Name:         "红玫瑰",
-		Origin:       "云南",
-		ShelfLife:    "7天",
-		Preservation: "常温",
+		SKU:           "UPD001",
+		Name:          "红玫瑰",
+		Origin:        "云南",
+		ShelfLife:     "7天",
+		Preservation:  "常温",
 		PurchasePrice: 50.00,
 		SalePrice:     100.00,
 		Stock:         100,
@@ -319,10 +323,10 @@ func TestFlowerService_UpdateFlower(t *testing.T) {
 			name: "update valid flower",
 			sku:  "UPD001",
 			request: &UpdateFlowerRequest{
-				Name:         stringPtr("红玫瑰（特大）"),
-				Origin:       stringPtr("云南"),
-				ShelfLife:    stringPtr("7天"),
-				Preservation: stringPtr("常温"),
+				Name:          stringPtr("红玫瑰（特大）"),
+				Origin:        stringPtr("云南"),
+				ShelfLife:     stringPtr("7天"),
+				Preservation:  stringPtr("常温"),
 				PurchasePrice: float64Ptr(60.00),
 				SalePrice:     float64Ptr(120.00),
 			},
@@ -343,10 +347,10 @@ func TestFlowerService_UpdateFlower(t *testing.T) {
 			name: "update with invalid sale price",
 			sku:  "UPD001",
 			request: &UpdateFlowerRequest{
-				Name:         stringPtr("红玫瑰"),
-				Origin:       stringPtr("云南"),
-				ShelfLife:    stringPtr("7天"),
-				Preservation: stringPtr("常温"),
+				Name:          stringPtr("红玫瑰"),
+				Origin:        stringPtr("云南"),
+				ShelfLife:     stringPtr("7天"),
+				Preservation:  stringPtr("常温"),
 				PurchasePrice: float64Ptr(100.00),
 				SalePrice:     float64Ptr(50.00),
 			},
@@ -356,7 +360,7 @@ func TestFlowerService_UpdateFlower(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := service.UpdateFlower(ctx, tt.sku, tt.request)
+			err := service.UpdateFlower(ctx, tt.sku, tt.request, 1)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("UpdateFlower() error = %v, wantErr %v", err, tt.wantErr)
@@ -376,11 +380,11 @@ func TestFlowerService_DeleteFlower(t *testing.T) {
 
 	// 创建测试鲜花
 	createReq := &CreateFlowerRequest{
-		SKU:          "DEL001",
-		Name:         "红玫瑰",
-		Origin:       "云南",
-		ShelfLife:    "7天",
-		Preservation: "常温",
+		SKU:           "DEL001",
+		Name:          "红玫瑰",
+		Origin:        "云南",
+		ShelfLife:     "7天",
+		Preservation:  "常温",
 		PurchasePrice: 50.00,
 		SalePrice:     100.00,
 		Stock:         100,
@@ -408,23 +412,71 @@ func TestFlowerService_DeleteFlower(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := service.DeleteFlower(ctx, tt.sku)
+			err := service.DeleteFlower(ctx, tt.sku, 1)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("DeleteFlower() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
-			// 验证删除后鲜花不存在
+			// 删除仅下架：鲜花仍可查到，但不再出现在默认列表中
 			if !tt.wantErr {
-				_, err := service.GetFlower(ctx, tt.sku)
-				if err == nil {
-					t.Error("DeleteFlower() flower still exists after deletion")
+				got, err := service.GetFlower(ctx, tt.sku)
+				if err != nil {
+					t.Fatalf("GetFlower() after delete error = %v", err)
+				}
+				if got.IsActive {
+					t.Error("DeleteFlower() flower still active after deletion")
+				}
+
+				list, err := service.ListFlowers(ctx, FlowerFilter{})
+				if err != nil {
+					t.Fatalf("ListFlowers() error = %v", err)
+				}
+				for _, f := range list {
+					if f.SKU == tt.sku {
+						t.Error("ListFlowers() returned deactivated flower")
+					}
 				}
 			}
 		})
 	}
 }
 
+// TestFlowerService_PurgeAndActivateFlower 测试物理删除与重新上架
+func TestFlowerService_PurgeAndActivateFlower(t *testing.T) {
+	service, _ := setupTestService(t)
+	ctx := context.Background()
+
+	for _, sku := range []string{"ACT001", "PRG001"} {
+		if err := service.CreateFlower(ctx, &CreateFlowerRequest{
+			SKU: sku, Name: "红玫瑰", Origin: "云南", ShelfLife: "7天", Preservation: "常温",
+			PurchasePrice: 50.00, SalePrice: 100.00, Stock: 10,
+		}); err != nil {
+			t.Fatalf("failed to create flower: %v", err)
+		}
+		if err := service.DeleteFlower(ctx, sku, 1); err != nil {
+			t.Fatalf("DeleteFlower() error = %v", err)
+		}
+	}
+
+	if err := service.ActivateFlower(ctx, "ACT001", 1); err != nil {
+		t.Fatalf("ActivateFlower() error = %v", err)
+	}
+	if got, _ := service.GetFlower(ctx, "ACT001"); got == nil || !got.IsActive {
+		t.Error("ActivateFlower() flower not active")
+	}
+	if err := service.ActivateFlower(ctx, "NONEXIST", 1); !errors.Is(err, ErrFlowerNotFound) {
+		t.Errorf("ActivateFlower() non-existing error = %v, want ErrFlowerNotFound", err)
+	}
+
+	if err := service.PurgeFlower(ctx, "PRG001"); err != nil {
+		t.Fatalf("PurgeFlower() error = %v", err)
+	}
+	if _, err := service.GetFlower(ctx, "PRG001"); !errors.Is(err, ErrFlowerNotFound) {
+		t.Errorf("GetFlower() after purge error = %v, want ErrFlowerNotFound", err)
+	}
+}
+
 // TestFlowerService_AddStock 测试进货入库
 func TestFlowerService_AddStock(t *testing.T) {
 	if testing.Short() {
@@ -436,11 +488,11 @@ func TestFlowerService_AddStock(t *testing.T) {
 
 	// 创建测试鲜花
 	createReq := &CreateFlowerRequest{
-		SKU:          "STK001",
-		Name:         "红玫瑰",
-		Origin:       "云南",
-		ShelfLife:    "7天",
-		Preservation: "常温",
+		SKU:           "STK001",
+		Name:          "红玫瑰",
+		Origin:        "云南",
+		ShelfLife:     "7天",
+		Preservation:  "常温",
 		PurchasePrice: 50.00,
 		SalePrice:     100.00,
 		Stock:         100,
@@ -450,11 +502,11 @@ func TestFlowerService_AddStock(t *testing.T) {
 	}
 
 	tests := []struct {
-		name       string
-		sku        string
-		quantity   int
-		wantStock  int
-		wantErr    bool
+		name      string
+		sku       string
+		quantity  int
+		wantStock int
+		wantErr   bool
 	}{
 		{
 			name:      "add stock - positive quantity",
@@ -488,7 +540,7 @@ func TestFlowerService_AddStock(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := service.AddStock(ctx, tt.sku, tt.quantity)
+			err := service.AddStock(ctx, tt.sku, tt.quantity, 1)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("AddStock() error = %v, wantErr %v", err, tt.wantErr)
@@ -522,31 +574,31 @@ func TestFlowerService_LowStockWarning(t *testing.T) {
 	// 创建测试鲜花（包括低库存的）
 	flowers := []*CreateFlowerRequest{
 		{
-			SKU:          "LOW001",
-			Name:         "红玫瑰",
-			Origin:       "云南",
-			ShelfLife:    "7天",
-			Preservation: "常温",
+			SKU:           "LOW001",
+			Name:          "红玫瑰",
+			Origin:        "云南",
+			ShelfLife:     "7天",
+			Preservation:  "常温",
 			PurchasePrice: 50.00,
 			SalePrice:     100.00,
 			Stock:         100, // 正常库存
 		},
 		{
-			SKU:          "LOW002",
-			Name:         "白百合",
-			Origin:       "荷兰",
-			ShelfLife:    "14天",
-			Preservation: "低温",
+			SKU:           "LOW002",
+			Name:          "白百合",
+			Origin:        "荷兰",
+			ShelfLife:     "14天",
+			Preservation:  "低温",
 			PurchasePrice: 80.00,
 			SalePrice:     150.00,
 			Stock:         10, // 等于阈值
 		},
 		{
-			SKU:          "LOW003",
-			Name:         "康乃馨",
-			Origin:       "哥伦比亚",
-			ShelfLife:    "10天",
-			Preservation: "常温",
+			SKU:           "LOW003",
+			Name:          "康乃馨",
+			Origin:        "哥伦比亚",
+			ShelfLife:     "10天",
+			Preservation:  "常温",
 			PurchasePrice: 40.00,
 			SalePrice:     80.00,
 			Stock:         5, // 低于阈值
@@ -578,6 +630,105 @@ func TestFlowerService_LowStockWarning(t *testing.T) {
 	}
 }
 
+// TestFlowerService_LowStockThreshold 测试配置的预警阈值及筛选条件覆盖决定 LowStock 标识
+func TestFlowerService_LowStockThreshold(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name      string
+		threshold int // 服务配置的阈值
+		override  int // 筛选条件中的阈值覆盖
+		wantLow   []string
+	}{
+		{name: "默认阈值", threshold: 0, wantLow: []string{"S005", "S010"}},
+		{name: "配置阈值 5", threshold: 5, wantLow: []string{"S005"}},
+		{name: "配置阈值 50", threshold: 50, wantLow: []string{"S005", "S010", "S030"}},
+		{name: "请求覆盖配置", threshold: 5, override: 30, wantLow: []string{"S005", "S010", "S030"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewFlowerServiceWithThreshold(NewFlowerRepository(setupTestDB(t)), nil, tt.threshold)
+			ctx := context.Background()
+
+			for _, stock := range []int{5, 10, 30, 100} {
+				if err := service.CreateFlower(ctx, &CreateFlowerRequest{
+					SKU: fmt.Sprintf("S%03d", stock), Name: "红玫瑰", Origin: "云南",
+					PurchasePrice: 50, SalePrice: 100, Stock: stock,
+				}); err != nil {
+					t.Fatalf("CreateFlower() error = %v", err)
+				}
+			}
+
+			result, err := service.ListFlowers(ctx, FlowerFilter{SortBy: "stock", LowStockThreshold: tt.override})
+			if err != nil {
+				t.Fatalf("ListFlowers() error = %v", err)
+			}
+
+			var gotLow []string
+			for _, f := range result {
+				if f.LowStock {
+					gotLow = append(gotLow, f.SKU)
+				}
+			}
+			if strings.Join(gotLow, ",") != strings.Join(tt.wantLow, ",") {
+				t.Errorf("low stock = %v, want %v", gotLow, tt.wantLow)
+			}
+		})
+	}
+}
+
+// TestFlowerService_ListLowStock 测试库存预警只返回阈值内的上架鲜花，按库存升序并给出缺口
+func TestFlowerService_ListLowStock(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	service := NewFlowerServiceWithThreshold(NewFlowerRepository(setupTestDB(t)), nil, 10)
+	ctx := context.Background()
+	for _, stock := range []int{10, 100, 3, 0, 11} {
+		if err := service.CreateFlower(ctx, &CreateFlowerRequest{
+			SKU: fmt.Sprintf("S%03d", stock), Name: "红玫瑰", Origin: "云南",
+			PurchasePrice: 50, SalePrice: 100, Stock: stock,
+		}); err != nil {
+			t.Fatalf("CreateFlower() error = %v", err)
+		}
+	}
+	// 已下架鲜花不参与预警
+	if err := service.DeleteFlower(ctx, "S000", 1); err != nil {
+		t.Fatalf("DeleteFlower() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		threshold     int
+		wantSKUs      []string
+		wantShortfall []int
+	}{
+		{name: "使用配置阈值", threshold: 0, wantSKUs: []string{"S003", "S010"}, wantShortfall: []int{7, 0}},
+		{name: "覆盖阈值", threshold: 11, wantSKUs: []string{"S003", "S010", "S011"}, wantShortfall: []int{8, 1, 0}},
+		{name: "无预警", threshold: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerts, err := service.ListLowStock(ctx, tt.threshold)
+			if err != nil {
+				t.Fatalf("ListLowStock() error = %v", err)
+			}
+			if len(alerts) != len(tt.wantSKUs) {
+				t.Fatalf("ListLowStock() = %d alerts, want %v", len(alerts), tt.wantSKUs)
+			}
+			for i, a := range alerts {
+				if a.SKU != tt.wantSKUs[i] || a.Shortfall != tt.wantShortfall[i] {
+					t.Errorf("alert[%d] = %+v, want %s with shortfall %d", i, a, tt.wantSKUs[i], tt.wantShortfall[i])
+				}
+			}
+		})
+	}
+}
+
 // 辅助函数
 func float64Ptr(f float64) *float64 {
 	return &f
@@ -586,3 +737,348 @@ func float64Ptr(f float64) *float64 {
 func stringPtr(s string) *string {
 	return &s
 }
+
+// TestFlowerService_UpdateFlower_Partial 测试部分更新只修改提供的字段
+func TestFlowerService_UpdateFlower_Partial(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name      string
+		request   *UpdateFlowerRequest
+		wantErr   bool
+		wantName  string
+		wantPrice float64
+	}{
+		{
+			name:      "只修改名称",
+			request:   &UpdateFlowerRequest{Name: stringPtr("红玫瑰（特大）")},
+			wantName:  "红玫瑰（特大）",
+			wantPrice: 100.00,
+		},
+		{
+			name:      "只修改售价",
+			request:   &UpdateFlowerRequest{SalePrice: float64Ptr(80.00)},
+			wantName:  "红玫瑰",
+			wantPrice: 80.00,
+		},
+		{
+			name:    "售价低于现有进价",
+			request: &UpdateFlowerRequest{SalePrice: float64Ptr(40.00)},
+			wantErr: true,
+		},
+		{
+			name:    "进价高于现有售价",
+			request: &UpdateFlowerRequest{PurchasePrice: float64Ptr(120.00)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := setupTestService(t)
+			ctx := context.Background()
+
+			createReq := &CreateFlowerRequest{
+				SKU:           "PAT001",
+				Name:          "红玫瑰",
+				Origin:        "云南",
+				ShelfLife:     "7天",
+				Preservation:  "常温",
+				PurchasePrice: 50.00,
+				SalePrice:     100.00,
+				Stock:         100,
+			}
+			if err := service.CreateFlower(ctx, createReq); err != nil {
+				t.Fatalf("failed to create flower: %v", err)
+			}
+
+			err := service.UpdateFlower(ctx, "PAT001", tt.request, 1)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateFlower() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			got, err := service.GetFlower(ctx, "PAT001")
+			if err != nil {
+				t.Fatalf("GetFlower() error = %v", err)
+			}
+
+			// 被拒绝的更新不应修改任何字段
+			wantName, wantPrice := tt.wantName, tt.wantPrice
+			if tt.wantErr {
+				wantName, wantPrice = "红玫瑰", 100.00
+			}
+			if got.Name != wantName {
+				t.Errorf("Name = %q, want %q", got.Name, wantName)
+			}
+			if got.SalePrice.ToFloat64() != wantPrice {
+				t.Errorf("SalePrice = %v, want %v", got.SalePrice, wantPrice)
+			}
+			if got.PurchasePrice.ToFloat64() != 50.00 || got.Origin != "云南" || got.Stock != 100 {
+				t.Errorf("untouched fields changed: %+v", got)
+			}
+		})
+	}
+}
+
+// stockRacingRepository 在 GetBySKU 返回后模拟一次并发的库存变动
+type stockRacingRepository struct {
+	FlowerRepository
+	delta int
+}
+
+func (r *stockRacingRepository) GetBySKU(ctx context.Context, sku string) (*Flower, error) {
+	f, err := r.FlowerRepository.GetBySKU(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.FlowerRepository.UpdateStock(ctx, sku, r.delta); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// TestFlowerService_UpdateFlower_KeepsConcurrentStock 测试更新鲜花信息不会覆盖读取之后发生的库存变动
+func TestFlowerService_UpdateFlower_KeepsConcurrentStock(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	repo := NewFlowerRepository(setupTestDB(t))
+	ctx := context.Background()
+	if err := NewFlowerService(repo).CreateFlower(ctx, &CreateFlowerRequest{
+		SKU: "RACE01", Name: "红玫瑰", Origin: "云南", PurchasePrice: 50.00, SalePrice: 100.00, Stock: 100,
+	}); err != nil {
+		t.Fatalf("failed to create flower: %v", err)
+	}
+
+	service := NewFlowerService(&stockRacingRepository{FlowerRepository: repo, delta: -30})
+	if err := service.UpdateFlower(ctx, "RACE01", &UpdateFlowerRequest{Name: stringPtr("红玫瑰（特大）")}, 1); err != nil {
+		t.Fatalf("UpdateFlower() error = %v", err)
+	}
+
+	got, err := repo.GetBySKU(ctx, "RACE01")
+	if err != nil {
+		t.Fatalf("GetBySKU() error = %v", err)
+	}
+	if got.Name != "红玫瑰（特大）" {
+		t.Errorf("Name = %q, want %q", got.Name, "红玫瑰（特大）")
+	}
+	if got.Stock != 70 {
+		t.Errorf("Stock = %d, want 70 (concurrent deduction lost)", got.Stock)
+	}
+}
+
+// TestFlowerService_CloneFlower 测试以已有鲜花为模板创建新 SKU
+func TestFlowerService_CloneFlower(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	service, _ := setupTestService(t)
+	ctx := context.Background()
+
+	maxQty := 5
+	if err := service.CreateFlower(ctx, &CreateFlowerRequest{
+		SKU:           "ROS001",
+		Name:          "红玫瑰",
+		Origin:        "云南",
+		ShelfLife:     "7天",
+		Preservation:  "冷藏",
+		PurchasePrice: 50.00,
+		SalePrice:     100.00,
+		Stock:         100,
+		MaxOrderQty:   &maxQty,
+	}); err != nil {
+		t.Fatalf("failed to create flower: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		sourceSKU string
+		newSKU    string
+		overrides *UpdateFlowerRequest
+		wantName  string
+		wantPrice float64
+		wantErr   error
+	}{
+		{name: "仅复制", sourceSKU: "ROS001", newSKU: "ROS002", wantName: "红玫瑰", wantPrice: 100.00},
+		{
+			name:      "带覆盖字段",
+			sourceSKU: "ROS001",
+			newSKU:    "ROS003",
+			overrides: &UpdateFlowerRequest{Name: stringPtr("粉玫瑰"), SalePrice: float64Ptr(120.00)},
+			wantName:  "粉玫瑰",
+			wantPrice: 120.00,
+		},
+		{name: "新 SKU 已存在", sourceSKU: "ROS001", newSKU: "ROS001", wantErr: ErrFlowerExists},
+		{name: "源鲜花不存在", sourceSKU: "NOPE", newSKU: "ROS004", wantErr: ErrFlowerNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := service.CloneFlower(ctx, tt.sourceSKU, tt.newSKU, tt.overrides)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CloneFlower() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CloneFlower() unexpected error = %v", err)
+			}
+
+			got, err := service.GetFlower(ctx, tt.newSKU)
+			if err != nil {
+				t.Fatalf("GetFlower() error = %v", err)
+			}
+			if got.Stock != 0 || resp.Stock != 0 {
+				t.Errorf("stock = %d, want 0", got.Stock)
+			}
+			if got.Name != tt.wantName || got.SalePrice.ToFloat64() != tt.wantPrice {
+				t.Errorf("clone = (%s, %v), want (%s, %v)", got.Name, got.SalePrice, tt.wantName, tt.wantPrice)
+			}
+			if got.Origin != "云南" || got.Preservation != "冷藏" {
+				t.Errorf("clone did not copy source fields: %+v", got)
+			}
+			if got.MaxOrderQty == nil || *got.MaxOrderQty != maxQty {
+				t.Errorf("MaxOrderQty = %v, want %d", got.MaxOrderQty, maxQty)
+			}
+		})
+	}
+
+	// 源鲜花保持不变
+	source, _ := service.GetFlower(ctx, "ROS001")
+	if source.Stock != 100 || source.Name != "红玫瑰" {
+		t.Errorf("source flower changed: %+v", source)
+	}
+}
+
+// TestFlowerService_BatchAddStock 测试批量入库整批生效，任一项无效时整批回滚
+func TestFlowerService_BatchAddStock(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupTestDB(t)
+	service := NewFlowerServiceWithThreshold(NewFlowerRepository(db), NewFlowerLogRepository(db), DefaultLowStockThreshold)
+	ctx := context.Background()
+	for _, sku := range []string{"BAT001", "BAT002"} {
+		if err := service.CreateFlower(ctx, &CreateFlowerRequest{
+			SKU: sku, Name: "红玫瑰", Origin: "云南", PurchasePrice: 50, SalePrice: 100, Stock: 10,
+		}); err != nil {
+			t.Fatalf("CreateFlower() error = %v", err)
+		}
+	}
+
+	stockOf := func(sku string) int {
+		t.Helper()
+		f, err := service.GetFlower(ctx, sku)
+		if err != nil {
+			t.Fatalf("GetFlower() error = %v", err)
+		}
+		return f.Stock
+	}
+
+	tests := []struct {
+		name  string
+		items []*StockAdjustment
+	}{
+		{name: "空列表", items: nil},
+		{name: "数量为负", items: []*StockAdjustment{{SKU: "BAT001", Quantity: 5}, {SKU: "BAT002", Quantity: -1}}},
+		{name: "SKU 重复", items: []*StockAdjustment{{SKU: "BAT001", Quantity: 5}, {SKU: "BAT001", Quantity: 1}}},
+		{name: "SKU 不存在整批回滚", items: []*StockAdjustment{{SKU: "BAT001", Quantity: 5}, {SKU: "NOPE", Quantity: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.BatchAddStock(ctx, tt.items, 7); apperror.KindOf(err) != apperror.KindValidation {
+				t.Fatalf("BatchAddStock() error = %v, want validation error", err)
+			}
+			if got := stockOf("BAT001"); got != 10 {
+				t.Errorf("BAT001 stock = %d, want unchanged 10", got)
+			}
+		})
+	}
+
+	results, err := service.BatchAddStock(ctx, []*StockAdjustment{{SKU: "BAT001", Quantity: 5}, {SKU: "BAT002", Quantity: 20}}, 7)
+	if err != nil {
+		t.Fatalf("BatchAddStock() error = %v", err)
+	}
+	if len(results) != 2 || results[0].PreviousStock != 10 || results[0].Stock != 15 || results[1].Stock != 30 {
+		t.Errorf("results = %+v, %+v, want BAT001 10->15 and BAT002 10->30", results[0], results[1])
+	}
+	if stockOf("BAT001") != 15 || stockOf("BAT002") != 30 {
+		t.Errorf("stock after batch = %d, %d, want 15, 30", stockOf("BAT001"), stockOf("BAT002"))
+	}
+	if logs, _ := service.GetFlowerLogs(ctx, "BAT002"); len(logs) != 1 || logs[0].Action != LogActionAddStock {
+		t.Errorf("BAT002 logs = %+v, want one add_stock log", logs)
+	}
+}
+
+// TestFlowerService_AuditLog 测试修改价格、进货与下架时记录操作人及变更前后的值
+func TestFlowerService_AuditLog(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupTestDB(t)
+	service := NewFlowerServiceWithThreshold(NewFlowerRepository(db), NewFlowerLogRepository(db), DefaultLowStockThreshold)
+	ctx := context.Background()
+
+	if err := service.CreateFlower(ctx, &CreateFlowerRequest{
+		SKU: "AUD001", Name: "红玫瑰", Origin: "云南", ShelfLife: "7天", Preservation: "冷藏",
+		PurchasePrice: 10, SalePrice: 15, Stock: 100,
+	}); err != nil {
+		t.Fatalf("CreateFlower() error = %v", err)
+	}
+
+	if err := service.AddStock(ctx, "AUD001", 20, 7); err != nil {
+		t.Fatalf("AddStock() error = %v", err)
+	}
+	logs, err := service.GetFlowerLogs(ctx, "AUD001")
+	if err != nil {
+		t.Fatalf("GetFlowerLogs() error = %v", err)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("GetFlowerLogs() after AddStock count = %d, want 1", len(logs))
+	}
+	if got := logs[0]; got.Action != LogActionAddStock || got.OperatorID != 7 ||
+		string(got.OldValue) != `{"stock":100}` || string(got.NewValue) != `{"stock":120}` {
+		t.Errorf("add_stock log = %+v (old %s, new %s)", got, got.OldValue, got.NewValue)
+	}
+
+	salePrice := 18.5
+	sameName := "红玫瑰"
+	if err := service.UpdateFlower(ctx, "AUD001", &UpdateFlowerRequest{Name: &sameName, SalePrice: &salePrice}, 8); err != nil {
+		t.Fatalf("UpdateFlower() error = %v", err)
+	}
+	if err := service.DeleteFlower(ctx, "AUD001", 9); err != nil {
+		t.Fatalf("DeleteFlower() error = %v", err)
+	}
+
+	logs, err = service.GetFlowerLogs(ctx, "AUD001")
+	if err != nil {
+		t.Fatalf("GetFlowerLogs() error = %v", err)
+	}
+	if len(logs) != 3 {
+		t.Fatalf("GetFlowerLogs() count = %d, want 3", len(logs))
+	}
+	// 最新在前；未变化的字段（名称）不记录
+	if got := logs[1]; got.Action != LogActionUpdate || got.OperatorID != 8 ||
+		string(got.OldValue) != `{"sale_price":"15.00"}` || string(got.NewValue) != `{"sale_price":"18.50"}` {
+		t.Errorf("update log = %+v (old %s, new %s)", got, got.OldValue, got.NewValue)
+	}
+	if got := logs[0]; got.Action != LogActionDeactivate || got.OperatorID != 9 {
+		t.Errorf("deactivate log = %+v", got)
+	}
+
+	// 校验失败的修改不记录日志
+	lowPrice := 1.0
+	if err := service.UpdateFlower(ctx, "AUD001", &UpdateFlowerRequest{SalePrice: &lowPrice}, 8); err == nil {
+		t.Fatal("UpdateFlower() with sale price below purchase price should fail")
+	}
+	if logs, _ := service.GetFlowerLogs(ctx, "AUD001"); len(logs) != 3 {
+		t.Errorf("GetFlowerLogs() after failed update count = %d, want 3", len(logs))
+	}
+}
@@ -1,11 +1,8 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
-	"strconv"
-	"strings"
 
 	"github.com/biqiangwu/flowerSalesSystem/internal/address"
 	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
@@ -19,16 +16,24 @@ func (h *Handler) HandleListAddresses(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 从上下文获取用户信息
-	u, ok := middleware.GetUserFromContext(r)
+	u, ok := middleware.UserFromContext(r.Context())
 	if !ok {
 		h.respondError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
-	ctx := context.Background()
-	addresses, err := h.addressService.ListAddresses(ctx, u.ID)
+	// 未指定 page_size 时返回全部地址；指定时 page 默认为 1
+	filter := address.AddressFilter{
+		PageSize: parseIntQuery(r.URL.Query().Get("page_size"), 0),
+	}
+	if filter.PageSize != 0 {
+		filter.Page = parseIntQuery(r.URL.Query().Get("page"), 1)
+	}
+
+	ctx := r.Context()
+	addresses, err := h.addressService.ListAddresses(ctx, u.ID, filter)
 	if err != nil {
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+		h.respondServiceError(w, r, err)
 		return
 	}
 
@@ -43,7 +48,7 @@ func (h *Handler) HandleCreateAddress(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 从上下文获取用户信息
-	u, ok := middleware.GetUserFromContext(r)
+	u, ok := middleware.UserFromContext(r.Context())
 	if !ok {
 		h.respondError(w, http.StatusUnauthorized, "unauthorized")
 		return
@@ -55,13 +60,13 @@ func (h *Handler) HandleCreateAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := r.Context()
 	if err := h.addressService.CreateAddress(ctx, u.ID, &address.CreateAddressRequest{
 		Label:   req.Label,
 		Address: req.Address,
 		Contact: req.Contact,
 	}); err != nil {
-		h.respondError(w, http.StatusBadRequest, err.Error())
+		h.respondServiceError(w, r, err)
 		return
 	}
 
@@ -78,15 +83,15 @@ func (h *Handler) HandleUpdateAddress(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 从上下文获取用户信息
-	u, ok := middleware.GetUserFromContext(r)
+	u, ok := middleware.UserFromContext(r.Context())
 	if !ok {
 		h.respondError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	// 从 URL 获取地址ID
-	addressID := extractAddressID(r.URL.Path)
-	if addressID <= 0 {
+	addressID, err := pathIDParam(r, "id")
+	if err != nil {
 		h.respondError(w, http.StatusBadRequest, "invalid address id")
 		return
 	}
@@ -97,7 +102,7 @@ func (h *Handler) HandleUpdateAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := r.Context()
 	updateReq := &address.UpdateAddressRequest{}
 	if req.Label != nil {
 		updateReq.Label = req.Label
@@ -110,15 +115,7 @@ func (h *Handler) HandleUpdateAddress(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.addressService.UpdateAddress(ctx, u.ID, addressID, updateReq); err != nil {
-		if strings.Contains(err.Error(), "不存在") {
-			h.respondError(w, http.StatusNotFound, "address not found")
-			return
-		}
-		if strings.Contains(err.Error(), "无权") {
-			h.respondError(w, http.StatusForbidden, err.Error())
-			return
-		}
-		h.respondError(w, http.StatusBadRequest, err.Error())
+		h.respondServiceError(w, r, err)
 		return
 	}
 
@@ -135,30 +132,22 @@ func (h *Handler) HandleDeleteAddress(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 从上下文获取用户信息
-	u, ok := middleware.GetUserFromContext(r)
+	u, ok := middleware.UserFromContext(r.Context())
 	if !ok {
 		h.respondError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	// 从 URL 获取地址ID
-	addressID := extractAddressID(r.URL.Path)
-	if addressID <= 0 {
+	addressID, err := pathIDParam(r, "id")
+	if err != nil {
 		h.respondError(w, http.StatusBadRequest, "invalid address id")
 		return
 	}
 
-	ctx := context.Background()
+	ctx := r.Context()
 	if err := h.addressService.DeleteAddress(ctx, u.ID, addressID); err != nil {
-		if strings.Contains(err.Error(), "不存在") {
-			h.respondError(w, http.StatusNotFound, "address not found")
-			return
-		}
-		if strings.Contains(err.Error(), "无权") {
-			h.respondError(w, http.StatusForbidden, err.Error())
-			return
-		}
-		h.respondError(w, http.StatusBadRequest, err.Error())
+		h.respondServiceError(w, r, err)
 		return
 	}
 
@@ -166,14 +155,3 @@ func (h *Handler) HandleDeleteAddress(w http.ResponseWriter, r *http.Request) {
 		"message": "address deleted successfully",
 	})
 }
-
-// extractAddressID 从 URL 路径中提取地址ID
-func extractAddressID(path string) int {
-	parts := strings.Split(strings.Trim(path, "/"), "/")
-	if len(parts) >= 3 && parts[0] == "api" && parts[1] == "addresses" {
-		if id, err := strconv.Atoi(parts[2]); err == nil {
-			return id
-		}
-	}
-	return 0
-}
@@ -2,7 +2,10 @@ package handler
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/biqiangwu/flowerSalesSystem/internal/address"
@@ -23,6 +26,7 @@ func setupAddressTestDB(t *testing.T) *sql.DB {
 	CREATE TABLE IF NOT EXISTS users (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		username TEXT UNIQUE NOT NULL,
+		email TEXT UNIQUE,
 		password_hash TEXT NOT NULL,
 		role TEXT NOT NULL DEFAULT 'customer',
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -96,7 +100,7 @@ func TestHandleListAddresses(t *testing.T) {
 		}
 	}
 
-	addresses, err := handler.addressService.ListAddresses(ctx, testUser.ID)
+	addresses, err := handler.addressService.ListAddresses(ctx, testUser.ID, address.AddressFilter{})
 	if err != nil {
 		t.Fatalf("failed to list addresses: %v", err)
 	}
@@ -105,3 +109,74 @@ func TestHandleListAddresses(t *testing.T) {
 		t.Errorf("got %d addresses, want 2", len(addresses))
 	}
 }
+
+// TestHandleListAddresses_Paging 测试地址列表分页参数
+func TestHandleListAddresses_Paging(t *testing.T) {
+	ctx, db := setupUserTestHandler(t)
+	handler := ctx.handler
+
+	createTableSQL := `
+	CREATE TABLE IF NOT EXISTS addresses (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		label TEXT,
+		address TEXT NOT NULL,
+		contact TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	if _, err := db.Exec(createTableSQL); err != nil {
+		t.Fatalf("failed to create addresses table: %v", err)
+	}
+	handler.addressService = address.NewAddressService(address.NewAddressRepository(db))
+
+	customer, token := createTestUserWithSession(t, ctx, "customer", user.RoleCustomer)
+	for i := 1; i <= 3; i++ {
+		req := &address.CreateAddressRequest{
+			Address: fmt.Sprintf("测试地址%d号", i),
+			Contact: "13800138000",
+		}
+		if err := handler.addressService.CreateAddress(t.Context(), customer.ID, req); err != nil {
+			t.Fatalf("failed to create test address: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantCount  int
+	}{
+		{"默认返回全部", "", http.StatusOK, 3},
+		{"分页第一页", "?page=1&page_size=2", http.StatusOK, 2},
+		{"分页第二页", "?page=2&page_size=2", http.StatusOK, 1},
+		{"仅指定每页数量", "?page_size=2", http.StatusOK, 2},
+		{"超过最大每页数量", fmt.Sprintf("?page=1&page_size=%d", address.MaxPageSize+1), http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/addresses"+tt.query, nil)
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: token})
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleListAddresses() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var addresses []*address.AddressResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &addresses); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if len(addresses) != tt.wantCount {
+				t.Errorf("HandleListAddresses() count = %d, want %d", len(addresses), tt.wantCount)
+			}
+		})
+	}
+}
@@ -1,9 +1,15 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
 )
 
 const (
@@ -11,6 +17,57 @@ const (
 	CookieName = "session_token"
 )
 
+// SessionCookieOptions Session Cookie 的属性，登录设置与登出清除时使用相同的属性
+type SessionCookieOptions struct {
+	Path     string        // 为空时使用 "/"
+	Domain   string        // 为空时仅对当前主机有效
+	SameSite http.SameSite // 为零值时使用 Lax
+	Secure   bool          // 启用 TLS 时应为 true
+}
+
+// ParseSameSite 解析 SameSite 配置，可选 lax/strict，空字符串视为 lax
+func ParseSameSite(s string) (http.SameSite, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	default:
+		return 0, fmt.Errorf("无效的 SameSite: %s（可选 lax/strict）", s)
+	}
+}
+
+// sessionCookie 按配置的属性创建 Session Cookie，maxAge < 0 表示清除
+func (h *Handler) sessionCookie(value string, maxAge int) *http.Cookie {
+	return h.newCookie(CookieName, value, maxAge, true)
+}
+
+// csrfCookie 创建 CSRF 令牌 Cookie，属性与 Session Cookie 一致，但前端脚本需读取后回传，不能设为 HttpOnly
+func (h *Handler) csrfCookie(value string, maxAge int) *http.Cookie {
+	return h.newCookie(middleware.CSRFCookieName, value, maxAge, false)
+}
+
+// newCookie 按配置的 Path、Domain、SameSite 与 Secure 属性创建 Cookie
+func (h *Handler) newCookie(name, value string, maxAge int, httpOnly bool) *http.Cookie {
+	opts := h.cookieOptions
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+	if opts.SameSite == 0 {
+		opts.SameSite = http.SameSiteLaxMode
+	}
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		MaxAge:   maxAge,
+		HttpOnly: httpOnly,
+		Secure:   opts.Secure,
+		SameSite: opts.SameSite,
+	}
+}
+
 // HandleRegister 处理用户注册
 func (h *Handler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -24,16 +81,16 @@ func (h *Handler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := r.Context()
 
 	// 注册用户
-	u, err := h.authService.Register(ctx, req.Username, req.Password)
+	u, err := h.authService.RegisterWithEmail(ctx, req.Username, req.Password, req.Email)
+	if errors.Is(err, user.ErrUsernameTaken) {
+		h.respondError(w, http.StatusConflict, user.ErrUsernameTaken.Error())
+		return
+	}
 	if err != nil {
-		if containsString(err.Error(), "already exists") {
-			h.respondError(w, http.StatusConflict, "username already exists")
-			return
-		}
-		h.respondError(w, http.StatusBadRequest, err.Error())
+		h.respondServiceError(w, r, err)
 		return
 	}
 
@@ -42,6 +99,7 @@ func (h *Handler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 		"user": map[string]interface{}{
 			"id":       u.ID,
 			"username": u.Username,
+			"email":    u.Email,
 			"role":     u.Role,
 		},
 	})
@@ -60,26 +118,23 @@ func (h *Handler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := r.Context()
 	session, err := h.authService.Login(ctx, req.Username, req.Password)
 	if err != nil {
-		if containsString(err.Error(), "invalid") {
-			h.respondError(w, http.StatusUnauthorized, "invalid username or password")
-			return
-		}
-		h.respondError(w, http.StatusBadRequest, err.Error())
+		h.respondServiceError(w, r, err)
 		return
 	}
 
-	// 设置 Session Cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     CookieName,
-		Value:    session.Token,
-		Path:     "/",
-		MaxAge:   86400, // 24 小时
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-	})
+	// 设置 Session Cookie 与 CSRF 令牌 Cookie，之后的非 GET 请求需在 X-CSRF-Token 请求头中回传令牌
+	csrfToken, err := middleware.NewCSRFToken()
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+	// Cookie 与会话同时过期，有效期由 SESSION_EXPIRY 决定
+	maxAge := max(int(time.Until(session.ExpiresAt).Seconds()), 1)
+	http.SetCookie(w, h.sessionCookie(session.Token, maxAge))
+	http.SetCookie(w, h.csrfCookie(csrfToken, maxAge))
 
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
 		"message": "login successful",
@@ -105,53 +160,17 @@ func (h *Handler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := r.Context()
 	if err := h.authService.Logout(ctx, cookie.Value); err != nil {
 		h.respondError(w, http.StatusUnauthorized, "invalid session")
 		return
 	}
 
-	// 清除 Session Cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     CookieName,
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-	})
+	// 清除 Session Cookie 与 CSRF 令牌 Cookie，Path 与 Domain 必须与设置时一致才能覆盖
+	http.SetCookie(w, h.sessionCookie("", -1))
+	http.SetCookie(w, h.csrfCookie("", -1))
 
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
 		"message": "logout successful",
 	})
 }
-
-// containsString 检查字符串是否包含子串（忽略大小写）
-func containsString(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && containsIgnoreCase(s, substr))
-}
-
-func containsIgnoreCase(s, substr string) bool {
-	// 简单实现，实际项目中可以使用 strings.ToLower
-	for i := 0; i <= len(s)-len(substr); i++ {
-		match := true
-		for j := 0; j < len(substr); j++ {
-			c1 := s[i+j]
-			c2 := substr[j]
-			if c1 >= 'A' && c1 <= 'Z' {
-				c1 += 32
-			}
-			if c2 >= 'A' && c2 <= 'Z' {
-				c2 += 32
-			}
-			if c1 != c2 {
-				match = false
-				break
-			}
-		}
-		if match {
-			return true
-		}
-	}
-	return false
-}
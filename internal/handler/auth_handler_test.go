@@ -8,9 +8,11 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/biqiangwu/flowerSalesSystem/internal/auth"
 	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
 	_ "github.com/mattn/go-sqlite3" // SQLite 驱动用于测试
 )
 
@@ -27,6 +29,7 @@ func setupTestDB(t *testing.T) *sql.DB {
 	CREATE TABLE IF NOT EXISTS users (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		username TEXT UNIQUE NOT NULL,
+		email TEXT UNIQUE,
 		password_hash TEXT NOT NULL,
 		role TEXT NOT NULL DEFAULT 'customer',
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -208,9 +211,9 @@ func TestHandleLogin(t *testing.T) {
 	}
 
 	tests := []struct {
-		name       string
-		request    LoginRequest
-		wantStatus int
+		name        string
+		request     LoginRequest
+		wantStatus  int
 		checkCookie func(*testing.T, []*http.Cookie)
 	}{
 		{
@@ -291,6 +294,124 @@ func TestHandleLogin(t *testing.T) {
 	}
 }
 
+// TestHandleLogin_CookieAttributes 测试登录与登出的 Session Cookie 与 CSRF Cookie 使用配置的 SameSite、Secure、Path 与 Domain
+func TestHandleLogin_CookieAttributes(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         SessionCookieOptions
+		wantSameSite http.SameSite
+		wantSecure   bool
+		wantPath     string
+		wantDomain   string
+	}{
+		{name: "默认", wantSameSite: http.SameSiteLaxMode, wantPath: "/"},
+		{
+			name:         "Strict 且启用 TLS",
+			opts:         SessionCookieOptions{Path: "/api", Domain: "example.com", SameSite: http.SameSiteStrictMode, Secure: true},
+			wantSameSite: http.SameSiteStrictMode,
+			wantSecure:   true,
+			wantPath:     "/api",
+			wantDomain:   "example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := setupTestHandler(t)
+			handler.SetSessionCookieOptions(tt.opts)
+
+			body, _ := json.Marshal(RegisterRequest{Username: "cookieuser", Password: "cookiepass123"})
+			handler.HandleRegister(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/register", bytes.NewReader(body)))
+
+			body, _ = json.Marshal(LoginRequest{Username: "cookieuser", Password: "cookiepass123"})
+			w := httptest.NewRecorder()
+			handler.HandleLogin(w, httptest.NewRequest("POST", "/api/login", bytes.NewReader(body)))
+			login := cookiesByName(w.Result().Cookies())
+			if w.Code != http.StatusOK || login[CookieName] == nil || login[middleware.CSRFCookieName] == nil {
+				t.Fatalf("HandleLogin() status = %d, cookies = %v", w.Code, login)
+			}
+
+			req := httptest.NewRequest("POST", "/api/logout", nil)
+			req.AddCookie(&http.Cookie{Name: CookieName, Value: login[CookieName].Value})
+			w = httptest.NewRecorder()
+			handler.HandleLogout(w, req)
+			logout := cookiesByName(w.Result().Cookies())
+
+			for _, name := range []string{CookieName, middleware.CSRFCookieName} {
+				if logout[name] == nil || logout[name].MaxAge >= 0 {
+					t.Fatalf("logout cookie %s = %v, want expired", name, logout[name])
+				}
+				for event, c := range map[string]*http.Cookie{"login": login[name], "logout": logout[name]} {
+					if c.SameSite != tt.wantSameSite || c.Secure != tt.wantSecure ||
+						c.Path != tt.wantPath || c.Domain != tt.wantDomain {
+						t.Errorf("%s cookie %s = %+v, want SameSite %v, Secure %v, Path %q, Domain %q",
+							event, name, c, tt.wantSameSite, tt.wantSecure, tt.wantPath, tt.wantDomain)
+					}
+				}
+			}
+			// 前端脚本需读取 CSRF 令牌，Session Cookie 则不可读取
+			if !login[CookieName].HttpOnly || login[middleware.CSRFCookieName].HttpOnly {
+				t.Errorf("HttpOnly = (session %v, csrf %v), want (true, false)",
+					login[CookieName].HttpOnly, login[middleware.CSRFCookieName].HttpOnly)
+			}
+		})
+	}
+}
+
+// TestHandleLogin_CookieMaxAge 测试登录 Cookie 的有效期与会话有效期一致
+func TestHandleLogin_CookieMaxAge(t *testing.T) {
+	db := setupTestDB(t)
+	sessionMgr := auth.NewMemorySessionManagerWithExpiry(2 * time.Hour)
+	handler := NewHandler(auth.NewAuthService(user.NewMySQLUserRepository(db), sessionMgr), nil)
+
+	body, _ := json.Marshal(RegisterRequest{Username: "maxageuser", Password: "maxagepass123"})
+	handler.HandleRegister(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/register", bytes.NewReader(body)))
+
+	body, _ = json.Marshal(LoginRequest{Username: "maxageuser", Password: "maxagepass123"})
+	w := httptest.NewRecorder()
+	handler.HandleLogin(w, httptest.NewRequest("POST", "/api/login", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("HandleLogin() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	login := cookiesByName(w.Result().Cookies())
+	for _, name := range []string{CookieName, middleware.CSRFCookieName} {
+		c := login[name]
+		if c == nil || c.MaxAge < 7200-5 || c.MaxAge > 7200 {
+			t.Errorf("cookie %s = %v, want MaxAge about 7200", name, c)
+		}
+	}
+}
+
+// cookiesByName 按名称索引响应 Cookie
+func cookiesByName(cookies []*http.Cookie) map[string]*http.Cookie {
+	byName := make(map[string]*http.Cookie, len(cookies))
+	for _, c := range cookies {
+		byName[c.Name] = c
+	}
+	return byName
+}
+
+// TestParseSameSite 测试 SameSite 配置解析
+func TestParseSameSite(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    http.SameSite
+		wantErr bool
+	}{
+		{input: "", want: http.SameSiteLaxMode},
+		{input: "Lax", want: http.SameSiteLaxMode},
+		{input: " strict ", want: http.SameSiteStrictMode},
+		{input: "none", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSameSite(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSameSite(%q) = (%v, %v), want (%v, wantErr %v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
 // TestHandleLogout 测试登出接口
 func TestHandleLogout(t *testing.T) {
 	handler := setupTestHandler(t)
@@ -373,6 +494,48 @@ func TestHandleLogout_MissingCookie(t *testing.T) {
 	}
 }
 
+// TestRegisterRoutes_LoginRateLimit 测试登录接口超过限流配额后返回 429，注册接口共享配额，登出不受限
+func TestRegisterRoutes_LoginRateLimit(t *testing.T) {
+	const limit = 3
+	handler := setupTestHandler(t)
+	handler.SetLoginRateLimit(limit, time.Minute)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	send := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(LoginRequest{Username: "nobody", Password: "wrongpassword"})
+		req := httptest.NewRequest("POST", path, bytes.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 1; i <= limit; i++ {
+		if w := send("/api/login", "192.0.2.1:1000"); w.Code == http.StatusTooManyRequests {
+			t.Fatalf("login %d status = 429 before reaching limit", i)
+		}
+	}
+
+	w := send("/api/login", "192.0.2.1:1000")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("login %d status = %d, want %d", limit+1, w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("rate limited response should set Retry-After")
+	}
+
+	if w := send("/api/register", "192.0.2.1:1000"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("register status = %d, want %d (shared quota)", w.Code, http.StatusTooManyRequests)
+	}
+	if w := send("/api/logout", "192.0.2.1:1000"); w.Code == http.StatusTooManyRequests {
+		t.Error("logout should not be rate limited")
+	}
+	if w := send("/api/login", "192.0.2.2:1000"); w.Code == http.StatusTooManyRequests {
+		t.Error("other client should not be rate limited")
+	}
+}
+
 // TestRespondJSON 测试 JSON 响应辅助函数
 func TestRespondJSON(t *testing.T) {
 	handler := NewHandler(nil, nil)
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/cart"
+	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
+)
+
+// HandleGetCart 获取当前用户的购物车
+func (h *Handler) HandleGetCart(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	resp, err := h.cartService.GetCart(r.Context(), u.ID)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, resp)
+}
+
+// HandleAddCartItem 将鲜花加入购物车，已在购物车中时累加数量，不占用库存
+func (h *Handler) HandleAddCartItem(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req AddCartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	resp, err := h.cartService.AddItem(r.Context(), u.ID, req.FlowerSKU, req.Quantity)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, resp)
+}
+
+// HandleUpdateCartItem 设置购物车中某鲜花的数量，数量为 0 时移除
+func (h *Handler) HandleUpdateCartItem(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req SetCartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	resp, err := h.cartService.UpdateQuantity(r.Context(), u.ID, r.PathValue("sku"), req.Quantity)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, resp)
+}
+
+// HandleRemoveCartItem 从购物车移除鲜花
+func (h *Handler) HandleRemoveCartItem(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	resp, err := h.cartService.RemoveItem(r.Context(), u.ID, r.PathValue("sku"))
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, resp)
+}
+
+// HandleCheckoutCart 结算购物车：校验库存并下单，成功后清空购物车
+func (h *Handler) HandleCheckoutCart(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req CheckoutCartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	orderNo, err := h.cartService.Checkout(r.Context(), u.ID, &cart.CheckoutRequest{
+		AddressID:    req.AddressID,
+		DiscountCode: req.DiscountCode,
+	})
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"message":  "order created successfully",
+		"order_no": orderNo,
+	})
+}
+
+// HandleDraftCartGone 处理已下线的草稿订单购物车接口（PUT /api/cart 与 POST /api/orders/{id}/checkout）
+// 返回 410，提示改用购物车项接口与 POST /api/cart/checkout
+func (h *Handler) HandleDraftCartGone(w http.ResponseWriter, r *http.Request) {
+	h.respondError(w, http.StatusGone, "draft order cart has been replaced: use /api/cart/items and POST /api/cart/checkout")
+}
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"net/http"
+)
+
+// HandleGetConfig 返回当前生效的配置（仅管理员）
+// GET /api/admin/config
+// 数据库密码、Session 密钥、密码 pepper 等敏感字段已脱敏
+func (h *Handler) HandleGetConfig(w http.ResponseWriter, r *http.Request) {
+	if h.config == nil {
+		h.respondError(w, http.StatusServiceUnavailable, "config not available")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, h.config.Redacted())
+}
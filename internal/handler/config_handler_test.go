@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/config"
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+)
+
+// TestHandleGetConfig 测试诊断配置接口的权限与脱敏
+func TestHandleGetConfig(t *testing.T) {
+	ctx, _ := setupUserTestHandler(t)
+	handler := ctx.handler
+	handler.SetConfig(&config.Config{
+		DBHost:                "mysql-service",
+		DBPort:                3306,
+		DBName:                "flower_sales",
+		DBUser:                "flower_user",
+		DBPassword:            "db-secret",
+		SessionSecret:         "session-secret",
+		SessionExpiry:         24,
+		ServerPort:            8080,
+		LogLevel:              "info",
+		StockWarningThreshold: 10,
+	})
+
+	_, adminSession := createTestUserWithSession(t, ctx, "admin", user.RoleAdmin)
+	_, clerkSession := createTestUserWithSession(t, ctx, "clerk", user.RoleClerk)
+
+	tests := []struct {
+		name       string
+		session    string
+		wantStatus int
+	}{
+		{name: "未登录", session: "", wantStatus: http.StatusUnauthorized},
+		{name: "店员无权限", session: clerkSession, wantStatus: http.StatusForbidden},
+		{name: "管理员获取配置", session: adminSession, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/admin/config", nil)
+			if tt.session != "" {
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: tt.session})
+			}
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleGetConfig() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if got["db_password"] != config.RedactedValue {
+				t.Errorf("db_password = %v, want %s", got["db_password"], config.RedactedValue)
+			}
+			if got["session_secret"] != config.RedactedValue {
+				t.Errorf("session_secret = %v, want %s", got["session_secret"], config.RedactedValue)
+			}
+			if got["password_pepper"] != "" {
+				t.Errorf("password_pepper = %v, want empty", got["password_pepper"])
+			}
+			if got["db_host"] != "mysql-service" || got["db_user"] != "flower_user" || got["log_level"] != "info" {
+				t.Errorf("non-secret fields missing: %v", got)
+			}
+			if got["server_port"] != float64(8080) || got["stock_warning_threshold"] != float64(10) {
+				t.Errorf("numeric fields = %v, %v", got["server_port"], got["stock_warning_threshold"])
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/discount"
+)
+
+// toServiceRequest 转换为服务层的优惠码请求
+func (req *DiscountRequest) toServiceRequest() *discount.DiscountRequest {
+	return &discount.DiscountRequest{
+		Code:       req.Code,
+		Type:       discount.Type(req.Type),
+		Value:      req.Value,
+		ValidFrom:  req.ValidFrom,
+		ValidTo:    req.ValidTo,
+		UsageLimit: req.UsageLimit,
+	}
+}
+
+// HandleListDiscounts 处理获取优惠码列表（管理员）
+func (h *Handler) HandleListDiscounts(w http.ResponseWriter, r *http.Request) {
+	discounts, err := h.discountService.ListDiscounts(r.Context())
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, discounts)
+}
+
+// HandleCreateDiscount 处理创建优惠码（管理员）
+func (h *Handler) HandleCreateDiscount(w http.ResponseWriter, r *http.Request) {
+	var req DiscountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	d, err := h.discountService.CreateDiscount(r.Context(), req.toServiceRequest())
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, d)
+}
+
+// HandleGetDiscount 处理获取优惠码详情（管理员）
+func (h *Handler) HandleGetDiscount(w http.ResponseWriter, r *http.Request) {
+	id, err := pathIDParam(r, "id")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid discount id")
+		return
+	}
+
+	d, err := h.discountService.GetDiscount(r.Context(), id)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, d)
+}
+
+// HandleUpdateDiscount 处理更新优惠码（管理员），请求体整体替换优惠码设置
+func (h *Handler) HandleUpdateDiscount(w http.ResponseWriter, r *http.Request) {
+	id, err := pathIDParam(r, "id")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid discount id")
+		return
+	}
+
+	var req DiscountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	d, err := h.discountService.UpdateDiscount(r.Context(), id, req.toServiceRequest())
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, d)
+}
+
+// HandleDeleteDiscount 处理删除优惠码（管理员）
+func (h *Handler) HandleDeleteDiscount(w http.ResponseWriter, r *http.Request) {
+	id, err := pathIDParam(r, "id")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid discount id")
+		return
+	}
+
+	if err := h.discountService.DeleteDiscount(r.Context(), id); err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "discount deleted successfully",
+	})
+}
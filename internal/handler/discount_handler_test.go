@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/address"
+	"github.com/biqiangwu/flowerSalesSystem/internal/discount"
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+	"github.com/biqiangwu/flowerSalesSystem/internal/order"
+)
+
+// TestHandleDiscounts 测试管理员维护优惠码以及顾客下单时使用优惠码
+func TestHandleDiscounts(t *testing.T) {
+	handler, db := setupOrderTestHandler(t)
+	insertTestData(t, db)
+
+	discountRepo := discount.NewDiscountRepository(db)
+	handler.discountService = discount.NewDiscountService(discountRepo)
+	handler.orderService = order.NewOrderServiceWithOptions(order.NewOrderRepository(db), flower.NewFlowerRepository(db),
+		order.NewOrderLogRepository(db), order.OrderServiceOptions{DiscountRepo: discountRepo})
+
+	customerToken := loginUser(t, handler, "customer", "password123")
+	adminToken := loginUser(t, handler, "admin", "password123")
+	if _, err := db.Exec("UPDATE users SET role = 'admin' WHERE username = 'admin'"); err != nil {
+		t.Fatalf("failed to promote admin: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: CookieName, Value: token})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/admin/discounts", adminToken, `{"code":"spring15","type":"percent","value":15,"usage_limit":1}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create discount status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var created discount.Discount
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	path := fmt.Sprintf("/api/admin/discounts/%d", created.ID)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{name: "顾客无权限", method: "POST", path: "/api/admin/discounts", token: customerToken,
+			body: `{"code":"MINE","type":"fixed","value":100}`, wantStatus: http.StatusForbidden},
+		{name: "请求体非法", method: "POST", path: "/api/admin/discounts", token: adminToken, body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "无效类型", method: "POST", path: "/api/admin/discounts", token: adminToken,
+			body: `{"code":"BOGO","type":"bogo","value":1}`, wantStatus: http.StatusBadRequest},
+		{name: "优惠码重复", method: "POST", path: "/api/admin/discounts", token: adminToken,
+			body: `{"code":"SPRING15","type":"fixed","value":100}`, wantStatus: http.StatusConflict},
+		{name: "列表", method: "GET", path: "/api/admin/discounts", token: adminToken, wantStatus: http.StatusOK},
+		{name: "详情", method: "GET", path: path, token: adminToken, wantStatus: http.StatusOK},
+		{name: "不存在", method: "GET", path: "/api/admin/discounts/999", token: adminToken, wantStatus: http.StatusNotFound},
+		{name: "更新", method: "PUT", path: path, token: adminToken,
+			body: `{"code":"SPRING15","type":"percent","value":20,"usage_limit":1}`, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do(tt.method, tt.path, tt.token, tt.body); w.Code != tt.wantStatus {
+				t.Errorf("%s %s status = %d, want %d, body = %s", tt.method, tt.path, w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+
+	// 顾客下单时使用优惠码，用尽后再次使用被拒绝
+	var customerID int
+	if err := db.QueryRow("SELECT id FROM users WHERE username = 'customer'").Scan(&customerID); err != nil {
+		t.Fatalf("failed to query customer: %v", err)
+	}
+	addr := &address.Address{UserID: customerID, Address: "上海市浦东新区", Contact: "13800138000"}
+	if err := address.NewAddressRepository(db).Create(context.Background(), addr); err != nil {
+		t.Fatalf("failed to create address: %v", err)
+	}
+	orderBody := fmt.Sprintf(`{"address_id":%d,"items":[{"flower_sku":"FLW001","quantity":1}],"discount_code":"spring15"}`, addr.ID)
+
+	w = do("POST", "/api/orders", customerToken, orderBody)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create order with discount status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var createResp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &createResp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	resp, err := handler.orderService.GetOrder(context.Background(), customerID, createResp["order_no"])
+	if err != nil {
+		t.Fatalf("GetOrder() error = %v", err)
+	}
+	if resp.TotalAmountCents != 8000 || resp.DiscountAmountCents != 2000 {
+		t.Errorf("total = %d, discount = %d, want 8000 and 2000", resp.TotalAmountCents, resp.DiscountAmountCents)
+	}
+	if w := do("POST", "/api/orders", customerToken, orderBody); w.Code != http.StatusBadRequest {
+		t.Errorf("create order with exhausted discount status = %d, want 400, body = %s", w.Code, w.Body.String())
+	}
+
+	if w := do("DELETE", path, adminToken, ""); w.Code != http.StatusOK {
+		t.Errorf("delete discount status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", path, adminToken, ""); w.Code != http.StatusNotFound {
+		t.Errorf("get deleted discount status = %d, want 404", w.Code)
+	}
+}
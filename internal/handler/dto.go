@@ -1,9 +1,12 @@
 package handler
 
+import "time"
+
 // RegisterRequest 注册请求
 type RegisterRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Email    string `json:"email,omitempty"` // 可选
 }
 
 // LoginRequest 登录请求
@@ -14,46 +17,178 @@ type LoginRequest struct {
 
 // ErrorResponse 错误响应
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"` // 请求 ID，与 X-Request-ID 响应头一致，便于排查日志
+}
+
+// ListResponse 列表接口的分页响应
+type ListResponse struct {
+	Data       interface{} `json:"data"`
+	Total      int         `json:"total"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"page_size"`
+	TotalPages int         `json:"total_pages"`
+}
+
+// newListResponse 根据总数与分页参数构建分页响应
+func newListResponse(data interface{}, total, page, pageSize int) *ListResponse {
+	totalPages := 0
+	if pageSize > 0 {
+		totalPages = (total + pageSize - 1) / pageSize
+	}
+	return &ListResponse{
+		Data:       data,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}
 }
 
 // CreateOrderRequest 创建订单请求
 type CreateOrderRequest struct {
-	AddressID int                      `json:"address_id"`
-	Items     []*CreateOrderItemRequest `json:"items"`
+	AddressID    int                       `json:"address_id"`
+	Items        []*CreateOrderItemRequest `json:"items"`
+	DiscountCode string                    `json:"discount_code,omitempty"` // 可选优惠码
 }
 
 // CreateOrderItemRequest 创建订单项请求
+// FlowerSKU 与 FlowerName 二选一，优先使用 FlowerSKU
 type CreateOrderItemRequest struct {
+	FlowerSKU  string `json:"flower_sku"`
+	FlowerName string `json:"flower_name,omitempty"`
+	Quantity   int    `json:"quantity"`
+}
+
+// AddCartItemRequest 加入购物车请求
+type AddCartItemRequest struct {
 	FlowerSKU string `json:"flower_sku"`
 	Quantity  int    `json:"quantity"`
 }
 
+// SetCartItemRequest 设置购物车商品数量请求，数量为 0 时移除
+type SetCartItemRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+// CheckoutCartRequest 结算购物车请求
+type CheckoutCartRequest struct {
+	AddressID    int    `json:"address_id"`
+	DiscountCode string `json:"discount_code,omitempty"`
+}
+
+// CancelOrderRequest 取消订单请求，请求体可省略
+type CancelOrderRequest struct {
+	Reason string `json:"reason"`
+}
+
+// UpdateOrderItemRequest 修改订单项数量请求
+type UpdateOrderItemRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+// BulkCancelOrdersRequest 批量取消订单请求
+type BulkCancelOrdersRequest struct {
+	OrderIDs []int `json:"order_ids"`
+}
+
 // ResetPasswordRequest 重置密码请求
 type ResetPasswordRequest struct {
 	NewPassword string `json:"new_password"`
 }
 
+// UserResponse 用户信息响应（不含密码哈希）
+type UserResponse struct {
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	Email     string `json:"email,omitempty"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+	// ImpersonatorID 代客登录会话中发起模拟的管理员 ID，仅 GET /me 返回，前端据此提示当前为代客登录
+	ImpersonatorID int `json:"impersonator_id,omitempty"`
+}
+
+// UpdateProfileRequest 修改本人资料请求，email 为空时清除邮箱
+type UpdateProfileRequest struct {
+	Email string `json:"email"`
+}
+
+// RequestPasswordResetRequest 申请重置密码请求，用户名与邮箱二选一
+type RequestPasswordResetRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// ConfirmPasswordResetRequest 使用重置令牌设置新密码请求
+type ConfirmPasswordResetRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// ChangePasswordRequest 修改本人密码请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
 // CreateFlowerRequest 创建鲜花请求
 type CreateFlowerRequest struct {
-	SKU           string  `json:"sku"`
-	Name          string  `json:"name"`
-	Origin        string  `json:"origin"`
-	ShelfLife     string  `json:"shelf_life"`
-	Preservation  string  `json:"preservation"`
-	PurchasePrice float64 `json:"purchase_price"`
-	SalePrice     float64 `json:"sale_price"`
-	Stock         int     `json:"stock"`
+	SKU           string     `json:"sku"`
+	Name          string     `json:"name"`
+	Origin        string     `json:"origin"`
+	ShelfLife     string     `json:"shelf_life"`
+	Preservation  string     `json:"preservation"`
+	PurchasePrice float64    `json:"purchase_price"`
+	SalePrice     float64    `json:"sale_price"`
+	DiscountPrice *float64   `json:"discount_price,omitempty"`
+	DiscountUntil *time.Time `json:"discount_until,omitempty"`
+	Stock         int        `json:"stock"`
+	MaxOrderQty   *int       `json:"max_order_qty,omitempty"`
+}
+
+// ImportFlowersResponse 批量导入鲜花结果，合法行已导入，失败行列在 Errors 中
+type ImportFlowersResponse struct {
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Errors  []*ImportRowError `json:"errors"`
+	Error   string            `json:"error,omitempty"` // 读取中断时的原因，此前的行已导入
+}
+
+// addError 记录一个导入失败的行
+func (r *ImportFlowersResponse) addError(row int, message string) {
+	r.Failed++
+	r.Errors = append(r.Errors, &ImportRowError{Row: row, Message: message})
+}
+
+// ImportRowError 导入失败的 CSV 行及原因
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// BatchAddStockItem 批量入库请求中的一项
+type BatchAddStockItem struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
 }
 
 // UpdateFlowerRequest 更新鲜花请求
 type UpdateFlowerRequest struct {
-	Name          *string  `json:"name,omitempty"`
-	Origin        *string  `json:"origin,omitempty"`
-	ShelfLife     *string  `json:"shelf_life,omitempty"`
-	Preservation  *string  `json:"preservation,omitempty"`
-	PurchasePrice *float64 `json:"purchase_price,omitempty"`
-	SalePrice     *float64 `json:"sale_price,omitempty"`
+	Name          *string    `json:"name,omitempty"`
+	Origin        *string    `json:"origin,omitempty"`
+	ShelfLife     *string    `json:"shelf_life,omitempty"`
+	Preservation  *string    `json:"preservation,omitempty"`
+	PurchasePrice *float64   `json:"purchase_price,omitempty"`
+	SalePrice     *float64   `json:"sale_price,omitempty"`
+	DiscountPrice *float64   `json:"discount_price,omitempty"` // 设置为 0 表示取消促销
+	DiscountUntil *time.Time `json:"discount_until,omitempty"`
+	MaxOrderQty   *int       `json:"max_order_qty,omitempty"`
+}
+
+// CloneFlowerRequest 克隆鲜花请求，未提供的字段沿用源鲜花
+type CloneFlowerRequest struct {
+	NewSKU string `json:"new_sku"`
+	UpdateFlowerRequest
 }
 
 // CreateAddressRequest 创建地址请求
@@ -74,3 +209,14 @@ type UpdateAddressRequest struct {
 type AddStockRequest struct {
 	Quantity int `json:"quantity"`
 }
+
+// DiscountRequest 创建或更新优惠码请求
+// type 为 percent 时 value 为减免百分比（1-100），为 fixed 时 value 为减免金额（分）
+type DiscountRequest struct {
+	Code       string     `json:"code"`
+	Type       string     `json:"type"`
+	Value      int64      `json:"value"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty"` // RFC 3339，如 2026-02-14T00:00:00+08:00
+	ValidTo    *time.Time `json:"valid_to,omitempty"`
+	UsageLimit int        `json:"usage_limit"` // 0 表示不限次数
+}
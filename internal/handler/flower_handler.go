@@ -1,13 +1,20 @@
 package handler
 
 import (
-	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
 )
 
 // HandleListFlowers 处理获取鲜花列表
@@ -17,29 +24,161 @@ func (h *Handler) HandleListFlowers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 解析查询参数
-	filter := flower.FlowerFilter{
+	// 解析查询参数；按毛利排序仅管理视图可用
+	filter := parseFlowerFilter(r)
+	if filter.SortBy == flower.SortMarginAsc || filter.SortBy == flower.SortMarginDesc {
+		h.respondError(w, http.StatusBadRequest, "invalid sort_by")
+		return
+	}
+
+	// 库存预警阈值覆盖仅管理员和店员可用
+	threshold, err := parseLowStockThreshold(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if threshold > 0 {
+		operator, err := h.getUserFromSession(r)
+		if err != nil {
+			h.respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if operator.Role != user.RoleAdmin && operator.Role != user.RoleClerk {
+			h.respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		filter.LowStockThreshold = threshold
+	}
+
+	withTotal, err := parseWithTotal(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	arrayFormat, err := parseArrayFormat(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	flowers, hasMore, err := h.flowerService.ListFlowersWithMore(ctx, filter)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	total, ok := h.flowerTotal(w, r, filter, withTotal, arrayFormat)
+	if !ok {
+		return
+	}
+	w.Header().Set(HasMoreHeader, strconv.FormatBool(hasMore))
+
+	// 公开列表不返回进价等成本信息
+	public := make([]*flower.PublicFlowerResponse, len(flowers))
+	for i, f := range flowers {
+		public[i] = f.Public()
+	}
+	h.respondList(w, arrayFormat, public, public, total, filter.Page, filter.PageSize)
+}
+
+// HandleAdminListFlowers 处理管理视图鲜花列表（仅管理员和店员）
+// 在公开列表的基础上返回进价与毛利，并支持按毛利排序
+func (h *Handler) HandleAdminListFlowers(w http.ResponseWriter, r *http.Request) {
+	// 管理视图默认包含已下架鲜花，include_inactive=false 时仅显示上架鲜花
+	filter := parseFlowerFilter(r)
+	filter.IncludeInactive = r.URL.Query().Get("include_inactive") != "false"
+	var err error
+	if filter.LowStockThreshold, err = parseLowStockThreshold(r); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	withTotal, err := parseWithTotal(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	arrayFormat, err := parseArrayFormat(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	flowers, hasMore, err := h.flowerService.ListAdminFlowersWithMore(r.Context(), filter)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	total, ok := h.flowerTotal(w, r, filter, withTotal, arrayFormat)
+	if !ok {
+		return
+	}
+	w.Header().Set(HasMoreHeader, strconv.FormatBool(hasMore))
+
+	h.respondList(w, arrayFormat, flowers, flowers, total, filter.Page, filter.PageSize)
+}
+
+// HandleLowStockAlerts 处理库存预警列表（仅管理员和店员）
+// 只返回库存低于或等于阈值的上架鲜花，按库存升序排列并给出缺口数量；threshold 参数可覆盖配置的阈值
+func (h *Handler) HandleLowStockAlerts(w http.ResponseWriter, r *http.Request) {
+	threshold, err := parseLowStockThreshold(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	alerts, err := h.flowerService.ListLowStock(r.Context(), threshold)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, alerts)
+}
+
+// parseFlowerFilter 从查询参数解析鲜花列表筛选条件
+func parseFlowerFilter(r *http.Request) flower.FlowerFilter {
+	return flower.FlowerFilter{
 		Search:   r.URL.Query().Get("search"),
 		Origin:   r.URL.Query().Get("origin"),
 		MinPrice: parseFloatQuery(r.URL.Query().Get("min_price")),
 		MaxPrice: parseFloatQuery(r.URL.Query().Get("max_price")),
 		SortBy:   r.URL.Query().Get("sort_by"),
+		InStock:  parseBoolQuery(r.URL.Query().Get("in_stock")),
 		Page:     parseIntQuery(r.URL.Query().Get("page"), 1),
 		PageSize: parseIntQuery(r.URL.Query().Get("page_size"), 10),
 	}
+}
 
-	ctx := context.Background()
-	flowers, err := h.flowerService.ListFlowers(ctx, filter)
-	if err != nil {
-		if strings.Contains(err.Error(), "最低价格") || strings.Contains(err.Error(), "页码") {
-			h.respondError(w, http.StatusBadRequest, err.Error())
-			return
-		}
-		h.respondError(w, http.StatusInternalServerError, err.Error())
-		return
+// parseLowStockThreshold 解析 threshold 查询参数（库存预警阈值覆盖），未提供时返回 0
+func parseLowStockThreshold(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("threshold")
+	if value == "" {
+		return 0, nil
+	}
+	threshold, err := strconv.Atoi(value)
+	if err != nil || threshold <= 0 {
+		return 0, fmt.Errorf("threshold must be a positive integer")
 	}
+	return threshold, nil
+}
 
-	h.respondJSON(w, http.StatusOK, flowers)
+// flowerTotal 统计筛选条件下的鲜花总数：分页响应总是统计，旧版数组格式仅在 with_total=true 时统计
+// with_total=true 时同时写入响应头；失败时已写出错误响应并返回 false
+func (h *Handler) flowerTotal(w http.ResponseWriter, r *http.Request, filter flower.FlowerFilter, withTotal, arrayFormat bool) (int, bool) {
+	if !withTotal && arrayFormat {
+		return 0, true
+	}
+	total, err := h.flowerService.CountFlowers(r.Context(), filter)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return 0, false
+	}
+	if withTotal {
+		w.Header().Set(TotalCountHeader, strconv.Itoa(total))
+	}
+	return total, true
 }
 
 // HandleGetFlower 处理获取鲜花详情
@@ -49,24 +188,25 @@ func (h *Handler) HandleGetFlower(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sku := extractFlowerSKU(r.URL.Path)
+	sku := r.PathValue("sku")
 	if sku == "" {
 		h.respondError(w, http.StatusBadRequest, "invalid flower SKU")
 		return
 	}
 
-	ctx := context.Background()
+	ctx := r.Context()
 	flowerResp, err := h.flowerService.GetFlower(ctx, sku)
 	if err != nil {
-		if strings.Contains(err.Error(), "不存在") {
-			h.respondError(w, http.StatusNotFound, "flower not found")
-			return
-		}
-		h.respondError(w, http.StatusBadRequest, err.Error())
+		h.respondServiceError(w, r, err)
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, flowerResp)
+	// 进价等成本信息仅店员和管理员可见，匿名与顾客只返回公开字段
+	if operator, err := h.getUserFromSession(r); err == nil && (operator.Role == user.RoleAdmin || operator.Role == user.RoleClerk) {
+		h.respondJSON(w, http.StatusOK, flowerResp)
+		return
+	}
+	h.respondJSON(w, http.StatusOK, flowerResp.Public())
 }
 
 // HandleCreateFlower 处理创建鲜花
@@ -82,7 +222,7 @@ func (h *Handler) HandleCreateFlower(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := r.Context()
 	if err := h.flowerService.CreateFlower(ctx, &flower.CreateFlowerRequest{
 		SKU:           req.SKU,
 		Name:          req.Name,
@@ -91,9 +231,12 @@ func (h *Handler) HandleCreateFlower(w http.ResponseWriter, r *http.Request) {
 		Preservation:  req.Preservation,
 		PurchasePrice: req.PurchasePrice,
 		SalePrice:     req.SalePrice,
+		DiscountPrice: req.DiscountPrice,
+		DiscountUntil: req.DiscountUntil,
 		Stock:         req.Stock,
+		MaxOrderQty:   req.MaxOrderQty,
 	}); err != nil {
-		h.respondError(w, http.StatusBadRequest, err.Error())
+		h.respondServiceError(w, r, err)
 		return
 	}
 
@@ -104,57 +247,79 @@ func (h *Handler) HandleCreateFlower(w http.ResponseWriter, r *http.Request) {
 }
 
 // HandleUpdateFlower 处理更新鲜花
+// PUT 与 PATCH 均按部分更新处理：只修改请求体中出现的字段，库存不通过此接口修改
 func (h *Handler) HandleUpdateFlower(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut && r.Method != http.MethodPatch {
 		h.respondError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	sku := extractFlowerSKU(r.URL.Path)
+	sku := r.PathValue("sku")
 	if sku == "" {
 		h.respondError(w, http.StatusBadRequest, "invalid flower SKU")
 		return
 	}
 
+	operator, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
 	var req UpdateFlowerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.respondError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
-	ctx := context.Background()
-	updateReq := &flower.UpdateFlowerRequest{}
-	if req.Name != nil {
-		updateReq.Name = req.Name
-	}
-	if req.Origin != nil {
-		updateReq.Origin = req.Origin
-	}
-	if req.ShelfLife != nil {
-		updateReq.ShelfLife = req.ShelfLife
-	}
-	if req.Preservation != nil {
-		updateReq.Preservation = req.Preservation
+	ctx := r.Context()
+	if err := h.flowerService.UpdateFlower(ctx, sku, req.toServiceRequest(), operator.ID); err != nil {
+		h.respondServiceError(w, r, err)
+		return
 	}
-	if req.PurchasePrice != nil {
-		updateReq.PurchasePrice = req.PurchasePrice
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "flower updated successfully",
+	})
+}
+
+// HandleCloneFlower 以已有鲜花为模板创建新 SKU（店员或管理员）
+// POST /api/flowers/{sku}/clone，新鲜花库存为 0
+func (h *Handler) HandleCloneFlower(w http.ResponseWriter, r *http.Request) {
+	sku := r.PathValue("sku")
+	if sku == "" {
+		h.respondError(w, http.StatusBadRequest, "invalid flower SKU")
+		return
 	}
-	if req.SalePrice != nil {
-		updateReq.SalePrice = req.SalePrice
+
+	var req CloneFlowerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
 	}
 
-	if err := h.flowerService.UpdateFlower(ctx, sku, updateReq); err != nil {
-		if strings.Contains(err.Error(), "不存在") {
-			h.respondError(w, http.StatusNotFound, "flower not found")
-			return
-		}
-		h.respondError(w, http.StatusBadRequest, err.Error())
+	cloned, err := h.flowerService.CloneFlower(r.Context(), sku, req.NewSKU, req.UpdateFlowerRequest.toServiceRequest())
+	if err != nil {
+		h.respondServiceError(w, r, err)
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "flower updated successfully",
-	})
+	h.respondJSON(w, http.StatusCreated, cloned)
+}
+
+// toServiceRequest 转换为服务层的部分更新请求
+func (req *UpdateFlowerRequest) toServiceRequest() *flower.UpdateFlowerRequest {
+	return &flower.UpdateFlowerRequest{
+		Name:          req.Name,
+		Origin:        req.Origin,
+		ShelfLife:     req.ShelfLife,
+		Preservation:  req.Preservation,
+		PurchasePrice: req.PurchasePrice,
+		SalePrice:     req.SalePrice,
+		DiscountPrice: req.DiscountPrice,
+		DiscountUntil: req.DiscountUntil,
+		MaxOrderQty:   req.MaxOrderQty,
+	}
 }
 
 // HandleDeleteFlower 处理删除鲜花
@@ -164,19 +329,34 @@ func (h *Handler) HandleDeleteFlower(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sku := extractFlowerSKU(r.URL.Path)
+	sku := r.PathValue("sku")
 	if sku == "" {
 		h.respondError(w, http.StatusBadRequest, "invalid flower SKU")
 		return
 	}
 
-	ctx := context.Background()
-	if err := h.flowerService.DeleteFlower(ctx, sku); err != nil {
-		if strings.Contains(err.Error(), "不存在") {
-			h.respondError(w, http.StatusNotFound, "flower not found")
-			return
-		}
-		h.respondError(w, http.StatusBadRequest, err.Error())
+	operator, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	// 默认仅下架；hard=true 时物理删除，仅管理员可用
+	hard := parseBoolQuery(r.URL.Query().Get("hard"))
+	if hard && operator.Role != user.RoleAdmin {
+		h.respondError(w, http.StatusForbidden, "access denied")
+		return
+	}
+
+	ctx := r.Context()
+	var err error
+	if hard {
+		err = h.flowerService.PurgeFlower(ctx, sku)
+	} else {
+		err = h.flowerService.DeleteFlower(ctx, sku, operator.ID)
+	}
+	if err != nil {
+		h.respondServiceError(w, r, err)
 		return
 	}
 
@@ -185,6 +365,31 @@ func (h *Handler) HandleDeleteFlower(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleActivateFlower 重新上架已下架的鲜花（店员或管理员）
+// POST /api/flowers/{sku}/activate
+func (h *Handler) HandleActivateFlower(w http.ResponseWriter, r *http.Request) {
+	sku := r.PathValue("sku")
+	if sku == "" {
+		h.respondError(w, http.StatusBadRequest, "invalid flower SKU")
+		return
+	}
+
+	operator, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.flowerService.ActivateFlower(r.Context(), sku, operator.ID); err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "flower activated successfully",
+	})
+}
+
 // HandleAddStock 处理进货入库
 func (h *Handler) HandleAddStock(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -192,12 +397,18 @@ func (h *Handler) HandleAddStock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sku := extractFlowerSKU(r.URL.Path)
+	sku := r.PathValue("sku")
 	if sku == "" {
 		h.respondError(w, http.StatusBadRequest, "invalid flower SKU")
 		return
 	}
 
+	operator, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
 	var req struct {
 		Quantity int `json:"quantity"`
 	}
@@ -206,22 +417,18 @@ func (h *Handler) HandleAddStock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
-	if err := h.flowerService.AddStock(ctx, sku, req.Quantity); err != nil {
-		if strings.Contains(err.Error(), "不存在") {
-			h.respondError(w, http.StatusNotFound, "flower not found")
-			return
-		}
-		if strings.Contains(err.Error(), "不能为负") {
-			h.respondError(w, http.StatusBadRequest, err.Error())
-			return
-		}
-		h.respondError(w, http.StatusBadRequest, err.Error())
+	ctx := r.Context()
+	if err := h.flowerService.AddStock(ctx, sku, req.Quantity, operator.ID); err != nil {
+		h.respondServiceError(w, r, err)
 		return
 	}
 
 	// 获取更新后的库存
-	flowerResp, _ := h.flowerService.GetFlower(ctx, sku)
+	flowerResp, err := h.flowerService.GetFlower(ctx, sku)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
 
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
 		"message": "stock added successfully",
@@ -230,14 +437,35 @@ func (h *Handler) HandleAddStock(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// extractFlowerSKU 从 URL 路径中提取鲜花 SKU
-func extractFlowerSKU(path string) string {
-	// 路径格式: /api/flowers/{sku} 或 /api/flowers/{sku}/stock
-	parts := strings.Split(strings.Trim(path, "/"), "/")
-	if len(parts) >= 3 && parts[0] == "api" && parts[1] == "flowers" {
-		return parts[2]
+// HandleBatchAddStock 处理批量进货入库（仅管理员和店员）
+// POST /api/flowers/stock/batch，请求体为 [{sku, quantity}]；整批在一个事务中执行，任一项失败则全部回滚
+func (h *Handler) HandleBatchAddStock(w http.ResponseWriter, r *http.Request) {
+	operator, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req []BatchAddStockItem
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	items := make([]*flower.StockAdjustment, len(req))
+	for i, item := range req {
+		items[i] = &flower.StockAdjustment{SKU: item.SKU, Quantity: item.Quantity}
 	}
-	return ""
+
+	results, err := h.flowerService.BatchAddStock(r.Context(), items, operator.ID)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"results": results,
+	})
 }
 
 // parseFloatQuery 解析浮点数查询参数
@@ -249,6 +477,12 @@ func parseFloatQuery(s string) float64 {
 	return f
 }
 
+// parseBoolQuery 解析布尔查询参数，无法解析时视为 false
+func parseBoolQuery(s string) bool {
+	b, _ := strconv.ParseBool(s)
+	return b
+}
+
 // parseIntQuery 解析整数查询参数
 func parseIntQuery(s string, defaultVal int) int {
 	if s == "" {
@@ -260,3 +494,139 @@ func parseIntQuery(s string, defaultVal int) int {
 	}
 	return i
 }
+
+// HandleGetFlowerLogs 查询鲜花的变更审计日志（仅管理员），最新在前
+// GET /api/admin/flowers/{sku}/logs
+func (h *Handler) HandleGetFlowerLogs(w http.ResponseWriter, r *http.Request) {
+	sku := r.PathValue("sku")
+	if sku == "" {
+		h.respondError(w, http.StatusBadRequest, "invalid flower SKU")
+		return
+	}
+
+	logs, err := h.flowerService.GetFlowerLogs(r.Context(), sku)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, logs)
+}
+
+// flowerImportColumns 批量导入 CSV 的必需列，首行为表头，列顺序不限
+var flowerImportColumns = []string{"sku", "name", "origin", "shelf_life", "preservation", "purchase_price", "sale_price", "stock"}
+
+// MaxFlowerImportSize 批量导入上传文件的大小上限，请求体大小限制中间件对导入接口使用该上限
+const MaxFlowerImportSize = 5 << 20
+
+// HandleImportFlowers 通过上传 CSV 批量创建鲜花（店员或管理员）
+// POST /api/flowers/import，multipart 表单字段 file；每行按创建鲜花的规则校验，
+// 合法行逐条导入，非法行与格式错误的行记录在 errors 中（row 为 CSV 行号，表头为第 1 行）
+func (h *Handler) HandleImportFlowers(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxFlowerImportSize)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "missing or invalid CSV file")
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid CSV header")
+		return
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff") // 兼容 Excel 导出的 UTF-8 BOM
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range flowerImportColumns {
+		if _, ok := columns[name]; !ok {
+			h.respondError(w, http.StatusBadRequest, fmt.Sprintf("missing CSV column: %s", name))
+			return
+		}
+	}
+
+	resp := &ImportFlowersResponse{Errors: []*ImportRowError{}}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		// 格式错误只影响当前行，记为失败行后继续读取
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			resp.addError(parseErr.StartLine, fmt.Sprintf("invalid CSV: %v", parseErr.Err))
+			continue
+		}
+		// 读取中断（如超出大小上限）时之前的行已导入，返回已导入的结果与错误原因
+		if err != nil {
+			resp.Error = fmt.Sprintf("failed to read CSV: %v", err)
+			h.respondJSON(w, http.StatusBadRequest, resp)
+			return
+		}
+		row, _ := reader.FieldPos(0)
+
+		req, err := parseFlowerImportRow(record, columns)
+		if err != nil {
+			resp.addError(row, err.Error())
+			continue
+		}
+		if err := h.flowerService.CreateFlower(r.Context(), req); err != nil {
+			resp.addError(row, importErrorMessage(row, err))
+			continue
+		}
+		resp.Created++
+	}
+
+	h.respondJSON(w, http.StatusOK, resp)
+}
+
+// importErrorMessage 返回导入失败行的原因，未分类的内部错误（如数据库错误）只记录日志，不返回给客户端
+func importErrorMessage(row int, err error) string {
+	if apperror.KindOf(err) != apperror.KindInternal {
+		return err.Error()
+	}
+	slog.Error("failed to import flower", "row", row, "error", err)
+	return "failed to create flower"
+}
+
+// parseFlowerImportRow 将 CSV 行转换为创建鲜花请求，只解析字段类型，业务校验由服务层完成
+func parseFlowerImportRow(record []string, columns map[string]int) (*flower.CreateFlowerRequest, error) {
+	field := func(name string) string {
+		i := columns[name]
+		if i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	purchasePrice, err := strconv.ParseFloat(field("purchase_price"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid purchase_price: %q", field("purchase_price"))
+	}
+	salePrice, err := strconv.ParseFloat(field("sale_price"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sale_price: %q", field("sale_price"))
+	}
+	stock, err := strconv.Atoi(field("stock"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid stock: %q", field("stock"))
+	}
+
+	return &flower.CreateFlowerRequest{
+		SKU:           field("sku"),
+		Name:          field("name"),
+		Origin:        field("origin"),
+		ShelfLife:     field("shelf_life"),
+		Preservation:  field("preservation"),
+		PurchasePrice: purchasePrice,
+		SalePrice:     salePrice,
+		Stock:         stock,
+	}, nil
+}
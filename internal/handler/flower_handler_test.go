@@ -1,14 +1,21 @@
 package handler
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -31,10 +38,23 @@ func setupFlowerTestDB(t *testing.T) *sql.DB {
 		purchase_price INTEGER NOT NULL,
 		sale_price INTEGER NOT NULL,
 		stock INTEGER NOT NULL DEFAULT 0,
+		max_order_qty INTEGER,
+		discount_price INTEGER,
+		discount_until DATETIME,
 		is_active INTEGER NOT NULL DEFAULT 1,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 	);
+
+	CREATE TABLE IF NOT EXISTS flower_logs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		sku TEXT NOT NULL,
+		operator_id INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		old_value TEXT NOT NULL,
+		new_value TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
 	`
 
 	if _, err := db.Exec(createTableSQL); err != nil {
@@ -64,6 +84,16 @@ func setupFlowerTestHandler(t *testing.T) *Handler {
 	return h
 }
 
+// setupStaffFlowerTestHandler 创建带认证服务的鲜花测试 Handler，并返回店员会话 token（鲜花写接口需要员工权限）
+func setupStaffFlowerTestHandler(t *testing.T) (*Handler, string) {
+	t.Helper()
+
+	ctx, _ := setupUserTestHandler(t)
+	ctx.handler.flowerService = flower.NewFlowerService(flower.NewFlowerRepository(setupFlowerTestDB(t)))
+	_, token := createTestUserWithSession(t, ctx, "flowerclerk", user.RoleClerk)
+	return ctx.handler, token
+}
+
 // TestHandleListFlowers 测试获取鲜花列表
 func TestHandleListFlowers(t *testing.T) {
 	handler := setupFlowerTestHandler(t)
@@ -95,10 +125,1079 @@ func TestHandleListFlowers(t *testing.T) {
 	}
 
 	var resp []json.RawMessage
-	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+	if err := unmarshalListData(w.Body.Bytes(), &resp); err != nil {
 		t.Fatalf("failed to parse response: %v", err)
 	}
 	if len(resp) != 3 {
 		t.Errorf("got %d flowers, want 3", len(resp))
 	}
 }
+
+// TestHandleUpdateFlower_Patch 测试 PATCH 部分更新鲜花
+func TestHandleUpdateFlower_Patch(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantName   string
+		wantPrice  float64
+	}{
+		{
+			name:       "只修改名称",
+			body:       `{"name":"测试鲜花（新）"}`,
+			wantStatus: http.StatusOK,
+			wantName:   "测试鲜花（新）",
+			wantPrice:  15.0,
+		},
+		{
+			name:       "售价低于进价",
+			body:       `{"sale_price":5.0}`,
+			wantStatus: http.StatusBadRequest,
+			wantName:   "测试鲜花",
+			wantPrice:  15.0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, token := setupStaffFlowerTestHandler(t)
+			ctx := t.Context()
+
+			createReq := &flower.CreateFlowerRequest{
+				SKU:           "FLW001",
+				Name:          "测试鲜花",
+				Origin:        "云南",
+				ShelfLife:     "7天",
+				Preservation:  "冷藏",
+				PurchasePrice: 10.0,
+				SalePrice:     15.0,
+				Stock:         100,
+			}
+			if err := handler.flowerService.CreateFlower(ctx, createReq); err != nil {
+				t.Fatalf("failed to create test flower: %v", err)
+			}
+
+			req := httptest.NewRequest("PATCH", "/api/flowers/FLW001", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: token})
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleUpdateFlower() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			got, err := handler.flowerService.GetFlower(ctx, "FLW001")
+			if err != nil {
+				t.Fatalf("GetFlower() error = %v", err)
+			}
+			if got.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", got.Name, tt.wantName)
+			}
+			if got.SalePrice.ToFloat64() != tt.wantPrice || got.PurchasePrice.ToFloat64() != 10.0 || got.Stock != 100 {
+				t.Errorf("price/stock = (%v, %v, %d), want (%v, 10, 100)", got.SalePrice, got.PurchasePrice, got.Stock, tt.wantPrice)
+			}
+		})
+	}
+}
+
+// TestHandleListFlowers_InStock 测试 in_stock 参数
+func TestHandleListFlowers_InStock(t *testing.T) {
+	handler := setupFlowerTestHandler(t)
+
+	ctx := t.Context()
+	for i, stock := range []int{10, 0, 3} {
+		req := &flower.CreateFlowerRequest{
+			SKU:           fmt.Sprintf("FLW%03d", i+1),
+			Name:          fmt.Sprintf("测试鲜花%d", i+1),
+			Origin:        "云南",
+			PurchasePrice: 10.0,
+			SalePrice:     15.0,
+			Stock:         stock,
+		}
+		if err := handler.flowerService.CreateFlower(ctx, req); err != nil {
+			t.Fatalf("failed to create test flower: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCount int
+	}{
+		{name: "默认显示全部", query: "", wantCount: 3},
+		{name: "仅显示有库存", query: "?in_stock=true", wantCount: 2},
+		{name: "in_stock=false 显示全部", query: "?in_stock=false", wantCount: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/flowers"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.HandleListFlowers(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("HandleListFlowers() status = %d, want %d", w.Code, http.StatusOK)
+			}
+
+			var resp []struct {
+				SKU   string `json:"sku"`
+				Stock int    `json:"stock"`
+			}
+			if err := unmarshalListData(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if len(resp) != tt.wantCount {
+				t.Errorf("got %d flowers, want %d", len(resp), tt.wantCount)
+			}
+			for _, f := range resp {
+				if tt.query == "?in_stock=true" && f.Stock <= 0 {
+					t.Errorf("flower %s with stock %d should be excluded", f.SKU, f.Stock)
+				}
+			}
+		})
+	}
+}
+
+// countingFlowerRepository 记录 Count 调用次数的鲜花仓储
+type countingFlowerRepository struct {
+	flower.FlowerRepository
+	countCalls int
+}
+
+func (r *countingFlowerRepository) Count(ctx context.Context, filter flower.FlowerFilter) (int, error) {
+	r.countCalls++
+	return r.FlowerRepository.Count(ctx, filter)
+}
+
+// TestHandleListFlowers_WithTotal 测试 with_total 参数
+func TestHandleListFlowers_WithTotal(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		wantStatus     int
+		wantTotal      string
+		wantCountCalls int
+	}{
+		{name: "默认不统计总数", query: "?page=1&page_size=2&format=array", wantStatus: http.StatusOK, wantTotal: "", wantCountCalls: 0},
+		{name: "with_total=false", query: "?page=1&page_size=2&with_total=false&format=array", wantStatus: http.StatusOK, wantTotal: "", wantCountCalls: 0},
+		{name: "with_total=true", query: "?page=1&page_size=2&with_total=true&format=array", wantStatus: http.StatusOK, wantTotal: "3", wantCountCalls: 1},
+		{name: "非法参数", query: "?with_total=maybe", wantStatus: http.StatusBadRequest, wantCountCalls: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &countingFlowerRepository{FlowerRepository: flower.NewFlowerRepository(setupFlowerTestDB(t))}
+			handler := &Handler{flowerService: flower.NewFlowerService(repo)}
+
+			for i := 1; i <= 3; i++ {
+				req := &flower.CreateFlowerRequest{
+					SKU:           fmt.Sprintf("FLW%03d", i),
+					Name:          fmt.Sprintf("测试鲜花%d", i),
+					Origin:        "云南",
+					PurchasePrice: 10.0,
+					SalePrice:     15.0,
+					Stock:         10,
+				}
+				if err := handler.flowerService.CreateFlower(t.Context(), req); err != nil {
+					t.Fatalf("failed to create test flower: %v", err)
+				}
+			}
+
+			req := httptest.NewRequest("GET", "/api/flowers"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.HandleListFlowers(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleListFlowers() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get(TotalCountHeader); got != tt.wantTotal {
+				t.Errorf("%s = %q, want %q", TotalCountHeader, got, tt.wantTotal)
+			}
+			if repo.countCalls != tt.wantCountCalls {
+				t.Errorf("Count() calls = %d, want %d", repo.countCalls, tt.wantCountCalls)
+			}
+		})
+	}
+}
+
+// TestHandleGetFlower_PriceFormats 测试鲜花响应同时包含格式化价格与分
+func TestHandleGetFlower_PriceFormats(t *testing.T) {
+	handler, clerkToken := setupStaffFlowerTestHandler(t)
+
+	createReq := &flower.CreateFlowerRequest{
+		SKU:           "FLW001",
+		Name:          "测试鲜花",
+		Origin:        "云南",
+		PurchasePrice: 10.0,
+		SalePrice:     15.5,
+		Stock:         100,
+	}
+	if err := handler.flowerService.CreateFlower(t.Context(), createReq); err != nil {
+		t.Fatalf("failed to create test flower: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/flowers/FLW001", nil)
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: clerkToken})
+	w := httptest.NewRecorder()
+
+	routeRequest(handler, w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("HandleGetFlower() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	wants := []string{
+		`"purchase_price":"10.00"`,
+		`"purchase_price_cents":1000`,
+		`"sale_price":"15.50"`,
+		`"sale_price_cents":1550`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("response = %s, want contains %s", w.Body.String(), want)
+		}
+	}
+}
+
+// TestHandleGetFlower_HidesCostFromPublic 测试匿名访问鲜花详情时不返回进价
+func TestHandleGetFlower_HidesCostFromPublic(t *testing.T) {
+	handler, _ := setupStaffFlowerTestHandler(t)
+	if err := handler.flowerService.CreateFlower(t.Context(), &flower.CreateFlowerRequest{
+		SKU: "FLW001", Name: "测试鲜花", Origin: "云南", PurchasePrice: 10.0, SalePrice: 15.5, Stock: 100,
+	}); err != nil {
+		t.Fatalf("failed to create test flower: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	routeRequest(handler, w, httptest.NewRequest("GET", "/api/flowers/FLW001", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("HandleGetFlower() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	for _, key := range []string{"purchase_price", "purchase_price_cents"} {
+		if _, ok := body[key]; ok {
+			t.Errorf("anonymous response contains %q: %s", key, w.Body.String())
+		}
+	}
+	if body["sale_price"] != "15.50" {
+		t.Errorf("sale_price = %v, want 15.50", body["sale_price"])
+	}
+}
+
+// TestHandleListFlowers_HasMore 测试列表通过多取一行返回是否还有下一页
+func TestHandleListFlowers_HasMore(t *testing.T) {
+	repo := &countingFlowerRepository{FlowerRepository: flower.NewFlowerRepository(setupFlowerTestDB(t))}
+	handler := &Handler{flowerService: flower.NewFlowerService(repo)}
+
+	for i := 1; i <= 3; i++ {
+		req := &flower.CreateFlowerRequest{
+			SKU:           fmt.Sprintf("FLW%03d", i),
+			Name:          fmt.Sprintf("测试鲜花%d", i),
+			Origin:        "云南",
+			PurchasePrice: 10.0,
+			SalePrice:     15.0,
+			Stock:         10,
+		}
+		if err := handler.flowerService.CreateFlower(t.Context(), req); err != nil {
+			t.Fatalf("failed to create test flower: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		wantHasMore string
+		wantCount   int
+	}{
+		{name: "满页且还有数据", query: "?page=1&page_size=2&format=array", wantHasMore: "true", wantCount: 2},
+		{name: "最后一页不满", query: "?page=2&page_size=2&format=array", wantHasMore: "false", wantCount: 1},
+		{name: "最后一页恰好满页", query: "?page=1&page_size=3&format=array", wantHasMore: "false", wantCount: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/flowers"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.HandleListFlowers(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("HandleListFlowers() status = %d, want %d", w.Code, http.StatusOK)
+			}
+			if got := w.Header().Get(HasMoreHeader); got != tt.wantHasMore {
+				t.Errorf("%s = %q, want %q", HasMoreHeader, got, tt.wantHasMore)
+			}
+
+			var resp []json.RawMessage
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if len(resp) != tt.wantCount {
+				t.Errorf("got %d flowers, want %d", len(resp), tt.wantCount)
+			}
+		})
+	}
+
+	if repo.countCalls != 0 {
+		t.Errorf("Count() called %d times, want 0", repo.countCalls)
+	}
+}
+
+// TestHandleListFlowers_Envelope 测试鲜花列表分页响应中的总数与总页数
+func TestHandleListFlowers_Envelope(t *testing.T) {
+	repo := &countingFlowerRepository{FlowerRepository: flower.NewFlowerRepository(setupFlowerTestDB(t))}
+	handler := &Handler{flowerService: flower.NewFlowerService(repo)}
+
+	for i := 1; i <= 5; i++ {
+		req := &flower.CreateFlowerRequest{
+			SKU:           fmt.Sprintf("FLW%03d", i),
+			Name:          fmt.Sprintf("测试鲜花%d", i),
+			Origin:        "云南",
+			PurchasePrice: 10.0,
+			SalePrice:     15.0,
+			Stock:         10,
+		}
+		if err := handler.flowerService.CreateFlower(t.Context(), req); err != nil {
+			t.Fatalf("failed to create test flower: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		wantCount      int
+		wantTotalPages int
+	}{
+		{name: "第一页", query: "?page=1&page_size=2", wantCount: 2, wantTotalPages: 3},
+		{name: "第二页", query: "?page=2&page_size=2", wantCount: 2, wantTotalPages: 3},
+		{name: "最后一页", query: "?page=3&page_size=2", wantCount: 1, wantTotalPages: 3},
+		{name: "单页", query: "?page=1&page_size=5", wantCount: 5, wantTotalPages: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/flowers"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.HandleListFlowers(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("HandleListFlowers() status = %d, want %d", w.Code, http.StatusOK)
+			}
+
+			var resp struct {
+				Data       []json.RawMessage `json:"data"`
+				Total      int               `json:"total"`
+				TotalPages int               `json:"total_pages"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if len(resp.Data) != tt.wantCount {
+				t.Errorf("got %d flowers, want %d", len(resp.Data), tt.wantCount)
+			}
+			if resp.Total != 5 {
+				t.Errorf("total = %d, want 5", resp.Total)
+			}
+			if resp.TotalPages != tt.wantTotalPages {
+				t.Errorf("total_pages = %d, want %d", resp.TotalPages, tt.wantTotalPages)
+			}
+			if got := w.Header().Get(TotalCountHeader); got != "" {
+				t.Errorf("%s = %q, want empty without with_total", TotalCountHeader, got)
+			}
+		})
+	}
+
+	if repo.countCalls != len(tests) {
+		t.Errorf("Count() called %d times, want %d", repo.countCalls, len(tests))
+	}
+}
+
+// TestHandleCloneFlower 测试克隆鲜花接口
+func TestHandleCloneFlower(t *testing.T) {
+	ctx, _ := setupUserTestHandler(t)
+	handler := ctx.handler
+	handler.flowerService = flower.NewFlowerService(flower.NewFlowerRepository(setupFlowerTestDB(t)))
+	if err := handler.flowerService.CreateFlower(context.Background(), &flower.CreateFlowerRequest{
+		SKU: "ROS001", Name: "红玫瑰", Origin: "云南", ShelfLife: "7天", Preservation: "冷藏",
+		PurchasePrice: 50, SalePrice: 100, Stock: 80,
+	}); err != nil {
+		t.Fatalf("failed to create flower: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	_, clerkToken := createTestUserWithSession(t, ctx, "clerk", user.RoleClerk)
+	_, customerToken := createTestUserWithSession(t, ctx, "customer", user.RoleCustomer)
+
+	tests := []struct {
+		name       string
+		token      string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "未登录", path: "/api/flowers/ROS001/clone", body: `{"new_sku":"ROS002"}`, wantStatus: http.StatusUnauthorized},
+		{name: "顾客无权限", token: customerToken, path: "/api/flowers/ROS001/clone", body: `{"new_sku":"ROS002"}`, wantStatus: http.StatusForbidden},
+		{name: "克隆成功", token: clerkToken, path: "/api/flowers/ROS001/clone", body: `{"new_sku":"ROS002","name":"粉玫瑰"}`, wantStatus: http.StatusCreated},
+		{name: "新 SKU 重复", token: clerkToken, path: "/api/flowers/ROS001/clone", body: `{"new_sku":"ROS002"}`, wantStatus: http.StatusConflict},
+		{name: "源鲜花不存在", token: clerkToken, path: "/api/flowers/NOPE/clone", body: `{"new_sku":"ROS003"}`, wantStatus: http.StatusNotFound},
+		{name: "缺少新 SKU", token: clerkToken, path: "/api/flowers/ROS001/clone", body: `{}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: tt.token})
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+
+			var resp struct {
+				SKU    string `json:"sku"`
+				Name   string `json:"name"`
+				Origin string `json:"origin"`
+				Stock  int    `json:"stock"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp.SKU != "ROS002" || resp.Name != "粉玫瑰" || resp.Origin != "云南" || resp.Stock != 0 {
+				t.Errorf("clone response = %+v", resp)
+			}
+		})
+	}
+}
+
+// TestHandleDeleteFlower_SoftDelete 测试删除鲜花默认下架，hard=true 仅管理员可物理删除
+func TestHandleDeleteFlower_SoftDelete(t *testing.T) {
+	ctx, _ := setupUserTestHandler(t)
+	handler := ctx.handler
+	handler.flowerService = flower.NewFlowerService(flower.NewFlowerRepository(setupFlowerTestDB(t)))
+	for _, sku := range []string{"ROS001", "LIL001"} {
+		if err := handler.flowerService.CreateFlower(context.Background(), &flower.CreateFlowerRequest{
+			SKU: sku, Name: "红玫瑰", Origin: "云南", PurchasePrice: 50, SalePrice: 100, Stock: 80,
+		}); err != nil {
+			t.Fatalf("failed to create flower: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	_, adminToken := createTestUserWithSession(t, ctx, "admin", user.RoleAdmin)
+	_, clerkToken := createTestUserWithSession(t, ctx, "clerk", user.RoleClerk)
+	_, customerToken := createTestUserWithSession(t, ctx, "customer", user.RoleCustomer)
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: token})
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	listSKUs := func(path, token string) []string {
+		t.Helper()
+		w := do("GET", path, token)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, body = %s", path, w.Code, w.Body.String())
+		}
+		var got []struct {
+			SKU string `json:"sku"`
+		}
+		if err := unmarshalListData(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		skus := make([]string, len(got))
+		for i, f := range got {
+			skus[i] = f.SKU
+		}
+		return skus
+	}
+
+	if w := do("DELETE", "/api/flowers/ROS001", clerkToken); w.Code != http.StatusOK {
+		t.Fatalf("soft delete status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if skus := listSKUs("/api/flowers", ""); len(skus) != 1 || skus[0] != "LIL001" {
+		t.Errorf("public list = %v, want [LIL001]", skus)
+	}
+	if skus := listSKUs("/api/admin/flowers", clerkToken); len(skus) != 2 {
+		t.Errorf("admin list = %v, want both flowers", skus)
+	}
+	if skus := listSKUs("/api/admin/flowers?include_inactive=false", clerkToken); len(skus) != 1 {
+		t.Errorf("admin list include_inactive=false = %v, want [LIL001]", skus)
+	}
+	// 已下架鲜花仍可按 SKU 查询，供历史订单使用
+	if w := do("GET", "/api/flowers/ROS001", ""); w.Code != http.StatusOK {
+		t.Errorf("get deactivated flower status = %d, want 200", w.Code)
+	}
+
+	if w := do("POST", "/api/flowers/ROS001/activate", customerToken); w.Code != http.StatusForbidden {
+		t.Errorf("customer activate status = %d, want 403", w.Code)
+	}
+	if w := do("POST", "/api/flowers/ROS001/activate", clerkToken); w.Code != http.StatusOK {
+		t.Fatalf("activate status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if skus := listSKUs("/api/flowers", ""); len(skus) != 2 {
+		t.Errorf("public list after activate = %v, want both flowers", skus)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "未登录", wantStatus: http.StatusUnauthorized},
+		{name: "店员无权物理删除", token: clerkToken, wantStatus: http.StatusForbidden},
+		{name: "管理员物理删除", token: adminToken, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do("DELETE", "/api/flowers/LIL001?hard=true", tt.token); w.Code != tt.wantStatus {
+				t.Errorf("hard delete status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+	if w := do("GET", "/api/flowers/LIL001", ""); w.Code != http.StatusNotFound {
+		t.Errorf("get purged flower status = %d, want 404", w.Code)
+	}
+}
+
+// TestHandleFlower_ErrorStatus 测试鲜花接口按错误分类返回状态码
+func TestHandleFlower_ErrorStatus(t *testing.T) {
+	handler, token := setupStaffFlowerTestHandler(t)
+	if err := handler.flowerService.CreateFlower(context.Background(), &flower.CreateFlowerRequest{
+		SKU: "ROS001", Name: "红玫瑰", Origin: "云南", ShelfLife: "7天", Preservation: "冷藏",
+		PurchasePrice: 50, SalePrice: 100, Stock: 10,
+	}); err != nil {
+		t.Fatalf("failed to create flower: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "获取不存在的鲜花", method: "GET", path: "/api/flowers/NOPE", wantStatus: http.StatusNotFound},
+		{name: "更新不存在的鲜花", method: "PATCH", path: "/api/flowers/NOPE", body: `{"name":"白玫瑰"}`, wantStatus: http.StatusNotFound},
+		{name: "售价低于进价", method: "PATCH", path: "/api/flowers/ROS001", body: `{"sale_price":10}`, wantStatus: http.StatusBadRequest},
+		{name: "进货数量为负", method: "POST", path: "/api/flowers/ROS001/stock", body: `{"quantity":-1}`, wantStatus: http.StatusBadRequest},
+		{name: "缺少 SKU", method: "POST", path: "/api/flowers", body: `{"sku":"","name":"白玫瑰","origin":"云南"}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: token})
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+// TestHandleListFlowers_ContextDone 测试请求上下文已取消或已超时时立即返回错误，不继续查询
+func TestHandleListFlowers_ContextDone(t *testing.T) {
+	handler := &Handler{flowerService: flower.NewFlowerService(flower.NewFlowerRepository(setupFlowerTestDB(t)))}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		timeout    time.Duration // 经过 RequestTimeoutMiddleware 时的超时
+		wantStatus int
+	}{
+		{name: "客户端已断开", ctx: canceled, wantStatus: StatusClientClosedRequest},
+		{name: "请求超时", ctx: context.Background(), timeout: time.Nanosecond, wantStatus: http.StatusGatewayTimeout},
+		{name: "未超时", ctx: context.Background(), timeout: time.Minute, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h http.Handler = http.HandlerFunc(handler.HandleListFlowers)
+			if tt.timeout > 0 {
+				h = middleware.RequestTimeoutMiddleware(tt.timeout)(h)
+			}
+			req := httptest.NewRequest("GET", "/api/flowers", nil).WithContext(tt.ctx)
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+// TestHandleAdminListFlowers 测试管理视图返回进价与毛利，公开列表不返回
+func TestHandleAdminListFlowers(t *testing.T) {
+	ctx, _ := setupUserTestHandler(t)
+	handler := ctx.handler
+	handler.flowerService = flower.NewFlowerService(flower.NewFlowerRepository(setupFlowerTestDB(t)))
+	for _, req := range []*flower.CreateFlowerRequest{
+		{SKU: "ROS001", Name: "红玫瑰", Origin: "云南", PurchasePrice: 50, SalePrice: 60, Stock: 80},
+		{SKU: "LIL001", Name: "百合", Origin: "云南", PurchasePrice: 20, SalePrice: 55.5, Stock: 30},
+		{SKU: "TUL001", Name: "郁金香", Origin: "荷兰", PurchasePrice: 30, SalePrice: 45, Stock: 10},
+	} {
+		if err := handler.flowerService.CreateFlower(context.Background(), req); err != nil {
+			t.Fatalf("failed to create flower: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	_, clerkToken := createTestUserWithSession(t, ctx, "clerk", user.RoleClerk)
+	_, customerToken := createTestUserWithSession(t, ctx, "customer", user.RoleCustomer)
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: token})
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("权限", func(t *testing.T) {
+		if w := get("/api/admin/flowers", ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("未登录 status = %d, want 401", w.Code)
+		}
+		if w := get("/api/admin/flowers", customerToken); w.Code != http.StatusForbidden {
+			t.Errorf("顾客 status = %d, want 403", w.Code)
+		}
+	})
+
+	t.Run("按毛利排序并返回成本", func(t *testing.T) {
+		w := get("/api/admin/flowers?sort_by=margin_desc&origin=云南&with_total=true", clerkToken)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+		var got []struct {
+			SKU                string `json:"sku"`
+			PurchasePriceCents int64  `json:"purchase_price_cents"`
+			Margin             string `json:"margin"`
+			MarginCents        int64  `json:"margin_cents"`
+			IsActive           bool   `json:"is_active"`
+		}
+		if err := unmarshalListData(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("len = %d, want 2 (origin filter)", len(got))
+		}
+		if got[0].SKU != "LIL001" || got[0].Margin != "35.50" || got[0].MarginCents != 3550 || got[0].PurchasePriceCents != 2000 {
+			t.Errorf("first = %+v, want LIL001 with margin 35.50", got[0])
+		}
+		if got[1].SKU != "ROS001" || got[1].MarginCents != 1000 || !got[1].IsActive {
+			t.Errorf("second = %+v, want active ROS001 with margin 10.00", got[1])
+		}
+		if total := w.Header().Get(TotalCountHeader); total != "2" {
+			t.Errorf("%s = %q, want 2", TotalCountHeader, total)
+		}
+	})
+
+	t.Run("公开列表隐藏成本", func(t *testing.T) {
+		w := get("/api/flowers", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+		for _, hidden := range []string{"purchase_price", "margin"} {
+			if strings.Contains(w.Body.String(), hidden) {
+				t.Errorf("public list contains %q: %s", hidden, w.Body.String())
+			}
+		}
+		if !strings.Contains(w.Body.String(), `"sale_price":"60.00"`) {
+			t.Errorf("public list missing sale price: %s", w.Body.String())
+		}
+	})
+
+	t.Run("公开列表不支持按毛利排序", func(t *testing.T) {
+		if w := get("/api/flowers?sort_by=margin_desc", ""); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", w.Code)
+		}
+	})
+}
+
+// TestHandleLowStockAlerts 测试库存预警接口的权限、阈值过滤与排序
+func TestHandleLowStockAlerts(t *testing.T) {
+	ctx, _ := setupUserTestHandler(t)
+	handler := ctx.handler
+	handler.flowerService = flower.NewFlowerServiceWithThreshold(flower.NewFlowerRepository(setupFlowerTestDB(t)), nil, 5)
+	for _, stock := range []int{5, 20, 1} {
+		if err := handler.flowerService.CreateFlower(context.Background(), &flower.CreateFlowerRequest{
+			SKU: fmt.Sprintf("S%03d", stock), Name: "红玫瑰", Origin: "云南",
+			PurchasePrice: 50, SalePrice: 100, Stock: stock,
+		}); err != nil {
+			t.Fatalf("failed to create flower: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	_, clerkToken := createTestUserWithSession(t, ctx, "clerk", user.RoleClerk)
+	_, customerToken := createTestUserWithSession(t, ctx, "customer", user.RoleCustomer)
+
+	tests := []struct {
+		name       string
+		query      string
+		token      string
+		wantStatus int
+		wantSKUs   []string
+	}{
+		{name: "未登录", token: "", wantStatus: http.StatusUnauthorized},
+		{name: "顾客无权访问", token: customerToken, wantStatus: http.StatusForbidden},
+		{name: "配置阈值", token: clerkToken, wantStatus: http.StatusOK, wantSKUs: []string{"S001", "S005"}},
+		{name: "覆盖阈值", query: "?threshold=20", token: clerkToken, wantStatus: http.StatusOK, wantSKUs: []string{"S001", "S005", "S020"}},
+		{name: "阈值无效", query: "?threshold=-1", token: clerkToken, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/flowers/low-stock"+tt.query, nil)
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: tt.token})
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got []flower.LowStockAlert
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			var skus []string
+			for _, a := range got {
+				skus = append(skus, a.SKU)
+				if a.Shortfall != a.Threshold-a.Stock {
+					t.Errorf("alert %+v shortfall mismatch", a)
+				}
+			}
+			if strings.Join(skus, ",") != strings.Join(tt.wantSKUs, ",") {
+				t.Errorf("skus = %v, want %v", skus, tt.wantSKUs)
+			}
+		})
+	}
+}
+
+// TestHandleBatchAddStock 测试批量入库的权限、整批生效与无效 SKU 整批回滚
+func TestHandleBatchAddStock(t *testing.T) {
+	ctx, _ := setupUserTestHandler(t)
+	handler := ctx.handler
+	handler.flowerService = flower.NewFlowerService(flower.NewFlowerRepository(setupFlowerTestDB(t)))
+	for _, sku := range []string{"ROS001", "LIL001"} {
+		if err := handler.flowerService.CreateFlower(context.Background(), &flower.CreateFlowerRequest{
+			SKU: sku, Name: "红玫瑰", Origin: "云南", PurchasePrice: 50, SalePrice: 100, Stock: 10,
+		}); err != nil {
+			t.Fatalf("failed to create flower: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	_, clerkToken := createTestUserWithSession(t, ctx, "clerk", user.RoleClerk)
+	_, customerToken := createTestUserWithSession(t, ctx, "customer", user.RoleCustomer)
+
+	tests := []struct {
+		name       string
+		body       string
+		token      string
+		wantStatus int
+		wantStock  [2]int // ROS001、LIL001 请求后的库存
+	}{
+		{name: "顾客无权访问", body: `[{"sku":"ROS001","quantity":5}]`, token: customerToken, wantStatus: http.StatusForbidden, wantStock: [2]int{10, 10}},
+		{name: "包含不存在的 SKU 整批回滚", body: `[{"sku":"ROS001","quantity":5},{"sku":"NOPE","quantity":1}]`, token: clerkToken, wantStatus: http.StatusBadRequest, wantStock: [2]int{10, 10}},
+		{name: "数量为负", body: `[{"sku":"ROS001","quantity":-5}]`, token: clerkToken, wantStatus: http.StatusBadRequest, wantStock: [2]int{10, 10}},
+		{name: "整批生效", body: `[{"sku":"ROS001","quantity":5},{"sku":"LIL001","quantity":20}]`, token: clerkToken, wantStatus: http.StatusOK, wantStock: [2]int{15, 30}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/flowers/stock/batch", strings.NewReader(tt.body))
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: tt.token})
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			for i, sku := range []string{"ROS001", "LIL001"} {
+				f, err := handler.flowerService.GetFlower(context.Background(), sku)
+				if err != nil {
+					t.Fatalf("GetFlower() error = %v", err)
+				}
+				if f.Stock != tt.wantStock[i] {
+					t.Errorf("%s stock = %d, want %d", sku, f.Stock, tt.wantStock[i])
+				}
+			}
+			if tt.wantStatus == http.StatusOK && !strings.Contains(w.Body.String(), `"previous_stock":10`) {
+				t.Errorf("body missing per-SKU results: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+// TestHandleListFlowers_ThresholdOverride 测试店员可通过 threshold 参数覆盖库存预警阈值
+func TestHandleListFlowers_ThresholdOverride(t *testing.T) {
+	ctx, _ := setupUserTestHandler(t)
+	handler := ctx.handler
+	handler.flowerService = flower.NewFlowerServiceWithThreshold(flower.NewFlowerRepository(setupFlowerTestDB(t)), nil, 5)
+	for _, stock := range []int{3, 20} {
+		if err := handler.flowerService.CreateFlower(context.Background(), &flower.CreateFlowerRequest{
+			SKU: fmt.Sprintf("S%03d", stock), Name: "红玫瑰", Origin: "云南",
+			PurchasePrice: 50, SalePrice: 100, Stock: stock,
+		}); err != nil {
+			t.Fatalf("failed to create flower: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	_, clerkToken := createTestUserWithSession(t, ctx, "clerk", user.RoleClerk)
+	_, customerToken := createTestUserWithSession(t, ctx, "customer", user.RoleCustomer)
+
+	tests := []struct {
+		name       string
+		token      string
+		path       string
+		wantStatus int
+		wantLow    int // 预期 low_stock 为 true 的数量
+	}{
+		{name: "使用配置阈值", path: "/api/flowers", wantStatus: http.StatusOK, wantLow: 1},
+		{name: "店员覆盖阈值", token: clerkToken, path: "/api/flowers?threshold=50", wantStatus: http.StatusOK, wantLow: 2},
+		{name: "管理视图覆盖阈值", token: clerkToken, path: "/api/admin/flowers?threshold=2", wantStatus: http.StatusOK, wantLow: 0},
+		{name: "未登录不能覆盖", path: "/api/flowers?threshold=50", wantStatus: http.StatusUnauthorized},
+		{name: "顾客不能覆盖", token: customerToken, path: "/api/flowers?threshold=50", wantStatus: http.StatusForbidden},
+		{name: "无效阈值", token: clerkToken, path: "/api/flowers?threshold=-1", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: tt.token})
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got []struct {
+				LowStock bool `json:"low_stock"`
+			}
+			if err := unmarshalListData(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			low := 0
+			for _, f := range got {
+				if f.LowStock {
+					low++
+				}
+			}
+			if low != tt.wantLow {
+				t.Errorf("low stock count = %d, want %d", low, tt.wantLow)
+			}
+		})
+	}
+}
+
+// TestHandleGetFlowerLogs 测试进货后管理员可查看记录了操作人的审计日志，店员无权查看
+func TestHandleGetFlowerLogs(t *testing.T) {
+	ctx, _ := setupUserTestHandler(t)
+	handler := ctx.handler
+	db := setupFlowerTestDB(t)
+	handler.flowerService = flower.NewFlowerServiceWithThreshold(flower.NewFlowerRepository(db), flower.NewFlowerLogRepository(db), 0)
+	if err := handler.flowerService.CreateFlower(context.Background(), &flower.CreateFlowerRequest{
+		SKU: "ROS001", Name: "红玫瑰", Origin: "云南", PurchasePrice: 50, SalePrice: 60, Stock: 80,
+	}); err != nil {
+		t.Fatalf("failed to create flower: %v", err)
+	}
+
+	clerk, clerkToken := createTestUserWithSession(t, ctx, "logclerk", user.RoleClerk)
+	_, adminToken := createTestUserWithSession(t, ctx, "logadmin", user.RoleAdmin)
+
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: token})
+		w := httptest.NewRecorder()
+		routeRequest(handler, w, req)
+		return w
+	}
+
+	if w := do("POST", "/api/flowers/ROS001/stock", `{"quantity":20}`, clerkToken); w.Code != http.StatusOK {
+		t.Fatalf("add stock status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	if w := do("GET", "/api/admin/flowers/ROS001/logs", "", clerkToken); w.Code != http.StatusForbidden {
+		t.Errorf("clerk get logs status = %d, want 403", w.Code)
+	}
+
+	w := do("GET", "/api/admin/flowers/ROS001/logs", "", adminToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("admin get logs status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var logs []*flower.FlowerLog
+	if err := json.Unmarshal(w.Body.Bytes(), &logs); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("got %d logs, want 1", len(logs))
+	}
+	if got := logs[0]; got.Action != flower.LogActionAddStock || got.OperatorID != clerk.ID ||
+		string(got.OldValue) != `{"stock":80}` || string(got.NewValue) != `{"stock":100}` {
+		t.Errorf("log = %+v (old %s, new %s), want add_stock by clerk from 80 to 100", got, got.OldValue, got.NewValue)
+	}
+}
+
+// TestHandleImportFlowers 测试通过 CSV 批量导入鲜花：合法行导入，非法行返回行号与原因
+func TestHandleImportFlowers(t *testing.T) {
+	handler, clerkToken := setupStaffFlowerTestHandler(t)
+
+	csvData := "sku,name,origin,shelf_life,preservation,purchase_price,sale_price,stock\n" +
+		"ROS001,红玫瑰,云南,7天,冷藏,10.5,15.8,100\n" +
+		"ROS002,,云南,7天,冷藏,10,15,50\n" +
+		"ROS003,白玫瑰,云南,7天,冷藏,abc,15,50\n"
+
+	newImportRequest := func(content string) *http.Request {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, err := mw.CreateFormFile("file", "flowers.csv")
+		if err != nil {
+			t.Fatalf("CreateFormFile() error = %v", err)
+		}
+		part.Write([]byte(content))
+		mw.Close()
+
+		req := httptest.NewRequest("POST", "/api/flowers/import", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return req
+	}
+
+	t.Run("顾客无权导入", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		routeRequest(handler, w, newImportRequest(csvData))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want 401", w.Code)
+		}
+	})
+
+	t.Run("缺少必需列", func(t *testing.T) {
+		req := newImportRequest("sku,name\nROS009,百合\n")
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: clerkToken})
+		w := httptest.NewRecorder()
+		routeRequest(handler, w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", w.Code)
+		}
+	})
+
+	t.Run("部分成功", func(t *testing.T) {
+		req := newImportRequest(csvData)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: clerkToken})
+		w := httptest.NewRecorder()
+		routeRequest(handler, w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+
+		var resp ImportFlowersResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if resp.Created != 1 || resp.Failed != 2 || len(resp.Errors) != 2 {
+			t.Fatalf("resp = %+v, want 1 created and 2 failed", resp)
+		}
+		if resp.Errors[0].Row != 3 || resp.Errors[1].Row != 4 {
+			t.Errorf("error rows = %d, %d, want 3, 4", resp.Errors[0].Row, resp.Errors[1].Row)
+		}
+
+		f, err := handler.flowerService.GetFlower(context.Background(), "ROS001")
+		if err != nil {
+			t.Fatalf("GetFlower() error = %v", err)
+		}
+		if f.Name != "红玫瑰" || f.Stock != 100 || f.SalePrice.String() != "15.80" {
+			t.Errorf("imported flower = %+v, want 红玫瑰 with stock 100 at 15.80", f)
+		}
+		if _, err := handler.flowerService.GetFlower(context.Background(), "ROS002"); err == nil {
+			t.Error("invalid row ROS002 should not be imported")
+		}
+	})
+
+	t.Run("格式错误与数据库错误记为失败行并继续导入", func(t *testing.T) {
+		content := "sku,name,origin,shelf_life,preservation,purchase_price,sale_price,stock\n" +
+			"LIL001,百\"合,云南,7天,冷藏,10,15,50\n" +
+			"ROS001,红玫瑰,云南,7天,冷藏,10,15,50\n" +
+			"LIL002,百合,云南,7天,冷藏,10,15,50\n"
+		req := newImportRequest(content)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: clerkToken})
+		w := httptest.NewRecorder()
+		routeRequest(handler, w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+
+		var resp ImportFlowersResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if resp.Created != 1 || resp.Failed != 2 || len(resp.Errors) != 2 {
+			t.Fatalf("resp = %+v, want 1 created and 2 failed", resp)
+		}
+		if resp.Errors[0].Row != 2 || resp.Errors[1].Row != 3 {
+			t.Errorf("error rows = %d, %d, want 2, 3", resp.Errors[0].Row, resp.Errors[1].Row)
+		}
+		// 重复 SKU 触发的数据库错误不能原样返回
+		if msg := resp.Errors[1].Message; msg != "failed to create flower" {
+			t.Errorf("duplicate row message = %q, want generic message", msg)
+		}
+		if _, err := handler.flowerService.GetFlower(context.Background(), "LIL002"); err != nil {
+			t.Errorf("row after CSV syntax error should be imported: %v", err)
+		}
+	})
+}
@@ -1,32 +1,86 @@
 package handler
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/biqiangwu/flowerSalesSystem/internal/address"
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 	"github.com/biqiangwu/flowerSalesSystem/internal/auth"
+	"github.com/biqiangwu/flowerSalesSystem/internal/cart"
+	"github.com/biqiangwu/flowerSalesSystem/internal/config"
+	"github.com/biqiangwu/flowerSalesSystem/internal/discount"
 	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+	"github.com/biqiangwu/flowerSalesSystem/internal/maintenance"
 	"github.com/biqiangwu/flowerSalesSystem/internal/order"
+	"github.com/biqiangwu/flowerSalesSystem/internal/report"
 	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
 )
 
 // Handler HTTP 处理器
 type Handler struct {
-	authService     auth.AuthService
-	orderService    order.OrderService
-	orderLogService order.OrderLogService
-	userService     user.UserService
-	flowerService   flower.FlowerService
-	addressService  address.AddressService
-	userRepo        user.UserRepository // 用于测试时获取用户信息
+	authService          auth.AuthService
+	orderService         order.OrderService
+	orderLogService      order.OrderLogService
+	userService          user.UserService
+	flowerService        flower.FlowerService
+	addressService       address.AddressService
+	reportService        report.ReportService
+	impersonationService auth.ImpersonationService
+	passwordResetService auth.PasswordResetService
+	exposeResetToken     bool // 申请重置密码时是否在响应中返回令牌，仅用于开发环境
+	maintenanceService   maintenance.MaintenanceService
+	discountService      discount.DiscountService
+	cartService          cart.CartService
+	config               *config.Config
+	cookieOptions        SessionCookieOptions    // Session Cookie 属性
+	apiPrefix            string                  // API 版本前缀，如 /api/v1
+	db                   *sql.DB                 // 用于就绪检查
+	reportConcurrency    int                     // 报表接口最大并发数
+	loginRateLimit       int                     // 登录与注册接口每个客户端 IP 在窗口内的请求上限
+	loginRateWindow      time.Duration           // 登录限流窗口
+	trustedProxies       []*net.IPNet            // 受信任的反向代理，限流时从其转发的请求头中取客户端 IP
+	orderIdempotency     *order.IdempotencyCache // 下单幂等键缓存，为 nil 时忽略 Idempotency-Key
+	userRepo             user.UserRepository     // 用于测试时获取用户信息
 }
 
+// DefaultReportConcurrency 报表接口默认最大并发数
+const DefaultReportConcurrency = 2
+
+// 登录与注册接口默认限流：每个客户端 IP 每分钟 10 次
+const (
+	DefaultLoginRateLimit  = 10
+	DefaultLoginRateWindow = time.Minute
+)
+
+// DefaultIdempotencyWindow 下单幂等键默认有效期
+const DefaultIdempotencyWindow = time.Hour
+
+// API 路由前缀：路由注册在 DefaultAPIPrefix（可配置）下，LegacyAPIPrefix 作为旧版别名保留一个版本
+const (
+	DefaultAPIPrefix = "/api/v1"
+	LegacyAPIPrefix  = "/api"
+)
+
 // NewHandler 创建 Handler
 func NewHandler(authService auth.AuthService, orderService order.OrderService) *Handler {
 	return &Handler{
-		authService:  authService,
-		orderService: orderService,
+		authService:       authService,
+		orderService:      orderService,
+		reportConcurrency: DefaultReportConcurrency,
+		loginRateLimit:    DefaultLoginRateLimit,
+		loginRateWindow:   DefaultLoginRateWindow,
+		orderIdempotency:  order.NewIdempotencyCache(DefaultIdempotencyWindow),
+		apiPrefix:         DefaultAPIPrefix,
 	}
 }
 
@@ -43,54 +97,279 @@ func (h *Handler) respondJSON(w http.ResponseWriter, status int, data interface{
 }
 
 // respondError 返回错误响应
+// 请求 ID 由 RequestIDMiddleware 写入响应头，此处回填到响应体
 func (h *Handler) respondError(w http.ResponseWriter, status int, message string) {
-	h.respondJSON(w, status, ErrorResponse{Error: message})
+	h.respondJSON(w, status, ErrorResponse{Error: message, RequestID: w.Header().Get(middleware.RequestIDHeader)})
+}
+
+// respondServiceError 按服务层错误分类返回对应状态码（见 apperror.HTTPStatus）
+// 客户端已断开时返回 499，超过请求超时时间返回 504，未分类错误返回 500
+func (h *Handler) respondServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	if h.respondCanceled(w, r, err) {
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		h.respondError(w, http.StatusGatewayTimeout, "request timed out")
+		return
+	}
+	h.respondError(w, apperror.HTTPStatus(err), err.Error())
+}
+
+// StatusClientClosedRequest 客户端在响应前断开连接（沿用 nginx 的 499 约定）
+const StatusClientClosedRequest = 499
+
+// respondCanceled 请求因客户端断开而取消时返回 499 并返回 true
+// 这类错误不是服务端故障，调用方据此跳过 500 响应
+func (h *Handler) respondCanceled(w http.ResponseWriter, r *http.Request, err error) bool {
+	if !errors.Is(err, context.Canceled) && !errors.Is(r.Context().Err(), context.Canceled) {
+		return false
+	}
+	w.WriteHeader(StatusClientClosedRequest)
+	return true
+}
+
+// TotalCountHeader 列表接口按需返回总数时使用的响应头
+const TotalCountHeader = "X-Total-Count"
+
+// HasMoreHeader 列表接口标识是否还有下一页的响应头（true/false），通过多取一行计算，无需 COUNT
+const HasMoreHeader = "X-Has-More"
+
+// parseWithTotal 解析 with_total 查询参数，默认不统计总数
+// 只有调用方明确需要时才执行 COUNT 查询，避免无限滚动场景下的额外开销
+func parseWithTotal(r *http.Request) (bool, error) {
+	v := r.URL.Query().Get("with_total")
+	if v == "" {
+		return false, nil
+	}
+	withTotal, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("with_total must be true or false")
+	}
+	return withTotal, nil
+}
+
+// parseArrayFormat 解析 format 查询参数：默认返回带分页元数据的 ListResponse，
+// format=array 时返回旧版裸数组，供尚未迁移的调用方使用
+func parseArrayFormat(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("format") {
+	case "":
+		return false, nil
+	case "array":
+		return true, nil
+	default:
+		return false, fmt.Errorf("format must be array or omitted")
+	}
+}
+
+// respondList 按 format 输出列表：旧版格式直接输出 legacy，否则输出带分页元数据的 ListResponse
+func (h *Handler) respondList(w http.ResponseWriter, arrayFormat bool, legacy, data interface{}, total, page, pageSize int) {
+	if arrayFormat {
+		h.respondJSON(w, http.StatusOK, legacy)
+		return
+	}
+	h.respondJSON(w, http.StatusOK, newListResponse(data, total, page, pageSize))
+}
+
+// pathIDParam 将路由通配符 name 匹配到的路径段解析为正整数 ID
+// 例如路由 "POST /api/orders/{id}/complete" 下请求 /api/orders/123/complete 时 pathIDParam(r, "id") 为 123
+// 字符串参数直接使用 r.PathValue；段不存在、非数字或 <= 0 时返回错误，调用方应映射为 400
+func pathIDParam(r *http.Request, name string) (int, error) {
+	value := r.PathValue(name)
+	if value == "" {
+		return 0, fmt.Errorf("missing %s in path", name)
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be numeric: %q", name, value)
+	}
+	if id <= 0 {
+		return 0, fmt.Errorf("id must be positive: %d", id)
+	}
+	return id, nil
 }
 
 // RegisterRoutes 注册所有路由
+// 每个路由同时注册在版本前缀（默认 /api/v1，见 SetAPIPrefix）与旧版 /api 前缀下，旧前缀保留一个版本后移除
+// 路径按方法和路径精确匹配，末尾斜杠与前缀大小写由 middleware.NormalizePathPrefixesMiddleware 统一处理
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	prefixes := h.APIPrefixes()
+	// handle 按 "METHOD /path" 在每个 API 前缀下注册路由，path 不含前缀
+	handle := func(pattern string, handler http.HandlerFunc) {
+		method, path, _ := strings.Cut(pattern, " ")
+		for _, prefix := range prefixes {
+			mux.HandleFunc(method+" "+prefix+path, handler)
+		}
+	}
+
+	// 受保护的路由在进入处理器前完成认证与角色校验：未登录或 session 无效/过期返回 401，角色不符返回 403
+	// 处理器通过 middleware.UserFromContext 获取当前用户
+	requireAuth := middleware.AuthMiddleware(h.authService)
+	requireRole := func(roles ...user.Role) func(http.HandlerFunc) http.HandlerFunc {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return requireAuth(middleware.RoleMiddleware(roles...)(next))
+		}
+	}
+	requireStaff := requireRole(user.RoleAdmin, user.RoleClerk)
+	requireAdmin := requireRole(user.RoleAdmin)
+
+	// ========== 健康检查路由 ==========
+	// 供容器编排探活，无需认证
+	handle("GET /health", h.HandleHealth)
+	handle("GET /ready", h.HandleReady)
+
 	// ========== 认证路由 ==========
-	mux.HandleFunc("POST /api/register", h.HandleRegister)
-	mux.HandleFunc("POST /api/login", h.HandleLogin)
-	mux.HandleFunc("POST /api/logout", h.HandleLogout)
+	// 登录与注册按客户端 IP 共享限流配额，防止暴力破解与批量注册，超出返回 429
+	limitAuth := middleware.RateLimitMiddleware(h.loginRateLimit, h.loginRateWindow, h.trustedProxies)
+	handle("POST /register", limitAuth(h.HandleRegister))
+	handle("POST /login", limitAuth(h.HandleLogin))
+	handle("POST /logout", h.HandleLogout)
+	// 忘记密码：与登录共享限流配额，防止批量申请令牌与暴力猜测
+	handle("POST /password-reset/request", limitAuth(h.HandleRequestPasswordReset))
+	handle("POST /password-reset/confirm", limitAuth(h.HandleConfirmPasswordReset))
 
 	// ========== 鲜花路由 ==========
 	// 公开路由：所有用户可访问
-	mux.HandleFunc("GET /api/flowers", h.HandleListFlowers)
-	mux.HandleFunc("GET /api/flowers/", h.HandleGetFlower)
+	handle("GET /flowers", h.HandleListFlowers)
+	handle("GET /flowers/{sku}", h.HandleGetFlower)
 
 	// 需要认证的路由：店员和管理员
-	mux.HandleFunc("POST /api/flowers", h.HandleCreateFlower)
-	mux.HandleFunc("PUT /api/flowers/", h.HandleUpdateFlower)
-	mux.HandleFunc("DELETE /api/flowers/", h.HandleDeleteFlower)
-	mux.HandleFunc("POST /api/flowers/stock", h.HandleAddStock)
+	handle("POST /flowers", requireStaff(h.HandleCreateFlower))
+	handle("POST /flowers/import", requireStaff(h.HandleImportFlowers))
+	handle("PUT /flowers/{sku}", requireStaff(h.HandleUpdateFlower))
+	handle("PATCH /flowers/{sku}", requireStaff(h.HandleUpdateFlower))
+	handle("DELETE /flowers/{sku}", requireStaff(h.HandleDeleteFlower))
+	handle("POST /flowers/{sku}/stock", requireStaff(h.HandleAddStock))
+	handle("POST /flowers/stock/batch", requireStaff(h.HandleBatchAddStock))
+	handle("POST /flowers/{sku}/clone", requireStaff(h.HandleCloneFlower))
+	handle("POST /flowers/{sku}/activate", requireStaff(h.HandleActivateFlower))
+	handle("GET /admin/flowers", requireStaff(h.HandleAdminListFlowers))
+	// 库存预警：比 /flowers/{sku} 更具体，优先匹配
+	handle("GET /flowers/low-stock", requireStaff(h.HandleLowStockAlerts))
+	// 仅管理员：鲜花变更审计日志
+	handle("GET /admin/flowers/{sku}/logs", requireAdmin(h.HandleGetFlowerLogs))
 
 	// ========== 地址路由 ==========
 	// 需要认证的路由：所有登录用户
-	mux.HandleFunc("GET /api/addresses", h.HandleListAddresses)
-	mux.HandleFunc("POST /api/addresses", h.HandleCreateAddress)
-	mux.HandleFunc("PUT /api/addresses/", h.HandleUpdateAddress)
-	mux.HandleFunc("DELETE /api/addresses/", h.HandleDeleteAddress)
+	handle("GET /addresses", requireAuth(h.HandleListAddresses))
+	handle("POST /addresses", requireAuth(h.HandleCreateAddress))
+	handle("PUT /addresses/{id}", requireAuth(h.HandleUpdateAddress))
+	handle("DELETE /addresses/{id}", requireAuth(h.HandleDeleteAddress))
 
 	// ========== 订单路由 ==========
 	// 需要认证的路由：所有登录用户
-	mux.HandleFunc("POST /api/orders", h.HandleCreateOrder)
-	mux.HandleFunc("GET /api/orders", h.HandleListOrders)
-	mux.HandleFunc("GET /api/orders/", h.HandleGetOrder)
+	handle("POST /orders", requireAuth(h.HandleCreateOrder))
+	handle("GET /orders", requireAuth(h.HandleListOrders))
+	handle("GET /orders/{orderNo}", requireAuth(h.HandleGetOrder))
+	// 店员和管理员按订单ID查看任意订单
+	handle("GET /admin/orders/{id}", requireStaff(h.HandleGetOrderByID))
+
+	// 订单状态流转路由：发货仅店员和管理员；完成与取消的权限由订单服务按角色和所有者判断
+	handle("POST /orders/{id}/ship", requireStaff(h.HandleShipOrder))
+	handle("POST /orders/{id}/complete", requireAuth(h.HandleCompleteOrder))
+	handle("POST /orders/{id}/cancel", requireAuth(h.HandleCancelOrder))
+	handle("DELETE /orders/{id}/items/{itemID}", requireAuth(h.HandleCancelOrderItem))
+	handle("PATCH /orders/{id}/items/{itemID}", requireStaff(h.HandleUpdateOrderItem))
+	handle("POST /me/orders/bulk-cancel", requireAuth(h.HandleBulkCancelOwnOrders))
+	handle("GET /me/orders/summary", requireAuth(h.HandleOrderSummary))
+	handle("POST /orders/{id}/reorder", requireAuth(h.HandleReorder))
 
-	// 订单状态流转路由
-	mux.HandleFunc("POST /api/orders/complete", h.HandleCompleteOrder)
-	mux.HandleFunc("POST /api/orders/cancel", h.HandleCancelOrder)
+	// 购物车
+	handle("GET /cart", requireAuth(h.HandleGetCart))
+	handle("POST /cart/items", requireAuth(h.HandleAddCartItem))
+	handle("PUT /cart/items/{sku}", requireAuth(h.HandleUpdateCartItem))
+	handle("DELETE /cart/items/{sku}", requireAuth(h.HandleRemoveCartItem))
+	handle("POST /cart/checkout", requireAuth(h.HandleCheckoutCart))
+	// 早期以草稿订单实现的购物车接口已下线，草稿订单由迁移并入购物车，旧接口返回 410 并提示替代接口
+	handle("PUT /cart", h.HandleDraftCartGone)
+	handle("POST /orders/{id}/checkout", h.HandleDraftCartGone)
 
 	// ========== 用户管理路由 ==========
-	// 需要管理员权限的路由
-	mux.HandleFunc("GET /api/users", h.HandleListUsers)
-	mux.HandleFunc("DELETE /api/users/", h.HandleDeleteUser)
-	mux.HandleFunc("POST /api/users/reset-password", h.HandleResetPassword)
+	// 删除用户与重置密码的权限由用户服务按操作者角色判断
+	handle("GET /users", requireAuth(h.HandleListUsers))
+	handle("DELETE /users/{id}", requireAuth(h.HandleDeleteUser))
+	handle("POST /users/{id}/reset-password", requireAuth(h.HandleResetPassword))
+	// 所有登录用户：查看与修改本人信息、修改本人密码
+	handle("GET /me", requireAuth(h.HandleGetCurrentUser))
+	// 代客登录会话不能修改资料与密码，避免管理员借此接管账号
+	handle("PATCH /me", requireAuth(middleware.DenyImpersonation(h.HandleUpdateProfile)))
+	handle("POST /me/password", requireAuth(middleware.DenyImpersonation(h.HandleChangePassword)))
+	handle("POST /admin/users/{id}/impersonate", requireAdmin(h.HandleImpersonateUser))
 
 	// ========== 订单日志路由 ==========
 	// 需要认证的路由
-	mux.HandleFunc("GET /api/orders/logs", h.HandleGetOrderLogs)
+	handle("GET /orders/logs", requireAuth(h.HandleGetOrderLogs))
+	handle("GET /orders/{id}/logs", requireAuth(h.HandleGetOrderLogs))
+
+	// ========== 报表路由 ==========
+	// 报表聚合查询较重，使用独立的并发配额，饱和时返回 429，不影响普通接口
+	limitReports := middleware.ConcurrencyLimitMiddleware(h.reportConcurrency)
+
+	// 需要管理员权限的路由；先认证再占用并发配额，未授权请求不消耗配额
+	handle("GET /reports/new-users", requireAdmin(limitReports(h.HandleNewUserReport)))
+	handle("GET /reports/sales", requireAdmin(limitReports(h.HandleSalesReport)))
+
+	// 需要店员或管理员权限的路由
+	handle("GET /reports/blocked-orders", requireStaff(limitReports(h.HandleBlockedOrdersReport)))
+
+	// ========== 数据维护路由 ==========
+	// 需要管理员权限的路由
+	handle("POST /admin/maintenance/orphan-order-items", requireAdmin(h.HandleCleanupOrphanOrderItems))
+	handle("POST /admin/maintenance/optimize", requireAdmin(h.HandleOptimizeDatabase))
+
+	// ========== 优惠码路由 ==========
+	// 需要管理员权限的路由
+	handle("GET /admin/discounts", requireAdmin(h.HandleListDiscounts))
+	handle("POST /admin/discounts", requireAdmin(h.HandleCreateDiscount))
+	handle("GET /admin/discounts/{id}", requireAdmin(h.HandleGetDiscount))
+	handle("PUT /admin/discounts/{id}", requireAdmin(h.HandleUpdateDiscount))
+	handle("DELETE /admin/discounts/{id}", requireAdmin(h.HandleDeleteDiscount))
+
+	// ========== 诊断路由 ==========
+	// 需要管理员权限的路由
+	handle("GET /admin/config", requireAdmin(h.HandleGetConfig))
+	handle("GET /admin/stats", requireAdmin(h.HandleGetStats))
+
+	// ========== 兜底路由 ==========
+	// 未匹配的 API 路径返回 JSON 404，路径存在但方法不支持时返回 JSON 405，避免落入静态文件/SPA 处理
+	// 同时注册不带斜杠的前缀（如 /api），避免 ServeMux 将其重定向到 /api/ 后又被规范化中间件去掉斜杠
+	fallbackPatterns := make(map[string]bool, 2*len(prefixes))
+	for _, prefix := range prefixes {
+		fallbackPatterns[prefix] = true
+		fallbackPatterns[prefix+"/"] = true
+	}
+	fallback := h.methodNotAllowed(mux, fallbackPatterns, h.HandleNotFound)
+	for pattern := range fallbackPatterns {
+		mux.HandleFunc(pattern, fallback)
+	}
+}
+
+// routeMethods 兜底路由探测的请求方法
+var routeMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// methodNotAllowed 包装兜底路由：请求路径匹配其他方法的路由时返回 405 并设置 Allow 头，否则交给 next
+// 兜底路由匹配所有方法，ServeMux 自身不会再对这些路径返回 405，因此需要按方法探测
+func (h *Handler) methodNotAllowed(mux *http.ServeMux, fallbackPatterns map[string]bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range routeMethods {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if _, pattern := mux.Handler(probe); pattern != "" && !fallbackPatterns[pattern] {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) == 0 {
+			next(w, r)
+			return
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		h.respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// HandleNotFound 处理未知的 API 路径
+func (h *Handler) HandleNotFound(w http.ResponseWriter, r *http.Request) {
+	h.respondError(w, http.StatusNotFound, "not found")
 }
 
 // SetServices 设置所有服务（用于依赖注入）
@@ -111,3 +390,111 @@ func (h *Handler) SetServices(
 	h.addressService = addressSvc
 	h.userRepo = userRepo
 }
+
+// SetReportService 设置报表服务
+func (h *Handler) SetReportService(reportSvc report.ReportService) {
+	h.reportService = reportSvc
+}
+
+// SetImpersonationService 设置代客登录服务
+func (h *Handler) SetImpersonationService(impersonationSvc auth.ImpersonationService) {
+	h.impersonationService = impersonationSvc
+}
+
+// SetPasswordResetService 设置忘记密码服务
+// exposeToken 为 true 时申请接口直接返回重置令牌，仅用于尚未接入邮件发送的开发环境
+func (h *Handler) SetPasswordResetService(passwordResetSvc auth.PasswordResetService, exposeToken bool) {
+	h.passwordResetService = passwordResetSvc
+	h.exposeResetToken = exposeToken
+}
+
+// SetMaintenanceService 设置数据维护服务
+func (h *Handler) SetMaintenanceService(maintenanceSvc maintenance.MaintenanceService) {
+	h.maintenanceService = maintenanceSvc
+}
+
+// SetDiscountService 设置优惠码管理服务
+func (h *Handler) SetDiscountService(discountSvc discount.DiscountService) {
+	h.discountService = discountSvc
+}
+
+// SetCartService 设置购物车服务
+func (h *Handler) SetCartService(cartSvc cart.CartService) {
+	h.cartService = cartSvc
+}
+
+// SetDB 设置就绪检查使用的数据库连接
+func (h *Handler) SetDB(db *sql.DB) {
+	h.db = db
+}
+
+// SetReportConcurrency 设置报表接口最大并发数，需在 RegisterRoutes 之前调用
+func (h *Handler) SetReportConcurrency(limit int) {
+	h.reportConcurrency = limit
+}
+
+// SetLoginRateLimit 设置登录与注册接口的限流（window 内最多 limit 次），需在 RegisterRoutes 之前调用
+// limit <= 0 表示不限制
+func (h *Handler) SetLoginRateLimit(limit int, window time.Duration) {
+	h.loginRateLimit = limit
+	h.loginRateWindow = window
+}
+
+// SetTrustedProxies 设置受信任的反向代理，需在 RegisterRoutes 之前调用
+// 部署在 Ingress 等代理之后时必须配置，否则所有请求共享代理 IP 的限流配额
+func (h *Handler) SetTrustedProxies(proxies []*net.IPNet) {
+	h.trustedProxies = proxies
+}
+
+// SetIdempotencyWindow 设置下单幂等键的有效期，window <= 0 表示不启用幂等键
+func (h *Handler) SetIdempotencyWindow(window time.Duration) {
+	if window <= 0 {
+		h.orderIdempotency = nil
+		return
+	}
+	h.orderIdempotency = order.NewIdempotencyCache(window)
+}
+
+// SetSessionCookieOptions 设置 Session Cookie 的 Path、Domain、SameSite 与 Secure 属性
+func (h *Handler) SetSessionCookieOptions(opts SessionCookieOptions) {
+	h.cookieOptions = opts
+}
+
+// SetAPIPrefix 设置 API 版本前缀（如 /api/v1），需在 RegisterRoutes 之前调用
+// 前缀统一为以 / 开头、不以 / 结尾的小写形式，为空时使用 DefaultAPIPrefix
+func (h *Handler) SetAPIPrefix(prefix string) {
+	prefix = strings.ToLower(strings.Trim(strings.TrimSpace(prefix), "/"))
+	if prefix == "" {
+		h.apiPrefix = DefaultAPIPrefix
+		return
+	}
+	h.apiPrefix = "/" + prefix
+}
+
+// APIPrefixes 返回注册路由的所有前缀：版本前缀在前，旧版 /api 前缀在后（与版本前缀相同时只返回一个）
+func (h *Handler) APIPrefixes() []string {
+	prefix := h.apiPrefix
+	if prefix == "" {
+		prefix = DefaultAPIPrefix
+	}
+	if prefix == LegacyAPIPrefix {
+		return []string{LegacyAPIPrefix}
+	}
+	return []string{prefix, LegacyAPIPrefix}
+}
+
+// APIPaths 返回 paths（不含前缀，如 /login）在所有 API 前缀下的完整路径，供按路径配置的中间件使用
+func (h *Handler) APIPaths(paths ...string) []string {
+	var full []string
+	for _, prefix := range h.APIPrefixes() {
+		for _, path := range paths {
+			full = append(full, prefix+path)
+		}
+	}
+	return full
+}
+
+// SetConfig 设置当前生效的配置（用于诊断接口）
+func (h *Handler) SetConfig(cfg *config.Config) {
+	h.config = cfg
+}
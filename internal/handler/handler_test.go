@@ -1,12 +1,39 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+	"github.com/biqiangwu/flowerSalesSystem/internal/order"
+	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
 )
 
+// unmarshalListData 解析分页列表响应中的 data 字段
+func unmarshalListData(body []byte, v interface{}) error {
+	var resp struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return err
+	}
+	return json.Unmarshal(resp.Data, v)
+}
+
+// routeRequest 通过 RegisterRoutes 注册的路由分发请求，使路径参数按生产环境方式解析
+func routeRequest(h *Handler, w http.ResponseWriter, r *http.Request) {
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	mux.ServeHTTP(w, r)
+}
+
 // TestNewHandler 测试 Handler 创建
 func TestNewHandler(t *testing.T) {
 	h := NewHandler(nil, nil)
@@ -130,9 +157,224 @@ func TestHandler_respondError(t *testing.T) {
 	}
 }
 
+// TestHandler_respondError_RequestID 测试错误响应体携带请求 ID
+func TestHandler_respondError_RequestID(t *testing.T) {
+	h := NewHandler(nil, nil)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.respondError(w, http.StatusBadRequest, "invalid input")
+	})
+
+	req := httptest.NewRequest("GET", "/api/flowers", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-abc")
+	w := httptest.NewRecorder()
+	middleware.RequestIDMiddleware(next).ServeHTTP(w, req)
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.RequestID != "req-abc" {
+		t.Errorf("request_id = %q, want %q", resp.RequestID, "req-abc")
+	}
+}
+
+// TestHandler_respondServiceError 测试服务层错误按分类映射状态码
+func TestHandler_respondServiceError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "校验失败", err: apperror.Validation("数量必须大于0"), wantStatus: http.StatusBadRequest},
+		{name: "包装后的不存在错误", err: fmt.Errorf("订单不存在: %w", fmt.Errorf("%w: 1", order.ErrOrderNotFound)), wantStatus: http.StatusNotFound},
+		{name: "无权操作", err: apperror.Forbidden("无权访问该订单"), wantStatus: http.StatusForbidden},
+		{name: "未分类错误", err: errors.New("connection refused"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(nil, nil)
+			w := httptest.NewRecorder()
+
+			h.respondServiceError(w, httptest.NewRequest("GET", "/", nil), tt.err)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("respondServiceError() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp.Error != tt.err.Error() {
+				t.Errorf("respondServiceError() error = %q, want %q", resp.Error, tt.err.Error())
+			}
+		})
+	}
+}
+
 // TestCookieName 测试 Cookie 名称常量
 func TestCookieName(t *testing.T) {
 	if CookieName != "session_token" {
 		t.Errorf("CookieName = %q, want session_token", CookieName)
 	}
 }
+
+// TestRegisterRoutes_PathNormalization 测试末尾斜杠、前缀大小写与未知 API 路径的路由行为
+func TestRegisterRoutes_PathNormalization(t *testing.T) {
+	h := setupFlowerTestHandler(t)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	router := middleware.NormalizePathMiddleware(mux)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"列表路径", "GET", "/api/flowers", http.StatusOK},
+		{"列表路径带末尾斜杠", "GET", "/api/flowers/", http.StatusOK},
+		{"列表路径大写前缀", "GET", "/API/Flowers", http.StatusOK},
+		{"未知 API 路径", "GET", "/api/unknown", http.StatusNotFound},
+		{"未知 API 子路径", "GET", "/api/flowers/FLW001/unknown", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("%s %s status = %d, want %d, body = %s", tt.method, tt.path, w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if tt.wantStatus == http.StatusNotFound {
+				if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", ct)
+				}
+				var resp ErrorResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("failed to parse response: %v", err)
+				}
+				if resp.Error == "" {
+					t.Error("expected error message in 404 response")
+				}
+			}
+		})
+	}
+}
+
+// TestRegisterRoutes_APIPrefix 测试路由同时注册在版本前缀与旧版 /api 前缀下，版本前缀可配置
+func TestRegisterRoutes_APIPrefix(t *testing.T) {
+	tests := []struct {
+		name       string
+		prefix     string // 为空表示使用默认前缀
+		path       string
+		wantStatus int
+	}{
+		{name: "默认版本前缀", path: "/api/v1/flowers", wantStatus: http.StatusOK},
+		{name: "默认版本前缀大小写与末尾斜杠", path: "/API/V1/Flowers/", wantStatus: http.StatusOK},
+		{name: "旧版前缀", path: "/api/flowers", wantStatus: http.StatusOK},
+		{name: "版本前缀下未知路径", path: "/api/v1/unknown", wantStatus: http.StatusNotFound},
+		{name: "自定义前缀", prefix: "rest/v2/", path: "/rest/v2/flowers", wantStatus: http.StatusOK},
+		{name: "自定义前缀时旧版前缀仍可用", prefix: "/rest/v2", path: "/api/flowers", wantStatus: http.StatusOK},
+		{name: "自定义前缀时默认版本前缀不可用", prefix: "/rest/v2", path: "/api/v1/flowers", wantStatus: http.StatusNotFound},
+		{name: "自定义前缀下未知路径", prefix: "/rest/v2", path: "/rest/v2/unknown", wantStatus: http.StatusNotFound},
+		{name: "前缀与旧版相同", prefix: "/api", path: "/api/flowers", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := setupFlowerTestHandler(t)
+			if tt.prefix != "" {
+				h.SetAPIPrefix(tt.prefix)
+			}
+			mux := http.NewServeMux()
+			h.RegisterRoutes(mux)
+			router := middleware.NormalizePathPrefixesMiddleware(h.APIPrefixes()...)(mux)
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("GET %s status = %d, want %d, body = %s", tt.path, w.Code, tt.wantStatus, w.Body.String())
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+		})
+	}
+}
+
+// TestHandler_APIPaths 测试按路径配置的中间件获取所有前缀下的完整路径
+func TestHandler_APIPaths(t *testing.T) {
+	h := NewHandler(nil, nil)
+	want := []string{"/api/v1/login", "/api/v1/register", "/api/login", "/api/register"}
+	if got := h.APIPaths("/login", "/register"); !reflect.DeepEqual(got, want) {
+		t.Errorf("APIPaths() = %v, want %v", got, want)
+	}
+
+	h.SetAPIPrefix("/api/")
+	if got := h.APIPaths("/login"); !reflect.DeepEqual(got, []string{"/api/login"}) {
+		t.Errorf("APIPaths() with legacy prefix = %v, want [/api/login]", got)
+	}
+}
+
+// TestRegisterRoutes_Dispatch 测试路由按方法与路径分发到对应处理器，并解析路径参数
+func TestRegisterRoutes_Dispatch(t *testing.T) {
+	h := setupFlowerTestHandler(t)
+	if err := h.flowerService.CreateFlower(context.Background(), &flower.CreateFlowerRequest{
+		SKU: "FLW001", Name: "红玫瑰", Origin: "云南", PurchasePrice: 50, SalePrice: 100, Stock: 10,
+	}); err != nil {
+		t.Fatalf("failed to create flower: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantAllow  string
+		wantSKU    string
+	}{
+		{name: "详情按 SKU 分发", method: "GET", path: "/api/flowers/FLW001", wantStatus: http.StatusOK, wantSKU: "FLW001"},
+		{name: "SKU 不存在", method: "GET", path: "/api/flowers/NOPE", wantStatus: http.StatusNotFound},
+		{name: "详情不支持 POST", method: "POST", path: "/api/flowers/FLW001", wantStatus: http.StatusMethodNotAllowed, wantAllow: "GET, PUT, PATCH, DELETE"},
+		{name: "列表不支持 DELETE", method: "DELETE", path: "/api/flowers", wantStatus: http.StatusMethodNotAllowed, wantAllow: "GET, POST"},
+		{name: "取消订单仅支持 POST", method: "GET", path: "/api/orders/1/cancel", wantStatus: http.StatusMethodNotAllowed, wantAllow: "POST"},
+		{name: "未知路径", method: "POST", path: "/api/flowers/FLW001/unknown", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			routeRequest(h, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("%s %s status = %d, want %d, body = %s", tt.method, tt.path, w.Code, tt.wantStatus, w.Body.String())
+			}
+			if allow := w.Header().Get("Allow"); allow != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", allow, tt.wantAllow)
+			}
+			if w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", w.Header().Get("Content-Type"))
+			}
+			if tt.wantSKU != "" {
+				var resp struct {
+					SKU string `json:"sku"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("failed to parse response: %v", err)
+				}
+				if resp.SKU != tt.wantSKU {
+					t.Errorf("sku = %q, want %q", resp.SKU, tt.wantSKU)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// readinessTimeout 就绪检查中数据库 Ping 的超时时间
+const readinessTimeout = 2 * time.Second
+
+// HandleHealth 存活检查，进程能处理请求即返回 200
+// GET /api/health
+func (h *Handler) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	h.respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// HandleReady 就绪检查，数据库可达时返回 200，否则返回 503 及原因
+// GET /api/ready
+func (h *Handler) HandleReady(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		h.respondError(w, http.StatusServiceUnavailable, "database not configured")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	if err := h.db.PingContext(ctx); err != nil {
+		h.respondError(w, http.StatusServiceUnavailable, "database unreachable: "+err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestHandleHealth 测试存活检查不依赖数据库，始终返回 200
+func TestHandleHealth(t *testing.T) {
+	handler := NewHandler(nil, nil)
+
+	req := httptest.NewRequest("GET", "/api/health", nil)
+	w := httptest.NewRecorder()
+	routeRequest(handler, w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("HandleHealth() status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+// TestHandleReady 测试就绪检查按数据库可达性返回 200 或 503
+func TestHandleReady(t *testing.T) {
+	liveDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { liveDB.Close() })
+
+	closedDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	closedDB.Close()
+
+	tests := []struct {
+		name       string
+		db         *sql.DB
+		wantStatus int
+	}{
+		{name: "数据库可达", db: liveDB, wantStatus: http.StatusOK},
+		{name: "数据库已关闭", db: closedDB, wantStatus: http.StatusServiceUnavailable},
+		{name: "未配置数据库", db: nil, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, nil)
+			handler.SetDB(tt.db)
+
+			req := httptest.NewRequest("GET", "/api/ready", nil)
+			w := httptest.NewRecorder()
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleReady() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				return
+			}
+
+			var resp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !strings.Contains(resp.Error, "database") {
+				t.Errorf("error = %q, want database reason", resp.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
+)
+
+// HandleImpersonateUser 处理管理员代客登录请求
+// 路径格式: /api/admin/users/{id}/impersonate
+// 返回的 token 只用于代客会话，不会覆盖管理员自身的 Cookie
+func (h *Handler) HandleImpersonateUser(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "未登录或 session 无效")
+		return
+	}
+
+	targetID, err := pathIDParam(r, "id")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	session, err := h.impersonationService.Impersonate(r.Context(), currentUser, targetID)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"token":           session.Token,
+		"impersonated":    true,
+		"impersonator_id": session.ImpersonatorID,
+		"expires_at":      session.ExpiresAt,
+		"user": map[string]interface{}{
+			"id":       session.UserID,
+			"username": session.Username,
+			"role":     session.Role,
+		},
+	})
+}
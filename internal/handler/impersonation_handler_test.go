@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/auth"
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+	"github.com/biqiangwu/flowerSalesSystem/internal/order"
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+)
+
+// setupImpersonationTestHandler 创建代客登录测试用的 Handler
+func setupImpersonationTestHandler(t *testing.T) (*Handler, *sql.DB) {
+	t.Helper()
+
+	db := setupOrderTestDB(t)
+
+	createTableSQL := `
+		CREATE TABLE IF NOT EXISTS impersonation_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			admin_id INTEGER NOT NULL,
+			target_user_id INTEGER NOT NULL,
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`
+	if _, err := db.Exec(createTableSQL); err != nil {
+		t.Fatalf("failed to create impersonation_logs table: %v", err)
+	}
+
+	userRepo := user.NewMySQLUserRepository(db)
+	sessionMgr := auth.NewMemorySessionManager()
+	authSvc := auth.NewAuthService(userRepo, sessionMgr)
+	orderSvc := order.NewOrderService(order.NewOrderRepository(db), flower.NewFlowerRepository(db), order.NewOrderLogRepository(db))
+
+	h := &Handler{
+		authService:          authSvc,
+		orderService:         orderSvc,
+		impersonationService: auth.NewImpersonationService(userRepo, sessionMgr, auth.NewImpersonationLogRepository(db), auth.DefaultImpersonationTTL),
+	}
+
+	return h, db
+}
+
+// TestHandleImpersonateUser 测试管理员代客登录接口
+func TestHandleImpersonateUser(t *testing.T) {
+	h, db := setupImpersonationTestHandler(t)
+	ctx := context.Background()
+
+	customerID, addressID := insertTestData(t, db)
+	orderNo, err := h.orderService.CreateOrder(ctx, customerID, &order.CreateOrderRequest{
+		AddressID: addressID,
+		Items:     []*order.CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+
+	adminToken := loginUser(t, h, "admin", "password123")
+	clerkToken := loginUser(t, h, "clerk", "password123")
+	customerToken := loginUser(t, h, "customer2", "password123")
+	if _, err := db.Exec("UPDATE users SET role = 'admin' WHERE username = 'admin'"); err != nil {
+		t.Fatalf("failed to promote admin: %v", err)
+	}
+	if _, err := db.Exec("UPDATE users SET role = 'clerk' WHERE username = 'clerk'"); err != nil {
+		t.Fatalf("failed to promote clerk: %v", err)
+	}
+
+	impersonate := func(token, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/admin/users/"+id+"/impersonate", nil)
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: CookieName, Value: token})
+		}
+		w := httptest.NewRecorder()
+		routeRequest(h, w, req)
+		return w
+	}
+
+	customerPath := strconv.Itoa(customerID)
+	denied := []struct {
+		name       string
+		token      string
+		id         string
+		wantStatus int
+	}{
+		{"未登录", "", customerPath, http.StatusUnauthorized},
+		{"店员无权模拟", clerkToken, customerPath, http.StatusForbidden},
+		{"顾客无权模拟", customerToken, customerPath, http.StatusForbidden},
+		{"无效的用户ID", adminToken, "abc", http.StatusBadRequest},
+		{"用户不存在", adminToken, "99999", http.StatusNotFound},
+	}
+	for _, tt := range denied {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := impersonate(tt.token, tt.id); w.Code != tt.wantStatus {
+				t.Errorf("HandleImpersonateUser() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+
+	// 管理员模拟顾客
+	w := impersonate(adminToken, customerPath)
+	if w.Code != http.StatusOK {
+		t.Fatalf("HandleImpersonateUser() status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Token          string    `json:"token"`
+		Impersonated   bool      `json:"impersonated"`
+		ImpersonatorID int       `json:"impersonator_id"`
+		ExpiresAt      time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if !resp.Impersonated || resp.Token == "" || resp.ImpersonatorID == 0 {
+		t.Fatalf("response = %+v, want flagged impersonation session", resp)
+	}
+	if resp.ExpiresAt.After(time.Now().Add(auth.DefaultImpersonationTTL)) {
+		t.Errorf("expires_at = %v, want within %v", resp.ExpiresAt, auth.DefaultImpersonationTTL)
+	}
+
+	// 代客会话可以看到顾客的订单
+	req := httptest.NewRequest("GET", "/api/orders", nil)
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: resp.Token})
+	w = httptest.NewRecorder()
+	routeRequest(h, w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("HandleListOrders() status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var orders []struct {
+		OrderNo string `json:"order_no"`
+	}
+	if err := unmarshalListData(w.Body.Bytes(), &orders); err != nil {
+		t.Fatalf("failed to parse orders: %v", err)
+	}
+	if len(orders) != 1 || orders[0].OrderNo != orderNo {
+		t.Errorf("HandleListOrders() = %+v, want order %s", orders, orderNo)
+	}
+
+	// 代客会话不能执行管理员操作
+	if w := impersonate(resp.Token, customerPath); w.Code != http.StatusForbidden {
+		t.Errorf("impersonation session calling admin endpoint status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	sessionRequest := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: CookieName, Value: resp.Token})
+		w := httptest.NewRecorder()
+		routeRequest(h, w, req)
+		return w
+	}
+
+	// GET /me 标明当前为代客登录
+	w = sessionRequest("GET", "/api/me", "")
+	var me struct {
+		ID             int `json:"id"`
+		ImpersonatorID int `json:"impersonator_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &me); err != nil {
+		t.Fatalf("failed to parse /me: %v", err)
+	}
+	if me.ID != customerID || me.ImpersonatorID != resp.ImpersonatorID {
+		t.Errorf("GET /me = %+v, want user %d impersonated by %d", me, customerID, resp.ImpersonatorID)
+	}
+
+	// 代客会话不能修改资料、邮箱与密码
+	accountChanges := []struct {
+		method, path, body string
+	}{
+		{"PATCH", "/api/me", `{"email":"attacker@example.com"}`},
+		{"POST", "/api/me/password", `{"old_password":"password123","new_password":"newpassword123"}`},
+	}
+	for _, tt := range accountChanges {
+		if w := sessionRequest(tt.method, tt.path, tt.body); w.Code != http.StatusForbidden {
+			t.Errorf("%s %s with impersonation session status = %d, want %d", tt.method, tt.path, w.Code, http.StatusForbidden)
+		}
+	}
+	var email sql.NullString
+	if err := db.QueryRow("SELECT email FROM users WHERE id = ?", customerID).Scan(&email); err != nil {
+		t.Fatalf("failed to query email: %v", err)
+	}
+	if email.String != "" {
+		t.Errorf("email = %q, want unchanged", email.String)
+	}
+
+	// 代客会话中的订单操作记录实际操作的管理员
+	body := `{"address_id":` + strconv.Itoa(addressID) + `,"items":[{"flower_sku":"FLW001","quantity":1}]}`
+	if w := sessionRequest("POST", "/api/orders", body); w.Code != http.StatusCreated {
+		t.Fatalf("create order with impersonation session status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var operatorID, impersonatorID int
+	if err := db.QueryRow("SELECT operator_id, impersonator_id FROM order_logs WHERE action = 'create_order' ORDER BY id DESC LIMIT 1").Scan(&operatorID, &impersonatorID); err != nil {
+		t.Fatalf("failed to query order log: %v", err)
+	}
+	if operatorID != customerID || impersonatorID != resp.ImpersonatorID {
+		t.Errorf("order log = (operator %d, impersonator %d), want (operator %d, impersonator %d)", operatorID, impersonatorID, customerID, resp.ImpersonatorID)
+	}
+
+	// 审计记录
+	var adminID, targetID int
+	if err := db.QueryRow("SELECT admin_id, target_user_id FROM impersonation_logs").Scan(&adminID, &targetID); err != nil {
+		t.Fatalf("failed to query audit log: %v", err)
+	}
+	if adminID != resp.ImpersonatorID || targetID != customerID {
+		t.Errorf("audit log = (admin %d, target %d), want (admin %d, target %d)", adminID, targetID, resp.ImpersonatorID, customerID)
+	}
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// HandleCleanupOrphanOrderItems 处理孤立订单项检测与清理（仅管理员）
+// POST /api/admin/maintenance/orphan-order-items?dry_run=false
+// 默认 dry_run=true，只报告不删除
+func (h *Handler) HandleCleanupOrphanOrderItems(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	dryRun := true
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "dry_run must be true or false")
+			return
+		}
+	}
+
+	result, err := h.maintenanceService.CleanupOrphanOrderItems(r.Context(), dryRun)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, result)
+}
+
+// HandleOptimizeDatabase 处理数据库维护请求（仅管理员）
+// POST /api/admin/maintenance/optimize
+// 按数据库类型执行 ANALYZE/OPTIMIZE 或 VACUUM/ANALYZE，返回各语句耗时；同一时间只允许一个任务
+func (h *Handler) HandleOptimizeDatabase(w http.ResponseWriter, r *http.Request) {
+	result, err := h.maintenanceService.OptimizeDatabase(r.Context())
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, result)
+}
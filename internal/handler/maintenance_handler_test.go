@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/maintenance"
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+)
+
+// TestHandleCleanupOrphanOrderItems 测试孤立订单项清理接口
+func TestHandleCleanupOrphanOrderItems(t *testing.T) {
+	ctx, db := setupUserTestHandler(t)
+	handler := ctx.handler
+
+	createTablesSQL := `
+	CREATE TABLE IF NOT EXISTS orders (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_no TEXT UNIQUE NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS order_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_id INTEGER NOT NULL,
+		flower_sku TEXT NOT NULL,
+		flower_name TEXT NOT NULL,
+		quantity INTEGER NOT NULL
+	);
+	INSERT INTO order_items (order_id, flower_sku, flower_name, quantity) VALUES (999, 'FLW001', '红玫瑰', 1);
+	`
+	if _, err := db.Exec(createTablesSQL); err != nil {
+		t.Fatalf("failed to prepare tables: %v", err)
+	}
+	handler.maintenanceService = maintenance.NewMaintenanceService(maintenance.NewMaintenanceRepository(db))
+
+	_, adminSession := createTestUserWithSession(t, ctx, "admin", user.RoleAdmin)
+	_, clerkSession := createTestUserWithSession(t, ctx, "clerk", user.RoleClerk)
+
+	tests := []struct {
+		name        string
+		session     string
+		query       string
+		wantStatus  int
+		wantDeleted int
+	}{
+		{name: "未登录", session: "", wantStatus: http.StatusUnauthorized},
+		{name: "店员无权限", session: clerkSession, wantStatus: http.StatusForbidden},
+		{name: "非法 dry_run", session: adminSession, query: "?dry_run=nope", wantStatus: http.StatusBadRequest},
+		{name: "默认 dry-run", session: adminSession, wantStatus: http.StatusOK, wantDeleted: 0},
+		{name: "实际删除", session: adminSession, query: "?dry_run=false", wantStatus: http.StatusOK, wantDeleted: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/admin/maintenance/orphan-order-items"+tt.query, nil)
+			if tt.session != "" {
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: tt.session})
+			}
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleCleanupOrphanOrderItems() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var result maintenance.OrphanCleanupResult
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if result.Found != 1 || result.Deleted != tt.wantDeleted {
+				t.Errorf("result = (found %d, deleted %d), want (1, %d)", result.Found, result.Deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+// TestHandleOptimizeDatabase 测试数据库维护接口（SQLite 执行 VACUUM/ANALYZE）
+func TestHandleOptimizeDatabase(t *testing.T) {
+	ctx, db := setupUserTestHandler(t)
+	handler := ctx.handler
+	handler.maintenanceService = maintenance.NewMaintenanceService(maintenance.NewMaintenanceRepository(db))
+
+	_, adminSession := createTestUserWithSession(t, ctx, "admin", user.RoleAdmin)
+	_, clerkSession := createTestUserWithSession(t, ctx, "clerk", user.RoleClerk)
+
+	tests := []struct {
+		name       string
+		session    string
+		wantStatus int
+	}{
+		{name: "未登录", session: "", wantStatus: http.StatusUnauthorized},
+		{name: "店员无权限", session: clerkSession, wantStatus: http.StatusForbidden},
+		{name: "管理员执行", session: adminSession, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/admin/maintenance/optimize", nil)
+			if tt.session != "" {
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: tt.session})
+			}
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleOptimizeDatabase() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var result maintenance.OptimizeResult
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if result.Dialect != maintenance.DialectSQLite || len(result.Steps) != 2 {
+				t.Errorf("result = %+v, want sqlite with 2 steps", result)
+			}
+		})
+	}
+}
@@ -1,15 +1,24 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/biqiangwu/flowerSalesSystem/internal/order"
+	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
 )
 
+// IdempotencyKeyHeader 下单请求的幂等键请求头
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength 幂等键最大长度
+const maxIdempotencyKeyLength = 255
+
 // HandleCreateOrder 处理创建订单
 func (h *Handler) HandleCreateOrder(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -18,11 +27,12 @@ func (h *Handler) HandleCreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 验证用户身份
-	userID, ok := h.authenticateRequest(r)
+	u, ok := middleware.UserFromContext(r.Context())
 	if !ok {
 		h.respondError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
+	userID := u.ID
 
 	// 解析请求
 	var req CreateOrderRequest
@@ -31,22 +41,39 @@ func (h *Handler) HandleCreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 转换为服务层请求
-	serviceReq := &order.CreateOrderRequest{
-		AddressID: req.AddressID,
-		Items:     make([]*order.CreateOrderItemRequest, len(req.Items)),
-	}
-	for i, item := range req.Items {
-		serviceReq.Items[i] = &order.CreateOrderItemRequest{
-			FlowerSKU: item.FlowerSKU,
-			Quantity:  item.Quantity,
+	// 携带幂等键时，同一用户重复提交返回首次创建的订单号，不再重复下单
+	idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+	if idempotencyKey != "" && h.orderIdempotency != nil {
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			h.respondError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+			return
+		}
+		orderNo, done, err := h.orderIdempotency.Reserve(userID, idempotencyKey)
+		if err != nil {
+			h.respondServiceError(w, r, err)
+			return
+		}
+		if done {
+			h.respondJSON(w, http.StatusOK, map[string]interface{}{
+				"message":  "order already created",
+				"order_no": orderNo,
+			})
+			return
 		}
 	}
 
-	ctx := context.Background()
-	orderNo, err := h.orderService.CreateOrder(ctx, userID, serviceReq)
+	ctx := r.Context()
+	orderNo, err := h.orderService.CreateOrder(ctx, userID, req.toServiceRequest())
+	if idempotencyKey != "" && h.orderIdempotency != nil {
+		// 下单失败时释放幂等键，允许客户端使用同一键重试
+		if err != nil {
+			h.orderIdempotency.Release(userID, idempotencyKey)
+		} else {
+			h.orderIdempotency.Complete(userID, idempotencyKey, orderNo)
+		}
+	}
 	if err != nil {
-		h.respondError(w, http.StatusBadRequest, err.Error())
+		h.respondServiceError(w, r, err)
 		return
 	}
 
@@ -56,6 +83,23 @@ func (h *Handler) HandleCreateOrder(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// toServiceRequest 转换为服务层的创建订单请求
+func (req *CreateOrderRequest) toServiceRequest() *order.CreateOrderRequest {
+	serviceReq := &order.CreateOrderRequest{
+		AddressID:    req.AddressID,
+		Items:        make([]*order.CreateOrderItemRequest, len(req.Items)),
+		DiscountCode: req.DiscountCode,
+	}
+	for i, item := range req.Items {
+		serviceReq.Items[i] = &order.CreateOrderItemRequest{
+			FlowerSKU:  item.FlowerSKU,
+			FlowerName: item.FlowerName,
+			Quantity:   item.Quantity,
+		}
+	}
+	return serviceReq
+}
+
 // HandleGetOrder 处理获取订单详情
 func (h *Handler) HandleGetOrder(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -64,31 +108,72 @@ func (h *Handler) HandleGetOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 验证用户身份
-	userID, ok := h.authenticateRequest(r)
+	u, ok := middleware.UserFromContext(r.Context())
 	if !ok {
 		h.respondError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
+	userID := u.ID
 
 	// 从 URL 获取订单号
-	orderNo := extractOrderNo(r.URL.Path)
+	orderNo := r.PathValue("orderNo")
 	if orderNo == "" {
 		h.respondError(w, http.StatusBadRequest, "invalid order number")
 		return
 	}
 
-	ctx := context.Background()
-	orderResp, err := h.orderService.GetOrder(ctx, userID, orderNo)
-	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			h.respondError(w, http.StatusNotFound, "order not found")
+	// enrich=true 时附带订单项鲜花的当前在售状态与库存，默认不查询
+	enrich := false
+	if v := r.URL.Query().Get("enrich"); v != "" {
+		var err error
+		if enrich, err = strconv.ParseBool(v); err != nil {
+			h.respondError(w, http.StatusBadRequest, "enrich must be true or false")
 			return
 		}
-		if strings.Contains(err.Error(), "无权") {
-			h.respondError(w, http.StatusForbidden, "access denied")
+	}
+
+	ctx := r.Context()
+	orderResp, err := h.orderService.GetOrder(ctx, userID, orderNo)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	if enrich {
+		if err := h.orderService.EnrichOrderItems(ctx, orderResp); err != nil {
+			h.respondServiceError(w, r, err)
 			return
 		}
-		h.respondError(w, http.StatusBadRequest, err.Error())
+	}
+
+	h.respondJSON(w, http.StatusOK, orderResp)
+}
+
+// HandleGetOrderByID 处理按订单ID获取订单详情，供店员和管理员查看顾客订单
+func (h *Handler) HandleGetOrderByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// 验证用户身份，角色由服务层用于权限判断
+	operator, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	// 从 URL 获取订单ID
+	orderID, err := pathIDParam(r, "id")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid order id")
+		return
+	}
+
+	ctx := r.Context()
+	orderResp, err := h.orderService.GetOrderByID(ctx, operator.ID, operator.Role, orderID)
+	if err != nil {
+		h.respondServiceError(w, r, err)
 		return
 	}
 
@@ -103,15 +188,17 @@ func (h *Handler) HandleListOrders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 验证用户身份
-	userID, ok := h.authenticateRequest(r)
+	u, ok := middleware.UserFromContext(r.Context())
 	if !ok {
 		h.respondError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
+	userID := u.ID
 
 	// 解析查询参数
 	filter := order.OrderListFilter{
 		Status: r.URL.Query().Get("status"),
+		SortBy: r.URL.Query().Get("sort"),
 	}
 
 	if page := r.URL.Query().Get("page"); page != "" {
@@ -132,52 +219,166 @@ func (h *Handler) HandleListOrders(w http.ResponseWriter, r *http.Request) {
 		filter.PageSize = 10
 	}
 
-	ctx := context.Background()
-	orders, err := h.orderService.ListOrders(ctx, userID, filter)
+	// 默认返回订单项，include_items=false 时返回不含订单项的精简列表
+	filter.IncludeItems = true
+	if includeItems := r.URL.Query().Get("include_items"); includeItems != "" {
+		v, err := strconv.ParseBool(includeItems)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "include_items must be true or false")
+			return
+		}
+		filter.IncludeItems = v
+	}
+
+	start, end, err := parseOrderTimeRange(r)
 	if err != nil {
 		h.respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	filter.StartTime, filter.EndTime = start, end
 
-	h.respondJSON(w, http.StatusOK, orders)
-}
+	withTotal, err := parseWithTotal(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	arrayFormat, err := parseArrayFormat(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-// authenticateRequest 验证请求并返回用户 ID
-func (h *Handler) authenticateRequest(r *http.Request) (int, bool) {
-	cookie, err := r.Cookie(CookieName)
+	ctx := r.Context()
+	orders, hasMore, err := h.orderService.ListOrdersWithMore(ctx, userID, filter)
 	if err != nil {
-		return 0, false
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	// 分页响应总是包含总数；旧版数组格式仅在 with_total=true 时统计
+	total := 0
+	if withTotal || !arrayFormat {
+		total, err = h.orderService.CountOrders(ctx, userID, filter)
+		if err != nil {
+			h.respondServiceError(w, r, err)
+			return
+		}
+	}
+	if withTotal {
+		w.Header().Set(TotalCountHeader, strconv.Itoa(total))
+	}
+	w.Header().Set(HasMoreHeader, strconv.FormatBool(hasMore))
+
+	h.respondList(w, arrayFormat, orders, orders, total, filter.Page, filter.PageSize)
+}
+
+// parseOrderTimeRange 解析订单列表的 start/end 查询参数，支持 RFC3339 或 YYYY-MM-DD（UTC）
+// 仅给出日期时，end 包含当天全天；结束时间早于开始时间返回错误
+func parseOrderTimeRange(r *http.Request) (time.Time, time.Time, error) {
+	var start, end time.Time
+	if v := r.URL.Query().Get("start"); v != "" {
+		t, _, err := parseOrderTime(v)
+		if err != nil {
+			return start, end, fmt.Errorf("invalid start: 时间格式应为 RFC3339 或 YYYY-MM-DD")
+		}
+		start = t
+	}
+	if v := r.URL.Query().Get("end"); v != "" {
+		t, dateOnly, err := parseOrderTime(v)
+		if err != nil {
+			return start, end, fmt.Errorf("invalid end: 时间格式应为 RFC3339 或 YYYY-MM-DD")
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		end = t
+	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return start, end, fmt.Errorf("end must not be before start")
+	}
+	return start, end, nil
+}
+
+// parseOrderTime 解析 RFC3339 时间或 YYYY-MM-DD 日期，dateOnly 表示输入只包含日期
+func parseOrderTime(v string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.Parse(time.RFC3339, v); err == nil {
+		return t, false, nil
+	}
+	if t, err = time.Parse(time.DateOnly, v); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, err
+}
+
+// HandleOrderSummary 按状态统计本人的订单数量
+// GET /api/me/orders/summary，返回 {"counts": {"pending": 1, ...}}
+func (h *Handler) HandleOrderSummary(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
 	}
 
-	ctx := context.Background()
-	u, err := h.authService.ValidateSession(ctx, cookie.Value)
+	counts, err := h.orderService.CountOrdersByStatus(r.Context(), u.ID)
 	if err != nil {
-		return 0, false
+		h.respondServiceError(w, r, err)
+		return
 	}
 
-	return u.ID, true
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"counts": counts,
+	})
 }
 
-// extractOrderNo 从 URL 路径中提取订单号
-func extractOrderNo(path string) string {
-	// 路径格式: /api/orders/{order_no}
-	parts := strings.Split(strings.Trim(path, "/"), "/")
-	if len(parts) >= 3 && parts[0] == "api" && parts[1] == "orders" {
-		return parts[2]
+// HandleBulkCancelOwnOrders 批量取消本人的待处理订单
+// POST /api/me/orders/bulk-cancel，返回每个订单的处理结果（cancelled/skipped/failed）
+func (h *Handler) HandleBulkCancelOwnOrders(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	userID := u.ID
+
+	var req BulkCancelOrdersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	results, err := h.orderService.BulkCancelOwn(r.Context(), userID, req.OrderIDs)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
 	}
-	return ""
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"results": results,
+	})
 }
 
-// extractOrderID 从 URL 路径中提取订单ID
-// 路径格式: /api/orders/{order_id}/complete 或 /api/orders/{order_id}/cancel
-func extractOrderID(path string) int {
-	parts := strings.Split(strings.Trim(path, "/"), "/")
-	if len(parts) >= 3 && parts[0] == "api" && parts[1] == "orders" {
-		if id, err := strconv.Atoi(parts[2]); err == nil {
-			return id
-		}
+// HandleShipOrder 处理订单发货（仅管理员和店员）
+func (h *Handler) HandleShipOrder(w http.ResponseWriter, r *http.Request) {
+	operator, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	orderID, err := pathIDParam(r, "id")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid order id")
+		return
 	}
-	return 0
+
+	if err := h.orderService.ShipOrder(r.Context(), orderID, operator.ID); err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "order shipped successfully",
+	})
 }
 
 // HandleCompleteOrder 处理完成订单
@@ -187,32 +388,24 @@ func (h *Handler) HandleCompleteOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 验证用户身份
-	userID, ok := h.authenticateRequest(r)
+	// 验证用户身份，角色由服务层用于权限判断
+	operator, ok := middleware.UserFromContext(r.Context())
 	if !ok {
 		h.respondError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	// 从 URL 获取订单ID
-	orderID := extractOrderID(r.URL.Path)
-	if orderID <= 0 {
+	orderID, err := pathIDParam(r, "id")
+	if err != nil {
 		h.respondError(w, http.StatusBadRequest, "invalid order id")
 		return
 	}
 
-	ctx := context.Background()
-	err := h.orderService.CompleteOrder(ctx, orderID, userID)
+	ctx := r.Context()
+	err = h.orderService.CompleteOrder(ctx, orderID, operator.ID, operator.Role)
 	if err != nil {
-		if strings.Contains(err.Error(), "不存在") {
-			h.respondError(w, http.StatusNotFound, "order not found")
-			return
-		}
-		if strings.Contains(err.Error(), "状态") {
-			h.respondError(w, http.StatusBadRequest, err.Error())
-			return
-		}
-		h.respondError(w, http.StatusBadRequest, err.Error())
+		h.respondServiceError(w, r, err)
 		return
 	}
 
@@ -221,39 +414,38 @@ func (h *Handler) HandleCompleteOrder(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// HandleCancelOrder 处理取消订单
+// HandleCancelOrder 处理取消订单，可选的请求体 {"reason": "..."} 记录取消原因
 func (h *Handler) HandleCancelOrder(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		h.respondError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	// 验证用户身份
-	userID, ok := h.authenticateRequest(r)
+	// 验证用户身份，角色由服务层用于权限判断
+	operator, ok := middleware.UserFromContext(r.Context())
 	if !ok {
 		h.respondError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	// 从 URL 获取订单ID
-	orderID := extractOrderID(r.URL.Path)
-	if orderID <= 0 {
+	orderID, err := pathIDParam(r, "id")
+	if err != nil {
 		h.respondError(w, http.StatusBadRequest, "invalid order id")
 		return
 	}
 
-	ctx := context.Background()
-	err := h.orderService.CancelOrder(ctx, orderID, userID)
+	// 请求体可选，可携带取消原因
+	var req CancelOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	ctx := r.Context()
+	err = h.orderService.CancelOrder(ctx, orderID, operator.ID, operator.Role, req.Reason)
 	if err != nil {
-		if strings.Contains(err.Error(), "不存在") {
-			h.respondError(w, http.StatusNotFound, "order not found")
-			return
-		}
-		if strings.Contains(err.Error(), "状态") {
-			h.respondError(w, http.StatusBadRequest, err.Error())
-			return
-		}
-		h.respondError(w, http.StatusBadRequest, err.Error())
+		h.respondServiceError(w, r, err)
 		return
 	}
 
@@ -261,3 +453,96 @@ func (h *Handler) HandleCancelOrder(w http.ResponseWriter, r *http.Request) {
 		"message": "order cancelled successfully",
 	})
 }
+
+// HandleCancelOrderItem 取消本人待处理订单中的单个订单项，回退库存并重新计算总额；最后一项被取消时整单取消
+// DELETE /api/orders/{id}/items/{itemID}
+func (h *Handler) HandleCancelOrderItem(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	orderID, err := pathIDParam(r, "id")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid order id")
+		return
+	}
+	itemID, err := pathIDParam(r, "itemID")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid order item id")
+		return
+	}
+
+	if err := h.orderService.CancelOrderItem(r.Context(), orderID, itemID, u.ID); err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "order item cancelled successfully",
+	})
+}
+
+// HandleUpdateOrderItem 修改待处理订单中订单项的数量（管理员和店员），按差额调整库存并重新计算总额
+// PATCH /api/orders/{id}/items/{itemID}
+func (h *Handler) HandleUpdateOrderItem(w http.ResponseWriter, r *http.Request) {
+	operator, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	orderID, err := pathIDParam(r, "id")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid order id")
+		return
+	}
+	itemID, err := pathIDParam(r, "itemID")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid order item id")
+		return
+	}
+
+	var req UpdateOrderItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := h.orderService.UpdateOrderItemQuantity(r.Context(), orderID, itemID, req.Quantity, operator.ID, operator.Role); err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "order item updated successfully",
+	})
+}
+
+// HandleReorder 按历史订单再次下单，只能对本人订单操作，返回新订单号
+// POST /api/orders/{id}/reorder
+func (h *Handler) HandleReorder(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	orderID, err := pathIDParam(r, "id")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid order id")
+		return
+	}
+
+	orderNo, err := h.orderService.Reorder(r.Context(), u.ID, orderID)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"message":  "order created successfully",
+		"order_no": orderNo,
+	})
+}
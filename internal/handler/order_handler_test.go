@@ -5,12 +5,15 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/biqiangwu/flowerSalesSystem/internal/address"
 	"github.com/biqiangwu/flowerSalesSystem/internal/auth"
+	"github.com/biqiangwu/flowerSalesSystem/internal/cart"
 	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
 	"github.com/biqiangwu/flowerSalesSystem/internal/order"
 	"github.com/biqiangwu/flowerSalesSystem/internal/user"
@@ -31,6 +34,7 @@ func setupOrderTestDB(t *testing.T) *sql.DB {
 		CREATE TABLE IF NOT EXISTS users (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			username TEXT UNIQUE NOT NULL,
+			email TEXT UNIQUE,
 			password_hash TEXT NOT NULL,
 			role TEXT NOT NULL,
 			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -63,6 +67,9 @@ func setupOrderTestDB(t *testing.T) *sql.DB {
 			purchase_price INTEGER NOT NULL,
 			sale_price INTEGER NOT NULL,
 			stock INTEGER NOT NULL DEFAULT 0,
+			max_order_qty INTEGER,
+			discount_price INTEGER,
+			discount_until DATETIME,
 			is_active INTEGER NOT NULL DEFAULT 1,
 			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
@@ -77,7 +84,11 @@ func setupOrderTestDB(t *testing.T) *sql.DB {
 			user_id INTEGER NOT NULL,
 			address_id INTEGER NOT NULL,
 			total_amount INTEGER NOT NULL,
+			discount_id INTEGER,
+			discount_amount INTEGER NOT NULL DEFAULT 0,
 			status TEXT NOT NULL,
+			reserved_at DATETIME,
+			version INTEGER NOT NULL DEFAULT 0,
 			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id),
@@ -105,18 +116,50 @@ func setupOrderTestDB(t *testing.T) *sql.DB {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			order_id INTEGER NOT NULL,
 			operator_id INTEGER NOT NULL,
+			impersonator_id INTEGER,
 			action TEXT NOT NULL,
 			old_status TEXT,
 			new_status TEXT NOT NULL,
+			reason TEXT,
 			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
 			FOREIGN KEY (operator_id) REFERENCES users(id)
 		);
 	`
 
+	// 创建优惠码表
+	createDiscountsTable := `
+		CREATE TABLE IF NOT EXISTS discounts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			code TEXT NOT NULL UNIQUE,
+			type TEXT NOT NULL,
+			value INTEGER NOT NULL,
+			valid_from DATETIME,
+			valid_to DATETIME,
+			usage_limit INTEGER NOT NULL DEFAULT 0,
+			used_count INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`
+
+	// 创建购物车表
+	createCartItemsTable := `
+		CREATE TABLE IF NOT EXISTS cart_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			flower_sku TEXT NOT NULL,
+			quantity INTEGER NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, flower_sku)
+		);
+	`
+
 	tables := []string{
 		createUsersTable, createAddressesTable, createFlowersTable,
 		createOrdersTable, createOrderItemsTable, createOrderLogsTable,
+		createDiscountsTable, createCartItemsTable,
 	}
 
 	for _, tableSQL := range tables {
@@ -153,6 +196,7 @@ func setupOrderTestHandler(t *testing.T) (*Handler, *sql.DB) {
 	handler := &Handler{
 		authService:  authSvc,
 		orderService: orderSvc,
+		cartService:  cart.NewCartService(cart.NewCartRepository(db), flowerRepo, orderSvc),
 	}
 
 	return handler, db
@@ -310,7 +354,7 @@ func TestHandleCreateOrder_Success(t *testing.T) {
 	req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
 	w := httptest.NewRecorder()
 
-	handler.HandleCreateOrder(w, req)
+	routeRequest(handler, w, req)
 
 	if w.Code != http.StatusCreated {
 		t.Errorf("HandleCreateOrder() status = %d, want %d, body = %s", w.Code, http.StatusCreated, w.Body.String())
@@ -327,6 +371,79 @@ func TestHandleCreateOrder_Success(t *testing.T) {
 	}
 }
 
+// TestHandleCreateOrder_IdempotencyKey 测试携带相同 Idempotency-Key 重复下单只创建一个订单
+func TestHandleCreateOrder_IdempotencyKey(t *testing.T) {
+	handler, db := setupOrderTestHandler(t)
+	handler.orderIdempotency = order.NewIdempotencyCache(time.Minute)
+	ctx := context.Background()
+
+	sessionToken := loginUser(t, handler, "testuser", "password123")
+	u, _ := user.NewMySQLUserRepository(db).GetByUsername(ctx, "testuser")
+
+	addr := &address.Address{UserID: u.ID, Label: "家", Address: "北京市朝阳区", Contact: "张三"}
+	if err := address.NewAddressRepository(db).Create(ctx, addr); err != nil {
+		t.Fatalf("failed to create address: %v", err)
+	}
+	if err := flower.NewFlowerRepository(db).Create(ctx, &flower.Flower{
+		SKU: "FLW001", Name: "红玫瑰", Origin: "云南", ShelfLife: "7天", Preservation: "冷藏",
+		PurchasePrice: flower.Decimal{Value: 5000}, SalePrice: flower.Decimal{Value: 10000}, Stock: 100, IsActive: true,
+	}); err != nil {
+		t.Fatalf("failed to create flower: %v", err)
+	}
+
+	createOrder := func(key string, quantity int) (int, string) {
+		body, _ := json.Marshal(CreateOrderRequest{
+			AddressID: addr.ID,
+			Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: quantity}},
+		})
+		req := httptest.NewRequest("POST", "/api/orders", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(IdempotencyKeyHeader, key)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
+		w := httptest.NewRecorder()
+
+		routeRequest(handler, w, req)
+
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		orderNo, _ := resp["order_no"].(string)
+		return w.Code, orderNo
+	}
+	countOrders := func() int {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM orders").Scan(&n); err != nil {
+			t.Fatalf("failed to count orders: %v", err)
+		}
+		return n
+	}
+
+	status, firstNo := createOrder("pay-123", 5)
+	if status != http.StatusCreated || firstNo == "" {
+		t.Fatalf("first request status = %d, order_no = %q, want 201 with order_no", status, firstNo)
+	}
+	status, secondNo := createOrder("pay-123", 5)
+	if status != http.StatusOK || secondNo != firstNo {
+		t.Errorf("repeated request = (%d, %q), want (200, %q)", status, secondNo, firstNo)
+	}
+	if n := countOrders(); n != 1 {
+		t.Errorf("orders after repeated key = %d, want 1", n)
+	}
+	if f, _ := flower.NewFlowerRepository(db).GetBySKU(ctx, "FLW001"); f.Stock != 95 {
+		t.Errorf("stock = %d, want 95 (deducted once)", f.Stock)
+	}
+
+	// 下单失败不占用幂等键，使用同一键重试可以成功
+	if status, _ := createOrder("pay-456", 1000); status != http.StatusBadRequest {
+		t.Errorf("insufficient stock status = %d, want 400", status)
+	}
+	if status, orderNo := createOrder("pay-456", 1); status != http.StatusCreated || orderNo == firstNo {
+		t.Errorf("retry after failure = (%d, %q), want 201 with a new order", status, orderNo)
+	}
+	if n := countOrders(); n != 2 {
+		t.Errorf("orders after retry = %d, want 2", n)
+	}
+}
+
 // TestHandleCreateOrder_Unauthorized 测试未授权创建订单
 func TestHandleCreateOrder_Unauthorized(t *testing.T) {
 	handler, _ := setupOrderTestHandler(t)
@@ -343,7 +460,7 @@ func TestHandleCreateOrder_Unauthorized(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	handler.HandleCreateOrder(w, req)
+	routeRequest(handler, w, req)
 
 	if w.Code != http.StatusUnauthorized {
 		t.Errorf("HandleCreateOrder() unauthorized status = %d, want %d", w.Code, http.StatusUnauthorized)
@@ -367,7 +484,7 @@ func TestHandleCreateOrder_EmptyItems(t *testing.T) {
 	req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
 	w := httptest.NewRecorder()
 
-	handler.HandleCreateOrder(w, req)
+	routeRequest(handler, w, req)
 
 	if w.Code != http.StatusBadRequest {
 		t.Errorf("HandleCreateOrder() empty items status = %d, want %d", w.Code, http.StatusBadRequest)
@@ -385,7 +502,7 @@ func TestHandleCreateOrder_InvalidJSON(t *testing.T) {
 	req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
 	w := httptest.NewRecorder()
 
-	handler.HandleCreateOrder(w, req)
+	routeRequest(handler, w, req)
 
 	if w.Code != http.StatusBadRequest {
 		t.Errorf("HandleCreateOrder() invalid json status = %d, want %d", w.Code, http.StatusBadRequest)
@@ -446,7 +563,7 @@ func TestHandleGetOrder_Success(t *testing.T) {
 	req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
 	w := httptest.NewRecorder()
 
-	handler.HandleGetOrder(w, req)
+	routeRequest(handler, w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("HandleGetOrder() status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body.String())
@@ -463,6 +580,108 @@ func TestHandleGetOrder_Success(t *testing.T) {
 	}
 }
 
+// TestHandleGetOrder_Enrich 测试 enrich=true 时订单项附带鲜花当前状态，已删除的鲜花不可再次购买
+func TestHandleGetOrder_Enrich(t *testing.T) {
+	handler, db := setupOrderTestHandler(t)
+	ctx := context.Background()
+
+	sessionToken := loginUser(t, handler, "testuser", "password123")
+	u, _ := user.NewMySQLUserRepository(db).GetByUsername(ctx, "testuser")
+
+	addr := &address.Address{UserID: u.ID, Label: "家", Address: "北京市朝阳区", Contact: "张三"}
+	if err := address.NewAddressRepository(db).Create(ctx, addr); err != nil {
+		t.Fatalf("failed to create address: %v", err)
+	}
+	flowerRepo := flower.NewFlowerRepository(db)
+	for _, sku := range []string{"FLW001", "FLW002"} {
+		if err := flowerRepo.Create(ctx, &flower.Flower{
+			SKU: sku, Name: "红玫瑰", Origin: "云南", ShelfLife: "7天", Preservation: "冷藏",
+			PurchasePrice: flower.Decimal{Value: 5000}, SalePrice: flower.Decimal{Value: 10000}, Stock: 100, IsActive: true,
+		}); err != nil {
+			t.Fatalf("failed to create flower: %v", err)
+		}
+	}
+
+	orderSvc := order.NewOrderService(order.NewOrderRepository(db), flowerRepo, order.NewOrderLogRepository(db))
+	orderNo, err := orderSvc.CreateOrder(ctx, u.ID, &order.CreateOrderRequest{
+		AddressID: addr.ID,
+		Items: []*order.CreateOrderItemRequest{
+			{FlowerSKU: "FLW001", Quantity: 5},
+			{FlowerSKU: "FLW002", Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	if err := flowerRepo.Delete(ctx, "FLW002"); err != nil {
+		t.Fatalf("failed to delete flower: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantEnrich bool
+	}{
+		{name: "默认不附带当前状态", query: "", wantStatus: http.StatusOK, wantEnrich: false},
+		{name: "enrich=true 附带当前状态", query: "?enrich=true", wantStatus: http.StatusOK, wantEnrich: true},
+		{name: "enrich 参数非法", query: "?enrich=yes-please", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/orders/"+orderNo+tt.query, nil)
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleGetOrder() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Items []struct {
+					FlowerSKU    string `json:"flower_sku"`
+					IsActive     *bool  `json:"is_active"`
+					CurrentStock *int   `json:"current_stock"`
+					Available    *bool  `json:"available"`
+				} `json:"items"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if len(resp.Items) != 2 {
+				t.Fatalf("items = %d, want 2", len(resp.Items))
+			}
+			for _, item := range resp.Items {
+				if !tt.wantEnrich {
+					if item.Available != nil || item.CurrentStock != nil || item.IsActive != nil {
+						t.Errorf("item %s has enriched fields without enrich", item.FlowerSKU)
+					}
+					continue
+				}
+				if item.Available == nil || item.CurrentStock == nil || item.IsActive == nil {
+					t.Fatalf("item %s missing enriched fields", item.FlowerSKU)
+				}
+				switch item.FlowerSKU {
+				case "FLW001":
+					if !*item.Available || !*item.IsActive || *item.CurrentStock != 95 {
+						t.Errorf("FLW001 = (available %v, active %v, stock %d), want (true, true, 95)", *item.Available, *item.IsActive, *item.CurrentStock)
+					}
+				case "FLW002":
+					if *item.Available || *item.IsActive || *item.CurrentStock != 0 {
+						t.Errorf("deleted FLW002 = (available %v, active %v, stock %d), want (false, false, 0)", *item.Available, *item.IsActive, *item.CurrentStock)
+					}
+				}
+			}
+		})
+	}
+}
+
 // TestHandleGetOrder_NotFound 测试获取不存在的订单
 func TestHandleGetOrder_NotFound(t *testing.T) {
 	handler, _ := setupOrderTestHandler(t)
@@ -473,7 +692,7 @@ func TestHandleGetOrder_NotFound(t *testing.T) {
 	req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
 	w := httptest.NewRecorder()
 
-	handler.HandleGetOrder(w, req)
+	routeRequest(handler, w, req)
 
 	if w.Code != http.StatusNotFound {
 		t.Errorf("HandleGetOrder() not found status = %d, want %d", w.Code, http.StatusNotFound)
@@ -509,13 +728,75 @@ func TestHandleGetOrder_Unauthorized(t *testing.T) {
 	req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
 	w := httptest.NewRecorder()
 
-	handler.HandleGetOrder(w, req)
+	routeRequest(handler, w, req)
 
 	if w.Code != http.StatusForbidden {
 		t.Errorf("HandleGetOrder() unauthorized status = %d, want %d", w.Code, http.StatusForbidden)
 	}
 }
 
+// TestHandleGetOrderByID 测试店员按订单ID查看顾客订单，顾客无权使用该接口
+func TestHandleGetOrderByID(t *testing.T) {
+	handler, db := setupOrderTestHandler(t)
+	ctx := context.Background()
+
+	userID, addressID := insertTestData(t, db)
+	orderSvc := order.NewOrderService(order.NewOrderRepository(db), flower.NewFlowerRepository(db), order.NewOrderLogRepository(db))
+	orderNo, err := orderSvc.CreateOrder(ctx, userID, &order.CreateOrderRequest{
+		AddressID: addressID,
+		Items:     []*order.CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 5}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	created, _, err := order.NewOrderRepository(db).GetByOrderNo(ctx, orderNo)
+	if err != nil {
+		t.Fatalf("GetByOrderNo() error = %v", err)
+	}
+
+	clerkSession := loginUser(t, handler, "clerk1", "password123")
+	db.Exec("UPDATE users SET role = ? WHERE username = ?", "clerk", "clerk1")
+	customerSession := loginUser(t, handler, "user2", "password123")
+
+	tests := []struct {
+		name       string
+		session    string
+		orderID    int
+		wantStatus int
+	}{
+		{name: "店员查看顾客订单", session: clerkSession, orderID: created.ID, wantStatus: http.StatusOK},
+		{name: "顾客查看他人订单", session: customerSession, orderID: created.ID, wantStatus: http.StatusForbidden},
+		{name: "订单不存在", session: clerkSession, orderID: 99999, wantStatus: http.StatusNotFound},
+		{name: "未登录", orderID: created.ID, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", fmt.Sprintf("/api/admin/orders/%d", tt.orderID), nil)
+			if tt.session != "" {
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: tt.session})
+			}
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleGetOrderByID() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp["order_no"] != orderNo || resp["user_id"] != float64(userID) {
+				t.Errorf("order = (%v, user %v), want (%s, user %d)", resp["order_no"], resp["user_id"], orderNo, userID)
+			}
+		})
+	}
+}
+
 // TestHandleListOrders_Success 测试成功获取订单列表
 func TestHandleListOrders_Success(t *testing.T) {
 	handler, db := setupOrderTestHandler(t)
@@ -574,7 +855,7 @@ func TestHandleListOrders_Success(t *testing.T) {
 	req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
 	w := httptest.NewRecorder()
 
-	handler.HandleListOrders(w, req)
+	routeRequest(handler, w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("HandleListOrders() status = %d, want %d", w.Code, http.StatusOK)
@@ -582,7 +863,7 @@ func TestHandleListOrders_Success(t *testing.T) {
 
 	// 验证响应
 	var resp []map[string]interface{}
-	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+	if err := unmarshalListData(w.Body.Bytes(), &resp); err != nil {
 		t.Fatalf("failed to parse response: %v", err)
 	}
 
@@ -598,9 +879,416 @@ func TestHandleListOrders_Unauthorized(t *testing.T) {
 	req := httptest.NewRequest("GET", "/api/orders", nil)
 	w := httptest.NewRecorder()
 
-	handler.HandleListOrders(w, req)
+	routeRequest(handler, w, req)
 
 	if w.Code != http.StatusUnauthorized {
 		t.Errorf("HandleListOrders() unauthorized status = %d, want %d", w.Code, http.StatusUnauthorized)
 	}
 }
+
+// TestHandleListOrders_IncludeItems 测试 include_items 参数
+func TestHandleListOrders_IncludeItems(t *testing.T) {
+	handler, db := setupOrderTestHandler(t)
+	ctx := context.Background()
+
+	_, addressID := insertTestData(t, db)
+	sessionToken := loginUser(t, handler, "listuser", "password123")
+	u, err := user.NewMySQLUserRepository(db).GetByUsername(ctx, "listuser")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+
+	_, err = handler.orderService.CreateOrder(ctx, u.ID, &order.CreateOrderRequest{
+		AddressID: addressID,
+		Items:     []*order.CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantItems  bool
+	}{
+		{name: "默认包含订单项", query: "", wantStatus: http.StatusOK, wantItems: true},
+		{name: "显式包含订单项", query: "?include_items=true", wantStatus: http.StatusOK, wantItems: true},
+		{name: "不包含订单项", query: "?include_items=false", wantStatus: http.StatusOK, wantItems: false},
+		{name: "非法参数", query: "?include_items=maybe", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/orders"+tt.query, nil)
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleListOrders() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp []map[string]interface{}
+			if err := unmarshalListData(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if len(resp) != 1 {
+				t.Fatalf("HandleListOrders() count = %d, want 1", len(resp))
+			}
+			_, hasItems := resp[0]["items"]
+			if hasItems != tt.wantItems {
+				t.Errorf("HandleListOrders() has items = %v, want %v", hasItems, tt.wantItems)
+			}
+		})
+	}
+}
+
+// TestHandleListOrders_Sort 测试订单列表 sort 参数
+func TestHandleListOrders_Sort(t *testing.T) {
+	handler, db := setupOrderTestHandler(t)
+	ctx := context.Background()
+
+	_, addressID := insertTestData(t, db)
+	sessionToken := loginUser(t, handler, "sortuser", "password123")
+	u, err := user.NewMySQLUserRepository(db).GetByUsername(ctx, "sortuser")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+
+	for _, quantity := range []int{2, 1, 3} {
+		_, err := handler.orderService.CreateOrder(ctx, u.ID, &order.CreateOrderRequest{
+			AddressID: addressID,
+			Items:     []*order.CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: quantity}},
+		})
+		if err != nil {
+			t.Fatalf("failed to create order: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantFirst  float64
+	}{
+		{name: "按金额升序", query: "?sort=total_amount_asc", wantStatus: http.StatusOK, wantFirst: 10000},
+		{name: "按金额降序", query: "?sort=total_amount_desc", wantStatus: http.StatusOK, wantFirst: 30000},
+		{name: "非法排序", query: "?sort=total_amount%20DESC", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/orders"+tt.query, nil)
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleListOrders() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp []map[string]interface{}
+			if err := unmarshalListData(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if len(resp) != 3 || resp[0]["total_amount_cents"] != tt.wantFirst {
+				t.Errorf("HandleListOrders() first total = %v, want %v", resp[0]["total_amount_cents"], tt.wantFirst)
+			}
+		})
+	}
+}
+
+// TestHandleListOrders_WithTotal 测试订单列表 with_total 参数
+func TestHandleListOrders_WithTotal(t *testing.T) {
+	handler, db := setupOrderTestHandler(t)
+	ctx := context.Background()
+
+	_, addressID := insertTestData(t, db)
+	sessionToken := loginUser(t, handler, "totaluser", "password123")
+	u, err := user.NewMySQLUserRepository(db).GetByUsername(ctx, "totaluser")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := handler.orderService.CreateOrder(ctx, u.ID, &order.CreateOrderRequest{
+			AddressID: addressID,
+			Items:     []*order.CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+		})
+		if err != nil {
+			t.Fatalf("failed to create order: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantTotal string
+		wantCount int
+	}{
+		{name: "默认不返回总数", query: "?page=1&page_size=2", wantTotal: "", wantCount: 2},
+		{name: "返回总数", query: "?page=1&page_size=2&with_total=true", wantTotal: "3", wantCount: 2},
+		{name: "第二页总数不变", query: "?page=2&page_size=2&with_total=true", wantTotal: "3", wantCount: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/orders"+tt.query, nil)
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("HandleListOrders() status = %d, body = %s", w.Code, w.Body.String())
+			}
+			if got := w.Header().Get(TotalCountHeader); got != tt.wantTotal {
+				t.Errorf("%s = %q, want %q", TotalCountHeader, got, tt.wantTotal)
+			}
+
+			var resp []map[string]interface{}
+			if err := unmarshalListData(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if len(resp) != tt.wantCount {
+				t.Errorf("HandleListOrders() count = %d, want %d", len(resp), tt.wantCount)
+			}
+		})
+	}
+}
+
+// TestHandleListOrders_Envelope 测试订单列表分页响应中的总数与总页数，以及 format=array 旧版格式
+func TestHandleListOrders_Envelope(t *testing.T) {
+	handler, db := setupOrderTestHandler(t)
+	ctx := context.Background()
+
+	_, addressID := insertTestData(t, db)
+	sessionToken := loginUser(t, handler, "pageuser", "password123")
+	u, err := user.NewMySQLUserRepository(db).GetByUsername(ctx, "pageuser")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := handler.orderService.CreateOrder(ctx, u.ID, &order.CreateOrderRequest{
+			AddressID: addressID,
+			Items:     []*order.CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+		})
+		if err != nil {
+			t.Fatalf("failed to create order: %v", err)
+		}
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/orders"+query, nil)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
+		w := httptest.NewRecorder()
+		routeRequest(handler, w, req)
+		return w
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		wantCount      int
+		wantPage       int
+		wantPageSize   int
+		wantTotalPages int
+	}{
+		{name: "第一页", query: "?page=1&page_size=2", wantCount: 2, wantPage: 1, wantPageSize: 2, wantTotalPages: 2},
+		{name: "第二页", query: "?page=2&page_size=2", wantCount: 1, wantPage: 2, wantPageSize: 2, wantTotalPages: 2},
+		{name: "超出范围", query: "?page=5&page_size=2", wantCount: 0, wantPage: 5, wantPageSize: 2, wantTotalPages: 2},
+		{name: "默认分页", query: "", wantCount: 3, wantPage: 1, wantPageSize: 10, wantTotalPages: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(tt.query)
+			if w.Code != http.StatusOK {
+				t.Fatalf("HandleListOrders() status = %d, body = %s", w.Code, w.Body.String())
+			}
+
+			var resp struct {
+				Data       []map[string]interface{} `json:"data"`
+				Total      int                      `json:"total"`
+				Page       int                      `json:"page"`
+				PageSize   int                      `json:"page_size"`
+				TotalPages int                      `json:"total_pages"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if len(resp.Data) != tt.wantCount {
+				t.Errorf("data count = %d, want %d", len(resp.Data), tt.wantCount)
+			}
+			if resp.Total != 3 {
+				t.Errorf("total = %d, want 3", resp.Total)
+			}
+			if resp.Page != tt.wantPage || resp.PageSize != tt.wantPageSize || resp.TotalPages != tt.wantTotalPages {
+				t.Errorf("page = %d, page_size = %d, total_pages = %d, want %d, %d, %d",
+					resp.Page, resp.PageSize, resp.TotalPages, tt.wantPage, tt.wantPageSize, tt.wantTotalPages)
+			}
+		})
+	}
+
+	t.Run("format=array 返回旧版数组", func(t *testing.T) {
+		w := get("?page=1&page_size=2&format=array")
+		if w.Code != http.StatusOK {
+			t.Fatalf("HandleListOrders() status = %d, body = %s", w.Code, w.Body.String())
+		}
+		var resp []map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if len(resp) != 2 {
+			t.Errorf("HandleListOrders() count = %d, want 2", len(resp))
+		}
+	})
+
+	t.Run("非法 format", func(t *testing.T) {
+		if w := get("?format=csv"); w.Code != http.StatusBadRequest {
+			t.Errorf("HandleListOrders() status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+// TestHandleListOrders_TimeRange 测试按 start/end 筛选订单列表
+func TestHandleListOrders_TimeRange(t *testing.T) {
+	handler, db := setupOrderTestHandler(t)
+	ctx := context.Background()
+
+	_, addressID := insertTestData(t, db)
+	sessionToken := loginUser(t, handler, "rangeuser", "password123")
+	u, err := user.NewMySQLUserRepository(db).GetByUsername(ctx, "rangeuser")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+
+	for _, at := range []time.Time{
+		time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 2, 23, 30, 0, 0, time.UTC),
+		time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC),
+	} {
+		orderNo, err := handler.orderService.CreateOrder(ctx, u.ID, &order.CreateOrderRequest{
+			AddressID: addressID,
+			Items:     []*order.CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+		})
+		if err != nil {
+			t.Fatalf("failed to create order: %v", err)
+		}
+		if _, err := db.Exec("UPDATE orders SET created_at = ? WHERE order_no = ?", at, orderNo); err != nil {
+			t.Fatalf("failed to set created_at: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantTotal  int
+	}{
+		{name: "不限", query: "", wantStatus: http.StatusOK, wantTotal: 4},
+		{name: "按日期包含全天", query: "?start=2026-03-02&end=2026-03-02", wantStatus: http.StatusOK, wantTotal: 2},
+		{name: "仅开始日期", query: "?start=2026-03-02", wantStatus: http.StatusOK, wantTotal: 3},
+		{name: "RFC3339", query: "?start=2026-03-02T09:00:00Z&end=2026-03-03T00:00:00Z", wantStatus: http.StatusOK, wantTotal: 3},
+		{name: "结束早于开始", query: "?start=2026-03-03&end=2026-03-01", wantStatus: http.StatusBadRequest},
+		{name: "格式错误", query: "?start=03/01/2026", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/orders"+tt.query, nil)
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleListOrders() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Data  []map[string]interface{} `json:"data"`
+				Total int                      `json:"total"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp.Total != tt.wantTotal || len(resp.Data) != tt.wantTotal {
+				t.Errorf("total = %d, data = %d, want %d", resp.Total, len(resp.Data), tt.wantTotal)
+			}
+		})
+	}
+}
+
+// TestHandleListOrders_HasMore 测试订单列表返回是否还有下一页
+func TestHandleListOrders_HasMore(t *testing.T) {
+	handler, db := setupOrderTestHandler(t)
+	ctx := context.Background()
+
+	_, addressID := insertTestData(t, db)
+	sessionToken := loginUser(t, handler, "moreuser", "password123")
+	u, err := user.NewMySQLUserRepository(db).GetByUsername(ctx, "moreuser")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := handler.orderService.CreateOrder(ctx, u.ID, &order.CreateOrderRequest{
+			AddressID: addressID,
+			Items:     []*order.CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+		})
+		if err != nil {
+			t.Fatalf("failed to create order: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		wantHasMore string
+		wantCount   int
+	}{
+		{name: "满页且还有数据", query: "?page=1&page_size=2", wantHasMore: "true", wantCount: 2},
+		{name: "最后一页不满", query: "?page=2&page_size=2", wantHasMore: "false", wantCount: 1},
+		{name: "最后一页恰好满页", query: "?page=1&page_size=3", wantHasMore: "false", wantCount: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/orders"+tt.query, nil)
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("HandleListOrders() status = %d, body = %s", w.Code, w.Body.String())
+			}
+			if got := w.Header().Get(HasMoreHeader); got != tt.wantHasMore {
+				t.Errorf("%s = %q, want %q", HasMoreHeader, got, tt.wantHasMore)
+			}
+
+			var resp []map[string]interface{}
+			if err := unmarshalListData(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if len(resp) != tt.wantCount {
+				t.Errorf("HandleListOrders() count = %d, want %d", len(resp), tt.wantCount)
+			}
+		})
+	}
+}
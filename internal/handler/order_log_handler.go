@@ -4,13 +4,19 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/order"
+	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
 )
 
+// defaultOrderLogPageSize 订单日志未指定 page_size 时的每页数量
+const defaultOrderLogPageSize = 50
+
 // HandleGetOrderLogs 处理获取订单日志请求
 // 支持两种 URL 格式：
 // 1. GET /api/orders/logs?order_id=123
 // 2. GET /api/orders/123/logs
+// 可选查询参数：action 操作类型、start/end 记录时间、page/page_size 分页，结果按时间倒序
 func (h *Handler) HandleGetOrderLogs(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		h.respondError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -18,8 +24,7 @@ func (h *Handler) HandleGetOrderLogs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 验证用户身份
-	_, ok := h.authenticateRequest(r)
-	if !ok {
+	if _, ok := middleware.UserFromContext(r.Context()); !ok {
 		h.respondError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
@@ -29,26 +34,18 @@ func (h *Handler) HandleGetOrderLogs(w http.ResponseWriter, r *http.Request) {
 	var err error
 
 	// 方式1: 优先从查询参数解析 ?order_id=123
-	orderIDStr := r.URL.Query().Get("order_id")
-	if orderIDStr != "" {
+	// 方式2: 从 URL 路径解析 /api/orders/{orderID}/logs
+	if orderIDStr := r.URL.Query().Get("order_id"); orderIDStr != "" {
 		orderID, err = strconv.Atoi(orderIDStr)
-		if err != nil {
+		if err != nil || orderID <= 0 {
 			h.respondError(w, http.StatusBadRequest, "订单ID格式错误")
 			return
 		}
-	} else {
-		// 方式2: 从 URL 路径解析 /api/orders/{orderID}/logs
-		path := r.URL.Path
-		if strings.HasPrefix(path, "/api/orders/") && strings.HasSuffix(path, "/logs") {
-			// 提取订单 ID: /api/orders/123/logs -> 123
-			parts := strings.Split(path, "/")
-			if len(parts) >= 4 {
-				orderID, err = strconv.Atoi(parts[3])
-				if err != nil {
-					h.respondError(w, http.StatusBadRequest, "订单ID格式错误")
-					return
-				}
-			}
+	} else if r.PathValue("id") != "" {
+		orderID, err = pathIDParam(r, "id")
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "订单ID格式错误")
+			return
 		}
 	}
 
@@ -58,13 +55,50 @@ func (h *Handler) HandleGetOrderLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	filter, err := parseOrderLogFilter(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// 查询订单日志
-	logs, err := h.orderLogService.GetOrderLogs(r.Context(), orderID)
+	logs, err := h.orderLogService.GetOrderLogs(r.Context(), orderID, filter)
 	if err != nil {
-		h.respondError(w, http.StatusInternalServerError, fmt.Sprintf("查询订单日志失败: %v", err))
+		h.respondServiceError(w, r, fmt.Errorf("查询订单日志失败: %w", err))
 		return
 	}
 
 	// 返回日志列表
 	h.respondJSON(w, http.StatusOK, logs)
 }
+
+// parseOrderLogFilter 解析订单日志的筛选与分页参数，page 默认为 1，page_size 默认为 defaultOrderLogPageSize
+func parseOrderLogFilter(r *http.Request) (order.OrderLogFilter, error) {
+	filter := order.OrderLogFilter{
+		Action:   r.URL.Query().Get("action"),
+		Page:     1,
+		PageSize: defaultOrderLogPageSize,
+	}
+
+	if v := r.URL.Query().Get("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page <= 0 {
+			return filter, fmt.Errorf("page must be a positive integer")
+		}
+		filter.Page = page
+	}
+	if v := r.URL.Query().Get("page_size"); v != "" {
+		pageSize, err := strconv.Atoi(v)
+		if err != nil || pageSize <= 0 {
+			return filter, fmt.Errorf("page_size must be a positive integer")
+		}
+		filter.PageSize = pageSize
+	}
+
+	start, end, err := parseOrderTimeRange(r)
+	if err != nil {
+		return filter, err
+	}
+	filter.StartTime, filter.EndTime = start, end
+	return filter, nil
+}
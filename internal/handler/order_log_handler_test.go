@@ -3,11 +3,13 @@ package handler
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/biqiangwu/flowerSalesSystem/internal/address"
@@ -51,10 +53,10 @@ func TestHandleGetOrderLogs(t *testing.T) {
 	}
 
 	tests := []struct {
-		name        string
-		setupFunc   func(t *testing.T, h *Handler, db *sql.DB) string // 返回 sessionToken
-		urlParams   string
-		wantStatus  int
+		name         string
+		setupFunc    func(t *testing.T, h *Handler, db *sql.DB) string // 返回 sessionToken
+		urlParams    string
+		wantStatus   int
 		validateResp func(t *testing.T, body string)
 	}{
 		{
@@ -201,8 +203,8 @@ func TestHandleGetOrderLogs(t *testing.T) {
 			setupFunc: func(t *testing.T, h *Handler, db *sql.DB) string {
 				return loginUser(t, h, "testuser3", "password123")
 			},
-			urlParams:  "?order_id=0",
-			wantStatus: http.StatusBadRequest,
+			urlParams:    "?order_id=0",
+			wantStatus:   http.StatusBadRequest,
 			validateResp: nil,
 		},
 		{
@@ -210,8 +212,8 @@ func TestHandleGetOrderLogs(t *testing.T) {
 			setupFunc: func(t *testing.T, h *Handler, db *sql.DB) string {
 				return loginUser(t, h, "testuser4", "password123")
 			},
-			urlParams:  "?order_id=abc",
-			wantStatus: http.StatusBadRequest,
+			urlParams:    "?order_id=abc",
+			wantStatus:   http.StatusBadRequest,
 			validateResp: nil,
 		},
 		{
@@ -219,8 +221,8 @@ func TestHandleGetOrderLogs(t *testing.T) {
 			setupFunc: func(t *testing.T, h *Handler, db *sql.DB) string {
 				return "" // 不返回 sessionToken，模拟未登录
 			},
-			urlParams:  "?order_id=1",
-			wantStatus: http.StatusUnauthorized,
+			urlParams:    "?order_id=1",
+			wantStatus:   http.StatusUnauthorized,
 			validateResp: nil,
 		},
 	}
@@ -251,7 +253,7 @@ func TestHandleGetOrderLogs(t *testing.T) {
 			}
 			w := httptest.NewRecorder()
 
-			handler.HandleGetOrderLogs(w, req)
+			routeRequest(handler, w, req)
 
 			if w.Code != tt.wantStatus {
 				t.Errorf("HandleGetOrderLogs() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
@@ -341,7 +343,7 @@ func TestHandleGetOrderLogs_ParseOrderIDFromURL(t *testing.T) {
 			req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
 			w := httptest.NewRecorder()
 
-			handler.HandleGetOrderLogs(w, req)
+			routeRequest(handler, w, req)
 
 			if w.Code != tt.wantStatus {
 				t.Errorf("HandleGetOrderLogs() status = %d, want %d", w.Code, tt.wantStatus)
@@ -387,7 +389,7 @@ func TestHandleGetOrderLogs_QueryParams(t *testing.T) {
 			req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
 			w := httptest.NewRecorder()
 
-			handler.HandleGetOrderLogs(w, req)
+			routeRequest(handler, w, req)
 
 			if w.Code != tt.wantStatus {
 				t.Errorf("HandleGetOrderLogs() status = %d, want %d", w.Code, tt.wantStatus)
@@ -396,6 +398,67 @@ func TestHandleGetOrderLogs_QueryParams(t *testing.T) {
 	}
 }
 
+// TestHandleGetOrderLogs_FilterAndPage 测试按 action 筛选与 page/page_size 分页，结果最新在前
+func TestHandleGetOrderLogs_FilterAndPage(t *testing.T) {
+	handler, _ := setupOrderLogTestHandler(t)
+	sessionToken := loginUser(t, handler, "testuser", "password123")
+	ctx := context.Background()
+
+	// 订单 1 依次记录 12 条日志，每 3 条中有 1 条发货日志，操作人 ID 为序号便于识别
+	for i := 1; i <= 12; i++ {
+		action := "update_order"
+		if i%3 == 0 {
+			action = "ship_order"
+		}
+		if err := handler.orderLogService.LogOrderAction(ctx, 1, i, action, order.StatusPending, order.StatusShipped); err != nil {
+			t.Fatalf("LogOrderAction() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		wantStatus    int
+		wantOperators []int
+	}{
+		{name: "按操作类型筛选", query: "&action=ship_order", wantStatus: http.StatusOK, wantOperators: []int{12, 9, 6, 3}},
+		{name: "分页最新在前", query: "&page=2&page_size=5", wantStatus: http.StatusOK, wantOperators: []int{7, 6, 5, 4, 3}},
+		{name: "筛选与分页组合", query: "&action=ship_order&page=2&page_size=3", wantStatus: http.StatusOK, wantOperators: []int{3}},
+		{name: "page 非法", query: "&page=0", wantStatus: http.StatusBadRequest},
+		{name: "page_size 非数字", query: "&page_size=abc", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/orders/logs?order_id=1"+tt.query, nil)
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleGetOrderLogs() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var logs []*order.OrderLog
+			if err := json.Unmarshal(w.Body.Bytes(), &logs); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if len(logs) != len(tt.wantOperators) {
+				t.Fatalf("got %d logs, want %d", len(logs), len(tt.wantOperators))
+			}
+			for i, l := range logs {
+				if l.OperatorID != tt.wantOperators[i] {
+					t.Errorf("logs[%d].OperatorID = %d, want %d", i, l.OperatorID, tt.wantOperators[i])
+				}
+			}
+		})
+	}
+}
+
 // TestHandleGetOrderLogs_Unauthorized 不同场景的未授权测试
 func TestHandleGetOrderLogs_Unauthorized(t *testing.T) {
 	if testing.Short() {
@@ -438,7 +501,7 @@ func TestHandleGetOrderLogs_Unauthorized(t *testing.T) {
 			tt.setupCookie(req)
 			w := httptest.NewRecorder()
 
-			handler.HandleGetOrderLogs(w, req)
+			routeRequest(handler, w, req)
 
 			if w.Code != tt.wantStatus {
 				t.Errorf("HandleGetOrderLogs() status = %d, want %d", w.Code, tt.wantStatus)
@@ -446,3 +509,64 @@ func TestHandleGetOrderLogs_Unauthorized(t *testing.T) {
 		})
 	}
 }
+
+// TestHandleCancelOrder_ReasonInLogs 测试取消原因随取消请求写入订单日志，并在日志接口中返回
+func TestHandleCancelOrder_ReasonInLogs(t *testing.T) {
+	handler, db := setupOrderLogTestHandler(t)
+	userID, addressID := insertTestData(t, db)
+
+	clerkToken := loginUser(t, handler, "clerk", "password123")
+	if _, err := db.Exec("UPDATE users SET role = 'clerk' WHERE username = 'clerk'"); err != nil {
+		t.Fatalf("failed to promote clerk: %v", err)
+	}
+
+	orderNo, err := handler.orderService.CreateOrder(context.Background(), userID, &order.CreateOrderRequest{
+		AddressID: addressID,
+		Items:     []*order.CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	created, _, _ := order.NewOrderRepository(db).GetByOrderNo(context.Background(), orderNo)
+	cancelPath := "/api/orders/" + strconv.Itoa(created.ID) + "/cancel"
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "请求体非法", body: `{"reason":`, wantStatus: http.StatusBadRequest},
+		{name: "原因过长", body: `{"reason":"` + strings.Repeat("长", order.MaxLogReasonLength+1) + `"}`, wantStatus: http.StatusBadRequest},
+		{name: "携带原因取消", body: `{"reason":"  顾客电话要求取消  "}`, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", cancelPath, strings.NewReader(tt.body))
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: clerkToken})
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("HandleCancelOrder() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/api/orders/logs?action=cancel_order&order_id="+strconv.Itoa(created.ID), nil)
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: clerkToken})
+	w := httptest.NewRecorder()
+	routeRequest(handler, w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("HandleGetOrderLogs() status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	var logs []*order.OrderLog
+	if err := json.Unmarshal(w.Body.Bytes(), &logs); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(logs) != 1 || logs[0].Reason != "顾客电话要求取消" {
+		t.Fatalf("cancel logs = %+v, want one log with reason 顾客电话要求取消", logs)
+	}
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HandleRequestPasswordReset 为用户名或邮箱对应的账号生成一次性重置令牌
+// 无论账号是否存在都返回相同的响应，避免探测账号；开发环境可配置在响应中返回令牌
+// POST /api/password-reset/request
+func (h *Handler) HandleRequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req RequestPasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "无效的请求格式")
+		return
+	}
+
+	token, err := h.passwordResetService.RequestReset(r.Context(), req.Username, req.Email)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"message": "如果账号存在，重置密码的说明已发送",
+	}
+	if h.exposeResetToken && token != "" {
+		resp["token"] = token
+	}
+	h.respondJSON(w, http.StatusOK, resp)
+}
+
+// HandleConfirmPasswordReset 核销重置令牌并设置新密码，令牌只能使用一次
+// POST /api/password-reset/confirm
+func (h *Handler) HandleConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req ConfirmPasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "无效的请求格式")
+		return
+	}
+
+	if err := h.passwordResetService.ConfirmReset(r.Context(), req.Token, req.NewPassword); err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]string{
+		"message": "密码已重置",
+	})
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/auth"
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+)
+
+// setupPasswordResetTestHandler 在用户管理测试 Handler 上启用忘记密码服务
+func setupPasswordResetTestHandler(t *testing.T, exposeToken bool) *testContext {
+	t.Helper()
+
+	ctx, db := setupUserTestHandler(t)
+	// 内存数据库每个连接独立，限制为单连接
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS password_resets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			expires_at DATETIME NOT NULL,
+			used_at DATETIME,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+		t.Fatalf("failed to create password_resets table: %v", err)
+	}
+
+	resetSvc := auth.NewPasswordResetService(ctx.userRepo, ctx.authSvc, auth.NewPasswordResetRepository(db), time.Hour)
+	ctx.handler.SetPasswordResetService(resetSvc, exposeToken)
+	return ctx
+}
+
+// postJSON 发送 JSON 请求并返回响应
+func postJSON(h *Handler, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	routeRequest(h, w, req)
+	return w
+}
+
+// TestHandlePasswordReset 测试忘记密码流程：申请令牌、使用令牌重置密码、令牌不能重复使用
+func TestHandlePasswordReset(t *testing.T) {
+	ctx := setupPasswordResetTestHandler(t, true)
+	createTestUserWithSession(t, ctx, "resetme", user.RoleCustomer)
+
+	w := postJSON(ctx.handler, "/api/password-reset/request", `{"username":"resetme"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("request reset status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	token := resp["token"]
+	if token == "" {
+		t.Fatalf("response should include token when exposed: %v", resp)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "请求格式错误", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "新密码过短", body: `{"token":"` + token + `","new_password":"123"}`, wantStatus: http.StatusBadRequest},
+		{name: "重置成功", body: `{"token":"` + token + `","new_password":"newpassword456"}`, wantStatus: http.StatusOK},
+		{name: "令牌已使用", body: `{"token":"` + token + `","new_password":"another789"}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postJSON(ctx.handler, "/api/password-reset/confirm", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Errorf("confirm reset status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+
+	if _, err := ctx.authSvc.Login(t.Context(), "resetme", "newpassword456"); err != nil {
+		t.Errorf("Login() with new password error = %v", err)
+	}
+}
+
+// TestHandleRequestPasswordReset_HidesToken 测试未开启返回令牌时响应不含令牌，且不暴露账号是否存在
+func TestHandleRequestPasswordReset_HidesToken(t *testing.T) {
+	ctx := setupPasswordResetTestHandler(t, false)
+	createTestUserWithSession(t, ctx, "resetme", user.RoleCustomer)
+
+	var bodies []string
+	for _, body := range []string{`{"username":"resetme"}`, `{"username":"nobody"}`} {
+		w := postJSON(ctx.handler, "/api/password-reset/request", body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("request reset %s status = %d, body = %s", body, w.Code, w.Body.String())
+		}
+		bodies = append(bodies, w.Body.String())
+	}
+	if bodies[0] != bodies[1] {
+		t.Errorf("responses differ for existing and unknown accounts: %q vs %q", bodies[0], bodies[1])
+	}
+
+	if w := postJSON(ctx.handler, "/api/password-reset/request", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("request reset without account status = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/report"
+)
+
+// defaultReportDays 未指定日期区间时默认统计的天数
+const defaultReportDays = 30
+
+// HandleNewUserReport 处理每日新增用户报表（仅管理员）
+// GET /api/reports/new-users?start=2026-01-01&end=2026-01-31
+func (h *Handler) HandleNewUserReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	dateRange, err := parseReportRange(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	series, err := h.reportService.NewUserReport(r.Context(), dateRange)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"start": dateRange.Start.Format(report.DateLayout),
+		"end":   dateRange.End.Format(report.DateLayout),
+		"data":  series,
+	})
+}
+
+// HandleSalesReport 处理销售汇总报表（仅管理员）
+// GET /api/reports/sales?start=2026-01-01&end=2026-01-31，区间规则同新增用户报表
+func (h *Handler) HandleSalesReport(w http.ResponseWriter, r *http.Request) {
+	dateRange, err := parseReportRange(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sales, err := h.reportService.SalesReport(r.Context(), dateRange)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, sales)
+}
+
+// HandleBlockedOrdersReport 处理缺货待处理订单报表（店员和管理员）
+// GET /api/reports/blocked-orders
+func (h *Handler) HandleBlockedOrdersReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	orders, err := h.reportService.BlockedOrders(r.Context())
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, orders)
+}
+
+// parseReportRange 解析报表的 start/end 查询参数（YYYY-MM-DD）
+// 未指定 end 时默认为今天，未指定 start 时默认为 end 前 29 天
+func parseReportRange(r *http.Request) (report.DateRange, error) {
+	var dr report.DateRange
+
+	end := time.Now().UTC()
+	if s := r.URL.Query().Get("end"); s != "" {
+		t, err := time.Parse(report.DateLayout, s)
+		if err != nil {
+			return dr, fmt.Errorf("invalid end: 日期格式应为 YYYY-MM-DD")
+		}
+		end = t
+	}
+
+	start := end.AddDate(0, 0, -(defaultReportDays - 1))
+	if s := r.URL.Query().Get("start"); s != "" {
+		t, err := time.Parse(report.DateLayout, s)
+		if err != nil {
+			return dr, fmt.Errorf("invalid start: 日期格式应为 YYYY-MM-DD")
+		}
+		start = t
+	}
+
+	dr.Start = start
+	dr.End = end
+	return dr, dr.Validate()
+}
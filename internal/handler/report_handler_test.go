@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/report"
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
+)
+
+// TestHandleNewUserReport 测试每日新增用户报表接口
+func TestHandleNewUserReport(t *testing.T) {
+	ctx, db := setupUserTestHandler(t)
+	handler := ctx.handler
+	handler.reportService = report.NewReportService(report.NewReportRepository(db))
+
+	_, adminToken := createTestUserWithSession(t, ctx, "admin", user.RoleAdmin)
+	_, customerToken := createTestUserWithSession(t, ctx, "customer", user.RoleCustomer)
+
+	tests := []struct {
+		name       string
+		token      string
+		query      string
+		wantStatus int
+		wantDays   int
+	}{
+		{"管理员查询指定区间", adminToken, "?start=2026-01-01&end=2026-01-07", http.StatusOK, 7},
+		{"管理员默认区间", adminToken, "", http.StatusOK, 30},
+		{"结束早于开始", adminToken, "?start=2026-01-07&end=2026-01-01", http.StatusBadRequest, 0},
+		{"日期格式错误", adminToken, "?start=2026/01/01", http.StatusBadRequest, 0},
+		{"普通用户无权访问", customerToken, "", http.StatusForbidden, 0},
+		{"未登录", "", "", http.StatusUnauthorized, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/reports/new-users"+tt.query, nil)
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: CookieName, Value: tt.token})
+			}
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Data []report.DailyCount `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if len(resp.Data) != tt.wantDays {
+				t.Errorf("got %d days, want %d", len(resp.Data), tt.wantDays)
+			}
+		})
+	}
+}
+
+// TestHandleSalesReport 测试销售汇总报表接口（仅管理员）
+func TestHandleSalesReport(t *testing.T) {
+	ctx, db := setupUserTestHandler(t)
+	handler := ctx.handler
+	handler.reportService = report.NewReportService(report.NewReportRepository(db))
+
+	_, err := db.Exec(`
+		CREATE TABLE orders (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			order_no TEXT UNIQUE NOT NULL,
+			user_id INTEGER NOT NULL,
+			address_id INTEGER NOT NULL,
+			total_amount INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE order_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			order_id INTEGER NOT NULL,
+			flower_sku TEXT NOT NULL,
+			flower_name TEXT NOT NULL,
+			quantity INTEGER NOT NULL,
+			unit_price INTEGER NOT NULL,
+			subtotal INTEGER NOT NULL
+		);
+		INSERT INTO orders (id, order_no, user_id, address_id, total_amount, status, created_at) VALUES
+			(1, 'ORD001', 1, 1, 3000, 'completed', '2026-01-02 10:00:00'),
+			(2, 'ORD002', 1, 1, 1000, 'pending', '2026-01-03 10:00:00');
+		INSERT INTO order_items (order_id, flower_sku, flower_name, quantity, unit_price, subtotal) VALUES
+			(1, 'ROSE', '红玫瑰', 3, 1000, 3000),
+			(2, 'ROSE', '红玫瑰', 1, 1000, 1000);
+	`)
+	if err != nil {
+		t.Fatalf("failed to seed orders: %v", err)
+	}
+
+	_, adminToken := createTestUserWithSession(t, ctx, "salesadmin", user.RoleAdmin)
+	_, clerkToken := createTestUserWithSession(t, ctx, "salesclerk", user.RoleClerk)
+
+	tests := []struct {
+		name       string
+		token      string
+		query      string
+		wantStatus int
+	}{
+		{"管理员查询", adminToken, "?start=2026-01-01&end=2026-01-07", http.StatusOK},
+		{"日期格式错误", adminToken, "?end=2026/01/07", http.StatusBadRequest},
+		{"店员无权访问", clerkToken, "", http.StatusForbidden},
+		{"未登录", "", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/reports/sales"+tt.query, nil)
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: CookieName, Value: tt.token})
+			}
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				TotalRevenue      string         `json:"total_revenue"`
+				TotalRevenueCents int64          `json:"total_revenue_cents"`
+				OrderCounts       map[string]int `json:"order_counts"`
+				TopSelling        []struct {
+					FlowerSKU string `json:"flower_sku"`
+					Quantity  int    `json:"quantity"`
+				} `json:"top_selling"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp.TotalRevenue != "30.00" || resp.TotalRevenueCents != 3000 {
+				t.Errorf("revenue = %s (%d cents), want 30.00", resp.TotalRevenue, resp.TotalRevenueCents)
+			}
+			if resp.OrderCounts["completed"] != 1 || resp.OrderCounts["pending"] != 1 {
+				t.Errorf("order counts = %v, want 1 completed and 1 pending", resp.OrderCounts)
+			}
+			if len(resp.TopSelling) != 1 || resp.TopSelling[0].FlowerSKU != "ROSE" || resp.TopSelling[0].Quantity != 3 {
+				t.Errorf("top selling = %+v, want ROSE x3", resp.TopSelling)
+			}
+		})
+	}
+}
+
+// blockingReportRepository 模拟长查询：一直阻塞到 context 被取消
+type blockingReportRepository struct {
+	report.ReportRepository
+	started chan struct{}
+	stopped chan struct{}
+}
+
+func (r *blockingReportRepository) CountNewUsersByDay(ctx context.Context, dr report.DateRange) (map[string]int, error) {
+	close(r.started)
+	<-ctx.Done()
+	close(r.stopped)
+	return nil, fmt.Errorf("count new users by day: %w", ctx.Err())
+}
+
+// TestHandleNewUserReport_ClientCanceled 测试客户端断开后查询被取消且不返回 500
+func TestHandleNewUserReport_ClientCanceled(t *testing.T) {
+	ctx, _ := setupUserTestHandler(t)
+	handler := ctx.handler
+	repo := &blockingReportRepository{
+		started: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	handler.reportService = report.NewReportService(repo)
+
+	_, adminToken := createTestUserWithSession(t, ctx, "admin", user.RoleAdmin)
+
+	var logs bytes.Buffer
+	prevOutput := log.Writer()
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(prevOutput) })
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/api/reports/new-users", nil).WithContext(reqCtx)
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: adminToken})
+	w := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		middleware.LoggingMiddleware(http.HandlerFunc(handler.HandleNewUserReport)).ServeHTTP(w, req)
+		close(done)
+	}()
+
+	<-repo.started
+	cancel()
+	<-repo.stopped
+	<-done
+
+	if w.Code != StatusClientClosedRequest {
+		t.Errorf("status = %d, want %d", w.Code, StatusClientClosedRequest)
+	}
+	if strings.Contains(logs.String(), " - 500 - ") {
+		t.Errorf("canceled request logged as server error: %s", logs.String())
+	}
+}
+
+// TestReportRoutes_ConcurrencyLimit 测试报表并发配额饱和时返回 429，普通接口不受影响
+func TestReportRoutes_ConcurrencyLimit(t *testing.T) {
+	ctx, _ := setupUserTestHandler(t)
+	handler := ctx.handler
+	repo := &blockingReportRepository{
+		started: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	handler.reportService = report.NewReportService(repo)
+	handler.SetReportConcurrency(1)
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	_, adminToken := createTestUserWithSession(t, ctx, "admin", user.RoleAdmin)
+	newRequest := func(c context.Context, path string) *http.Request {
+		req := httptest.NewRequest("GET", path, nil).WithContext(c)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: adminToken})
+		return req
+	}
+
+	// 第一个报表请求占满配额并阻塞在查询中
+	reqCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		mux.ServeHTTP(httptest.NewRecorder(), newRequest(reqCtx, "/api/reports/new-users"))
+		close(done)
+	}()
+	<-repo.started
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// 其它报表请求共享配额，被拒绝
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, newRequest(context.Background(), "/api/reports/blocked-orders"))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("report status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+
+	// 普通接口不受报表负载影响
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, newRequest(context.Background(), "/api/users"))
+	if w.Code != http.StatusOK {
+		t.Errorf("users status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body.String())
+	}
+}
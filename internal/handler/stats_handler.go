@@ -0,0 +1,11 @@
+package handler
+
+import (
+	"net/http"
+)
+
+// HandleGetStats 返回自服务启动以来的业务计数（仅管理员）
+// GET /api/admin/stats：订单创建/完成/取消数与已完成订单营收，进程重启后清零
+func (h *Handler) HandleGetStats(w http.ResponseWriter, r *http.Request) {
+	h.respondJSON(w, http.StatusOK, h.orderService.Stats())
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/order"
+)
+
+// TestHandleGetStats 测试业务计数接口的权限与返回内容
+func TestHandleGetStats(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	handler, db := setupOrderTestHandler(t)
+	userID, addressID := insertTestData(t, db)
+
+	if _, err := handler.orderService.CreateOrder(context.Background(), userID, &order.CreateOrderRequest{
+		AddressID: addressID,
+		Items:     []*order.CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 2}},
+	}); err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+
+	customerToken := loginUser(t, handler, "customer", "password123")
+	adminToken := loginUser(t, handler, "admin", "password123")
+	if _, err := db.Exec("UPDATE users SET role = 'admin' WHERE username = 'admin'"); err != nil {
+		t.Fatalf("failed to promote admin: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "未登录", wantStatus: http.StatusUnauthorized},
+		{name: "顾客无权限", token: customerToken, wantStatus: http.StatusForbidden},
+		{name: "管理员查看", token: adminToken, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/admin/stats", nil)
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: tt.token})
+			}
+			w := httptest.NewRecorder()
+
+			routeRequest(handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleGetStats() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp["orders_created"] != float64(1) || resp["orders_completed"] != float64(0) {
+				t.Errorf("stats = %v, want 1 created and 0 completed", resp)
+			}
+			if resp["revenue"] != "0.00" {
+				t.Errorf("revenue = %v, want 0.00", resp["revenue"])
+			}
+		})
+	}
+}
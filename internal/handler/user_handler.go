@@ -2,72 +2,79 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strconv"
-	"strings"
 
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/biqiangwu/flowerSalesSystem/internal/auth"
 	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+	"github.com/biqiangwu/flowerSalesSystem/pkg/middleware"
 )
 
 // HandleListUsers 处理获取用户列表请求
 func (h *Handler) HandleListUsers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	// 从 session 中获取用户信息
-	_, err := h.getUserFromSession(r)
-	if err != nil {
+	// 从上下文获取当前用户（由 AuthMiddleware 注入）
+	_, ok := middleware.UserFromContext(r.Context())
+	if !ok {
 		h.respondError(w, http.StatusUnauthorized, "未登录或 session 无效")
 		return
 	}
 
 	// 解析分页参数
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	page := parseIntQuery(r.URL.Query().Get("page"), 1)
+	pageSize := parseIntQuery(r.URL.Query().Get("page_size"), user.DefaultPageSize)
+	arrayFormat, err := parseArrayFormat(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// 获取用户列表
-	users, err := h.userService.ListUsers(ctx, page, pageSize)
+	users, err := h.userService.ListUsers(ctx, page, pageSize, r.URL.Query().Get("sort"))
+	if apperror.KindOf(err) == apperror.KindValidation {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		h.respondError(w, http.StatusInternalServerError, "获取用户列表失败")
 		return
 	}
 
 	// 转换为响应格式
-	type UserResponse struct {
-		ID        int    `json:"id"`
-		Username  string `json:"username"`
-		Role      string `json:"role"`
-		CreatedAt string `json:"created_at"`
-	}
-
 	userResponses := make([]UserResponse, len(users))
 	for i, u := range users {
-		userResponses[i] = UserResponse{
-			ID:        u.ID,
-			Username:  u.Username,
-			Role:      string(u.Role),
-			CreatedAt: u.CreatedAt.Format("2006-01-02 15:04:05"),
-		}
+		userResponses[i] = newUserResponse(u)
 	}
 
-	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+	// format=array 时保持旧版 {"users": [...]} 响应，无需统计总数
+	total := 0
+	if !arrayFormat {
+		total, err = h.userService.CountUsers(ctx)
+		if err != nil {
+			h.respondError(w, http.StatusInternalServerError, "获取用户列表失败")
+			return
+		}
+	}
+	h.respondList(w, arrayFormat, map[string]interface{}{
 		"users": userResponses,
-	})
+	}, userResponses, total, page, pageSize)
 }
 
 // HandleDeleteUser 处理删除用户请求
 func (h *Handler) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	// 从 session 中获取操作者信息
-	operator, err := h.getUserFromSession(r)
-	if err != nil {
+	// 从上下文获取操作者信息（由 AuthMiddleware 注入）
+	operator, ok := middleware.UserFromContext(r.Context())
+	if !ok {
 		h.respondError(w, http.StatusUnauthorized, "未登录或 session 无效")
 		return
 	}
 
 	// 从 URL 中提取用户 ID
-	userIDStr := strings.TrimPrefix(r.URL.Path, "/api/users/")
-	userID, err := strconv.Atoi(userIDStr)
+	userID, err := pathIDParam(r, "id")
 	if err != nil {
 		h.respondError(w, http.StatusBadRequest, "无效的用户 ID")
 		return
@@ -97,20 +104,15 @@ func (h *Handler) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	// 从 session 中获取操作者信息
-	operator, err := h.getUserFromSession(r)
-	if err != nil {
+	// 从上下文获取操作者信息（由 AuthMiddleware 注入）
+	operator, ok := middleware.UserFromContext(r.Context())
+	if !ok {
 		h.respondError(w, http.StatusUnauthorized, "未登录或 session 无效")
 		return
 	}
 
 	// 从 URL 中提取用户 ID
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) < 5 {
-		h.respondError(w, http.StatusBadRequest, "无效的请求路径")
-		return
-	}
-	userID, err := strconv.Atoi(parts[3])
+	userID, err := pathIDParam(r, "id")
 	if err != nil {
 		h.respondError(w, http.StatusBadRequest, "无效的用户 ID")
 		return
@@ -134,8 +136,8 @@ func (h *Handler) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
 			h.respondError(w, http.StatusForbidden, "权限不足")
 			return
 		}
-		if err == user.ErrInvalidPassword {
-			h.respondError(w, http.StatusBadRequest, "密码无效（至少需要 6 个字符）")
+		if errors.Is(err, user.ErrInvalidPassword) {
+			h.respondError(w, http.StatusBadRequest, err.Error())
 			return
 		}
 		h.respondError(w, http.StatusInternalServerError, "重置密码失败")
@@ -147,7 +149,93 @@ func (h *Handler) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// getUserFromSession 从请求中获取用户信息
+// HandleGetCurrentUser 返回当前登录用户的信息，用于页面刷新后恢复登录状态
+func (h *Handler) HandleGetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "未登录或 session 无效")
+		return
+	}
+
+	resp := newUserResponse(u)
+	resp.ImpersonatorID, _ = auth.ImpersonatorFromContext(r.Context())
+	h.respondJSON(w, http.StatusOK, resp)
+}
+
+// HandleUpdateProfile 修改当前用户本人的资料（邮箱），返回更新后的用户信息
+// PATCH /api/me
+func (h *Handler) HandleUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	operator, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "未登录或 session 无效")
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "无效的请求格式")
+		return
+	}
+
+	u, err := h.userService.UpdateProfile(r.Context(), operator.ID, req.Email)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, newUserResponse(u))
+}
+
+// HandleChangePassword 处理当前用户修改自己的密码
+// 需提供原密码，只能修改 session 用户本人的密码
+func (h *Handler) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+	operator, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		h.respondError(w, http.StatusUnauthorized, "未登录或 session 无效")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "无效的请求格式")
+		return
+	}
+
+	if !h.authService.VerifyPassword(req.OldPassword, operator.PasswordHash) {
+		h.respondError(w, http.StatusUnauthorized, "原密码错误")
+		return
+	}
+
+	// HashPassword 同时按密码强度策略校验，不满足时返回校验错误（400）
+	passwordHash, err := h.authService.HashPassword(req.NewPassword)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	if err := h.userRepo.UpdatePassword(r.Context(), operator.ID, passwordHash); err != nil {
+		h.respondError(w, http.StatusInternalServerError, "修改密码失败")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]string{
+		"message": "密码已修改",
+	})
+}
+
+// newUserResponse 将用户模型转换为响应格式
+func newUserResponse(u *user.User) UserResponse {
+	return UserResponse{
+		ID:        u.ID,
+		Username:  u.Username,
+		Email:     u.Email,
+		Role:      string(u.Role),
+		CreatedAt: u.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// getUserFromSession 从请求的 session Cookie 中识别用户
+// 仅用于未经过 AuthMiddleware 的公开路由上的可选身份识别；受保护路由使用 middleware.UserFromContext
 func (h *Handler) getUserFromSession(r *http.Request) (*user.User, error) {
 	cookie, err := r.Cookie("session_token")
 	if err != nil {
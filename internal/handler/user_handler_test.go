@@ -18,11 +18,11 @@ import (
 
 // testContext 包含测试所需的上下文
 type testContext struct {
-	db          *sql.DB
-	handler     *Handler
-	userRepo    user.UserRepository
-	sessionMgr  auth.SessionManager
-	authSvc     auth.AuthService
+	db         *sql.DB
+	handler    *Handler
+	userRepo   user.UserRepository
+	sessionMgr auth.SessionManager
+	authSvc    auth.AuthService
 }
 
 // setupUserTestHandler 创建测试用的用户管理 Handler
@@ -38,6 +38,7 @@ func setupUserTestHandler(t *testing.T) (*testContext, *sql.DB) {
 	CREATE TABLE IF NOT EXISTS users (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		username TEXT UNIQUE NOT NULL,
+		email TEXT UNIQUE,
 		password_hash TEXT NOT NULL,
 		role TEXT NOT NULL DEFAULT 'customer',
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -62,6 +63,7 @@ func setupUserTestHandler(t *testing.T) (*testContext, *sql.DB) {
 	handler := &Handler{
 		authService: authSvc,
 		userService: userSvc,
+		userRepo:    userRepo,
 	}
 
 	ctx := &testContext{
@@ -166,7 +168,7 @@ func TestHandleListUsers(t *testing.T) {
 			}
 			w := httptest.NewRecorder()
 
-			handler.HandleListUsers(w, req)
+			routeRequest(handler, w, req)
 
 			if w.Code != tt.wantStatus {
 				t.Errorf("HandleListUsers() status = %d, want %d", w.Code, tt.wantStatus)
@@ -179,7 +181,7 @@ func TestHandleListUsers(t *testing.T) {
 					t.Fatalf("failed to parse response: %v", err)
 				}
 
-				users, ok := resp["users"].([]interface{})
+				users, ok := resp["data"].([]interface{})
 				if !ok {
 					t.Fatal("response missing users array")
 				}
@@ -193,6 +195,106 @@ func TestHandleListUsers(t *testing.T) {
 	}
 }
 
+// TestHandleListUsers_Envelope 测试用户列表分页响应中的总数，以及 format=array 旧版格式
+func TestHandleListUsers_Envelope(t *testing.T) {
+	ctx, _ := setupUserTestHandler(t)
+	handler := ctx.handler
+
+	for i := 1; i <= 4; i++ {
+		_, _ = createTestUserWithSession(t, ctx, "pageuser"+strconv.Itoa(i), user.RoleCustomer)
+	}
+	_, adminSession := createTestUserWithSession(t, ctx, "admin", user.RoleAdmin)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/users"+query, nil)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: adminSession})
+		w := httptest.NewRecorder()
+		routeRequest(handler, w, req)
+		return w
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		wantCount      int
+		wantPageSize   int
+		wantTotalPages int
+	}{
+		{name: "第一页", query: "?page=1&page_size=2", wantCount: 2, wantPageSize: 2, wantTotalPages: 3},
+		{name: "最后一页", query: "?page=3&page_size=2", wantCount: 1, wantPageSize: 2, wantTotalPages: 3},
+		{name: "默认分页", query: "", wantCount: 5, wantPageSize: user.DefaultPageSize, wantTotalPages: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(tt.query)
+			if w.Code != http.StatusOK {
+				t.Fatalf("HandleListUsers() status = %d, body = %s", w.Code, w.Body.String())
+			}
+
+			var resp struct {
+				Data       []json.RawMessage `json:"data"`
+				Total      int               `json:"total"`
+				PageSize   int               `json:"page_size"`
+				TotalPages int               `json:"total_pages"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if len(resp.Data) != tt.wantCount {
+				t.Errorf("data count = %d, want %d", len(resp.Data), tt.wantCount)
+			}
+			if resp.Total != 5 {
+				t.Errorf("total = %d, want 5", resp.Total)
+			}
+			if resp.PageSize != tt.wantPageSize || resp.TotalPages != tt.wantTotalPages {
+				t.Errorf("page_size = %d, total_pages = %d, want %d, %d",
+					resp.PageSize, resp.TotalPages, tt.wantPageSize, tt.wantTotalPages)
+			}
+		})
+	}
+
+	t.Run("format=array 返回旧版格式", func(t *testing.T) {
+		w := get("?format=array")
+		if w.Code != http.StatusOK {
+			t.Fatalf("HandleListUsers() status = %d, body = %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Users []json.RawMessage `json:"users"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if len(resp.Users) != 5 {
+			t.Errorf("users count = %d, want 5", len(resp.Users))
+		}
+	})
+
+	t.Run("按用户名倒序", func(t *testing.T) {
+		w := get("?sort=username_desc&page_size=1")
+		if w.Code != http.StatusOK {
+			t.Fatalf("HandleListUsers() status = %d, body = %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data []struct {
+				Username string `json:"username"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if len(resp.Data) != 1 || resp.Data[0].Username != "pageuser4" {
+			t.Errorf("first user = %+v, want pageuser4", resp.Data)
+		}
+	})
+
+	t.Run("非法排序", func(t *testing.T) {
+		if w := get("?sort=password_hash"); w.Code != http.StatusBadRequest {
+			t.Errorf("HandleListUsers() status = %d, want 400", w.Code)
+		}
+	})
+}
+
 // TestHandleDeleteUser 测试删除用户接口
 func TestHandleDeleteUser(t *testing.T) {
 	ctx, _ := setupUserTestHandler(t)
@@ -261,7 +363,7 @@ func TestHandleDeleteUser(t *testing.T) {
 			}
 			w := httptest.NewRecorder()
 
-			handler.HandleDeleteUser(w, req)
+			routeRequest(handler, w, req)
 
 			if w.Code != tt.wantStatus {
 				t.Errorf("HandleDeleteUser() status = %d, want %d", w.Code, tt.wantStatus)
@@ -373,7 +475,7 @@ func TestHandleResetPassword(t *testing.T) {
 			}
 			w := httptest.NewRecorder()
 
-			handler.HandleResetPassword(w, req)
+			routeRequest(handler, w, req)
 
 			if w.Code != tt.wantStatus {
 				t.Errorf("HandleResetPassword() status = %d, want %d", w.Code, tt.wantStatus)
@@ -381,3 +483,158 @@ func TestHandleResetPassword(t *testing.T) {
 		})
 	}
 }
+
+// TestHandleChangePassword 测试修改本人密码接口
+func TestHandleChangePassword(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		noSession   bool
+		wantStatus  int
+		newPassword string // 修改成功后应能使用的新密码
+	}{
+		{name: "修改成功", body: `{"old_password":"password123","new_password":"newpassword456"}`, wantStatus: http.StatusOK, newPassword: "newpassword456"},
+		{name: "原密码错误", body: `{"old_password":"wrongpassword","new_password":"newpassword456"}`, wantStatus: http.StatusUnauthorized},
+		{name: "新密码过短", body: `{"old_password":"password123","new_password":"123"}`, wantStatus: http.StatusBadRequest},
+		{name: "请求格式错误", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "未登录", body: `{"old_password":"password123","new_password":"newpassword456"}`, noSession: true, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := setupUserTestHandler(t)
+			// 另一个用户用于确认只修改 session 用户本人的密码
+			createTestUserWithSession(t, ctx, "bystander", user.RoleCustomer)
+			_, session := createTestUserWithSession(t, ctx, "changer", user.RoleCustomer)
+
+			req := httptest.NewRequest("POST", "/api/me/password", bytes.NewBufferString(tt.body))
+			if !tt.noSession {
+				req.AddCookie(&http.Cookie{Name: CookieName, Value: session})
+			}
+			w := httptest.NewRecorder()
+
+			routeRequest(ctx.handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleChangePassword() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			bg := context.Background()
+			if tt.newPassword != "" {
+				if _, err := ctx.authSvc.Login(bg, "changer", tt.newPassword); err != nil {
+					t.Errorf("Login() with new password error = %v", err)
+				}
+				if _, err := ctx.authSvc.Login(bg, "changer", "password123"); err == nil {
+					t.Error("Login() with old password should fail after change")
+				}
+			} else if _, err := ctx.authSvc.Login(bg, "changer", "password123"); err != nil {
+				t.Errorf("Login() with unchanged password error = %v", err)
+			}
+			if _, err := ctx.authSvc.Login(bg, "bystander", "password123"); err != nil {
+				t.Errorf("other user's password should be unchanged: %v", err)
+			}
+		})
+	}
+}
+
+// TestHandleGetCurrentUser 测试获取当前登录用户信息接口
+func TestHandleGetCurrentUser(t *testing.T) {
+	ctx, _ := setupUserTestHandler(t)
+	clerk, clerkSession := createTestUserWithSession(t, ctx, "meclerk", user.RoleClerk)
+
+	tests := []struct {
+		name       string
+		session    string
+		wantStatus int
+	}{
+		{name: "已登录", session: clerkSession, wantStatus: http.StatusOK},
+		{name: "未登录", session: "", wantStatus: http.StatusUnauthorized},
+		{name: "无效 session", session: "invalid_token", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/me", nil)
+			if tt.session != "" {
+				req.AddCookie(&http.Cookie{Name: CookieName, Value: tt.session})
+			}
+			w := httptest.NewRecorder()
+
+			routeRequest(ctx.handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleGetCurrentUser() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp["id"] != float64(clerk.ID) || resp["username"] != "meclerk" || resp["role"] != string(user.RoleClerk) {
+				t.Errorf("HandleGetCurrentUser() = %v, want id %d meclerk clerk", resp, clerk.ID)
+			}
+			if resp["created_at"] == "" || resp["created_at"] == nil {
+				t.Error("response should include created_at")
+			}
+			for _, key := range []string{"password_hash", "PasswordHash"} {
+				if _, ok := resp[key]; ok {
+					t.Errorf("response should not include %s", key)
+				}
+			}
+		})
+	}
+}
+
+// TestHandleUpdateProfile 测试修改本人邮箱接口
+func TestHandleUpdateProfile(t *testing.T) {
+	ctx, _ := setupUserTestHandler(t)
+	other, _ := createTestUserWithSession(t, ctx, "other", user.RoleCustomer)
+	if _, err := ctx.handler.userService.UpdateProfile(context.Background(), other.ID, "other@example.com"); err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+	_, session := createTestUserWithSession(t, ctx, "profile", user.RoleCustomer)
+
+	tests := []struct {
+		name       string
+		body       string
+		session    string
+		wantStatus int
+		wantEmail  string
+	}{
+		{name: "未登录", body: `{"email":"me@example.com"}`, wantStatus: http.StatusUnauthorized},
+		{name: "请求格式错误", body: `{`, session: session, wantStatus: http.StatusBadRequest},
+		{name: "格式无效", body: `{"email":"me@"}`, session: session, wantStatus: http.StatusBadRequest},
+		{name: "邮箱已被占用", body: `{"email":"other@example.com"}`, session: session, wantStatus: http.StatusConflict},
+		{name: "设置邮箱", body: `{"email":"Me@Example.com"}`, session: session, wantStatus: http.StatusOK, wantEmail: "me@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PATCH", "/api/me", bytes.NewBufferString(tt.body))
+			if tt.session != "" {
+				req.AddCookie(&http.Cookie{Name: CookieName, Value: tt.session})
+			}
+			w := httptest.NewRecorder()
+
+			routeRequest(ctx.handler, w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleUpdateProfile() status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp UserResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp.Email != tt.wantEmail || resp.Username != "profile" {
+				t.Errorf("HandleUpdateProfile() = %+v, want profile with email %s", resp, tt.wantEmail)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package maintenance
+
+// OrphanOrderItem 没有对应订单的订单项
+type OrphanOrderItem struct {
+	ID         int    `json:"id"`
+	OrderID    int    `json:"order_id"`
+	FlowerSKU  string `json:"flower_sku"`
+	FlowerName string `json:"flower_name"`
+	Quantity   int    `json:"quantity"`
+}
+
+// OrphanCleanupResult 孤立订单项清理结果
+type OrphanCleanupResult struct {
+	DryRun  bool               `json:"dry_run"`
+	Found   int                `json:"found"`
+	Deleted int                `json:"deleted"`
+	Items   []*OrphanOrderItem `json:"items"`
+}
+
+// 数据库类型
+const (
+	DialectMySQL  = "mysql"
+	DialectSQLite = "sqlite"
+)
+
+// OptimizeStep 单条维护语句的执行情况
+type OptimizeStep struct {
+	Statement  string `json:"statement"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// OptimizeResult 数据库维护（ANALYZE/OPTIMIZE/VACUUM）执行结果
+type OptimizeResult struct {
+	Dialect    string          `json:"dialect"`
+	Steps      []*OptimizeStep `json:"steps"`
+	DurationMs int64           `json:"duration_ms"`
+}
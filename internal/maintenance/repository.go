@@ -0,0 +1,150 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maintainedTables MySQL 下执行 ANALYZE/OPTIMIZE 的业务表
+var maintainedTables = []string{
+	"users", "addresses", "flowers", "orders", "order_items", "order_logs", "flower_logs", "impersonation_logs",
+}
+
+// MaintenanceRepository 定义数据维护的数据访问接口
+type MaintenanceRepository interface {
+	ListOrphanOrderItems(ctx context.Context) ([]*OrphanOrderItem, error)
+	DeleteOrphanOrderItems(ctx context.Context, ids []int) (int, error)
+	OptimizeTables(ctx context.Context) (*OptimizeResult, error)
+}
+
+// maintenanceRepository 实现 MaintenanceRepository 接口
+type maintenanceRepository struct {
+	db *sql.DB
+}
+
+// NewMaintenanceRepository 创建 MaintenanceRepository 实例
+func NewMaintenanceRepository(db *sql.DB) MaintenanceRepository {
+	return &maintenanceRepository{db: db}
+}
+
+// ListOrphanOrderItems 查询 order_id 没有对应订单的订单项
+func (r *maintenanceRepository) ListOrphanOrderItems(ctx context.Context) ([]*OrphanOrderItem, error) {
+	query := `
+		SELECT oi.id, oi.order_id, oi.flower_sku, oi.flower_name, oi.quantity
+		FROM order_items oi
+		LEFT JOIN orders o ON o.id = oi.order_id
+		WHERE o.id IS NULL
+		ORDER BY oi.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list orphan order items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*OrphanOrderItem
+	for rows.Next() {
+		var item OrphanOrderItem
+		if err := rows.Scan(&item.ID, &item.OrderID, &item.FlowerSKU, &item.FlowerName, &item.Quantity); err != nil {
+			return nil, fmt.Errorf("scan orphan order item: %w", err)
+		}
+		items = append(items, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate orphan order items: %w", err)
+	}
+
+	return items, nil
+}
+
+// DeleteOrphanOrderItems 删除指定的孤立订单项
+// 删除时再次确认订单不存在，避免误删期间被补回订单的数据
+func (r *maintenanceRepository) DeleteOrphanOrderItems(ctx context.Context, ids []int) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	query := `
+		DELETE FROM order_items
+		WHERE id IN (` + placeholders + `)
+		AND order_id NOT IN (SELECT id FROM orders)
+	`
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete orphan order items: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("check rows affected: %w", err)
+	}
+
+	return int(affected), nil
+}
+
+// OptimizeTables 按当前数据库类型执行统计信息刷新与空间整理
+// MySQL: ANALYZE TABLE + OPTIMIZE TABLE；SQLite: VACUUM + ANALYZE
+func (r *maintenanceRepository) OptimizeTables(ctx context.Context) (*OptimizeResult, error) {
+	dialect := detectDialect(r.db)
+
+	var statements []string
+	switch dialect {
+	case DialectSQLite:
+		statements = []string{"VACUUM", "ANALYZE"}
+	default:
+		tables := strings.Join(maintainedTables, ", ")
+		statements = []string{"ANALYZE TABLE " + tables, "OPTIMIZE TABLE " + tables}
+	}
+
+	result := &OptimizeResult{Dialect: dialect, Steps: make([]*OptimizeStep, 0, len(statements))}
+	start := time.Now()
+
+	for _, stmt := range statements {
+		stepStart := time.Now()
+		if err := r.execMaintenance(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("exec %q: %w", stmt, err)
+		}
+		result.Steps = append(result.Steps, &OptimizeStep{
+			Statement:  stmt,
+			DurationMs: time.Since(stepStart).Milliseconds(),
+		})
+	}
+
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// execMaintenance 执行维护语句
+// MySQL 的 ANALYZE/OPTIMIZE TABLE 会返回结果集，需要读完才能释放连接
+func (r *maintenanceRepository) execMaintenance(ctx context.Context, stmt string) error {
+	rows, err := r.db.QueryContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+	}
+	return rows.Err()
+}
+
+// detectDialect 根据驱动类型判断数据库类型，无法识别时按 MySQL 处理
+func detectDialect(db *sql.DB) string {
+	driverType := strings.ToLower(fmt.Sprintf("%T", db.Driver()))
+	if strings.Contains(driverType, "sqlite") {
+		return DialectSQLite
+	}
+	return DialectMySQL
+}
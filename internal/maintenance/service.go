@@ -0,0 +1,79 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+)
+
+// ErrOptimizeRunning 已有数据库维护任务在执行
+var ErrOptimizeRunning = apperror.New(apperror.KindConflict, "数据库维护正在执行，请稍后再试")
+
+// MaintenanceService 定义数据维护业务逻辑接口
+type MaintenanceService interface {
+	CleanupOrphanOrderItems(ctx context.Context, dryRun bool) (*OrphanCleanupResult, error)
+	OptimizeDatabase(ctx context.Context) (*OptimizeResult, error)
+}
+
+// maintenanceService 实现 MaintenanceService 接口
+type maintenanceService struct {
+	repo MaintenanceRepository
+
+	// optimizeMu 保证同一时间只有一个数据库维护任务
+	optimizeMu sync.Mutex
+}
+
+// NewMaintenanceService 创建 MaintenanceService 实例
+func NewMaintenanceService(repo MaintenanceRepository) MaintenanceService {
+	return &maintenanceService{repo: repo}
+}
+
+// CleanupOrphanOrderItems 检测孤立订单项，dryRun 为 false 时一并删除
+func (s *maintenanceService) CleanupOrphanOrderItems(ctx context.Context, dryRun bool) (*OrphanCleanupResult, error) {
+	items, err := s.repo.ListOrphanOrderItems(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("查询孤立订单项失败: %w", err)
+	}
+	if items == nil {
+		items = []*OrphanOrderItem{}
+	}
+
+	result := &OrphanCleanupResult{
+		DryRun: dryRun,
+		Found:  len(items),
+		Items:  items,
+	}
+
+	if dryRun || len(items) == 0 {
+		return result, nil
+	}
+
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+
+	deleted, err := s.repo.DeleteOrphanOrderItems(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("删除孤立订单项失败: %w", err)
+	}
+	result.Deleted = deleted
+
+	return result, nil
+}
+
+// OptimizeDatabase 执行数据库维护，已有任务在执行时直接返回 ErrOptimizeRunning
+func (s *maintenanceService) OptimizeDatabase(ctx context.Context) (*OptimizeResult, error) {
+	if !s.optimizeMu.TryLock() {
+		return nil, ErrOptimizeRunning
+	}
+	defer s.optimizeMu.Unlock()
+
+	result, err := s.repo.OptimizeTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("数据库维护失败: %w", err)
+	}
+	return result, nil
+}
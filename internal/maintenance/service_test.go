@@ -0,0 +1,193 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite 驱动
+)
+
+// setupMaintenanceTestDB 创建维护测试数据库（订单与订单项表）
+func setupMaintenanceTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+
+	createTablesSQL := `
+	CREATE TABLE IF NOT EXISTS orders (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_no TEXT UNIQUE NOT NULL,
+		user_id INTEGER NOT NULL,
+		address_id INTEGER NOT NULL,
+		total_amount INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS order_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_id INTEGER NOT NULL,
+		flower_sku TEXT NOT NULL,
+		flower_name TEXT NOT NULL,
+		quantity INTEGER NOT NULL,
+		unit_price INTEGER NOT NULL,
+		subtotal INTEGER NOT NULL
+	);
+	`
+
+	if _, err := db.Exec(createTablesSQL); err != nil {
+		db.Close()
+		t.Fatalf("failed to create tables: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	return db
+}
+
+// insertOrderItem 插入订单项（不校验订单是否存在，用于模拟孤立数据）
+func insertOrderItem(t *testing.T, db *sql.DB, orderID int, sku string) {
+	t.Helper()
+	_, err := db.Exec(`INSERT INTO order_items (order_id, flower_sku, flower_name, quantity, unit_price, subtotal)
+		VALUES (?, ?, ?, 1, 1000, 1000)`, orderID, sku, "鲜花"+sku)
+	if err != nil {
+		t.Fatalf("failed to insert order item: %v", err)
+	}
+}
+
+// TestMaintenanceService_CleanupOrphanOrderItems 测试孤立订单项检测与清理
+func TestMaintenanceService_CleanupOrphanOrderItems(t *testing.T) {
+	tests := []struct {
+		name        string
+		dryRun      bool
+		wantDeleted int
+		wantRemain  int // 清理后剩余的订单项数量
+	}{
+		{name: "dry-run 只报告", dryRun: true, wantDeleted: 0, wantRemain: 3},
+		{name: "实际删除孤立订单项", dryRun: false, wantDeleted: 2, wantRemain: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupMaintenanceTestDB(t)
+			ctx := context.Background()
+
+			if _, err := db.Exec(`INSERT INTO orders (id, order_no, user_id, address_id, total_amount, status)
+				VALUES (1, 'ORD001', 1, 1, 1000, 'pending')`); err != nil {
+				t.Fatalf("failed to insert order: %v", err)
+			}
+			insertOrderItem(t, db, 1, "FLW001")
+			insertOrderItem(t, db, 404, "FLW002")
+			insertOrderItem(t, db, 405, "FLW003")
+
+			service := NewMaintenanceService(NewMaintenanceRepository(db))
+			result, err := service.CleanupOrphanOrderItems(ctx, tt.dryRun)
+			if err != nil {
+				t.Fatalf("CleanupOrphanOrderItems() error = %v", err)
+			}
+
+			if result.Found != 2 || len(result.Items) != 2 {
+				t.Fatalf("CleanupOrphanOrderItems() found = %d, want 2", result.Found)
+			}
+			if result.Items[0].OrderID != 404 || result.Items[1].OrderID != 405 {
+				t.Errorf("orphan order ids = %d, %d, want 404, 405", result.Items[0].OrderID, result.Items[1].OrderID)
+			}
+			if result.DryRun != tt.dryRun || result.Deleted != tt.wantDeleted {
+				t.Errorf("result = (dry_run %v, deleted %d), want (%v, %d)", result.DryRun, result.Deleted, tt.dryRun, tt.wantDeleted)
+			}
+
+			var remain int
+			if err := db.QueryRow("SELECT COUNT(*) FROM order_items").Scan(&remain); err != nil {
+				t.Fatalf("failed to count order items: %v", err)
+			}
+			if remain != tt.wantRemain {
+				t.Errorf("remaining order items = %d, want %d", remain, tt.wantRemain)
+			}
+		})
+	}
+}
+
+// TestMaintenanceService_CleanupOrphanOrderItems_None 测试没有孤立订单项
+func TestMaintenanceService_CleanupOrphanOrderItems_None(t *testing.T) {
+	db := setupMaintenanceTestDB(t)
+	service := NewMaintenanceService(NewMaintenanceRepository(db))
+
+	result, err := service.CleanupOrphanOrderItems(context.Background(), false)
+	if err != nil {
+		t.Fatalf("CleanupOrphanOrderItems() error = %v", err)
+	}
+	if result.Found != 0 || result.Deleted != 0 || result.Items == nil {
+		t.Errorf("CleanupOrphanOrderItems() = %+v, want empty result with non-nil items", result)
+	}
+}
+
+// TestMaintenanceService_OptimizeDatabase_SQLite 测试 SQLite 下执行 VACUUM/ANALYZE
+func TestMaintenanceService_OptimizeDatabase_SQLite(t *testing.T) {
+	db := setupMaintenanceTestDB(t)
+	service := NewMaintenanceService(NewMaintenanceRepository(db))
+
+	result, err := service.OptimizeDatabase(context.Background())
+	if err != nil {
+		t.Fatalf("OptimizeDatabase() error = %v", err)
+	}
+	if result.Dialect != DialectSQLite {
+		t.Errorf("Dialect = %q, want %q", result.Dialect, DialectSQLite)
+	}
+	if len(result.Steps) != 2 || result.Steps[0].Statement != "VACUUM" || result.Steps[1].Statement != "ANALYZE" {
+		t.Errorf("Steps = %+v, want VACUUM then ANALYZE", result.Steps)
+	}
+}
+
+// blockingOptimizeRepository 在 OptimizeTables 中阻塞，用于测试并发保护
+type blockingOptimizeRepository struct {
+	MaintenanceRepository
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingOptimizeRepository) OptimizeTables(ctx context.Context) (*OptimizeResult, error) {
+	close(r.started)
+	<-r.release
+	return &OptimizeResult{Dialect: DialectSQLite}, nil
+}
+
+// TestMaintenanceService_OptimizeDatabase_Concurrent 测试维护任务不能并发执行
+func TestMaintenanceService_OptimizeDatabase_Concurrent(t *testing.T) {
+	repo := &blockingOptimizeRepository{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	service := NewMaintenanceService(repo)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.OptimizeDatabase(context.Background())
+		done <- err
+	}()
+	<-repo.started
+
+	if _, err := service.OptimizeDatabase(context.Background()); !errors.Is(err, ErrOptimizeRunning) {
+		t.Errorf("concurrent OptimizeDatabase() error = %v, want ErrOptimizeRunning", err)
+	}
+
+	close(repo.release)
+	if err := <-done; err != nil {
+		t.Fatalf("first OptimizeDatabase() error = %v", err)
+	}
+
+	// 前一个任务结束后可以再次执行
+	repo.started = make(chan struct{})
+	repo.release = make(chan struct{})
+	close(repo.release)
+	if _, err := service.OptimizeDatabase(context.Background()); err != nil {
+		t.Errorf("OptimizeDatabase() after release error = %v", err)
+	}
+}
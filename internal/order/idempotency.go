@@ -0,0 +1,96 @@
+package order
+
+import (
+	"sync"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+)
+
+// ErrIdempotencyInProgress 相同幂等键的下单请求仍在处理中
+var ErrIdempotencyInProgress = apperror.New(apperror.KindConflict, "相同幂等键的下单请求正在处理中")
+
+// IdempotencyCache 记录已处理的下单幂等键及其订单号，按用户隔离，超过 ttl 后过期
+// 仅保存在内存中：服务重启或多实例部署时不共享，用于拦截双击与网络重试造成的重复下单
+type IdempotencyCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[idempotencyKey]*idempotencyEntry
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+// idempotencyKey 幂等键按用户隔离，不同用户使用相同的键互不影响
+type idempotencyKey struct {
+	userID int
+	key    string
+}
+
+// idempotencyEntry 幂等键状态：orderNo 为空表示请求仍在处理中
+type idempotencyEntry struct {
+	orderNo   string
+	expiresAt time.Time
+}
+
+// NewIdempotencyCache 创建幂等键缓存，ttl 为键的有效期
+func NewIdempotencyCache(ttl time.Duration) *IdempotencyCache {
+	return &IdempotencyCache{
+		ttl:     ttl,
+		entries: make(map[idempotencyKey]*idempotencyEntry),
+		now:     time.Now,
+	}
+}
+
+// Reserve 占用幂等键
+// 键已完成时返回原订单号与 true；键仍在处理中返回 ErrIdempotencyInProgress；
+// 否则占用该键并返回 ("", false, nil)，调用方随后必须调用 Complete 或 Release
+func (c *IdempotencyCache) Reserve(userID int, key string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.sweep(now)
+
+	k := idempotencyKey{userID: userID, key: key}
+	if entry, ok := c.entries[k]; ok && now.Before(entry.expiresAt) {
+		if entry.orderNo == "" {
+			return "", false, ErrIdempotencyInProgress
+		}
+		return entry.orderNo, true, nil
+	}
+
+	c.entries[k] = &idempotencyEntry{expiresAt: now.Add(c.ttl)}
+	return "", false, nil
+}
+
+// Complete 记录幂等键对应的订单号，有效期从完成时开始计算
+func (c *IdempotencyCache) Complete(userID int, key, orderNo string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[idempotencyKey{userID: userID, key: key}] = &idempotencyEntry{
+		orderNo:   orderNo,
+		expiresAt: c.now().Add(c.ttl),
+	}
+}
+
+// Release 下单失败时释放占用的幂等键，允许使用同一键重试
+func (c *IdempotencyCache) Release(userID int, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, idempotencyKey{userID: userID, key: key})
+}
+
+// sweep 每个 ttl 周期清理一次过期的键，避免缓存无限增长；调用方需持有锁
+func (c *IdempotencyCache) sweep(now time.Time) {
+	if now.Before(c.nextSweep) {
+		return
+	}
+	for k, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.nextSweep = now.Add(c.ttl)
+}
@@ -0,0 +1,46 @@
+package order
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestIdempotencyCache 测试幂等键的占用、完成、释放与过期
+func TestIdempotencyCache(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	cache := NewIdempotencyCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	if _, done, err := cache.Reserve(1, "k1"); done || err != nil {
+		t.Fatalf("first Reserve() = (%v, %v), want (false, nil)", done, err)
+	}
+	// 处理中的键再次提交返回冲突
+	if _, _, err := cache.Reserve(1, "k1"); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("Reserve() while in progress error = %v, want ErrIdempotencyInProgress", err)
+	}
+	// 不同用户使用相同的键互不影响
+	if _, done, err := cache.Reserve(2, "k1"); done || err != nil {
+		t.Errorf("Reserve() by another user = (%v, %v), want (false, nil)", done, err)
+	}
+
+	cache.Complete(1, "k1", "ORD001")
+	if orderNo, done, err := cache.Reserve(1, "k1"); orderNo != "ORD001" || !done || err != nil {
+		t.Errorf("Reserve() after Complete = (%q, %v, %v), want (ORD001, true, nil)", orderNo, done, err)
+	}
+
+	// 释放后可以重新占用
+	cache.Release(2, "k1")
+	if _, done, err := cache.Reserve(2, "k1"); done || err != nil {
+		t.Errorf("Reserve() after Release = (%v, %v), want (false, nil)", done, err)
+	}
+
+	// 超过有效期后键失效，过期的键会被清理
+	now = now.Add(time.Minute)
+	if _, done, err := cache.Reserve(1, "k1"); done || err != nil {
+		t.Errorf("Reserve() after expiry = (%v, %v), want (false, nil)", done, err)
+	}
+	if len(cache.entries) != 1 {
+		t.Errorf("entries after sweep = %d, want 1", len(cache.entries))
+	}
+}
@@ -1,19 +1,35 @@
 package order
 
 import (
-	"fmt"
 	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 )
 
 // OrderLog 订单操作日志实体
 type OrderLog struct {
-	ID         int       `json:"id"`
-	OrderID    int       `json:"order_id"`
-	OperatorID int       `json:"operator_id"`
-	Action     string    `json:"action"`     // 操作类型：create_order, complete_order, cancel_order 等
-	OldStatus  OrderStatus `json:"old_status"` // 变更前状态
-	NewStatus  OrderStatus `json:"new_status"` // 变更后状态
-	CreatedAt  time.Time `json:"created_at"`
+	ID             int         `json:"id"`
+	OrderID        int         `json:"order_id"`
+	OperatorID     int         `json:"operator_id"`
+	Action         string      `json:"action"`                    // 操作类型：create_order, complete_order, cancel_order 等
+	OldStatus      OrderStatus `json:"old_status"`                // 变更前状态
+	NewStatus      OrderStatus `json:"new_status"`                // 变更后状态
+	Reason         string      `json:"reason,omitempty"`          // 操作原因，如取消原因，可为空
+	ImpersonatorID int         `json:"impersonator_id,omitempty"` // 代客登录时实际操作的管理员 ID，OperatorID 为被代理的顾客；非代客登录为 0
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+// MaxLogReasonLength 订单日志操作原因的最大字符数
+const MaxLogReasonLength = 255
+
+// OrderLogFilter 订单日志筛选与分页条件，零值表示不筛选、不分页
+type OrderLogFilter struct {
+	Action string // 按操作类型筛选，如 complete_order
+	// StartTime/EndTime 按记录时间筛选（包含边界），零值表示不限
+	StartTime time.Time
+	EndTime   time.Time
+	Page      int
+	PageSize  int
 }
 
 // NewOrderLog 创建订单日志
@@ -31,16 +47,19 @@ func NewOrderLog(orderID, operatorID int, action string, newStatus, oldStatus Or
 // Validate 验证订单日志数据
 func (l *OrderLog) Validate() error {
 	if l.OrderID <= 0 {
-		return fmt.Errorf("订单ID不能为空")
+		return apperror.Validation("订单ID不能为空")
 	}
 	if l.OperatorID <= 0 {
-		return fmt.Errorf("操作人ID不能为空")
+		return apperror.Validation("操作人ID不能为空")
 	}
 	if l.Action == "" {
-		return fmt.Errorf("操作类型不能为空")
+		return apperror.Validation("操作类型不能为空")
+	}
+	if len([]rune(l.Reason)) > MaxLogReasonLength {
+		return apperror.Validation("操作原因不能超过%d个字符", MaxLogReasonLength)
 	}
 	if l.NewStatus == "" {
-		return fmt.Errorf("新状态不能为空")
+		return apperror.Validation("新状态不能为空")
 	}
 	if err := l.NewStatus.Validate(); err != nil {
 		return err
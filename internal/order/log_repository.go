@@ -5,12 +5,15 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/auth"
 )
 
 // OrderLogRepository 定义订单日志数据访问接口
 type OrderLogRepository interface {
 	CreateLog(ctx context.Context, log *OrderLog) error
-	GetLogs(ctx context.Context, orderID int) ([]*OrderLog, error)
+	CreateLogTx(ctx context.Context, tx *sql.Tx, log *OrderLog) error
+	GetLogs(ctx context.Context, orderID int, filter OrderLogFilter) ([]*OrderLog, error)
 }
 
 // orderLogRepository 实现 OrderLogRepository 接口
@@ -25,15 +28,32 @@ func NewOrderLogRepository(db *sql.DB) OrderLogRepository {
 
 // CreateLog 创建订单日志
 func (r *orderLogRepository) CreateLog(ctx context.Context, log *OrderLog) error {
+	return createLog(ctx, r.db, log)
+}
+
+// CreateLogTx 在调用方事务中创建订单日志，使日志与业务操作一同提交或回滚
+func (r *orderLogRepository) CreateLogTx(ctx context.Context, tx *sql.Tx, log *OrderLog) error {
+	return createLog(ctx, tx, log)
+}
+
+// createLog 插入一条订单日志
+// 代客登录会话中的操作从上下文取得管理员 ID 一并记录，所有写日志的路径都经过这里，无需各自传递
+func createLog(ctx context.Context, exec execer, log *OrderLog) error {
 	log.CreatedAt = time.Now()
+	if adminID, ok := auth.ImpersonatorFromContext(ctx); ok && log.ImpersonatorID == 0 {
+		log.ImpersonatorID = adminID
+	}
 
 	query := `
-		INSERT INTO order_logs (order_id, operator_id, action, old_status, new_status, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO order_logs (order_id, operator_id, impersonator_id, action, old_status, new_status, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := r.db.ExecContext(ctx, query,
-		log.OrderID, log.OperatorID, log.Action, string(log.OldStatus), string(log.NewStatus), log.CreatedAt,
+	// 未填写原因、非代客登录时存为 NULL
+	reason := sql.NullString{String: log.Reason, Valid: log.Reason != ""}
+	impersonatorID := sql.NullInt64{Int64: int64(log.ImpersonatorID), Valid: log.ImpersonatorID != 0}
+	result, err := exec.ExecContext(ctx, query,
+		log.OrderID, log.OperatorID, impersonatorID, log.Action, string(log.OldStatus), string(log.NewStatus), reason, log.CreatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("create order log: %w", err)
@@ -48,15 +68,35 @@ func (r *orderLogRepository) CreateLog(ctx context.Context, log *OrderLog) error
 	return nil
 }
 
-// GetLogs 获取订单的所有日志
-func (r *orderLogRepository) GetLogs(ctx context.Context, orderID int) ([]*OrderLog, error) {
+// GetLogs 按筛选条件获取订单日志，按记录时间倒序（最新在前）
+func (r *orderLogRepository) GetLogs(ctx context.Context, orderID int, filter OrderLogFilter) ([]*OrderLog, error) {
 	query := `
-		SELECT id, order_id, operator_id, action, old_status, new_status, created_at
-		FROM order_logs WHERE order_id = ?
-		ORDER BY created_at ASC
-	`
+		SELECT id, order_id, operator_id, impersonator_id, action, old_status, new_status, reason, created_at
+		FROM order_logs WHERE order_id = ?`
+	args := []interface{}{orderID}
+
+	if filter.Action != "" {
+		query += " AND action = ?"
+		args = append(args, filter.Action)
+	}
+	if !filter.StartTime.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, filter.StartTime)
+	}
+	if !filter.EndTime.IsZero() {
+		query += " AND created_at <= ?"
+		args = append(args, filter.EndTime)
+	}
+
+	// 同一时刻写入的日志按 ID 倒序，保证分页稳定
+	query += " ORDER BY created_at DESC, id DESC"
+
+	if filter.Page > 0 && filter.PageSize > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	}
 
-	rows, err := r.db.QueryContext(ctx, query, orderID)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("get order logs: %w", err)
 	}
@@ -66,14 +106,18 @@ func (r *orderLogRepository) GetLogs(ctx context.Context, orderID int) ([]*Order
 	for rows.Next() {
 		var log OrderLog
 		var oldStatus, newStatus string
+		var reason sql.NullString
+		var impersonatorID sql.NullInt64
 
-		err := rows.Scan(&log.ID, &log.OrderID, &log.OperatorID, &log.Action, &oldStatus, &newStatus, &log.CreatedAt)
+		err := rows.Scan(&log.ID, &log.OrderID, &log.OperatorID, &impersonatorID, &log.Action, &oldStatus, &newStatus, &reason, &log.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("scan order log: %w", err)
 		}
 
 		log.OldStatus = OrderStatus(oldStatus)
 		log.NewStatus = OrderStatus(newStatus)
+		log.Reason = reason.String
+		log.ImpersonatorID = int(impersonatorID.Int64)
 
 		logs = append(logs, &log)
 	}
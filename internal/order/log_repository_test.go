@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
+	"github.com/biqiangwu/flowerSalesSystem/internal/auth"
 	_ "github.com/mattn/go-sqlite3" // SQLite 驱动
 )
 
@@ -23,9 +25,11 @@ func setupTestDB(t *testing.T) *sql.DB {
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		order_id INTEGER NOT NULL,
 		operator_id INTEGER NOT NULL,
+		impersonator_id INTEGER,
 		action TEXT NOT NULL,
 		old_status TEXT,
 		new_status TEXT NOT NULL,
+		reason TEXT,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
 		FOREIGN KEY (operator_id) REFERENCES users(id)
@@ -98,6 +102,60 @@ func TestOrderLogRepository_CreateLog(t *testing.T) {
 	}
 }
 
+// TestOrderLogRepository_Reason 测试操作原因的存取，未填写原因时读取为空
+func TestOrderLogRepository_Reason(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewOrderLogRepository(db)
+	ctx := context.Background()
+
+	withReason := NewOrderLog(1, 2, "cancel_order", StatusCancelled, StatusPending)
+	withReason.Reason = "顾客要求取消"
+	if err := repo.CreateLog(ctx, withReason); err != nil {
+		t.Fatalf("CreateLog() error = %v", err)
+	}
+	if err := repo.CreateLog(ctx, NewOrderLog(1, 2, "ship_order", StatusShipped, StatusPending)); err != nil {
+		t.Fatalf("CreateLog() error = %v", err)
+	}
+
+	logs, err := repo.GetLogs(ctx, 1, OrderLogFilter{})
+	if err != nil {
+		t.Fatalf("GetLogs() error = %v", err)
+	}
+	reasons := make(map[string]string)
+	for _, l := range logs {
+		reasons[l.Action] = l.Reason
+	}
+	if reasons["cancel_order"] != "顾客要求取消" || reasons["ship_order"] != "" {
+		t.Errorf("reasons = %v, want cancel_order=顾客要求取消 and empty ship_order", reasons)
+	}
+}
+
+// TestOrderLogRepository_Impersonator 测试代客登录会话中写入的日志记录实际操作的管理员
+func TestOrderLogRepository_Impersonator(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewOrderLogRepository(db)
+	ctx := context.Background()
+
+	if err := repo.CreateLog(auth.WithImpersonator(ctx, 9), NewOrderLog(1, 2, "cancel_order", StatusCancelled, StatusPending)); err != nil {
+		t.Fatalf("CreateLog() error = %v", err)
+	}
+	if err := repo.CreateLog(ctx, NewOrderLog(1, 2, "ship_order", StatusShipped, StatusPending)); err != nil {
+		t.Fatalf("CreateLog() error = %v", err)
+	}
+
+	logs, err := repo.GetLogs(ctx, 1, OrderLogFilter{})
+	if err != nil {
+		t.Fatalf("GetLogs() error = %v", err)
+	}
+	impersonators := make(map[string]int)
+	for _, l := range logs {
+		impersonators[l.Action] = l.ImpersonatorID
+	}
+	if impersonators["cancel_order"] != 9 || impersonators["ship_order"] != 0 {
+		t.Errorf("impersonators = %v, want cancel_order=9 and ship_order=0", impersonators)
+	}
+}
+
 // TestOrderLogRepository_GetLogs 测试获取日志
 func TestOrderLogRepository_GetLogs(t *testing.T) {
 	if testing.Short() {
@@ -156,7 +214,7 @@ func TestOrderLogRepository_GetLogs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := repo.GetLogs(ctx, tt.orderID)
+			result, err := repo.GetLogs(ctx, tt.orderID, OrderLogFilter{})
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetLogs() error = %v, wantErr %v", err, tt.wantErr)
@@ -171,3 +229,72 @@ func TestOrderLogRepository_GetLogs(t *testing.T) {
 		})
 	}
 }
+
+// TestOrderLogRepository_GetLogs_FilterAndPage 测试按操作类型、时间筛选与分页获取日志
+func TestOrderLogRepository_GetLogs_FilterAndPage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupTestDB(t)
+	repo := NewOrderLogRepository(db)
+	ctx := context.Background()
+
+	// 25 条日志按分钟递增，操作人 ID 为 i+1 便于识别；每 5 条中有 1 条发货日志
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 25; i++ {
+		action := "update_order"
+		if i%5 == 0 {
+			action = "ship_order"
+		}
+		if _, err := db.Exec(
+			"INSERT INTO order_logs (order_id, operator_id, action, old_status, new_status, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+			1, i+1, action, string(StatusPending), string(StatusShipped), base.Add(time.Duration(i)*time.Minute),
+		); err != nil {
+			t.Fatalf("failed to insert log: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		filter        OrderLogFilter
+		wantOperators []int // 期望结果的操作人 ID（最新在前），为空时只校验数量
+		wantCount     int
+	}{
+		{name: "不筛选返回全部", filter: OrderLogFilter{}, wantCount: 25},
+		{name: "按操作类型筛选", filter: OrderLogFilter{Action: "ship_order"}, wantOperators: []int{21, 16, 11, 6, 1}},
+		{name: "第一页最新在前", filter: OrderLogFilter{Page: 1, PageSize: 3}, wantOperators: []int{25, 24, 23}},
+		{name: "最后一页不足一页", filter: OrderLogFilter{Page: 3, PageSize: 10}, wantCount: 5},
+		{name: "操作类型与分页组合", filter: OrderLogFilter{Action: "ship_order", Page: 2, PageSize: 2}, wantOperators: []int{11, 6}},
+		{name: "超出页数返回空", filter: OrderLogFilter{Page: 4, PageSize: 10}, wantCount: 0},
+		{
+			name:          "按时间范围筛选",
+			filter:        OrderLogFilter{StartTime: base.Add(10 * time.Minute), EndTime: base.Add(12 * time.Minute)},
+			wantOperators: []int{13, 12, 11},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.GetLogs(ctx, 1, tt.filter)
+			if err != nil {
+				t.Fatalf("GetLogs() error = %v", err)
+			}
+
+			if tt.wantOperators == nil {
+				if len(got) != tt.wantCount {
+					t.Errorf("GetLogs() count = %d, want %d", len(got), tt.wantCount)
+				}
+				return
+			}
+			if len(got) != len(tt.wantOperators) {
+				t.Fatalf("GetLogs() count = %d, want %d", len(got), len(tt.wantOperators))
+			}
+			for i, log := range got {
+				if log.OperatorID != tt.wantOperators[i] {
+					t.Errorf("GetLogs()[%d].OperatorID = %d, want %d", i, log.OperatorID, tt.wantOperators[i])
+				}
+			}
+		})
+	}
+}
@@ -3,12 +3,14 @@ package order
 import (
 	"context"
 	"fmt"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 )
 
 // OrderLogService 定义订单日志服务接口
 type OrderLogService interface {
 	LogOrderAction(ctx context.Context, orderID, operatorID int, action string, oldStatus, newStatus OrderStatus) error
-	GetOrderLogs(ctx context.Context, orderID int) ([]*OrderLog, error)
+	GetOrderLogs(ctx context.Context, orderID int, filter OrderLogFilter) ([]*OrderLog, error)
 }
 
 // orderLogService 实现 OrderLogService 接口
@@ -39,15 +41,21 @@ func (s *orderLogService) LogOrderAction(ctx context.Context, orderID, operatorI
 	return nil
 }
 
-// GetOrderLogs 获取订单的所有操作日志
-func (s *orderLogService) GetOrderLogs(ctx context.Context, orderID int) ([]*OrderLog, error) {
+// GetOrderLogs 按筛选条件获取订单的操作日志，最新在前
+func (s *orderLogService) GetOrderLogs(ctx context.Context, orderID int, filter OrderLogFilter) ([]*OrderLog, error) {
 	// 验证订单ID
 	if orderID <= 0 {
-		return nil, fmt.Errorf("订单ID无效")
+		return nil, apperror.Validation("订单ID无效")
+	}
+	if filter.Page < 0 || filter.PageSize < 0 {
+		return nil, apperror.Validation("分页参数不能为负数")
+	}
+	if !filter.StartTime.IsZero() && !filter.EndTime.IsZero() && filter.EndTime.Before(filter.StartTime) {
+		return nil, apperror.Validation("结束时间不能早于开始时间")
 	}
 
 	// 查询日志
-	logs, err := s.logRepo.GetLogs(ctx, orderID)
+	logs, err := s.logRepo.GetLogs(ctx, orderID, filter)
 	if err != nil {
 		return nil, fmt.Errorf("查询订单日志: %w", err)
 	}
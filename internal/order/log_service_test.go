@@ -3,6 +3,9 @@ package order
 import (
 	"context"
 	"testing"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 
 	_ "github.com/mattn/go-sqlite3" // SQLite 驱动
 )
@@ -115,7 +118,7 @@ func TestOrderLogService_LogOrderAction(t *testing.T) {
 				}
 			} else {
 				// 验证日志已记录
-				logs, getErr := logRepo.GetLogs(ctx, tt.orderID)
+				logs, getErr := logRepo.GetLogs(ctx, tt.orderID, OrderLogFilter{})
 				if getErr != nil {
 					t.Errorf("failed to verify log: %v", getErr)
 				}
@@ -200,7 +203,7 @@ func TestOrderLogService_GetOrderLogs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			logs, err := service.GetOrderLogs(ctx, tt.orderID)
+			logs, err := service.GetOrderLogs(ctx, tt.orderID, OrderLogFilter{})
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetOrderLogs() error = %v, wantErr %v", err, tt.wantErr)
@@ -223,6 +226,29 @@ func TestOrderLogService_GetOrderLogs(t *testing.T) {
 	}
 }
 
+// TestOrderLogService_GetOrderLogs_InvalidFilter 测试无效的日志筛选条件返回校验错误
+func TestOrderLogService_GetOrderLogs_InvalidFilter(t *testing.T) {
+	service := NewOrderLogService(NewOrderLogRepository(setupTestDB(t)))
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		filter OrderLogFilter
+	}{
+		{name: "页码为负", filter: OrderLogFilter{Page: -1}},
+		{name: "每页数量为负", filter: OrderLogFilter{PageSize: -10}},
+		{name: "结束时间早于开始时间", filter: OrderLogFilter{StartTime: now, EndTime: now.Add(-time.Hour)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.GetOrderLogs(context.Background(), 1, tt.filter); apperror.KindOf(err) != apperror.KindValidation {
+				t.Errorf("GetOrderLogs() error = %v, want validation error", err)
+			}
+		})
+	}
+}
+
 // min 返回两个整数中的最小值
 func min(a, b int) int {
 	if a < b {
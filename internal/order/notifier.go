@@ -0,0 +1,70 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Notifier 订单状态变更通知，例如在发货、完成或取消时通知顾客
+// 通知在状态变更提交之后调用，返回错误只记录日志，不会回滚状态变更
+type Notifier interface {
+	OrderStatusChanged(ctx context.Context, order *Order, oldStatus, newStatus OrderStatus) error
+}
+
+// 通知方式
+const (
+	NotifierNone = "none" // 不通知（默认）
+	NotifierLog  = "log"  // 写入日志，用于调试或由日志采集转发
+)
+
+// NewNotifier 按名称创建通知实现，空字符串视为 none
+func NewNotifier(kind string) (Notifier, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "", NotifierNone:
+		return NewNoopNotifier(), nil
+	case NotifierLog:
+		return NewLogNotifier(), nil
+	default:
+		return nil, fmt.Errorf("无效的通知方式: %s（可选 none/log）", kind)
+	}
+}
+
+// noopNotifier 不做任何通知
+type noopNotifier struct{}
+
+// NewNoopNotifier 创建不做任何通知的 Notifier
+func NewNoopNotifier() Notifier {
+	return noopNotifier{}
+}
+
+// OrderStatusChanged 忽略状态变更
+func (noopNotifier) OrderStatusChanged(ctx context.Context, order *Order, oldStatus, newStatus OrderStatus) error {
+	return nil
+}
+
+// logNotifier 将状态变更写入日志
+type logNotifier struct{}
+
+// NewLogNotifier 创建将状态变更写入日志的 Notifier
+func NewLogNotifier() Notifier {
+	return logNotifier{}
+}
+
+// OrderStatusChanged 记录一条状态变更日志
+func (logNotifier) OrderStatusChanged(ctx context.Context, order *Order, oldStatus, newStatus OrderStatus) error {
+	slog.InfoContext(ctx, "order status changed", "order_id", order.ID, "order_no", order.OrderNo,
+		"user_id", order.UserID, "old_status", oldStatus, "new_status", newStatus)
+	return nil
+}
+
+// notifyStatusChanged 调用通知实现，失败只记录警告
+func (s *orderService) notifyStatusChanged(ctx context.Context, order *Order, oldStatus, newStatus OrderStatus) {
+	changed := *order
+	changed.Status = newStatus
+	if err := s.notifier.OrderStatusChanged(ctx, &changed, oldStatus, newStatus); err != nil {
+		slog.Warn("failed to notify order status change", "order_id", order.ID,
+			"old_status", oldStatus, "new_status", newStatus, "error", err)
+	}
+}
@@ -0,0 +1,135 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/address"
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+)
+
+// statusChange 一次状态变更通知
+type statusChange struct {
+	orderID   int
+	status    OrderStatus
+	oldStatus OrderStatus
+	newStatus OrderStatus
+}
+
+// recordingNotifier 记录收到的通知，可配置返回错误
+type recordingNotifier struct {
+	changes []statusChange
+	err     error
+}
+
+func (n *recordingNotifier) OrderStatusChanged(ctx context.Context, order *Order, oldStatus, newStatus OrderStatus) error {
+	n.changes = append(n.changes, statusChange{orderID: order.ID, status: order.Status, oldStatus: oldStatus, newStatus: newStatus})
+	return n.err
+}
+
+// TestOrderService_Notifier 测试发货、完成与取消时调用通知，通知失败不影响状态变更
+func TestOrderService_Notifier(t *testing.T) {
+	tests := []struct {
+		name        string
+		notifyErr   error
+		transitions func(ctx context.Context, s OrderService, orderID int) error
+		want        []statusChange
+		wantStatus  OrderStatus
+	}{
+		{
+			name: "发货后完成",
+			transitions: func(ctx context.Context, s OrderService, orderID int) error {
+				if err := s.ShipOrder(ctx, orderID, 1); err != nil {
+					return err
+				}
+				return s.CompleteOrder(ctx, orderID, 1, user.RoleAdmin)
+			},
+			want: []statusChange{
+				{status: StatusShipped, oldStatus: StatusPending, newStatus: StatusShipped},
+				{status: StatusCompleted, oldStatus: StatusShipped, newStatus: StatusCompleted},
+			},
+			wantStatus: StatusCompleted,
+		},
+		{
+			name: "取消",
+			transitions: func(ctx context.Context, s OrderService, orderID int) error {
+				return s.CancelOrder(ctx, orderID, 1, user.RoleCustomer, "")
+			},
+			want:       []statusChange{{status: StatusCancelled, oldStatus: StatusPending, newStatus: StatusCancelled}},
+			wantStatus: StatusCancelled,
+		},
+		{
+			name:      "通知失败不回滚",
+			notifyErr: errors.New("smtp unavailable"),
+			transitions: func(ctx context.Context, s OrderService, orderID int) error {
+				return s.ShipOrder(ctx, orderID, 1)
+			},
+			want:       []statusChange{{status: StatusShipped, oldStatus: StatusPending, newStatus: StatusShipped}},
+			wantStatus: StatusShipped,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t)
+			ctx := context.Background()
+
+			insertTestUser(t, db, 1, "user1")
+			insertTestAddress(t, db, 1, 1)
+			insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+			notifier := &recordingNotifier{err: tt.notifyErr}
+			orderRepo := NewOrderRepository(db)
+			service := NewOrderServiceWithOptions(orderRepo, flower.NewFlowerRepository(db), NewOrderLogRepository(db),
+				OrderServiceOptions{AddressRepo: address.NewAddressRepository(db), Notifier: notifier})
+
+			orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+				AddressID: 1,
+				Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+			})
+			if err != nil {
+				t.Fatalf("CreateOrder() error = %v", err)
+			}
+			order, _, err := orderRepo.GetByOrderNo(ctx, orderNo)
+			if err != nil {
+				t.Fatalf("GetByOrderNo() error = %v", err)
+			}
+
+			if err := tt.transitions(ctx, service, order.ID); err != nil {
+				t.Fatalf("transition error = %v", err)
+			}
+
+			if len(notifier.changes) != len(tt.want) {
+				t.Fatalf("notifications = %+v, want %+v", notifier.changes, tt.want)
+			}
+			for i, want := range tt.want {
+				want.orderID = order.ID
+				if notifier.changes[i] != want {
+					t.Errorf("notification[%d] = %+v, want %+v", i, notifier.changes[i], want)
+				}
+			}
+
+			updated, _, err := orderRepo.GetByID(ctx, order.ID)
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			if updated.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", updated.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// TestNewNotifier 测试按名称创建通知实现
+func TestNewNotifier(t *testing.T) {
+	for _, kind := range []string{"", "none", "LOG", " log "} {
+		if _, err := NewNotifier(kind); err != nil {
+			t.Errorf("NewNotifier(%q) error = %v", kind, err)
+		}
+	}
+	if _, err := NewNotifier("email"); err == nil {
+		t.Error("NewNotifier(\"email\") expected error, got nil")
+	}
+}
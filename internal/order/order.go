@@ -5,6 +5,7 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
 )
 
@@ -14,6 +15,7 @@ type OrderStatus string
 // 订单状态常量
 const (
 	StatusPending   OrderStatus = "pending"   // 待处理
+	StatusShipped   OrderStatus = "shipped"   // 已发货（配送中）
 	StatusCompleted OrderStatus = "completed" // 已完成
 	StatusCancelled OrderStatus = "cancelled" // 已取消
 )
@@ -21,44 +23,80 @@ const (
 // Validate 验证订单状态是否有效
 func (s OrderStatus) Validate() error {
 	switch s {
-	case StatusPending, StatusCompleted, StatusCancelled:
+	case StatusPending, StatusShipped, StatusCompleted, StatusCancelled:
 		return nil
 	default:
-		return fmt.Errorf("无效的订单状态: %s", s)
+		return apperror.Validation("无效的订单状态: %s", s)
 	}
 }
 
 // Order 订单实体
 type Order struct {
-	ID          int                  `json:"id"`
-	OrderNo     string               `json:"order_no"`
-	UserID      int                  `json:"user_id"`
-	AddressID   int                  `json:"address_id"`
-	TotalAmount flower.Decimal       `json:"total_amount"`
-	Status      OrderStatus          `json:"status"`
-	CreatedAt   time.Time            `json:"created_at"`
-	UpdatedAt   time.Time            `json:"updated_at"`
-	Items       []*OrderItem         `json:"items,omitempty"` // 订单项（可选）
+	ID          int            `json:"id"`
+	OrderNo     string         `json:"order_no"`
+	UserID      int            `json:"user_id"`
+	AddressID   int            `json:"address_id"`
+	TotalAmount flower.Decimal `json:"total_amount"`
+	// DiscountID 下单时使用的优惠码，DiscountAmount 为减免金额，已计入 TotalAmount
+	DiscountID     *int           `json:"discount_id,omitempty"`
+	DiscountAmount flower.Decimal `json:"discount_amount"`
+	Status         OrderStatus    `json:"status"`
+	ReservedAt     *time.Time     `json:"reserved_at,omitempty"` // 库存预留时间（下单或结算时）
+	Version        int            `json:"version"`               // 乐观锁版本，每次更新订单时递增
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	Items          []*OrderItem   `json:"items,omitempty"` // 订单项（可选）
 }
 
 // OrderItem 订单项实体
 type OrderItem struct {
-	ID          int            `json:"id"`
-	OrderID     int            `json:"order_id"`
-	FlowerSKU   string         `json:"flower_sku"`
-	FlowerName  string         `json:"flower_name"`
-	Quantity    int            `json:"quantity"`
-	UnitPrice   flower.Decimal `json:"unit_price"`
-	Subtotal    flower.Decimal `json:"subtotal"`
+	ID         int            `json:"id"`
+	OrderID    int            `json:"order_id"`
+	FlowerSKU  string         `json:"flower_sku"`
+	FlowerName string         `json:"flower_name"`
+	Quantity   int            `json:"quantity"`
+	UnitPrice  flower.Decimal `json:"unit_price"`
+	Subtotal   flower.Decimal `json:"subtotal"`
 }
 
 // OrderFilter 订单筛选条件
 type OrderFilter struct {
-	UserID  int        // 按用户筛选
-	Status  string     // 按状态筛选
-	OrderNo string     // 按订单号筛选
-	Page    int
+	UserID  int    // 按用户筛选
+	Status  string // 按状态筛选
+	OrderNo string // 按订单号筛选
+	// StartTime/EndTime 按下单时间筛选（包含边界），零值表示不限
+	StartTime time.Time
+	EndTime   time.Time
+	// SortBy 排序方式，见 OrderSortOptions，为空时按下单时间倒序
+	SortBy   string
+	Page     int
 	PageSize int
+	// LookAhead 分页时多取一行，用于判断是否还有下一页
+	LookAhead bool
+}
+
+// orderSortClauses 订单列表允许的排序方式及对应的 ORDER BY 子句
+// 排序只能从此白名单中选择，请求参数不会拼接进 SQL；按 id 兜底保证分页稳定
+var orderSortClauses = map[string]string{
+	"created_at_asc":    "created_at ASC, id ASC",
+	"created_at_desc":   "created_at DESC, id DESC",
+	"total_amount_asc":  "total_amount ASC, id ASC",
+	"total_amount_desc": "total_amount DESC, id DESC",
+	"status_asc":        "status ASC, id DESC",
+	"status_desc":       "status DESC, id DESC",
+}
+
+// OrderSortOptions 订单列表支持的排序方式
+func OrderSortOptions() []string {
+	return []string{"created_at_asc", "created_at_desc", "total_amount_asc", "total_amount_desc", "status_asc", "status_desc"}
+}
+
+// orderSortClause 返回排序方式对应的 ORDER BY 子句，未知或为空时按下单时间倒序
+func orderSortClause(sortBy string) string {
+	if clause, ok := orderSortClauses[sortBy]; ok {
+		return clause
+	}
+	return "created_at DESC"
 }
 
 // NewOrder 创建新订单
@@ -90,19 +128,19 @@ func NewOrderItem(orderID int, flowerSKU, flowerName string, quantity int, unitP
 // Validate 验证订单数据
 func (o *Order) Validate() error {
 	if o.OrderNo == "" {
-		return fmt.Errorf("订单编号不能为空")
+		return apperror.Validation("订单编号不能为空")
 	}
 	if o.UserID <= 0 {
-		return fmt.Errorf("用户ID不能为空")
+		return apperror.Validation("用户ID不能为空")
 	}
 	if o.AddressID <= 0 {
-		return fmt.Errorf("地址ID不能为空")
+		return apperror.Validation("地址ID不能为空")
 	}
 	if o.TotalAmount.Value < 0 {
-		return fmt.Errorf("订单金额不能为负数")
+		return apperror.Validation("订单金额不能为负数")
 	}
 	if o.Status == "" {
-		return fmt.Errorf("订单状态不能为空")
+		return apperror.Validation("订单状态不能为空")
 	}
 	if err := o.Status.Validate(); err != nil {
 		return err
@@ -113,21 +151,21 @@ func (o *Order) Validate() error {
 // Validate 验证订单项数据
 func (i *OrderItem) Validate() error {
 	if i.FlowerSKU == "" {
-		return fmt.Errorf("鲜花SKU不能为空")
+		return apperror.Validation("鲜花SKU不能为空")
 	}
 	if i.FlowerName == "" {
-		return fmt.Errorf("鲜花名称不能为空")
+		return apperror.Validation("鲜花名称不能为空")
 	}
 	if i.Quantity <= 0 {
-		return fmt.Errorf("数量必须大于0")
+		return apperror.Validation("数量必须大于0")
 	}
 	if i.UnitPrice.Value < 0 {
-		return fmt.Errorf("单价不能为负数")
+		return apperror.Validation("单价不能为负数")
 	}
 	// 验证小计是否正确
 	expectedSubtotal := i.UnitPrice.Value * int64(i.Quantity)
 	if i.Subtotal.Value != expectedSubtotal {
-		return fmt.Errorf("小计金额不正确: expected %d, got %d", expectedSubtotal, i.Subtotal.Value)
+		return apperror.Validation("小计金额不正确: expected %d, got %d", expectedSubtotal, i.Subtotal.Value)
 	}
 	return nil
 }
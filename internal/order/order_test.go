@@ -1,6 +1,7 @@
 package order
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
@@ -161,32 +162,32 @@ func TestOrderValidation(t *testing.T) {
 
 func TestOrderItemValidation(t *testing.T) {
 	tests := []struct {
-		name     string
-		item     *OrderItem
-		wantErr  bool
-		errMsg   string
+		name    string
+		item    *OrderItem
+		wantErr bool
+		errMsg  string
 	}{
 		{
 			name: "valid order item",
 			item: &OrderItem{
-				OrderID:     1,
-				FlowerSKU:   "FLW001",
-				FlowerName:  "红玫瑰",
-				Quantity:    10,
-				UnitPrice:   flower.Decimal{Value: 1000},
-				Subtotal:    flower.Decimal{Value: 10000},
+				OrderID:    1,
+				FlowerSKU:  "FLW001",
+				FlowerName: "红玫瑰",
+				Quantity:   10,
+				UnitPrice:  flower.Decimal{Value: 1000},
+				Subtotal:   flower.Decimal{Value: 10000},
 			},
 			wantErr: false,
 		},
 		{
 			name: "invalid - empty flower SKU",
 			item: &OrderItem{
-				OrderID:     1,
-				FlowerSKU:   "",
-				FlowerName:  "红玫瑰",
-				Quantity:    10,
-				UnitPrice:   flower.Decimal{Value: 1000},
-				Subtotal:    flower.Decimal{Value: 10000},
+				OrderID:    1,
+				FlowerSKU:  "",
+				FlowerName: "红玫瑰",
+				Quantity:   10,
+				UnitPrice:  flower.Decimal{Value: 1000},
+				Subtotal:   flower.Decimal{Value: 10000},
 			},
 			wantErr: true,
 			errMsg:  "鲜花SKU不能为空",
@@ -194,12 +195,12 @@ func TestOrderItemValidation(t *testing.T) {
 		{
 			name: "invalid - empty flower name",
 			item: &OrderItem{
-				OrderID:     1,
-				FlowerSKU:   "FLW001",
-				FlowerName:  "",
-				Quantity:    10,
-				UnitPrice:   flower.Decimal{Value: 1000},
-				Subtotal:    flower.Decimal{Value: 10000},
+				OrderID:    1,
+				FlowerSKU:  "FLW001",
+				FlowerName: "",
+				Quantity:   10,
+				UnitPrice:  flower.Decimal{Value: 1000},
+				Subtotal:   flower.Decimal{Value: 10000},
 			},
 			wantErr: true,
 			errMsg:  "鲜花名称不能为空",
@@ -207,12 +208,12 @@ func TestOrderItemValidation(t *testing.T) {
 		{
 			name: "invalid - quantity is 0",
 			item: &OrderItem{
-				OrderID:     1,
-				FlowerSKU:   "FLW001",
-				FlowerName:  "红玫瑰",
-				Quantity:    0,
-				UnitPrice:   flower.Decimal{Value: 1000},
-				Subtotal:    flower.Decimal{Value: 0},
+				OrderID:    1,
+				FlowerSKU:  "FLW001",
+				FlowerName: "红玫瑰",
+				Quantity:   0,
+				UnitPrice:  flower.Decimal{Value: 1000},
+				Subtotal:   flower.Decimal{Value: 0},
 			},
 			wantErr: true,
 			errMsg:  "数量必须大于0",
@@ -220,12 +221,12 @@ func TestOrderItemValidation(t *testing.T) {
 		{
 			name: "invalid - negative quantity",
 			item: &OrderItem{
-				OrderID:     1,
-				FlowerSKU:   "FLW001",
-				FlowerName:  "红玫瑰",
-				Quantity:    -10,
-				UnitPrice:   flower.Decimal{Value: 1000},
-				Subtotal:    flower.Decimal{Value: -10000},
+				OrderID:    1,
+				FlowerSKU:  "FLW001",
+				FlowerName: "红玫瑰",
+				Quantity:   -10,
+				UnitPrice:  flower.Decimal{Value: 1000},
+				Subtotal:   flower.Decimal{Value: -10000},
 			},
 			wantErr: true,
 			errMsg:  "数量必须大于0",
@@ -233,12 +234,12 @@ func TestOrderItemValidation(t *testing.T) {
 		{
 			name: "invalid - unit price is negative",
 			item: &OrderItem{
-				OrderID:     1,
-				FlowerSKU:   "FLW001",
-				FlowerName:  "红玫瑰",
-				Quantity:    10,
-				UnitPrice:   flower.Decimal{Value: -1000},
-				Subtotal:    flower.Decimal{Value: -10000},
+				OrderID:    1,
+				FlowerSKU:  "FLW001",
+				FlowerName: "红玫瑰",
+				Quantity:   10,
+				UnitPrice:  flower.Decimal{Value: -1000},
+				Subtotal:   flower.Decimal{Value: -10000},
 			},
 			wantErr: true,
 			errMsg:  "单价不能为负数",
@@ -246,12 +247,12 @@ func TestOrderItemValidation(t *testing.T) {
 		{
 			name: "invalid - subtotal mismatch",
 			item: &OrderItem{
-				OrderID:     1,
-				FlowerSKU:   "FLW001",
-				FlowerName:  "红玫瑰",
-				Quantity:    10,
-				UnitPrice:   flower.Decimal{Value: 1000},
-				Subtotal:    flower.Decimal{Value: 5000}, // 应该是 10000
+				OrderID:    1,
+				FlowerSKU:  "FLW001",
+				FlowerName: "红玫瑰",
+				Quantity:   10,
+				UnitPrice:  flower.Decimal{Value: 1000},
+				Subtotal:   flower.Decimal{Value: 5000}, // 应该是 10000
 			},
 			wantErr: true,
 			errMsg:  "小计金额不正确: expected 10000, got 5000",
@@ -315,3 +316,28 @@ func TestNewOrderItem(t *testing.T) {
 		t.Errorf("NewOrderItem() Subtotal = %d, want 10000", item.Subtotal.Value)
 	}
 }
+
+// TestStats_Concurrent 测试计数器并发更新安全
+func TestStats_Concurrent(t *testing.T) {
+	stats := NewStats()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats.recordCreated()
+			stats.recordCompleted(flower.Decimal{Value: 150})
+			stats.recordCancelled()
+		}()
+	}
+	wg.Wait()
+
+	got := stats.Snapshot()
+	if got.OrdersCreated != 50 || got.OrdersCompleted != 50 || got.OrdersCancelled != 50 {
+		t.Errorf("counters = %+v, want 50 each", got)
+	}
+	if got.RevenueCents != 7500 {
+		t.Errorf("RevenueCents = %d, want 7500", got.RevenueCents)
+	}
+}
@@ -0,0 +1,28 @@
+package order
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RunReservationReaper 每隔 interval 释放一次库存预留超过 ttl 的待处理订单，直到 ctx 取消
+func RunReservationReaper(ctx context.Context, svc OrderService, ttl, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := svc.ReleaseExpiredReservations(ctx, ttl)
+			if err != nil {
+				slog.Warn("failed to release expired reservations", "error", err)
+			}
+			if released > 0 {
+				slog.Info("released expired reservations", "count", released)
+			}
+		}
+	}
+}
@@ -4,18 +4,36 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
 )
 
+// ErrOrderNotFound 订单不存在
+var ErrOrderNotFound = apperror.New(apperror.KindNotFound, "order not found")
+
+// ErrOrderVersionConflict 订单已被其他请求修改，需重新获取后再操作
+var ErrOrderVersionConflict = apperror.New(apperror.KindConflict, "order was modified by another request")
+
 // OrderRepository 定义订单数据访问接口
 type OrderRepository interface {
 	Create(ctx context.Context, order *Order, items []*OrderItem) error
+	CreateTx(ctx context.Context, tx *sql.Tx, order *Order, items []*OrderItem) error
 	GetByID(ctx context.Context, id int) (*Order, []*OrderItem, error)
 	GetByOrderNo(ctx context.Context, orderNo string) (*Order, []*OrderItem, error)
 	List(ctx context.Context, filter OrderFilter) ([]*Order, error)
-	UpdateStatus(ctx context.Context, id int, status OrderStatus) error
+	Count(ctx context.Context, filter OrderFilter) (int, error)
+	CountByStatus(ctx context.Context, userID int) (map[string]int, error)
+	ListItemsByOrderIDs(ctx context.Context, orderIDs []int) (map[int][]*OrderItem, error)
+	UpdateStatus(ctx context.Context, id int, status OrderStatus, version int) error
+	BeginTx(ctx context.Context) (*sql.Tx, error)
+	UpdateStatusTx(ctx context.Context, tx *sql.Tx, id int, from, to OrderStatus) error
+	ReplaceItemsTx(ctx context.Context, tx *sql.Tx, order *Order, items []*OrderItem) error
+	RemoveItemTx(ctx context.Context, tx *sql.Tx, orderID, itemID int, status OrderStatus, totalAmount flower.Decimal) error
+	UpdateItemQuantityTx(ctx context.Context, tx *sql.Tx, orderID, itemID, quantity int, subtotal flower.Decimal, status OrderStatus, totalAmount flower.Decimal) error
+	ListExpiredReservations(ctx context.Context, reservedBefore time.Time, afterID, limit int) ([]int, error)
 }
 
 // orderRepository 实现 OrderRepository 接口
@@ -28,6 +46,11 @@ func NewOrderRepository(db *sql.DB) OrderRepository {
 	return &orderRepository{db: db}
 }
 
+// execer 可执行写操作的对象（*sql.DB 或 *sql.Tx）
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
 // Create 创建订单及订单项（需要事务处理）
 func (r *orderRepository) Create(ctx context.Context, order *Order, items []*OrderItem) error {
 	// 开启事务
@@ -37,15 +60,29 @@ func (r *orderRepository) Create(ctx context.Context, order *Order, items []*Ord
 	}
 	defer tx.Rollback()
 
+	if err := r.CreateTx(ctx, tx, order, items); err != nil {
+		return err
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// CreateTx 在调用方事务中创建订单及订单项，提交或回滚由调用方负责
+func (r *orderRepository) CreateTx(ctx context.Context, tx *sql.Tx, order *Order, items []*OrderItem) error {
 	// 插入订单
 	orderQuery := `
-		INSERT INTO orders (order_no, user_id, address_id, total_amount, status, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO orders (order_no, user_id, address_id, total_amount, discount_id, discount_amount, status, reserved_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	result, err := tx.ExecContext(ctx, orderQuery,
-		order.OrderNo, order.UserID, order.AddressID, order.TotalAmount.Value,
-		string(order.Status), order.CreatedAt, order.UpdatedAt,
+		order.OrderNo, order.UserID, order.AddressID, order.TotalAmount.Value, order.DiscountID, order.DiscountAmount.Value,
+		string(order.Status), order.ReservedAt, order.CreatedAt, order.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("create order: %w", err)
@@ -74,11 +111,6 @@ func (r *orderRepository) Create(ctx context.Context, order *Order, items []*Ord
 		}
 	}
 
-	// 提交事务
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
-	}
-
 	return nil
 }
 
@@ -86,28 +118,33 @@ func (r *orderRepository) Create(ctx context.Context, order *Order, items []*Ord
 func (r *orderRepository) GetByID(ctx context.Context, id int) (*Order, []*OrderItem, error) {
 	// 获取订单
 	orderQuery := `
-		SELECT id, order_no, user_id, address_id, total_amount, status, created_at, updated_at
+		SELECT id, order_no, user_id, address_id, total_amount, discount_id, discount_amount, status, reserved_at, version, created_at, updated_at
 		FROM orders WHERE id = ?
 	`
 
 	var order Order
-	var totalAmount int64
+	var totalAmount, discountAmount int64
+	var discountID sql.NullInt64
 	var status string
+	var reservedAt sql.NullTime
 
 	err := r.db.QueryRowContext(ctx, orderQuery, id).Scan(
-		&order.ID, &order.OrderNo, &order.UserID, &order.AddressID, &totalAmount, &status,
-		&order.CreatedAt, &order.UpdatedAt,
+		&order.ID, &order.OrderNo, &order.UserID, &order.AddressID, &totalAmount, &discountID, &discountAmount, &status,
+		&reservedAt, &order.Version, &order.CreatedAt, &order.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, nil, fmt.Errorf("order not found: %d", id)
+		return nil, nil, fmt.Errorf("%w: %d", ErrOrderNotFound, id)
 	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("get order by id: %w", err)
 	}
 
 	order.TotalAmount = flower.Decimal{Value: totalAmount}
+	order.DiscountID = nullIntPtr(discountID)
+	order.DiscountAmount = flower.Decimal{Value: discountAmount}
 	order.Status = OrderStatus(status)
+	order.ReservedAt = nullTimePtr(reservedAt)
 
 	// 获取订单项
 	itemsQuery := `
@@ -150,28 +187,33 @@ func (r *orderRepository) GetByID(ctx context.Context, id int) (*Order, []*Order
 func (r *orderRepository) GetByOrderNo(ctx context.Context, orderNo string) (*Order, []*OrderItem, error) {
 	// 获取订单
 	orderQuery := `
-		SELECT id, order_no, user_id, address_id, total_amount, status, created_at, updated_at
+		SELECT id, order_no, user_id, address_id, total_amount, discount_id, discount_amount, status, reserved_at, version, created_at, updated_at
 		FROM orders WHERE order_no = ?
 	`
 
 	var order Order
-	var totalAmount int64
+	var totalAmount, discountAmount int64
+	var discountID sql.NullInt64
 	var status string
+	var reservedAt sql.NullTime
 
 	err := r.db.QueryRowContext(ctx, orderQuery, orderNo).Scan(
-		&order.ID, &order.OrderNo, &order.UserID, &order.AddressID, &totalAmount, &status,
-		&order.CreatedAt, &order.UpdatedAt,
+		&order.ID, &order.OrderNo, &order.UserID, &order.AddressID, &totalAmount, &discountID, &discountAmount, &status,
+		&reservedAt, &order.Version, &order.CreatedAt, &order.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, nil, fmt.Errorf("order not found: %s", orderNo)
+		return nil, nil, fmt.Errorf("%w: %s", ErrOrderNotFound, orderNo)
 	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("get order by order no: %w", err)
 	}
 
 	order.TotalAmount = flower.Decimal{Value: totalAmount}
+	order.DiscountID = nullIntPtr(discountID)
+	order.DiscountAmount = flower.Decimal{Value: discountAmount}
 	order.Status = OrderStatus(status)
+	order.ReservedAt = nullTimePtr(reservedAt)
 
 	// 获取订单项
 	itemsQuery := `
@@ -212,10 +254,107 @@ func (r *orderRepository) GetByOrderNo(ctx context.Context, orderNo string) (*Or
 
 // List 根据筛选条件获取订单列表
 func (r *orderRepository) List(ctx context.Context, filter OrderFilter) ([]*Order, error) {
+	where, args := buildOrderWhere(filter)
+	query := `
+		SELECT id, order_no, user_id, address_id, total_amount, discount_id, discount_amount, status, reserved_at, version, created_at, updated_at
+		FROM orders` + where
+
+	// 排序
+	query += " ORDER BY " + orderSortClause(filter.SortBy)
+
+	// 分页
+	if filter.Page > 0 && filter.PageSize > 0 {
+		offset := (filter.Page - 1) * filter.PageSize
+		limit := filter.PageSize
+		if filter.LookAhead {
+			limit++
+		}
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []*Order
+	for rows.Next() {
+		var order Order
+		var totalAmount, discountAmount int64
+		var discountID sql.NullInt64
+		var status string
+		var reservedAt sql.NullTime
+
+		err := rows.Scan(&order.ID, &order.OrderNo, &order.UserID, &order.AddressID, &totalAmount, &discountID, &discountAmount, &status,
+			&reservedAt, &order.Version, &order.CreatedAt, &order.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan order: %w", err)
+		}
+
+		order.TotalAmount = flower.Decimal{Value: totalAmount}
+		order.DiscountID = nullIntPtr(discountID)
+		order.DiscountAmount = flower.Decimal{Value: discountAmount}
+		order.Status = OrderStatus(status)
+		order.ReservedAt = nullTimePtr(reservedAt)
+
+		orders = append(orders, &order)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// Count 统计符合筛选条件的订单数量（忽略分页）
+func (r *orderRepository) Count(ctx context.Context, filter OrderFilter) (int, error) {
+	where, args := buildOrderWhere(filter)
+	query := "SELECT COUNT(*) FROM orders" + where
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count orders: %w", err)
+	}
+	return count, nil
+}
+
+// CountByStatus 按状态统计用户的订单数量，没有订单的状态不出现在结果中
+func (r *orderRepository) CountByStatus(ctx context.Context, userID int) (map[string]int, error) {
 	query := `
-		SELECT id, order_no, user_id, address_id, total_amount, status, created_at, updated_at
-		FROM orders WHERE 1=1
+		SELECT status, COUNT(*) FROM orders
+		WHERE user_id = ?
+		GROUP BY status
 	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("count orders by status: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("scan order status count: %w", err)
+		}
+		counts[status] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate order status counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// buildOrderWhere 根据筛选条件构建 WHERE 子句，List 与 Count 共用
+func buildOrderWhere(filter OrderFilter) (string, []interface{}) {
+	query := " WHERE 1=1"
 	args := []interface{}{}
 
 	// 用户筛选
@@ -236,52 +375,74 @@ func (r *orderRepository) List(ctx context.Context, filter OrderFilter) ([]*Orde
 		args = append(args, "%"+filter.OrderNo+"%")
 	}
 
-	// 排序
-	query += " ORDER BY created_at DESC"
+	// 下单时间筛选
+	if !filter.StartTime.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, filter.StartTime)
+	}
+	if !filter.EndTime.IsZero() {
+		query += " AND created_at <= ?"
+		args = append(args, filter.EndTime)
+	}
 
-	// 分页
-	if filter.Page > 0 && filter.PageSize > 0 {
-		offset := (filter.Page - 1) * filter.PageSize
-		query += " LIMIT ? OFFSET ?"
-		args = append(args, filter.PageSize, offset)
+	return query, args
+}
+
+// ListItemsByOrderIDs 批量获取多个订单的订单项，按订单 ID 分组
+// 使用单条 IN 查询，避免列表场景下逐个订单查询订单项
+func (r *orderRepository) ListItemsByOrderIDs(ctx context.Context, orderIDs []int) (map[int][]*OrderItem, error) {
+	result := make(map[int][]*OrderItem, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(orderIDs)), ",")
+	query := `
+		SELECT id, order_id, flower_sku, flower_name, quantity, unit_price, subtotal
+		FROM order_items WHERE order_id IN (` + placeholders + `)
+		ORDER BY order_id, id
+	`
+
+	args := make([]interface{}, len(orderIDs))
+	for i, id := range orderIDs {
+		args[i] = id
 	}
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("list orders: %w", err)
+		return nil, fmt.Errorf("list order items: %w", err)
 	}
 	defer rows.Close()
 
-	var orders []*Order
 	for rows.Next() {
-		var order Order
-		var totalAmount int64
-		var status string
+		var item OrderItem
+		var unitPrice, subtotal int64
 
-		err := rows.Scan(&order.ID, &order.OrderNo, &order.UserID, &order.AddressID, &totalAmount, &status,
-			&order.CreatedAt, &order.UpdatedAt)
+		err := rows.Scan(&item.ID, &item.OrderID, &item.FlowerSKU, &item.FlowerName,
+			&item.Quantity, &unitPrice, &subtotal)
 		if err != nil {
-			return nil, fmt.Errorf("scan order: %w", err)
+			return nil, fmt.Errorf("scan order item: %w", err)
 		}
 
-		order.TotalAmount = flower.Decimal{Value: totalAmount}
-		order.Status = OrderStatus(status)
+		item.UnitPrice = flower.Decimal{Value: unitPrice}
+		item.Subtotal = flower.Decimal{Value: subtotal}
 
-		orders = append(orders, &order)
+		result[item.OrderID] = append(result[item.OrderID], &item)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate orders: %w", err)
+		return nil, fmt.Errorf("iterate order items: %w", err)
 	}
 
-	return orders, nil
+	return result, nil
 }
 
-// UpdateStatus 更新订单状态
-func (r *orderRepository) UpdateStatus(ctx context.Context, id int, status OrderStatus) error {
-	query := `UPDATE orders SET status = ?, updated_at = ? WHERE id = ?`
+// UpdateStatus 更新订单状态（乐观锁）
+// 仅当订单版本仍为 version 时更新并递增版本；订单已被其他请求修改（或不存在）时返回 ErrOrderVersionConflict
+func (r *orderRepository) UpdateStatus(ctx context.Context, id int, status OrderStatus, version int) error {
+	query := `UPDATE orders SET status = ?, version = version + 1, updated_at = ? WHERE id = ? AND version = ?`
 
-	result, err := r.db.ExecContext(ctx, query, string(status), time.Now(), id)
+	result, err := r.db.ExecContext(ctx, query, string(status), time.Now(), id, version)
 	if err != nil {
 		return fmt.Errorf("update order status: %w", err)
 	}
@@ -291,8 +452,184 @@ func (r *orderRepository) UpdateStatus(ctx context.Context, id int, status Order
 		return fmt.Errorf("check rows affected: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("order not found: %d", id)
+		return fmt.Errorf("%w: %d", ErrOrderVersionConflict, id)
+	}
+
+	return nil
+}
+
+// BeginTx 开启事务，供需要跨仓储（订单 + 库存）保持原子性的操作使用
+func (r *orderRepository) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// UpdateStatusTx 在调用方事务中将订单从 from 状态更新为 to 状态
+// 订单不存在或状态已不是 from 时返回错误，避免并发请求重复流转
+func (r *orderRepository) UpdateStatusTx(ctx context.Context, tx *sql.Tx, id int, from, to OrderStatus) error {
+	query := `UPDATE orders SET status = ?, version = version + 1, updated_at = ? WHERE id = ? AND status = ?`
+
+	result, err := tx.ExecContext(ctx, query, string(to), time.Now(), id, string(from))
+	if err != nil {
+		return fmt.Errorf("update order status: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return apperror.Conflict("order not found or status changed: %d", id)
+	}
+
+	return nil
+}
+
+// ReplaceItemsTx 在调用方事务中替换订单的全部订单项，并按 order 更新收货地址、订单金额与库存预留时间
+func (r *orderRepository) ReplaceItemsTx(ctx context.Context, tx *sql.Tx, order *Order, items []*OrderItem) error {
+	orderID := order.ID
+	if _, err := tx.ExecContext(ctx, `DELETE FROM order_items WHERE order_id = ?`, orderID); err != nil {
+		return fmt.Errorf("delete order items: %w", err)
+	}
+
+	for _, item := range items {
+		item.OrderID = orderID
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO order_items (order_id, flower_sku, flower_name, quantity, unit_price, subtotal)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, item.OrderID, item.FlowerSKU, item.FlowerName, item.Quantity, item.UnitPrice.Value, item.Subtotal.Value)
+		if err != nil {
+			return fmt.Errorf("create order item: %w", err)
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `UPDATE orders SET address_id = ?, total_amount = ?, reserved_at = ?, version = version + 1, updated_at = ? WHERE id = ?`,
+		order.AddressID, order.TotalAmount.Value, order.ReservedAt, time.Now(), orderID)
+	if err != nil {
+		return fmt.Errorf("update order amount: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("%w: %d", ErrOrderNotFound, orderID)
+	}
+
+	return nil
+}
+
+// RemoveItemTx 删除订单中的单个订单项并更新订单总额
+// 订单状态已不是 status 时返回冲突错误，避免与并发的状态流转交错
+func (r *orderRepository) RemoveItemTx(ctx context.Context, tx *sql.Tx, orderID, itemID int, status OrderStatus, totalAmount flower.Decimal) error {
+	result, err := tx.ExecContext(ctx, `UPDATE orders SET total_amount = ?, version = version + 1, updated_at = ? WHERE id = ? AND status = ?`,
+		totalAmount.Value, time.Now(), orderID, string(status))
+	if err != nil {
+		return fmt.Errorf("update order amount: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return apperror.Conflict("order not found or status changed: %d", orderID)
+	}
+
+	result, err = tx.ExecContext(ctx, `DELETE FROM order_items WHERE id = ? AND order_id = ?`, itemID, orderID)
+	if err != nil {
+		return fmt.Errorf("delete order item: %w", err)
+	}
+	rows, err = result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return apperror.Conflict("order item not found or already removed: %d", itemID)
+	}
+
+	return nil
+}
+
+// UpdateItemQuantityTx 更新订单项的数量与小计并更新订单总额
+// 订单状态已不是 status 时返回冲突错误，避免与并发的状态流转交错
+func (r *orderRepository) UpdateItemQuantityTx(ctx context.Context, tx *sql.Tx, orderID, itemID, quantity int, subtotal flower.Decimal, status OrderStatus, totalAmount flower.Decimal) error {
+	result, err := tx.ExecContext(ctx, `UPDATE orders SET total_amount = ?, version = version + 1, updated_at = ? WHERE id = ? AND status = ?`,
+		totalAmount.Value, time.Now(), orderID, string(status))
+	if err != nil {
+		return fmt.Errorf("update order amount: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return apperror.Conflict("order not found or status changed: %d", orderID)
+	}
+
+	result, err = tx.ExecContext(ctx, `UPDATE order_items SET quantity = ?, subtotal = ? WHERE id = ? AND order_id = ?`,
+		quantity, subtotal.Value, itemID, orderID)
+	if err != nil {
+		return fmt.Errorf("update order item: %w", err)
+	}
+	rows, err = result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return apperror.Conflict("order item not found or already removed: %d", itemID)
 	}
 
 	return nil
 }
+
+// ListExpiredReservations 获取库存预留时间早于 reservedBefore 的待处理订单 ID
+// 按 ID 升序返回 ID 大于 afterID 的最多 limit 条，用于分批处理
+func (r *orderRepository) ListExpiredReservations(ctx context.Context, reservedBefore time.Time, afterID, limit int) ([]int, error) {
+	query := `
+		SELECT id FROM orders
+		WHERE status = ? AND reserved_at IS NOT NULL AND reserved_at < ? AND id > ?
+		ORDER BY id LIMIT ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, string(StatusPending), reservedBefore, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list expired reservations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan order id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate expired reservations: %w", err)
+	}
+
+	return ids, nil
+}
+
+// nullTimePtr 将可空时间转换为指针，NULL 对应 nil
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// nullIntPtr 将可空整数列转换为指针，NULL 时返回 nil
+func nullIntPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
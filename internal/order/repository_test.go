@@ -3,16 +3,19 @@ package order
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3" // SQLite 驱动
 
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
 )
 
 // setupOrderTestDB 创建测试数据库连接（包含订单表）
-func setupOrderTestDB(t *testing.T) *sql.DB {
+func setupOrderTestDB(t testing.TB) *sql.DB {
 	t.Helper()
 
 	db, err := sql.Open("sqlite3", ":memory:")
@@ -28,7 +31,11 @@ func setupOrderTestDB(t *testing.T) *sql.DB {
 		user_id INTEGER NOT NULL,
 		address_id INTEGER NOT NULL,
 		total_amount INTEGER NOT NULL,
+		discount_id INTEGER,
+		discount_amount INTEGER NOT NULL DEFAULT 0,
 		status TEXT NOT NULL,
+		reserved_at DATETIME,
+		version INTEGER NOT NULL DEFAULT 0,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (user_id) REFERENCES users(id),
@@ -265,6 +272,180 @@ func TestOrderRepository_List(t *testing.T) {
 	}
 }
 
+// TestOrderRepository_ListItemsByOrderIDs 测试批量获取订单项按订单 ID 正确分组
+func TestOrderRepository_ListItemsByOrderIDs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupOrderTestDB(t)
+	repo := NewOrderRepository(db)
+	ctx := context.Background()
+
+	// 每个订单包含 i 个订单项，SKU 带上订单序号便于区分
+	want := make(map[int][]string)
+	for i := 1; i <= 3; i++ {
+		var items []*OrderItem
+		for j := 1; j <= i; j++ {
+			items = append(items, NewOrderItem(0, fmt.Sprintf("FLW%d%02d", i, j), fmt.Sprintf("鲜花%d-%d", i, j), j, 1000))
+		}
+		order := NewOrder(1, 1)
+		order.TotalAmount = flower.Decimal{Value: 1000}
+		if err := repo.Create(ctx, order, items); err != nil {
+			t.Fatalf("failed to create order %d: %v", i, err)
+		}
+		for _, item := range items {
+			want[order.ID] = append(want[order.ID], item.FlowerSKU)
+		}
+	}
+
+	ids := []int{999}
+	for id := range want {
+		ids = append(ids, id)
+	}
+
+	got, err := repo.ListItemsByOrderIDs(ctx, ids)
+	if err != nil {
+		t.Fatalf("ListItemsByOrderIDs() error = %v", err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("ListItemsByOrderIDs() orders = %d, want %d", len(got), len(want))
+	}
+	for id, skus := range want {
+		items := got[id]
+		if len(items) != len(skus) {
+			t.Errorf("order %d items = %d, want %d", id, len(items), len(skus))
+			continue
+		}
+		for i, item := range items {
+			if item.OrderID != id || item.FlowerSKU != skus[i] {
+				t.Errorf("order %d item %d = (order %d, %s), want (order %d, %s)", id, i, item.OrderID, item.FlowerSKU, id, skus[i])
+			}
+		}
+	}
+
+	empty, err := repo.ListItemsByOrderIDs(ctx, nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("ListItemsByOrderIDs(nil) = %v, %v, want empty map", empty, err)
+	}
+}
+
+// BenchmarkOrderItemsLoading 对比订单列表逐个查询订单项（N+1）与单条 IN 查询批量加载
+func BenchmarkOrderItemsLoading(b *testing.B) {
+	const orderCount = 20
+
+	db := setupOrderTestDB(b)
+	repo := NewOrderRepository(db)
+	ctx := context.Background()
+
+	for i := 0; i < orderCount; i++ {
+		items := []*OrderItem{
+			NewOrderItem(0, "FLW001", "红玫瑰", 1, 1000),
+			NewOrderItem(0, "FLW002", "白百合", 2, 1500),
+		}
+		order := NewOrder(1, 1)
+		order.TotalAmount = flower.Decimal{Value: 4000}
+		if err := repo.Create(ctx, order, items); err != nil {
+			b.Fatalf("failed to create order: %v", err)
+		}
+	}
+	filter := OrderFilter{UserID: 1, Page: 1, PageSize: orderCount}
+
+	b.Run("PerOrder", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			orders, err := repo.List(ctx, filter)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, o := range orders {
+				if _, _, err := repo.GetByID(ctx, o.ID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		b.ReportMetric(float64(1+2*orderCount), "queries/op") // GetByID 每次查询订单与订单项两条
+	})
+
+	b.Run("Batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			orders, err := repo.List(ctx, filter)
+			if err != nil {
+				b.Fatal(err)
+			}
+			ids := make([]int, len(orders))
+			for j, o := range orders {
+				ids[j] = o.ID
+			}
+			if _, err := repo.ListItemsByOrderIDs(ctx, ids); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(2, "queries/op")
+	})
+}
+
+// TestOrderRepository_List_TimeRange 测试按下单时间区间筛选订单
+func TestOrderRepository_List_TimeRange(t *testing.T) {
+	db := setupOrderTestDB(t)
+	repo := NewOrderRepository(db)
+	ctx := context.Background()
+
+	day := func(d, h int) time.Time { return time.Date(2026, 3, d, h, 0, 0, 0, time.UTC) }
+	created := map[string]time.Time{}
+	for _, at := range []time.Time{day(1, 9), day(2, 9), day(2, 18), day(3, 9)} {
+		order := NewOrder(1, 1)
+		order.TotalAmount = flower.Decimal{Value: 1000}
+		order.CreatedAt, order.UpdatedAt = at, at
+		items := []*OrderItem{NewOrderItem(0, "FLW001", "红玫瑰", 1, 1000)}
+		if err := repo.Create(ctx, order, items); err != nil {
+			t.Fatalf("failed to create order: %v", err)
+		}
+		created[order.OrderNo] = at
+	}
+
+	tests := []struct {
+		name  string
+		start time.Time
+		end   time.Time
+		want  int
+	}{
+		{name: "不限", want: 4},
+		{name: "仅开始时间", start: day(2, 0), want: 3},
+		{name: "仅结束时间", end: day(2, 9), want: 2},
+		{name: "单日", start: day(2, 0), end: day(3, 0).Add(-time.Second), want: 2},
+		{name: "边界包含", start: day(1, 9), end: day(1, 9), want: 1},
+		{name: "区间内无订单", start: day(4, 0), end: day(5, 0), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := OrderFilter{UserID: 1, StartTime: tt.start, EndTime: tt.end}
+
+			orders, err := repo.List(ctx, filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(orders) != tt.want {
+				t.Errorf("List() count = %d, want %d", len(orders), tt.want)
+			}
+			for _, o := range orders {
+				at := created[o.OrderNo]
+				if (!tt.start.IsZero() && at.Before(tt.start)) || (!tt.end.IsZero() && at.After(tt.end)) {
+					t.Errorf("order %s created at %v is outside range", o.OrderNo, at)
+				}
+			}
+
+			count, err := repo.Count(ctx, filter)
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			if count != tt.want {
+				t.Errorf("Count() = %d, want %d", count, tt.want)
+			}
+		})
+	}
+}
+
 // TestOrderRepository_UpdateStatus 测试更新订单状态
 func TestOrderRepository_UpdateStatus(t *testing.T) {
 	if testing.Short() {
@@ -287,7 +468,7 @@ func TestOrderRepository_UpdateStatus(t *testing.T) {
 	}
 
 	// 更新状态
-	err := repo.UpdateStatus(ctx, order.ID, StatusCompleted)
+	err := repo.UpdateStatus(ctx, order.ID, StatusCompleted, order.Version)
 	if err != nil {
 		t.Fatalf("UpdateStatus() error = %v", err)
 	}
@@ -301,4 +482,54 @@ func TestOrderRepository_UpdateStatus(t *testing.T) {
 	if updatedOrder.Status != StatusCompleted {
 		t.Errorf("UpdateStatus() Status = %s, want %s", updatedOrder.Status, StatusCompleted)
 	}
+	if updatedOrder.Version != order.Version+1 {
+		t.Errorf("UpdateStatus() Version = %d, want %d", updatedOrder.Version, order.Version+1)
+	}
+}
+
+// TestOrderRepository_UpdateStatus_StaleVersion 测试两个请求基于同一版本更新状态时，后提交的旧版本更新失败且不覆盖先提交的结果
+func TestOrderRepository_UpdateStatus_StaleVersion(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupOrderTestDB(t)
+	repo := NewOrderRepository(db)
+	ctx := context.Background()
+
+	order := NewOrder(1, 1)
+	order.TotalAmount = flower.Decimal{Value: 1000}
+	if err := repo.Create(ctx, order, []*OrderItem{NewOrderItem(0, "FLW001", "红玫瑰", 1, 1000)}); err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+
+	// 两个店员同时读取到同一版本的订单
+	first, _, err := repo.GetByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	second, _, err := repo.GetByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+
+	if err := repo.UpdateStatus(ctx, first.ID, StatusCompleted, first.Version); err != nil {
+		t.Fatalf("UpdateStatus() fresh error = %v", err)
+	}
+
+	err = repo.UpdateStatus(ctx, second.ID, StatusCancelled, second.Version)
+	if !errors.Is(err, ErrOrderVersionConflict) {
+		t.Fatalf("UpdateStatus() stale error = %v, want ErrOrderVersionConflict", err)
+	}
+	if apperror.KindOf(err) != apperror.KindConflict {
+		t.Errorf("UpdateStatus() stale error kind = %v, want KindConflict", apperror.KindOf(err))
+	}
+
+	current, _, err := repo.GetByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if current.Status != StatusCompleted || current.Version != first.Version+1 {
+		t.Errorf("order = (%s, version %d), want (%s, version %d)", current.Status, current.Version, StatusCompleted, first.Version+1)
+	}
 }
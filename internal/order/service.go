@@ -2,76 +2,195 @@ package order
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"time"
 
+	"github.com/biqiangwu/flowerSalesSystem/internal/address"
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/biqiangwu/flowerSalesSystem/internal/discount"
 	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
+)
+
+// 错误定义
+var (
+	ErrExceedsMaxOrderQty  = apperror.New(apperror.KindValidation, "超过单笔订单限购数量")
+	ErrAmountOverflow      = apperror.New(apperror.KindValidation, "订单金额溢出")
+	ErrFlowerNameNotFound  = apperror.New(apperror.KindValidation, "未找到该名称的在售鲜花")
+	ErrFlowerNameAmbiguous = apperror.New(apperror.KindValidation, "鲜花名称对应多个 SKU，请使用 SKU 下单")
+	ErrOrderForbidden      = apperror.New(apperror.KindForbidden, "无权操作该订单")
+	ErrTooManyOrderItems   = apperror.New(apperror.KindValidation, "订单项数量超过上限")
+	ErrExceedsMaxItemQty   = apperror.New(apperror.KindValidation, "单个订单项购买数量超过上限")
+	ErrDuplicateOrderSKU   = apperror.New(apperror.KindValidation, "同一订单中鲜花 SKU 重复，请合并为一个订单项")
 )
 
 // OrderService 定义订单业务逻辑接口
 type OrderService interface {
 	CreateOrder(ctx context.Context, userID int, req *CreateOrderRequest) (string, error)
+	Reorder(ctx context.Context, userID int, orderID int) (string, error)
 	GetOrder(ctx context.Context, userID int, orderNo string) (*OrderResponse, error)
+	GetOrderByID(ctx context.Context, operatorID int, operatorRole user.Role, orderID int) (*OrderResponse, error)
+	EnrichOrderItems(ctx context.Context, order *OrderResponse) error
 	ListOrders(ctx context.Context, userID int, filter OrderListFilter) ([]*OrderResponse, error)
-	CompleteOrder(ctx context.Context, orderID int, operatorID int) error
-	CancelOrder(ctx context.Context, orderID int, operatorID int) error
+	ListOrdersWithMore(ctx context.Context, userID int, filter OrderListFilter) ([]*OrderResponse, bool, error)
+	CountOrders(ctx context.Context, userID int, filter OrderListFilter) (int, error)
+	CountOrdersByStatus(ctx context.Context, userID int) (map[string]int, error)
+	ShipOrder(ctx context.Context, orderID int, operatorID int) error
+	CompleteOrder(ctx context.Context, orderID int, operatorID int, operatorRole user.Role) error
+	CancelOrder(ctx context.Context, orderID int, operatorID int, operatorRole user.Role, reason string) error
+	CancelOrderItem(ctx context.Context, orderID int, itemID int, operatorID int) error
+	UpdateOrderItemQuantity(ctx context.Context, orderID int, itemID int, newQty int, operatorID int, operatorRole user.Role) error
+	BulkCancelOwn(ctx context.Context, userID int, orderIDs []int) ([]*BulkCancelResult, error)
+	Stats() StatsSnapshot
+	ReleaseExpiredReservations(ctx context.Context, ttl time.Duration) (int, error)
+}
+
+// MaxBulkCancel 单次批量取消允许的最大订单数
+const MaxBulkCancel = 50
+
+// expireBatchSize 释放超时库存预留时每批处理的订单数
+const expireBatchSize = 100
+
+// 批量取消中单个订单的处理结果
+const (
+	BulkCancelCancelled = "cancelled" // 已取消
+	BulkCancelSkipped   = "skipped"   // 不存在、非本人或非待处理，跳过
+	BulkCancelFailed    = "failed"    // 取消过程中出错，已回滚
+)
+
+// BulkCancelResult 批量取消中单个订单的结果
+type BulkCancelResult struct {
+	OrderID int    `json:"order_id"`
+	Result  string `json:"result"`
+	Reason  string `json:"reason,omitempty"`
 }
 
 // CreateOrderRequest 创建订单请求
 type CreateOrderRequest struct {
-	AddressID int                       `json:"address_id"`
-	Items     []*CreateOrderItemRequest `json:"items"`
+	AddressID    int                       `json:"address_id"`
+	Items        []*CreateOrderItemRequest `json:"items"`
+	DiscountCode string                    `json:"discount_code,omitempty"` // 可选，不区分大小写
 }
 
 // CreateOrderItemRequest 创建订单项请求
+// FlowerSKU 为规范字段；未提供时可用 FlowerName（POS 场景），按名称解析为唯一在售 SKU
 type CreateOrderItemRequest struct {
-	FlowerSKU string `json:"flower_sku"`
-	Quantity  int    `json:"quantity"`
+	FlowerSKU  string `json:"flower_sku"`
+	FlowerName string `json:"flower_name,omitempty"`
+	Quantity   int    `json:"quantity"`
 }
 
 // OrderResponse 订单响应
 type OrderResponse struct {
-	ID          int                `json:"id"`
-	OrderNo     string             `json:"order_no"`
-	UserID      int                `json:"user_id"`
-	AddressID   int                `json:"address_id"`
-	TotalAmount int64              `json:"total_amount"` // 以分为单位
-	Status      string             `json:"status"`
-	CreatedAt   string             `json:"created_at"`
-	UpdatedAt   string             `json:"updated_at"`
-	Items       []*OrderItemResponse `json:"items,omitempty"`
+	ID                  int                  `json:"id"`
+	OrderNo             string               `json:"order_no"`
+	UserID              int                  `json:"user_id"`
+	AddressID           int                  `json:"address_id"`
+	TotalAmount         flower.Decimal       `json:"total_amount"`       // 两位小数，如 "175.00"
+	TotalAmountCents    int64                `json:"total_amount_cents"` // 以分为单位
+	DiscountID          *int                 `json:"discount_id,omitempty"`
+	DiscountAmount      flower.Decimal       `json:"discount_amount"`       // 两位小数，已计入 TotalAmount
+	DiscountAmountCents int64                `json:"discount_amount_cents"` // 以分为单位
+	Status              string               `json:"status"`
+	CreatedAt           string               `json:"created_at"`
+	UpdatedAt           string               `json:"updated_at"`
+	Items               []*OrderItemResponse `json:"items,omitempty"`
+	// Address 收货地址，仅订单详情填充；地址已被删除时为空
+	Address *OrderAddressResponse `json:"address,omitempty"`
+}
+
+// OrderAddressResponse 订单详情中的收货地址
+type OrderAddressResponse struct {
+	Label   string `json:"label"`
+	Address string `json:"address"`
+	Contact string `json:"contact"`
 }
 
 // OrderItemResponse 订单项响应
 type OrderItemResponse struct {
-	ID         int    `json:"id"`
-	FlowerSKU  string `json:"flower_sku"`
-	FlowerName string `json:"flower_name"`
-	Quantity   int    `json:"quantity"`
-	UnitPrice  int64  `json:"unit_price"`  // 以分为单位
-	Subtotal   int64  `json:"subtotal"`    // 以分为单位
+	ID             int            `json:"id"`
+	FlowerSKU      string         `json:"flower_sku"`
+	FlowerName     string         `json:"flower_name"`
+	Quantity       int            `json:"quantity"`
+	UnitPrice      flower.Decimal `json:"unit_price"`       // 两位小数
+	UnitPriceCents int64          `json:"unit_price_cents"` // 以分为单位
+	Subtotal       flower.Decimal `json:"subtotal"`         // 两位小数
+	SubtotalCents  int64          `json:"subtotal_cents"`   // 以分为单位
+	// 以下字段反映鲜花的当前状态，仅在 EnrichOrderItems 后填充；鲜花已删除时视为已下架、库存为 0
+	IsActive     *bool `json:"is_active,omitempty"`
+	CurrentStock *int  `json:"current_stock,omitempty"`
+	Available    *bool `json:"available,omitempty"` // 在售且有库存，可再次购买
 }
 
 // OrderListFilter 订单列表筛选条件
 type OrderListFilter struct {
-	Status   string
-	OrderNo  string
-	Page     int
-	PageSize int
+	Status  string
+	OrderNo string
+	// StartTime/EndTime 按下单时间筛选（包含边界），零值表示不限
+	StartTime time.Time
+	EndTime   time.Time
+	SortBy    string // 排序方式，见 OrderSortOptions，为空时按下单时间倒序
+	Page      int
+	PageSize  int
+	// IncludeItems 是否加载订单项，精简列表可关闭以省去订单项查询
+	IncludeItems bool
 }
 
 // orderService 实现 OrderService 接口
 type orderService struct {
-	orderRepo OrderRepository
-	flowerRepo flower.FlowerRepository
-	logRepo   OrderLogRepository
+	orderRepo    OrderRepository
+	flowerRepo   flower.FlowerRepository
+	logRepo      OrderLogRepository
+	addressRepo  address.AddressRepository   // 订单详情查询收货地址，为 nil 时不填充
+	stockCheck   StockCheckMode              // 完成订单时的库存核对策略
+	catalogCheck StockCheckMode              // 完成订单时的商品目录核对策略
+	notifier     Notifier                    // 订单状态变更通知
+	discountRepo discount.DiscountRepository // 下单时校验与核销优惠码，为 nil 时不支持优惠码
+	stats        *Stats                      // 业务事件计数
+	limits       OrderLimits                 // 订单规模上限
+}
+
+// OrderLimits 订单规模上限，字段 <= 0 表示不限制
+type OrderLimits struct {
+	MaxItemQuantity int // 单个订单项的最大购买数量
+	MaxOrderItems   int // 单个订单的最大订单项数
+}
+
+// OrderServiceOptions 订单服务的可选依赖与策略，零值字段表示不启用对应功能
+type OrderServiceOptions struct {
+	AddressRepo  address.AddressRepository   // 订单详情查询收货地址，为 nil 时不填充
+	StockCheck   StockCheckMode              // 完成订单时的库存核对策略，为空时不核对
+	CatalogCheck StockCheckMode              // 完成订单时的商品目录核对策略，为空时不核对
+	Notifier     Notifier                    // 发货、完成与取消后通知状态变更，为 nil 时不通知
+	DiscountRepo discount.DiscountRepository // 下单时校验与核销优惠码，为 nil 时携带优惠码的下单请求返回校验错误
+	Limits       OrderLimits                 // 订单规模上限
 }
 
 // NewOrderService 创建 OrderService 实例
 func NewOrderService(orderRepo OrderRepository, flowerRepo flower.FlowerRepository, logRepo OrderLogRepository) OrderService {
+	return NewOrderServiceWithOptions(orderRepo, flowerRepo, logRepo, OrderServiceOptions{})
+}
+
+// NewOrderServiceWithOptions 创建订单服务，可选依赖与策略由 opts 提供
+func NewOrderServiceWithOptions(orderRepo OrderRepository, flowerRepo flower.FlowerRepository, logRepo OrderLogRepository, opts OrderServiceOptions) OrderService {
+	notifier := opts.Notifier
+	if notifier == nil {
+		notifier = NewNoopNotifier()
+	}
 	return &orderService{
-		orderRepo:  orderRepo,
-		flowerRepo: flowerRepo,
-		logRepo:    logRepo,
+		orderRepo:    orderRepo,
+		flowerRepo:   flowerRepo,
+		logRepo:      logRepo,
+		addressRepo:  opts.AddressRepo,
+		stockCheck:   opts.StockCheck,
+		catalogCheck: opts.CatalogCheck,
+		notifier:     notifier,
+		discountRepo: opts.DiscountRepo,
+		stats:        NewStats(),
+		limits:       opts.Limits,
 	}
 }
 
@@ -82,6 +201,11 @@ func (s *orderService) CreateOrder(ctx context.Context, userID int, req *CreateO
 		return "", err
 	}
 
+	// 将按名称指定的订单项解析为 SKU
+	if err := s.resolveItemSKUs(ctx, req.Items); err != nil {
+		return "", err
+	}
+
 	// 验证所有鲜花并计算总金额
 	orderItems, totalAmount, err := s.validateAndPrepareItems(ctx, req.Items)
 	if err != nil {
@@ -91,30 +215,115 @@ func (s *orderService) CreateOrder(ctx context.Context, userID int, req *CreateO
 	// 创建订单实体
 	order := NewOrder(userID, req.AddressID)
 	order.TotalAmount = flower.Decimal{Value: totalAmount}
+	order.ReservedAt = &order.CreatedAt
 
-	// 执行事务：创建订单 + 扣减库存
-	err = s.executeCreateOrderTransaction(ctx, order, orderItems)
-	if err != nil {
-		return "", err
+	// 应用优惠码，使用次数在下单事务中核销
+	if req.DiscountCode != "" {
+		if err := s.applyDiscount(ctx, order, req.DiscountCode); err != nil {
+			return "", err
+		}
 	}
 
-	// 记录订单日志
-	log := NewOrderLog(order.ID, userID, "create_order", StatusPending, "")
-	if err := s.logRepo.CreateLog(ctx, log); err != nil {
-		// 日志记录失败不影响订单创建
-		fmt.Printf("warning: failed to create order log: %v\n", err)
+	// 执行事务：扣减库存 + 核销优惠码 + 创建订单 + 记录日志
+	if err := s.executeCreateOrderTransaction(ctx, order, orderItems); err != nil {
+		return "", err
 	}
 
+	s.stats.recordCreated()
 	return order.OrderNo, nil
 }
 
+// Reorder 按历史订单的收货地址与订单项再次下单，返回新订单号
+// 走与 CreateOrder 相同的流程，按当前价格计价并重新校验在售状态与库存，库存不足时返回具体的鲜花与缺口
+func (s *orderService) Reorder(ctx context.Context, userID int, orderID int) (string, error) {
+	order, items, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return "", err
+	}
+	if order.UserID != userID {
+		return "", ErrOrderForbidden
+	}
+
+	req := &CreateOrderRequest{AddressID: order.AddressID, Items: make([]*CreateOrderItemRequest, len(items))}
+	for i, item := range items {
+		req.Items[i] = &CreateOrderItemRequest{FlowerSKU: item.FlowerSKU, Quantity: item.Quantity}
+	}
+	return s.CreateOrder(ctx, userID, req)
+}
+
 // validateCreateRequest 验证创建订单请求
 func (s *orderService) validateCreateRequest(req *CreateOrderRequest) error {
 	if req.AddressID <= 0 {
-		return fmt.Errorf("地址ID不能为空")
+		return apperror.Validation("地址ID不能为空")
 	}
 	if len(req.Items) == 0 {
-		return fmt.Errorf("订单项不能为空")
+		return apperror.Validation("订单项不能为空")
+	}
+	return s.checkItemCount(len(req.Items))
+}
+
+// checkItemCount 校验订单项数不超过上限
+func (s *orderService) checkItemCount(count int) error {
+	if limit := s.limits.MaxOrderItems; limit > 0 && count > limit {
+		return fmt.Errorf("%w: 最多 %d 项, 当前 %d 项", ErrTooManyOrderItems, limit, count)
+	}
+	return nil
+}
+
+// applyDiscount 校验优惠码并从订单总额中扣减优惠金额
+// 优惠码不存在、不在有效期内或已达使用次数上限时返回校验错误
+func (s *orderService) applyDiscount(ctx context.Context, order *Order, code string) error {
+	if s.discountRepo == nil {
+		return discount.ErrInvalidCode
+	}
+
+	d, err := s.discountRepo.GetByCode(ctx, discount.NormalizeCode(code))
+	if errors.Is(err, discount.ErrDiscountNotFound) {
+		return fmt.Errorf("%w: %s", discount.ErrInvalidCode, code)
+	}
+	if err != nil {
+		return fmt.Errorf("获取优惠码失败: %w", err)
+	}
+	if err := d.CheckUsable(time.Now()); err != nil {
+		return err
+	}
+
+	amount := d.Amount(order.TotalAmount)
+	order.DiscountID = &d.ID
+	order.DiscountAmount = amount
+	order.TotalAmount = order.TotalAmount.Sub(amount)
+	return nil
+}
+
+// nonNegative 将修改订单项后重新计算的总额限制为不低于 0
+// 优惠减免金额在下单时确定，减少订单项后总额可能低于减免金额，此时按 0 计
+func nonNegative(total flower.Decimal) flower.Decimal {
+	if total.Value < 0 {
+		return flower.Decimal{}
+	}
+	return total
+}
+
+// resolveItemSKUs 将仅指定鲜花名称的订单项解析为唯一在售 SKU
+// 已指定 SKU 的订单项保持不变；名称不存在或对应多个 SKU 时返回错误
+func (s *orderService) resolveItemSKUs(ctx context.Context, items []*CreateOrderItemRequest) error {
+	for _, item := range items {
+		if item.FlowerSKU != "" || item.FlowerName == "" {
+			continue
+		}
+
+		skus, err := s.flowerRepo.ListActiveSKUsByName(ctx, item.FlowerName)
+		if err != nil {
+			return fmt.Errorf("解析鲜花名称失败: %w", err)
+		}
+		switch len(skus) {
+		case 0:
+			return fmt.Errorf("%w: %s", ErrFlowerNameNotFound, item.FlowerName)
+		case 1:
+			item.FlowerSKU = skus[0]
+		default:
+			return fmt.Errorf("%w: %s (%s)", ErrFlowerNameAmbiguous, item.FlowerName, strings.Join(skus, ", "))
+		}
 	}
 	return nil
 }
@@ -124,76 +333,132 @@ func (s *orderService) validateAndPrepareItems(ctx context.Context, items []*Cre
 	orderItems := make([]*OrderItem, 0, len(items))
 	var totalAmount int64
 
+	// 一次查询取出所有订单项的鲜花，避免逐项查询；按名称下单的订单项已解析为 SKU，重复检查覆盖两种写法
+	skus := make([]string, len(items))
+	seen := make(map[string]bool, len(items))
+	for i, item := range items {
+		if seen[item.FlowerSKU] {
+			return nil, 0, fmt.Errorf("%w: %s", ErrDuplicateOrderSKU, item.FlowerSKU)
+		}
+		seen[item.FlowerSKU] = true
+		skus[i] = item.FlowerSKU
+	}
+	flowers, err := s.flowerRepo.GetBySKUs(ctx, skus)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取鲜花信息失败: %w", err)
+	}
+
 	for _, item := range items {
 		// 验证数量
 		if item.Quantity <= 0 {
-			return nil, 0, fmt.Errorf("数量必须大于0")
+			return nil, 0, apperror.Validation("数量必须大于0")
+		}
+		if limit := s.limits.MaxItemQuantity; limit > 0 && item.Quantity > limit {
+			return nil, 0, fmt.Errorf("%w: %s (上限: %d, 需要: %d)", ErrExceedsMaxItemQty, item.FlowerSKU, limit, item.Quantity)
 		}
 
 		// 获取鲜花信息
-		flw, err := s.flowerRepo.GetBySKU(ctx, item.FlowerSKU)
-		if err != nil {
-			return nil, 0, fmt.Errorf("获取鲜花信息失败: %w", err)
+		flw, ok := flowers[item.FlowerSKU]
+		if !ok {
+			return nil, 0, apperror.Validation("鲜花不存在: %s", item.FlowerSKU)
 		}
 
 		// 验证鲜花是否上架
 		if !flw.IsActive {
-			return nil, 0, fmt.Errorf("鲜花 %s 已下架", flw.Name)
+			return nil, 0, apperror.Validation("鲜花 %s 已下架", flw.Name)
+		}
+
+		// 验证单笔限购数量（优先于库存校验）
+		if flw.MaxOrderQty != nil && item.Quantity > *flw.MaxOrderQty {
+			return nil, 0, fmt.Errorf("%w: %s (限购: %d, 需要: %d)", ErrExceedsMaxOrderQty, flw.Name, *flw.MaxOrderQty, item.Quantity)
 		}
 
 		// 验证库存
 		if flw.Stock < item.Quantity {
-			return nil, 0, fmt.Errorf("库存不足: %s (库存: %d, 需要: %d)", flw.Name, flw.Stock, item.Quantity)
+			return nil, 0, apperror.Validation("库存不足: %s (库存: %d, 需要: %d)", flw.Name, flw.Stock, item.Quantity)
+		}
+
+		// 按实际售价（促销有效时为促销价）计算小计与总额，防止大金额溢出
+		price := flw.EffectivePrice()
+		subtotal, err := price.CheckedMul(int64(item.Quantity))
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %s 小计超出范围", ErrAmountOverflow, flw.Name)
+		}
+		total, err := flower.Decimal{Value: totalAmount}.CheckedAdd(subtotal)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: 订单总额超出范围", ErrAmountOverflow)
 		}
+		totalAmount = total.Value
 
-		// 创建订单项
-		orderItem := NewOrderItem(0, item.FlowerSKU, flw.Name, item.Quantity, flw.SalePrice.Value)
+		// 创建订单项：小计只由 NewOrderItem 按单价与数量计算，并再次校验
+		orderItem := NewOrderItem(0, item.FlowerSKU, flw.Name, item.Quantity, price.Value)
+		if err := orderItem.Validate(); err != nil {
+			return nil, 0, err
+		}
 		orderItems = append(orderItems, orderItem)
-		totalAmount += orderItem.Subtotal.Value
 	}
 
 	return orderItems, totalAmount, nil
 }
 
-// executeCreateOrderTransaction 执行创建订单事务
-func (s *orderService) executeCreateOrderTransaction(ctx context.Context, order *Order, items []*OrderItem) error {
-	// 注意：这里需要使用数据库事务，但由于 Repository 接口不支持事务传递，
-	// 我们需要实现一个两阶段提交的方式，或者修改接口支持事务。
-
-	// 为了简单起见，我们先扣减库存，再创建订单。
-	// 如果创建订单失败，则需要回滚库存。
-
-	// 1. 先扣减所有库存
+// verifyOrderTotal 写入前核对金额：每个订单项的小计等于单价 × 数量，订单总额等于小计之和减去优惠金额
+// 金额均由服务端计算，核对失败说明计算路径被绕过或篡改，拒绝写入
+func verifyOrderTotal(order *Order, items []*OrderItem) error {
+	var sum flower.Decimal
 	for _, item := range items {
-		err := s.flowerRepo.UpdateStock(ctx, item.FlowerSKU, -item.Quantity)
+		if err := item.Validate(); err != nil {
+			return err
+		}
+		total, err := sum.CheckedAdd(item.Subtotal)
 		if err != nil {
-			// 库存扣减失败，需要回滚已扣减的库存
-			s.rollbackStock(ctx, items, item)
-			return fmt.Errorf("扣减库存失败: %w", err)
+			return fmt.Errorf("%w: 订单总额超出范围", ErrAmountOverflow)
 		}
+		sum = total
+	}
+	if expected := sum.Sub(order.DiscountAmount); order.TotalAmount != expected {
+		return apperror.Validation("订单总额不正确: expected %d, got %d", expected.Value, order.TotalAmount.Value)
 	}
+	return nil
+}
 
-	// 2. 创建订单
-	err := s.orderRepo.Create(ctx, order, items)
+// executeCreateOrderTransaction 在单个事务中扣减库存、创建订单及订单项并记录日志
+// 任一步失败则整体回滚，库存不会出现漂移；扣减带库存条件，并发下单不会超卖
+func (s *orderService) executeCreateOrderTransaction(ctx context.Context, order *Order, items []*OrderItem) error {
+	if err := verifyOrderTotal(order, items); err != nil {
+		return err
+	}
+
+	tx, err := s.orderRepo.BeginTx(ctx)
 	if err != nil {
-		// 订单创建失败，回滚库存
-		s.rollbackStock(ctx, items, nil)
 		return fmt.Errorf("创建订单失败: %w", err)
 	}
+	defer tx.Rollback()
 
-	return nil
-}
-
-// rollbackStock 回滚库存
-func (s *orderService) rollbackStock(ctx context.Context, items []*OrderItem, failedItem *OrderItem) {
 	for _, item := range items {
-		if failedItem != nil && item.FlowerSKU == failedItem.FlowerSKU {
-			break // 失败的项不需要回滚
+		if err := s.flowerRepo.DeductStockTx(ctx, tx, item.FlowerSKU, item.Quantity); err != nil {
+			return fmt.Errorf("扣减库存失败: %w", err)
 		}
-		if err := s.flowerRepo.UpdateStock(ctx, item.FlowerSKU, item.Quantity); err != nil {
-			fmt.Printf("warning: failed to rollback stock for %s: %v\n", item.FlowerSKU, err)
+	}
+
+	if order.DiscountID != nil {
+		if err := s.discountRepo.RedeemTx(ctx, tx, *order.DiscountID); err != nil {
+			return err
 		}
 	}
+
+	if err := s.orderRepo.CreateTx(ctx, tx, order, items); err != nil {
+		return fmt.Errorf("创建订单失败: %w", err)
+	}
+
+	log := NewOrderLog(order.ID, order.UserID, "create_order", StatusPending, "")
+	if err := s.logRepo.CreateLogTx(ctx, tx, log); err != nil {
+		return fmt.Errorf("记录订单日志失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("创建订单失败: %w", err)
+	}
+	return nil
 }
 
 // GetOrder 获取订单详情（验证用户权限）
@@ -205,66 +470,204 @@ func (s *orderService) GetOrder(ctx context.Context, userID int, orderNo string)
 
 	// 验证用户只能访问自己的订单
 	if order.UserID != userID {
-		return nil, fmt.Errorf("无权访问该订单")
+		return nil, apperror.Forbidden("无权访问该订单")
 	}
 
-	return s.toResponse(order, items), nil
+	return s.toDetailResponse(ctx, order, items)
+}
+
+// GetOrderByID 按订单ID获取订单详情，管理员和店员可查看任意订单，顾客只能查看自己的订单
+func (s *orderService) GetOrderByID(ctx context.Context, operatorID int, operatorRole user.Role, orderID int) (*OrderResponse, error) {
+	order, items, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.UserID != operatorID && !s.canManageOrders(operatorRole) {
+		return nil, apperror.Forbidden("无权访问该订单")
+	}
+
+	return s.toDetailResponse(ctx, order, items)
 }
 
 // ListOrders 获取订单列表（验证用户权限）
 func (s *orderService) ListOrders(ctx context.Context, userID int, filter OrderListFilter) ([]*OrderResponse, error) {
-	// 构建筛选条件（强制只能查看自己的订单）
-	orderFilter := OrderFilter{
-		UserID:   userID,
-		Status:   filter.Status,
-		OrderNo:  filter.OrderNo,
-		Page:     filter.Page,
-		PageSize: filter.PageSize,
+	responses, _, err := s.listOrders(ctx, userID, filter, false)
+	return responses, err
+}
+
+// ListOrdersWithMore 获取一页订单，并通过多取一行判断是否还有下一页（无需 COUNT）
+func (s *orderService) ListOrdersWithMore(ctx context.Context, userID int, filter OrderListFilter) ([]*OrderResponse, bool, error) {
+	return s.listOrders(ctx, userID, filter, true)
+}
+
+// listOrders 查询订单列表，lookAhead 为 true 时多取一行判断是否还有下一页
+// 多取的一行在加载订单项之前去掉，不会产生额外的订单项查询
+func (s *orderService) listOrders(ctx context.Context, userID int, filter OrderListFilter, lookAhead bool) ([]*OrderResponse, bool, error) {
+	if err := filter.validate(); err != nil {
+		return nil, false, err
 	}
 
-	orders, err := s.orderRepo.List(ctx, orderFilter)
+	repoFilter := toOrderFilter(userID, filter)
+	repoFilter.LookAhead = lookAhead
+
+	orders, err := s.orderRepo.List(ctx, repoFilter)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+
+	hasMore := false
+	if lookAhead && filter.PageSize > 0 && len(orders) > filter.PageSize {
+		orders = orders[:filter.PageSize]
+		hasMore = true
+	}
+
+	// 按需批量加载订单项，不需要时完全跳过订单项查询
+	var itemsByOrder map[int][]*OrderItem
+	if filter.IncludeItems && len(orders) > 0 {
+		orderIDs := make([]int, len(orders))
+		for i, o := range orders {
+			orderIDs[i] = o.ID
+		}
+		itemsByOrder, err = s.orderRepo.ListItemsByOrderIDs(ctx, orderIDs)
+		if err != nil {
+			return nil, false, err
+		}
 	}
 
 	responses := make([]*OrderResponse, len(orders))
 	for i, o := range orders {
-		// 获取订单项
-		_, orderItems, err := s.orderRepo.GetByID(ctx, o.ID)
-		if err != nil {
-			// 获取订单项失败，跳过
-			responses[i] = s.toResponse(o, nil)
-			continue
+		responses[i] = s.toResponse(o, itemsByOrder[o.ID])
+	}
+
+	return responses, hasMore, nil
+}
+
+// CountOrders 统计用户符合筛选条件的订单总数（忽略分页）
+func (s *orderService) CountOrders(ctx context.Context, userID int, filter OrderListFilter) (int, error) {
+	if err := filter.validate(); err != nil {
+		return 0, err
+	}
+	return s.orderRepo.Count(ctx, toOrderFilter(userID, filter))
+}
+
+// CountOrdersByStatus 按状态统计用户的订单数量，没有订单的状态计为 0
+func (s *orderService) CountOrdersByStatus(ctx context.Context, userID int) (map[string]int, error) {
+	counts, err := s.orderRepo.CountByStatus(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range []OrderStatus{StatusPending, StatusShipped, StatusCompleted, StatusCancelled} {
+		if _, ok := counts[string(status)]; !ok {
+			counts[string(status)] = 0
 		}
-		responses[i] = s.toResponse(o, orderItems)
 	}
+	return counts, nil
+}
 
-	return responses, nil
+// validate 验证筛选条件：结束时间不能早于开始时间，排序方式必须在白名单内
+func (f OrderListFilter) validate() error {
+	if !f.StartTime.IsZero() && !f.EndTime.IsZero() && f.EndTime.Before(f.StartTime) {
+		return apperror.Validation("结束时间不能早于开始时间")
+	}
+	if _, ok := orderSortClauses[f.SortBy]; f.SortBy != "" && !ok {
+		return apperror.Validation("无效的排序方式: %s，可选值: %s", f.SortBy, strings.Join(OrderSortOptions(), ", "))
+	}
+	return nil
+}
+
+// toOrderFilter 构建仓储层筛选条件（强制只能查看自己的订单）
+func toOrderFilter(userID int, filter OrderListFilter) OrderFilter {
+	return OrderFilter{
+		UserID:    userID,
+		Status:    filter.Status,
+		OrderNo:   filter.OrderNo,
+		StartTime: filter.StartTime,
+		EndTime:   filter.EndTime,
+		SortBy:    filter.SortBy,
+		Page:      filter.Page,
+		PageSize:  filter.PageSize,
+	}
+}
+
+// EnrichOrderItems 为订单项填充对应鲜花的当前在售状态与库存，用于判断能否再次购买
+// 每个 SKU 查询一次鲜花，默认的订单详情不调用以避免额外查询
+func (s *orderService) EnrichOrderItems(ctx context.Context, order *OrderResponse) error {
+	flowers := make(map[string]*flower.Flower)
+	for _, item := range order.Items {
+		flw, seen := flowers[item.FlowerSKU]
+		if !seen {
+			var err error
+			flw, err = s.flowerRepo.GetBySKU(ctx, item.FlowerSKU)
+			if errors.Is(err, flower.ErrFlowerNotFound) {
+				flw = nil
+			} else if err != nil {
+				return fmt.Errorf("获取鲜花当前状态失败: %w", err)
+			}
+			flowers[item.FlowerSKU] = flw
+		}
+
+		isActive, stock := false, 0
+		if flw != nil {
+			isActive, stock = flw.IsActive, flw.Stock
+		}
+		available := isActive && stock > 0
+		item.IsActive = &isActive
+		item.CurrentStock = &stock
+		item.Available = &available
+	}
+	return nil
+}
+
+// toDetailResponse 转换为订单详情响应，附带收货地址
+// 地址已被删除时 Address 为空，不影响订单详情的返回
+func (s *orderService) toDetailResponse(ctx context.Context, order *Order, items []*OrderItem) (*OrderResponse, error) {
+	response := s.toResponse(order, items)
+	if s.addressRepo == nil {
+		return response, nil
+	}
+
+	addr, err := s.addressRepo.GetByID(ctx, order.AddressID)
+	if errors.Is(err, address.ErrAddressNotFound) {
+		return response, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取收货地址失败: %w", err)
+	}
+
+	response.Address = &OrderAddressResponse{Label: addr.Label, Address: addr.Address, Contact: addr.Contact}
+	return response, nil
 }
 
 // toResponse 将 Order 实体转换为响应 DTO
 func (s *orderService) toResponse(order *Order, items []*OrderItem) *OrderResponse {
 	response := &OrderResponse{
-		ID:          order.ID,
-		OrderNo:     order.OrderNo,
-		UserID:      order.UserID,
-		AddressID:   order.AddressID,
-		TotalAmount: order.TotalAmount.Value,
-		Status:      string(order.Status),
-		CreatedAt:   order.CreatedAt.Format("2006-01-02 15:04:05"),
-		UpdatedAt:   order.UpdatedAt.Format("2006-01-02 15:04:05"),
+		ID:                  order.ID,
+		OrderNo:             order.OrderNo,
+		UserID:              order.UserID,
+		AddressID:           order.AddressID,
+		TotalAmount:         order.TotalAmount,
+		TotalAmountCents:    order.TotalAmount.Cents(),
+		DiscountID:          order.DiscountID,
+		DiscountAmount:      order.DiscountAmount,
+		DiscountAmountCents: order.DiscountAmount.Cents(),
+		Status:              string(order.Status),
+		CreatedAt:           order.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:           order.UpdatedAt.Format("2006-01-02 15:04:05"),
 	}
 
 	if items != nil {
 		response.Items = make([]*OrderItemResponse, len(items))
 		for i, item := range items {
 			response.Items[i] = &OrderItemResponse{
-				ID:         item.ID,
-				FlowerSKU:  item.FlowerSKU,
-				FlowerName: item.FlowerName,
-				Quantity:   item.Quantity,
-				UnitPrice:  item.UnitPrice.Value,
-				Subtotal:   item.Subtotal.Value,
+				ID:             item.ID,
+				FlowerSKU:      item.FlowerSKU,
+				FlowerName:     item.FlowerName,
+				Quantity:       item.Quantity,
+				UnitPrice:      item.UnitPrice,
+				UnitPriceCents: item.UnitPrice.Cents(),
+				Subtotal:       item.Subtotal,
+				SubtotalCents:  item.Subtotal.Cents(),
 			}
 		}
 	}
@@ -272,21 +675,73 @@ func (s *orderService) toResponse(order *Order, items []*OrderItem) *OrderRespon
 	return response
 }
 
-// CompleteOrder 完成订单
-func (s *orderService) CompleteOrder(ctx context.Context, orderID int, operatorID int) error {
+// ShipOrder 发货：将待处理订单流转为已发货，库存保持扣减
+func (s *orderService) ShipOrder(ctx context.Context, orderID int, operatorID int) error {
 	// 获取订单
 	order, _, err := s.orderRepo.GetByID(ctx, orderID)
 	if err != nil {
 		return fmt.Errorf("订单不存在: %w", err)
 	}
 
-	// 验证订单状态流转：只有待处理订单可以完成
+	// 验证订单状态流转：只有待处理订单可以发货
 	if order.Status != StatusPending {
-		return fmt.Errorf("订单状态不正确，当前状态: %s, 只有待处理订单可以完成", order.Status)
+		return apperror.Validation("订单状态不正确，当前状态: %s, 只有待处理订单可以发货", order.Status)
+	}
+
+	// 条件更新，避免与并发的取消或完成冲突
+	tx, err := s.orderRepo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("发货失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.orderRepo.UpdateStatusTx(ctx, tx, orderID, StatusPending, StatusShipped); err != nil {
+		return fmt.Errorf("更新订单状态失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("发货失败: %w", err)
+	}
+
+	// 记录订单日志
+	log := NewOrderLog(orderID, operatorID, "ship_order", StatusShipped, order.Status)
+	if err := s.logRepo.CreateLog(ctx, log); err != nil {
+		// 日志记录失败不影响业务操作
+		slog.Warn("failed to create order log", "order_id", orderID, "action", log.Action, "error", err)
+	}
+
+	s.notifyStatusChanged(ctx, order, order.Status, StatusShipped)
+
+	return nil
+}
+
+// CompleteOrder 完成订单（仅管理员和店员）
+func (s *orderService) CompleteOrder(ctx context.Context, orderID int, operatorID int, operatorRole user.Role) error {
+	// 权限验证：顾客不能完成订单
+	if !s.canManageOrders(operatorRole) {
+		return ErrOrderForbidden
+	}
+
+	// 获取订单
+	order, items, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("订单不存在: %w", err)
+	}
+
+	// 验证订单状态流转：待处理或已发货订单可以完成
+	if order.Status != StatusPending && order.Status != StatusShipped {
+		return apperror.Validation("订单状态不正确，当前状态: %s, 只有待处理或已发货订单可以完成", order.Status)
+	}
+
+	// 按配置核对库存与商品目录：warn 仅记录警告，strict 拒绝完成
+	if err := s.runCompletionCheck(s.stockCheck, order, func() error { return s.checkItemsStock(ctx, items) }); err != nil {
+		return err
+	}
+	if err := s.runCompletionCheck(s.catalogCheck, order, func() error { return s.checkItemsCatalog(ctx, items) }); err != nil {
+		return err
 	}
 
 	// 更新订单状态为已完成
-	if err := s.orderRepo.UpdateStatus(ctx, orderID, StatusCompleted); err != nil {
+	if err := s.orderRepo.UpdateStatus(ctx, orderID, StatusCompleted, order.Version); err != nil {
 		return fmt.Errorf("更新订单状态失败: %w", err)
 	}
 
@@ -294,44 +749,344 @@ func (s *orderService) CompleteOrder(ctx context.Context, orderID int, operatorI
 	log := NewOrderLog(orderID, operatorID, "complete_order", StatusCompleted, order.Status)
 	if err := s.logRepo.CreateLog(ctx, log); err != nil {
 		// 日志记录失败不影响业务操作
-		fmt.Printf("warning: failed to create order log: %v\n", err)
+		slog.Warn("failed to create order log", "order_id", orderID, "action", log.Action, "error", err)
 	}
 
+	s.notifyStatusChanged(ctx, order, order.Status, StatusCompleted)
+
+	s.stats.recordCompleted(order.TotalAmount)
+
 	return nil
 }
 
-// CancelOrder 取消订单（含库存回退）
-func (s *orderService) CancelOrder(ctx context.Context, orderID int, operatorID int) error {
+// CancelOrder 取消订单（含库存回退），仅订单所有者或管理员、店员可以取消
+// reason 为可选的取消原因，记录在订单日志中
+func (s *orderService) CancelOrder(ctx context.Context, orderID int, operatorID int, operatorRole user.Role, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if len([]rune(reason)) > MaxLogReasonLength {
+		return apperror.Validation("取消原因不能超过%d个字符", MaxLogReasonLength)
+	}
+
 	// 获取订单
 	order, items, err := s.orderRepo.GetByID(ctx, orderID)
 	if err != nil {
 		return fmt.Errorf("订单不存在: %w", err)
 	}
 
-	// 验证订单状态流转：只有待处理订单可以取消
+	// 权限验证：顾客只能取消自己的订单
+	if order.UserID != operatorID && !s.canManageOrders(operatorRole) {
+		return ErrOrderForbidden
+	}
+
+	// 验证订单状态流转：只有待处理订单可以取消，已发货订单库存保持扣减
+	if order.Status == StatusShipped {
+		return apperror.Validation("订单已发货，不能取消")
+	}
 	if order.Status != StatusPending {
-		return fmt.Errorf("订单状态不正确，当前状态: %s, 只有待处理订单可以取消", order.Status)
+		return apperror.Validation("订单状态不正确，当前状态: %s, 只有待处理订单可以取消", order.Status)
+	}
+
+	return s.cancelPending(ctx, order, items, operatorID, "cancel_order", reason)
+}
+
+// CancelOrderItem 取消本人待处理订单中的单个订单项：回退该项库存并重新计算订单总额
+// 取消的是最后一个订单项时整单取消；订单发货后不能再取消订单项
+func (s *orderService) CancelOrderItem(ctx context.Context, orderID int, itemID int, operatorID int) error {
+	order, items, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("订单不存在: %w", err)
+	}
+
+	if order.UserID != operatorID {
+		return ErrOrderForbidden
+	}
+	if order.Status == StatusShipped || order.Status == StatusCompleted {
+		return apperror.Validation("订单已发货，不能取消订单项")
+	}
+	if order.Status != StatusPending {
+		return apperror.Validation("订单状态不正确，当前状态: %s, 只有待处理订单可以取消订单项", order.Status)
+	}
+
+	var target *OrderItem
+	for _, item := range items {
+		if item.ID == itemID {
+			target = item
+			break
+		}
+	}
+	if target == nil {
+		return apperror.NotFound("订单项不存在: %d", itemID)
+	}
+
+	if len(items) == 1 {
+		return s.cancelPending(ctx, order, items, operatorID, "cancel_order", "")
+	}
+
+	tx, err := s.orderRepo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("取消订单项失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.flowerRepo.UpdateStockTx(ctx, tx, target.FlowerSKU, target.Quantity); err != nil {
+		return fmt.Errorf("回退库存失败 %s: %w", target.FlowerSKU, err)
+	}
+	if err := s.orderRepo.RemoveItemTx(ctx, tx, orderID, itemID, StatusPending, nonNegative(order.TotalAmount.Sub(target.Subtotal))); err != nil {
+		return fmt.Errorf("取消订单项失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("取消订单项失败: %w", err)
+	}
+
+	// 记录订单日志，订单状态不变
+	log := NewOrderLog(orderID, operatorID, "cancel_order_item", StatusPending, order.Status)
+	if err := s.logRepo.CreateLog(ctx, log); err != nil {
+		// 日志记录失败不影响业务操作
+		slog.Warn("failed to create order log", "order_id", orderID, "action", log.Action, "error", err)
+	}
+
+	return nil
+}
+
+// UpdateOrderItemQuantity 修改待处理订单中订单项的数量（仅管理员和店员，用于协助电话订单）
+// 按差额扣减或回退库存，增加数量时校验数量上限、限购数量与库存；单价保持下单时的价格，重新计算小计与订单总额
+// 使用了优惠码的订单不允许修改数量
+func (s *orderService) UpdateOrderItemQuantity(ctx context.Context, orderID int, itemID int, newQty int, operatorID int, operatorRole user.Role) error {
+	if !s.canManageOrders(operatorRole) {
+		return ErrOrderForbidden
+	}
+	if newQty <= 0 {
+		return apperror.Validation("数量必须大于0")
+	}
+
+	order, items, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("订单不存在: %w", err)
+	}
+	if order.Status != StatusPending {
+		return apperror.Validation("订单状态不正确，当前状态: %s, 只有待处理订单可以修改数量", order.Status)
+	}
+
+	var target *OrderItem
+	for _, item := range items {
+		if item.ID == itemID {
+			target = item
+			break
+		}
+	}
+	if target == nil {
+		return apperror.NotFound("订单项不存在: %d", itemID)
+	}
+
+	delta := newQty - target.Quantity
+	if delta == 0 {
+		return nil
+	}
+
+	// 优惠金额按下单时的总额计算，修改数量后无法按原规则重算，已使用优惠码的订单不允许修改
+	if order.DiscountID != nil {
+		return apperror.Validation("订单已使用优惠码，不能修改数量")
+	}
+
+	// 增加数量时按下单规则校验单项数量上限与鲜花限购数量
+	if delta > 0 {
+		if limit := s.limits.MaxItemQuantity; limit > 0 && newQty > limit {
+			return fmt.Errorf("%w: %s (上限: %d, 需要: %d)", ErrExceedsMaxItemQty, target.FlowerSKU, limit, newQty)
+		}
+		flw, err := s.flowerRepo.GetBySKU(ctx, target.FlowerSKU)
+		if err != nil {
+			return fmt.Errorf("获取鲜花信息失败: %w", err)
+		}
+		if flw.MaxOrderQty != nil && newQty > *flw.MaxOrderQty {
+			return fmt.Errorf("%w: %s (限购: %d, 需要: %d)", ErrExceedsMaxOrderQty, flw.Name, *flw.MaxOrderQty, newQty)
+		}
+	}
+
+	subtotal, err := target.UnitPrice.CheckedMul(int64(newQty))
+	if err != nil {
+		return fmt.Errorf("%w: %s 小计超出范围", ErrAmountOverflow, target.FlowerName)
+	}
+	total, err := order.TotalAmount.Sub(target.Subtotal).CheckedAdd(subtotal)
+	if err != nil {
+		return fmt.Errorf("%w: 订单总额超出范围", ErrAmountOverflow)
+	}
+	total = nonNegative(total)
+
+	tx, err := s.orderRepo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("修改订单项数量失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 增加数量时带库存条件扣减差额，减少数量时回退差额
+	if delta > 0 {
+		if err := s.flowerRepo.DeductStockTx(ctx, tx, target.FlowerSKU, delta); err != nil {
+			return fmt.Errorf("扣减库存失败: %w", err)
+		}
+	} else if err := s.flowerRepo.UpdateStockTx(ctx, tx, target.FlowerSKU, -delta); err != nil {
+		return fmt.Errorf("回退库存失败 %s: %w", target.FlowerSKU, err)
+	}
+
+	if err := s.orderRepo.UpdateItemQuantityTx(ctx, tx, orderID, itemID, newQty, subtotal, StatusPending, total); err != nil {
+		return fmt.Errorf("修改订单项数量失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("修改订单项数量失败: %w", err)
 	}
 
-	// 回退库存
+	// 记录订单日志，订单状态不变
+	log := NewOrderLog(orderID, operatorID, "update_item_quantity", StatusPending, order.Status)
+	if err := s.logRepo.CreateLog(ctx, log); err != nil {
+		// 日志记录失败不影响业务操作
+		slog.Warn("failed to create order log", "order_id", orderID, "action", log.Action, "error", err)
+	}
+
+	return nil
+}
+
+// BulkCancelOwn 批量取消本人的待处理订单
+// 每个订单在独立事务中取消并回退库存；不存在、非本人或非待处理的订单跳过，不影响其它订单
+// 非本人订单与不存在的订单返回相同原因，避免泄露他人订单是否存在
+func (s *orderService) BulkCancelOwn(ctx context.Context, userID int, orderIDs []int) ([]*BulkCancelResult, error) {
+	if len(orderIDs) == 0 {
+		return nil, apperror.Validation("订单ID列表不能为空")
+	}
+	if len(orderIDs) > MaxBulkCancel {
+		return nil, apperror.Validation("单次最多取消%d个订单", MaxBulkCancel)
+	}
+
+	results := make([]*BulkCancelResult, 0, len(orderIDs))
+	seen := make(map[int]bool, len(orderIDs))
+	for _, id := range orderIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		result := &BulkCancelResult{OrderID: id, Result: BulkCancelSkipped}
+		results = append(results, result)
+
+		order, items, err := s.orderRepo.GetByID(ctx, id)
+		if err != nil && !errors.Is(err, ErrOrderNotFound) {
+			result.Result = BulkCancelFailed
+			result.Reason = err.Error()
+			continue
+		}
+		if err != nil || order.UserID != userID {
+			result.Reason = "订单不存在"
+			continue
+		}
+		if order.Status != StatusPending {
+			result.Reason = fmt.Sprintf("订单状态为 %s，只有待处理订单可以取消", order.Status)
+			continue
+		}
+
+		if err := s.cancelPending(ctx, order, items, userID, "cancel_order", ""); err != nil {
+			result.Result = BulkCancelFailed
+			result.Reason = err.Error()
+			continue
+		}
+		result.Result = BulkCancelCancelled
+	}
+
+	return results, nil
+}
+
+// ReleaseExpiredReservations 取消库存预留超过 ttl 仍未处理的待处理订单并回退库存，返回取消的订单数
+// 每个订单在独立事务中取消，操作人记为下单用户；期间已被处理（状态变化）的订单跳过
+func (s *orderService) ReleaseExpiredReservations(ctx context.Context, ttl time.Duration) (int, error) {
+	if ttl <= 0 {
+		return 0, apperror.Validation("库存预留时长必须大于0")
+	}
+
+	before := time.Now().Add(-ttl)
+	released := 0
+	afterID := 0
+	for {
+		ids, err := s.orderRepo.ListExpiredReservations(ctx, before, afterID, expireBatchSize)
+		if err != nil {
+			return released, fmt.Errorf("查询超时订单失败: %w", err)
+		}
+
+		for _, id := range ids {
+			afterID = id
+			order, items, err := s.orderRepo.GetByID(ctx, id)
+			if err != nil {
+				return released, err
+			}
+			if order.Status != StatusPending {
+				continue
+			}
+			if err := s.cancelPending(ctx, order, items, order.UserID, "expire_order", ""); err != nil {
+				if apperror.KindOf(err) == apperror.KindConflict {
+					continue
+				}
+				return released, err
+			}
+			released++
+		}
+
+		if len(ids) < expireBatchSize {
+			return released, nil
+		}
+	}
+}
+
+// Stats 返回自服务启动以来的订单业务计数
+func (s *orderService) Stats() StatsSnapshot {
+	return s.stats.Snapshot()
+}
+
+// canManageOrders 检查角色是否可以处理他人订单（管理员和店员）
+func (s *orderService) canManageOrders(role user.Role) bool {
+	return role == user.RoleAdmin || role == user.RoleClerk
+}
+
+// cancelPending 在事务中回退库存并将待处理订单置为已取消，随后按 action 与 reason 记录日志
+func (s *orderService) cancelPending(ctx context.Context, order *Order, items []*OrderItem, operatorID int, action, reason string) error {
+	orderID := order.ID
+
+	// 库存回退、优惠码归还与状态更新在同一事务中完成，任一步失败则整体回滚，订单保持待处理
+	tx, err := s.orderRepo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+	defer tx.Rollback()
+
 	for _, item := range items {
-		if err := s.flowerRepo.UpdateStock(ctx, item.FlowerSKU, item.Quantity); err != nil {
-			// 库存回退失败，记录警告但继续处理
-			fmt.Printf("warning: failed to rollback stock for %s: %v\n", item.FlowerSKU, err)
+		if err := s.flowerRepo.UpdateStockTx(ctx, tx, item.FlowerSKU, item.Quantity); err != nil {
+			return fmt.Errorf("回退库存失败 %s: %w", item.FlowerSKU, err)
+		}
+	}
+
+	// 归还下单时核销的优惠码使用次数
+	if order.DiscountID != nil && s.discountRepo != nil {
+		if err := s.discountRepo.ReleaseTx(ctx, tx, *order.DiscountID); err != nil {
+			return fmt.Errorf("归还优惠码失败: %w", err)
 		}
 	}
 
-	// 更新订单状态为已取消
-	if err := s.orderRepo.UpdateStatus(ctx, orderID, StatusCancelled); err != nil {
+	if err := s.orderRepo.UpdateStatusTx(ctx, tx, orderID, StatusPending, StatusCancelled); err != nil {
 		return fmt.Errorf("更新订单状态失败: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+
 	// 记录订单日志
-	log := NewOrderLog(orderID, operatorID, "cancel_order", StatusCancelled, order.Status)
+	log := NewOrderLog(orderID, operatorID, action, StatusCancelled, order.Status)
+	log.Reason = reason
 	if err := s.logRepo.CreateLog(ctx, log); err != nil {
 		// 日志记录失败不影响业务操作
-		fmt.Printf("warning: failed to create order log: %v\n", err)
+		slog.Warn("failed to create order log", "order_id", orderID, "action", log.Action, "error", err)
 	}
 
+	s.notifyStatusChanged(ctx, order, order.Status, StatusCancelled)
+
+	s.stats.recordCancelled()
+
 	return nil
 }
@@ -3,11 +3,24 @@ package order
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3" // SQLite 驱动
 
+	"github.com/biqiangwu/flowerSalesSystem/internal/address"
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/biqiangwu/flowerSalesSystem/internal/discount"
 	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
 )
 
 // setupServiceTestDB 创建测试数据库连接（包含所有必需表）
@@ -24,6 +37,7 @@ func setupServiceTestDB(t *testing.T) *sql.DB {
 		CREATE TABLE IF NOT EXISTS users (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			username TEXT UNIQUE NOT NULL,
+			email TEXT UNIQUE,
 			password_hash TEXT NOT NULL,
 			role TEXT NOT NULL,
 			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
@@ -55,6 +69,9 @@ func setupServiceTestDB(t *testing.T) *sql.DB {
 			purchase_price INTEGER NOT NULL,
 			sale_price INTEGER NOT NULL,
 			stock INTEGER NOT NULL DEFAULT 0,
+			max_order_qty INTEGER,
+			discount_price INTEGER,
+			discount_until DATETIME,
 			is_active INTEGER NOT NULL DEFAULT 1,
 			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
@@ -69,7 +86,11 @@ func setupServiceTestDB(t *testing.T) *sql.DB {
 			user_id INTEGER NOT NULL,
 			address_id INTEGER NOT NULL,
 			total_amount INTEGER NOT NULL,
+			discount_id INTEGER,
+			discount_amount INTEGER NOT NULL DEFAULT 0,
 			status TEXT NOT NULL,
+			reserved_at DATETIME,
+			version INTEGER NOT NULL DEFAULT 0,
 			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id),
@@ -97,18 +118,37 @@ func setupServiceTestDB(t *testing.T) *sql.DB {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			order_id INTEGER NOT NULL,
 			operator_id INTEGER NOT NULL,
+			impersonator_id INTEGER,
 			action TEXT NOT NULL,
 			old_status TEXT,
 			new_status TEXT NOT NULL,
+			reason TEXT,
 			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
 			FOREIGN KEY (operator_id) REFERENCES users(id)
 		);
 	`
 
+	// 创建优惠码表
+	createDiscountsTable := `
+		CREATE TABLE IF NOT EXISTS discounts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			code TEXT NOT NULL UNIQUE,
+			type TEXT NOT NULL,
+			value INTEGER NOT NULL,
+			valid_from DATETIME,
+			valid_to DATETIME,
+			usage_limit INTEGER NOT NULL DEFAULT 0,
+			used_count INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`
+
 	tables := []string{
 		createUsersTable, createAddressesTable, createFlowersTable,
 		createOrdersTable, createOrderItemsTable, createOrderLogsTable,
+		createDiscountsTable,
 	}
 
 	for _, tableSQL := range tables {
@@ -232,7 +272,7 @@ func TestOrderService_CreateOrder_Success(t *testing.T) {
 	}
 
 	// 验证订单日志
-	logs, err := logRepo.GetLogs(ctx, order.ID)
+	logs, err := logRepo.GetLogs(ctx, order.ID, OrderLogFilter{})
 	if err != nil {
 		t.Fatalf("GetLogs() error = %v", err)
 	}
@@ -311,6 +351,107 @@ func TestOrderService_CreateOrder_FlowerNotFound(t *testing.T) {
 	}
 }
 
+// TestOrderService_CreateOrder_MultiItemErrors 测试批量获取鲜花后仍按订单项给出具体错误（不存在、已下架、库存不足）
+func TestOrderService_CreateOrder_MultiItemErrors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 10)
+	insertTestFlower(t, db, "FLW002", "百合", 2000, 10)
+	insertTestFlower(t, db, "FLW003", "郁金香", 3000, 1)
+	if _, err := db.Exec("UPDATE flowers SET is_active = 0 WHERE sku = 'FLW002'"); err != nil {
+		t.Fatalf("failed to deactivate flower: %v", err)
+	}
+
+	service := NewOrderService(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db))
+
+	tests := []struct {
+		name    string
+		second  *CreateOrderItemRequest
+		wantMsg string
+	}{
+		{name: "鲜花不存在", second: &CreateOrderItemRequest{FlowerSKU: "NONEXIST", Quantity: 1}, wantMsg: "鲜花不存在: NONEXIST"},
+		{name: "鲜花已下架", second: &CreateOrderItemRequest{FlowerSKU: "FLW002", Quantity: 1}, wantMsg: "鲜花 百合 已下架"},
+		{name: "库存不足", second: &CreateOrderItemRequest{FlowerSKU: "FLW003", Quantity: 2}, wantMsg: "库存不足: 郁金香"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+				AddressID: 1,
+				Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}, tt.second},
+			})
+			if apperror.KindOf(err) != apperror.KindValidation || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("CreateOrder() error = %v, want validation error containing %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+// TestOrderService_CreateOrder_RejectsTamperedAmounts 测试写入前核对金额：篡改的小计或总额被拒绝，库存与订单不变
+func TestOrderService_CreateOrder_RejectsTamperedAmounts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(order *Order, item *OrderItem)
+	}{
+		{name: "小计被改低", tamper: func(order *Order, item *OrderItem) {
+			item.Subtotal = flower.Decimal{Value: 1}
+			order.TotalAmount = item.Subtotal
+		}},
+		{name: "单价被改低", tamper: func(order *Order, item *OrderItem) {
+			item.UnitPrice = flower.Decimal{Value: 1}
+		}},
+		{name: "总额被改低", tamper: func(order *Order, item *OrderItem) {
+			order.TotalAmount = flower.Decimal{Value: 1}
+		}},
+		{name: "优惠金额与总额不符", tamper: func(order *Order, item *OrderItem) {
+			order.DiscountAmount = flower.Decimal{Value: 500}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t)
+			ctx := context.Background()
+
+			insertTestUser(t, db, 1, "user1")
+			insertTestAddress(t, db, 1, 1)
+			insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+			service := NewOrderService(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db)).(*orderService)
+
+			items, total, err := service.validateAndPrepareItems(ctx, []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 3}})
+			if err != nil {
+				t.Fatalf("validateAndPrepareItems() error = %v", err)
+			}
+			order := NewOrder(1, 1)
+			order.TotalAmount = flower.Decimal{Value: total}
+			tt.tamper(order, items[0])
+
+			err = service.executeCreateOrderTransaction(ctx, order, items)
+			if apperror.KindOf(err) != apperror.KindValidation {
+				t.Fatalf("executeCreateOrderTransaction() error = %v, want validation error", err)
+			}
+
+			var orders, stock int
+			db.QueryRow("SELECT COUNT(*) FROM orders").Scan(&orders)
+			db.QueryRow("SELECT stock FROM flowers WHERE sku = 'FLW001'").Scan(&stock)
+			if orders != 0 || stock != 100 {
+				t.Errorf("orders = %d, stock = %d, want 0 orders and stock 100", orders, stock)
+			}
+		})
+	}
+}
+
 // TestOrderService_CreateOrder_EmptyItems 测试空订单项时创建订单失败
 func TestOrderService_CreateOrder_EmptyItems(t *testing.T) {
 	if testing.Short() {
@@ -420,6 +561,117 @@ func TestOrderService_GetOrder_Success(t *testing.T) {
 	}
 }
 
+// TestOrderService_GetOrder_Address 测试订单详情附带收货地址，地址已删除时为空
+func TestOrderService_GetOrder_Address(t *testing.T) {
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestUser(t, db, 2, "clerk")
+	insertTestAddress(t, db, 1, 1)
+	insertTestAddress(t, db, 2, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+	service := NewOrderServiceWithOptions(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db),
+		OrderServiceOptions{AddressRepo: address.NewAddressRepository(db)})
+
+	orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+		AddressID: 1,
+		Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	deletedNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+		AddressID: 2,
+		Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	if _, err := db.Exec("DELETE FROM addresses WHERE id = 2"); err != nil {
+		t.Fatalf("failed to delete address: %v", err)
+	}
+
+	response, err := service.GetOrder(ctx, 1, orderNo)
+	if err != nil {
+		t.Fatalf("GetOrder() error = %v", err)
+	}
+	want := OrderAddressResponse{Label: "家", Address: "北京市朝阳区", Contact: "张三"}
+	if response.Address == nil || *response.Address != want {
+		t.Errorf("GetOrder() Address = %+v, want %+v", response.Address, want)
+	}
+
+	// 店员按 ID 查看订单同样可以看到收货地址
+	byID, err := service.GetOrderByID(ctx, 2, user.RoleClerk, response.ID)
+	if err != nil {
+		t.Fatalf("GetOrderByID() error = %v", err)
+	}
+	if byID.Address == nil || *byID.Address != want {
+		t.Errorf("GetOrderByID() Address = %+v, want %+v", byID.Address, want)
+	}
+
+	deleted, err := service.GetOrder(ctx, 1, deletedNo)
+	if err != nil {
+		t.Fatalf("GetOrder() with deleted address error = %v", err)
+	}
+	if deleted.Address != nil || deleted.AddressID != 2 {
+		t.Errorf("GetOrder() with deleted address = (%d, %+v), want (2, nil)", deleted.AddressID, deleted.Address)
+	}
+}
+
+// TestOrderService_GetOrder_AmountFormats 测试订单金额同时返回格式化字符串与分
+func TestOrderService_GetOrder_AmountFormats(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1050, 100)
+
+	service := NewOrderService(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db))
+
+	orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+		AddressID: 1,
+		Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 3}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+
+	resp, err := service.GetOrder(ctx, 1, orderNo)
+	if err != nil {
+		t.Fatalf("GetOrder() error = %v", err)
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	wants := []string{
+		`"total_amount":"31.50"`,
+		`"total_amount_cents":3150`,
+		`"unit_price":"10.50"`,
+		`"unit_price_cents":1050`,
+		`"subtotal":"31.50"`,
+		`"subtotal_cents":3150`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("response JSON = %s, want contains %s", data, want)
+		}
+	}
+
+	if resp.TotalAmount.String() != "31.50" || resp.TotalAmountCents != resp.TotalAmount.Cents() {
+		t.Errorf("TotalAmount = %s, TotalAmountCents = %d, inconsistent", resp.TotalAmount, resp.TotalAmountCents)
+	}
+}
+
 // TestOrderService_GetOrder_NotFound 测试获取不存在的订单
 func TestOrderService_GetOrder_NotFound(t *testing.T) {
 	if testing.Short() {
@@ -480,6 +732,68 @@ func TestOrderService_GetOrder_Unauthorized(t *testing.T) {
 	}
 }
 
+// TestOrderService_GetOrderByID 测试按订单ID获取订单：员工可查看任意订单，顾客只能查看自己的订单
+func TestOrderService_GetOrderByID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestUser(t, db, 2, "user2")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+	service := NewOrderService(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db))
+
+	orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+		AddressID: 1,
+		Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	created, err := service.GetOrder(ctx, 1, orderNo)
+	if err != nil {
+		t.Fatalf("GetOrder() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		operatorID int
+		role       user.Role
+		orderID    int
+		wantErr    bool
+		wantKind   apperror.Kind
+	}{
+		{name: "顾客查看自己的订单", operatorID: 1, role: user.RoleCustomer, orderID: created.ID},
+		{name: "店员查看任意订单", operatorID: 2, role: user.RoleClerk, orderID: created.ID},
+		{name: "管理员查看任意订单", operatorID: 2, role: user.RoleAdmin, orderID: created.ID},
+		{name: "顾客查看他人订单", operatorID: 2, role: user.RoleCustomer, orderID: created.ID, wantErr: true, wantKind: apperror.KindForbidden},
+		{name: "订单不存在", operatorID: 2, role: user.RoleClerk, orderID: 99999, wantErr: true, wantKind: apperror.KindNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := service.GetOrderByID(ctx, tt.operatorID, tt.role, tt.orderID)
+			if tt.wantErr {
+				if kind := apperror.KindOf(err); kind != tt.wantKind {
+					t.Fatalf("GetOrderByID() error kind = %v, want %v (err = %v)", kind, tt.wantKind, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetOrderByID() error = %v", err)
+			}
+			if resp.OrderNo != orderNo || len(resp.Items) != 1 {
+				t.Errorf("GetOrderByID() = %s with %d items, want %s with 1 item", resp.OrderNo, len(resp.Items), orderNo)
+			}
+		})
+	}
+}
+
 // TestOrderService_ListOrders_Success 测试成功获取订单列表
 func TestOrderService_ListOrders_Success(t *testing.T) {
 	if testing.Short() {
@@ -535,6 +849,58 @@ func TestOrderService_ListOrders_Success(t *testing.T) {
 	}
 }
 
+// TestOrderService_ListOrders_Sort 测试订单列表排序：按金额升序时最便宜的订单在前，无效排序方式被拒绝
+func TestOrderService_ListOrders_Sort(t *testing.T) {
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+	service := NewOrderService(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db))
+	for _, quantity := range []int{2, 1, 3} {
+		if _, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+			AddressID: 1,
+			Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: quantity}},
+		}); err != nil {
+			t.Fatalf("CreateOrder() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		sortBy string
+		want   []int64
+	}{
+		{name: "金额升序", sortBy: "total_amount_asc", want: []int64{1000, 2000, 3000}},
+		{name: "金额降序", sortBy: "total_amount_desc", want: []int64{3000, 2000, 1000}},
+		{name: "下单时间升序", sortBy: "created_at_asc", want: []int64{2000, 1000, 3000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, err := service.ListOrders(ctx, 1, OrderListFilter{SortBy: tt.sortBy})
+			if err != nil {
+				t.Fatalf("ListOrders() error = %v", err)
+			}
+			got := make([]int64, len(orders))
+			for i, o := range orders {
+				got[i] = o.TotalAmountCents
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("totals = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, sortBy := range []string{"total_amount", "id; DROP TABLE orders", "price_asc"} {
+		if _, err := service.ListOrders(ctx, 1, OrderListFilter{SortBy: sortBy}); apperror.KindOf(err) != apperror.KindValidation {
+			t.Errorf("ListOrders(sort=%q) error = %v, want validation error", sortBy, err)
+		}
+	}
+}
+
 // TestOrderService_ListOrders_WithStatusFilter 测试按状态筛选订单
 func TestOrderService_ListOrders_WithStatusFilter(t *testing.T) {
 	if testing.Short() {
@@ -641,6 +1007,133 @@ func TestOrderService_CreateOrder_TransactionRollback(t *testing.T) {
 	}
 }
 
+// failingOrderRepository 在事务内创建订单时返回错误，模拟扣减库存之后的故障
+type failingOrderRepository struct {
+	OrderRepository
+}
+
+func (r *failingOrderRepository) CreateTx(ctx context.Context, tx *sql.Tx, order *Order, items []*OrderItem) error {
+	return errors.New("simulated failure")
+}
+
+// failingLogRepository 在事务内写日志时返回错误，模拟订单写入之后的故障
+type failingLogRepository struct {
+	OrderLogRepository
+}
+
+func (r *failingLogRepository) CreateLogTx(ctx context.Context, tx *sql.Tx, log *OrderLog) error {
+	return errors.New("simulated failure")
+}
+
+// TestOrderService_CreateOrder_AtomicOnFailure 测试扣减库存后任一步失败时整体回滚，库存无漂移
+func TestOrderService_CreateOrder_AtomicOnFailure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name    string
+		wrapOrd bool
+		wrapLog bool
+	}{
+		{name: "创建订单失败", wrapOrd: true},
+		{name: "记录日志失败", wrapLog: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t)
+			ctx := context.Background()
+
+			insertTestUser(t, db, 1, "user1")
+			insertTestAddress(t, db, 1, 1)
+			insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+			insertTestFlower(t, db, "FLW002", "白百合", 1500, 50)
+
+			flowerRepo := flower.NewFlowerRepository(db)
+			var orderRepo OrderRepository = NewOrderRepository(db)
+			var logRepo OrderLogRepository = NewOrderLogRepository(db)
+			if tt.wrapOrd {
+				orderRepo = &failingOrderRepository{OrderRepository: orderRepo}
+			}
+			if tt.wrapLog {
+				logRepo = &failingLogRepository{OrderLogRepository: logRepo}
+			}
+			service := NewOrderService(orderRepo, flowerRepo, logRepo)
+
+			_, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+				AddressID: 1,
+				Items: []*CreateOrderItemRequest{
+					{FlowerSKU: "FLW001", Quantity: 10},
+					{FlowerSKU: "FLW002", Quantity: 5},
+				},
+			})
+			if err == nil {
+				t.Fatal("CreateOrder() should fail")
+			}
+
+			for sku, want := range map[string]int{"FLW001": 100, "FLW002": 50} {
+				f, _ := flowerRepo.GetBySKU(ctx, sku)
+				if f.Stock != want {
+					t.Errorf("%s stock = %d, want %d (no drift)", sku, f.Stock, want)
+				}
+			}
+
+			var orders, items int
+			db.QueryRow("SELECT COUNT(*) FROM orders").Scan(&orders)
+			db.QueryRow("SELECT COUNT(*) FROM order_items").Scan(&items)
+			if orders != 0 || items != 0 {
+				t.Errorf("orders = %d, items = %d, want none persisted", orders, items)
+			}
+		})
+	}
+}
+
+// TestOrderService_CreateOrder_ConcurrentNoOversell 测试并发下单同一 SKU 不会超卖
+func TestOrderService_CreateOrder_ConcurrentNoOversell(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupServiceTestDB(t)
+	// 内存数据库每个连接相互独立，限制为单连接以共享同一数据库
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 5)
+
+	flowerRepo := flower.NewFlowerRepository(db)
+	service := NewOrderService(NewOrderRepository(db), flowerRepo, NewOrderLogRepository(db))
+
+	const buyers = 12
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+				AddressID: 1,
+				Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+			})
+			if err == nil {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := succeeded.Load(); got != 5 {
+		t.Errorf("succeeded orders = %d, want 5", got)
+	}
+	f, _ := flowerRepo.GetBySKU(ctx, "FLW001")
+	if f.Stock != 0 {
+		t.Errorf("stock = %d, want 0", f.Stock)
+	}
+}
+
 // TestOrderService_CreateOrder_InactiveFlower 测试购买已下架鲜花失败
 func TestOrderService_CreateOrder_InactiveFlower(t *testing.T) {
 	if testing.Short() {
@@ -674,3 +1167,716 @@ func TestOrderService_CreateOrder_InactiveFlower(t *testing.T) {
 		t.Error("CreateOrder() should fail when flower is inactive")
 	}
 }
+
+// TestOrderService_CreateOrder_MaxOrderQty 测试单笔订单限购数量
+func TestOrderService_CreateOrder_MaxOrderQty(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name        string
+		maxOrderQty interface{} // nil 表示不限购
+		quantity    int
+		wantErr     error
+		wantOK      bool
+	}{
+		{name: "恰好达到限购数量", maxOrderQty: 3, quantity: 3, wantOK: true},
+		{name: "超过限购数量", maxOrderQty: 3, quantity: 4, wantErr: ErrExceedsMaxOrderQty},
+		{name: "未设置限购", maxOrderQty: nil, quantity: 50, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t)
+			ctx := context.Background()
+
+			insertTestUser(t, db, 1, "user1")
+			insertTestAddress(t, db, 1, 1)
+			insertTestFlower(t, db, "ORC001", "蝴蝶兰", 20000, 100)
+			if _, err := db.Exec("UPDATE flowers SET max_order_qty = ? WHERE sku = ?", tt.maxOrderQty, "ORC001"); err != nil {
+				t.Fatalf("failed to set max_order_qty: %v", err)
+			}
+
+			flowerRepo := flower.NewFlowerRepository(db)
+			service := NewOrderService(NewOrderRepository(db), flowerRepo, NewOrderLogRepository(db))
+
+			_, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+				AddressID: 1,
+				Items: []*CreateOrderItemRequest{
+					{FlowerSKU: "ORC001", Quantity: tt.quantity},
+				},
+			})
+
+			if tt.wantOK && err != nil {
+				t.Fatalf("CreateOrder() unexpected error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateOrder() error = %v, want %v", err, tt.wantErr)
+			}
+
+			// 被拒绝的订单不应扣减库存
+			flw, _ := flowerRepo.GetBySKU(ctx, "ORC001")
+			wantStock := 100
+			if tt.wantOK {
+				wantStock -= tt.quantity
+			}
+			if flw.Stock != wantStock {
+				t.Errorf("stock = %d, want %d", flw.Stock, wantStock)
+			}
+		})
+	}
+}
+
+// TestOrderService_CreateOrder_OrderLimits 测试订单项数、单项购买数量上限与重复 SKU 校验
+func TestOrderService_CreateOrder_OrderLimits(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name    string
+		limits  OrderLimits
+		items   []*CreateOrderItemRequest
+		wantErr error
+	}{
+		{
+			name:   "恰好达到上限",
+			limits: OrderLimits{MaxItemQuantity: 5, MaxOrderItems: 2},
+			items: []*CreateOrderItemRequest{
+				{FlowerSKU: "FLW001", Quantity: 5},
+				{FlowerSKU: "FLW002", Quantity: 1},
+			},
+		},
+		{
+			name:    "单项数量超过上限",
+			limits:  OrderLimits{MaxItemQuantity: 5},
+			items:   []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 6}},
+			wantErr: ErrExceedsMaxItemQty,
+		},
+		{
+			name:   "订单项数超过上限",
+			limits: OrderLimits{MaxOrderItems: 2},
+			items: []*CreateOrderItemRequest{
+				{FlowerSKU: "FLW001", Quantity: 1},
+				{FlowerSKU: "FLW002", Quantity: 1},
+				{FlowerSKU: "FLW003", Quantity: 1},
+			},
+			wantErr: ErrTooManyOrderItems,
+		},
+		{
+			name: "未设置上限",
+			items: []*CreateOrderItemRequest{
+				{FlowerSKU: "FLW001", Quantity: 80},
+				{FlowerSKU: "FLW002", Quantity: 1},
+				{FlowerSKU: "FLW003", Quantity: 1},
+			},
+		},
+		{
+			name: "重复 SKU",
+			items: []*CreateOrderItemRequest{
+				{FlowerSKU: "FLW001", Quantity: 1},
+				{FlowerSKU: "FLW001", Quantity: 2},
+			},
+			wantErr: ErrDuplicateOrderSKU,
+		},
+		{
+			name: "名称与 SKU 指向同一鲜花",
+			items: []*CreateOrderItemRequest{
+				{FlowerSKU: "FLW001", Quantity: 1},
+				{FlowerName: "红玫瑰", Quantity: 2},
+			},
+			wantErr: ErrDuplicateOrderSKU,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t)
+			ctx := context.Background()
+
+			insertTestUser(t, db, 1, "user1")
+			insertTestAddress(t, db, 1, 1)
+			insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+			insertTestFlower(t, db, "FLW002", "白百合", 2000, 100)
+			insertTestFlower(t, db, "FLW003", "向日葵", 1500, 100)
+
+			flowerRepo := flower.NewFlowerRepository(db)
+			service := NewOrderServiceWithOptions(NewOrderRepository(db), flowerRepo, NewOrderLogRepository(db), OrderServiceOptions{Limits: tt.limits})
+
+			_, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{AddressID: 1, Items: tt.items})
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("CreateOrder() unexpected error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) || apperror.KindOf(err) != apperror.KindValidation {
+				t.Fatalf("CreateOrder() error = %v, want validation error %v", err, tt.wantErr)
+			}
+
+			// 被拒绝的订单不应扣减库存
+			flw, _ := flowerRepo.GetBySKU(ctx, "FLW001")
+			if flw.Stock != 100 {
+				t.Errorf("stock = %d, want 100", flw.Stock)
+			}
+		})
+	}
+}
+
+// TestOrderService_CreateOrder_FlowerDiscountPrice 测试下单按鲜花实际售价计价：促销有效时使用促销价
+func TestOrderService_CreateOrder_FlowerDiscountPrice(t *testing.T) {
+	tests := []struct {
+		name          string
+		discountPrice interface{} // nil 表示无促销
+		discountUntil interface{} // nil 表示长期有效
+		wantPrice     int64
+	}{
+		{name: "促销有效", discountPrice: 8000, discountUntil: time.Now().Add(time.Hour), wantPrice: 8000},
+		{name: "促销已过期", discountPrice: 8000, discountUntil: time.Now().Add(-time.Hour), wantPrice: 10000},
+		{name: "无促销", discountPrice: nil, wantPrice: 10000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t)
+			ctx := context.Background()
+
+			insertTestUser(t, db, 1, "user1")
+			insertTestAddress(t, db, 1, 1)
+			insertTestFlower(t, db, "FLW001", "红玫瑰", 10000, 100)
+			if _, err := db.Exec("UPDATE flowers SET discount_price = ?, discount_until = ? WHERE sku = ?",
+				tt.discountPrice, tt.discountUntil, "FLW001"); err != nil {
+				t.Fatalf("failed to set discount: %v", err)
+			}
+
+			orderRepo := NewOrderRepository(db)
+			service := NewOrderService(orderRepo, flower.NewFlowerRepository(db), NewOrderLogRepository(db))
+
+			orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+				AddressID: 1,
+				Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 2}},
+			})
+			if err != nil {
+				t.Fatalf("CreateOrder() error = %v", err)
+			}
+
+			order, items, err := orderRepo.GetByOrderNo(ctx, orderNo)
+			if err != nil {
+				t.Fatalf("GetByOrderNo() error = %v", err)
+			}
+			if len(items) != 1 || items[0].UnitPrice.Value != tt.wantPrice {
+				t.Errorf("item price = %+v, want %d", items, tt.wantPrice)
+			}
+			if order.TotalAmount.Value != tt.wantPrice*2 {
+				t.Errorf("TotalAmount = %d, want %d", order.TotalAmount.Value, tt.wantPrice*2)
+			}
+		})
+	}
+}
+
+// TestOrderService_CreateOrder_AmountOverflow 测试订单金额溢出检测
+func TestOrderService_CreateOrder_AmountOverflow(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	const half = math.MaxInt64/2 + 1 // 两件相加即溢出
+
+	tests := []struct {
+		name      string
+		flowers   map[string]int // sku -> 售价（分）
+		items     []*CreateOrderItemRequest
+		wantErr   error
+		wantTotal int64
+		wantYuan  string
+	}{
+		{
+			name:    "单项小计溢出",
+			flowers: map[string]int{"BIG001": half},
+			items:   []*CreateOrderItemRequest{{FlowerSKU: "BIG001", Quantity: 2}},
+			wantErr: ErrAmountOverflow,
+		},
+		{
+			name:    "多项累加溢出",
+			flowers: map[string]int{"BIG001": half, "BIG002": half},
+			items: []*CreateOrderItemRequest{
+				{FlowerSKU: "BIG001", Quantity: 1},
+				{FlowerSKU: "BIG002", Quantity: 1},
+			},
+			wantErr: ErrAmountOverflow,
+		},
+		{
+			name:    "正常金额",
+			flowers: map[string]int{"ROSE001": 1000, "LILY001": 1500},
+			items: []*CreateOrderItemRequest{
+				{FlowerSKU: "ROSE001", Quantity: 10},
+				{FlowerSKU: "LILY001", Quantity: 5},
+			},
+			wantTotal: 17500,
+			wantYuan:  `"total_amount":"175.00"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t)
+			ctx := context.Background()
+
+			insertTestUser(t, db, 1, "user1")
+			insertTestAddress(t, db, 1, 1)
+			for sku, price := range tt.flowers {
+				insertTestFlower(t, db, sku, sku, price, 100)
+			}
+
+			service := NewOrderService(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db))
+
+			orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{AddressID: 1, Items: tt.items})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CreateOrder() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateOrder() unexpected error = %v", err)
+			}
+
+			resp, err := service.GetOrder(ctx, 1, orderNo)
+			if err != nil {
+				t.Fatalf("GetOrder() error = %v", err)
+			}
+			if resp.TotalAmountCents != tt.wantTotal {
+				t.Errorf("TotalAmountCents = %d, want %d", resp.TotalAmountCents, tt.wantTotal)
+			}
+
+			data, err := json.Marshal(resp)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if !strings.Contains(string(data), tt.wantYuan) {
+				t.Errorf("response JSON = %s, want contains %s", data, tt.wantYuan)
+			}
+		})
+	}
+}
+
+// TestOrderService_ListOrders_IncludeItems 测试订单列表按需加载订单项
+func TestOrderService_ListOrders_IncludeItems(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+	insertTestFlower(t, db, "FLW002", "白百合", 1500, 100)
+
+	service := NewOrderService(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db))
+
+	// 各订单的订单项不同，用于验证批量加载后订单项归属正确
+	wantSKUs := make(map[string][]string)
+	for _, skus := range [][]string{{"FLW001"}, {"FLW001", "FLW002"}, {"FLW002"}} {
+		req := &CreateOrderRequest{AddressID: 1}
+		for _, sku := range skus {
+			req.Items = append(req.Items, &CreateOrderItemRequest{FlowerSKU: sku, Quantity: 1})
+		}
+		orderNo, err := service.CreateOrder(ctx, 1, req)
+		if err != nil {
+			t.Fatalf("CreateOrder() error = %v", err)
+		}
+		wantSKUs[orderNo] = skus
+	}
+
+	tests := []struct {
+		name         string
+		includeItems bool
+	}{
+		{name: "包含订单项", includeItems: true},
+		{name: "不包含订单项", includeItems: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, err := service.ListOrders(ctx, 1, OrderListFilter{Page: 1, PageSize: 10, IncludeItems: tt.includeItems})
+			if err != nil {
+				t.Fatalf("ListOrders() error = %v", err)
+			}
+			if len(orders) != len(wantSKUs) {
+				t.Fatalf("ListOrders() count = %d, want %d", len(orders), len(wantSKUs))
+			}
+			for _, o := range orders {
+				if !tt.includeItems {
+					if len(o.Items) != 0 {
+						t.Errorf("order %s items = %d, want 0", o.OrderNo, len(o.Items))
+					}
+					continue
+				}
+				want := wantSKUs[o.OrderNo]
+				if len(o.Items) != len(want) {
+					t.Errorf("order %s items = %d, want %d", o.OrderNo, len(o.Items), len(want))
+					continue
+				}
+				for i, item := range o.Items {
+					if item.FlowerSKU != want[i] {
+						t.Errorf("order %s item %d SKU = %s, want %s", o.OrderNo, i, item.FlowerSKU, want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestOrderService_CountOrdersByStatus 测试按状态统计订单数量，不含其他用户的订单
+func TestOrderService_CountOrdersByStatus(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestUser(t, db, 2, "user2")
+	insertTestAddress(t, db, 1, 1)
+	insertTestAddress(t, db, 2, 2)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+	service := NewOrderService(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db))
+
+	seed := []struct {
+		userID int
+		status OrderStatus
+	}{
+		{1, StatusPending}, {1, StatusPending}, {1, StatusCompleted},
+		{1, StatusCancelled}, {2, StatusShipped},
+	}
+	for _, o := range seed {
+		orderNo, err := service.CreateOrder(ctx, o.userID, &CreateOrderRequest{
+			AddressID: o.userID,
+			Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+		})
+		if err != nil {
+			t.Fatalf("CreateOrder() error = %v", err)
+		}
+		if _, err := db.Exec("UPDATE orders SET status = ? WHERE order_no = ?", o.status, orderNo); err != nil {
+			t.Fatalf("failed to set order status: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		userID int
+		want   map[string]int
+	}{
+		{name: "混合状态", userID: 1, want: map[string]int{"pending": 2, "shipped": 0, "completed": 1, "cancelled": 1}},
+		{name: "其他用户", userID: 2, want: map[string]int{"pending": 0, "shipped": 1, "completed": 0, "cancelled": 0}},
+		{name: "没有订单", userID: 3, want: map[string]int{"pending": 0, "shipped": 0, "completed": 0, "cancelled": 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.CountOrdersByStatus(ctx, tt.userID)
+			if err != nil {
+				t.Fatalf("CountOrdersByStatus() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CountOrdersByStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestOrderService_CreateOrder_ByFlowerName 测试按鲜花名称下单（POS 场景）
+func TestOrderService_CreateOrder_ByFlowerName(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name       string
+		flowerName string
+		wantSKU    string
+		wantErr    error
+	}{
+		{name: "名称唯一解析", flowerName: "白百合", wantSKU: "LIL001"},
+		{name: "已下架同名不参与解析", flowerName: "向日葵", wantSKU: "SUN002"},
+		{name: "名称对应多个 SKU", flowerName: "红玫瑰", wantErr: ErrFlowerNameAmbiguous},
+		{name: "未知名称", flowerName: "蓝色妖姬", wantErr: ErrFlowerNameNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t)
+			ctx := context.Background()
+
+			insertTestUser(t, db, 1, "user1")
+			insertTestAddress(t, db, 1, 1)
+			insertTestFlower(t, db, "ROS001", "红玫瑰", 1000, 100)
+			insertTestFlower(t, db, "ROS002", "红玫瑰", 1200, 100)
+			insertTestFlower(t, db, "LIL001", "白百合", 1500, 100)
+			insertTestFlower(t, db, "SUN001", "向日葵", 800, 100)
+			insertTestFlower(t, db, "SUN002", "向日葵", 900, 100)
+			if _, err := db.Exec("UPDATE flowers SET is_active = 0 WHERE sku = ?", "SUN001"); err != nil {
+				t.Fatalf("failed to deactivate flower: %v", err)
+			}
+
+			service := NewOrderService(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db))
+
+			orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+				AddressID: 1,
+				Items: []*CreateOrderItemRequest{
+					{FlowerName: tt.flowerName, Quantity: 2},
+				},
+			})
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CreateOrder() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateOrder() unexpected error = %v", err)
+			}
+
+			resp, err := service.GetOrder(ctx, 1, orderNo)
+			if err != nil {
+				t.Fatalf("GetOrder() error = %v", err)
+			}
+			if len(resp.Items) != 1 || resp.Items[0].FlowerSKU != tt.wantSKU {
+				t.Errorf("order items = %+v, want sku %s", resp.Items, tt.wantSKU)
+			}
+		})
+	}
+}
+
+// TestOrderService_ListOrders_InvalidTimeRange 测试结束时间早于开始时间时返回校验错误
+func TestOrderService_ListOrders_InvalidTimeRange(t *testing.T) {
+	db := setupServiceTestDB(t)
+	service := NewOrderService(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db))
+	ctx := context.Background()
+
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	filter := OrderListFilter{StartTime: start, EndTime: start.Add(-time.Hour), Page: 1, PageSize: 10}
+
+	if _, err := service.ListOrders(ctx, 1, filter); apperror.KindOf(err) != apperror.KindValidation {
+		t.Errorf("ListOrders() error = %v, want validation error", err)
+	}
+	if _, err := service.CountOrders(ctx, 1, filter); apperror.KindOf(err) != apperror.KindValidation {
+		t.Errorf("CountOrders() error = %v, want validation error", err)
+	}
+
+	filter.EndTime = start
+	if _, err := service.ListOrders(ctx, 1, filter); err != nil {
+		t.Errorf("ListOrders() with start == end error = %v", err)
+	}
+}
+
+// TestOrderService_CreateOrder_Discount 测试下单时应用优惠码：有效的百分比优惠码、过期与用尽的优惠码
+func TestOrderService_CreateOrder_Discount(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-48 * time.Hour)
+	yesterday := now.Add(-24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		discount  *discount.Discount
+		code      string
+		wantErr   error
+		wantTotal int64
+		wantOff   int64
+	}{
+		{
+			name:      "有效的百分比优惠码",
+			discount:  &discount.Discount{Code: "SPRING15", Type: discount.TypePercent, Value: 15, UsageLimit: 10},
+			code:      " spring15 ",
+			wantTotal: 2550,
+			wantOff:   450,
+		},
+		{
+			name:      "固定金额优惠不超过订单金额",
+			discount:  &discount.Discount{Code: "BIG", Type: discount.TypeFixed, Value: 100000},
+			code:      "BIG",
+			wantTotal: 0,
+			wantOff:   3000,
+		},
+		{
+			name:     "过期的优惠码",
+			discount: &discount.Discount{Code: "OLD", Type: discount.TypePercent, Value: 10, ValidFrom: &past, ValidTo: &yesterday},
+			code:     "OLD",
+			wantErr:  discount.ErrDiscountExpired,
+		},
+		{
+			name:     "已用尽的优惠码",
+			discount: &discount.Discount{Code: "ONCE", Type: discount.TypeFixed, Value: 500, UsageLimit: 1, UsedCount: 1},
+			code:     "ONCE",
+			wantErr:  discount.ErrDiscountExhausted,
+		},
+		{
+			name:    "不存在的优惠码",
+			code:    "NOPE",
+			wantErr: discount.ErrInvalidCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t)
+			ctx := context.Background()
+
+			insertTestUser(t, db, 1, "user1")
+			insertTestAddress(t, db, 1, 1)
+			insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+			discountRepo := discount.NewDiscountRepository(db)
+			if tt.discount != nil {
+				if err := discountRepo.Create(ctx, tt.discount); err != nil {
+					t.Fatalf("Create discount error = %v", err)
+				}
+			}
+
+			orderRepo := NewOrderRepository(db)
+			flowerRepo := flower.NewFlowerRepository(db)
+			service := NewOrderServiceWithOptions(orderRepo, flowerRepo, NewOrderLogRepository(db),
+				OrderServiceOptions{DiscountRepo: discountRepo})
+
+			orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+				AddressID:    1,
+				Items:        []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 3}},
+				DiscountCode: tt.code,
+			})
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CreateOrder() error = %v, want %v", err, tt.wantErr)
+				}
+				// 被拒绝的下单不扣减库存
+				if flw, _ := flowerRepo.GetBySKU(ctx, "FLW001"); flw.Stock != 100 {
+					t.Errorf("stock = %d, want 100", flw.Stock)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateOrder() error = %v", err)
+			}
+
+			order, _, err := orderRepo.GetByOrderNo(ctx, orderNo)
+			if err != nil {
+				t.Fatalf("GetByOrderNo() error = %v", err)
+			}
+			if order.TotalAmount.Value != tt.wantTotal || order.DiscountAmount.Value != tt.wantOff {
+				t.Errorf("total = %d, discount = %d, want %d and %d",
+					order.TotalAmount.Value, order.DiscountAmount.Value, tt.wantTotal, tt.wantOff)
+			}
+			if order.DiscountID == nil || *order.DiscountID != tt.discount.ID {
+				t.Errorf("DiscountID = %v, want %d", order.DiscountID, tt.discount.ID)
+			}
+
+			d, err := discountRepo.GetByID(ctx, tt.discount.ID)
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			if d.UsedCount != 1 {
+				t.Errorf("UsedCount = %d, want 1", d.UsedCount)
+			}
+		})
+	}
+}
+
+// TestOrderService_CreateOrder_DiscountUsageLimit 测试优惠码达到使用次数上限后再次下单被拒绝
+func TestOrderService_CreateOrder_DiscountUsageLimit(t *testing.T) {
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+	discountRepo := discount.NewDiscountRepository(db)
+	if err := discountRepo.Create(ctx, &discount.Discount{Code: "TWICE", Type: discount.TypeFixed, Value: 100, UsageLimit: 2}); err != nil {
+		t.Fatalf("Create discount error = %v", err)
+	}
+	service := NewOrderServiceWithOptions(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db),
+		OrderServiceOptions{DiscountRepo: discountRepo})
+
+	req := &CreateOrderRequest{
+		AddressID:    1,
+		Items:        []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+		DiscountCode: "TWICE",
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := service.CreateOrder(ctx, 1, req); err != nil {
+			t.Fatalf("CreateOrder() #%d error = %v", i+1, err)
+		}
+	}
+	if _, err := service.CreateOrder(ctx, 1, req); !errors.Is(err, discount.ErrDiscountExhausted) {
+		t.Errorf("CreateOrder() after limit error = %v, want ErrDiscountExhausted", err)
+	}
+}
+
+// TestOrderService_CancelOrder_ReleasesDiscount 测试取消与超时释放订单时归还优惠码使用次数
+func TestOrderService_CancelOrder_ReleasesDiscount(t *testing.T) {
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+	discountRepo := discount.NewDiscountRepository(db)
+	d := &discount.Discount{Code: "ONCE", Type: discount.TypeFixed, Value: 100, UsageLimit: 1}
+	if err := discountRepo.Create(ctx, d); err != nil {
+		t.Fatalf("Create discount error = %v", err)
+	}
+	service := NewOrderServiceWithOptions(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db),
+		OrderServiceOptions{DiscountRepo: discountRepo})
+
+	req := &CreateOrderRequest{
+		AddressID:    1,
+		Items:        []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+		DiscountCode: "ONCE",
+	}
+	usedCount := func() int {
+		t.Helper()
+		got, err := discountRepo.GetByID(ctx, d.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		return got.UsedCount
+	}
+
+	// 顾客取消后优惠码可再次使用
+	orderNo, err := service.CreateOrder(ctx, 1, req)
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	created, err := service.GetOrder(ctx, 1, orderNo)
+	if err != nil {
+		t.Fatalf("GetOrder() error = %v", err)
+	}
+	if err := service.CancelOrder(ctx, created.ID, 1, user.RoleCustomer, ""); err != nil {
+		t.Fatalf("CancelOrder() error = %v", err)
+	}
+	if got := usedCount(); got != 0 {
+		t.Errorf("UsedCount after cancel = %d, want 0", got)
+	}
+
+	// 库存预留超时释放同样归还
+	if _, err := service.CreateOrder(ctx, 1, req); err != nil {
+		t.Fatalf("CreateOrder() after cancel error = %v", err)
+	}
+	if _, err := db.Exec("UPDATE orders SET reserved_at = ? WHERE status = 'pending'", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("failed to backdate reservation: %v", err)
+	}
+	released, err := service.ReleaseExpiredReservations(ctx, time.Minute)
+	if err != nil || released != 1 {
+		t.Fatalf("ReleaseExpiredReservations() = %d, %v, want 1", released, err)
+	}
+	if got := usedCount(); got != 0 {
+		t.Errorf("UsedCount after reservation expiry = %d, want 0", got)
+	}
+}
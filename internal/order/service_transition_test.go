@@ -2,11 +2,18 @@ package order
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3" // SQLite 驱动
 
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/biqiangwu/flowerSalesSystem/internal/discount"
 	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+	"github.com/biqiangwu/flowerSalesSystem/internal/user"
 )
 
 // TestOrderService_CompleteOrder_Success 测试成功完成订单
@@ -48,7 +55,7 @@ func TestOrderService_CompleteOrder_Success(t *testing.T) {
 	}
 
 	// 完成订单
-	err = service.CompleteOrder(ctx, order.ID, 1)
+	err = service.CompleteOrder(ctx, order.ID, 1, user.RoleAdmin)
 	if err != nil {
 		t.Fatalf("CompleteOrder() error = %v", err)
 	}
@@ -64,7 +71,7 @@ func TestOrderService_CompleteOrder_Success(t *testing.T) {
 	}
 
 	// 验证订单日志
-	logs, err := logRepo.GetLogs(ctx, order.ID)
+	logs, err := logRepo.GetLogs(ctx, order.ID, OrderLogFilter{})
 	if err != nil {
 		t.Fatalf("GetLogs() error = %v", err)
 	}
@@ -74,8 +81,8 @@ func TestOrderService_CompleteOrder_Success(t *testing.T) {
 		t.Errorf("CompleteOrder() should create completion log, got %d logs", len(logs))
 	}
 
-	// 验证最后一条日志是完成操作
-	lastLog := logs[len(logs)-1]
+	// 日志按时间倒序，第一条为最近的完成操作
+	lastLog := logs[0]
 	if lastLog.Action != "complete_order" {
 		t.Errorf("CompleteOrder() log action = %s, want complete_order", lastLog.Action)
 	}
@@ -99,7 +106,7 @@ func TestOrderService_CompleteOrder_OrderNotFound(t *testing.T) {
 	service := NewOrderService(orderRepo, flowerRepo, logRepo)
 
 	// 尝试完成不存在的订单
-	err := service.CompleteOrder(ctx, 99999, 1)
+	err := service.CompleteOrder(ctx, 99999, 1, user.RoleAdmin)
 	if err == nil {
 		t.Error("CompleteOrder() should fail when order not found")
 	}
@@ -112,22 +119,22 @@ func TestOrderService_CompleteOrder_InvalidStatusTransition(t *testing.T) {
 	}
 
 	tests := []struct {
-		name        string
+		name          string
 		initialStatus OrderStatus
-		wantErr     bool
-		errContains string
+		wantErr       bool
+		errContains   string
 	}{
 		{
-			name:        "从已完成状态尝试完成",
+			name:          "从已完成状态尝试完成",
 			initialStatus: StatusCompleted,
-			wantErr:     true,
-			errContains: "状态",
+			wantErr:       true,
+			errContains:   "状态",
 		},
 		{
-			name:        "从已取消状态尝试完成",
+			name:          "从已取消状态尝试完成",
 			initialStatus: StatusCancelled,
-			wantErr:     true,
-			errContains: "状态",
+			wantErr:       true,
+			errContains:   "状态",
 		},
 	}
 
@@ -160,7 +167,7 @@ func TestOrderService_CompleteOrder_InvalidStatusTransition(t *testing.T) {
 			db.Exec("UPDATE orders SET status = ? WHERE id = ?", tt.initialStatus, order.ID)
 
 			// 尝试完成订单
-			err := service.CompleteOrder(ctx, order.ID, 1)
+			err := service.CompleteOrder(ctx, order.ID, 1, user.RoleAdmin)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CompleteOrder() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -215,7 +222,7 @@ func TestOrderService_CancelOrder_Success(t *testing.T) {
 	stockAfterCreate := flw.Stock // 应该是 90 (100 - 10)
 
 	// 取消订单
-	err = service.CancelOrder(ctx, order.ID, 1)
+	err = service.CancelOrder(ctx, order.ID, 1, user.RoleAdmin, "")
 	if err != nil {
 		t.Fatalf("CancelOrder() error = %v", err)
 	}
@@ -238,7 +245,7 @@ func TestOrderService_CancelOrder_Success(t *testing.T) {
 	}
 
 	// 验证订单日志
-	logs, err := logRepo.GetLogs(ctx, order.ID)
+	logs, err := logRepo.GetLogs(ctx, order.ID, OrderLogFilter{})
 	if err != nil {
 		t.Fatalf("GetLogs() error = %v", err)
 	}
@@ -248,8 +255,8 @@ func TestOrderService_CancelOrder_Success(t *testing.T) {
 		t.Errorf("CancelOrder() should create cancellation log, got %d logs", len(logs))
 	}
 
-	// 验证最后一条日志是取消操作
-	lastLog := logs[len(logs)-1]
+	// 日志按时间倒序，第一条为最近的取消操作
+	lastLog := logs[0]
 	if lastLog.Action != "cancel_order" {
 		t.Errorf("CancelOrder() log action = %s, want cancel_order", lastLog.Action)
 	}
@@ -273,7 +280,7 @@ func TestOrderService_CancelOrder_OrderNotFound(t *testing.T) {
 	service := NewOrderService(orderRepo, flowerRepo, logRepo)
 
 	// 尝试取消不存在的订单
-	err := service.CancelOrder(ctx, 99999, 1)
+	err := service.CancelOrder(ctx, 99999, 1, user.RoleAdmin, "")
 	if err == nil {
 		t.Error("CancelOrder() should fail when order not found")
 	}
@@ -286,22 +293,22 @@ func TestOrderService_CancelOrder_InvalidStatusTransition(t *testing.T) {
 	}
 
 	tests := []struct {
-		name        string
+		name          string
 		initialStatus OrderStatus
-		wantErr     bool
-		errContains string
+		wantErr       bool
+		errContains   string
 	}{
 		{
-			name:        "从已取消状态尝试取消",
+			name:          "从已取消状态尝试取消",
 			initialStatus: StatusCancelled,
-			wantErr:     true,
-			errContains: "状态",
+			wantErr:       true,
+			errContains:   "状态",
 		},
 		{
-			name:        "从已完成状态尝试取消",
+			name:          "从已完成状态尝试取消",
 			initialStatus: StatusCompleted,
-			wantErr:     true,
-			errContains: "状态",
+			wantErr:       true,
+			errContains:   "状态",
 		},
 	}
 
@@ -334,7 +341,7 @@ func TestOrderService_CancelOrder_InvalidStatusTransition(t *testing.T) {
 			db.Exec("UPDATE orders SET status = ? WHERE id = ?", tt.initialStatus, order.ID)
 
 			// 尝试取消订单
-			err := service.CancelOrder(ctx, order.ID, 1)
+			err := service.CancelOrder(ctx, order.ID, 1, user.RoleAdmin, "")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CancelOrder() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -394,7 +401,7 @@ func TestOrderService_CancelOrder_StockRollbackTransaction(t *testing.T) {
 	stock2BeforeCancel := flw2.Stock
 
 	// 取消订单
-	err = service.CancelOrder(ctx, order.ID, 1)
+	err = service.CancelOrder(ctx, order.ID, 1, user.RoleAdmin, "")
 	if err != nil {
 		t.Fatalf("CancelOrder() error = %v", err)
 	}
@@ -449,7 +456,7 @@ func TestOrderService_CancelOrder_RollbackFailure(t *testing.T) {
 	db.Exec("DELETE FROM flowers WHERE sku = ?", "FLW001")
 
 	// 尝试取消订单
-	err := service.CancelOrder(ctx, order.ID, 1)
+	err := service.CancelOrder(ctx, order.ID, 1, user.RoleAdmin, "")
 	// 取消应该失败或部分成功（取决于实现）
 	// 关键是不应该出现panic，并且应该有错误处理
 	if err == nil {
@@ -496,7 +503,7 @@ func TestOrderService_CompleteOrder_MultipleItems(t *testing.T) {
 	}
 
 	// 完成订单
-	err = service.CompleteOrder(ctx, order.ID, 1)
+	err = service.CompleteOrder(ctx, order.ID, 1, user.RoleAdmin)
 	if err != nil {
 		t.Fatalf("CompleteOrder() error = %v", err)
 	}
@@ -522,3 +529,997 @@ func indexOf(s, substr string) int {
 	}
 	return -1
 }
+
+// failingStockRepository 对指定 SKU 的事务内库存更新返回错误
+type failingStockRepository struct {
+	flower.FlowerRepository
+	failSKU string
+}
+
+func (r *failingStockRepository) UpdateStockTx(ctx context.Context, tx *sql.Tx, sku string, delta int) error {
+	if sku == r.failSKU {
+		return errors.New("stock restore failed")
+	}
+	return r.FlowerRepository.UpdateStockTx(ctx, tx, sku, delta)
+}
+
+// TestOrderService_CancelOrder_StockRestoreFails 测试库存回退失败时整个取消操作回滚
+func TestOrderService_CancelOrder_StockRestoreFails(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+	insertTestFlower(t, db, "FLW002", "白百合", 1500, 100)
+
+	flowerRepo := flower.NewFlowerRepository(db)
+	orderRepo := NewOrderRepository(db)
+	logRepo := NewOrderLogRepository(db)
+
+	orderNo, err := NewOrderService(orderRepo, flowerRepo, logRepo).CreateOrder(ctx, 1, &CreateOrderRequest{
+		AddressID: 1,
+		Items: []*CreateOrderItemRequest{
+			{FlowerSKU: "FLW001", Quantity: 10},
+			{FlowerSKU: "FLW002", Quantity: 5},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	order, _, err := orderRepo.GetByOrderNo(ctx, orderNo)
+	if err != nil {
+		t.Fatalf("GetByOrderNo() error = %v", err)
+	}
+
+	// 第一项回退成功，第二项回退失败
+	service := NewOrderService(orderRepo, &failingStockRepository{FlowerRepository: flowerRepo, failSKU: "FLW002"}, logRepo)
+	if err := service.CancelOrder(ctx, order.ID, 1, user.RoleAdmin, ""); err == nil {
+		t.Fatal("CancelOrder() should fail when stock restore fails")
+	}
+
+	updated, _, err := orderRepo.GetByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if updated.Status != StatusPending {
+		t.Errorf("order status = %s, want %s", updated.Status, StatusPending)
+	}
+
+	// 已执行的部分回退也必须回滚
+	wantStock := map[string]int{"FLW001": 90, "FLW002": 95}
+	for sku, want := range wantStock {
+		flw, err := flowerRepo.GetBySKU(ctx, sku)
+		if err != nil {
+			t.Fatalf("GetBySKU(%s) error = %v", sku, err)
+		}
+		if flw.Stock != want {
+			t.Errorf("%s stock = %d, want %d (no partial restore)", sku, flw.Stock, want)
+		}
+	}
+
+	logs, err := logRepo.GetLogs(ctx, order.ID, OrderLogFilter{})
+	if err != nil {
+		t.Fatalf("GetLogs() error = %v", err)
+	}
+	for _, l := range logs {
+		if l.Action == "cancel_order" {
+			t.Error("failed cancel should not write a cancel_order log")
+		}
+	}
+}
+
+// TestOrderService_CompleteOrder_StockCheck 测试完成订单时按策略核对库存
+func TestOrderService_CompleteOrder_StockCheck(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name       string
+		mode       StockCheckMode
+		drift      bool // 下单后将库存改为负数，模拟扣减漂移
+		wantErr    error
+		wantStatus OrderStatus
+	}{
+		{name: "严格模式库存一致", mode: StockCheckStrict, wantStatus: StatusCompleted},
+		{name: "严格模式库存漂移被拒绝", mode: StockCheckStrict, drift: true, wantErr: ErrStockDrift, wantStatus: StatusPending},
+		{name: "告警模式库存漂移照常完成", mode: StockCheckWarn, drift: true, wantStatus: StatusCompleted},
+		{name: "关闭核对", mode: StockCheckOff, drift: true, wantStatus: StatusCompleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t)
+			ctx := context.Background()
+
+			insertTestUser(t, db, 1, "user1")
+			insertTestAddress(t, db, 1, 1)
+			insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+			orderRepo := NewOrderRepository(db)
+			service := NewOrderServiceWithOptions(orderRepo, flower.NewFlowerRepository(db), NewOrderLogRepository(db), OrderServiceOptions{StockCheck: tt.mode})
+
+			orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+				AddressID: 1,
+				Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 10}},
+			})
+			if err != nil {
+				t.Fatalf("CreateOrder() error = %v", err)
+			}
+			if tt.drift {
+				if _, err := db.Exec("UPDATE flowers SET stock = -3 WHERE sku = ?", "FLW001"); err != nil {
+					t.Fatalf("failed to simulate drift: %v", err)
+				}
+			}
+
+			o, _, err := orderRepo.GetByOrderNo(ctx, orderNo)
+			if err != nil {
+				t.Fatalf("GetByOrderNo() error = %v", err)
+			}
+
+			err = service.CompleteOrder(ctx, o.ID, 1, user.RoleAdmin)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CompleteOrder() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("CompleteOrder() unexpected error = %v", err)
+			}
+
+			updated, _, err := orderRepo.GetByID(ctx, o.ID)
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			if updated.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", updated.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// TestOrderService_CompleteOrder_CatalogCheck 测试完成订单时按策略核对鲜花是否仍存在且在售
+func TestOrderService_CompleteOrder_CatalogCheck(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name       string
+		mode       StockCheckMode
+		mutate     string // 下单后对鲜花执行的修改，模拟目录变化
+		wantErr    error
+		wantStatus OrderStatus
+	}{
+		{name: "严格模式鲜花在售", mode: StockCheckStrict, wantStatus: StatusCompleted},
+		{name: "严格模式鲜花已下架被拒绝", mode: StockCheckStrict, mutate: "UPDATE flowers SET is_active = 0 WHERE sku = 'FLW001'", wantErr: ErrCatalogDrift, wantStatus: StatusPending},
+		{name: "严格模式鲜花已删除被拒绝", mode: StockCheckStrict, mutate: "DELETE FROM flowers WHERE sku = 'FLW001'", wantErr: ErrCatalogDrift, wantStatus: StatusPending},
+		{name: "告警模式鲜花已下架照常完成", mode: StockCheckWarn, mutate: "UPDATE flowers SET is_active = 0 WHERE sku = 'FLW001'", wantStatus: StatusCompleted},
+		{name: "关闭核对", mode: StockCheckOff, mutate: "DELETE FROM flowers WHERE sku = 'FLW001'", wantStatus: StatusCompleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t)
+			ctx := context.Background()
+
+			insertTestUser(t, db, 1, "user1")
+			insertTestAddress(t, db, 1, 1)
+			insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+			orderRepo := NewOrderRepository(db)
+			service := NewOrderServiceWithOptions(orderRepo, flower.NewFlowerRepository(db), NewOrderLogRepository(db), OrderServiceOptions{CatalogCheck: tt.mode})
+
+			orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+				AddressID: 1,
+				Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+			})
+			if err != nil {
+				t.Fatalf("CreateOrder() error = %v", err)
+			}
+			if tt.mutate != "" {
+				if _, err := db.Exec(tt.mutate); err != nil {
+					t.Fatalf("failed to mutate catalog: %v", err)
+				}
+			}
+
+			o, _, err := orderRepo.GetByOrderNo(ctx, orderNo)
+			if err != nil {
+				t.Fatalf("GetByOrderNo() error = %v", err)
+			}
+
+			err = service.CompleteOrder(ctx, o.ID, 1, user.RoleAdmin)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CompleteOrder() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("CompleteOrder() unexpected error = %v", err)
+			}
+
+			updated, _, err := orderRepo.GetByID(ctx, o.ID)
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			if updated.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", updated.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// TestParseStockCheckMode 测试核对策略解析
+func TestParseStockCheckMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    StockCheckMode
+		wantErr bool
+	}{
+		{input: "", want: StockCheckOff},
+		{input: "off", want: StockCheckOff},
+		{input: "Warn", want: StockCheckWarn},
+		{input: " strict ", want: StockCheckStrict},
+		{input: "block", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseStockCheckMode(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseStockCheckMode(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseStockCheckMode(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+// TestOrderService_BulkCancelOwn 测试批量取消本人待处理订单
+func TestOrderService_BulkCancelOwn(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestUser(t, db, 2, "user2")
+	insertTestAddress(t, db, 1, 1)
+	insertTestAddress(t, db, 2, 2)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+	flowerRepo := flower.NewFlowerRepository(db)
+	orderRepo := NewOrderRepository(db)
+	service := NewOrderService(orderRepo, flowerRepo, NewOrderLogRepository(db))
+
+	createOrder := func(userID, addressID, quantity int) int {
+		t.Helper()
+		orderNo, err := service.CreateOrder(ctx, userID, &CreateOrderRequest{
+			AddressID: addressID,
+			Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: quantity}},
+		})
+		if err != nil {
+			t.Fatalf("CreateOrder() error = %v", err)
+		}
+		o, _, err := orderRepo.GetByOrderNo(ctx, orderNo)
+		if err != nil {
+			t.Fatalf("GetByOrderNo() error = %v", err)
+		}
+		return o.ID
+	}
+
+	ownPending := createOrder(1, 1, 5)
+	ownCompleted := createOrder(1, 1, 2)
+	if err := service.CompleteOrder(ctx, ownCompleted, 1, user.RoleAdmin); err != nil {
+		t.Fatalf("CompleteOrder() error = %v", err)
+	}
+	othersPending := createOrder(2, 2, 3)
+
+	results, err := service.BulkCancelOwn(ctx, 1, []int{ownPending, ownCompleted, othersPending, 9999, ownPending})
+	if err != nil {
+		t.Fatalf("BulkCancelOwn() error = %v", err)
+	}
+
+	want := map[int]string{
+		ownPending:    BulkCancelCancelled,
+		ownCompleted:  BulkCancelSkipped,
+		othersPending: BulkCancelSkipped,
+		9999:          BulkCancelSkipped,
+	}
+	if len(results) != len(want) {
+		t.Fatalf("len(results) = %d, want %d (duplicates should be ignored)", len(results), len(want))
+	}
+	for _, r := range results {
+		if r.Result != want[r.OrderID] {
+			t.Errorf("order %d result = %s, want %s (reason: %s)", r.OrderID, r.Result, want[r.OrderID], r.Reason)
+		}
+	}
+
+	// 仅本人待处理订单被取消，其余订单状态不变
+	wantStatus := map[int]OrderStatus{
+		ownPending:    StatusCancelled,
+		ownCompleted:  StatusCompleted,
+		othersPending: StatusPending,
+	}
+	for id, status := range wantStatus {
+		o, _, err := orderRepo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID(%d) error = %v", id, err)
+		}
+		if o.Status != status {
+			t.Errorf("order %d status = %s, want %s", id, o.Status, status)
+		}
+	}
+
+	// 只回退被取消订单的库存：100 - 5 - 2 - 3 + 5
+	flw, _ := flowerRepo.GetBySKU(ctx, "FLW001")
+	if flw.Stock != 95 {
+		t.Errorf("stock = %d, want 95", flw.Stock)
+	}
+}
+
+// TestOrderService_BulkCancelOwn_InvalidInput 测试批量取消的参数校验
+func TestOrderService_BulkCancelOwn_InvalidInput(t *testing.T) {
+	db := setupServiceTestDB(t)
+	service := NewOrderService(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db))
+
+	tooMany := make([]int, MaxBulkCancel+1)
+	for i := range tooMany {
+		tooMany[i] = i + 1
+	}
+
+	for _, ids := range [][]int{nil, tooMany} {
+		if _, err := service.BulkCancelOwn(context.Background(), 1, ids); err == nil {
+			t.Errorf("BulkCancelOwn(%d ids) should fail", len(ids))
+		}
+	}
+}
+
+// TestOrderService_Stats 测试订单业务计数随创建、完成、取消更新，营收只计已完成订单
+func TestOrderService_Stats(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+	orderRepo := NewOrderRepository(db)
+	service := NewOrderService(orderRepo, flower.NewFlowerRepository(db), NewOrderLogRepository(db))
+
+	createOrder := func(quantity int) int {
+		t.Helper()
+		orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+			AddressID: 1,
+			Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: quantity}},
+		})
+		if err != nil {
+			t.Fatalf("CreateOrder() error = %v", err)
+		}
+		o, _, _ := orderRepo.GetByOrderNo(ctx, orderNo)
+		return o.ID
+	}
+
+	completed := createOrder(3) // 30.00
+	cancelled := createOrder(2) // 20.00
+	bulkCancelled := createOrder(1)
+	createOrder(4) // 保持待处理
+
+	// 失败的操作不计数
+	if _, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+		AddressID: 1,
+		Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1000}},
+	}); err == nil {
+		t.Fatal("CreateOrder() should fail on insufficient stock")
+	}
+
+	if err := service.CompleteOrder(ctx, completed, 1, user.RoleAdmin); err != nil {
+		t.Fatalf("CompleteOrder() error = %v", err)
+	}
+	if err := service.CancelOrder(ctx, cancelled, 1, user.RoleAdmin, ""); err != nil {
+		t.Fatalf("CancelOrder() error = %v", err)
+	}
+	if err := service.CancelOrder(ctx, completed, 1, user.RoleAdmin, ""); err == nil {
+		t.Fatal("CancelOrder() should fail on completed order")
+	}
+	if _, err := service.BulkCancelOwn(ctx, 1, []int{bulkCancelled}); err != nil {
+		t.Fatalf("BulkCancelOwn() error = %v", err)
+	}
+
+	stats := service.Stats()
+	if stats.OrdersCreated != 4 {
+		t.Errorf("OrdersCreated = %d, want 4", stats.OrdersCreated)
+	}
+	if stats.OrdersCompleted != 1 {
+		t.Errorf("OrdersCompleted = %d, want 1", stats.OrdersCompleted)
+	}
+	if stats.OrdersCancelled != 2 {
+		t.Errorf("OrdersCancelled = %d, want 2", stats.OrdersCancelled)
+	}
+	if stats.RevenueCents != 3000 || stats.Revenue.String() != "30.00" {
+		t.Errorf("revenue = (%d, %s), want (3000, 30.00)", stats.RevenueCents, stats.Revenue.String())
+	}
+}
+
+// TestOrderService_Reorder 测试按历史订单再次下单：按当前价格计价，库存不足时拒绝且不扣减库存
+func TestOrderService_Reorder(t *testing.T) {
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestUser(t, db, 2, "user2")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 10)
+	insertTestFlower(t, db, "FLW002", "百合", 2000, 10)
+
+	orderRepo := NewOrderRepository(db)
+	flowerRepo := flower.NewFlowerRepository(db)
+	service := NewOrderService(orderRepo, flowerRepo, NewOrderLogRepository(db))
+
+	orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+		AddressID: 1,
+		Items: []*CreateOrderItemRequest{
+			{FlowerSKU: "FLW001", Quantity: 3},
+			{FlowerSKU: "FLW002", Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	original, _, err := orderRepo.GetByOrderNo(ctx, orderNo)
+	if err != nil {
+		t.Fatalf("GetByOrderNo() error = %v", err)
+	}
+
+	// 涨价后再次购买按当前价格计价
+	if _, err := db.Exec("UPDATE flowers SET sale_price = 1500 WHERE sku = 'FLW001'"); err != nil {
+		t.Fatalf("failed to update price: %v", err)
+	}
+
+	if _, err := service.Reorder(ctx, 2, original.ID); apperror.KindOf(err) != apperror.KindForbidden {
+		t.Errorf("Reorder() by other user error = %v, want forbidden", err)
+	}
+
+	newNo, err := service.Reorder(ctx, 1, original.ID)
+	if err != nil {
+		t.Fatalf("Reorder() error = %v", err)
+	}
+	if newNo == orderNo {
+		t.Fatalf("Reorder() returned original order number %s", orderNo)
+	}
+	reordered, items, err := orderRepo.GetByOrderNo(ctx, newNo)
+	if err != nil {
+		t.Fatalf("GetByOrderNo() error = %v", err)
+	}
+	if reordered.Status != StatusPending || reordered.AddressID != 1 || len(items) != 2 {
+		t.Errorf("reordered = (%s, address %d, %d items), want (pending, address 1, 2 items)", reordered.Status, reordered.AddressID, len(items))
+	}
+	if reordered.TotalAmount.Value != 3*1500+2000 {
+		t.Errorf("reordered total = %d, want %d", reordered.TotalAmount.Value, 3*1500+2000)
+	}
+
+	// 剩余库存 FLW001=4，再次购买 3 支后只剩 1 支，第三次购买库存不足
+	if _, err := service.Reorder(ctx, 1, original.ID); err != nil {
+		t.Fatalf("second Reorder() error = %v", err)
+	}
+	_, err = service.Reorder(ctx, 1, original.ID)
+	if apperror.KindOf(err) != apperror.KindValidation || !strings.Contains(err.Error(), "库存不足") {
+		t.Fatalf("Reorder() with insufficient stock error = %v, want 库存不足 validation error", err)
+	}
+	for sku, want := range map[string]int{"FLW001": 1, "FLW002": 7} {
+		f, _ := flowerRepo.GetBySKU(ctx, sku)
+		if f.Stock != want {
+			t.Errorf("%s stock = %d, want %d", sku, f.Stock, want)
+		}
+	}
+}
+
+// TestOrderService_CancelOrderItem 测试取消单个订单项：只回退该项库存并重新计算总额，取消最后一项时整单取消
+func TestOrderService_CancelOrderItem(t *testing.T) {
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestUser(t, db, 2, "user2")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 10)
+	insertTestFlower(t, db, "FLW002", "百合", 2000, 10)
+
+	orderRepo := NewOrderRepository(db)
+	flowerRepo := flower.NewFlowerRepository(db)
+	service := NewOrderService(orderRepo, flowerRepo, NewOrderLogRepository(db))
+
+	createOrder := func() (*Order, []*OrderItem) {
+		t.Helper()
+		orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+			AddressID: 1,
+			Items: []*CreateOrderItemRequest{
+				{FlowerSKU: "FLW001", Quantity: 3},
+				{FlowerSKU: "FLW002", Quantity: 2},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CreateOrder() error = %v", err)
+		}
+		o, items, err := orderRepo.GetByOrderNo(ctx, orderNo)
+		if err != nil {
+			t.Fatalf("GetByOrderNo() error = %v", err)
+		}
+		return o, items
+	}
+	itemID := func(items []*OrderItem, sku string) int {
+		for _, item := range items {
+			if item.FlowerSKU == sku {
+				return item.ID
+			}
+		}
+		t.Fatalf("item %s not found", sku)
+		return 0
+	}
+	assertStock := func(want map[string]int) {
+		t.Helper()
+		for sku, stock := range want {
+			f, _ := flowerRepo.GetBySKU(ctx, sku)
+			if f.Stock != stock {
+				t.Errorf("%s stock = %d, want %d", sku, f.Stock, stock)
+			}
+		}
+	}
+
+	o, items := createOrder()
+	assertStock(map[string]int{"FLW001": 7, "FLW002": 8})
+
+	if err := service.CancelOrderItem(ctx, o.ID, itemID(items, "FLW002"), 2); !errors.Is(err, ErrOrderForbidden) {
+		t.Errorf("CancelOrderItem() by other user error = %v, want ErrOrderForbidden", err)
+	}
+	if err := service.CancelOrderItem(ctx, o.ID, 99999, 1); apperror.KindOf(err) != apperror.KindNotFound {
+		t.Errorf("CancelOrderItem() unknown item error = %v, want not found", err)
+	}
+
+	// 取消百合：只回退百合库存，总额扣除百合小计
+	if err := service.CancelOrderItem(ctx, o.ID, itemID(items, "FLW002"), 1); err != nil {
+		t.Fatalf("CancelOrderItem() error = %v", err)
+	}
+	updated, remaining, _ := orderRepo.GetByID(ctx, o.ID)
+	if updated.Status != StatusPending || len(remaining) != 1 || updated.TotalAmount.Value != 3000 {
+		t.Errorf("after cancel item = (%s, %d items, total %d), want (pending, 1 item, 3000)", updated.Status, len(remaining), updated.TotalAmount.Value)
+	}
+	assertStock(map[string]int{"FLW001": 7, "FLW002": 10})
+
+	// 取消最后一项时整单取消
+	if err := service.CancelOrderItem(ctx, o.ID, itemID(items, "FLW001"), 1); err != nil {
+		t.Fatalf("CancelOrderItem() last item error = %v", err)
+	}
+	updated, _, _ = orderRepo.GetByID(ctx, o.ID)
+	if updated.Status != StatusCancelled {
+		t.Errorf("status after cancelling last item = %s, want cancelled", updated.Status)
+	}
+	assertStock(map[string]int{"FLW001": 10, "FLW002": 10})
+
+	// 已发货订单不能取消订单项
+	shipped, shippedItems := createOrder()
+	if err := service.ShipOrder(ctx, shipped.ID, 2); err != nil {
+		t.Fatalf("ShipOrder() error = %v", err)
+	}
+	if err := service.CancelOrderItem(ctx, shipped.ID, itemID(shippedItems, "FLW001"), 1); apperror.KindOf(err) != apperror.KindValidation {
+		t.Errorf("CancelOrderItem() on shipped order error = %v, want validation error", err)
+	}
+	assertStock(map[string]int{"FLW001": 7, "FLW002": 8})
+}
+
+// TestOrderService_ShippedTransitions 测试已发货状态的流转矩阵
+func TestOrderService_ShippedTransitions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	ship := func(s OrderService, ctx context.Context, id int) error { return s.ShipOrder(ctx, id, 1) }
+	complete := func(s OrderService, ctx context.Context, id int) error {
+		return s.CompleteOrder(ctx, id, 1, user.RoleAdmin)
+	}
+	cancel := func(s OrderService, ctx context.Context, id int) error {
+		return s.CancelOrder(ctx, id, 1, user.RoleAdmin, "")
+	}
+
+	tests := []struct {
+		name       string
+		from       OrderStatus
+		action     func(OrderService, context.Context, int) error
+		wantErr    bool
+		wantStatus OrderStatus
+		wantStock  int // 下单 10 支，初始库存 100
+		wantLog    string
+	}{
+		{name: "待处理可发货", from: StatusPending, action: ship, wantStatus: StatusShipped, wantStock: 90, wantLog: "ship_order"},
+		{name: "已发货不能重复发货", from: StatusShipped, action: ship, wantErr: true, wantStatus: StatusShipped, wantStock: 90},
+		{name: "已完成不能发货", from: StatusCompleted, action: ship, wantErr: true, wantStatus: StatusCompleted, wantStock: 90},
+		{name: "已取消不能发货", from: StatusCancelled, action: ship, wantErr: true, wantStatus: StatusCancelled, wantStock: 90},
+		{name: "已发货可完成", from: StatusShipped, action: complete, wantStatus: StatusCompleted, wantStock: 90, wantLog: "complete_order"},
+		{name: "待处理仍可直接完成", from: StatusPending, action: complete, wantStatus: StatusCompleted, wantStock: 90, wantLog: "complete_order"},
+		{name: "已发货不能取消且库存保持扣减", from: StatusShipped, action: cancel, wantErr: true, wantStatus: StatusShipped, wantStock: 90},
+		{name: "待处理可取消并回退库存", from: StatusPending, action: cancel, wantStatus: StatusCancelled, wantStock: 100, wantLog: "cancel_order"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t)
+			ctx := context.Background()
+
+			insertTestUser(t, db, 1, "user1")
+			insertTestAddress(t, db, 1, 1)
+			insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+			flowerRepo := flower.NewFlowerRepository(db)
+			orderRepo := NewOrderRepository(db)
+			logRepo := NewOrderLogRepository(db)
+			service := NewOrderService(orderRepo, flowerRepo, logRepo)
+
+			orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+				AddressID: 1,
+				Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 10}},
+			})
+			if err != nil {
+				t.Fatalf("CreateOrder() error = %v", err)
+			}
+			order, _, _ := orderRepo.GetByOrderNo(ctx, orderNo)
+			if _, err := db.Exec("UPDATE orders SET status = ? WHERE id = ?", tt.from, order.ID); err != nil {
+				t.Fatalf("failed to set status: %v", err)
+			}
+
+			err = tt.action(service, ctx, order.ID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("action error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && apperror.KindOf(err) != apperror.KindValidation {
+				t.Errorf("error kind = %v, want validation", apperror.KindOf(err))
+			}
+
+			updated, _, _ := orderRepo.GetByID(ctx, order.ID)
+			if updated.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", updated.Status, tt.wantStatus)
+			}
+			f, _ := flowerRepo.GetBySKU(ctx, "FLW001")
+			if f.Stock != tt.wantStock {
+				t.Errorf("stock = %d, want %d", f.Stock, tt.wantStock)
+			}
+
+			if tt.wantLog != "" {
+				logs, err := logRepo.GetLogs(ctx, order.ID, OrderLogFilter{})
+				if err != nil {
+					t.Fatalf("GetLogs() error = %v", err)
+				}
+				last := logs[0]
+				if last.Action != tt.wantLog || last.OldStatus != tt.from || last.NewStatus != tt.wantStatus {
+					t.Errorf("last log = %s %s->%s, want %s %s->%s", last.Action, last.OldStatus, last.NewStatus, tt.wantLog, tt.from, tt.wantStatus)
+				}
+			}
+		})
+	}
+}
+
+// TestOrderService_TransitionPermissions 测试取消与完成订单的权限校验
+func TestOrderService_TransitionPermissions(t *testing.T) {
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "owner")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+	service := NewOrderService(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db))
+	createOrder := func() int {
+		orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+			AddressID: 1,
+			Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 1}},
+		})
+		if err != nil {
+			t.Fatalf("CreateOrder() error = %v", err)
+		}
+		order, _, err := NewOrderRepository(db).GetByOrderNo(ctx, orderNo)
+		if err != nil {
+			t.Fatalf("GetByOrderNo() error = %v", err)
+		}
+		return order.ID
+	}
+
+	tests := []struct {
+		name       string
+		transition func(orderID int) error
+		wantErr    error
+	}{
+		{name: "其他顾客不能取消", transition: func(id int) error { return service.CancelOrder(ctx, id, 2, user.RoleCustomer, "") }, wantErr: ErrOrderForbidden},
+		{name: "所有者可以取消", transition: func(id int) error { return service.CancelOrder(ctx, id, 1, user.RoleCustomer, "") }},
+		{name: "店员可以取消他人订单", transition: func(id int) error { return service.CancelOrder(ctx, id, 2, user.RoleClerk, "") }},
+		{name: "顾客不能完成自己的订单", transition: func(id int) error { return service.CompleteOrder(ctx, id, 1, user.RoleCustomer) }, wantErr: ErrOrderForbidden},
+		{name: "管理员可以完成订单", transition: func(id int) error { return service.CompleteOrder(ctx, id, 2, user.RoleAdmin) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderID := createOrder()
+			err := tt.transition(orderID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && apperror.HTTPStatus(err) != 403 {
+				t.Errorf("HTTPStatus() = %d, want 403", apperror.HTTPStatus(err))
+			}
+		})
+	}
+}
+
+// TestOrderService_ReleaseExpiredReservations 测试库存预留超时的待处理订单被自动取消并回退库存
+func TestOrderService_ReleaseExpiredReservations(t *testing.T) {
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "owner")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+	orderRepo := NewOrderRepository(db)
+	flowerRepo := flower.NewFlowerRepository(db)
+	logRepo := NewOrderLogRepository(db)
+	service := NewOrderService(orderRepo, flowerRepo, logRepo)
+	createOrder := func(quantity int) *Order {
+		orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+			AddressID: 1,
+			Items:     []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: quantity}},
+		})
+		if err != nil {
+			t.Fatalf("CreateOrder() error = %v", err)
+		}
+		order, _, err := orderRepo.GetByOrderNo(ctx, orderNo)
+		if err != nil {
+			t.Fatalf("GetByOrderNo() error = %v", err)
+		}
+		return order
+	}
+
+	expired := createOrder(10)
+	fresh := createOrder(5)
+	shipped := createOrder(3)
+	if expired.ReservedAt == nil {
+		t.Fatal("ReservedAt = nil, want reservation time of a created order")
+	}
+	if err := service.ShipOrder(ctx, shipped.ID, 1); err != nil {
+		t.Fatalf("ShipOrder() error = %v", err)
+	}
+
+	// 将过期订单与已发货订单的预留时间回拨到 ttl 之前
+	past := time.Now().Add(-2 * time.Hour)
+	if _, err := db.Exec(`UPDATE orders SET reserved_at = ? WHERE id IN (?, ?)`, past, expired.ID, shipped.ID); err != nil {
+		t.Fatalf("backdate reserved_at error = %v", err)
+	}
+
+	if _, err := service.ReleaseExpiredReservations(ctx, 0); apperror.KindOf(err) != apperror.KindValidation {
+		t.Errorf("ReleaseExpiredReservations(0) error = %v, want validation error", err)
+	}
+
+	released, err := service.ReleaseExpiredReservations(ctx, time.Hour)
+	if err != nil {
+		t.Fatalf("ReleaseExpiredReservations() error = %v", err)
+	}
+	if released != 1 {
+		t.Errorf("released = %d, want 1", released)
+	}
+
+	wantStatus := map[int]OrderStatus{expired.ID: StatusCancelled, fresh.ID: StatusPending, shipped.ID: StatusShipped}
+	for id, want := range wantStatus {
+		order, _, err := orderRepo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID(%d) error = %v", id, err)
+		}
+		if order.Status != want {
+			t.Errorf("order %d status = %s, want %s", id, order.Status, want)
+		}
+	}
+
+	// 库存：100 - 10 - 5 - 3 + 10（过期订单回退）
+	f, err := flowerRepo.GetBySKU(ctx, "FLW001")
+	if err != nil {
+		t.Fatalf("GetBySKU() error = %v", err)
+	}
+	if f.Stock != 92 {
+		t.Errorf("stock = %d, want 92", f.Stock)
+	}
+
+	logs, err := logRepo.GetLogs(ctx, expired.ID, OrderLogFilter{})
+	if err != nil {
+		t.Fatalf("GetLogs() error = %v", err)
+	}
+	if len(logs) == 0 || logs[0].Action != "expire_order" || logs[0].OperatorID != 1 {
+		t.Errorf("latest log = %+v, want expire_order by order owner", logs)
+	}
+
+	// 再次执行不会重复取消
+	if released, err := service.ReleaseExpiredReservations(ctx, time.Hour); err != nil || released != 0 {
+		t.Errorf("second ReleaseExpiredReservations() = (%d, %v), want (0, nil)", released, err)
+	}
+}
+
+// TestOrderService_UpdateOrderItemQuantity 测试店员修改订单项数量：按差额调整库存并重新计算总额
+func TestOrderService_UpdateOrderItemQuantity(t *testing.T) {
+	db := setupServiceTestDB(t)
+	ctx := context.Background()
+
+	insertTestUser(t, db, 1, "user1")
+	insertTestUser(t, db, 2, "clerk1")
+	insertTestAddress(t, db, 1, 1)
+	insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 10)
+	insertTestFlower(t, db, "FLW002", "百合", 2000, 10)
+
+	orderRepo := NewOrderRepository(db)
+	flowerRepo := flower.NewFlowerRepository(db)
+	logRepo := NewOrderLogRepository(db)
+	service := NewOrderService(orderRepo, flowerRepo, logRepo)
+
+	orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+		AddressID: 1,
+		Items: []*CreateOrderItemRequest{
+			{FlowerSKU: "FLW001", Quantity: 3},
+			{FlowerSKU: "FLW002", Quantity: 2},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	o, items, _ := orderRepo.GetByOrderNo(ctx, orderNo)
+	var roseID int
+	for _, item := range items {
+		if item.FlowerSKU == "FLW001" {
+			roseID = item.ID
+		}
+	}
+
+	assertOrder := func(wantQty int, wantTotal int64, wantStock int) {
+		t.Helper()
+		updated, updatedItems, _ := orderRepo.GetByID(ctx, o.ID)
+		if updated.TotalAmount.Value != wantTotal {
+			t.Errorf("total = %d, want %d", updated.TotalAmount.Value, wantTotal)
+		}
+		for _, item := range updatedItems {
+			if item.ID == roseID && (item.Quantity != wantQty || item.Subtotal.Value != int64(wantQty)*1000) {
+				t.Errorf("item = (%d, %d), want (%d, %d)", item.Quantity, item.Subtotal.Value, wantQty, wantQty*1000)
+			}
+		}
+		f, _ := flowerRepo.GetBySKU(ctx, "FLW001")
+		if f.Stock != wantStock {
+			t.Errorf("FLW001 stock = %d, want %d", f.Stock, wantStock)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		itemID    int
+		qty       int
+		role      user.Role
+		wantKind  apperror.Kind
+		wantErr   bool
+		wantQty   int
+		wantTotal int64
+		wantStock int
+	}{
+		{name: "顾客无权修改", itemID: roseID, qty: 1, role: user.RoleCustomer, wantKind: apperror.KindForbidden, wantErr: true, wantQty: 3, wantTotal: 7000, wantStock: 7},
+		{name: "数量非法", itemID: roseID, qty: 0, role: user.RoleClerk, wantKind: apperror.KindValidation, wantErr: true, wantQty: 3, wantTotal: 7000, wantStock: 7},
+		{name: "订单项不存在", itemID: 99999, qty: 1, role: user.RoleClerk, wantKind: apperror.KindNotFound, wantErr: true, wantQty: 3, wantTotal: 7000, wantStock: 7},
+		{name: "增加数量超出库存被拒绝", itemID: roseID, qty: 11, role: user.RoleClerk, wantKind: apperror.KindValidation, wantErr: true, wantQty: 3, wantTotal: 7000, wantStock: 7},
+		{name: "减少数量回退库存", itemID: roseID, qty: 1, role: user.RoleClerk, wantQty: 1, wantTotal: 5000, wantStock: 9},
+		{name: "增加数量扣减库存", itemID: roseID, qty: 10, role: user.RoleAdmin, wantQty: 10, wantTotal: 14000, wantStock: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.UpdateOrderItemQuantity(ctx, o.ID, tt.itemID, tt.qty, 2, tt.role)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateOrderItemQuantity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && apperror.KindOf(err) != tt.wantKind {
+				t.Errorf("UpdateOrderItemQuantity() error kind = %v, want %v", apperror.KindOf(err), tt.wantKind)
+			}
+			assertOrder(tt.wantQty, tt.wantTotal, tt.wantStock)
+		})
+	}
+
+	logs, err := logRepo.GetLogs(ctx, o.ID, OrderLogFilter{Action: "update_item_quantity"})
+	if err != nil {
+		t.Fatalf("GetLogs() error = %v", err)
+	}
+	if len(logs) != 2 {
+		t.Errorf("update_item_quantity logs = %d, want 2", len(logs))
+	}
+
+	// 发货后不能再修改数量
+	if err := service.ShipOrder(ctx, o.ID, 2); err != nil {
+		t.Fatalf("ShipOrder() error = %v", err)
+	}
+	if err := service.UpdateOrderItemQuantity(ctx, o.ID, roseID, 2, 2, user.RoleClerk); apperror.KindOf(err) != apperror.KindValidation {
+		t.Errorf("UpdateOrderItemQuantity() on shipped order error = %v, want validation", err)
+	}
+}
+
+// TestOrderService_UpdateOrderItemQuantity_Limits 测试增加数量时校验数量上限、限购数量，并拒绝修改使用了优惠码的订单
+func TestOrderService_UpdateOrderItemQuantity_Limits(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxOrderQty interface{} // nil 表示不限购
+		limits      OrderLimits
+		code        string
+		qty         int
+		wantErr     error
+		wantKind    apperror.Kind // KindInternal 表示不按错误分类校验
+	}{
+		{name: "超过单项数量上限", limits: OrderLimits{MaxItemQuantity: 5}, qty: 6, wantErr: ErrExceedsMaxItemQty},
+		{name: "超过鲜花限购数量", maxOrderQty: 4, qty: 5, wantErr: ErrExceedsMaxOrderQty},
+		{name: "恰好达到限购数量", maxOrderQty: 4, limits: OrderLimits{MaxItemQuantity: 4}, qty: 4},
+		{name: "使用优惠码的订单不能修改", code: "SAVE", qty: 1, wantKind: apperror.KindValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t)
+			ctx := context.Background()
+
+			insertTestUser(t, db, 1, "user1")
+			insertTestAddress(t, db, 1, 1)
+			insertTestFlower(t, db, "FLW001", "红玫瑰", 1000, 100)
+
+			discountRepo := discount.NewDiscountRepository(db)
+			if err := discountRepo.Create(ctx, &discount.Discount{Code: "SAVE", Type: discount.TypeFixed, Value: 500}); err != nil {
+				t.Fatalf("Create discount error = %v", err)
+			}
+			service := NewOrderServiceWithOptions(NewOrderRepository(db), flower.NewFlowerRepository(db), NewOrderLogRepository(db),
+				OrderServiceOptions{DiscountRepo: discountRepo, Limits: tt.limits})
+
+			orderNo, err := service.CreateOrder(ctx, 1, &CreateOrderRequest{
+				AddressID:    1,
+				Items:        []*CreateOrderItemRequest{{FlowerSKU: "FLW001", Quantity: 2}},
+				DiscountCode: tt.code,
+			})
+			if err != nil {
+				t.Fatalf("CreateOrder() error = %v", err)
+			}
+			// 下单后再设置限购，模拟限购在下单之后收紧
+			if _, err := db.Exec("UPDATE flowers SET max_order_qty = ? WHERE sku = ?", tt.maxOrderQty, "FLW001"); err != nil {
+				t.Fatalf("failed to set max_order_qty: %v", err)
+			}
+			o, items, err := NewOrderRepository(db).GetByOrderNo(ctx, orderNo)
+			if err != nil {
+				t.Fatalf("GetByOrderNo() error = %v", err)
+			}
+
+			err = service.UpdateOrderItemQuantity(ctx, o.ID, items[0].ID, tt.qty, 1, user.RoleClerk)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("UpdateOrderItemQuantity() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantKind != apperror.KindInternal:
+				if apperror.KindOf(err) != tt.wantKind {
+					t.Fatalf("UpdateOrderItemQuantity() error = %v, want kind %v", err, tt.wantKind)
+				}
+			case err != nil:
+				t.Fatalf("UpdateOrderItemQuantity() unexpected error = %v", err)
+			}
+
+			// 被拒绝的修改不应改变数量与总额
+			updated, updatedItems, _ := NewOrderRepository(db).GetByID(ctx, o.ID)
+			wantQty, wantTotal := 2, o.TotalAmount.Value
+			if tt.wantErr == nil && tt.wantKind == apperror.KindInternal {
+				wantQty, wantTotal = tt.qty, int64(tt.qty)*1000
+			}
+			if updatedItems[0].Quantity != wantQty || updated.TotalAmount.Value != wantTotal {
+				t.Errorf("item quantity = %d, total = %d, want %d, %d", updatedItems[0].Quantity, updated.TotalAmount.Value, wantQty, wantTotal)
+			}
+		})
+	}
+}
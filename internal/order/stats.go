@@ -0,0 +1,62 @@
+package order
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+)
+
+// Stats 订单业务事件计数器（进程内，自启动起累计，重启清零）
+// 各计数使用原子操作，可被并发请求安全更新
+type Stats struct {
+	startedAt    time.Time
+	created      atomic.Int64
+	completed    atomic.Int64
+	cancelled    atomic.Int64
+	revenueCents atomic.Int64 // 仅累计已完成订单的金额
+}
+
+// StatsSnapshot 计数器快照
+type StatsSnapshot struct {
+	Since           time.Time      `json:"since"`
+	OrdersCreated   int64          `json:"orders_created"`
+	OrdersCompleted int64          `json:"orders_completed"`
+	OrdersCancelled int64          `json:"orders_cancelled"`
+	Revenue         flower.Decimal `json:"revenue"`       // 两位小数
+	RevenueCents    int64          `json:"revenue_cents"` // 以分为单位
+}
+
+// NewStats 创建计数器
+func NewStats() *Stats {
+	return &Stats{startedAt: time.Now()}
+}
+
+// recordCreated 记录一笔订单创建
+func (s *Stats) recordCreated() {
+	s.created.Add(1)
+}
+
+// recordCompleted 记录一笔订单完成，并累计营收
+func (s *Stats) recordCompleted(amount flower.Decimal) {
+	s.completed.Add(1)
+	s.revenueCents.Add(amount.Cents())
+}
+
+// recordCancelled 记录一笔订单取消
+func (s *Stats) recordCancelled() {
+	s.cancelled.Add(1)
+}
+
+// Snapshot 返回当前计数
+func (s *Stats) Snapshot() StatsSnapshot {
+	revenue := s.revenueCents.Load()
+	return StatsSnapshot{
+		Since:           s.startedAt,
+		OrdersCreated:   s.created.Load(),
+		OrdersCompleted: s.completed.Load(),
+		OrdersCancelled: s.cancelled.Load(),
+		Revenue:         flower.Decimal{Value: revenue},
+		RevenueCents:    revenue,
+	}
+}
@@ -0,0 +1,104 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+)
+
+// ErrStockDrift 完成订单时发现库存状态异常（如库存为负），扣减与实际库存不一致
+var ErrStockDrift = apperror.New(apperror.KindConflict, "库存状态异常")
+
+// ErrCatalogDrift 完成订单时发现订单项对应的鲜花已删除或已下架
+var ErrCatalogDrift = apperror.New(apperror.KindConflict, "商品目录异常")
+
+// StockCheckMode 完成订单时的核对策略，库存核对与商品目录核对共用
+type StockCheckMode string
+
+const (
+	// StockCheckOff 不核对（默认）
+	StockCheckOff StockCheckMode = "off"
+	// StockCheckWarn 核对异常时仅记录警告，订单照常完成
+	StockCheckWarn StockCheckMode = "warn"
+	// StockCheckStrict 核对异常时拒绝完成订单
+	StockCheckStrict StockCheckMode = "strict"
+)
+
+// ParseStockCheckMode 解析核对策略，空字符串视为 off
+func ParseStockCheckMode(s string) (StockCheckMode, error) {
+	switch mode := StockCheckMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return StockCheckOff, nil
+	case StockCheckOff, StockCheckWarn, StockCheckStrict:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("无效的核对策略: %s（可选 off/warn/strict）", s)
+	}
+}
+
+// checkItemsStock 核对订单项对应鲜花的库存状态
+// 下单时已扣减库存，此时任一鲜花库存为负说明扣减与实际不一致
+// 已删除的鲜花不参与核对（由商品目录核对负责）
+func (s *orderService) checkItemsStock(ctx context.Context, items []*OrderItem) error {
+	var drifted []string
+	for _, item := range items {
+		flw, err := s.flowerRepo.GetBySKU(ctx, item.FlowerSKU)
+		if err != nil {
+			continue
+		}
+		if flw.Stock < 0 {
+			drifted = append(drifted, fmt.Sprintf("%s(库存 %d)", item.FlowerSKU, flw.Stock))
+		}
+	}
+
+	if len(drifted) > 0 {
+		return fmt.Errorf("%w: %s", ErrStockDrift, strings.Join(drifted, ", "))
+	}
+	return nil
+}
+
+// checkItemsCatalog 核对订单项对应鲜花是否仍存在且在售
+// 下单后鲜花可能被删除或下架，履约时应提醒工作人员处理目录不一致
+func (s *orderService) checkItemsCatalog(ctx context.Context, items []*OrderItem) error {
+	var problems []string
+	for _, item := range items {
+		flw, err := s.flowerRepo.GetBySKU(ctx, item.FlowerSKU)
+		if errors.Is(err, flower.ErrFlowerNotFound) {
+			problems = append(problems, fmt.Sprintf("%s(已删除)", item.FlowerSKU))
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("核对商品目录失败: %w", err)
+		}
+		if !flw.IsActive {
+			problems = append(problems, fmt.Sprintf("%s(已下架)", item.FlowerSKU))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrCatalogDrift, strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// runCompletionCheck 按策略执行完成订单前的核对
+// off 跳过；warn 仅记录警告；strict 核对失败时返回错误，拒绝完成
+func (s *orderService) runCompletionCheck(mode StockCheckMode, order *Order, check func() error) error {
+	if mode != StockCheckWarn && mode != StockCheckStrict {
+		return nil
+	}
+	err := check()
+	if err == nil {
+		return nil
+	}
+	if mode == StockCheckStrict {
+		return fmt.Errorf("订单 %s 无法完成: %w", order.OrderNo, err)
+	}
+	slog.Warn("order completed with stock inconsistency", "order_no", order.OrderNo, "error", err)
+	return nil
+}
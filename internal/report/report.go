@@ -0,0 +1,89 @@
+package report
+
+import (
+	"time"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+)
+
+// DateLayout 报表日期格式
+const DateLayout = "2006-01-02"
+
+// MaxReportDays 单次报表允许查询的最大天数
+const MaxReportDays = 366
+
+// TopSellingLimit 销售报表中热销鲜花的数量
+const TopSellingLimit = 10
+
+// DailyCount 表示某一天的计数
+type DailyCount struct {
+	Date  string `json:"date"` // YYYY-MM-DD
+	Count int    `json:"count"`
+}
+
+// DateRange 报表日期区间（按天，包含首尾两天）
+type DateRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Validate 验证日期区间是否有效
+func (r DateRange) Validate() error {
+	if r.Start.IsZero() || r.End.IsZero() {
+		return apperror.Validation("开始日期和结束日期不能为空")
+	}
+	if r.End.Before(r.Start) {
+		return apperror.Validation("结束日期不能早于开始日期")
+	}
+	if r.Days() > MaxReportDays {
+		return apperror.Validation("查询区间不能超过%d天", MaxReportDays)
+	}
+	return nil
+}
+
+// Days 返回区间包含的天数
+func (r DateRange) Days() int {
+	return int(truncateDay(r.End).Sub(truncateDay(r.Start)).Hours()/24) + 1
+}
+
+// truncateDay 将时间截断到当天零点（UTC）
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// BlockedOrder 表示因库存不足而暂时无法履约的待处理订单
+type BlockedOrder struct {
+	OrderID   int            `json:"order_id"`
+	OrderNo   string         `json:"order_no"`
+	UserID    int            `json:"user_id"`
+	CreatedAt time.Time      `json:"created_at"`
+	Items     []*BlockedItem `json:"items"` // 缺货的订单项
+}
+
+// BlockedItem 表示缺货的订单项
+type BlockedItem struct {
+	FlowerSKU    string `json:"flower_sku"`
+	FlowerName   string `json:"flower_name"`
+	Quantity     int    `json:"quantity"`
+	CurrentStock int    `json:"current_stock"`
+}
+
+// SalesReport 销售汇总报表：区间内按下单时间统计
+type SalesReport struct {
+	Start             string         `json:"start"`               // YYYY-MM-DD
+	End               string         `json:"end"`                 // YYYY-MM-DD
+	TotalRevenue      flower.Decimal `json:"total_revenue"`       // 已完成订单金额合计，两位小数
+	TotalRevenueCents int64          `json:"total_revenue_cents"` // 以分为单位
+	OrderCounts       map[string]int `json:"order_counts"`        // 按状态统计的订单数
+	TopSelling        []*FlowerSales `json:"top_selling"`         // 已完成订单中按销量排序的鲜花
+}
+
+// FlowerSales 单个鲜花的销量
+type FlowerSales struct {
+	FlowerSKU   string         `json:"flower_sku"`
+	FlowerName  string         `json:"flower_name"`
+	Quantity    int            `json:"quantity"`
+	Amount      flower.Decimal `json:"amount"`       // 两位小数
+	AmountCents int64          `json:"amount_cents"` // 以分为单位
+}
@@ -0,0 +1,210 @@
+package report
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+)
+
+// ReportRepository 定义报表数据访问接口
+type ReportRepository interface {
+	CountNewUsersByDay(ctx context.Context, r DateRange) (map[string]int, error)
+	ListBlockedOrders(ctx context.Context) ([]*BlockedOrder, error)
+	SumCompletedRevenue(ctx context.Context, r DateRange) (int64, error)
+	CountOrdersByStatus(ctx context.Context, r DateRange) (map[string]int, error)
+	ListTopSellingFlowers(ctx context.Context, r DateRange, limit int) ([]*FlowerSales, error)
+}
+
+// reportRepository 实现 ReportRepository 接口
+type reportRepository struct {
+	db *sql.DB
+}
+
+// NewReportRepository 创建 ReportRepository 实例
+func NewReportRepository(db *sql.DB) ReportRepository {
+	return &reportRepository{db: db}
+}
+
+// CountNewUsersByDay 按注册日期统计新用户数量（单条 GROUP BY 查询）
+func (r *reportRepository) CountNewUsersByDay(ctx context.Context, dr DateRange) (map[string]int, error) {
+	query := `
+		SELECT DATE(created_at) AS day, COUNT(*)
+		FROM users
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY DATE(created_at)
+	`
+
+	start, end := dayBounds(dr)
+
+	rows, err := r.db.QueryContext(ctx, query, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("count new users by day: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var day string
+		var count int
+		if err := rows.Scan(&day, &count); err != nil {
+			return nil, fmt.Errorf("scan new user count: %w", err)
+		}
+		counts[normalizeDay(day)] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate new user counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// ListBlockedOrders 查询包含缺货商品（当前库存 <= 0）的待处理订单
+// 通过 order_items 与 flowers 当前库存关联，一次查询返回所有缺货订单项
+func (r *reportRepository) ListBlockedOrders(ctx context.Context) ([]*BlockedOrder, error) {
+	query := `
+		SELECT o.id, o.order_no, o.user_id, o.created_at,
+			oi.flower_sku, oi.flower_name, oi.quantity, f.stock
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.id
+		JOIN flowers f ON f.sku = oi.flower_sku
+		WHERE o.status = 'pending' AND f.stock <= 0
+		ORDER BY o.created_at ASC, o.id ASC, oi.id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list blocked orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []*BlockedOrder
+	var current *BlockedOrder
+	for rows.Next() {
+		var o BlockedOrder
+		var item BlockedItem
+		err := rows.Scan(&o.OrderID, &o.OrderNo, &o.UserID, &o.CreatedAt,
+			&item.FlowerSKU, &item.FlowerName, &item.Quantity, &item.CurrentStock)
+		if err != nil {
+			return nil, fmt.Errorf("scan blocked order: %w", err)
+		}
+
+		// 结果按订单排序，相邻行属于同一订单时合并订单项
+		if current == nil || current.OrderID != o.OrderID {
+			current = &o
+			orders = append(orders, current)
+		}
+		current.Items = append(current.Items, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate blocked orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// SumCompletedRevenue 统计区间内下单且已完成订单的金额合计（分）
+func (r *reportRepository) SumCompletedRevenue(ctx context.Context, dr DateRange) (int64, error) {
+	query := `
+		SELECT COALESCE(SUM(total_amount), 0)
+		FROM orders
+		WHERE status = 'completed' AND created_at >= ? AND created_at < ?
+	`
+
+	start, end := dayBounds(dr)
+
+	var revenue int64
+	if err := r.db.QueryRowContext(ctx, query, start, end).Scan(&revenue); err != nil {
+		return 0, fmt.Errorf("sum completed revenue: %w", err)
+	}
+	return revenue, nil
+}
+
+// CountOrdersByStatus 按状态统计区间内下单的订单数（单条 GROUP BY 查询）
+func (r *reportRepository) CountOrdersByStatus(ctx context.Context, dr DateRange) (map[string]int, error) {
+	query := `
+		SELECT status, COUNT(*)
+		FROM orders
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY status
+	`
+
+	start, end := dayBounds(dr)
+
+	rows, err := r.db.QueryContext(ctx, query, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("count orders by status: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("scan order count: %w", err)
+		}
+		counts[status] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate order counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// ListTopSellingFlowers 统计区间内已完成订单中各鲜花的销量，按销量降序返回前 limit 个
+// 鲜花名称取订单项中的下单快照
+func (r *reportRepository) ListTopSellingFlowers(ctx context.Context, dr DateRange, limit int) ([]*FlowerSales, error) {
+	query := `
+		SELECT oi.flower_sku, MAX(oi.flower_name), SUM(oi.quantity) AS total_qty, SUM(oi.subtotal)
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE o.status = 'completed' AND o.created_at >= ? AND o.created_at < ?
+		GROUP BY oi.flower_sku
+		ORDER BY total_qty DESC, oi.flower_sku ASC
+		LIMIT ?
+	`
+
+	start, end := dayBounds(dr)
+
+	rows, err := r.db.QueryContext(ctx, query, start, end, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list top selling flowers: %w", err)
+	}
+	defer rows.Close()
+
+	sales := []*FlowerSales{}
+	for rows.Next() {
+		var s FlowerSales
+		if err := rows.Scan(&s.FlowerSKU, &s.FlowerName, &s.Quantity, &s.AmountCents); err != nil {
+			return nil, fmt.Errorf("scan flower sales: %w", err)
+		}
+		s.Amount = flower.Decimal{Value: s.AmountCents}
+		sales = append(sales, &s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate flower sales: %w", err)
+	}
+
+	return sales, nil
+}
+
+// dayBounds 将日期区间转换为查询边界 [start, end)，end 为结束日期的次日零点
+func dayBounds(dr DateRange) (string, string) {
+	return truncateDay(dr.Start).Format(DateLayout), truncateDay(dr.End).AddDate(0, 0, 1).Format(DateLayout)
+}
+
+// normalizeDay 统一不同驱动返回的日期格式
+// MySQL (parseTime=true) 返回 RFC3339 时间，SQLite 返回 YYYY-MM-DD
+func normalizeDay(day string) string {
+	if len(day) > len(DateLayout) {
+		return day[:len(DateLayout)]
+	}
+	return day
+}
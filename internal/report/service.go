@@ -0,0 +1,88 @@
+package report
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/flower"
+)
+
+// ReportService 定义报表业务逻辑接口
+type ReportService interface {
+	NewUserReport(ctx context.Context, r DateRange) ([]*DailyCount, error)
+	BlockedOrders(ctx context.Context) ([]*BlockedOrder, error)
+	SalesReport(ctx context.Context, r DateRange) (*SalesReport, error)
+}
+
+// reportService 实现 ReportService 接口
+type reportService struct {
+	repo ReportRepository
+}
+
+// NewReportService 创建 ReportService 实例
+func NewReportService(repo ReportRepository) ReportService {
+	return &reportService{repo: repo}
+}
+
+// NewUserReport 获取每日新增用户数，没有注册的日期补 0
+func (s *reportService) NewUserReport(ctx context.Context, r DateRange) ([]*DailyCount, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	counts, err := s.repo.CountNewUsersByDay(ctx, r)
+	if err != nil {
+		return nil, fmt.Errorf("统计新增用户失败: %w", err)
+	}
+
+	series := make([]*DailyCount, 0, r.Days())
+	for day := truncateDay(r.Start); !day.After(truncateDay(r.End)); day = day.AddDate(0, 0, 1) {
+		key := day.Format(DateLayout)
+		series = append(series, &DailyCount{Date: key, Count: counts[key]})
+	}
+
+	return series, nil
+}
+
+// BlockedOrders 获取因缺货而无法履约的待处理订单
+func (s *reportService) BlockedOrders(ctx context.Context) ([]*BlockedOrder, error) {
+	orders, err := s.repo.ListBlockedOrders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("查询缺货订单失败: %w", err)
+	}
+	if orders == nil {
+		orders = []*BlockedOrder{}
+	}
+	return orders, nil
+}
+
+// SalesReport 获取区间内的销售汇总：已完成订单营收、各状态订单数与热销鲜花
+func (s *reportService) SalesReport(ctx context.Context, r DateRange) (*SalesReport, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	revenue, err := s.repo.SumCompletedRevenue(ctx, r)
+	if err != nil {
+		return nil, fmt.Errorf("统计营收失败: %w", err)
+	}
+
+	counts, err := s.repo.CountOrdersByStatus(ctx, r)
+	if err != nil {
+		return nil, fmt.Errorf("统计订单数失败: %w", err)
+	}
+
+	topSelling, err := s.repo.ListTopSellingFlowers(ctx, r, TopSellingLimit)
+	if err != nil {
+		return nil, fmt.Errorf("统计热销鲜花失败: %w", err)
+	}
+
+	return &SalesReport{
+		Start:             r.Start.Format(DateLayout),
+		End:               r.End.Format(DateLayout),
+		TotalRevenue:      flower.Decimal{Value: revenue},
+		TotalRevenueCents: revenue,
+		OrderCounts:       counts,
+		TopSelling:        topSelling,
+	}, nil
+}
@@ -0,0 +1,330 @@
+package report
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite 驱动
+)
+
+// setupReportTestDB 创建报表测试数据库
+func setupReportTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+
+	createTableSQL := `
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT UNIQUE NOT NULL,
+		email TEXT UNIQUE,
+		password_hash TEXT NOT NULL,
+		role TEXT NOT NULL DEFAULT 'customer',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS flowers (
+		sku TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		origin TEXT NOT NULL,
+		shelf_life TEXT NOT NULL,
+		preservation TEXT NOT NULL,
+		purchase_price INTEGER NOT NULL,
+		sale_price INTEGER NOT NULL,
+		stock INTEGER NOT NULL DEFAULT 0,
+		max_order_qty INTEGER,
+		discount_price INTEGER,
+		discount_until DATETIME,
+		is_active INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS orders (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_no TEXT UNIQUE NOT NULL,
+		user_id INTEGER NOT NULL,
+		address_id INTEGER NOT NULL,
+		total_amount INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS order_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_id INTEGER NOT NULL,
+		flower_sku TEXT NOT NULL,
+		flower_name TEXT NOT NULL,
+		quantity INTEGER NOT NULL,
+		unit_price INTEGER NOT NULL,
+		subtotal INTEGER NOT NULL
+	);
+	`
+
+	if _, err := db.Exec(createTableSQL); err != nil {
+		db.Close()
+		t.Fatalf("failed to create tables: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	return db
+}
+
+// insertUserAt 插入指定注册时间的用户
+func insertUserAt(t *testing.T, db *sql.DB, username, createdAt string) {
+	t.Helper()
+	_, err := db.Exec("INSERT INTO users (username, password_hash, created_at) VALUES (?, ?, ?)",
+		username, "hash", createdAt)
+	if err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+}
+
+// mustDate 解析 YYYY-MM-DD 日期
+func mustDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(DateLayout, s)
+	if err != nil {
+		t.Fatalf("invalid date %q: %v", s, err)
+	}
+	return d
+}
+
+// TestReportService_NewUserReport 测试每日新增用户报表（含补零）
+func TestReportService_NewUserReport(t *testing.T) {
+	db := setupReportTestDB(t)
+	ctx := context.Background()
+
+	insertUserAt(t, db, "u1", "2026-01-09 23:59:59") // 区间外
+	insertUserAt(t, db, "u2", "2026-01-10 00:00:00")
+	insertUserAt(t, db, "u3", "2026-01-10 18:30:00")
+	insertUserAt(t, db, "u4", "2026-01-12 09:00:00")
+	insertUserAt(t, db, "u5", "2026-01-13 23:59:59")
+	insertUserAt(t, db, "u6", "2026-01-14 00:00:00") // 区间外
+
+	service := NewReportService(NewReportRepository(db))
+
+	series, err := service.NewUserReport(ctx, DateRange{
+		Start: mustDate(t, "2026-01-10"),
+		End:   mustDate(t, "2026-01-13"),
+	})
+	if err != nil {
+		t.Fatalf("NewUserReport() error = %v", err)
+	}
+
+	want := []DailyCount{
+		{Date: "2026-01-10", Count: 2},
+		{Date: "2026-01-11", Count: 0},
+		{Date: "2026-01-12", Count: 1},
+		{Date: "2026-01-13", Count: 1},
+	}
+
+	if len(series) != len(want) {
+		t.Fatalf("NewUserReport() returned %d days, want %d", len(series), len(want))
+	}
+	for i, w := range want {
+		if *series[i] != w {
+			t.Errorf("series[%d] = %+v, want %+v", i, *series[i], w)
+		}
+	}
+}
+
+// TestDateRange_Validate 测试日期区间验证
+func TestDateRange_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		start   string
+		end     string
+		wantErr bool
+	}{
+		{"同一天", "2026-01-10", "2026-01-10", false},
+		{"正常区间", "2026-01-01", "2026-01-31", false},
+		{"结束早于开始", "2026-01-10", "2026-01-09", true},
+		{"区间过长", "2024-01-01", "2026-01-01", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dr := DateRange{Start: mustDate(t, tt.start), End: mustDate(t, tt.end)}
+			if err := dr.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// insertFlowerWithStock 插入指定库存的鲜花
+func insertFlowerWithStock(t *testing.T, db *sql.DB, sku, name string, stock int) {
+	t.Helper()
+	_, err := db.Exec(`INSERT INTO flowers (sku, name, origin, shelf_life, preservation, purchase_price, sale_price, stock)
+		VALUES (?, ?, '云南', '7天', '冷藏', 500, 1000, ?)`, sku, name, stock)
+	if err != nil {
+		t.Fatalf("failed to insert flower: %v", err)
+	}
+}
+
+// insertOrderWithItems 插入订单及其订单项（sku -> 数量）
+func insertOrderWithItems(t *testing.T, db *sql.DB, id int, status string, items map[string]int) {
+	t.Helper()
+	_, err := db.Exec(`INSERT INTO orders (id, order_no, user_id, address_id, total_amount, status)
+		VALUES (?, ?, 1, 1, 0, ?)`, id, fmt.Sprintf("ORD%03d", id), status)
+	if err != nil {
+		t.Fatalf("failed to insert order: %v", err)
+	}
+	for sku, qty := range items {
+		_, err := db.Exec(`INSERT INTO order_items (order_id, flower_sku, flower_name, quantity, unit_price, subtotal)
+			VALUES (?, ?, ?, ?, 1000, ?)`, id, sku, sku, qty, qty*1000)
+		if err != nil {
+			t.Fatalf("failed to insert order item: %v", err)
+		}
+	}
+}
+
+// TestReportService_BlockedOrders 测试缺货待处理订单报表
+func TestReportService_BlockedOrders(t *testing.T) {
+	db := setupReportTestDB(t)
+	ctx := context.Background()
+
+	insertFlowerWithStock(t, db, "ROSE", "红玫瑰", 0)   // 已售罄
+	insertFlowerWithStock(t, db, "LILY", "白百合", 20)  // 库存充足
+	insertFlowerWithStock(t, db, "TULIP", "郁金香", -2) // 超卖导致负库存
+
+	insertOrderWithItems(t, db, 1, "pending", map[string]int{"ROSE": 3, "LILY": 1}) // 缺货
+	insertOrderWithItems(t, db, 2, "pending", map[string]int{"LILY": 2})            // 正常
+	insertOrderWithItems(t, db, 3, "completed", map[string]int{"ROSE": 1})          // 已完成，不关注
+	insertOrderWithItems(t, db, 4, "pending", map[string]int{"TULIP": 5})           // 缺货
+
+	service := NewReportService(NewReportRepository(db))
+
+	orders, err := service.BlockedOrders(ctx)
+	if err != nil {
+		t.Fatalf("BlockedOrders() error = %v", err)
+	}
+
+	if len(orders) != 2 {
+		t.Fatalf("BlockedOrders() returned %d orders, want 2", len(orders))
+	}
+
+	if orders[0].OrderID != 1 || orders[1].OrderID != 4 {
+		t.Errorf("BlockedOrders() order ids = [%d %d], want [1 4]", orders[0].OrderID, orders[1].OrderID)
+	}
+
+	// 只列出缺货的订单项
+	if len(orders[0].Items) != 1 || orders[0].Items[0].FlowerSKU != "ROSE" {
+		t.Errorf("order 1 blocked items = %+v, want only ROSE", orders[0].Items)
+	}
+	if orders[1].Items[0].CurrentStock != -2 {
+		t.Errorf("order 4 current stock = %d, want -2", orders[1].Items[0].CurrentStock)
+	}
+}
+
+// TestReportService_BlockedOrders_Empty 测试没有缺货订单时返回空列表
+func TestReportService_BlockedOrders_Empty(t *testing.T) {
+	db := setupReportTestDB(t)
+	service := NewReportService(NewReportRepository(db))
+
+	orders, err := service.BlockedOrders(context.Background())
+	if err != nil {
+		t.Fatalf("BlockedOrders() error = %v", err)
+	}
+	if orders == nil || len(orders) != 0 {
+		t.Errorf("BlockedOrders() = %v, want empty slice", orders)
+	}
+}
+
+// TestReportService_Canceled 测试报表查询使用调用方 context，取消后返回 context.Canceled
+func TestReportService_Canceled(t *testing.T) {
+	db := setupReportTestDB(t)
+	service := NewReportService(NewReportRepository(db))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dr := DateRange{Start: mustDate(t, "2026-01-01"), End: mustDate(t, "2026-01-07")}
+	if _, err := service.NewUserReport(ctx, dr); !errors.Is(err, context.Canceled) {
+		t.Errorf("NewUserReport() error = %v, want context.Canceled", err)
+	}
+	if _, err := service.BlockedOrders(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("BlockedOrders() error = %v, want context.Canceled", err)
+	}
+}
+
+// insertSalesOrder 插入指定下单时间的订单及其订单项（sku -> 数量，单价 10.00），订单金额为订单项小计之和
+func insertSalesOrder(t *testing.T, db *sql.DB, id int, status, createdAt string, items map[string]int) {
+	t.Helper()
+	total := 0
+	for _, qty := range items {
+		total += qty * 1000
+	}
+	_, err := db.Exec(`INSERT INTO orders (id, order_no, user_id, address_id, total_amount, status, created_at)
+		VALUES (?, ?, 1, 1, ?, ?, ?)`, id, fmt.Sprintf("ORD%03d", id), total, status, createdAt)
+	if err != nil {
+		t.Fatalf("failed to insert order: %v", err)
+	}
+	for sku, qty := range items {
+		_, err := db.Exec(`INSERT INTO order_items (order_id, flower_sku, flower_name, quantity, unit_price, subtotal)
+			VALUES (?, ?, ?, ?, 1000, ?)`, id, sku, sku, qty, qty*1000)
+		if err != nil {
+			t.Fatalf("failed to insert order item: %v", err)
+		}
+	}
+}
+
+// TestReportService_SalesReport 测试销售汇总：营收只计已完成订单，订单数按状态统计，热销按销量排序
+func TestReportService_SalesReport(t *testing.T) {
+	db := setupReportTestDB(t)
+	ctx := context.Background()
+
+	insertSalesOrder(t, db, 1, "completed", "2026-01-10 09:00:00", map[string]int{"ROSE": 3, "LILY": 1})
+	insertSalesOrder(t, db, 2, "completed", "2026-01-12 18:00:00", map[string]int{"LILY": 5})
+	insertSalesOrder(t, db, 3, "pending", "2026-01-11 10:00:00", map[string]int{"ROSE": 10})
+	insertSalesOrder(t, db, 4, "cancelled", "2026-01-11 11:00:00", map[string]int{"TULIP": 20})
+	insertSalesOrder(t, db, 6, "completed", "2026-01-14 00:00:00", map[string]int{"ROSE": 50}) // 区间外
+
+	service := NewReportService(NewReportRepository(db))
+
+	sales, err := service.SalesReport(ctx, DateRange{Start: mustDate(t, "2026-01-10"), End: mustDate(t, "2026-01-13")})
+	if err != nil {
+		t.Fatalf("SalesReport() error = %v", err)
+	}
+
+	if sales.TotalRevenueCents != 9000 || sales.TotalRevenue.String() != "90.00" {
+		t.Errorf("revenue = %s (%d cents), want 90.00", sales.TotalRevenue, sales.TotalRevenueCents)
+	}
+
+	wantCounts := map[string]int{"completed": 2, "pending": 1, "cancelled": 1}
+	if len(sales.OrderCounts) != len(wantCounts) {
+		t.Errorf("order counts = %v, want %v", sales.OrderCounts, wantCounts)
+	}
+	for status, want := range wantCounts {
+		if got := sales.OrderCounts[status]; got != want {
+			t.Errorf("order counts[%s] = %d, want %d", status, got, want)
+		}
+	}
+
+	if len(sales.TopSelling) != 2 {
+		t.Fatalf("top selling = %d flowers, want 2", len(sales.TopSelling))
+	}
+	if top := sales.TopSelling[0]; top.FlowerSKU != "LILY" || top.Quantity != 6 || top.AmountCents != 6000 {
+		t.Errorf("top selling[0] = %+v, want LILY x6", top)
+	}
+	if second := sales.TopSelling[1]; second.FlowerSKU != "ROSE" || second.Quantity != 3 {
+		t.Errorf("top selling[1] = %+v, want ROSE x3", second)
+	}
+
+	if _, err := service.SalesReport(ctx, DateRange{Start: mustDate(t, "2026-01-13"), End: mustDate(t, "2026-01-10")}); err == nil {
+		t.Error("SalesReport() should fail when end is before start")
+	}
+}
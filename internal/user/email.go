@@ -0,0 +1,39 @@
+package user
+
+import (
+	"net/mail"
+	"strings"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+)
+
+// MaxEmailLength 邮箱地址的最大长度
+const MaxEmailLength = 254
+
+// ErrInvalidEmail 邮箱格式无效
+var ErrInvalidEmail = apperror.New(apperror.KindValidation, "邮箱格式无效")
+
+// NormalizeEmail 去除首尾空白并转为小写，邮箱按规范化后的值存储与比较
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateEmail 校验邮箱格式：必须是不带显示名的单个地址，域名包含点号
+// 只做基本的格式检查，邮箱是否真实可达需由后续的验证流程确认
+func ValidateEmail(email string) error {
+	if email == "" || len(email) > MaxEmailLength {
+		return ErrInvalidEmail
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return ErrInvalidEmail
+	}
+
+	at := strings.LastIndex(email, "@")
+	domain := email[at+1:]
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return ErrInvalidEmail
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package user
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy 密码强度策略，注册、重置密码与修改密码共用
+type PasswordPolicy struct {
+	MinLength     int  // 最小字符数
+	RequireDigit  bool // 必须包含数字
+	RequireLetter bool // 必须包含字母
+}
+
+// DefaultPasswordPolicy 默认密码策略：至少 6 个字符，不限制字符类型
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 6}
+
+// ValidatePassword 按策略校验密码，不满足时返回包装 ErrInvalidPassword 的错误并说明原因
+func ValidatePassword(password string, policy PasswordPolicy) error {
+	if password == "" {
+		return fmt.Errorf("%w: 密码不能为空", ErrInvalidPassword)
+	}
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		return fmt.Errorf("%w: 密码长度不能少于%d个字符", ErrInvalidPassword, policy.MinLength)
+	}
+
+	var hasDigit, hasLetter bool
+	for _, r := range password {
+		switch {
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsLetter(r):
+			hasLetter = true
+		}
+	}
+	if policy.RequireDigit && !hasDigit {
+		return fmt.Errorf("%w: 密码必须包含数字", ErrInvalidPassword)
+	}
+	if policy.RequireLetter && !hasLetter {
+		return fmt.Errorf("%w: 密码必须包含字母", ErrInvalidPassword)
+	}
+	return nil
+}
+
+// PepperPassword 将服务端 pepper 混入密码，返回交给 bcrypt 的输入
+// pepper 为空时原样返回密码，兼容未配置 pepper 时生成的哈希；
+// 配置 pepper 时使用 HMAC-SHA256 并做 base64 编码，结果固定 44 字节，不会触发 bcrypt 的 72 字节上限
+//
+// 注意：pepper 不随哈希存储，更换或丢失 pepper 会导致所有已有密码无法验证，
+// 轮换时需要让用户重置密码（或在迁移期间同时校验新旧 pepper）
+func PepperPassword(password, pepper string) []byte {
+	if pepper == "" {
+		return []byte(password)
+	}
+
+	mac := hmac.New(sha256.New, []byte(pepper))
+	mac.Write([]byte(password))
+	sum := mac.Sum(nil)
+
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(sum)))
+	base64.StdEncoding.Encode(encoded, sum)
+	return encoded
+}
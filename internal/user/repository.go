@@ -3,7 +3,12 @@ package user
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
+	"github.com/go-sql-driver/mysql"
 )
 
 // UserRepository 用户数据访问接口
@@ -11,9 +16,13 @@ type UserRepository interface {
 	Create(ctx context.Context, u *User) error
 	GetByID(ctx context.Context, id int) (*User, error)
 	GetByUsername(ctx context.Context, username string) (*User, error)
-	List(ctx context.Context, page, pageSize int) ([]*User, error)
+	// List 分页获取用户，sortBy 见 UserSortOptions，为空时按 ID 排序
+	List(ctx context.Context, page, pageSize int, sortBy string) ([]*User, error)
+	Count(ctx context.Context) (int, error)
 	Delete(ctx context.Context, id int) error
 	UpdatePassword(ctx context.Context, id int, passwordHash string) error
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	UpdateEmail(ctx context.Context, id int, email string) error
 }
 
 // MySQLUserRepository MySQL 用户数据访问实现
@@ -29,11 +38,18 @@ func NewMySQLUserRepository(db *sql.DB) *MySQLUserRepository {
 // Create 创建新用户
 func (r *MySQLUserRepository) Create(ctx context.Context, u *User) error {
 	query := `
-		INSERT INTO users (username, password_hash, role)
-		VALUES (?, ?, ?)
+		INSERT INTO users (username, email, password_hash, role)
+		VALUES (?, ?, ?, ?)
 	`
-	result, err := r.db.ExecContext(ctx, query, u.Username, u.PasswordHash, u.Role)
+	result, err := r.db.ExecContext(ctx, query, u.Username, nullEmail(u.Email), u.PasswordHash, u.Role)
 	if err != nil {
+		// 并发注册同名用户或相同邮箱时由唯一约束兜底
+		if isDuplicateKey(err) && isEmailKey(err) {
+			return fmt.Errorf("%w: %s", ErrEmailTaken, u.Email)
+		}
+		if isDuplicateKey(err) {
+			return fmt.Errorf("%w: %s", ErrUsernameTaken, u.Username)
+		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
@@ -57,14 +73,16 @@ func (r *MySQLUserRepository) Create(ctx context.Context, u *User) error {
 // GetByID 根据 ID 获取用户
 func (r *MySQLUserRepository) GetByID(ctx context.Context, id int) (*User, error) {
 	query := `
-		SELECT id, username, password_hash, role, created_at, updated_at
+		SELECT id, username, email, password_hash, role, created_at, updated_at
 		FROM users
 		WHERE id = ?
 	`
 	user := &User{}
+	var email sql.NullString
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&user.ID,
 		&user.Username,
+		&email,
 		&user.PasswordHash,
 		&user.Role,
 		&user.CreatedAt,
@@ -72,24 +90,28 @@ func (r *MySQLUserRepository) GetByID(ctx context.Context, id int) (*User, error
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found: id=%d", id)
+			return nil, apperror.NotFound("user not found: id=%d", id)
 		}
 		return nil, fmt.Errorf("failed to get user by id: %w", err)
 	}
+	user.Email = email.String
 	return user, nil
 }
 
-// GetByUsername 根据用户名获取用户
+// GetByUsername 根据用户名获取用户，username 按 NormalizeUsername 规范化后查询
 func (r *MySQLUserRepository) GetByUsername(ctx context.Context, username string) (*User, error) {
+	username = NormalizeUsername(username)
 	query := `
-		SELECT id, username, password_hash, role, created_at, updated_at
+		SELECT id, username, email, password_hash, role, created_at, updated_at
 		FROM users
 		WHERE username = ?
 	`
 	user := &User{}
+	var email sql.NullString
 	err := r.db.QueryRowContext(ctx, query, username).Scan(
 		&user.ID,
 		&user.Username,
+		&email,
 		&user.PasswordHash,
 		&user.Role,
 		&user.CreatedAt,
@@ -97,20 +119,21 @@ func (r *MySQLUserRepository) GetByUsername(ctx context.Context, username string
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found: username=%s", username)
+			return nil, apperror.NotFound("user not found: username=%s", username)
 		}
 		return nil, fmt.Errorf("failed to get user by username: %w", err)
 	}
+	user.Email = email.String
 	return user, nil
 }
 
 // List 分页获取用户列表
-func (r *MySQLUserRepository) List(ctx context.Context, page, pageSize int) ([]*User, error) {
+func (r *MySQLUserRepository) List(ctx context.Context, page, pageSize int, sortBy string) ([]*User, error) {
 	offset := (page - 1) * pageSize
 	query := `
-		SELECT id, username, password_hash, role, created_at, updated_at
+		SELECT id, username, email, password_hash, role, created_at, updated_at
 		FROM users
-		ORDER BY id
+		ORDER BY ` + userSortClause(sortBy) + `
 		LIMIT ? OFFSET ?
 	`
 	rows, err := r.db.QueryContext(ctx, query, pageSize, offset)
@@ -122,9 +145,11 @@ func (r *MySQLUserRepository) List(ctx context.Context, page, pageSize int) ([]*
 	var users []*User
 	for rows.Next() {
 		user := &User{}
+		var email sql.NullString
 		err := rows.Scan(
 			&user.ID,
 			&user.Username,
+			&email,
 			&user.PasswordHash,
 			&user.Role,
 			&user.CreatedAt,
@@ -133,6 +158,7 @@ func (r *MySQLUserRepository) List(ctx context.Context, page, pageSize int) ([]*
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
+		user.Email = email.String
 		users = append(users, user)
 	}
 
@@ -143,6 +169,15 @@ func (r *MySQLUserRepository) List(ctx context.Context, page, pageSize int) ([]*
 	return users, nil
 }
 
+// Count 统计用户总数
+func (r *MySQLUserRepository) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
 // Delete 删除用户
 func (r *MySQLUserRepository) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM users WHERE id = ?`
@@ -157,7 +192,7 @@ func (r *MySQLUserRepository) Delete(ctx context.Context, id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("user not found: id=%d", id)
+		return apperror.NotFound("user not found: id=%d", id)
 	}
 
 	return nil
@@ -177,8 +212,81 @@ func (r *MySQLUserRepository) UpdatePassword(ctx context.Context, id int, passwo
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("user not found: id=%d", id)
+		return apperror.NotFound("user not found: id=%d", id)
+	}
+
+	return nil
+}
+
+// GetByEmail 根据邮箱获取用户，email 应为规范化后的值
+func (r *MySQLUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	query := `
+		SELECT id, username, email, password_hash, role, created_at, updated_at
+		FROM users
+		WHERE email = ?
+	`
+	user := &User{}
+	var storedEmail sql.NullString
+	err := r.db.QueryRowContext(ctx, query, email).Scan(
+		&user.ID,
+		&user.Username,
+		&storedEmail,
+		&user.PasswordHash,
+		&user.Role,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperror.NotFound("user not found: email=%s", email)
+		}
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+	user.Email = storedEmail.String
+	return user, nil
+}
+
+// UpdateEmail 更新用户邮箱，email 为空时清除邮箱
+func (r *MySQLUserRepository) UpdateEmail(ctx context.Context, id int, email string) error {
+	query := `UPDATE users SET email = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, query, nullEmail(email), id)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return fmt.Errorf("%w: %s", ErrEmailTaken, email)
+		}
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperror.NotFound("user not found: id=%d", id)
 	}
 
 	return nil
 }
+
+// nullEmail 未设置邮箱时存为 NULL，唯一索引允许多个 NULL
+func nullEmail(email string) sql.NullString {
+	return sql.NullString{String: email, Valid: email != ""}
+}
+
+// isEmailKey 判断唯一约束冲突是否来自邮箱列
+// MySQL 错误信息包含索引名 idx_users_email，SQLite 包含列名 users.email
+func isEmailKey(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "idx_users_email") || strings.Contains(msg, "users.email")
+}
+
+// isDuplicateKey 判断错误是否为违反唯一约束
+// MySQL 返回 1062 (ER_DUP_ENTRY)；SQLite（测试环境）没有在此引入驱动，按错误信息识别
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1062
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
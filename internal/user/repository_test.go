@@ -3,9 +3,11 @@ package user
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"testing"
 	_ "github.com/mattn/go-sqlite3" // SQLite 驱动
+	"strings"
+	"testing"
 )
 
 // setupTestDB 创建测试数据库连接
@@ -26,6 +28,7 @@ func setupTestDB(t *testing.T) *sql.DB {
 	CREATE TABLE IF NOT EXISTS users (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		username TEXT UNIQUE NOT NULL,
+		email TEXT UNIQUE,
 		password_hash TEXT NOT NULL,
 		role TEXT NOT NULL DEFAULT 'customer',
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -142,8 +145,34 @@ func TestMySQLUserRepository_Create_DuplicateUsername(t *testing.T) {
 		Role:         RoleCustomer,
 	}
 	err := repo.Create(ctx, user2)
-	if err == nil {
-		t.Error("Create() expected error for duplicate username, got nil")
+	if !errors.Is(err, ErrUsernameTaken) {
+		t.Errorf("Create() error = %v, want ErrUsernameTaken", err)
+	}
+}
+
+// TestMySQLUserRepository_Create_DuplicateEmail 测试邮箱唯一约束：重复邮箱返回 ErrEmailTaken，未设置邮箱不冲突
+func TestMySQLUserRepository_Create_DuplicateEmail(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewMySQLUserRepository(db)
+	ctx := context.Background()
+
+	users := []*User{
+		{Username: "mail1", Email: "same@example.com", PasswordHash: "hash", Role: RoleCustomer},
+		{Username: "nomail1", PasswordHash: "hash", Role: RoleCustomer},
+		{Username: "nomail2", PasswordHash: "hash", Role: RoleCustomer},
+	}
+	for _, u := range users {
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatalf("Create(%s) error = %v", u.Username, err)
+		}
+	}
+
+	err := repo.Create(ctx, &User{Username: "mail2", Email: "same@example.com", PasswordHash: "hash", Role: RoleCustomer})
+	if !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("Create() error = %v, want ErrEmailTaken", err)
+	}
+	if err := repo.UpdateEmail(ctx, users[1].ID, "same@example.com"); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("UpdateEmail() error = %v, want ErrEmailTaken", err)
 	}
 }
 
@@ -291,12 +320,12 @@ func TestMySQLUserRepository_List(t *testing.T) {
 	}
 
 	tests := []struct {
-		name       string
-		page       int
-		pageSize   int
-		minCount   int
-		maxCount   int
-		wantErr    bool
+		name     string
+		page     int
+		pageSize int
+		minCount int
+		maxCount int
+		wantErr  bool
 	}{
 		{
 			name:     "list first page",
@@ -326,7 +355,7 @@ func TestMySQLUserRepository_List(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			users, err := repo.List(ctx, tt.page, tt.pageSize)
+			users, err := repo.List(ctx, tt.page, tt.pageSize, "")
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("List() error = %v, wantErr %v", err, tt.wantErr)
@@ -342,6 +371,75 @@ func TestMySQLUserRepository_List(t *testing.T) {
 	}
 }
 
+// TestMySQLUserRepository_ListSort 测试用户列表按用户名排序
+func TestMySQLUserRepository_ListSort(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewMySQLUserRepository(db)
+	ctx := context.Background()
+
+	for _, name := range []string{"bob", "carol", "alice"} {
+		if err := repo.Create(ctx, &User{Username: name, PasswordHash: "hash123", Role: RoleCustomer}); err != nil {
+			t.Fatalf("failed to create user %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		sortBy string
+		want   string
+	}{
+		{sortBy: "", want: "bob,carol,alice"},
+		{sortBy: "username_asc", want: "alice,bob,carol"},
+		{sortBy: "username_desc", want: "carol,bob,alice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			users, err := repo.List(ctx, 1, 10, tt.sortBy)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			names := make([]string, len(users))
+			for i, u := range users {
+				names[i] = u.Username
+			}
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("List(%q) = %s, want %s", tt.sortBy, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMySQLUserRepository_Count 测试 Count 方法
+func TestMySQLUserRepository_Count(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewMySQLUserRepository(db)
+	ctx := context.Background()
+
+	before, err := repo.Count(ctx)
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		user := &User{
+			Username:     fmt.Sprintf("countuser%d", i),
+			PasswordHash: "hash123",
+			Role:         RoleCustomer,
+		}
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("failed to create user %d: %v", i, err)
+		}
+	}
+
+	after, err := repo.Count(ctx)
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if after-before != 3 {
+		t.Errorf("Count() = %d, want %d", after, before+3)
+	}
+}
+
 // TestMySQLUserRepository_Delete 测试 Delete 方法
 func TestMySQLUserRepository_Delete(t *testing.T) {
 	if testing.Short() {
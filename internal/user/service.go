@@ -4,48 +4,98 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/biqiangwu/flowerSalesSystem/internal/apperror"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // 错误定义
 var (
-	ErrUserNotFound          = errors.New("用户不存在")
-	ErrInsufficientPermission = errors.New("权限不足")
-	ErrInvalidPassword       = errors.New("密码无效")
+	ErrUserNotFound           = apperror.New(apperror.KindNotFound, "用户不存在")
+	ErrInsufficientPermission = apperror.New(apperror.KindForbidden, "权限不足")
+	ErrInvalidPassword        = apperror.New(apperror.KindValidation, "密码无效")
+	ErrUsernameTaken          = apperror.New(apperror.KindConflict, "username already exists")
+	ErrEmailTaken             = apperror.New(apperror.KindConflict, "email already exists")
 )
 
+// DefaultPageSize 用户列表默认每页条数
+const DefaultPageSize = 100
+
+// userSortClauses 用户列表允许的排序方式及对应的 ORDER BY 子句
+// 排序只能从此白名单中选择，请求参数不会拼接进 SQL
+var userSortClauses = map[string]string{
+	"username_asc":    "username ASC",
+	"username_desc":   "username DESC",
+	"created_at_asc":  "created_at ASC, id ASC",
+	"created_at_desc": "created_at DESC, id DESC",
+}
+
+// UserSortOptions 用户列表支持的排序方式
+func UserSortOptions() []string {
+	return []string{"username_asc", "username_desc", "created_at_asc", "created_at_desc"}
+}
+
+// userSortClause 返回排序方式对应的 ORDER BY 子句，未知或为空时按 ID 排序
+func userSortClause(sortBy string) string {
+	if clause, ok := userSortClauses[sortBy]; ok {
+		return clause
+	}
+	return "id"
+}
+
 // UserService 定义用户管理业务逻辑接口
 type UserService interface {
-	ListUsers(ctx context.Context, page, pageSize int) ([]*User, error)
+	ListUsers(ctx context.Context, page, pageSize int, sortBy string) ([]*User, error)
+	CountUsers(ctx context.Context) (int, error)
 	DeleteUser(ctx context.Context, userID int, operatorID int, operatorRole Role) error
 	ResetPassword(ctx context.Context, userID int, newPassword string, operatorID int, operatorRole Role) error
+	UpdateProfile(ctx context.Context, userID int, email string) (*User, error)
 }
 
 // userService 实现 UserService 接口
 type userService struct {
-	repo UserRepository
+	repo   UserRepository
+	pepper string
+	policy PasswordPolicy
 }
 
 // NewUserService 创建 UserService 实例
 func NewUserService(repo UserRepository) UserService {
+	return NewUserServiceWithPepper(repo, "")
+}
+
+// NewUserServiceWithPepper 创建带密码 pepper 的用户服务
+// pepper 必须与认证服务一致，否则重置后的密码无法登录
+func NewUserServiceWithPepper(repo UserRepository, pepper string) UserService {
+	return NewUserServiceWithPolicy(repo, pepper, DefaultPasswordPolicy)
+}
+
+// NewUserServiceWithPolicy 创建带密码 pepper 与密码强度策略的用户服务
+func NewUserServiceWithPolicy(repo UserRepository, pepper string, policy PasswordPolicy) UserService {
 	return &userService{
-		repo: repo,
+		repo:   repo,
+		pepper: pepper,
+		policy: policy,
 	}
 }
 
 // ListUsers 获取用户列表
-func (s *userService) ListUsers(ctx context.Context, page, pageSize int) ([]*User, error) {
+func (s *userService) ListUsers(ctx context.Context, page, pageSize int, sortBy string) ([]*User, error) {
+	if _, ok := userSortClauses[sortBy]; sortBy != "" && !ok {
+		return nil, apperror.Validation("无效的排序方式: %s，可选值: %s", sortBy, strings.Join(UserSortOptions(), ", "))
+	}
+
 	// 验证分页参数
 	if page < 1 {
 		page = 1
 	}
 	if pageSize <= 0 {
-		pageSize = 100 // 默认每页 100 条
+		pageSize = DefaultPageSize
 	}
 
 	// 调用 repository 获取用户列表
-	users, err := s.repo.List(ctx, page, pageSize)
+	users, err := s.repo.List(ctx, page, pageSize, sortBy)
 	if err != nil {
 		return nil, fmt.Errorf("获取用户列表失败: %w", err)
 	}
@@ -53,6 +103,15 @@ func (s *userService) ListUsers(ctx context.Context, page, pageSize int) ([]*Use
 	return users, nil
 }
 
+// CountUsers 统计用户总数
+func (s *userService) CountUsers(ctx context.Context) (int, error) {
+	count, err := s.repo.Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("统计用户数量失败: %w", err)
+	}
+	return count, nil
+}
+
 // DeleteUser 删除用户
 func (s *userService) DeleteUser(ctx context.Context, userID int, operatorID int, operatorRole Role) error {
 	// 权限验证：只有 admin 和 clerk 可以删除用户
@@ -106,6 +165,45 @@ func (s *userService) ResetPassword(ctx context.Context, userID int, newPassword
 	return nil
 }
 
+// UpdateProfile 更新用户本人的资料，目前只有邮箱；email 为空时清除邮箱
+func (s *userService) UpdateProfile(ctx context.Context, userID int, email string) (*User, error) {
+	email = NormalizeEmail(email)
+	if email != "" {
+		if err := ValidateEmail(email); err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if u.Email == email {
+		return u, nil
+	}
+
+	// 邮箱已被其他用户使用；并发修改时由唯一约束兜底
+	if email != "" {
+		other, err := s.repo.GetByEmail(ctx, email)
+		if err == nil && other.ID != userID {
+			return nil, ErrEmailTaken
+		}
+		if err != nil && apperror.KindOf(err) != apperror.KindNotFound {
+			return nil, fmt.Errorf("检查邮箱失败: %w", err)
+		}
+	}
+
+	if err := s.repo.UpdateEmail(ctx, userID, email); err != nil {
+		if errors.Is(err, ErrEmailTaken) {
+			return nil, ErrEmailTaken
+		}
+		return nil, fmt.Errorf("更新邮箱失败: %w", err)
+	}
+
+	u.Email = email
+	return u, nil
+}
+
 // canDeleteUser 检查角色是否可以删除用户
 func (s *userService) canDeleteUser(role Role) bool {
 	return role == RoleAdmin || role == RoleClerk
@@ -116,20 +214,14 @@ func (s *userService) canResetPassword(role Role) bool {
 	return role == RoleAdmin || role == RoleClerk
 }
 
-// validatePassword 验证密码强度
+// validatePassword 按服务配置的策略验证密码强度
 func (s *userService) validatePassword(password string) error {
-	if len(password) == 0 {
-		return ErrInvalidPassword
-	}
-	if len(password) < 6 {
-		return ErrInvalidPassword
-	}
-	return nil
+	return ValidatePassword(password, s.policy)
 }
 
 // hashPassword 对密码进行哈希
 func (s *userService) hashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := bcrypt.GenerateFromPassword(PepperPassword(password, s.pepper), bcrypt.DefaultCost)
 	if err != nil {
 		return "", err
 	}
@@ -53,12 +53,12 @@ func TestUserService_ListUsers(t *testing.T) {
 	}
 
 	tests := []struct {
-		name       string
-		page       int
-		pageSize   int
-		minCount   int
-		maxCount   int
-		wantErr    bool
+		name     string
+		page     int
+		pageSize int
+		minCount int
+		maxCount int
+		wantErr  bool
 	}{
 		{
 			name:     "list first page with 3 items",
@@ -104,7 +104,7 @@ func TestUserService_ListUsers(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := service.ListUsers(ctx, tt.page, tt.pageSize)
+			result, err := service.ListUsers(ctx, tt.page, tt.pageSize, "")
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ListUsers() error = %v, wantErr %v", err, tt.wantErr)
@@ -134,42 +134,42 @@ func TestUserService_DeleteUser(t *testing.T) {
 	user2 := createTestUser(t, ctx, NewMySQLUserRepository(db), "deleteuser2", RoleClerk)
 
 	tests := []struct {
-		name        string
-		userID      int
-		operatorID  int
+		name         string
+		userID       int
+		operatorID   int
 		operatorRole Role
-		wantErr     bool
-		errType     error
+		wantErr      bool
+		errType      error
 	}{
 		{
-			name:        "admin deletes user",
-			userID:      user1.ID,
-			operatorID:  999, // 不存在的管理员ID
+			name:         "admin deletes user",
+			userID:       user1.ID,
+			operatorID:   999, // 不存在的管理员ID
 			operatorRole: RoleAdmin,
-			wantErr:     false,
+			wantErr:      false,
 		},
 		{
-			name:        "clerk deletes user",
-			userID:      user2.ID,
-			operatorID:  998, // 不存在的店员ID
+			name:         "clerk deletes user",
+			userID:       user2.ID,
+			operatorID:   998, // 不存在的店员ID
 			operatorRole: RoleClerk,
-			wantErr:     false,
+			wantErr:      false,
 		},
 		{
-			name:        "delete non-existing user",
-			userID:      99999,
-			operatorID:  997,
+			name:         "delete non-existing user",
+			userID:       99999,
+			operatorID:   997,
 			operatorRole: RoleAdmin,
-			wantErr:     true,
-			errType:     ErrUserNotFound,
+			wantErr:      true,
+			errType:      ErrUserNotFound,
 		},
 		{
-			name:        "customer cannot delete user - insufficient permission",
-			userID:      user1.ID,
-			operatorID:  996,
+			name:         "customer cannot delete user - insufficient permission",
+			userID:       user1.ID,
+			operatorID:   996,
 			operatorRole: RoleCustomer,
-			wantErr:     true,
-			errType:     ErrInsufficientPermission,
+			wantErr:      true,
+			errType:      ErrInsufficientPermission,
 		},
 	}
 
@@ -391,3 +391,50 @@ func TestUserService_PermissionValidation(t *testing.T) {
 		})
 	}
 }
+
+// TestUserService_UpdateProfile 测试修改本人邮箱：设置、清除、重复邮箱与格式无效
+func TestUserService_UpdateProfile(t *testing.T) {
+	service, db := setupTestService(t)
+	repo := NewMySQLUserRepository(db)
+	ctx := context.Background()
+
+	alice := createTestUser(t, ctx, repo, "alice", RoleCustomer)
+	bob := createTestUser(t, ctx, repo, "bob", RoleCustomer)
+
+	if _, err := service.UpdateProfile(ctx, bob.ID, "bob@example.com"); err != nil {
+		t.Fatalf("UpdateProfile(bob) error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		email     string
+		wantErr   error
+		wantEmail string
+	}{
+		{name: "设置邮箱", email: " Alice@Example.com", wantEmail: "alice@example.com"},
+		{name: "重复设置相同邮箱", email: "alice@example.com", wantEmail: "alice@example.com"},
+		{name: "邮箱已被占用", email: "BOB@example.com", wantErr: ErrEmailTaken, wantEmail: "alice@example.com"},
+		{name: "格式无效", email: "not-an-email", wantErr: ErrInvalidEmail, wantEmail: "alice@example.com"},
+		{name: "清除邮箱", email: "", wantEmail: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := service.UpdateProfile(ctx, alice.ID, tt.email)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateProfile() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && u.Email != tt.wantEmail {
+				t.Errorf("UpdateProfile() Email = %q, want %q", u.Email, tt.wantEmail)
+			}
+			stored, _ := repo.GetByID(ctx, alice.ID)
+			if stored.Email != tt.wantEmail {
+				t.Errorf("stored Email = %q, want %q", stored.Email, tt.wantEmail)
+			}
+		})
+	}
+
+	if _, err := service.UpdateProfile(ctx, 99999, "x@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("UpdateProfile() unknown user error = %v, want ErrUserNotFound", err)
+	}
+}
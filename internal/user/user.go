@@ -1,6 +1,9 @@
 package user
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Role 用户角色类型
 type Role string
@@ -16,8 +19,14 @@ const (
 type User struct {
 	ID           int
 	Username     string
+	Email        string // 可选，未设置时为空
 	PasswordHash string
 	Role         Role
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
+
+// NormalizeUsername 去除首尾空白并转为小写，用户名按规范化后的值存储、查询与判重
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
@@ -1,6 +1,8 @@
 package user
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -136,3 +138,74 @@ func TestUserZeroValue(t *testing.T) {
 		t.Errorf("UpdatedAt zero value = %v, want zero time", u.UpdatedAt)
 	}
 }
+
+// TestValidatePassword 测试密码强度策略的各项规则
+func TestValidatePassword(t *testing.T) {
+	strict := PasswordPolicy{MinLength: 8, RequireDigit: true, RequireLetter: true}
+
+	tests := []struct {
+		name     string
+		password string
+		policy   PasswordPolicy
+		wantErr  string // 期望错误信息中包含的原因，空表示通过
+	}{
+		{name: "默认策略通过", password: "123456", policy: DefaultPasswordPolicy},
+		{name: "空密码", password: "", policy: DefaultPasswordPolicy, wantErr: "不能为空"},
+		{name: "默认策略长度不足", password: "12345", policy: DefaultPasswordPolicy, wantErr: "不能少于6个字符"},
+		{name: "按字符计算长度", password: "密码很安全啊", policy: DefaultPasswordPolicy},
+		{name: "严格策略通过", password: "flower2024", policy: strict},
+		{name: "严格策略长度不足", password: "abc123", policy: strict, wantErr: "不能少于8个字符"},
+		{name: "缺少数字", password: "flowerpower", policy: strict, wantErr: "必须包含数字"},
+		{name: "缺少字母", password: "12345678", policy: strict, wantErr: "必须包含字母"},
+		{name: "只要求数字", password: "rose-7-lily", policy: PasswordPolicy{MinLength: 6, RequireDigit: true}},
+		{name: "最小长度为 0 仍拒绝空密码", password: "", policy: PasswordPolicy{}, wantErr: "不能为空"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePassword(tt.password, tt.policy)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidatePassword(%q) error = %v, want nil", tt.password, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidPassword) {
+				t.Fatalf("ValidatePassword(%q) error = %v, want ErrInvalidPassword", tt.password, err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidatePassword(%q) error = %q, want reason %q", tt.password, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidateEmail 测试邮箱格式校验
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		email   string
+		wantErr bool
+	}{
+		{"user@example.com", false},
+		{"first.last+tag@mail.example.cn", false},
+		{"", true},
+		{"user", true},
+		{"user@", true},
+		{"@example.com", true},
+		{"user@example", true},
+		{"user@example.", true},
+		{"user@@example.com", true},
+		{"two@example.com, three@example.com", true},
+		{"Name <user@example.com>", true},
+		{strings.Repeat("a", MaxEmailLength) + "@example.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			err := ValidateEmail(tt.email)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEmail(%q) error = %v, wantErr %v", tt.email, err, tt.wantErr)
+			}
+		})
+	}
+}
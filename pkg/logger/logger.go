@@ -0,0 +1,40 @@
+// Package logger 提供分级的结构化日志，每条日志输出为一行 JSON
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// ParseLevel 解析日志级别 debug、info、warn（或 warning）、error，不区分大小写
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("invalid log level: %q", s)
+}
+
+// New 创建写入 w 的 JSON 日志记录器，低于 level 的日志被丢弃
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// Setup 按日志级别名称创建日志记录器并设为默认记录器
+// 设置后 slog 的包级函数与标准库 log 的输出都会以 JSON 行写入 w
+func Setup(w io.Writer, levelName string) error {
+	level, err := ParseLevel(levelName)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(New(w, level))
+	return nil
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestParseLevel 测试日志级别解析
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", "debug", slog.LevelDebug, false},
+		{"info", "info", slog.LevelInfo, false},
+		{"warn", "warn", slog.LevelWarn, false},
+		{"warning 别名", "warning", slog.LevelWarn, false},
+		{"error", "error", slog.LevelError, false},
+		{"大小写与空白", " DEBUG ", slog.LevelDebug, false},
+		{"空字符串", "", slog.LevelInfo, true},
+		{"未知级别", "verbose", slog.LevelInfo, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLevel(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNew_Level 测试低于配置级别的日志被丢弃，输出为 JSON 行
+func TestNew_Level(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     slog.Level
+		wantDebug bool
+	}{
+		{"info 级别不输出 debug", slog.LevelInfo, false},
+		{"debug 级别输出 debug", slog.LevelDebug, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := New(&buf, tt.level)
+
+			log.Debug("debug message", "key", "value")
+			log.Warn("warn message", "order_id", 42)
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			wantLines := 1
+			if tt.wantDebug {
+				wantLines = 2
+			}
+			if len(lines) != wantLines {
+				t.Fatalf("got %d log lines, want %d: %s", len(lines), wantLines, buf.String())
+			}
+
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(lines[len(lines)-1]), &entry); err != nil {
+				t.Fatalf("log line is not JSON: %v", err)
+			}
+			if entry["level"] != "WARN" || entry["msg"] != "warn message" || entry["order_id"] != float64(42) {
+				t.Errorf("log entry = %v, want WARN warn message with order_id 42", entry)
+			}
+		})
+	}
+}
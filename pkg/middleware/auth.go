@@ -2,7 +2,7 @@ package middleware
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
 	"net/http"
 
 	"github.com/biqiangwu/flowerSalesSystem/internal/auth"
@@ -15,35 +15,62 @@ type contextKey string
 // userKey 是用于存储用户信息的上下文键
 const userKey contextKey = "user"
 
-// AuthMiddleware 认证中间件，验证用户的 session token
-func AuthMiddleware(authService auth.AuthService, next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// 从 Cookie 读取 session_token
-		cookie, err := r.Cookie("session_token")
-		if err != nil {
-			if errors.Is(err, http.ErrNoCookie) {
-				http.Error(w, "unauthorized: missing session token", http.StatusUnauthorized)
+// sessionCookieName 认证使用的 Cookie 名称，与 handler.CookieName 一致
+const sessionCookieName = "session_token"
+
+// AuthMiddleware 认证中间件，验证用户的 session token 并将用户信息注入请求上下文
+// 缺少 Cookie、session 无效或已过期时返回 401，处理器通过 UserFromContext 获取当前用户
+// 代客登录会话同时注入管理员 ID，通过 auth.ImpersonatorFromContext 获取
+func AuthMiddleware(authService auth.AuthService) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			cookie, err := r.Cookie(sessionCookieName)
+			if err != nil {
+				writeError(w, http.StatusUnauthorized, "unauthorized")
 				return
 			}
-			http.Error(w, "unauthorized: invalid cookie", http.StatusUnauthorized)
-			return
+
+			// 验证 Session（包括过期检查）
+			u, impersonatorID, err := authService.ResolveSession(r.Context(), cookie.Value)
+			if err != nil {
+				writeError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+
+			// 将用户信息注入上下文
+			ctx := context.WithValue(r.Context(), userKey, u)
+			ctx = auth.WithImpersonator(ctx, impersonatorID)
+			next(w, r.WithContext(ctx))
 		}
+	}
+}
 
-		// 验证 Session
-		u, err := authService.ValidateSession(r.Context(), cookie.Value)
-		if err != nil {
-			http.Error(w, "unauthorized: "+err.Error(), http.StatusUnauthorized)
+// DenyImpersonation 拒绝代客登录会话访问，返回 403
+// 用于修改资料、邮箱与密码等账号操作，避免管理员借代客登录接管账号；需放在 AuthMiddleware 之后使用
+func DenyImpersonation(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.ImpersonatorFromContext(r.Context()); ok {
+			writeError(w, http.StatusForbidden, "impersonation session cannot change account settings")
 			return
 		}
-
-		// 将用户信息注入上下文
-		ctx := context.WithValue(r.Context(), userKey, u)
-		next(w, r.WithContext(ctx))
+		next(w, r)
 	}
 }
 
-// GetUserFromContext 从上下文中获取用户信息
-func GetUserFromContext(r *http.Request) (*user.User, bool) {
-	u, ok := r.Context().Value(userKey).(*user.User)
-	return u, ok
+// UserFromContext 从上下文中获取 AuthMiddleware 注入的用户信息
+func UserFromContext(ctx context.Context) (*user.User, bool) {
+	u, ok := ctx.Value(userKey).(*user.User)
+	return u, ok && u != nil
+}
+
+// writeError 以 JSON 输出错误响应，格式与 handler 包的 ErrorResponse 一致
+func writeError(w http.ResponseWriter, status int, message string) {
+	body := map[string]string{"error": message}
+	if id := w.Header().Get(RequestIDHeader); id != "" {
+		body["request_id"] = id
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
 }
@@ -12,15 +12,20 @@ import (
 
 // mockAuthService 是 AuthService 的模拟实现，用于测试
 type mockAuthService struct {
-	validToken   string
-	validUser    *user.User
-	validateErr  error
+	validToken     string
+	validUser      *user.User
+	validateErr    error
+	impersonatorID int
 }
 
 func (m *mockAuthService) Register(ctx context.Context, username, password string) (*user.User, error) {
 	return nil, nil
 }
 
+func (m *mockAuthService) RegisterWithEmail(ctx context.Context, username, password, email string) (*user.User, error) {
+	return nil, nil
+}
+
 func (m *mockAuthService) Login(ctx context.Context, username, password string) (*auth.Session, error) {
 	return nil, nil
 }
@@ -39,6 +44,14 @@ func (m *mockAuthService) ValidateSession(ctx context.Context, sessionToken stri
 	return nil, &authError{"invalid session token"}
 }
 
+func (m *mockAuthService) ResolveSession(ctx context.Context, sessionToken string) (*user.User, int, error) {
+	u, err := m.ValidateSession(ctx, sessionToken)
+	if err != nil {
+		return nil, 0, err
+	}
+	return u, m.impersonatorID, nil
+}
+
 func (m *mockAuthService) HashPassword(password string) (string, error) {
 	return "", nil
 }
@@ -66,16 +79,16 @@ func TestAuthMiddleware(t *testing.T) {
 	}
 
 	tests := []struct {
-		name           string
-		validToken     string
-		validateErr    error
-		cookie         *http.Cookie
-		wantStatus     int
-		wantUserInCtx  bool
+		name          string
+		validToken    string
+		validateErr   error
+		cookie        *http.Cookie
+		wantStatus    int
+		wantUserInCtx bool
 	}{
 		{
-			name:       "有效 Session - 调用 next handler，用户信息注入上下文",
-			validToken: "valid-token-123",
+			name:        "有效 Session - 调用 next handler，用户信息注入上下文",
+			validToken:  "valid-token-123",
 			validateErr: nil,
 			cookie: &http.Cookie{
 				Name:  "session_token",
@@ -85,8 +98,8 @@ func TestAuthMiddleware(t *testing.T) {
 			wantUserInCtx: true,
 		},
 		{
-			name:       "无效 Session - 返回 401",
-			validToken: "valid-token-123",
+			name:        "无效 Session - 返回 401",
+			validToken:  "valid-token-123",
 			validateErr: nil,
 			cookie: &http.Cookie{
 				Name:  "session_token",
@@ -96,16 +109,16 @@ func TestAuthMiddleware(t *testing.T) {
 			wantUserInCtx: false,
 		},
 		{
-			name:       "缺失 Cookie - 返回 401",
-			validToken: "valid-token-123",
-			validateErr: nil,
-			cookie:     nil,
+			name:          "缺失 Cookie - 返回 401",
+			validToken:    "valid-token-123",
+			validateErr:   nil,
+			cookie:        nil,
 			wantStatus:    http.StatusUnauthorized,
 			wantUserInCtx: false,
 		},
 		{
-			name:       "过期 Session - 返回 401",
-			validToken: "valid-token-123",
+			name:        "过期 Session - 返回 401",
+			validToken:  "valid-token-123",
 			validateErr: &authError{"session expired"},
 			cookie: &http.Cookie{
 				Name:  "session_token",
@@ -138,7 +151,7 @@ func TestAuthMiddleware(t *testing.T) {
 			})
 
 			// 应用中间件
-			handler := AuthMiddleware(mockAuth, nextHandler)
+			handler := AuthMiddleware(mockAuth)(nextHandler)
 
 			// 创建请求
 			req := httptest.NewRequest("GET", "/test", nil)
@@ -176,3 +189,43 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+// TestDenyImpersonation 测试代客登录会话访问账号设置返回 403，普通会话正常通过
+func TestDenyImpersonation(t *testing.T) {
+	tests := []struct {
+		name           string
+		impersonatorID int
+		wantStatus     int
+	}{
+		{name: "普通会话", impersonatorID: 0, wantStatus: http.StatusOK},
+		{name: "代客登录会话", impersonatorID: 9, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuth := &mockAuthService{
+				validToken:     "valid-token-123",
+				validUser:      &user.User{ID: 1, Username: "testuser", Role: user.RoleCustomer},
+				impersonatorID: tt.impersonatorID,
+			}
+			var gotImpersonator int
+			next := func(w http.ResponseWriter, r *http.Request) {
+				gotImpersonator, _ = auth.ImpersonatorFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}
+			handler := AuthMiddleware(mockAuth)(DenyImpersonation(next))
+
+			req := httptest.NewRequest(http.MethodPatch, "/api/me", nil)
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: "valid-token-123"})
+			w := httptest.NewRecorder()
+			handler(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.impersonatorID == 0 && gotImpersonator != 0 {
+				t.Errorf("ImpersonatorFromContext() = %d, want 0 for regular session", gotImpersonator)
+			}
+		})
+	}
+}
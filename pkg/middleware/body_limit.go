@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// MaxBodyBytesMiddleware 请求体大小限制中间件，超出上限时返回 413，不进入后续处理器
+// 默认上限为 limit，pathLimits 可为指定路径（如批量导入）设置更高的上限；上限 <= 0 表示不限制
+// 声明了 Content-Length 的请求直接按长度判断；未声明长度（分块传输）的请求最多读取上限 + 1 字节后判断，
+// 未超出时以读取的内容替换请求体，处理器照常读取
+func MaxBodyBytesMiddleware(limit int64, pathLimits map[string]int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			maxBytes := limit
+			if pathLimit, ok := pathLimits[r.URL.Path]; ok {
+				maxBytes = pathLimit
+			}
+			if maxBytes <= 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.ContentLength > maxBytes {
+				writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			if r.ContentLength >= 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid request body")
+				return
+			}
+			if int64(len(body)) > maxBytes {
+				writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMaxBodyBytesMiddleware 测试超出上限返回 413，未超出时处理器读取到完整请求体
+func TestMaxBodyBytesMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		chunked    bool // 不声明 Content-Length
+		wantStatus int
+	}{
+		{name: "未超出上限", path: "/api/orders", body: strings.Repeat("a", 16), wantStatus: http.StatusOK},
+		{name: "超出上限", path: "/api/orders", body: strings.Repeat("a", 17), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "分块传输超出上限", path: "/api/orders", body: strings.Repeat("a", 17), chunked: true, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "分块传输未超出上限", path: "/api/orders", body: strings.Repeat("a", 16), chunked: true, wantStatus: http.StatusOK},
+		{name: "导入接口使用更高上限", path: "/api/flowers/import", body: strings.Repeat("a", 64), wantStatus: http.StatusOK},
+		{name: "导入接口超出上限", path: "/api/flowers/import", body: strings.Repeat("a", 65), wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Errorf("read body error = %v", err)
+				}
+				got = string(body)
+			})
+			handler := MaxBodyBytesMiddleware(16, map[string]int64{"/api/flowers/import": 64})(next)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && got != tt.body {
+				t.Errorf("handler read %d bytes, want %d", len(got), len(tt.body))
+			}
+			if tt.wantStatus != http.StatusOK && got != "" {
+				t.Error("handler should not run when body is too large")
+			}
+		})
+	}
+}
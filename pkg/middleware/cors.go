@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CORS 预检响应允许的方法与请求头
+const (
+	corsAllowMethods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsAllowHeaders = "Content-Type, Idempotency-Key, X-Request-ID, X-CSRF-Token"
+)
+
+// CORSMiddleware 跨域中间件，允许 allowedOrigins 中的前端来源携带 Cookie 调用 API
+// 明确列出的来源原样回写到 Access-Control-Allow-Origin 并允许携带凭证
+// allowedOrigins 中的 "*" 表示允许任意来源匿名调用：其它来源只返回字面量 *，不允许携带凭证，避免任意站点以用户身份调用 API
+// 预检请求（OPTIONS + Access-Control-Request-Method）直接返回 204，不进入后续处理器
+// exposedHeaders 为跨域脚本可读取的自定义响应头（如分页总数）；allowedOrigins 为空时不做任何处理
+func CORSMiddleware(allowedOrigins []string, exposedHeaders ...string) func(http.Handler) http.Handler {
+	if len(allowedOrigins) == 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	allowAll := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[origin] = true
+	}
+	exposed := strings.Join(exposedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && origin != "" && r.Header.Get("Access-Control-Request-Method") != ""
+
+			if origin != "" {
+				// 响应随 Origin 变化，避免缓存把一个来源的响应返回给另一个来源
+				w.Header().Add("Vary", "Origin")
+				allowOrigin := ""
+				if allowed[origin] {
+					allowOrigin = origin
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				} else if allowAll {
+					allowOrigin = "*"
+				}
+				if allowOrigin != "" {
+					w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+					if preflight {
+						w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+						w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+					} else if exposed != "" {
+						w.Header().Set("Access-Control-Expose-Headers", exposed)
+					}
+				}
+			}
+
+			// 不允许的来源同样返回 204，但不带允许头，由浏览器拒绝后续请求
+			if preflight {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestCORSMiddleware 测试跨域响应头与预检请求
+func TestCORSMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		origins       []string
+		method        string
+		origin        string
+		preflight     bool
+		wantStatus    int
+		wantNext      bool
+		wantAllow     string
+		wantCreds     bool
+		wantMethods   bool
+		wantExposeHdr string
+	}{
+		{name: "允许来源预检", origins: []string{"https://shop.example.com"}, method: "OPTIONS", origin: "https://shop.example.com", preflight: true,
+			wantStatus: http.StatusNoContent, wantAllow: "https://shop.example.com", wantCreds: true, wantMethods: true},
+		{name: "不允许来源预检", origins: []string{"https://shop.example.com"}, method: "OPTIONS", origin: "https://evil.example.com", preflight: true,
+			wantStatus: http.StatusNoContent},
+		{name: "允许来源普通请求", origins: []string{"https://shop.example.com"}, method: "GET", origin: "https://shop.example.com",
+			wantStatus: http.StatusOK, wantNext: true, wantAllow: "https://shop.example.com", wantCreds: true, wantExposeHdr: "X-Total-Count"},
+		{name: "不允许来源普通请求", origins: []string{"https://shop.example.com"}, method: "GET", origin: "https://evil.example.com",
+			wantStatus: http.StatusOK, wantNext: true},
+		{name: "通配来源不允许携带凭证", origins: []string{"*"}, method: "POST", origin: "https://any.example.com",
+			wantStatus: http.StatusOK, wantNext: true, wantAllow: "*", wantExposeHdr: "X-Total-Count"},
+		{name: "通配来源预检不允许携带凭证", origins: []string{"*"}, method: "OPTIONS", origin: "https://any.example.com", preflight: true,
+			wantStatus: http.StatusNoContent, wantAllow: "*", wantMethods: true},
+		{name: "通配与明确来源并存", origins: []string{"*", "https://shop.example.com"}, method: "GET", origin: "https://shop.example.com",
+			wantStatus: http.StatusOK, wantNext: true, wantAllow: "https://shop.example.com", wantCreds: true, wantExposeHdr: "X-Total-Count"},
+		{name: "同源请求不处理", origins: []string{"https://shop.example.com"}, method: "GET",
+			wantStatus: http.StatusOK, wantNext: true},
+		{name: "非预检 OPTIONS 交给后续处理器", origins: []string{"https://shop.example.com"}, method: "OPTIONS", origin: "https://shop.example.com",
+			wantStatus: http.StatusOK, wantNext: true, wantAllow: "https://shop.example.com", wantCreds: true, wantExposeHdr: "X-Total-Count"},
+		{name: "未配置来源", method: "OPTIONS", origin: "https://shop.example.com", preflight: true,
+			wantStatus: http.StatusOK, wantNext: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			})
+
+			req := httptest.NewRequest(tt.method, "/api/flowers", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			w := httptest.NewRecorder()
+
+			CORSMiddleware(tt.origins, "X-Total-Count")(next).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if called != tt.wantNext {
+				t.Errorf("next called = %v, want %v", called, tt.wantNext)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllow {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllow)
+			}
+			wantCredentials := ""
+			if tt.wantCreds {
+				wantCredentials = "true"
+			}
+			// 通配来源任何情况下都不能与携带凭证同时出现
+			if w.Header().Get("Access-Control-Allow-Origin") == "*" && w.Header().Get("Access-Control-Allow-Credentials") != "" {
+				t.Error("wildcard Access-Control-Allow-Origin must not be combined with Access-Control-Allow-Credentials")
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, wantCredentials)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); (got != "") != tt.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods = %q, want set = %v", got, tt.wantMethods)
+			}
+			if got := w.Header().Get("Access-Control-Expose-Headers"); got != tt.wantExposeHdr {
+				t.Errorf("Access-Control-Expose-Headers = %q, want %q", got, tt.wantExposeHdr)
+			}
+		})
+	}
+}
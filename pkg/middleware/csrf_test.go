@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestCSRFMiddleware 测试双重提交校验：令牌匹配时放行，缺失或不一致时返回 403，GET 与豁免路径不校验
+func TestCSRFMiddleware(t *testing.T) {
+	const token = "0123456789abcdef"
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		cookie     string
+		header     string
+		wantStatus int
+	}{
+		{name: "令牌匹配", method: http.MethodPost, path: "/api/orders", cookie: token, header: token, wantStatus: http.StatusOK},
+		{name: "缺少请求头", method: http.MethodPost, path: "/api/orders", cookie: token, wantStatus: http.StatusForbidden},
+		{name: "缺少 Cookie", method: http.MethodDelete, path: "/api/orders/1", header: token, wantStatus: http.StatusForbidden},
+		{name: "令牌不一致", method: http.MethodPut, path: "/api/orders/1", cookie: token, header: "forged", wantStatus: http.StatusForbidden},
+		{name: "GET 不校验", method: http.MethodGet, path: "/api/orders", wantStatus: http.StatusOK},
+		{name: "HEAD 不校验", method: http.MethodHead, path: "/api/orders", wantStatus: http.StatusOK},
+		{name: "登录豁免", method: http.MethodPost, path: "/api/login", wantStatus: http.StatusOK},
+		{name: "注册豁免", method: http.MethodPost, path: "/api/register", wantStatus: http.StatusOK},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := CSRFMiddleware("/api/login", "/api/register")(next)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+// TestNewCSRFToken 测试生成的令牌长度固定且每次不同
+func TestNewCSRFToken(t *testing.T) {
+	a, err := NewCSRFToken()
+	if err != nil {
+		t.Fatalf("NewCSRFToken() error = %v", err)
+	}
+	b, _ := NewCSRFToken()
+	if len(a) != 64 || a == b {
+		t.Errorf("NewCSRFToken() = %q, %q, want distinct 64-char tokens", a, b)
+	}
+}
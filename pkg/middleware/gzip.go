@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// incompressibleTypes 本身已压缩的内容类型前缀，再次压缩只会浪费 CPU
+var incompressibleTypes = []string{
+	"image/", "video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip", "application/pdf", "application/octet-stream",
+}
+
+// GzipMiddleware gzip 响应压缩中间件
+// 客户端声明 Accept-Encoding: gzip 且响应体达到 minSize 字节时压缩，minSize <= 0 表示压缩所有非空响应；
+// 已压缩的内容类型（图片、压缩包等）、已设置 Content-Encoding 的响应、HEAD 与 Range 请求不压缩
+// 响应体在达到 minSize 前先缓冲，处理器 panic 时缓冲的内容不会写出，恢复中间件仍可返回 500
+func GzipMiddleware(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if r.Method == http.MethodHead || r.Header.Get("Range") != "" || !acceptsGzip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipResponseWriter{ResponseWriter: w, minSize: minSize}
+			next.ServeHTTP(gw, r)
+			gw.Close()
+		})
+	}
+}
+
+// acceptsGzip 判断客户端是否接受 gzip 编码（忽略 q=0 的声明）
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			continue
+		}
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(v, 64); err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter 缓冲响应体直到可以决定是否压缩，之后直接写出（压缩或原样）
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize int
+	status  int
+	buf     []byte
+	started bool         // 已写出响应头
+	gz      *gzip.Writer // 决定压缩后非 nil
+}
+
+// WriteHeader 记录状态码，写出推迟到决定是否压缩时
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.started || w.status != 0 {
+		return
+	}
+	w.status = status
+}
+
+// Write 未决定是否压缩时缓冲内容，缓冲达到 minSize 后写出响应头并开始写出
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.started {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Close 写出未达到 minSize 的缓冲内容（原样发送）并结束 gzip 流
+func (w *gzipResponseWriter) Close() error {
+	if !w.started && w.status != 0 {
+		if err := w.start(false); err != nil {
+			return err
+		}
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
+
+// start 决定是否压缩，写出响应头与缓冲的内容；largeEnough 表示响应体已达到 minSize
+func (w *gzipResponseWriter) start(largeEnough bool) error {
+	w.started = true
+
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if largeEnough && w.compressible() {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		// 压缩后的表示与原内容字节不同，强 ETag 降级为弱 ETag
+		if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			h.Set("ETag", "W/"+etag)
+		}
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// compressible 判断响应是否适合压缩
+func (w *gzipResponseWriter) compressible() bool {
+	switch w.status {
+	case http.StatusNoContent, http.StatusPartialContent, http.StatusNotModified:
+		return false
+	}
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := strings.ToLower(h.Get("Content-Type"))
+	if strings.HasPrefix(contentType, "image/svg+xml") {
+		return true
+	}
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
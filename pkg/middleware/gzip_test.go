@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestGzipMiddleware 测试客户端支持 gzip 时压缩达到阈值的响应，其余情况原样返回
+func TestGzipMiddleware(t *testing.T) {
+	largeJSON := `{"flowers":[` + strings.Repeat(`{"sku":"FLW001","name":"红玫瑰"},`, 100) + `{}]}`
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		wantGzip       bool
+	}{
+		{name: "大 JSON 压缩", acceptEncoding: "gzip, deflate, br", contentType: "application/json", body: largeJSON, wantGzip: true},
+		{name: "客户端不支持 gzip", acceptEncoding: "", contentType: "application/json", body: largeJSON, wantGzip: false},
+		{name: "客户端拒绝 gzip", acceptEncoding: "gzip;q=0, br", contentType: "application/json", body: largeJSON, wantGzip: false},
+		{name: "小于阈值不压缩", acceptEncoding: "gzip", contentType: "application/json", body: `{"ok":true}`, wantGzip: false},
+		{name: "已压缩的类型不压缩", acceptEncoding: "gzip", contentType: "image/png", body: largeJSON, wantGzip: false},
+		{name: "未设置类型时按内容识别", acceptEncoding: "gzip", body: strings.Repeat("<p>花</p>", 200), wantGzip: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.Header().Set("Content-Length", strconv.Itoa(len(tt.body)))
+				w.WriteHeader(http.StatusOK)
+				// 分两次写入，验证缓冲跨越多次 Write
+				io.WriteString(w, tt.body[:len(tt.body)/2])
+				io.WriteString(w, tt.body[len(tt.body)/2:])
+			})
+			handler := GzipMiddleware(256)(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/flowers", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			if vary := w.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", vary)
+			}
+
+			body := w.Body.String()
+			if tt.wantGzip {
+				if ce := w.Header().Get("Content-Encoding"); ce != "gzip" {
+					t.Fatalf("Content-Encoding = %q, want gzip", ce)
+				}
+				if cl := w.Header().Get("Content-Length"); cl != "" {
+					t.Errorf("Content-Length = %q, want removed", cl)
+				}
+				zr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("gzip.NewReader() error = %v", err)
+				}
+				data, err := io.ReadAll(zr)
+				if err != nil {
+					t.Fatalf("read gzip body error = %v", err)
+				}
+				if w.Body.Len() >= len(tt.body) {
+					t.Errorf("compressed size = %d, want < %d", w.Body.Len(), len(tt.body))
+				}
+				body = string(data)
+			} else if ce := w.Header().Get("Content-Encoding"); ce != "" {
+				t.Errorf("Content-Encoding = %q, want empty", ce)
+			}
+
+			if body != tt.body {
+				t.Errorf("body length = %d, want %d", len(body), len(tt.body))
+			}
+		})
+	}
+}
+
+// TestGzipMiddleware_NoBody 测试无响应体的状态码原样返回
+func TestGzipMiddleware_NoBody(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc"`)
+		w.WriteHeader(http.StatusNotModified)
+	})
+	handler := GzipMiddleware(0)(next)
+
+	req := httptest.NewRequest(http.MethodGet, "/index.html", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotModified)
+	}
+	if ce := w.Header().Get("Content-Encoding"); ce != "" || w.Body.Len() != 0 {
+		t.Errorf("Content-Encoding = %q, body = %d bytes, want no encoding and empty body", ce, w.Body.Len())
+	}
+	if etag := w.Header().Get("ETag"); etag != `"abc"` {
+		t.Errorf("ETag = %q, want unchanged", etag)
+	}
+}
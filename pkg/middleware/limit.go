@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// ConcurrencyLimitMiddleware 并发限制中间件，同一时间最多允许 limit 个请求进入
+// 超出时不排队，直接返回 429，避免昂贵请求堆积拖垮数据库
+// 每次调用返回的中间件拥有独立的信号量，被它包裹的所有路由共享该配额
+// limit <= 0 时不做限制
+func ConcurrencyLimitMiddleware(limit int) func(http.HandlerFunc) http.HandlerFunc {
+	if limit <= 0 {
+		return func(next http.HandlerFunc) http.HandlerFunc { return next }
+	}
+
+	sem := make(chan struct{}, limit)
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			default:
+				w.Header().Set("Retry-After", "1")
+				writeError(w, http.StatusTooManyRequests, "too many concurrent requests")
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
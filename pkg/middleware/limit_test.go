@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// TestConcurrencyLimitMiddleware 测试并发数达到上限时返回 429，释放后恢复
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	limit := ConcurrencyLimitMiddleware(1)
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	slow := limit(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	fast := limit(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		slow(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+	}()
+	<-entered
+
+	// 同一个限制器包裹的其它路由共享配额
+	w := httptest.NewRecorder()
+	fast(w, httptest.NewRequest("GET", "/fast", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("saturated status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("saturated response should set Retry-After")
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("saturated Content-Type = %q, want application/json", ct)
+	}
+
+	close(release)
+	wg.Wait()
+
+	w = httptest.NewRecorder()
+	fast(w, httptest.NewRequest("GET", "/fast", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("released status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+// TestConcurrencyLimitMiddleware_Unlimited 测试 limit <= 0 时不做限制
+func TestConcurrencyLimitMiddleware_Unlimited(t *testing.T) {
+	called := false
+	h := ConcurrencyLimitMiddleware(0)(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	h(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if !called {
+		t.Error("handler should be called when limit is disabled")
+	}
+}
@@ -1,12 +1,12 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 )
 
-// LoggingMiddleware 日志中间件，记录请求方法、路径、状态码、耗时
+// LoggingMiddleware 日志中间件，记录请求方法、路径、状态码、耗时，经过 RequestIDMiddleware 时附带请求 ID
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// 记录请求开始时间
@@ -21,8 +21,16 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 		// 计算耗时
 		duration := time.Since(start)
 
-		// 记录日志
-		log.Printf("[%s] %s - %d - %v", r.Method, r.URL.Path, rw.status, duration)
+		// 记录日志，服务端错误使用 error 级别
+		level := slog.LevelInfo
+		if rw.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		attrs := []any{"method", r.Method, "path", r.URL.Path, "status", rw.status, "duration", duration.String()}
+		if id := RequestIDFromContext(r.Context()); id != "" {
+			attrs = append(attrs, "request_id", id)
+		}
+		slog.Log(r.Context(), level, "http request", attrs...)
 	})
 }
 
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// NormalizePathMiddleware 规范化 /api 前缀下的请求路径，见 NormalizePathPrefixesMiddleware
+func NormalizePathMiddleware(next http.Handler) http.Handler {
+	return NormalizePathPrefixesMiddleware("/api")(next)
+}
+
+// NormalizePathPrefixesMiddleware 规范化 API 请求路径
+// 去掉末尾斜杠（/api/v1/orders/ 等同于 /api/v1/orders），并将 API 前缀及其后的资源名转为小写；
+// 之后的路径段（SKU、订单号等）保持原样，不在任何前缀下的路径不做处理。前缀有重叠时按最长的匹配
+func NormalizePathPrefixesMiddleware(prefixes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			normalized := NormalizeAPIPathPrefixes(r.URL.Path, prefixes...)
+			if normalized == r.URL.Path {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r2 := new(http.Request)
+			*r2 = *r
+			u := *r.URL
+			u.Path = normalized
+			u.RawPath = ""
+			r2.URL = &u
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+// NormalizeAPIPath 返回 /api 前缀下规范化后的路径，非 API 路径原样返回
+func NormalizeAPIPath(path string) string {
+	return NormalizeAPIPathPrefixes(path, "/api")
+}
+
+// NormalizeAPIPathPrefixes 返回规范化后的 API 路径，不在任何前缀下的路径原样返回
+func NormalizeAPIPathPrefixes(path string, prefixes ...string) string {
+	matched := ""
+	for _, prefix := range prefixes {
+		if len(prefix) > len(matched) && hasPathPrefixFold(path, prefix) {
+			matched = prefix
+		}
+	}
+	if matched == "" {
+		return path
+	}
+
+	// 前缀的各段与紧随其后的资源名转为小写
+	lower := strings.Count(matched, "/") + 1
+	trimmed := strings.TrimRight(path, "/")
+	parts := strings.Split(strings.TrimPrefix(trimmed, "/"), "/")
+	for i := 0; i < len(parts) && i < lower; i++ {
+		parts[i] = strings.ToLower(parts[i])
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// hasPathPrefixFold 判断 path 是否以 prefix 为完整路径段前缀（不区分大小写），/apiary 不属于 /api
+func hasPathPrefixFold(path, prefix string) bool {
+	if len(path) < len(prefix) || !strings.EqualFold(path[:len(prefix)], prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || path[len(prefix)] == '/'
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestNormalizeAPIPath 测试 API 路径规范化
+func TestNormalizeAPIPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"无需处理", "/api/orders", "/api/orders"},
+		{"去掉末尾斜杠", "/api/orders/", "/api/orders"},
+		{"去掉多个末尾斜杠", "/api/flowers//", "/api/flowers"},
+		{"前缀大小写", "/API/Flowers", "/api/flowers"},
+		{"保留资源标识大小写", "/api/flowers/Rose-01/", "/api/flowers/Rose-01"},
+		{"仅 /api", "/api/", "/api"},
+		{"非 API 路径不处理", "/Orders/", "/Orders/"},
+		{"相似前缀不处理", "/apiary/", "/apiary/"},
+		{"根路径", "/", "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeAPIPath(tt.path); got != tt.want {
+				t.Errorf("NormalizeAPIPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNormalizePathMiddleware 测试中间件改写请求路径
+func TestNormalizePathMiddleware(t *testing.T) {
+	var gotPath string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+	})
+
+	req := httptest.NewRequest("GET", "/API/Orders/?page=1", nil)
+	NormalizePathMiddleware(next).ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotPath != "/api/orders" {
+		t.Errorf("path = %q, want %q", gotPath, "/api/orders")
+	}
+	if req.URL.Path != "/API/Orders/" {
+		t.Errorf("original request was modified: %q", req.URL.Path)
+	}
+}
+
+// TestNormalizeAPIPathPrefixes 测试多个前缀下的路径规范化：按最长前缀匹配，前缀与资源名转为小写
+func TestNormalizeAPIPathPrefixes(t *testing.T) {
+	prefixes := []string{"/api/v1", "/api"}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"版本前缀", "/api/v1/orders", "/api/v1/orders"},
+		{"版本前缀大小写与末尾斜杠", "/API/V1/Flowers/", "/api/v1/flowers"},
+		{"版本前缀下保留资源标识大小写", "/api/v1/flowers/Rose-01", "/api/v1/flowers/Rose-01"},
+		{"旧版前缀", "/API/Flowers/Rose-01/", "/api/flowers/Rose-01"},
+		{"仅版本前缀", "/api/v1/", "/api/v1"},
+		{"非 API 路径不处理", "/Orders/", "/Orders/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeAPIPathPrefixes(tt.path, prefixes...); got != tt.want {
+				t.Errorf("NormalizeAPIPathPrefixes(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitMiddleware 按客户端 IP 限流的中间件，使用滑动窗口：任意 window 时间内最多允许 limit 个请求
+// 超出时返回 429，并通过 Retry-After 告知最早可重试的秒数
+// 每次调用返回的中间件拥有独立的计数，被它包裹的所有路由共享该配额
+// 对端地址属于 trustedProxies 时，从 X-Forwarded-For / X-Real-IP 中取真实客户端 IP
+// limit <= 0 或 window <= 0 时不做限制
+func RateLimitMiddleware(limit int, window time.Duration, trustedProxies []*net.IPNet) func(http.HandlerFunc) http.HandlerFunc {
+	if limit <= 0 || window <= 0 {
+		return func(next http.HandlerFunc) http.HandlerFunc { return next }
+	}
+
+	limiter := &slidingWindowLimiter{
+		limit:  limit,
+		window: window,
+		hits:   make(map[string][]time.Time),
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if retryAfter, ok := limiter.allow(clientIP(r, trustedProxies), time.Now()); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				writeError(w, http.StatusTooManyRequests, "too many requests")
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// slidingWindowLimiter 记录每个客户端在窗口内的请求时间
+type slidingWindowLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	hits      map[string][]time.Time
+	lastSweep time.Time
+}
+
+// allow 判断 key 在 now 时刻是否允许请求，拒绝时返回需要等待的时长
+func (l *slidingWindowLimiter) allow(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cutoff := now.Add(-l.window)
+	l.sweep(now, cutoff)
+
+	hits := dropBefore(l.hits[key], cutoff)
+	if len(hits) >= l.limit {
+		l.hits[key] = hits
+		// 最早的请求移出窗口后即可重试，至少等待 1 秒
+		return max(hits[0].Sub(cutoff), time.Second), false
+	}
+
+	l.hits[key] = append(hits, now)
+	return 0, true
+}
+
+// sweep 每个窗口周期清理一次已无有效记录的客户端，避免内存随 IP 数量无限增长
+func (l *slidingWindowLimiter) sweep(now, cutoff time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for key, hits := range l.hits {
+		if hits = dropBefore(hits, cutoff); len(hits) == 0 {
+			delete(l.hits, key)
+		} else {
+			l.hits[key] = hits
+		}
+	}
+}
+
+// dropBefore 去掉 cutoff 及之前的请求时间（hits 按时间升序）
+func dropBefore(hits []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	return hits[i:]
+}
+
+// ParseTrustedProxies 解析受信任的反向代理地址，支持单个 IP 与 CIDR
+func ParseTrustedProxies(values []string) ([]*net.IPNet, error) {
+	proxies := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", value)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// clientIP 返回请求的客户端 IP
+// 默认只使用连接的对端地址；仅当对端是受信任的代理时才读取 X-Forwarded-For 与 X-Real-IP，
+// 并从右向左跳过受信任的代理，取第一个不受信任的地址，避免客户端伪造请求头绕过限流
+func clientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrustedProxy(host, trustedProxies) {
+		return host
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			host = hop
+			if !isTrustedProxy(hop, trustedProxies) {
+				break
+			}
+		}
+		return host
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return host
+}
+
+// isTrustedProxy 判断 host 是否属于受信任的代理
+func isTrustedProxy(host string, trustedProxies []*net.IPNet) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
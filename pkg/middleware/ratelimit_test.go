@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newRateLimitRequest 创建来自指定地址的请求
+func newRateLimitRequest(remoteAddr string) *http.Request {
+	req := httptest.NewRequest("POST", "/api/login", nil)
+	req.RemoteAddr = remoteAddr
+	return req
+}
+
+// TestRateLimitMiddleware 测试超过限额的第 N+1 个请求返回 429
+func TestRateLimitMiddleware(t *testing.T) {
+	const limit = 3
+	h := RateLimitMiddleware(limit, time.Minute, nil)(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for i := 1; i <= limit; i++ {
+		w := httptest.NewRecorder()
+		h(w, newRateLimitRequest("10.0.0.1:1234"))
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d status = %d, want %d", i, w.Code, http.StatusOK)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h(w, newRateLimitRequest("10.0.0.1:5678"))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request %d status = %d, want %d", limit+1, w.Code, http.StatusTooManyRequests)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 || retryAfter > 60 {
+		t.Errorf("Retry-After = %q, want 1..60 seconds", w.Header().Get("Retry-After"))
+	}
+
+	// 其它客户端不受影响
+	w = httptest.NewRecorder()
+	h(w, newRateLimitRequest("10.0.0.2:1234"))
+	if w.Code != http.StatusOK {
+		t.Errorf("other client status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+// TestRateLimitMiddleware_SharedQuota 测试同一个限流器包裹的路由共享配额
+func TestRateLimitMiddleware_SharedQuota(t *testing.T) {
+	limit := RateLimitMiddleware(1, time.Minute, nil)
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	login, register := limit(ok), limit(ok)
+
+	w := httptest.NewRecorder()
+	login(w, newRateLimitRequest("10.0.0.1:1234"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("login status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	w = httptest.NewRecorder()
+	register(w, newRateLimitRequest("10.0.0.1:1234"))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("register status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+}
+
+// TestRateLimitMiddleware_WindowSlides 测试窗口过后恢复放行
+func TestRateLimitMiddleware_WindowSlides(t *testing.T) {
+	h := RateLimitMiddleware(1, 50*time.Millisecond, nil)(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	send := func() int {
+		w := httptest.NewRecorder()
+		h(w, newRateLimitRequest("10.0.0.1:1234"))
+		return w.Code
+	}
+
+	if got := send(); got != http.StatusOK {
+		t.Fatalf("first status = %d, want %d", got, http.StatusOK)
+	}
+	if got := send(); got != http.StatusTooManyRequests {
+		t.Fatalf("second status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if got := send(); got != http.StatusOK {
+		t.Errorf("after window status = %d, want %d", got, http.StatusOK)
+	}
+}
+
+// TestRateLimitMiddleware_Unlimited 测试 limit <= 0 时不做限制
+func TestRateLimitMiddleware_Unlimited(t *testing.T) {
+	calls := 0
+	h := RateLimitMiddleware(0, time.Minute, nil)(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	})
+
+	for i := 0; i < 5; i++ {
+		h(httptest.NewRecorder(), newRateLimitRequest("10.0.0.1:1234"))
+	}
+	if calls != 5 {
+		t.Errorf("handler called %d times, want 5", calls)
+	}
+}
+
+// TestSlidingWindowLimiter_Sweep 测试过期客户端记录会被清理
+func TestSlidingWindowLimiter_Sweep(t *testing.T) {
+	l := &slidingWindowLimiter{limit: 1, window: time.Second, hits: make(map[string][]time.Time)}
+	start := time.Now()
+
+	l.allow("10.0.0.1", start)
+	l.allow("10.0.0.2", start.Add(2*time.Second))
+
+	if _, ok := l.hits["10.0.0.1"]; ok {
+		t.Error("expired client should be swept")
+	}
+	if len(l.hits) != 1 {
+		t.Errorf("tracked clients = %d, want 1", len(l.hits))
+	}
+}
+
+// TestClientIP 测试仅信任来自受信任代理的转发请求头
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		want       string
+	}{
+		{name: "直连客户端", remoteAddr: "203.0.113.5:1234", want: "203.0.113.5"},
+		{name: "不受信任的对端伪造请求头", remoteAddr: "203.0.113.5:1234", forwarded: "198.51.100.1", realIP: "198.51.100.2", want: "203.0.113.5"},
+		{name: "受信任代理转发", remoteAddr: "10.1.2.3:1234", forwarded: "198.51.100.1", want: "198.51.100.1"},
+		{name: "多级受信任代理", remoteAddr: "10.1.2.3:1234", forwarded: "198.51.100.1, 192.168.1.1, 10.0.0.9", want: "198.51.100.1"},
+		{name: "客户端伪造的最左侧地址被忽略", remoteAddr: "10.1.2.3:1234", forwarded: "1.1.1.1, 198.51.100.1", want: "198.51.100.1"},
+		{name: "受信任代理使用 X-Real-IP", remoteAddr: "192.168.1.1:1234", realIP: "198.51.100.3", want: "198.51.100.3"},
+		{name: "受信任代理未转发请求头", remoteAddr: "10.1.2.3:1234", want: "10.1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRateLimitRequest(tt.remoteAddr)
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := clientIP(req, trusted); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRateLimitMiddleware_TrustedProxy 测试同一代理后的不同客户端使用独立配额
+func TestRateLimitMiddleware_TrustedProxy(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.1"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+	h := RateLimitMiddleware(1, time.Minute, trusted)(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range []struct {
+		client string
+		want   int
+	}{
+		{client: "198.51.100.1", want: http.StatusOK},
+		{client: "198.51.100.2", want: http.StatusOK},
+		{client: "198.51.100.1", want: http.StatusTooManyRequests},
+	} {
+		req := newRateLimitRequest("10.0.0.1:1234")
+		req.Header.Set("X-Forwarded-For", tt.client)
+		w := httptest.NewRecorder()
+		h(w, req)
+		if w.Code != tt.want {
+			t.Errorf("client %s status = %d, want %d", tt.client, w.Code, tt.want)
+		}
+	}
+}
+
+// TestParseTrustedProxies_Invalid 测试无效的代理地址返回错误
+func TestParseTrustedProxies_Invalid(t *testing.T) {
+	for _, value := range []string{"not-an-ip", "10.0.0.0/33"} {
+		if _, err := ParseTrustedProxies([]string{value}); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) error = nil, want error", value)
+		}
+	}
+}
@@ -1,25 +1,56 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
 )
 
 // RecoveryMiddleware 恢复中间件，捕获 panic 并返回 500 错误
+// panic 信息与调用栈只记录在服务端日志中，客户端收到通用的 JSON 错误
 func RecoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoveryWriter{ResponseWriter: w}
+
 		// 使用 defer + recover 捕获 panic
 		defer func() {
 			if err := recover(); err != nil {
-				// 记录 panic 日志
-				log.Printf("panic recovered: %v", err)
+				// http.ErrAbortHandler 用于主动中止响应，交由 net/http 处理
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				// 记录 panic 日志与调用栈
+				slog.Error("panic recovered", "error", err, "method", r.Method, "path", r.URL.Path,
+					"request_id", RequestIDFromContext(r.Context()), "stack", string(debug.Stack()))
 
-				// 返回 500 错误
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				// handler 已写出响应头时无法再修改状态码，避免重复写入
+				if rw.wroteHeader {
+					return
+				}
+				writeError(w, http.StatusInternalServerError, "internal server error")
 			}
 		}()
 
 		// 调用下一个 handler
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(rw, r)
 	})
 }
+
+// recoveryWriter 包装 http.ResponseWriter 以记录响应头是否已写出
+type recoveryWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// WriteHeader 记录响应头已写出
+func (rw *recoveryWriter) WriteHeader(status int) {
+	rw.wroteHeader = true
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write 未显式调用 WriteHeader 时写入响应体同样会写出响应头
+func (rw *recoveryWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(b)
+}
@@ -1,18 +1,23 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 )
 
 // TestRecoveryMiddleware 测试恢复中间件
 func TestRecoveryMiddleware(t *testing.T) {
 	tests := []struct {
-		name           string
-		handlerFunc    http.HandlerFunc
-		wantStatus     int
-		wantBody       string
+		name        string
+		handlerFunc http.HandlerFunc
+		wantStatus  int
+		wantBody    string
 	}{
 		{
 			name: "正常请求 - 响应不变",
@@ -69,7 +74,7 @@ func TestRecoveryMiddleware(t *testing.T) {
 			// 创建响应记录器
 			rr := httptest.NewRecorder()
 
-				// 执行请求（捕获 panic）
+			// 执行请求（捕获 panic）
 			func() {
 				defer func() {
 					if recovered := recover(); recovered != nil {
@@ -93,6 +98,70 @@ func TestRecoveryMiddleware(t *testing.T) {
 	}
 }
 
+// TestRecoveryMiddleware_SafeResponse 测试 panic 时返回通用 JSON 错误，调用栈只写入服务端日志
+func TestRecoveryMiddleware_SafeResponse(t *testing.T) {
+	var logBuf bytes.Buffer
+	log.SetOutput(&logBuf)
+	defer log.SetOutput(os.Stderr)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("secret: db password leaked")
+	})
+	mux.HandleFunc("GET /partial", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("after header")
+	})
+	server := httptest.NewServer(RequestIDMiddleware(RecoveryMiddleware(mux)))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/panic")
+	if err != nil {
+		t.Fatalf("GET /panic error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusInternalServerError)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode body error = %v", err)
+	}
+	if body["error"] != "internal server error" {
+		t.Errorf("error = %q, want %q", body["error"], "internal server error")
+	}
+	if body["request_id"] == "" {
+		t.Error("response should include request_id")
+	}
+	for _, v := range body {
+		if strings.Contains(v, "secret") || strings.Contains(v, "goroutine") {
+			t.Errorf("response leaks panic details: %v", body)
+		}
+	}
+
+	logOutput := logBuf.String()
+	if !strings.Contains(logOutput, "secret: db password leaked") || !strings.Contains(logOutput, "goroutine") {
+		t.Errorf("log should contain panic value and stack, got: %s", logOutput)
+	}
+
+	// handler 已写出状态码后 panic，保留原状态码且不再写入错误体，服务继续可用
+	resp, err = http.Get(server.URL + "/partial")
+	if err != nil {
+		t.Fatalf("GET /partial error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+	}
+	if strings.Contains(logBuf.String(), "superfluous") {
+		t.Errorf("response header written twice, log: %s", logBuf.String())
+	}
+}
+
 // containsString 检查字符串是否包含子字符串（忽略大小写）
 func containsString(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// RequestIDHeader 请求 ID 的请求头与响应头
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 沿用客户端传入请求 ID 的最大长度，超长或含非法字符时重新生成
+const maxRequestIDLength = 128
+
+// requestIDKey 是用于存储请求 ID 的上下文键
+const requestIDKey contextKey = "request_id"
+
+// RequestIDMiddleware 请求 ID 中间件，为每个请求分配关联 ID，用于串联日志与错误响应
+// 请求携带合法的 X-Request-ID 时沿用，否则生成 UUID；ID 写入请求上下文与 X-Request-ID 响应头
+// 应放在日志中间件之外，使日志能够记录请求 ID
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+// RequestIDFromContext 从上下文中获取请求 ID，未经过 RequestIDMiddleware 时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// validRequestID 检查客户端传入的请求 ID：非空、不超长且只包含可打印 ASCII 字符，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID 生成随机 UUID（版本 4）
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // 版本 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 变体
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
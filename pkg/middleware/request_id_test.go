@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// uuidPattern UUID 版本 4 格式
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// TestRequestIDMiddleware 测试请求 ID 的生成、沿用与上下文传递
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		wantSame bool // 是否沿用传入的 ID
+	}{
+		{name: "未传入时生成 UUID"},
+		{name: "沿用传入的 ID", incoming: "client-req-42", wantSame: true},
+		{name: "含空格时重新生成", incoming: "bad id\nforged log"},
+		{name: "超长时重新生成", incoming: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctxID string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = RequestIDFromContext(r.Context())
+			})
+
+			req := httptest.NewRequest("GET", "/api/flowers", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+
+			RequestIDMiddleware(next).ServeHTTP(w, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			if got != ctxID {
+				t.Errorf("response header %q != context id %q", got, ctxID)
+			}
+			if tt.wantSame {
+				if got != tt.incoming {
+					t.Errorf("request id = %q, want %q", got, tt.incoming)
+				}
+				return
+			}
+			if !uuidPattern.MatchString(got) {
+				t.Errorf("request id = %q, want generated UUID", got)
+			}
+		})
+	}
+}
+
+// TestRequestIDMiddleware_LogsAndErrors 测试请求 ID 出现在访问日志与错误响应中
+func TestRequestIDMiddleware_LogsAndErrors(t *testing.T) {
+	var logBuf bytes.Buffer
+	log.SetOutput(&logBuf)
+	defer log.SetOutput(os.Stderr)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+	})
+	req := httptest.NewRequest("GET", "/api/orders", nil)
+	req.Header.Set(RequestIDHeader, "trace-123")
+	w := httptest.NewRecorder()
+
+	RequestIDMiddleware(LoggingMiddleware(next)).ServeHTTP(w, req)
+
+	if !strings.Contains(logBuf.String(), "request_id=trace-123") {
+		t.Errorf("log = %q, want request id", logBuf.String())
+	}
+
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if body["request_id"] != "trace-123" {
+		t.Errorf("error body = %v, want request_id trace-123", body)
+	}
+}
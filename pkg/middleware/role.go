@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/biqiangwu/flowerSalesSystem/internal/user"
@@ -9,13 +8,14 @@ import (
 
 // RoleMiddleware 角色中间件，验证用户角色是否有权限访问
 // 返回一个中间件函数，该函数接受下一个 handler 并返回一个新的 handler
+// 需放在 AuthMiddleware 之后使用；上下文中没有用户时返回 401，角色不在允许列表中时返回 403
 func RoleMiddleware(allowedRoles ...user.Role) func(http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			// 从上下文获取用户信息
-			u, ok := GetUserFromContext(r)
+			u, ok := UserFromContext(r.Context())
 			if !ok {
-				http.Error(w, "unauthorized: no user in context", http.StatusUnauthorized)
+				writeError(w, http.StatusUnauthorized, "unauthorized")
 				return
 			}
 
@@ -29,7 +29,7 @@ func RoleMiddleware(allowedRoles ...user.Role) func(http.HandlerFunc) http.Handl
 			}
 
 			if !allowed {
-				http.Error(w, fmt.Sprintf("forbidden: user role %s not allowed", u.Role), http.StatusForbidden)
+				writeError(w, http.StatusForbidden, "access denied")
 				return
 			}
 
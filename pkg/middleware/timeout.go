@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RequestTimeoutMiddleware 请求超时中间件，为请求上下文设置截止时间
+// 处理器通过 r.Context() 将截止时间传给服务层与数据库查询，超时后查询被取消；timeout <= 0 表示不限制
+func RequestTimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+	if timeout <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
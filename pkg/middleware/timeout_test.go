@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestRequestTimeoutMiddleware 测试请求上下文带有配置的截止时间，timeout <= 0 时不设置
+func TestRequestTimeoutMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		wantDeadline bool
+	}{
+		{name: "设置超时", timeout: time.Minute, wantDeadline: true},
+		{name: "不限制", timeout: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deadline time.Time
+			var ok bool
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, ok = r.Context().Deadline()
+			})
+
+			start := time.Now()
+			RequestTimeoutMiddleware(tt.timeout)(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/flowers", nil))
+
+			if ok != tt.wantDeadline {
+				t.Fatalf("has deadline = %v, want %v", ok, tt.wantDeadline)
+			}
+			if ok && (deadline.Before(start.Add(tt.timeout)) || deadline.After(time.Now().Add(tt.timeout))) {
+				t.Errorf("deadline = %v, want about %v from start", deadline, tt.timeout)
+			}
+		})
+	}
+}